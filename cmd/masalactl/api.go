@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"masala_inventory_managment/internal/infrastructure/network"
+)
+
+const (
+	apiV1Prefix        = "/api/v1"
+	apiTimeout         = 5 * time.Minute
+	envClientStateDir  = "MASALA_CLIENT_STATE_DIR"
+	clientStateDirName = "MasalaInventory"
+	serverPinsFileName = "server_pins.json"
+)
+
+// apiClient calls the server's /api/v1 with a bearer token.
+type apiClient struct {
+	baseURL string
+	http    *http.Client
+	token   string
+}
+
+// apiError is the error envelope of every /api/v1 route.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Fields  []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"fields,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	message := e.Message
+	for _, field := range e.Fields {
+		message += fmt.Sprintf("; %s: %s", field.Field, field.Message)
+	}
+	return fmt.Sprintf("server refused the request (%s): %s", e.Code, message)
+}
+
+// connect builds a client for the server and logs in unless a token was given.
+func (c *cli) connect() (*apiClient, error) {
+	client, err := c.newAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	if client.token != "" {
+		return client, nil
+	}
+	if c.username == "" || c.password == "" {
+		return nil, fmt.Errorf("not logged in: set %s, or %s and %s", envToken, envUsername, envPassword)
+	}
+	token, err := client.login(c.username, c.password)
+	if err != nil {
+		return nil, err
+	}
+	client.token = token.Token
+	return client, nil
+}
+
+// newAPIClient verifies HTTPS servers the way the desktop client does: by the certificate
+// fingerprint pinned on first use, shared with the client on this machine, or by the
+// fingerprint given with --fingerprint.
+func (c *cli) newAPIClient() (*apiClient, error) {
+	raw := strings.TrimSpace(c.server)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return nil, usageErrorf("invalid server address %q", c.server)
+	}
+	client := &http.Client{Timeout: apiTimeout}
+	if parsed.Scheme == "https" {
+		tlsConfig := network.NewPinStore(filepath.Join(clientStateDir(), serverPinsFileName)).ClientTLSConfig(parsed.Host)
+		if c.fingerprint != "" {
+			expected := network.NormalizeFingerprint(c.fingerprint)
+			if expected == "" {
+				return nil, usageErrorf("invalid certificate fingerprint %q", c.fingerprint)
+			}
+			tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+				if len(state.PeerCertificates) == 0 {
+					return fmt.Errorf("server presented no certificate")
+				}
+				if actual := network.Fingerprint(state.PeerCertificates[0].Raw); actual != expected {
+					return &network.FingerprintMismatchError{Server: parsed.Host, Expected: expected, Actual: actual}
+				}
+				return nil
+			}
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	return &apiClient{
+		baseURL: strings.TrimRight(parsed.Scheme+"://"+parsed.Host, "/") + apiV1Prefix,
+		http:    client,
+		token:   c.token,
+	}, nil
+}
+
+// clientStateDir matches the desktop client's state folder, so a server paired there is
+// trusted here too.
+func clientStateDir() string {
+	if dir := strings.TrimSpace(os.Getenv(envClientStateDir)); dir != "" {
+		return dir
+	}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(configDir, clientStateDirName)
+	}
+	return "."
+}
+
+// call sends body as JSON and decodes a JSON response into result, which may be nil.
+func (a *apiClient) call(method, path string, body, result interface{}) error {
+	response, err := a.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("unreadable response from server: %w", err)
+	}
+	return nil
+}
+
+// send performs the request and turns a non-2xx response into an error. The caller closes
+// the body of a successful response.
+func (a *apiClient) send(method, path string, body interface{}) (*http.Response, error) {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequest(method, a.baseURL+path, payload)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if a.token != "" {
+		request.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	response, err := a.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("server unreachable: %w", err)
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+	defer response.Body.Close()
+	var envelope struct {
+		Error apiError `json:"error"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&envelope); err != nil || envelope.Error.Message == "" {
+		return nil, fmt.Errorf("server returned %s", response.Status)
+	}
+	return nil, &envelope.Error
+}
+
+type tokenResult struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+func (r tokenResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, r.Token)
+}
+
+func (a *apiClient) login(username, password string) (tokenResult, error) {
+	var token tokenResult
+	err := a.call(http.MethodPost, "/auth/login", map[string]string{"username": username, "password": password}, &token)
+	return token, err
+}
+
+func runLogin(c *cli, args []string) (textResult, error) {
+	if len(args) != 0 {
+		return nil, usageErrorf("login takes no arguments")
+	}
+	if c.username == "" || c.password == "" {
+		return nil, usageErrorf("login needs %s and %s", envUsername, envPassword)
+	}
+	client, err := c.newAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	token, err := client.login(c.username, c.password)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+type userAccount struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	IsActive  bool   `json:"is_active"`
+	HasPin    bool   `json:"has_pin"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type userList []userAccount
+
+func (users userList) writeText(w io.Writer) {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "USERNAME\tROLE\tACTIVE\tPIN\tCREATED")
+	for _, user := range users {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", user.Username, user.Role, yesNo(user.IsActive), yesNo(user.HasPin), user.CreatedAt)
+	}
+	table.Flush()
+}
+
+func runUsers(c *cli, args []string) (textResult, error) {
+	if len(args) == 0 {
+		return nil, usageErrorf("users needs a subcommand: list, create, delete, enable, disable, role, password or pin")
+	}
+	want := map[string]int{"list": 0, "create": 2, "delete": 1, "enable": 1, "disable": 1, "role": 2, "password": 1, "pin": 1}
+	arity, ok := want[args[0]]
+	if !ok {
+		return nil, usageErrorf("unknown users subcommand %q", args[0])
+	}
+	if len(args)-1 != arity {
+		return nil, usageErrorf("users %s takes %d arguments", args[0], arity)
+	}
+	client, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	userPath := func(username string) string {
+		return "/users/" + url.PathEscape(username)
+	}
+
+	switch args[0] {
+	case "list":
+		var users userList
+		if err := client.call(http.MethodGet, "/users", nil, &users); err != nil {
+			return nil, err
+		}
+		return users, nil
+	case "create":
+		password, err := c.readSecret()
+		if err != nil {
+			return nil, err
+		}
+		body := map[string]string{"username": args[1], "role": args[2], "password": password}
+		if err := client.call(http.MethodPost, "/users", body, nil); err != nil {
+			return nil, err
+		}
+		return done("Created %s as %s", args[1], args[2]), nil
+	case "delete":
+		if err := client.call(http.MethodDelete, userPath(args[1]), nil, nil); err != nil {
+			return nil, err
+		}
+		return done("Deleted %s", args[1]), nil
+	case "enable", "disable":
+		active := args[0] == "enable"
+		if err := client.call(http.MethodPut, userPath(args[1])+"/active", map[string]bool{"is_active": active}, nil); err != nil {
+			return nil, err
+		}
+		return done("%s is now %s", args[1], map[bool]string{true: "enabled", false: "disabled"}[active]), nil
+	case "role":
+		if err := client.call(http.MethodPut, userPath(args[1])+"/role", map[string]string{"role": args[2]}, nil); err != nil {
+			return nil, err
+		}
+		return done("%s is now %s", args[1], args[2]), nil
+	case "password":
+		password, err := c.readSecret()
+		if err != nil {
+			return nil, err
+		}
+		if err := client.call(http.MethodPut, userPath(args[1])+"/password", map[string]string{"new_password": password}, nil); err != nil {
+			return nil, err
+		}
+		return done("Password reset for %s", args[1]), nil
+	default:
+		pin, err := c.readSecret()
+		if err != nil {
+			return nil, err
+		}
+		if err := client.call(http.MethodPut, userPath(args[1])+"/pin", map[string]string{"pin": pin}, nil); err != nil {
+			return nil, err
+		}
+		if pin == "" {
+			return done("PIN cleared for %s", args[1]), nil
+		}
+		return done("PIN set for %s", args[1]), nil
+	}
+}
+
+// paramFlags collects repeated --param name=value flags.
+type paramFlags url.Values
+
+func (p paramFlags) String() string {
+	return url.Values(p).Encode()
+}
+
+func (p paramFlags) Set(raw string) error {
+	name, value, ok := strings.Cut(raw, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", raw)
+	}
+	url.Values(p).Add(name, value)
+	return nil
+}
+
+type exportResult struct {
+	File        string `json:"file"`
+	ContentType string `json:"content_type"`
+	Bytes       int64  `json:"bytes"`
+}
+
+func (r exportResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Wrote %s (%d bytes)\n", r.File, r.Bytes)
+}
+
+// runExport streams a dataset export to a file, named by the server unless --out is given.
+// With --out - the file goes to standard output and no result is written.
+func runExport(c *cli, args []string) (textResult, error) {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(c.errOut)
+	format := flags.String("format", "csv", "csv, xlsx or pdf")
+	outPath := flags.String("out", "", "file to write, or - for standard output")
+	params := paramFlags{}
+	flags.Var(params, "param", "filter such as search=turmeric or active_only=true; repeatable")
+	dataset, rest := splitLeading(args)
+	if err := flags.Parse(rest); err != nil {
+		return nil, usageErrorf("export: %v", err)
+	}
+	if dataset == "" || flags.NArg() != 0 {
+		return nil, usageErrorf("export takes one DATASET: items, parties, lots, lot_movements, stock_adjustments, stock_summary, stock_valuation or audit_log")
+	}
+	client, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values(params)
+	query.Set("format", *format)
+	response, err := client.send(http.MethodGet, "/exports/"+url.PathEscape(dataset)+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if *outPath == "-" {
+		_, err := io.Copy(c.out, response.Body)
+		return nil, err
+	}
+	path := *outPath
+	if path == "" {
+		_, disposition, _ := mime.ParseMediaType(response.Header.Get("Content-Disposition"))
+		path = filepath.Base(disposition["filename"])
+		if path == "." || path == string(filepath.Separator) {
+			path = dataset + "." + *format
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	written, err := io.Copy(file, response.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("export interrupted: %w", err)
+	}
+	return exportResult{File: path, ContentType: response.Header.Get("Content-Type"), Bytes: written}, nil
+}
+
+type importRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type importReport struct {
+	Kind       string           `json:"kind"`
+	Format     string           `json:"format"`
+	TotalRows  int              `json:"total_rows"`
+	ValidRows  int              `json:"valid_rows"`
+	Errors     []importRowError `json:"errors"`
+	Committed  bool             `json:"committed"`
+	GRNNumbers []string         `json:"grn_numbers,omitempty"`
+}
+
+func (r importReport) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%s (%s): %d of %d rows valid\n", r.Kind, r.Format, r.ValidRows, r.TotalRows)
+	for _, rowErr := range r.Errors {
+		if rowErr.Field != "" {
+			fmt.Fprintf(w, "  row %d, %s: %s\n", rowErr.Row, rowErr.Field, rowErr.Message)
+		} else {
+			fmt.Fprintf(w, "  row %d: %s\n", rowErr.Row, rowErr.Message)
+		}
+	}
+	switch {
+	case r.Committed:
+		fmt.Fprintln(w, "Imported.")
+		if len(r.GRNNumbers) > 0 {
+			fmt.Fprintf(w, "GRNs: %s\n", strings.Join(r.GRNNumbers, ", "))
+		}
+	case len(r.Errors) == 0:
+		fmt.Fprintln(w, "Validated only; run again with --commit to import.")
+	}
+}
+
+// runImport validates a CSV or XLSX file on the server and, with --commit, imports it
+// when every row is valid.
+func runImport(c *cli, args []string) (textResult, error) {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(c.errOut)
+	commit := flags.Bool("commit", false, "import the file when every row is valid")
+	kind, rest := splitLeading(args)
+	path, rest := splitLeading(rest)
+	if err := flags.Parse(rest); err != nil {
+		return nil, usageErrorf("import: %v", err)
+	}
+	if kind == "" || path == "" || flags.NArg() != 0 {
+		return nil, usageErrorf("import takes KIND and FILE")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{"kind": kind, "file_name": filepath.Base(path), "content": content, "commit": *commit}
+	var report importReport
+	if err := client.call(http.MethodPost, "/imports", body, &report); err != nil {
+		return nil, err
+	}
+	if len(report.Errors) > 0 {
+		return report, fmt.Errorf("%d rows have errors; nothing was imported", report.TotalRows-report.ValidRows)
+	}
+	return report, nil
+}
+
+// splitLeading takes the first argument when it is not a flag, so arguments can come
+// before a subcommand's flags.
+func splitLeading(args []string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", args
+	}
+	return args[0], args[1:]
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"masala_inventory_managment"
+	domainBackup "masala_inventory_managment/internal/domain/backup"
+	infraBackup "masala_inventory_managment/internal/infrastructure/backup"
+	"masala_inventory_managment/internal/infrastructure/db"
+	"masala_inventory_managment/internal/infrastructure/license"
+	infraSys "masala_inventory_managment/internal/infrastructure/system"
+)
+
+const (
+	envLicensePublicKey     = "MASALA_LICENSE_PUBLIC_KEY"
+	defaultLicensePublicKey = "ebe55ca92c5a7161a80ce7718c7567e2566a6f51fb564f191bee61cb7b29d776"
+)
+
+// LicensePublicKey can be set via -ldflags "-X main.LicensePublicKey=...", as for the server.
+var LicensePublicKey string
+
+// lockServerInstance is replaced in tests, which must not contend for the real lock.
+var lockServerInstance = infraSys.LockServerInstance
+
+// requireServerStopped takes the server's single-instance lock before a command that
+// rewrites the database file, refusing while the server is running.
+func requireServerStopped(command string) error {
+	running, err := lockServerInstance()
+	if err != nil {
+		return fmt.Errorf("could not check whether the server is running: %w", err)
+	}
+	if running {
+		return fmt.Errorf("the server is running; stop it before %s", command)
+	}
+	return nil
+}
+
+// openDatabase connects to an existing database file; database commands never create one.
+func (c *cli) openDatabase() (*db.DatabaseManager, error) {
+	if _, err := os.Stat(c.dbPath); err != nil {
+		return nil, fmt.Errorf("database not found: %w", err)
+	}
+	manager := db.NewDatabaseManager(c.dbPath)
+	if err := manager.Connect(); err != nil {
+		return nil, fmt.Errorf("database connection failed: %w", err)
+	}
+	return manager, nil
+}
+
+// backupService is configured from the same environment as the server's, so the backups
+// it takes, and the pruning that follows them, keep the server's schedules and retention.
+func (c *cli) backupService(manager *db.DatabaseManager) (*infraBackup.Service, error) {
+	hardwareID, _ := license.GetHardwareID(license.NewHardwareProvider())
+	config, err := infraBackup.ConfigFromEnv(manager.GetDBPath(), Version, hardwareID)
+	if err != nil {
+		return nil, err
+	}
+	if c.backupPath != "" {
+		config.BackupPath = c.backupPath
+	}
+	logInfo := func(string, ...interface{}) {}
+	logError := func(format string, v ...interface{}) {
+		fmt.Fprintf(c.errOut, format+"\n", v...)
+	}
+	return infraBackup.NewService(manager, config, logInfo, logError), nil
+}
+
+type backupEntry struct {
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	CreatedAt     time.Time `json:"created_at"`
+	Schedule      string    `json:"schedule,omitempty"`
+	Size          int64     `json:"size"`
+	Encrypted     bool      `json:"encrypted"`
+	SchemaVersion uint      `json:"schema_version,omitempty"`
+	Verification  string    `json:"verification"`
+	VerifiedAt    time.Time `json:"verified_at,omitzero"`
+	Message       string    `json:"message,omitempty"`
+}
+
+func newBackupEntry(info domainBackup.BackupInfo) backupEntry {
+	entry := backupEntry{
+		Name:         info.Name,
+		Path:         info.Path,
+		CreatedAt:    info.CreatedAt,
+		Schedule:     info.Schedule,
+		Size:         info.Size,
+		Encrypted:    info.Encrypted,
+		Verification: info.Verification.Status,
+		VerifiedAt:   info.Verification.CheckedAt,
+		Message:      info.Verification.Message,
+	}
+	if info.Manifest != nil {
+		entry.SchemaVersion = info.Manifest.SchemaVersion
+	}
+	return entry
+}
+
+type backupList []backupEntry
+
+func (backups backupList) writeText(w io.Writer) {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tCREATED\tSCHEDULE\tSIZE\tENCRYPTED\tVERIFICATION")
+	for _, backup := range backups {
+		schedule := backup.Schedule
+		if schedule == "" {
+			schedule = "manual"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\t%s\n", backup.Name, backup.CreatedAt.Local().Format(time.DateTime), schedule, backup.Size, yesNo(backup.Encrypted), backup.Verification)
+	}
+	table.Flush()
+}
+
+type backupStatus struct {
+	LastBackupAt time.Time `json:"last_backup_at,omitzero"`
+	File         string    `json:"file,omitempty"`
+	Size         int64     `json:"size"`
+	Verification string    `json:"verification,omitempty"`
+	Encrypted    bool      `json:"encrypted"`
+	Backups      int       `json:"backups"`
+}
+
+func (s backupStatus) writeText(w io.Writer) {
+	if s.LastBackupAt.IsZero() {
+		fmt.Fprintln(w, "No backups yet")
+	} else {
+		fmt.Fprintf(w, "Last backup: %s (%s, %s)\n", s.LastBackupAt.Local().Format(time.DateTime), filepath.Base(s.File), s.Verification)
+	}
+	fmt.Fprintf(w, "Archives: %d, encrypted: %s\n", s.Backups, yesNo(s.Encrypted))
+}
+
+type verificationList []backupEntry
+
+func (results verificationList) writeText(w io.Writer) {
+	for _, result := range results {
+		if result.Message != "" {
+			fmt.Fprintf(w, "%s: %s - %s\n", result.Name, result.Verification, result.Message)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", result.Name, result.Verification)
+		}
+	}
+}
+
+type pointInTimeResult struct {
+	Backup      string    `json:"backup"`
+	Replayed    int       `json:"replayed"`
+	RecoveredTo time.Time `json:"recovered_to"`
+}
+
+func (r pointInTimeResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Restored %s and replayed %d changes, up to %s\n", r.Backup, r.Replayed, r.RecoveredTo.Local().Format(time.DateTime))
+}
+
+func runBackup(c *cli, args []string) (textResult, error) {
+	subcommand, rest := splitLeading(args)
+	flags := flag.NewFlagSet("backup "+subcommand, flag.ContinueOnError)
+	flags.SetOutput(c.errOut)
+	all := flags.Bool("all", false, "verify every archive")
+	at := flags.String("at", "", "restore to this RFC3339 time")
+	passphraseStdin := flags.Bool("passphrase-stdin", false, "read the passphrase of an archive from another installation from standard input")
+	name, rest := splitLeading(rest)
+	if err := flags.Parse(rest); err != nil {
+		return nil, usageErrorf("backup: %v", err)
+	}
+	if flags.NArg() != 0 {
+		return nil, usageErrorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	switch subcommand {
+	case "run", "status", "list":
+		if name != "" {
+			return nil, usageErrorf("backup %s takes no arguments", subcommand)
+		}
+	case "verify":
+		if (name == "") == !*all {
+			return nil, usageErrorf("backup verify takes NAME or --all")
+		}
+	case "restore":
+		if (name == "") == (*at == "") {
+			return nil, usageErrorf("backup restore takes NAME or --at TIME")
+		}
+	default:
+		return nil, usageErrorf("backup needs a subcommand: run, status, list, verify or restore")
+	}
+	if subcommand == "restore" {
+		if err := requireServerStopped("restoring a backup"); err != nil {
+			return nil, err
+		}
+	}
+
+	manager, err := c.openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer manager.Close()
+	service, err := c.backupService(manager)
+	if err != nil {
+		return nil, err
+	}
+
+	switch subcommand {
+	case "run":
+		if err := service.Execute(); err != nil {
+			return nil, err
+		}
+		status, _ := service.GetStatus()
+		return done("Backed up to %s (%d bytes)", status.FilePath, status.Size), nil
+	case "status":
+		backups, err := service.ListBackups()
+		if err != nil {
+			return nil, err
+		}
+		status := backupStatus{Backups: len(backups)}
+		if current, err := service.GetStatus(); err == nil {
+			status.Encrypted = current.Encrypted
+		}
+		// The last backup is read from the folder; the server keeps its status in memory.
+		if len(backups) > 0 {
+			latest := backups[0]
+			for _, backup := range backups[1:] {
+				if backup.CreatedAt.After(latest.CreatedAt) {
+					latest = backup
+				}
+			}
+			status.LastBackupAt, status.File, status.Size = latest.CreatedAt, latest.Path, latest.Size
+			status.Verification = latest.Verification.Status
+		}
+		return status, nil
+	case "list":
+		backups, err := service.ListBackups()
+		if err != nil {
+			return nil, err
+		}
+		list := backupList{}
+		for _, backup := range backups {
+			list = append(list, newBackupEntry(backup))
+		}
+		return list, nil
+	case "verify":
+		names := []string{name}
+		if *all {
+			backups, err := service.ListBackups()
+			if err != nil {
+				return nil, err
+			}
+			names = names[:0]
+			for _, backup := range backups {
+				names = append(names, backup.Path)
+			}
+		}
+		results := verificationList{}
+		failed := 0
+		for _, path := range names {
+			verification, err := service.Verify(path)
+			if err != nil {
+				return results, err
+			}
+			entry := backupEntry{Name: filepath.Base(path), Path: path, Verification: verification.Status, VerifiedAt: verification.CheckedAt, Message: verification.Message}
+			if verification.Status != domainBackup.VerificationPassed {
+				failed++
+			}
+			results = append(results, entry)
+		}
+		if failed > 0 {
+			return results, fmt.Errorf("%d of %d backups failed verification", failed, len(results))
+		}
+		return results, nil
+	default:
+		if *at != "" {
+			moment, err := time.Parse(time.RFC3339, *at)
+			if err != nil {
+				return nil, usageErrorf("--at must be an RFC3339 time such as 2026-03-14T15:04:05+05:30")
+			}
+			restored, err := service.RestoreToTime(moment)
+			if err != nil {
+				return nil, err
+			}
+			return pointInTimeResult{Backup: restored.Backup, Replayed: restored.Replayed, RecoveredTo: restored.RecoveredTo}, nil
+		}
+		if *passphraseStdin {
+			passphrase, err := c.readSecret()
+			if err != nil {
+				return nil, err
+			}
+			if err := service.RestoreWithPassphrase(name, passphrase); err != nil {
+				return nil, err
+			}
+		} else if err := service.Restore(name); err != nil {
+			return nil, err
+		}
+		return done("Restored %s", filepath.Base(name)), nil
+	}
+}
+
+type licenseResult struct {
+	license.StatusSnapshot
+	Valid bool `json:"valid"`
+}
+
+func (r licenseResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Status: %s\n", r.Status)
+	if r.ExpiresAt != "" {
+		fmt.Fprintf(w, "Expires: %s (%d days remaining)\n", r.ExpiresAt, r.DaysRemaining)
+	}
+	if r.HardwareID != "" {
+		fmt.Fprintf(w, "Hardware ID: %s\n", r.HardwareID)
+	}
+	if r.Message != "" {
+		fmt.Fprintf(w, "Problem: %s\n", r.Message)
+	}
+}
+
+// runLicense reads the license the way the server does at startup, without recording a
+// heartbeat.
+func runLicense(c *cli, args []string) (textResult, error) {
+	subcommand, rest := splitLeading(args)
+	flags := flag.NewFlagSet("license", flag.ContinueOnError)
+	flags.SetOutput(c.errOut)
+	licensePath := flags.String("license", "license.key", "license file")
+	if err := flags.Parse(rest); err != nil {
+		return nil, usageErrorf("license: %v", err)
+	}
+	if subcommand != "status" || flags.NArg() != 0 {
+		return nil, usageErrorf("license takes the subcommand status")
+	}
+	publicKey := strings.TrimSpace(LicensePublicKey)
+	if publicKey == "" {
+		publicKey = envOr(envLicensePublicKey, defaultLicensePublicKey)
+	}
+	heartbeatPath := filepath.Join(filepath.Dir(*licensePath), ".hw_hb")
+	snapshot, err := license.NewLicensingService(publicKey, *licensePath, heartbeatPath).GetCurrentStatus()
+	if err != nil {
+		snapshot.Message = err.Error()
+		return licenseResult{StatusSnapshot: snapshot}, fmt.Errorf("license is not valid")
+	}
+	if snapshot.Status == license.StatusExpired {
+		return licenseResult{StatusSnapshot: snapshot}, fmt.Errorf("license has expired")
+	}
+	return licenseResult{StatusSnapshot: snapshot, Valid: true}, nil
+}
+
+type migration struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+}
+
+type migrationStatus struct {
+	Version uint        `json:"version"`
+	Dirty   bool        `json:"dirty"`
+	Latest  uint        `json:"latest"`
+	Pending []migration `json:"pending"`
+}
+
+func newMigrationStatus(status *db.MigrationStatus) migrationStatus {
+	result := migrationStatus{Version: status.Version, Dirty: status.Dirty, Latest: status.Latest, Pending: []migration{}}
+	for _, pending := range status.Pending {
+		result.Pending = append(result.Pending, migration{Version: pending.Version, Name: pending.Name})
+	}
+	return result
+}
+
+func (s migrationStatus) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Schema version: %d (latest %d)\n", s.Version, s.Latest)
+	fmt.Fprintf(w, "Dirty: %s\n", yesNo(s.Dirty))
+	if len(s.Pending) == 0 {
+		fmt.Fprintln(w, "Pending migrations: none")
+		return
+	}
+	fmt.Fprintln(w, "Pending migrations:")
+	for _, pending := range s.Pending {
+		fmt.Fprintf(w, "  %d %s\n", pending.Version, pending.Name)
+	}
+}
+
+// runMigrate mirrors the server's migrate subcommand; up and down back up the database first.
+func runMigrate(c *cli, args []string) (textResult, error) {
+	if len(args) == 0 {
+		return nil, usageErrorf("migrate needs a subcommand: status, up, down, force or dry-run")
+	}
+	var number int
+	switch args[0] {
+	case "status", "up", "dry-run":
+		if len(args) != 1 {
+			return nil, usageErrorf("migrate %s takes no arguments", args[0])
+		}
+	case "down", "force":
+		if len(args) != 2 {
+			return nil, usageErrorf("migrate %s needs a number", args[0])
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, usageErrorf("migrate %s: %q is not a number", args[0], args[1])
+		}
+		number = n
+	default:
+		return nil, usageErrorf("unknown migrate subcommand %q", args[0])
+	}
+	if err := requireServerStopped("running migrate"); err != nil {
+		return nil, err
+	}
+
+	manager, err := c.openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer manager.Close()
+	backupService, err := c.backupService(manager)
+	if err != nil {
+		return nil, err
+	}
+	migrator := db.NewMigrator(manager)
+	migrator.BackupBeforeChanges(backupService.ExecuteBeforeMigration)
+	assets := masala_inventory_managment.MigrationAssets
+
+	switch args[0] {
+	case "up":
+		err = migrator.RunMigrations(assets, migrationsDir)
+	case "down":
+		err = migrator.Down(assets, migrationsDir, number)
+	case "force":
+		err = migrator.Force(assets, migrationsDir, number)
+	case "dry-run":
+		status, err := migrator.DryRun(assets, migrationsDir)
+		if err != nil {
+			return nil, fmt.Errorf("dry run failed on a copy of the database: %w", err)
+		}
+		return newMigrationStatus(status), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status, err := migrator.Status(assets, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+	return newMigrationStatus(status), nil
+}
+
+type integrityResult struct {
+	Database   string `json:"database"`
+	Integrity  string `json:"integrity"`
+	HashChains string `json:"hash_chains"`
+}
+
+func (r integrityResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "SQLite integrity check: %s\n", r.Integrity)
+	fmt.Fprintf(w, "Audit hash chains: %s\n", r.HashChains)
+}
+
+// runIntegrity runs SQLite's integrity check and verifies the audit hash chains.
+func runIntegrity(c *cli, args []string) (textResult, error) {
+	if len(args) != 0 {
+		return nil, usageErrorf("integrity takes no arguments")
+	}
+	manager, err := c.openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer manager.Close()
+
+	result := integrityResult{Database: c.dbPath, Integrity: "ok", HashChains: "ok"}
+	integrityErr := manager.IntegrityCheck()
+	if integrityErr != nil {
+		result.Integrity = integrityErr.Error()
+	}
+	chainErr := manager.VerifyHashChains()
+	if chainErr != nil {
+		result.HashChains = chainErr.Error()
+	}
+	if integrityErr != nil || chainErr != nil {
+		return result, fmt.Errorf("integrity check failed for %s", c.dbPath)
+	}
+	return result, nil
+}
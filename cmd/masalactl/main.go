@@ -0,0 +1,214 @@
+// Command masalactl administers a Masala Inventory server from a terminal. Users, exports
+// and imports go through the server's /api/v1; backups, license status, migrations and the
+// integrity check open the database directly.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Version can be set via -ldflags "-X main.Version=...". It is recorded in backup manifests.
+var Version = "dev"
+
+const (
+	envServerAddr   = "MASALA_SERVER_PROBE_ADDR"
+	envToken        = "MASALA_TOKEN"
+	envUsername     = "MASALA_USERNAME"
+	envPassword     = "MASALA_PASSWORD"
+	defaultServer   = "127.0.0.1:8090"
+	defaultDBPath   = "masala_inventory.db"
+	migrationsDir   = "internal/infrastructure/db/migrations"
+	exitFailure     = 1
+	exitUsageFailed = 2
+)
+
+const usage = `usage: masalactl [global flags] COMMAND [flags] [arguments]
+
+Server commands (through the HTTP API):
+  login                              print a bearer token for MASALA_TOKEN
+  users list
+  users create USERNAME ROLE         password is read from standard input
+  users delete USERNAME
+  users enable|disable USERNAME
+  users role USERNAME ROLE
+  users password USERNAME            new password is read from standard input
+  users pin USERNAME                 PIN is read from standard input; empty clears it
+  export DATASET [--format F] [--out FILE] [--param name=value]...
+  import KIND FILE [--commit]        KIND is items, parties or opening_stock
+
+Database commands (stop the server before restore and migrate):
+  backup run|status|list
+  backup verify NAME|--all
+  backup restore NAME [--passphrase-stdin]
+  backup restore --at TIME           RFC3339 time; replays shipped changes up to it
+  license status [--license FILE]
+  migrate status|up|dry-run
+  migrate down N
+  migrate force VERSION
+  integrity
+
+Global flags:`
+
+// cli carries the global flags and the streams commands read from and write to.
+type cli struct {
+	in          io.Reader
+	out         io.Writer
+	errOut      io.Writer
+	json        bool
+	server      string
+	token       string
+	username    string
+	password    string
+	fingerprint string
+	dbPath      string
+	backupPath  string
+}
+
+// textResult is a command result that can describe itself for a person. Results are
+// written as JSON instead when --json is given.
+type textResult interface {
+	writeText(w io.Writer)
+}
+
+// usageError is returned for a malformed command line.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+type command func(c *cli, args []string) (textResult, error)
+
+var commands = map[string]command{
+	"login":     runLogin,
+	"users":     runUsers,
+	"export":    runExport,
+	"import":    runImport,
+	"backup":    runBackup,
+	"license":   runLicense,
+	"migrate":   runMigrate,
+	"integrity": runIntegrity,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes one command and returns the exit status. A command can return a result
+// along with an error, such as a failed verification; both are written.
+func run(args []string, in io.Reader, out, errOut io.Writer) int {
+	c := &cli{in: in, out: out, errOut: errOut}
+	flags := flag.NewFlagSet("masalactl", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	flags.Usage = func() {
+		fmt.Fprintln(errOut, usage)
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&c.json, "json", false, "write results as JSON")
+	flags.StringVar(&c.server, "server", envOr(envServerAddr, defaultServer), "server address (host:port or URL), or "+envServerAddr)
+	flags.StringVar(&c.token, "token", os.Getenv(envToken), "bearer token, or "+envToken+"; otherwise "+envUsername+" and "+envPassword+" are used to log in")
+	flags.StringVar(&c.username, "username", os.Getenv(envUsername), "username to log in with, or "+envUsername)
+	flags.StringVar(&c.fingerprint, "fingerprint", "", "expected server certificate fingerprint; pinned on first use when omitted")
+	flags.StringVar(&c.dbPath, "db", defaultDBPath, "database file for database commands")
+	flags.StringVar(&c.backupPath, "backups", "", "backup folder (default: backups beside the database)")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return exitUsageFailed
+	}
+	c.password = os.Getenv(envPassword)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsageFailed
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		c.writeError(usageErrorf("unknown command %q", flags.Arg(0)))
+		return exitUsageFailed
+	}
+	result, err := cmd(c, flags.Args()[1:])
+	if result != nil {
+		c.writeResult(result)
+	}
+	if err != nil {
+		c.writeError(err)
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			return exitUsageFailed
+		}
+		return exitFailure
+	}
+	return 0
+}
+
+func (c *cli) writeResult(result textResult) {
+	if !c.json {
+		result.writeText(c.out)
+		return
+	}
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		fmt.Fprintf(c.errOut, "failed to write result: %v\n", err)
+	}
+}
+
+// writeError reports err on standard error, as {"error": "..."} when --json is given.
+func (c *cli) writeError(err error) {
+	if c.json {
+		encoded, _ := json.Marshal(map[string]string{"error": err.Error()})
+		fmt.Fprintln(c.errOut, string(encoded))
+		return
+	}
+	fmt.Fprintf(c.errOut, "masalactl: %v\n", err)
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintln(c.errOut, "Run masalactl -h for usage.")
+	}
+}
+
+// readSecret reads the first line of standard input, so passwords stay out of the
+// process list and shell history.
+func (c *cli) readSecret() (string, error) {
+	line, err := bufio.NewReader(c.in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read standard input: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// messageResult is the result of a command that only reports what it did.
+type messageResult struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+func (r messageResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, r.Message)
+}
+
+func done(format string, args ...interface{}) textResult {
+	return messageResult{OK: true, Message: fmt.Sprintf(format, args...)}
+}
+
+func envOr(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+	return fallback
+}
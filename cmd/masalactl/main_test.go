@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"masala_inventory_managment"
+	infraBackup "masala_inventory_managment/internal/infrastructure/backup"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestUsers_LogInPinTheServerAndCallTheAPI(t *testing.T) {
+	t.Setenv(envClientStateDir, t.TempDir())
+	t.Setenv(envToken, "")
+	t.Setenv(envUsername, "admin")
+	t.Setenv(envPassword, "s3cret-pass")
+	var pinBody map[string]string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/auth/login" {
+			var login map[string]string
+			json.NewDecoder(r.Body).Decode(&login)
+			if login["username"] != "admin" || login["password"] != "s3cret-pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": "unauthorized", "message": "invalid credentials"}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"token": "token-1", "expires_at": 1})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/users":
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"username": "admin", "role": "Admin", "is_active": true}})
+		case r.Method == http.MethodPut && r.URL.Path == "/api/v1/users/anita/pin":
+			json.NewDecoder(r.Body).Decode(&pinBody)
+			w.Write([]byte("null"))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": "forbidden", "message": "admin only"}})
+		}
+	}))
+	defer server.Close()
+
+	code, out, errOut := runCLI(t, "", "--json", "--server", server.URL, "users", "list")
+	if code != 0 {
+		t.Fatalf("users list failed (%d): %s", code, errOut)
+	}
+	var users []userAccount
+	if err := json.Unmarshal([]byte(out), &users); err != nil || len(users) != 1 || users[0].Username != "admin" {
+		t.Fatalf("unexpected users output %q: %v", out, err)
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv(envClientStateDir), serverPinsFileName)); err != nil {
+		t.Errorf("expected the server certificate to be pinned: %v", err)
+	}
+
+	if code, _, errOut := runCLI(t, "4321\n", "--server", server.URL, "users", "pin", "anita"); code != 0 || pinBody["pin"] != "4321" {
+		t.Errorf("users pin: code %d, body %v, %s", code, pinBody, errOut)
+	}
+	code, _, errOut = runCLI(t, "", "--json", "--server", server.URL, "users", "delete", "anita")
+	if code != exitFailure || !strings.Contains(errOut, `"error"`) || !strings.Contains(errOut, "admin only") {
+		t.Errorf("expected the server's error as JSON, got %d %q", code, errOut)
+	}
+
+	wrongPin := strings.Repeat("AB", 32)
+	if code, _, errOut := runCLI(t, "", "--fingerprint", wrongPin, "--server", server.URL, "users", "list"); code != exitFailure || !strings.Contains(errOut, "does not match") {
+		t.Errorf("expected a fingerprint mismatch, got %d %q", code, errOut)
+	}
+	if code, _, _ := runCLI(t, "", "--server", server.URL, "users", "role", "anita"); code != exitUsageFailed {
+		t.Errorf("expected a missing argument to be a usage error, got %d", code)
+	}
+}
+
+// stubServerInstance stands in for the server's single-instance lock.
+func stubServerInstance(t *testing.T, running bool) {
+	t.Helper()
+	previous := lockServerInstance
+	lockServerInstance = func() (bool, error) { return running, nil }
+	t.Cleanup(func() { lockServerInstance = previous })
+}
+
+func TestDatabaseCommands(t *testing.T) {
+	stubServerInstance(t, false)
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "masala_inventory.db")
+	manager := db.NewDatabaseManager(dbPath)
+	if err := manager.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.NewMigrator(manager).RunMigrations(masala_inventory_managment.MigrationAssets, migrationsDir); err != nil {
+		t.Fatal(err)
+	}
+	manager.Close()
+
+	code, out, errOut := runCLI(t, "", "--json", "--db", dbPath, "integrity")
+	if code != 0 || !strings.Contains(out, `"integrity": "ok"`) {
+		t.Fatalf("integrity: %d %s %s", code, out, errOut)
+	}
+
+	if code, _, errOut := runCLI(t, "", "--db", dbPath, "migrate", "down", "1"); code != 0 {
+		t.Fatalf("migrate down: %s", errOut)
+	}
+	code, out, _ = runCLI(t, "", "--json", "--db", dbPath, "migrate", "status")
+	var status migrationStatus
+	if err := json.Unmarshal([]byte(out), &status); err != nil || code != 0 || len(status.Pending) != 1 || status.Version+1 != status.Latest {
+		t.Fatalf("unexpected migrate status %q: %v", out, err)
+	}
+	// Backups are named to the second.
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	if code, _, errOut := runCLI(t, "", "--db", dbPath, "migrate", "up"); code != 0 {
+		t.Fatalf("migrate up: %s", errOut)
+	}
+
+	code, out, _ = runCLI(t, "", "--json", "--db", dbPath, "backup", "list")
+	var backups []backupEntry
+	if err := json.Unmarshal([]byte(out), &backups); err != nil || code != 0 || len(backups) != 2 || backups[0].Schedule != "pre-migration" {
+		t.Fatalf("expected a backup before each schema change, got %q: %v", out, err)
+	}
+	code, out, errOut = runCLI(t, "", "--db", dbPath, "backup", "verify", "--all")
+	if code != 0 || strings.Count(out, "PASSED") != 2 {
+		t.Errorf("backup verify: %d %q %s", code, out, errOut)
+	}
+	if code, _, _ := runCLI(t, "", "--db", dbPath, "backup", "restore"); code != exitUsageFailed {
+		t.Errorf("expected restore without a backup to be a usage error, got %d", code)
+	}
+	if code, _, errOut := runCLI(t, "", "--db", filepath.Join(dir, "missing.db"), "integrity"); code != exitFailure || !strings.Contains(errOut, "database not found") {
+		t.Errorf("expected a missing database to fail, got %d %q", code, errOut)
+	}
+}
+
+func TestDatabaseCommands_RefuseToRewriteTheDatabaseWhileTheServerRuns(t *testing.T) {
+	stubServerInstance(t, true)
+	dbPath := filepath.Join(t.TempDir(), "masala_inventory.db")
+	if err := os.WriteFile(dbPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"migrate", "up"}, {"migrate", "down", "1"}, {"backup", "restore", "backup.zip"}} {
+		code, _, errOut := runCLI(t, "", append([]string{"--db", dbPath}, args...)...)
+		if code != exitFailure || !strings.Contains(errOut, "server is running") {
+			t.Errorf("%v: expected a refusal while the server runs, got %d %q", args, code, errOut)
+		}
+	}
+	if code, _, errOut := runCLI(t, "", "--db", dbPath, "integrity"); strings.Contains(errOut, "server is running") {
+		t.Errorf("expected read-only commands to run alongside the server, got %d %q", code, errOut)
+	}
+}
+
+func TestBackupRun_KeepsTheServerRetention(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "masala_inventory.db")
+	manager := db.NewDatabaseManager(dbPath)
+	if err := manager.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.NewMigrator(manager).RunMigrations(masala_inventory_managment.MigrationAssets, migrationsDir); err != nil {
+		t.Fatal(err)
+	}
+	manager.Close()
+
+	// A nightly archive from ten days ago is inside the server's 30-day retention.
+	t.Setenv(infraBackup.EnvSchedules, "nightly|0 2 * * *|30")
+	backupDir := filepath.Join(dir, "backups")
+	if err := os.MkdirAll(backupDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(backupDir, "backup-"+time.Now().AddDate(0, 0, -10).Format("2006-01-02T150405")+"-nightly.zip")
+	if err := os.WriteFile(old, []byte("archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _, errOut := runCLI(t, "", "--db", dbPath, "backup", "run"); code != 0 {
+		t.Fatalf("backup run: %s", errOut)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Errorf("expected the 10-day-old nightly archive to be kept: %v", err)
+	}
+
+	t.Setenv(infraBackup.EnvSchedules, "nightly|0 2 * * *|not-a-number")
+	if code, _, errOut := runCLI(t, "", "--db", dbPath, "backup", "run"); code != exitFailure || !strings.Contains(errOut, infraBackup.EnvSchedules) {
+		t.Errorf("expected invalid backup settings to fail, got %d %q", code, errOut)
+	}
+}
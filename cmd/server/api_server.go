@@ -2,14 +2,25 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"masala_inventory_managment/internal/app"
+	appAudit "masala_inventory_managment/internal/app/audit"
+	appExport "masala_inventory_managment/internal/app/export"
 	appInventory "masala_inventory_managment/internal/app/inventory"
+	appInvoice "masala_inventory_managment/internal/app/invoice"
+	appLabel "masala_inventory_managment/internal/app/label"
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+	"masala_inventory_managment/internal/infrastructure/network"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -17,6 +28,7 @@ import (
 const (
 	envServerAPIBindAddr     = "MASALA_SERVER_API_BIND_ADDR"
 	defaultServerAPIBindAddr = "0.0.0.0:8090"
+	defaultServerAPIPort     = 8090
 )
 
 type serverLoginRequest struct {
@@ -24,6 +36,11 @@ type serverLoginRequest struct {
 	Password string `json:"password"`
 }
 
+type serverPinLoginRequest struct {
+	Username string `json:"username"`
+	Pin      string `json:"pin"`
+}
+
 type serverSessionRoleRequest struct {
 	AuthToken string `json:"auth_token"`
 }
@@ -34,16 +51,19 @@ type serverErrorResponse struct {
 
 type serverAPIApplication interface {
 	Login(username, password string) (app.AuthTokenResult, error)
+	LoginWithPinFrom(username, pin, clientAddr string) (app.AuthTokenResult, error)
 	GetSessionRole(authToken string) (string, error)
 	CreateUser(input app.CreateUserInput) error
 	ListUsers(input app.ListUsersInput) ([]app.UserAccountResult, error)
 	UpdateUserRole(input app.UpdateUserRoleInput) error
 	SetUserActive(input app.SetUserActiveInput) error
 	ResetUserPassword(input app.ResetUserPasswordInput) error
+	SetUserPin(input app.SetUserPinInput) error
 	DeleteUser(input app.DeleteUserInput) error
 	CreateItemMaster(input appInventory.CreateItemInput) (app.ItemMasterResult, error)
 	UpdateItemMaster(input appInventory.UpdateItemInput) (app.ItemMasterResult, error)
 	ListItems(input appInventory.ListItemsInput) ([]app.ItemMasterResult, error)
+	ListItemsPage(input appInventory.ListItemsInput) (app.ItemMasterPageResult, error)
 	CreatePackagingProfile(input appInventory.CreatePackagingProfileInput) (app.PackagingProfileResult, error)
 	ListPackagingProfiles(input appInventory.ListPackagingProfilesInput) ([]app.PackagingProfileResult, error)
 	CreateRecipe(input appInventory.CreateRecipeInput) (app.RecipeResult, error)
@@ -52,31 +72,59 @@ type serverAPIApplication interface {
 	CreateParty(input appInventory.CreatePartyInput) (app.PartyResult, error)
 	UpdateParty(input appInventory.UpdatePartyInput) (app.PartyResult, error)
 	ListParties(input appInventory.ListPartiesInput) ([]app.PartyResult, error)
+	ListPartiesPage(input appInventory.ListPartiesInput) (app.PartyPageResult, error)
 	ListMaterialLots(input appInventory.ListMaterialLotsInput) ([]app.MaterialLotResult, error)
+	ListMaterialLotsPage(input appInventory.ListMaterialLotsInput) (app.MaterialLotPageResult, error)
 	RecordLotStockMovement(input appInventory.RecordLotStockMovementInput) (app.LotStockMovementResult, error)
 	ListLotStockMovements(input appInventory.ListLotStockMovementsInput) ([]app.LotStockMovementResult, error)
+	ListLotStockMovementsPage(input appInventory.ListLotStockMovementsInput) (app.LotStockMovementPageResult, error)
 	CreateGRN(input appInventory.CreateGRNInput) (app.GRNResult, error)
 	CreateUnitConversionRule(input appInventory.CreateUnitConversionRuleInput) (app.UnitConversionRuleResult, error)
 	ListUnitConversionRules(input appInventory.ListUnitConversionRulesInput) ([]app.UnitConversionRuleResult, error)
 	ConvertQuantity(input appInventory.ConvertQuantityInput) (app.UnitConversionResult, error)
 	CreateStockAdjustment(input appInventory.CreateStockAdjustmentInput) (app.StockAdjustmentResult, error)
 	ListStockAdjustments(input appInventory.ListStockAdjustmentsInput) ([]app.StockAdjustmentResult, error)
+	ListStockAdjustmentsPage(input appInventory.ListStockAdjustmentsInput) (app.StockAdjustmentPageResult, error)
 	GetItemStockBalance(input appInventory.GetItemStockBalanceInput) (float64, error)
+	ImportMasterData(input appInventory.ImportInput) (appInventory.ImportReport, error)
+	ListAuditLog(input appAudit.ListAuditLogInput) ([]app.AuditLogEntryResult, error)
+	ExportAuditLogCSV(input appAudit.ListAuditLogInput) (string, error)
+	OpenExport(input appExport.ExportInput) (*appExport.Export, error)
+	GenerateInvoice(input appInvoice.GenerateInvoiceInput) (*domainInvoice.Invoice, error)
+	GetInvoice(input appInvoice.InvoiceIDInput) (*domainInvoice.Invoice, error)
+	ListInvoices(input appInvoice.ListInvoicesInput) ([]domainInvoice.Invoice, error)
+	InvoicePDF(input appInvoice.InvoiceIDInput) (*appInvoice.Document, error)
+	PrintLabels(input appLabel.PrintLabelsInput) (*appLabel.Document, error)
+	LookupLabel(input appLabel.LookupInput) (*appLabel.LotLookup, error)
+	SubscribeEvents() (<-chan domainEvents.Event, func(), error)
 }
 
-func startServerAuthAPIServer(application serverAPIApplication) (func(), error) {
-	router := buildServerAPIRouter(application)
+func startServerAuthAPIServer(application serverAPIApplication, tlsConfig *tls.Config, idempotency *idempotencyGuard, metrics *serverMetrics) (func(), error) {
+	mux, err := buildServerAPIRouter(application)
+	if err != nil {
+		return nil, err
+	}
+	var router http.Handler = mux
+	if metrics != nil {
+		mux.Handle("/metrics", metrics)
+		router = metrics.instrument(mux)
+	}
+	if idempotency != nil {
+		router = idempotency.wrap(router)
+	}
 
 	addr := resolveServerAPIBindAddr()
 	server := &http.Server{
 		Addr:              addr,
 		Handler:           router,
 		ReadHeaderTimeout: 5 * time.Second,
+		TLSConfig:         tlsConfig,
 	}
 
 	go func() {
-		slog.Info("Starting server auth API", "addr", addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Info("Starting server auth API", "addr", addr, "tls", true)
+		// Certificates come from tlsConfig.GetCertificate so rotation needs no restart.
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			slog.Error("Server auth API stopped unexpectedly", "error", err)
 		}
 	}()
@@ -92,7 +140,7 @@ func startServerAuthAPIServer(application serverAPIApplication) (func(), error)
 	return stop, nil
 }
 
-func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
+func buildServerAPIRouter(application serverAPIApplication) (*http.ServeMux, error) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -123,6 +171,27 @@ func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
 		writeServerJSON(w, http.StatusOK, result)
 	})
 
+	mux.HandleFunc("/auth/pin-login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var req serverPinLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.LoginWithPinFrom(req.Username, req.Pin, r.RemoteAddr)
+		if err != nil {
+			writeMappedServerError(w, "Server auth API pin login failed", err)
+			return
+		}
+
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
 	mux.HandleFunc("/auth/session-role", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -245,6 +314,71 @@ func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
 		writeServerJSON(w, http.StatusOK, map[string]bool{"ok": true})
 	})
 
+	mux.HandleFunc("/admin/users/pin", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input app.SetUserPinInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		if err := application.SetUserPin(input); err != nil {
+			writeMappedServerError(w, "Server admin set-user-pin failed", err)
+			return
+		}
+
+		writeServerJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+
+	mux.HandleFunc("/admin/audit/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appAudit.ListAuditLogInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.ListAuditLog(input)
+		if err != nil {
+			writeMappedServerError(w, "Server admin list-audit-log failed", err)
+			return
+		}
+
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("/admin/audit/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appAudit.ListAuditLogInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		content, err := application.ExportAuditLogCSV(input)
+		if err != nil {
+			writeMappedServerError(w, "Server admin export-audit-log failed", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit_log.csv"`)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(content))
+	})
+
 	mux.HandleFunc("/admin/users/delete", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -328,6 +462,26 @@ func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
 		writeServerJSON(w, http.StatusOK, result)
 	})
 
+	mux.HandleFunc("/inventory/items/page", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInventory.ListItemsInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.ListItemsPage(input)
+		if err != nil {
+			writeMappedServerError(w, "Server inventory list items page failed", err)
+			return
+		}
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
 	mux.HandleFunc("/inventory/packaging/create", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -493,6 +647,26 @@ func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
 		writeServerJSON(w, http.StatusOK, result)
 	})
 
+	mux.HandleFunc("/inventory/parties/page", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInventory.ListPartiesInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.ListPartiesPage(input)
+		if err != nil {
+			writeMappedServerError(w, "Server inventory list parties page failed", err)
+			return
+		}
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
 	mux.HandleFunc("/inventory/grns/create", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -533,6 +707,26 @@ func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
 		writeServerJSON(w, http.StatusOK, result)
 	})
 
+	mux.HandleFunc("/inventory/lots/page", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInventory.ListMaterialLotsInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.ListMaterialLotsPage(input)
+		if err != nil {
+			writeMappedServerError(w, "Server inventory list material lots page failed", err)
+			return
+		}
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
 	mux.HandleFunc("/inventory/lots/movements/create", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -573,6 +767,26 @@ func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
 		writeServerJSON(w, http.StatusOK, result)
 	})
 
+	mux.HandleFunc("/inventory/lots/movements/page", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInventory.ListLotStockMovementsInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.ListLotStockMovementsPage(input)
+		if err != nil {
+			writeMappedServerError(w, "Server inventory list lot movements page failed", err)
+			return
+		}
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
 	mux.HandleFunc("/inventory/reconciliation/create", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -613,6 +827,26 @@ func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
 		writeServerJSON(w, http.StatusOK, result)
 	})
 
+	mux.HandleFunc("/inventory/reconciliation/page", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInventory.ListStockAdjustmentsInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.ListStockAdjustmentsPage(input)
+		if err != nil {
+			writeMappedServerError(w, "Server inventory list stock adjustments page failed", err)
+			return
+		}
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
 	mux.HandleFunc("/inventory/reconciliation/balance", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -696,7 +930,187 @@ func buildServerAPIRouter(application serverAPIApplication) *http.ServeMux {
 		writeServerJSON(w, http.StatusOK, result)
 	})
 
-	return mux
+	mux.HandleFunc("/inventory/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInventory.ImportInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.ImportMasterData(input)
+		if err != nil {
+			writeMappedServerError(w, "Server inventory import failed", err)
+			return
+		}
+
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("/exports", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appExport.ExportInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		export, err := application.OpenExport(input)
+		if err != nil {
+			writeMappedServerError(w, "Server export failed", err)
+			return
+		}
+
+		streamServerResponse(w, "Server export stream failed", export.ContentType, export.FileName, export.Write)
+	})
+
+	mux.HandleFunc("/invoices/create", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInvoice.GenerateInvoiceInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.GenerateInvoice(input)
+		if err != nil {
+			writeMappedServerError(w, "Server invoice generate failed", err)
+			return
+		}
+
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("/invoices/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInvoice.ListInvoicesInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.ListInvoices(input)
+		if err != nil {
+			writeMappedServerError(w, "Server invoice list failed", err)
+			return
+		}
+
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("/invoices/get", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInvoice.InvoiceIDInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.GetInvoice(input)
+		if err != nil {
+			writeMappedServerError(w, "Server invoice get failed", err)
+			return
+		}
+
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("/invoices/pdf", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appInvoice.InvoiceIDInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		document, err := application.InvoicePDF(input)
+		if err != nil {
+			writeMappedServerError(w, "Server invoice pdf failed", err)
+			return
+		}
+
+		streamServerResponse(w, "Server invoice pdf stream failed", document.ContentType, document.FileName, func(out io.Writer) error {
+			_, err := out.Write(document.Data)
+			return err
+		})
+	})
+
+	mux.HandleFunc("/labels", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appLabel.PrintLabelsInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		document, err := application.PrintLabels(input)
+		if err != nil {
+			writeMappedServerError(w, "Server label print failed", err)
+			return
+		}
+
+		streamServerResponse(w, "Server label stream failed", document.ContentType, document.FileName, func(out io.Writer) error {
+			_, err := out.Write(document.Data)
+			return err
+		})
+	})
+
+	mux.HandleFunc("/labels/lookup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var input appLabel.LookupInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServerError(w, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+
+		result, err := application.LookupLabel(input)
+		if err != nil {
+			writeMappedServerError(w, "Server label lookup failed", err)
+			return
+		}
+
+		writeServerJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("/events", handleServerEvents(application))
+
+	if err := registerAPIV1Routes(mux, application); err != nil {
+		return nil, err
+	}
+
+	return mux, nil
 }
 
 func writeServerJSON(w http.ResponseWriter, status int, payload interface{}) {
@@ -715,6 +1129,21 @@ func writeServerError(w http.ResponseWriter, status int, message string) {
 	writeServerJSON(w, status, serverErrorResponse{Message: msg})
 }
 
+// streamServerResponse sends a body that is written as it is produced. Once streaming has
+// started the status cannot change, so a failure aborts the connection: the client sees a
+// broken download rather than a complete-looking truncated file.
+func streamServerResponse(w http.ResponseWriter, operation, contentType, filename string, write func(io.Writer) error) {
+	w.Header().Set("Content-Type", contentType)
+	if filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := write(w); err != nil {
+		slog.Error(operation, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
 func writeMappedServerError(w http.ResponseWriter, operation string, err error) {
 	status, msg := mapHTTPStatusFromError(err)
 
@@ -780,6 +1209,42 @@ func mapHTTPStatus(message string) int {
 	}
 }
 
+// resolveServerAPIPort is the port announced to discovering clients.
+func resolveServerAPIPort() int {
+	_, port, err := net.SplitHostPort(resolveServerAPIBindAddr())
+	if err != nil {
+		return defaultServerAPIPort
+	}
+	value, err := strconv.Atoi(port)
+	if err != nil || value <= 0 {
+		return defaultServerAPIPort
+	}
+	return value
+}
+
+// startServerDiscoveryResponder answers client discovery probes with this server's identity,
+// API port and current certificate fingerprint.
+func startServerDiscoveryResponder(dataDir string, certs *network.CertificateManager) (func(), error) {
+	serverID, err := network.LoadOrCreateServerID(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server id: %w", err)
+	}
+	name, err := os.Hostname()
+	if err != nil || strings.TrimSpace(name) == "" {
+		name = "Masala Inventory Server"
+	}
+	apiPort := resolveServerAPIPort()
+	return network.StartDiscoveryResponder(network.DefaultDiscoveryPort, func() network.ServerAnnouncement {
+		return network.ServerAnnouncement{
+			ServerID:    serverID,
+			Name:        name,
+			Version:     Version,
+			APIPort:     apiPort,
+			Fingerprint: certs.Fingerprint(),
+		}
+	})
+}
+
 func resolveServerAPIBindAddr() string {
 	raw := strings.TrimSpace(os.Getenv(envServerAPIBindAddr))
 	if raw == "" {
@@ -2,29 +2,41 @@ package main
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"masala_inventory_managment/internal/app"
+	appAudit "masala_inventory_managment/internal/app/audit"
+	appExport "masala_inventory_managment/internal/app/export"
 	appInventory "masala_inventory_managment/internal/app/inventory"
+	appInvoice "masala_inventory_managment/internal/app/invoice"
+	appLabel "masala_inventory_managment/internal/app/label"
 	appLicenseMode "masala_inventory_managment/internal/app/licensemode"
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
 )
 
 type stubServerAPIApplication struct {
 	loginFn                  func(username, password string) (app.AuthTokenResult, error)
+	loginWithPinFn           func(username, pin, clientAddr string) (app.AuthTokenResult, error)
 	getSessionRoleFn         func(authToken string) (string, error)
 	createUserFn             func(input app.CreateUserInput) error
 	listUsersFn              func(input app.ListUsersInput) ([]app.UserAccountResult, error)
 	updateUserRoleFn         func(input app.UpdateUserRoleInput) error
 	setUserActiveFn          func(input app.SetUserActiveInput) error
 	resetUserPasswordFn      func(input app.ResetUserPasswordInput) error
+	setUserPinFn             func(input app.SetUserPinInput) error
 	deleteUserFn             func(input app.DeleteUserInput) error
 	createItemMasterFn       func(input appInventory.CreateItemInput) (app.ItemMasterResult, error)
 	updateItemMasterFn       func(input appInventory.UpdateItemInput) (app.ItemMasterResult, error)
 	listItemsFn              func(input appInventory.ListItemsInput) ([]app.ItemMasterResult, error)
+	listItemsPageFn          func(input appInventory.ListItemsInput) (app.ItemMasterPageResult, error)
 	createPackagingProfileFn func(input appInventory.CreatePackagingProfileInput) (app.PackagingProfileResult, error)
 	listPackagingProfilesFn  func(input appInventory.ListPackagingProfilesInput) ([]app.PackagingProfileResult, error)
 	createRecipeFn           func(input appInventory.CreateRecipeInput) (app.RecipeResult, error)
@@ -33,16 +45,31 @@ type stubServerAPIApplication struct {
 	createPartyFn            func(input appInventory.CreatePartyInput) (app.PartyResult, error)
 	updatePartyFn            func(input appInventory.UpdatePartyInput) (app.PartyResult, error)
 	listPartiesFn            func(input appInventory.ListPartiesInput) ([]app.PartyResult, error)
+	listPartiesPageFn        func(input appInventory.ListPartiesInput) (app.PartyPageResult, error)
 	listMaterialLotsFn       func(input appInventory.ListMaterialLotsInput) ([]app.MaterialLotResult, error)
+	listMaterialLotsPageFn   func(input appInventory.ListMaterialLotsInput) (app.MaterialLotPageResult, error)
 	recordLotMovementFn      func(input appInventory.RecordLotStockMovementInput) (app.LotStockMovementResult, error)
 	listLotMovementsFn       func(input appInventory.ListLotStockMovementsInput) ([]app.LotStockMovementResult, error)
+	listLotMovementsPageFn   func(input appInventory.ListLotStockMovementsInput) (app.LotStockMovementPageResult, error)
 	createGRNFn              func(input appInventory.CreateGRNInput) (app.GRNResult, error)
 	createConversionRuleFn   func(input appInventory.CreateUnitConversionRuleInput) (app.UnitConversionRuleResult, error)
 	listConversionRulesFn    func(input appInventory.ListUnitConversionRulesInput) ([]app.UnitConversionRuleResult, error)
 	convertQuantityFn        func(input appInventory.ConvertQuantityInput) (app.UnitConversionResult, error)
 	createStockAdjFn         func(input appInventory.CreateStockAdjustmentInput) (app.StockAdjustmentResult, error)
 	listStockAdjFn           func(input appInventory.ListStockAdjustmentsInput) ([]app.StockAdjustmentResult, error)
+	listStockAdjPageFn       func(input appInventory.ListStockAdjustmentsInput) (app.StockAdjustmentPageResult, error)
 	getStockBalanceFn        func(input appInventory.GetItemStockBalanceInput) (float64, error)
+	listAuditLogFn           func(input appAudit.ListAuditLogInput) ([]app.AuditLogEntryResult, error)
+	exportAuditLogFn         func(input appAudit.ListAuditLogInput) (string, error)
+	subscribeEventsFn        func() (<-chan domainEvents.Event, func(), error)
+	importMasterDataFn       func(input appInventory.ImportInput) (appInventory.ImportReport, error)
+	openExportFn             func(input appExport.ExportInput) (*appExport.Export, error)
+	generateInvoiceFn        func(input appInvoice.GenerateInvoiceInput) (*domainInvoice.Invoice, error)
+	getInvoiceFn             func(input appInvoice.InvoiceIDInput) (*domainInvoice.Invoice, error)
+	listInvoicesFn           func(input appInvoice.ListInvoicesInput) ([]domainInvoice.Invoice, error)
+	invoicePDFFn             func(input appInvoice.InvoiceIDInput) (*appInvoice.Document, error)
+	printLabelsFn            func(input appLabel.PrintLabelsInput) (*appLabel.Document, error)
+	lookupLabelFn            func(input appLabel.LookupInput) (*appLabel.LotLookup, error)
 }
 
 func (s stubServerAPIApplication) Login(username, password string) (app.AuthTokenResult, error) {
@@ -52,6 +79,13 @@ func (s stubServerAPIApplication) Login(username, password string) (app.AuthToke
 	return app.AuthTokenResult{}, errors.New("not implemented")
 }
 
+func (s stubServerAPIApplication) LoginWithPinFrom(username, pin, clientAddr string) (app.AuthTokenResult, error) {
+	if s.loginWithPinFn != nil {
+		return s.loginWithPinFn(username, pin, clientAddr)
+	}
+	return app.AuthTokenResult{}, errors.New("not implemented")
+}
+
 func (s stubServerAPIApplication) GetSessionRole(authToken string) (string, error) {
 	if s.getSessionRoleFn != nil {
 		return s.getSessionRoleFn(authToken)
@@ -94,6 +128,13 @@ func (s stubServerAPIApplication) ResetUserPassword(input app.ResetUserPasswordI
 	return errors.New("not implemented")
 }
 
+func (s stubServerAPIApplication) SetUserPin(input app.SetUserPinInput) error {
+	if s.setUserPinFn != nil {
+		return s.setUserPinFn(input)
+	}
+	return errors.New("not implemented")
+}
+
 func (s stubServerAPIApplication) DeleteUser(input app.DeleteUserInput) error {
 	if s.deleteUserFn != nil {
 		return s.deleteUserFn(input)
@@ -122,6 +163,13 @@ func (s stubServerAPIApplication) ListItems(input appInventory.ListItemsInput) (
 	return nil, errors.New("not implemented")
 }
 
+func (s stubServerAPIApplication) ListItemsPage(input appInventory.ListItemsInput) (app.ItemMasterPageResult, error) {
+	if s.listItemsPageFn != nil {
+		return s.listItemsPageFn(input)
+	}
+	return app.ItemMasterPageResult{}, nil
+}
+
 func (s stubServerAPIApplication) CreatePackagingProfile(input appInventory.CreatePackagingProfileInput) (app.PackagingProfileResult, error) {
 	if s.createPackagingProfileFn != nil {
 		return s.createPackagingProfileFn(input)
@@ -178,6 +226,13 @@ func (s stubServerAPIApplication) ListParties(input appInventory.ListPartiesInpu
 	return nil, errors.New("not implemented")
 }
 
+func (s stubServerAPIApplication) ListPartiesPage(input appInventory.ListPartiesInput) (app.PartyPageResult, error) {
+	if s.listPartiesPageFn != nil {
+		return s.listPartiesPageFn(input)
+	}
+	return app.PartyPageResult{}, nil
+}
+
 func (s stubServerAPIApplication) ListMaterialLots(input appInventory.ListMaterialLotsInput) ([]app.MaterialLotResult, error) {
 	if s.listMaterialLotsFn != nil {
 		return s.listMaterialLotsFn(input)
@@ -185,6 +240,13 @@ func (s stubServerAPIApplication) ListMaterialLots(input appInventory.ListMateri
 	return nil, errors.New("not implemented")
 }
 
+func (s stubServerAPIApplication) ListMaterialLotsPage(input appInventory.ListMaterialLotsInput) (app.MaterialLotPageResult, error) {
+	if s.listMaterialLotsPageFn != nil {
+		return s.listMaterialLotsPageFn(input)
+	}
+	return app.MaterialLotPageResult{}, nil
+}
+
 func (s stubServerAPIApplication) RecordLotStockMovement(input appInventory.RecordLotStockMovementInput) (app.LotStockMovementResult, error) {
 	if s.recordLotMovementFn != nil {
 		return s.recordLotMovementFn(input)
@@ -199,6 +261,13 @@ func (s stubServerAPIApplication) ListLotStockMovements(input appInventory.ListL
 	return nil, errors.New("not implemented")
 }
 
+func (s stubServerAPIApplication) ListLotStockMovementsPage(input appInventory.ListLotStockMovementsInput) (app.LotStockMovementPageResult, error) {
+	if s.listLotMovementsPageFn != nil {
+		return s.listLotMovementsPageFn(input)
+	}
+	return app.LotStockMovementPageResult{}, nil
+}
+
 func (s stubServerAPIApplication) CreateGRN(input appInventory.CreateGRNInput) (app.GRNResult, error) {
 	if s.createGRNFn != nil {
 		return s.createGRNFn(input)
@@ -241,6 +310,34 @@ func (s stubServerAPIApplication) ListStockAdjustments(input appInventory.ListSt
 	return nil, errors.New("not implemented")
 }
 
+func (s stubServerAPIApplication) ListStockAdjustmentsPage(input appInventory.ListStockAdjustmentsInput) (app.StockAdjustmentPageResult, error) {
+	if s.listStockAdjPageFn != nil {
+		return s.listStockAdjPageFn(input)
+	}
+	return app.StockAdjustmentPageResult{}, nil
+}
+
+func (s stubServerAPIApplication) ListAuditLog(input appAudit.ListAuditLogInput) ([]app.AuditLogEntryResult, error) {
+	if s.listAuditLogFn != nil {
+		return s.listAuditLogFn(input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) ExportAuditLogCSV(input appAudit.ListAuditLogInput) (string, error) {
+	if s.exportAuditLogFn != nil {
+		return s.exportAuditLogFn(input)
+	}
+	return "", errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) SubscribeEvents() (<-chan domainEvents.Event, func(), error) {
+	if s.subscribeEventsFn != nil {
+		return s.subscribeEventsFn()
+	}
+	return nil, nil, errors.New("not implemented")
+}
+
 func (s stubServerAPIApplication) GetItemStockBalance(input appInventory.GetItemStockBalanceInput) (float64, error) {
 	if s.getStockBalanceFn != nil {
 		return s.getStockBalanceFn(input)
@@ -248,8 +345,64 @@ func (s stubServerAPIApplication) GetItemStockBalance(input appInventory.GetItem
 	return 0, errors.New("not implemented")
 }
 
+func (s stubServerAPIApplication) ImportMasterData(input appInventory.ImportInput) (appInventory.ImportReport, error) {
+	if s.importMasterDataFn != nil {
+		return s.importMasterDataFn(input)
+	}
+	return appInventory.ImportReport{}, errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) OpenExport(input appExport.ExportInput) (*appExport.Export, error) {
+	if s.openExportFn != nil {
+		return s.openExportFn(input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) GenerateInvoice(input appInvoice.GenerateInvoiceInput) (*domainInvoice.Invoice, error) {
+	if s.generateInvoiceFn != nil {
+		return s.generateInvoiceFn(input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) GetInvoice(input appInvoice.InvoiceIDInput) (*domainInvoice.Invoice, error) {
+	if s.getInvoiceFn != nil {
+		return s.getInvoiceFn(input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) ListInvoices(input appInvoice.ListInvoicesInput) ([]domainInvoice.Invoice, error) {
+	if s.listInvoicesFn != nil {
+		return s.listInvoicesFn(input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) InvoicePDF(input appInvoice.InvoiceIDInput) (*appInvoice.Document, error) {
+	if s.invoicePDFFn != nil {
+		return s.invoicePDFFn(input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) PrintLabels(input appLabel.PrintLabelsInput) (*appLabel.Document, error) {
+	if s.printLabelsFn != nil {
+		return s.printLabelsFn(input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (s stubServerAPIApplication) LookupLabel(input appLabel.LookupInput) (*appLabel.LotLookup, error) {
+	if s.lookupLabelFn != nil {
+		return s.lookupLabelFn(input)
+	}
+	return nil, errors.New("not implemented")
+}
+
 func TestServerAPI_ListUsersSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listUsersFn: func(input app.ListUsersInput) ([]app.UserAccountResult, error) {
 			if input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected auth token: %s", input.AuthToken)
@@ -278,7 +431,7 @@ func TestServerAPI_ListUsersSuccess(t *testing.T) {
 }
 
 func TestServerAPI_DeleteUserNotFoundReturnsNotFound(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		deleteUserFn: func(_ app.DeleteUserInput) error {
 			return errors.New("user not found")
 		},
@@ -292,7 +445,7 @@ func TestServerAPI_DeleteUserNotFoundReturnsNotFound(t *testing.T) {
 }
 
 func TestServerAPI_UpdateUserRoleDisabledReturnsForbidden(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		updateUserRoleFn: func(_ app.UpdateUserRoleInput) error {
 			return errors.New("forbidden: role changes are disabled")
 		},
@@ -307,7 +460,7 @@ func TestServerAPI_UpdateUserRoleDisabledReturnsForbidden(t *testing.T) {
 }
 
 func TestServerAPI_SetUserActiveSelfGuardReturnsConflict(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		setUserActiveFn: func(_ app.SetUserActiveInput) error {
 			return errors.New("cannot disable your own account")
 		},
@@ -322,7 +475,7 @@ func TestServerAPI_SetUserActiveSelfGuardReturnsConflict(t *testing.T) {
 }
 
 func TestServerAPI_LoginSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		loginFn: func(username, password string) (app.AuthTokenResult, error) {
 			if username != "admin" || password != "secret" {
 				t.Fatalf("unexpected credentials: %s/%s", username, password)
@@ -350,7 +503,7 @@ func TestServerAPI_LoginSuccess(t *testing.T) {
 }
 
 func TestServerAPI_LoginInvalidCredentialsReturnsUnauthorized(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		loginFn: func(_, _ string) (app.AuthTokenResult, error) {
 			return app.AuthTokenResult{}, errors.New("invalid credentials")
 		},
@@ -363,8 +516,45 @@ func TestServerAPI_LoginInvalidCredentialsReturnsUnauthorized(t *testing.T) {
 	assertErrorStatusAndMessage(t, rec, http.StatusUnauthorized, "invalid credentials")
 }
 
+func TestServerAPI_PinLoginForwardsClientAddress(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		loginWithPinFn: func(username, pin, clientAddr string) (app.AuthTokenResult, error) {
+			if username != "operator" || pin != "4821" {
+				t.Fatalf("unexpected credentials: %s/%s", username, pin)
+			}
+			if clientAddr == "" {
+				t.Fatalf("expected client address to be forwarded")
+			}
+			return app.AuthTokenResult{Token: "pin-token", ExpiresAt: 1893456000}, nil
+		},
+	})
+
+	rec := postJSON(t, router, "/auth/pin-login", map[string]string{
+		"username": "operator",
+		"pin":      "4821",
+	})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServerAPI_PinLoginUntrustedClientReturnsForbidden(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		loginWithPinFn: func(_, _, _ string) (app.AuthTokenResult, error) {
+			return app.AuthTokenResult{}, errors.New("forbidden: pin login is not allowed from this terminal")
+		},
+	})
+
+	rec := postJSON(t, router, "/auth/pin-login", map[string]string{
+		"username": "operator",
+		"pin":      "4821",
+	})
+	assertErrorStatusAndMessage(t, rec, http.StatusForbidden, "forbidden: pin login is not allowed from this terminal")
+}
+
 func TestServerAPI_CreateUserForbiddenReturnsForbidden(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createUserFn: func(_ app.CreateUserInput) error {
 			return errors.New("forbidden: insufficient permissions")
 		},
@@ -380,7 +570,7 @@ func TestServerAPI_CreateUserForbiddenReturnsForbidden(t *testing.T) {
 }
 
 func TestServerAPI_CreateItemValidationReturnsBadRequest(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createItemMasterFn: func(_ appInventory.CreateItemInput) (app.ItemMasterResult, error) {
 			return app.ItemMasterResult{}, &appInventory.ServiceError{
 				Code:    "validation_failed",
@@ -399,7 +589,7 @@ func TestServerAPI_CreateItemValidationReturnsBadRequest(t *testing.T) {
 }
 
 func TestServerAPI_ListItemsUnauthorizedReturnsUnauthorized(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listItemsFn: func(_ appInventory.ListItemsInput) ([]app.ItemMasterResult, error) {
 			return nil, &appInventory.ServiceError{
 				Code:    "unauthorized",
@@ -416,7 +606,7 @@ func TestServerAPI_ListItemsUnauthorizedReturnsUnauthorized(t *testing.T) {
 }
 
 func TestServerAPI_ConvertQuantityMissingRuleReturnsBadRequest(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		convertQuantityFn: func(_ appInventory.ConvertQuantityInput) (app.UnitConversionResult, error) {
 			return app.UnitConversionResult{}, &appInventory.ServiceError{
 				Code:    "validation_failed",
@@ -435,7 +625,7 @@ func TestServerAPI_ConvertQuantityMissingRuleReturnsBadRequest(t *testing.T) {
 }
 
 func TestServerAPI_CreateRecipeSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createRecipeFn: func(input appInventory.CreateRecipeInput) (app.RecipeResult, error) {
 			if input.RecipeCode != "RCP-GM-1" || input.OutputItemID != 20 || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected create recipe input: %+v", input)
@@ -478,7 +668,7 @@ func TestServerAPI_CreateRecipeSuccess(t *testing.T) {
 }
 
 func TestServerAPI_UpdateRecipeConflictReturnsConflict(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		updateRecipeFn: func(_ appInventory.UpdateRecipeInput) (app.RecipeResult, error) {
 			return app.RecipeResult{}, errors.New("Record modified by another user. Reload required.")
 		},
@@ -499,7 +689,7 @@ func TestServerAPI_UpdateRecipeConflictReturnsConflict(t *testing.T) {
 }
 
 func TestServerAPI_UpdateRecipeSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		updateRecipeFn: func(input appInventory.UpdateRecipeInput) (app.RecipeResult, error) {
 			if input.ID != 200 || input.RecipeCode != "RCP-GM-1" || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected update recipe input: %+v", input)
@@ -545,7 +735,7 @@ func TestServerAPI_UpdateRecipeSuccess(t *testing.T) {
 }
 
 func TestServerAPI_ListRecipesUnauthorizedReturnsUnauthorized(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listRecipesFn: func(_ appInventory.ListRecipesInput) ([]app.RecipeResult, error) {
 			return nil, &appInventory.ServiceError{
 				Code:    "unauthorized",
@@ -562,7 +752,7 @@ func TestServerAPI_ListRecipesUnauthorizedReturnsUnauthorized(t *testing.T) {
 }
 
 func TestServerAPI_ListRecipesSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listRecipesFn: func(input appInventory.ListRecipesInput) ([]app.RecipeResult, error) {
 			if input.ActiveOnly != true || input.Search != "RCP" || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected list recipes input: %+v", input)
@@ -604,7 +794,7 @@ func TestServerAPI_ListRecipesSuccess(t *testing.T) {
 }
 
 func TestServerAPI_CreatePartySuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createPartyFn: func(input appInventory.CreatePartyInput) (app.PartyResult, error) {
 			if input.PartyType != "SUPPLIER" || input.Name != "Acme Supplier" || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected create party input: %+v", input)
@@ -644,7 +834,7 @@ func TestServerAPI_CreatePartySuccess(t *testing.T) {
 }
 
 func TestServerAPI_UpdatePartyConflictReturnsConflict(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		updatePartyFn: func(_ appInventory.UpdatePartyInput) (app.PartyResult, error) {
 			return app.PartyResult{}, errors.New("Record modified by another user. Reload required.")
 		},
@@ -662,7 +852,7 @@ func TestServerAPI_UpdatePartyConflictReturnsConflict(t *testing.T) {
 }
 
 func TestServerAPI_UpdatePartySuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		updatePartyFn: func(input appInventory.UpdatePartyInput) (app.PartyResult, error) {
 			if input.ID != 51 || input.PartyType != "SUPPLIER" || input.Name != "Acme Supplier Updated" || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected update party input: %+v", input)
@@ -705,7 +895,7 @@ func TestServerAPI_UpdatePartySuccess(t *testing.T) {
 }
 
 func TestServerAPI_CreatePartyForbiddenReturnsForbidden(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createPartyFn: func(_ appInventory.CreatePartyInput) (app.PartyResult, error) {
 			return app.PartyResult{}, &appInventory.ServiceError{
 				Code:    "forbidden",
@@ -724,7 +914,7 @@ func TestServerAPI_CreatePartyForbiddenReturnsForbidden(t *testing.T) {
 }
 
 func TestServerAPI_ListPartiesUnauthorizedReturnsUnauthorized(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listPartiesFn: func(_ appInventory.ListPartiesInput) ([]app.PartyResult, error) {
 			return nil, &appInventory.ServiceError{
 				Code:    "unauthorized",
@@ -741,7 +931,7 @@ func TestServerAPI_ListPartiesUnauthorizedReturnsUnauthorized(t *testing.T) {
 }
 
 func TestServerAPI_ListPartiesSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listPartiesFn: func(input appInventory.ListPartiesInput) ([]app.PartyResult, error) {
 			if input.ActiveOnly != true || input.PartyType != "SUPPLIER" || input.Search != "Acme" || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected list parties input: %+v", input)
@@ -782,7 +972,7 @@ func TestServerAPI_ListPartiesSuccess(t *testing.T) {
 }
 
 func TestServerAPI_CreateGRNSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createGRNFn: func(input appInventory.CreateGRNInput) (app.GRNResult, error) {
 			if input.GRNNumber != "GRN-3001" || input.AuthToken != "operator-token" || len(input.Lines) != 2 {
 				t.Fatalf("unexpected create grn input: %+v", input)
@@ -827,7 +1017,7 @@ func TestServerAPI_CreateGRNSuccess(t *testing.T) {
 }
 
 func TestServerAPI_CreateGRNValidationReturnsBadRequest(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createGRNFn: func(_ appInventory.CreateGRNInput) (app.GRNResult, error) {
 			return app.GRNResult{}, &appInventory.ServiceError{
 				Code:    "validation_failed",
@@ -846,7 +1036,7 @@ func TestServerAPI_CreateGRNValidationReturnsBadRequest(t *testing.T) {
 }
 
 func TestServerAPI_CreateGRNNegativeQuantityReturnsBadRequest(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createGRNFn: func(_ appInventory.CreateGRNInput) (app.GRNResult, error) {
 			return app.GRNResult{}, &appInventory.ServiceError{
 				Code:    "validation_failed",
@@ -865,7 +1055,7 @@ func TestServerAPI_CreateGRNNegativeQuantityReturnsBadRequest(t *testing.T) {
 }
 
 func TestServerAPI_CreateGRNUnauthorizedReturnsUnauthorized(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createGRNFn: func(_ appInventory.CreateGRNInput) (app.GRNResult, error) {
 			return app.GRNResult{}, &appInventory.ServiceError{
 				Code:    "unauthorized",
@@ -884,7 +1074,7 @@ func TestServerAPI_CreateGRNUnauthorizedReturnsUnauthorized(t *testing.T) {
 }
 
 func TestServerAPI_CreateGRNForbiddenReturnsForbidden(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createGRNFn: func(_ appInventory.CreateGRNInput) (app.GRNResult, error) {
 			return app.GRNResult{}, &appInventory.ServiceError{
 				Code:    "forbidden",
@@ -903,7 +1093,7 @@ func TestServerAPI_CreateGRNForbiddenReturnsForbidden(t *testing.T) {
 }
 
 func TestServerAPI_CreateGRNReadOnlyLicenseReturnsForbidden(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createGRNFn: func(_ appInventory.CreateGRNInput) (app.GRNResult, error) {
 			return app.GRNResult{}, appLicenseMode.ErrReadOnlyMode
 		},
@@ -919,7 +1109,7 @@ func TestServerAPI_CreateGRNReadOnlyLicenseReturnsForbidden(t *testing.T) {
 }
 
 func TestServerAPI_CreateGRNConflictReturnsConflict(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createGRNFn: func(_ appInventory.CreateGRNInput) (app.GRNResult, error) {
 			return app.GRNResult{}, &appInventory.ServiceError{
 				Code:    "conflict",
@@ -938,7 +1128,7 @@ func TestServerAPI_CreateGRNConflictReturnsConflict(t *testing.T) {
 }
 
 func TestServerAPI_ListMaterialLotsSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listMaterialLotsFn: func(input appInventory.ListMaterialLotsInput) ([]app.MaterialLotResult, error) {
 			if input.AuthToken != "operator-token" || input.Search != "LOT-20260227" {
 				t.Fatalf("unexpected list lots input: %+v", input)
@@ -977,7 +1167,7 @@ func TestServerAPI_ListMaterialLotsSuccess(t *testing.T) {
 }
 
 func TestServerAPI_ListMaterialLotsForwardsFilterContract(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listMaterialLotsFn: func(input appInventory.ListMaterialLotsInput) ([]app.MaterialLotResult, error) {
 			if input.AuthToken != "operator-token" {
 				t.Fatalf("expected operator auth token, got %q", input.AuthToken)
@@ -1010,7 +1200,7 @@ func TestServerAPI_ListMaterialLotsForwardsFilterContract(t *testing.T) {
 }
 
 func TestServerAPI_ListMaterialLotsUnauthorizedReturnsUnauthorized(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listMaterialLotsFn: func(_ appInventory.ListMaterialLotsInput) ([]app.MaterialLotResult, error) {
 			return nil, &appInventory.ServiceError{
 				Code:    "unauthorized",
@@ -1026,7 +1216,7 @@ func TestServerAPI_ListMaterialLotsUnauthorizedReturnsUnauthorized(t *testing.T)
 }
 
 func TestServerAPI_ListMaterialLotsForbiddenReturnsForbidden(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listMaterialLotsFn: func(_ appInventory.ListMaterialLotsInput) ([]app.MaterialLotResult, error) {
 			return nil, &appInventory.ServiceError{
 				Code:    "forbidden",
@@ -1042,7 +1232,7 @@ func TestServerAPI_ListMaterialLotsForbiddenReturnsForbidden(t *testing.T) {
 }
 
 func TestServerAPI_ListMaterialLotsValidationReturnsBadRequest(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listMaterialLotsFn: func(_ appInventory.ListMaterialLotsInput) ([]app.MaterialLotResult, error) {
 			return nil, &appInventory.ServiceError{
 				Code:    "validation_failed",
@@ -1057,8 +1247,42 @@ func TestServerAPI_ListMaterialLotsValidationReturnsBadRequest(t *testing.T) {
 	assertErrorStatusAndMessage(t, rec, http.StatusBadRequest, "invalid lot query filter")
 }
 
+func TestServerAPI_ListMaterialLotsPageForwardsPageRequest(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		listMaterialLotsPageFn: func(input appInventory.ListMaterialLotsInput) (app.MaterialLotPageResult, error) {
+			if input.Limit != 25 || input.Cursor != "cursor-1" || input.SortBy != "lot_number" || input.SortOrder != "ASC" {
+				t.Fatalf("unexpected page input: %+v", input)
+			}
+			return app.MaterialLotPageResult{
+				Items:      []app.MaterialLotResult{{ID: 7, LotNumber: "LOT-7"}},
+				NextCursor: "cursor-2",
+				TotalCount: 40,
+			}, nil
+		},
+	})
+
+	rec := postJSON(t, router, "/inventory/lots/page", map[string]interface{}{
+		"limit":      25,
+		"cursor":     "cursor-1",
+		"sort_by":    "lot_number",
+		"sort_order": "ASC",
+		"auth_token": "operator-token",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	var payload app.MaterialLotPageResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload.Items) != 1 || payload.NextCursor != "cursor-2" || payload.TotalCount != 40 {
+		t.Fatalf("unexpected page payload: %+v", payload)
+	}
+}
+
 func TestServerAPI_RecordLotStockMovementSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		recordLotMovementFn: func(input appInventory.RecordLotStockMovementInput) (app.LotStockMovementResult, error) {
 			if input.AuthToken != "operator-token" || input.LotNumber != "LOT-20260227-001" || input.TransactionType != "OUT" {
 				t.Fatalf("unexpected record lot movement input: %+v", input)
@@ -1090,7 +1314,7 @@ func TestServerAPI_RecordLotStockMovementSuccess(t *testing.T) {
 }
 
 func TestServerAPI_RecordLotStockMovementValidationReturnsBadRequest(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		recordLotMovementFn: func(_ appInventory.RecordLotStockMovementInput) (app.LotStockMovementResult, error) {
 			return app.LotStockMovementResult{}, &appInventory.ServiceError{
 				Code:    "validation_failed",
@@ -1109,7 +1333,7 @@ func TestServerAPI_RecordLotStockMovementValidationReturnsBadRequest(t *testing.
 }
 
 func TestServerAPI_ListLotStockMovementsSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listLotMovementsFn: func(input appInventory.ListLotStockMovementsInput) ([]app.LotStockMovementResult, error) {
 			if input.AuthToken != "operator-token" || input.LotNumber != "LOT-20260227-001" {
 				t.Fatalf("unexpected list lot movements input: %+v", input)
@@ -1145,7 +1369,7 @@ func TestServerAPI_ListLotStockMovementsSuccess(t *testing.T) {
 }
 
 func TestServerAPI_CreateUnitConversionRuleSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createConversionRuleFn: func(input appInventory.CreateUnitConversionRuleInput) (app.UnitConversionRuleResult, error) {
 			if input.FromUnit != "GRAM" || input.ToUnit != "KG" || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected create conversion input: %+v", input)
@@ -1185,7 +1409,7 @@ func TestServerAPI_CreateUnitConversionRuleSuccess(t *testing.T) {
 }
 
 func TestServerAPI_ListUnitConversionRulesSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		listConversionRulesFn: func(input appInventory.ListUnitConversionRulesInput) ([]app.UnitConversionRuleResult, error) {
 			if input.FromUnit != "GRAM" || input.ToUnit != "KG" || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected list conversion input: %+v", input)
@@ -1225,7 +1449,7 @@ func TestServerAPI_ListUnitConversionRulesSuccess(t *testing.T) {
 }
 
 func TestServerAPI_ConvertQuantitySuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		convertQuantityFn: func(input appInventory.ConvertQuantityInput) (app.UnitConversionResult, error) {
 			if input.Quantity != 500 || input.SourceUnit != "GRAM" || input.TargetUnit != "KG" || input.AuthToken != "admin-token" {
 				t.Fatalf("unexpected convert quantity input: %+v", input)
@@ -1260,7 +1484,7 @@ func TestServerAPI_ConvertQuantitySuccess(t *testing.T) {
 }
 
 func TestServerAPI_CreateStockAdjustmentSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createStockAdjFn: func(input appInventory.CreateStockAdjustmentInput) (app.StockAdjustmentResult, error) {
 			if input.AuthToken != "operator-token" || input.ItemID != 11 || input.QtyDelta != -5 || input.ReasonCode != "Spoilage" {
 				t.Fatalf("unexpected create stock adjustment input: %+v", input)
@@ -1296,7 +1520,7 @@ func TestServerAPI_CreateStockAdjustmentSuccess(t *testing.T) {
 }
 
 func TestServerAPI_CreateStockAdjustmentValidationError(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createStockAdjFn: func(input appInventory.CreateStockAdjustmentInput) (app.StockAdjustmentResult, error) {
 			return app.StockAdjustmentResult{}, &appInventory.ServiceError{
 				Code:    "validation_failed",
@@ -1315,7 +1539,7 @@ func TestServerAPI_CreateStockAdjustmentValidationError(t *testing.T) {
 }
 
 func TestServerAPI_CreateStockAdjustmentForbidden(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		createStockAdjFn: func(input appInventory.CreateStockAdjustmentInput) (app.StockAdjustmentResult, error) {
 			return app.StockAdjustmentResult{}, &appInventory.ServiceError{
 				Code:    "forbidden",
@@ -1333,7 +1557,7 @@ func TestServerAPI_CreateStockAdjustmentForbidden(t *testing.T) {
 }
 
 func TestServerAPI_GetItemStockBalanceSuccess(t *testing.T) {
-	router := buildServerAPIRouter(stubServerAPIApplication{
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
 		getStockBalanceFn: func(input appInventory.GetItemStockBalanceInput) (float64, error) {
 			if input.AuthToken != "operator-token" || input.ItemID != 11 {
 				t.Fatalf("unexpected get stock balance input: %+v", input)
@@ -1360,6 +1584,166 @@ func TestServerAPI_GetItemStockBalanceSuccess(t *testing.T) {
 	}
 }
 
+func TestServerAPI_ImportMasterDataReturnsDryRunReport(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		importMasterDataFn: func(input appInventory.ImportInput) (appInventory.ImportReport, error) {
+			if input.AuthToken != "admin-token" || input.Kind != "items" || input.Commit {
+				t.Fatalf("unexpected import input: %+v", input)
+			}
+			if string(input.Content) != "sku,name\n" {
+				t.Fatalf("expected base64 content to be decoded, got %q", input.Content)
+			}
+			return appInventory.ImportReport{
+				Kind:      "items",
+				TotalRows: 2,
+				ValidRows: 1,
+				Errors:    []appInventory.ImportRowError{{Row: 3, Field: "item_type", Message: "item type is required"}},
+			}, nil
+		},
+	})
+
+	rec := postJSON(t, router, "/inventory/import", map[string]interface{}{
+		"auth_token": "admin-token",
+		"kind":       "items",
+		"file_name":  "items.csv",
+		"content":    base64.StdEncoding.EncodeToString([]byte("sku,name\n")),
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	var report appInventory.ImportReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if report.Committed || len(report.Errors) != 1 || report.Errors[0].Row != 3 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestServerAPI_ImportMasterDataForbidden(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		importMasterDataFn: func(input appInventory.ImportInput) (appInventory.ImportReport, error) {
+			return appInventory.ImportReport{}, &appInventory.ServiceError{Code: "forbidden", Message: "role is not allowed to modify master data"}
+		},
+	})
+
+	rec := postJSON(t, router, "/inventory/import", map[string]interface{}{"auth_token": "operator-token", "kind": "parties"})
+	assertErrorStatusAndMessage(t, rec, http.StatusForbidden, "role is not allowed to modify master data")
+}
+
+func TestServerAPI_ExportReportsErrorsBeforeStreaming(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		openExportFn: func(input appExport.ExportInput) (*appExport.Export, error) {
+			if input.AuthToken != "operator-token" || input.Dataset != "audit_log" || input.Format != "pdf" {
+				t.Fatalf("unexpected export input: %+v", input)
+			}
+			return nil, errors.New("forbidden: admin role required")
+		},
+	})
+
+	rec := postJSON(t, router, "/exports", map[string]interface{}{"auth_token": "operator-token", "dataset": "audit_log", "format": "pdf"})
+	assertErrorStatusAndMessage(t, rec, http.StatusForbidden, "forbidden: admin role required")
+}
+
+func TestServerAPI_GenerateInvoiceConflictForInvoicedDispatch(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		generateInvoiceFn: func(input appInvoice.GenerateInvoiceInput) (*domainInvoice.Invoice, error) {
+			if input.AuthToken != "operator-token" || input.DispatchReference != "DSP-1" || len(input.Lines) != 1 {
+				t.Fatalf("unexpected invoice input: %+v", input)
+			}
+			return nil, &appInventory.ServiceError{Code: "conflict", Message: "dispatch DSP-1 has already been invoiced as INV/2026-27/0001"}
+		},
+	})
+
+	rec := postJSON(t, router, "/invoices/create", map[string]interface{}{
+		"auth_token": "operator-token", "dispatch_reference": "DSP-1",
+		"lines": []map[string]interface{}{{"item_id": 1, "unit_price": 420, "hsn_code": "0909", "gst_rate": 5}},
+	})
+	assertErrorStatusAndMessage(t, rec, http.StatusConflict, "dispatch DSP-1 has already been invoiced as INV/2026-27/0001")
+}
+
+func TestServerAPI_InvoicePDFStreamsStoredDocument(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		invoicePDFFn: func(input appInvoice.InvoiceIDInput) (*appInvoice.Document, error) {
+			if input.ID != 7 {
+				return nil, domainInvoice.ErrInvoiceNotFound
+			}
+			return &appInvoice.Document{FileName: "INV-2026-27-0007.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")}, nil
+		},
+	})
+
+	rec := postJSON(t, router, "/invoices/pdf", map[string]interface{}{"auth_token": "operator-token", "id": 7})
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" || rec.Body.String() != "%PDF-1.4" {
+		t.Fatalf("unexpected response %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+	if rec.Header().Get("Content-Disposition") != `attachment; filename="INV-2026-27-0007.pdf"` {
+		t.Fatalf("unexpected content disposition %q", rec.Header().Get("Content-Disposition"))
+	}
+
+	rec = postJSON(t, router, "/invoices/pdf", map[string]interface{}{"auth_token": "operator-token", "id": 8})
+	assertErrorStatusAndMessage(t, rec, http.StatusNotFound, "invoice not found")
+}
+
+func TestServerAPI_PrintLabelsStreamsRenderedLabels(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		printLabelsFn: func(input appLabel.PrintLabelsInput) (*appLabel.Document, error) {
+			if input.AuthToken != "operator-token" || input.Symbology != "qr" || len(input.Labels) != 1 || input.Labels[0].Copies != 2 {
+				t.Fatalf("unexpected label input: %+v", input)
+			}
+			return &appLabel.Document{FileName: "LOT-20260101-001-label.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")}, nil
+		},
+	})
+
+	rec := postJSON(t, router, "/labels", map[string]interface{}{
+		"auth_token": "operator-token", "symbology": "qr",
+		"labels": []map[string]interface{}{{"lot_number": "LOT-20260101-001", "copies": 2}},
+	})
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" || rec.Body.String() != "%PDF-1.4" {
+		t.Fatalf("unexpected response %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+func TestServerAPI_LookupLabelUnknownLot(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		lookupLabelFn: func(input appLabel.LookupInput) (*appLabel.LotLookup, error) {
+			return nil, domainInventory.ErrLotNotFound
+		},
+	})
+
+	rec := postJSON(t, router, "/labels/lookup", map[string]interface{}{"auth_token": "operator-token", "code": "LOT-19990101-001"})
+	assertErrorStatusAndMessage(t, rec, http.StatusNotFound, "lot not found")
+}
+
+func TestStreamServerResponse_AbortsWhenWritingFails(t *testing.T) {
+	rec := httptest.NewRecorder()
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Fatalf("expected the handler to abort the connection, got %v", recovered)
+		}
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Disposition") != `attachment; filename="items.csv"` {
+			t.Fatalf("expected headers to be sent before streaming, got %d %v", rec.Code, rec.Header())
+		}
+		if rec.Body.String() != "SKU\n" {
+			t.Fatalf("expected the rows written before the failure, got %q", rec.Body.String())
+		}
+	}()
+
+	streamServerResponse(rec, "test stream", "text/csv", "items.csv", func(w io.Writer) error {
+		_, _ = io.WriteString(w, "SKU\n")
+		return errors.New("database is locked")
+	})
+}
+
+func newTestServerAPIRouter(t *testing.T, application serverAPIApplication) *http.ServeMux {
+	t.Helper()
+	mux, err := buildServerAPIRouter(application)
+	if err != nil {
+		t.Fatalf("buildServerAPIRouter failed: %v", err)
+	}
+	return mux
+}
+
 func postJSON(t *testing.T, handler http.Handler, path string, payload interface{}) *httptest.ResponseRecorder {
 	t.Helper()
 	body, err := json.Marshal(payload)
@@ -1388,3 +1772,58 @@ func assertErrorStatusAndMessage(t *testing.T, rec *httptest.ResponseRecorder, e
 		t.Fatalf("expected message %q, got %q", expectedMessage, payload.Message)
 	}
 }
+
+func TestServerAPI_ListAuditLogForwardsFilters(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		listAuditLogFn: func(input appAudit.ListAuditLogInput) ([]app.AuditLogEntryResult, error) {
+			if input.AuthToken != "admin-token" || input.EntityType != "item" || input.Actor != "operator" {
+				t.Fatalf("unexpected audit filter: %#v", input)
+			}
+			return []app.AuditLogEntryResult{{ID: 7, Actor: "operator", Action: "UPDATE", EntityType: "item", EntityID: "3"}}, nil
+		},
+	})
+
+	rec := postJSON(t, router, "/admin/audit/list", map[string]string{
+		"auth_token":  "admin-token",
+		"entity_type": "item",
+		"actor":       "operator",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	var payload []app.AuditLogEntryResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0].ID != 7 {
+		t.Fatalf("unexpected audit payload: %#v", payload)
+	}
+}
+
+func TestServerAPI_ExportAuditLogReturnsCSV(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		exportAuditLogFn: func(_ appAudit.ListAuditLogInput) (string, error) {
+			return "id,created_at\n1,2026-01-01T00:00:00Z\n", nil
+		},
+	})
+
+	rec := postJSON(t, router, "/admin/audit/export", map[string]string{"auth_token": "admin-token"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Fatalf("expected csv content type, got %q", got)
+	}
+}
+
+func TestServerAPI_ListAuditLogForbiddenReturnsForbidden(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		listAuditLogFn: func(_ appAudit.ListAuditLogInput) ([]app.AuditLogEntryResult, error) {
+			return nil, errors.New("forbidden: insufficient permissions")
+		},
+	})
+
+	rec := postJSON(t, router, "/admin/audit/list", map[string]string{"auth_token": "operator-token"})
+	assertErrorStatusAndMessage(t, rec, http.StatusForbidden, "forbidden: insufficient permissions")
+}
@@ -0,0 +1,1377 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"masala_inventory_managment/internal/app"
+	appAudit "masala_inventory_managment/internal/app/audit"
+	appExport "masala_inventory_managment/internal/app/export"
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	appInvoice "masala_inventory_managment/internal/app/invoice"
+	appLabel "masala_inventory_managment/internal/app/label"
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiV1Prefix = "/api/v1"
+
+// apiV1ErrorBody is the error envelope returned by every /api/v1 route.
+type apiV1ErrorBody struct {
+	Code    string                    `json:"code"`
+	Message string                    `json:"message"`
+	Fields  []appInventory.FieldError `json:"fields,omitempty"`
+}
+
+type apiV1ErrorEnvelope struct {
+	Error apiV1ErrorBody `json:"error"`
+}
+
+// apiV1RequestError marks malformed path, query or body input.
+type apiV1RequestError struct {
+	message string
+}
+
+func (e *apiV1RequestError) Error() string {
+	return e.message
+}
+
+func newAPIV1RequestError(format string, args ...interface{}) error {
+	return &apiV1RequestError{message: fmt.Sprintf(format, args...)}
+}
+
+// apiV1Param documents a path or query parameter.
+type apiV1Param struct {
+	name        string
+	kind        string // string, integer, number, boolean
+	description string
+}
+
+// apiV1RawResponse is returned by handlers that send a non-JSON body. A response with a
+// stream writes the body as it is produced instead of sending body.
+type apiV1RawResponse struct {
+	contentType string
+	filename    string
+	body        []byte
+	stream      func(io.Writer) error
+}
+
+type apiV1StockBalance struct {
+	ItemID  int64   `json:"item_id"`
+	Balance float64 `json:"balance"`
+}
+
+type apiV1SessionResult struct {
+	Role string `json:"role"`
+}
+
+type apiV1SetUserActiveBody struct {
+	IsActive bool `json:"is_active"`
+}
+
+type apiV1ResetPasswordBody struct {
+	NewPassword string `json:"new_password"`
+}
+
+type apiV1SetPinBody struct {
+	Pin string `json:"pin"`
+}
+
+type apiV1UpdateRoleBody struct {
+	Role string `json:"role"`
+}
+
+// apiV1Route is a single resource operation. The same table drives routing and the OpenAPI document.
+type apiV1Route struct {
+	method      string
+	path        string
+	operationID string
+	summary     string
+	tag         string
+	public      bool
+	status      int
+	query       []apiV1Param
+	request     interface{}
+	response    interface{}
+	rawTypes    []string
+	handler     func(r *http.Request, token string) (interface{}, error)
+}
+
+func apiV1Routes(application serverAPIApplication) []apiV1Route {
+	return []apiV1Route{
+		{
+			method: http.MethodPost, path: "/auth/login", operationID: "login", tag: "auth", public: true,
+			summary: "Exchange username and password for a bearer token",
+			request: serverLoginRequest{}, response: app.AuthTokenResult{},
+			handler: func(r *http.Request, _ string) (interface{}, error) {
+				var req serverLoginRequest
+				if err := decodeAPIV1Body(r, &req); err != nil {
+					return nil, err
+				}
+				return application.Login(req.Username, req.Password)
+			},
+		},
+		{
+			method: http.MethodPost, path: "/auth/pin-login", operationID: "pinLogin", tag: "auth", public: true,
+			summary: "Operator PIN login from a trusted terminal",
+			request: serverPinLoginRequest{}, response: app.AuthTokenResult{},
+			handler: func(r *http.Request, _ string) (interface{}, error) {
+				var req serverPinLoginRequest
+				if err := decodeAPIV1Body(r, &req); err != nil {
+					return nil, err
+				}
+				return application.LoginWithPinFrom(req.Username, req.Pin, r.RemoteAddr)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/session", operationID: "getSession", tag: "auth",
+			summary:  "Return the role of the current bearer token",
+			response: apiV1SessionResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				role, err := application.GetSessionRole(token)
+				if err != nil {
+					return nil, err
+				}
+				return apiV1SessionResult{Role: role}, nil
+			},
+		},
+		{
+			method: http.MethodGet, path: "/users", operationID: "listUsers", tag: "users",
+			summary:  "List user accounts",
+			response: []app.UserAccountResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				return application.ListUsers(app.ListUsersInput{AuthToken: token})
+			},
+		},
+		{
+			method: http.MethodPost, path: "/users", operationID: "createUser", tag: "users",
+			summary: "Create a user account",
+			request: app.CreateUserInput{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input app.CreateUserInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return nil, application.CreateUser(input)
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/users/{username}", operationID: "deleteUser", tag: "users",
+			summary: "Delete a user account",
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				return nil, application.DeleteUser(app.DeleteUserInput{AuthToken: token, Username: r.PathValue("username")})
+			},
+		},
+		{
+			method: http.MethodPut, path: "/users/{username}/active", operationID: "setUserActive", tag: "users",
+			summary: "Enable or disable a user account",
+			request: apiV1SetUserActiveBody{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var body apiV1SetUserActiveBody
+				if err := decodeAPIV1Body(r, &body); err != nil {
+					return nil, err
+				}
+				return nil, application.SetUserActive(app.SetUserActiveInput{AuthToken: token, Username: r.PathValue("username"), IsActive: body.IsActive})
+			},
+		},
+		{
+			method: http.MethodPut, path: "/users/{username}/role", operationID: "updateUserRole", tag: "users",
+			summary: "Change the role of a user account",
+			request: apiV1UpdateRoleBody{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var body apiV1UpdateRoleBody
+				if err := decodeAPIV1Body(r, &body); err != nil {
+					return nil, err
+				}
+				return nil, application.UpdateUserRole(app.UpdateUserRoleInput{AuthToken: token, Username: r.PathValue("username"), Role: body.Role})
+			},
+		},
+		{
+			method: http.MethodPut, path: "/users/{username}/password", operationID: "resetUserPassword", tag: "users",
+			summary: "Reset the password of a user account",
+			request: apiV1ResetPasswordBody{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var body apiV1ResetPasswordBody
+				if err := decodeAPIV1Body(r, &body); err != nil {
+					return nil, err
+				}
+				return nil, application.ResetUserPassword(app.ResetUserPasswordInput{AuthToken: token, Username: r.PathValue("username"), NewPassword: body.NewPassword})
+			},
+		},
+		{
+			method: http.MethodPut, path: "/users/{username}/pin", operationID: "setUserPin", tag: "users",
+			summary: "Set or clear (empty pin) an operator PIN",
+			request: apiV1SetPinBody{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var body apiV1SetPinBody
+				if err := decodeAPIV1Body(r, &body); err != nil {
+					return nil, err
+				}
+				return nil, application.SetUserPin(app.SetUserPinInput{AuthToken: token, Username: r.PathValue("username"), Pin: body.Pin})
+			},
+		},
+		{
+			method: http.MethodGet, path: "/items", operationID: "listItems", tag: "items",
+			summary: "List item master records",
+			query: append([]apiV1Param{
+				{name: "active_only", kind: "boolean"},
+				{name: "item_type", kind: "string", description: "RAW, BULK_POWDER, PACKING_MATERIAL or FINISHED_GOOD"},
+				{name: "search", kind: "string"},
+			}, apiV1PageParams("name, sku, created_at, updated_at")...),
+			response: app.ItemMasterPageResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				activeOnly, err := queryBool(q, "active_only")
+				if err != nil {
+					return nil, err
+				}
+				page, err := parseAPIV1Page(q)
+				if err != nil {
+					return nil, err
+				}
+				return application.ListItemsPage(appInventory.ListItemsInput{
+					ActiveOnly: activeOnly,
+					ItemType:   q.Get("item_type"),
+					Search:     q.Get("search"),
+					Limit:      page.limit,
+					Cursor:     page.cursor,
+					SortBy:     page.sortBy,
+					SortOrder:  page.sortOrder,
+					AuthToken:  token,
+				})
+			},
+		},
+		{
+			method: http.MethodPost, path: "/items", operationID: "createItem", tag: "items", status: http.StatusCreated,
+			summary: "Create an item master record",
+			request: appInventory.CreateItemInput{}, response: app.ItemMasterResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.CreateItemInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.CreateItemMaster(input)
+			},
+		},
+		{
+			method: http.MethodPut, path: "/items/{id}", operationID: "updateItem", tag: "items",
+			summary: "Update an item master record (optimistic concurrency via updated_at)",
+			request: appInventory.UpdateItemInput{}, response: app.ItemMasterResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				id, err := pathID(r, "id")
+				if err != nil {
+					return nil, err
+				}
+				var input appInventory.UpdateItemInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.ID = id
+				input.AuthToken = token
+				return application.UpdateItemMaster(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/items/{id}/stock-balance", operationID: "getItemStockBalance", tag: "stock",
+			summary:  "Current stock balance of an item",
+			response: apiV1StockBalance{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				id, err := pathID(r, "id")
+				if err != nil {
+					return nil, err
+				}
+				balance, err := application.GetItemStockBalance(appInventory.GetItemStockBalanceInput{ItemID: id, AuthToken: token})
+				if err != nil {
+					return nil, err
+				}
+				return apiV1StockBalance{ItemID: id, Balance: balance}, nil
+			},
+		},
+		{
+			method: http.MethodGet, path: "/packaging-profiles", operationID: "listPackagingProfiles", tag: "packaging",
+			summary: "List packaging profiles",
+			query: []apiV1Param{
+				{name: "active_only", kind: "boolean"},
+				{name: "pack_mode", kind: "string"},
+				{name: "search", kind: "string"},
+			},
+			response: []app.PackagingProfileResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				activeOnly, err := queryBool(q, "active_only")
+				if err != nil {
+					return nil, err
+				}
+				return application.ListPackagingProfiles(appInventory.ListPackagingProfilesInput{
+					ActiveOnly: activeOnly,
+					PackMode:   q.Get("pack_mode"),
+					Search:     q.Get("search"),
+					AuthToken:  token,
+				})
+			},
+		},
+		{
+			method: http.MethodPost, path: "/packaging-profiles", operationID: "createPackagingProfile", tag: "packaging", status: http.StatusCreated,
+			summary: "Create a packaging profile",
+			request: appInventory.CreatePackagingProfileInput{}, response: app.PackagingProfileResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.CreatePackagingProfileInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.CreatePackagingProfile(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/recipes", operationID: "listRecipes", tag: "recipes",
+			summary: "List recipes",
+			query: []apiV1Param{
+				{name: "active_only", kind: "boolean"},
+				{name: "output_item_id", kind: "integer"},
+				{name: "search", kind: "string"},
+			},
+			response: []app.RecipeResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				activeOnly, err := queryBool(q, "active_only")
+				if err != nil {
+					return nil, err
+				}
+				outputItemID, err := queryInt64Ptr(q, "output_item_id")
+				if err != nil {
+					return nil, err
+				}
+				return application.ListRecipes(appInventory.ListRecipesInput{
+					ActiveOnly:   activeOnly,
+					OutputItemID: outputItemID,
+					Search:       q.Get("search"),
+					AuthToken:    token,
+				})
+			},
+		},
+		{
+			method: http.MethodPost, path: "/recipes", operationID: "createRecipe", tag: "recipes", status: http.StatusCreated,
+			summary: "Create a recipe",
+			request: appInventory.CreateRecipeInput{}, response: app.RecipeResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.CreateRecipeInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.CreateRecipe(input)
+			},
+		},
+		{
+			method: http.MethodPut, path: "/recipes/{id}", operationID: "updateRecipe", tag: "recipes",
+			summary: "Update a recipe (optimistic concurrency via updated_at)",
+			request: appInventory.UpdateRecipeInput{}, response: app.RecipeResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				id, err := pathID(r, "id")
+				if err != nil {
+					return nil, err
+				}
+				var input appInventory.UpdateRecipeInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.ID = id
+				input.AuthToken = token
+				return application.UpdateRecipe(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/parties", operationID: "listParties", tag: "parties",
+			summary: "List suppliers and customers",
+			query: append([]apiV1Param{
+				{name: "active_only", kind: "boolean"},
+				{name: "party_type", kind: "string", description: "SUPPLIER or CUSTOMER"},
+				{name: "search", kind: "string"},
+			}, apiV1PageParams("name, created_at, updated_at")...),
+			response: app.PartyPageResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				activeOnly, err := queryBool(q, "active_only")
+				if err != nil {
+					return nil, err
+				}
+				page, err := parseAPIV1Page(q)
+				if err != nil {
+					return nil, err
+				}
+				return application.ListPartiesPage(appInventory.ListPartiesInput{
+					ActiveOnly: activeOnly,
+					PartyType:  q.Get("party_type"),
+					Search:     q.Get("search"),
+					Limit:      page.limit,
+					Cursor:     page.cursor,
+					SortBy:     page.sortBy,
+					SortOrder:  page.sortOrder,
+					AuthToken:  token,
+				})
+			},
+		},
+		{
+			method: http.MethodPost, path: "/parties", operationID: "createParty", tag: "parties", status: http.StatusCreated,
+			summary: "Create a supplier or customer",
+			request: appInventory.CreatePartyInput{}, response: app.PartyResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.CreatePartyInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.CreateParty(input)
+			},
+		},
+		{
+			method: http.MethodPut, path: "/parties/{id}", operationID: "updateParty", tag: "parties",
+			summary: "Update a supplier or customer (optimistic concurrency via updated_at)",
+			request: appInventory.UpdatePartyInput{}, response: app.PartyResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				id, err := pathID(r, "id")
+				if err != nil {
+					return nil, err
+				}
+				var input appInventory.UpdatePartyInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.ID = id
+				input.AuthToken = token
+				return application.UpdateParty(input)
+			},
+		},
+		{
+			method: http.MethodPost, path: "/grns", operationID: "createGRN", tag: "procurement", status: http.StatusCreated,
+			summary: "Record a goods received note and its inbound lots",
+			request: appInventory.CreateGRNInput{}, response: app.GRNResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.CreateGRNInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.CreateGRN(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/lots", operationID: "listMaterialLots", tag: "stock",
+			summary: "List material lots",
+			query: append([]apiV1Param{
+				{name: "item_id", kind: "integer"},
+				{name: "supplier", kind: "string"},
+				{name: "lot_number", kind: "string"},
+				{name: "grn_number", kind: "string"},
+				{name: "active_only", kind: "boolean"},
+				{name: "search", kind: "string"},
+			}, apiV1PageParams("created_at, lot_number, grn_number, quantity_received")...),
+			response: app.MaterialLotPageResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				itemID, err := queryInt64Ptr(q, "item_id")
+				if err != nil {
+					return nil, err
+				}
+				activeOnly, err := queryBool(q, "active_only")
+				if err != nil {
+					return nil, err
+				}
+				page, err := parseAPIV1Page(q)
+				if err != nil {
+					return nil, err
+				}
+				return application.ListMaterialLotsPage(appInventory.ListMaterialLotsInput{
+					ItemID:     itemID,
+					Supplier:   q.Get("supplier"),
+					LotNumber:  q.Get("lot_number"),
+					GRNNumber:  q.Get("grn_number"),
+					ActiveOnly: activeOnly,
+					Search:     q.Get("search"),
+					Limit:      page.limit,
+					Cursor:     page.cursor,
+					SortBy:     page.sortBy,
+					SortOrder:  page.sortOrder,
+					AuthToken:  token,
+				})
+			},
+		},
+		{
+			method: http.MethodGet, path: "/lots/{lot_number}/movements", operationID: "listLotStockMovements", tag: "stock",
+			summary:  "List stock ledger movements for a lot",
+			query:    apiV1PageParams("created_at, quantity"),
+			response: app.LotStockMovementPageResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				page, err := parseAPIV1Page(r.URL.Query())
+				if err != nil {
+					return nil, err
+				}
+				return application.ListLotStockMovementsPage(appInventory.ListLotStockMovementsInput{
+					LotNumber: r.PathValue("lot_number"),
+					Limit:     page.limit,
+					Cursor:    page.cursor,
+					SortBy:    page.sortBy,
+					SortOrder: page.sortOrder,
+					AuthToken: token,
+				})
+			},
+		},
+		{
+			method: http.MethodPost, path: "/lots/{lot_number}/movements", operationID: "recordLotStockMovement", tag: "stock", status: http.StatusCreated,
+			summary: "Record a stock movement against a lot",
+			request: appInventory.RecordLotStockMovementInput{}, response: app.LotStockMovementResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.RecordLotStockMovementInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.LotNumber = r.PathValue("lot_number")
+				input.AuthToken = token
+				return application.RecordLotStockMovement(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/stock-adjustments", operationID: "listStockAdjustments", tag: "stock",
+			summary:  "List stock adjustments for an item",
+			query:    append([]apiV1Param{{name: "item_id", kind: "integer"}}, apiV1PageParams("created_at, qty_delta")...),
+			response: app.StockAdjustmentPageResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				itemID, err := queryInt64Ptr(q, "item_id")
+				if err != nil {
+					return nil, err
+				}
+				page, err := parseAPIV1Page(q)
+				if err != nil {
+					return nil, err
+				}
+				input := appInventory.ListStockAdjustmentsInput{
+					Limit:     page.limit,
+					Cursor:    page.cursor,
+					SortBy:    page.sortBy,
+					SortOrder: page.sortOrder,
+					AuthToken: token,
+				}
+				if itemID != nil {
+					input.ItemID = *itemID
+				}
+				return application.ListStockAdjustmentsPage(input)
+			},
+		},
+		{
+			method: http.MethodPost, path: "/stock-adjustments", operationID: "createStockAdjustment", tag: "stock", status: http.StatusCreated,
+			summary: "Record a stock adjustment",
+			request: appInventory.CreateStockAdjustmentInput{}, response: app.StockAdjustmentResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.CreateStockAdjustmentInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.CreateStockAdjustment(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/unit-conversion-rules", operationID: "listUnitConversionRules", tag: "conversions",
+			summary: "List unit conversion rules",
+			query: []apiV1Param{
+				{name: "item_id", kind: "integer"},
+				{name: "from_unit", kind: "string"},
+				{name: "to_unit", kind: "string"},
+				{name: "active_only", kind: "boolean"},
+			},
+			response: []app.UnitConversionRuleResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				itemID, err := queryInt64Ptr(q, "item_id")
+				if err != nil {
+					return nil, err
+				}
+				activeOnly, err := queryBool(q, "active_only")
+				if err != nil {
+					return nil, err
+				}
+				return application.ListUnitConversionRules(appInventory.ListUnitConversionRulesInput{
+					ItemID:     itemID,
+					FromUnit:   q.Get("from_unit"),
+					ToUnit:     q.Get("to_unit"),
+					ActiveOnly: activeOnly,
+					AuthToken:  token,
+				})
+			},
+		},
+		{
+			method: http.MethodPost, path: "/unit-conversion-rules", operationID: "createUnitConversionRule", tag: "conversions", status: http.StatusCreated,
+			summary: "Create a unit conversion rule",
+			request: appInventory.CreateUnitConversionRuleInput{}, response: app.UnitConversionRuleResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.CreateUnitConversionRuleInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.CreateUnitConversionRule(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/unit-conversions", operationID: "convertQuantity", tag: "conversions",
+			summary: "Convert a quantity between units",
+			query: []apiV1Param{
+				{name: "quantity", kind: "number"},
+				{name: "source_unit", kind: "string"},
+				{name: "target_unit", kind: "string"},
+				{name: "item_id", kind: "integer"},
+			},
+			response: app.UnitConversionResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				quantity, err := queryFloat(q, "quantity")
+				if err != nil {
+					return nil, err
+				}
+				itemID, err := queryInt64Ptr(q, "item_id")
+				if err != nil {
+					return nil, err
+				}
+				return application.ConvertQuantity(appInventory.ConvertQuantityInput{
+					ItemID:     itemID,
+					Quantity:   quantity,
+					SourceUnit: q.Get("source_unit"),
+					TargetUnit: q.Get("target_unit"),
+					AuthToken:  token,
+				})
+			},
+		},
+		{
+			method: http.MethodPost, path: "/imports", operationID: "importMasterData", tag: "imports",
+			summary: "Validate an items, parties or opening stock CSV/XLSX file, and commit it when every row is valid",
+			request: appInventory.ImportInput{}, response: appInventory.ImportReport{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInventory.ImportInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.ImportMasterData(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/audit-log", operationID: "listAuditLog", tag: "audit",
+			summary:  "List audit log entries, newest first",
+			query:    auditLogQueryParams(),
+			response: []app.AuditLogEntryResult{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				input, err := auditLogInputFromQuery(r.URL.Query(), token)
+				if err != nil {
+					return nil, err
+				}
+				return application.ListAuditLog(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/audit-log/export", operationID: "exportAuditLog", tag: "audit",
+			summary:  "Export audit log entries as CSV",
+			query:    auditLogQueryParams(),
+			rawTypes: []string{"text/csv"},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				input, err := auditLogInputFromQuery(r.URL.Query(), token)
+				if err != nil {
+					return nil, err
+				}
+				content, err := application.ExportAuditLogCSV(input)
+				if err != nil {
+					return nil, err
+				}
+				return apiV1RawResponse{contentType: "text/csv; charset=utf-8", filename: "audit_log.csv", body: []byte(content)}, nil
+			},
+		},
+		{
+			method: http.MethodGet, path: "/exports/{dataset}", operationID: "exportDataset", tag: "exports",
+			summary:  "Stream items, parties, lots, lot_movements, stock_adjustments, stock_summary, stock_valuation or audit_log as CSV, XLSX or PDF",
+			query:    exportQueryParams(),
+			rawTypes: []string{"text/csv", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "application/pdf"},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				input, err := exportInputFromRequest(r, token)
+				if err != nil {
+					return nil, err
+				}
+				export, err := application.OpenExport(input)
+				if err != nil {
+					return nil, err
+				}
+				return apiV1RawResponse{contentType: export.ContentType, filename: export.FileName, stream: export.Write}, nil
+			},
+		},
+		{
+			method: http.MethodPost, path: "/invoices", operationID: "generateInvoice", tag: "invoices", status: http.StatusCreated,
+			summary: "Issue the next GST tax invoice for a dispatch and store its PDF",
+			request: appInvoice.GenerateInvoiceInput{}, response: domainInvoice.Invoice{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appInvoice.GenerateInvoiceInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				return application.GenerateInvoice(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/invoices", operationID: "listInvoices", tag: "invoices",
+			summary: "List issued invoices, newest first",
+			query: []apiV1Param{
+				{name: "financial_year", kind: "string", description: "e.g. 2026-27"},
+				{name: "customer_id", kind: "integer"},
+				{name: "limit", kind: "integer"},
+			},
+			response: []domainInvoice.Invoice{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				q := r.URL.Query()
+				customerID, err := queryInt64Ptr(q, "customer_id")
+				if err != nil {
+					return nil, err
+				}
+				limit, err := queryInt64Ptr(q, "limit")
+				if err != nil {
+					return nil, err
+				}
+				input := appInvoice.ListInvoicesInput{FinancialYear: q.Get("financial_year"), AuthToken: token}
+				if customerID != nil {
+					input.CustomerID = *customerID
+				}
+				if limit != nil {
+					input.Limit = int(*limit)
+				}
+				return application.ListInvoices(input)
+			},
+		},
+		{
+			method: http.MethodGet, path: "/invoices/{id}", operationID: "getInvoice", tag: "invoices",
+			summary:  "Get an issued invoice",
+			response: domainInvoice.Invoice{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				id, err := pathID(r, "id")
+				if err != nil {
+					return nil, err
+				}
+				return application.GetInvoice(appInvoice.InvoiceIDInput{ID: id, AuthToken: token})
+			},
+		},
+		{
+			method: http.MethodGet, path: "/invoices/{id}/pdf", operationID: "getInvoicePDF", tag: "invoices",
+			summary:  "Download the PDF stored when the invoice was issued",
+			rawTypes: []string{"application/pdf"},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				id, err := pathID(r, "id")
+				if err != nil {
+					return nil, err
+				}
+				document, err := application.InvoicePDF(appInvoice.InvoiceIDInput{ID: id, AuthToken: token})
+				if err != nil {
+					return nil, err
+				}
+				return apiV1RawResponse{contentType: document.ContentType, filename: document.FileName, body: document.Data}, nil
+			},
+		},
+		{
+			method: http.MethodPost, path: "/labels", operationID: "printLabels", tag: "labels",
+			summary:  "Render Code 128 or QR lot labels as a PDF (one page per label) or a PNG of one label",
+			request:  appLabel.PrintLabelsInput{},
+			rawTypes: []string{"application/pdf", "image/png"},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				var input appLabel.PrintLabelsInput
+				if err := decodeAPIV1Body(r, &input); err != nil {
+					return nil, err
+				}
+				input.AuthToken = token
+				document, err := application.PrintLabels(input)
+				if err != nil {
+					return nil, err
+				}
+				return apiV1RawResponse{contentType: document.ContentType, filename: document.FileName, body: document.Data}, nil
+			},
+		},
+		{
+			method: http.MethodGet, path: "/labels/lookup", operationID: "lookupLabel", tag: "labels",
+			summary:  "Resolve a scanned label code to its lot and remaining balance",
+			query:    []apiV1Param{{name: "code", kind: "string", description: "the scanned lot number"}},
+			response: appLabel.LotLookup{},
+			handler: func(r *http.Request, token string) (interface{}, error) {
+				return application.LookupLabel(appLabel.LookupInput{Code: r.URL.Query().Get("code"), AuthToken: token})
+			},
+		},
+	}
+}
+
+func exportQueryParams() []apiV1Param {
+	return []apiV1Param{
+		{name: "format", kind: "string", description: "csv (default), xlsx or pdf"},
+		{name: "search", kind: "string"},
+		{name: "active_only", kind: "boolean"},
+		{name: "item_type", kind: "string"},
+		{name: "party_type", kind: "string"},
+		{name: "item_id", kind: "integer"},
+		{name: "supplier", kind: "string"},
+		{name: "lot_number", kind: "string"},
+		{name: "sort_by", kind: "string"},
+		{name: "sort_order", kind: "string", description: "asc or desc"},
+		{name: "actor", kind: "string", description: "audit_log only"},
+		{name: "action", kind: "string", description: "audit_log only"},
+		{name: "entity_type", kind: "string", description: "audit_log only"},
+		{name: "from", kind: "string", description: "audit_log only: RFC3339 timestamp or YYYY-MM-DD"},
+		{name: "to", kind: "string", description: "audit_log only: RFC3339 timestamp or YYYY-MM-DD (inclusive day)"},
+	}
+}
+
+func exportInputFromRequest(r *http.Request, token string) (appExport.ExportInput, error) {
+	q := r.URL.Query()
+	activeOnly, err := queryBool(q, "active_only")
+	if err != nil {
+		return appExport.ExportInput{}, err
+	}
+	itemID, err := queryInt64Ptr(q, "item_id")
+	if err != nil {
+		return appExport.ExportInput{}, err
+	}
+	return appExport.ExportInput{
+		Dataset:    r.PathValue("dataset"),
+		Format:     q.Get("format"),
+		Search:     q.Get("search"),
+		ActiveOnly: activeOnly,
+		ItemType:   q.Get("item_type"),
+		PartyType:  q.Get("party_type"),
+		ItemID:     itemID,
+		Supplier:   q.Get("supplier"),
+		LotNumber:  q.Get("lot_number"),
+		SortBy:     q.Get("sort_by"),
+		SortOrder:  q.Get("sort_order"),
+		Actor:      q.Get("actor"),
+		Action:     q.Get("action"),
+		EntityType: q.Get("entity_type"),
+		From:       q.Get("from"),
+		To:         q.Get("to"),
+		AuthToken:  token,
+	}, nil
+}
+
+func auditLogQueryParams() []apiV1Param {
+	return []apiV1Param{
+		{name: "actor", kind: "string"},
+		{name: "action", kind: "string"},
+		{name: "entity_type", kind: "string"},
+		{name: "entity_id", kind: "string"},
+		{name: "from", kind: "string", description: "RFC3339 timestamp or YYYY-MM-DD"},
+		{name: "to", kind: "string", description: "RFC3339 timestamp or YYYY-MM-DD (inclusive day)"},
+		{name: "before_id", kind: "integer", description: "return entries older than this entry"},
+		{name: "limit", kind: "integer"},
+	}
+}
+
+func auditLogInputFromQuery(q url.Values, token string) (appAudit.ListAuditLogInput, error) {
+	limit, err := queryInt64Ptr(q, "limit")
+	if err != nil {
+		return appAudit.ListAuditLogInput{}, err
+	}
+	beforeID, err := queryInt64Ptr(q, "before_id")
+	if err != nil {
+		return appAudit.ListAuditLogInput{}, err
+	}
+	input := appAudit.ListAuditLogInput{
+		AuthToken:  token,
+		Actor:      q.Get("actor"),
+		Action:     q.Get("action"),
+		EntityType: q.Get("entity_type"),
+		EntityID:   q.Get("entity_id"),
+		From:       q.Get("from"),
+		To:         q.Get("to"),
+	}
+	if limit != nil {
+		input.Limit = int(*limit)
+	}
+	if beforeID != nil {
+		input.BeforeID = *beforeID
+	}
+	return input, nil
+}
+
+// registerAPIV1Routes mounts the resource-oriented API under /api/v1 next to the legacy routes.
+// It fails, mounting nothing, when the route table or the OpenAPI document built from it is
+// inconsistent.
+func registerAPIV1Routes(mux *http.ServeMux, application serverAPIApplication) error {
+	return mountAPIV1Routes(mux, apiV1Routes(application))
+}
+
+func mountAPIV1Routes(mux *http.ServeMux, routes []apiV1Route) error {
+	byPath := make(map[string][]apiV1Route)
+	paths := make([]string, 0)
+	for _, route := range routes {
+		for _, existing := range byPath[route.path] {
+			if existing.method == route.method {
+				return fmt.Errorf("route %s %s is registered twice", route.method, apiV1Prefix+route.path)
+			}
+		}
+		if _, ok := byPath[route.path]; !ok {
+			paths = append(paths, route.path)
+		}
+		byPath[route.path] = append(byPath[route.path], route)
+	}
+
+	document, err := buildOpenAPIDocument(routes)
+	if err != nil {
+		return fmt.Errorf("failed to build OpenAPI document: %w", err)
+	}
+	spec, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to build OpenAPI document: %w", err)
+	}
+
+	for _, path := range paths {
+		pathRoutes := byPath[path]
+		mux.HandleFunc(apiV1Prefix+path, func(w http.ResponseWriter, r *http.Request) {
+			for _, route := range pathRoutes {
+				if route.method == r.Method {
+					serveAPIV1Route(w, r, route)
+					return
+				}
+			}
+			allowed := make([]string, 0, len(pathRoutes))
+			for _, route := range pathRoutes {
+				allowed = append(allowed, route.method)
+			}
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeAPIV1Error(w, http.StatusMethodNotAllowed, apiV1ErrorBody{Code: "method_not_allowed", Message: "method not allowed"})
+		})
+	}
+
+	mux.HandleFunc(apiV1Prefix+"/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeAPIV1Error(w, http.StatusMethodNotAllowed, apiV1ErrorBody{Code: "method_not_allowed", Message: "method not allowed"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(spec)
+	})
+
+	mux.HandleFunc(apiV1Prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIV1Error(w, http.StatusNotFound, apiV1ErrorBody{Code: "not_found", Message: "resource not found"})
+	})
+	return nil
+}
+
+func serveAPIV1Route(w http.ResponseWriter, r *http.Request, route apiV1Route) {
+	token := ""
+	if !route.public {
+		token = bearerToken(r)
+	}
+
+	result, err := route.handler(r, token)
+	if err != nil {
+		writeMappedAPIV1Error(w, fmt.Sprintf("Server API v1 %s %s failed", route.method, route.path), err)
+		return
+	}
+
+	if raw, ok := result.(apiV1RawResponse); ok {
+		if raw.stream != nil {
+			streamServerResponse(w, fmt.Sprintf("Server API v1 %s %s stream failed", route.method, route.path), raw.contentType, raw.filename, raw.stream)
+			return
+		}
+		w.Header().Set("Content-Type", raw.contentType)
+		if raw.filename != "" {
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", raw.filename))
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw.body)
+		return
+	}
+	if route.response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	status := route.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	writeServerJSON(w, status, result)
+}
+
+func bearerToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) > len("bearer ") && strings.EqualFold(header[:len("bearer ")], "bearer ") {
+		return strings.TrimSpace(header[len("bearer "):])
+	}
+	return ""
+}
+
+func decodeAPIV1Body(r *http.Request, target interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(target); err != nil {
+		return newAPIV1RequestError("invalid request payload")
+	}
+	return nil
+}
+
+func pathID(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, newAPIV1RequestError("invalid %s: must be a positive integer", name)
+	}
+	return id, nil
+}
+
+func queryBool(q url.Values, name string) (bool, error) {
+	raw := strings.TrimSpace(q.Get(name))
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, newAPIV1RequestError("invalid %s: must be true or false", name)
+	}
+	return value, nil
+}
+
+func queryInt64Ptr(q url.Values, name string) (*int64, error) {
+	raw := strings.TrimSpace(q.Get(name))
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, newAPIV1RequestError("invalid %s: must be an integer", name)
+	}
+	return &value, nil
+}
+
+// apiV1PageQuery is the cursor-pagination query shared by the list endpoints.
+type apiV1PageQuery struct {
+	limit     int
+	cursor    string
+	sortBy    string
+	sortOrder string
+}
+
+func apiV1PageParams(sortKeys string) []apiV1Param {
+	return []apiV1Param{
+		{name: "limit", kind: "integer", description: "Page size (1-500); omit for every row"},
+		{name: "cursor", kind: "string", description: "next_cursor from the previous page"},
+		{name: "sort_by", kind: "string", description: "One of: " + sortKeys},
+		{name: "sort_order", kind: "string", description: "ASC or DESC"},
+	}
+}
+
+func parseAPIV1Page(q url.Values) (apiV1PageQuery, error) {
+	page := apiV1PageQuery{
+		cursor:    strings.TrimSpace(q.Get("cursor")),
+		sortBy:    strings.TrimSpace(q.Get("sort_by")),
+		sortOrder: strings.TrimSpace(q.Get("sort_order")),
+	}
+	if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return apiV1PageQuery{}, newAPIV1RequestError("invalid limit: must be an integer")
+		}
+		page.limit = limit
+	}
+	return page, nil
+}
+
+func queryFloat(q url.Values, name string) (float64, error) {
+	raw := strings.TrimSpace(q.Get(name))
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, newAPIV1RequestError("invalid %s: must be a number", name)
+	}
+	return value, nil
+}
+
+func writeAPIV1Error(w http.ResponseWriter, status int, body apiV1ErrorBody) {
+	if strings.TrimSpace(body.Message) == "" {
+		body.Message = fmt.Sprintf("request failed (status %d)", status)
+	}
+	writeServerJSON(w, status, apiV1ErrorEnvelope{Error: body})
+}
+
+// writeMappedAPIV1Error builds the error envelope. ServiceError codes are passed through;
+// other errors get a code derived from the same status mapping as the legacy routes.
+func writeMappedAPIV1Error(w http.ResponseWriter, operation string, err error) {
+	var requestErr *apiV1RequestError
+	if errors.As(err, &requestErr) {
+		writeAPIV1Error(w, http.StatusBadRequest, apiV1ErrorBody{Code: "invalid_request", Message: requestErr.message})
+		return
+	}
+
+	status, msg := mapHTTPStatusFromError(err)
+	if status >= 500 {
+		slog.Error(operation, "error", err)
+	}
+
+	body := apiV1ErrorBody{Code: apiV1CodeForStatus(status), Message: msg}
+	var inventoryErr *appInventory.ServiceError
+	if errors.As(err, &inventoryErr) {
+		if code := strings.TrimSpace(strings.ToLower(inventoryErr.Code)); code != "" {
+			body.Code = code
+		}
+		body.Fields = inventoryErr.Fields
+	}
+	writeAPIV1Error(w, status, body)
+}
+
+func apiV1CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "validation_failed"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	default:
+		return "internal_error"
+	}
+}
+
+// openAPIComponents collects the named schemas of an OpenAPI document. Schemas are named
+// after their Go type, so two types with the same name in different packages would share one
+// schema; the second is recorded in err instead of silently describing the wrong type.
+type openAPIComponents struct {
+	schemas map[string]interface{}
+	types   map[string]reflect.Type
+	err     error
+}
+
+// buildOpenAPIDocument generates an OpenAPI 3 document from the route table,
+// deriving schemas from the Go request/response types via their json tags.
+func buildOpenAPIDocument(routes []apiV1Route) (map[string]interface{}, error) {
+	schemas := &openAPIComponents{types: make(map[string]reflect.Type)}
+	schemas.schemas = map[string]interface{}{
+		"Error": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"error"},
+			"properties": map[string]interface{}{
+				"error": openAPISchema(reflect.TypeOf(apiV1ErrorBody{}), nil),
+			},
+		},
+	}
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+
+	paths := make(map[string]interface{})
+	for _, route := range routes {
+		operation := map[string]interface{}{
+			"operationId": route.operationID,
+			"summary":     route.summary,
+			"tags":        []string{route.tag},
+		}
+		if route.public {
+			operation["security"] = []interface{}{}
+		}
+
+		parameters := make([]interface{}, 0)
+		for _, name := range openAPIPathParams(route.path) {
+			kind := "string"
+			if name == "id" {
+				kind = "integer"
+			}
+			parameters = append(parameters, openAPIParameter("path", apiV1Param{name: name, kind: kind}, true))
+		}
+		for _, param := range route.query {
+			parameters = append(parameters, openAPIParameter("query", param, false))
+		}
+		if route.method == http.MethodPost && !route.public {
+			parameters = append(parameters, openAPIParameter("header", apiV1Param{
+				name:        idempotencyKeyHeader,
+				kind:        "string",
+				description: "Client-chosen key; a retry by the same user with the same key and body returns the first response instead of repeating the write.",
+			}, false))
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if route.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": openAPISchema(reflect.TypeOf(route.request), schemas),
+					},
+				},
+			}
+		}
+
+		responses := map[string]interface{}{"default": errorResponse}
+		switch {
+		case len(route.rawTypes) > 0:
+			content := make(map[string]interface{}, len(route.rawTypes))
+			for _, rawType := range route.rawTypes {
+				content[rawType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}}
+			}
+			responses["200"] = map[string]interface{}{"description": "OK", "content": content}
+		case route.response == nil:
+			responses["204"] = map[string]interface{}{"description": "No Content"}
+		default:
+			status := route.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			responses[strconv.Itoa(status)] = map[string]interface{}{
+				"description": http.StatusText(status),
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": openAPISchema(reflect.TypeOf(route.response), schemas),
+					},
+				},
+			}
+		}
+		operation["responses"] = responses
+
+		pathItem, ok := paths[apiV1Prefix+route.path].(map[string]interface{})
+		if !ok {
+			pathItem = make(map[string]interface{})
+			paths[apiV1Prefix+route.path] = pathItem
+		}
+		pathItem[strings.ToLower(route.method)] = operation
+	}
+
+	if schemas.err != nil {
+		return nil, schemas.err
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Masala Inventory API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+	}, nil
+}
+
+func openAPIPathParams(path string) []string {
+	names := make([]string, 0)
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"))
+		}
+	}
+	return names
+}
+
+func openAPIParameter(in string, param apiV1Param, required bool) map[string]interface{} {
+	parameter := map[string]interface{}{
+		"name":     param.name,
+		"in":       in,
+		"required": required,
+		"schema":   map[string]interface{}{"type": param.kind},
+	}
+	if param.description != "" {
+		parameter["description"] = param.description
+	}
+	return parameter
+}
+
+// openAPISchema converts a Go type into a JSON schema. Named structs are registered once
+// under components/schemas when a registry is supplied; auth_token fields are omitted because
+// /api/v1 takes the token from the Authorization header.
+func openAPISchema(t reflect.Type, schemas *openAPIComponents) map[string]interface{} {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema map[string]interface{}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		if schemas != nil && t.Name() != "" {
+			name := t.Name()
+			if registered, ok := schemas.types[name]; !ok {
+				schemas.types[name] = t
+				schemas.schemas[name] = map[string]interface{}{}
+				schemas.schemas[name] = openAPIObjectSchema(t, schemas)
+			} else if registered != t && schemas.err == nil {
+				schemas.err = fmt.Errorf("schema %s would describe both %s.%s and %s.%s", name, registered.PkgPath(), name, t.PkgPath(), name)
+			}
+			schema = map[string]interface{}{"$ref": "#/components/schemas/" + name}
+		} else {
+			schema = openAPIObjectSchema(t, schemas)
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			schema = map[string]interface{}{"type": "string", "format": "byte"}
+		} else {
+			schema = map[string]interface{}{"type": "array", "items": openAPISchema(t.Elem(), schemas)}
+		}
+	case t.Kind() == reflect.Map:
+		schema = map[string]interface{}{"type": "object", "additionalProperties": openAPISchema(t.Elem(), schemas)}
+	case t.Kind() == reflect.Bool:
+		schema = map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = map[string]interface{}{"type": "number"}
+	case t.Kind() == reflect.String:
+		schema = map[string]interface{}{"type": "string"}
+	default:
+		schema = map[string]interface{}{}
+	}
+
+	if nullable {
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+	}
+	return schema
+}
+
+func openAPIObjectSchema(t reflect.Type, schemas *openAPIComponents) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || name == "auth_token" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = openAPISchema(field.Type, schemas)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
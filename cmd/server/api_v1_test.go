@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"masala_inventory_managment/internal/app"
+	appExport "masala_inventory_managment/internal/app/export"
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	appInvoice "masala_inventory_managment/internal/app/invoice"
+	appLabel "masala_inventory_managment/internal/app/label"
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+)
+
+func doAPIV1(t *testing.T, handler http.Handler, method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var body *bytes.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("failed to marshal payload: %v", err)
+		}
+		body = bytes.NewReader(encoded)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeAPIV1Error(t *testing.T, rec *httptest.ResponseRecorder, expectedStatus int) apiV1ErrorBody {
+	t.Helper()
+	if rec.Code != expectedStatus {
+		t.Fatalf("expected status %d, got %d (%s)", expectedStatus, rec.Code, rec.Body.String())
+	}
+	var envelope apiV1ErrorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode error envelope: %v", err)
+	}
+	return envelope.Error
+}
+
+func TestServerAPIV1_ListItemsMapsQueryAndBearerToken(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		listItemsPageFn: func(input appInventory.ListItemsInput) (app.ItemMasterPageResult, error) {
+			if input.AuthToken != "token-1" || !input.ActiveOnly || input.ItemType != "RAW" || input.Search != "cumin" {
+				t.Fatalf("unexpected list input: %#v", input)
+			}
+			if input.Limit != 2 || input.Cursor != "abc" || input.SortBy != "sku" || input.SortOrder != "DESC" {
+				t.Fatalf("unexpected page input: %#v", input)
+			}
+			return app.ItemMasterPageResult{
+				Items:      []app.ItemMasterResult{{ID: 3, SKU: "RAW-3", Name: "Cumin"}},
+				NextCursor: "next",
+				TotalCount: 5,
+			}, nil
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodGet, "/api/v1/items?active_only=true&item_type=RAW&search=cumin&limit=2&cursor=abc&sort_by=sku&sort_order=DESC", "token-1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+	var page app.ItemMasterPageResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != 3 || page.NextCursor != "next" || page.TotalCount != 5 {
+		t.Fatalf("unexpected page: %#v", page)
+	}
+}
+
+func TestServerAPIV1_ListRejectsNonNumericLimit(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{})
+
+	rec := doAPIV1(t, router, http.MethodGet, "/api/v1/lots?limit=ten", "token-1", nil)
+	if body := decodeAPIV1Error(t, rec, http.StatusBadRequest); body.Code != "invalid_request" {
+		t.Fatalf("unexpected error body: %#v", body)
+	}
+}
+
+func TestServerAPIV1_UpdateItemTakesIDFromPath(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		updateItemMasterFn: func(input appInventory.UpdateItemInput) (app.ItemMasterResult, error) {
+			if input.ID != 42 || input.Name != "Turmeric" || input.AuthToken != "token-1" {
+				t.Fatalf("unexpected update input: %#v", input)
+			}
+			return app.ItemMasterResult{ID: input.ID, Name: input.Name}, nil
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodPut, "/api/v1/items/42", "token-1", map[string]interface{}{"id": 7, "name": "Turmeric"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServerAPIV1_CreateReturnsCreated(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		createPartyFn: func(input appInventory.CreatePartyInput) (app.PartyResult, error) {
+			return app.PartyResult{ID: 9, Name: input.Name}, nil
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodPost, "/api/v1/parties", "token-1", map[string]interface{}{"name": "Spice Co", "party_type": "SUPPLIER"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServerAPIV1_DeleteUserReturnsNoContent(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		deleteUserFn: func(input app.DeleteUserInput) error {
+			if input.Username != "packer" || input.AuthToken != "admin-token" {
+				t.Fatalf("unexpected delete input: %#v", input)
+			}
+			return nil
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodDelete, "/api/v1/users/packer", "admin-token", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d (%s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServerAPIV1_ServiceErrorCodeIsPassedThrough(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		createItemMasterFn: func(input appInventory.CreateItemInput) (app.ItemMasterResult, error) {
+			return app.ItemMasterResult{}, &appInventory.ServiceError{
+				Code:    "validation_failed",
+				Message: "item validation failed",
+				Fields:  []appInventory.FieldError{{Field: "name", Message: "name is required"}},
+			}
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodPost, "/api/v1/items", "token-1", map[string]interface{}{})
+	body := decodeAPIV1Error(t, rec, http.StatusBadRequest)
+	if body.Code != "validation_failed" || body.Message != "item validation failed" {
+		t.Fatalf("unexpected error body: %#v", body)
+	}
+	if len(body.Fields) != 1 || body.Fields[0].Field != "name" {
+		t.Fatalf("expected field errors in envelope, got %#v", body.Fields)
+	}
+}
+
+func TestServerAPIV1_PlainErrorsGetDerivedCode(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		deleteUserFn: func(input app.DeleteUserInput) error {
+			return errors.New("user not found")
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodDelete, "/api/v1/users/ghost", "admin-token", nil)
+	body := decodeAPIV1Error(t, rec, http.StatusNotFound)
+	if body.Code != "not_found" || body.Message != "user not found" {
+		t.Fatalf("unexpected error body: %#v", body)
+	}
+}
+
+func TestServerAPIV1_InvalidQueryAndMethodUseEnvelope(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{})
+
+	rec := doAPIV1(t, router, http.MethodGet, "/api/v1/items?active_only=maybe", "token-1", nil)
+	if body := decodeAPIV1Error(t, rec, http.StatusBadRequest); body.Code != "invalid_request" {
+		t.Fatalf("unexpected error body: %#v", body)
+	}
+
+	rec = doAPIV1(t, router, http.MethodPatch, "/api/v1/items", "token-1", nil)
+	if body := decodeAPIV1Error(t, rec, http.StatusMethodNotAllowed); body.Code != "method_not_allowed" {
+		t.Fatalf("unexpected error body: %#v", body)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, POST" {
+		t.Fatalf("expected Allow header, got %q", allow)
+	}
+
+	rec = doAPIV1(t, router, http.MethodGet, "/api/v1/nothing-here", "token-1", nil)
+	decodeAPIV1Error(t, rec, http.StatusNotFound)
+}
+
+func TestServerAPIV1_OpenAPIDocumentListsRoutes(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{})
+
+	rec := doAPIV1(t, router, http.MethodGet, "/api/v1/openapi.json", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode OpenAPI document: %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Fatalf("unexpected openapi version: %q", doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/api/v1/items/{id}"]["put"]; !ok {
+		t.Fatalf("expected PUT /api/v1/items/{id} in document")
+	}
+	if _, ok := doc.Paths["/api/v1/users/{username}"]["delete"]; !ok {
+		t.Fatalf("expected DELETE /api/v1/users/{username} in document")
+	}
+	item, ok := doc.Components.Schemas["CreateItemInput"]
+	if !ok {
+		t.Fatalf("expected CreateItemInput schema")
+	}
+	if _, leaked := item.Properties["auth_token"]; leaked {
+		t.Fatalf("expected auth_token to be omitted from schemas")
+	}
+}
+
+func TestServerAPIV1_RefusesAnInconsistentRouteTable(t *testing.T) {
+	get := func(path string, response interface{}) apiV1Route {
+		return apiV1Route{method: http.MethodGet, path: path, operationID: path, response: response}
+	}
+
+	err := mountAPIV1Routes(http.NewServeMux(), []apiV1Route{get("/invoices/pdf", appInvoice.Document{}), get("/labels/pdf", appLabel.Document{})})
+	if err == nil || !strings.Contains(err.Error(), "schema Document") {
+		t.Fatalf("expected two types named Document to be refused, got %v", err)
+	}
+	err = mountAPIV1Routes(http.NewServeMux(), []apiV1Route{get("/invoices/pdf", appInvoice.Document{}), get("/invoices/pdf", appInvoice.Document{})})
+	if err == nil || !strings.Contains(err.Error(), "registered twice") {
+		t.Fatalf("expected a duplicate route to be refused, got %v", err)
+	}
+	if err := mountAPIV1Routes(http.NewServeMux(), []apiV1Route{get("/invoices/pdf", appInvoice.Document{}), get("/invoices/list", appInvoice.Document{})}); err != nil {
+		t.Fatalf("expected one type used twice to share its schema, got %v", err)
+	}
+}
+
+func TestServerAPIV1_LegacyRoutesStillWork(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		listItemsFn: func(input appInventory.ListItemsInput) ([]app.ItemMasterResult, error) {
+			return []app.ItemMasterResult{}, nil
+		},
+	})
+
+	rec := postJSON(t, router, "/inventory/items/list", appInventory.ListItemsInput{AuthToken: "token-1"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected legacy route to keep working, got %d", rec.Code)
+	}
+}
+
+func TestServerAPIV1_ExportTakesDatasetFromPathAndFiltersFromQuery(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		openExportFn: func(input appExport.ExportInput) (*appExport.Export, error) {
+			if input.Dataset != "lots" || input.Format != "xlsx" || input.AuthToken != "token-1" {
+				t.Fatalf("unexpected export input: %+v", input)
+			}
+			if input.ItemID == nil || *input.ItemID != 7 || !input.ActiveOnly || input.Supplier != "Hill Farms" {
+				t.Fatalf("expected filters from the query, got %+v", input)
+			}
+			return nil, &appInventory.ServiceError{Code: "validation_failed", Message: "invalid sort_by"}
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodGet, "/api/v1/exports/lots?format=xlsx&item_id=7&active_only=true&supplier=Hill+Farms", "token-1", nil)
+	body := decodeAPIV1Error(t, rec, http.StatusBadRequest)
+	if body.Code != "validation_failed" || body.Message != "invalid sort_by" {
+		t.Fatalf("unexpected error body: %+v", body)
+	}
+
+	rec = doAPIV1(t, router, http.MethodGet, "/api/v1/exports/lots?item_id=abc", "token-1", nil)
+	decodeAPIV1Error(t, rec, http.StatusBadRequest)
+}
+
+func TestServerAPIV1_InvoiceRoutes(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		listInvoicesFn: func(input appInvoice.ListInvoicesInput) ([]domainInvoice.Invoice, error) {
+			if input.FinancialYear != "2026-27" || input.CustomerID != 4 || input.Limit != 20 || input.AuthToken != "token-1" {
+				t.Fatalf("unexpected list input: %+v", input)
+			}
+			return []domainInvoice.Invoice{{ID: 1, InvoiceNumber: "INV/2026-27/0001"}}, nil
+		},
+		invoicePDFFn: func(input appInvoice.InvoiceIDInput) (*appInvoice.Document, error) {
+			if input.ID != 1 || input.AuthToken != "token-1" {
+				t.Fatalf("unexpected pdf input: %+v", input)
+			}
+			return &appInvoice.Document{FileName: "INV-2026-27-0001.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")}, nil
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodGet, "/api/v1/invoices?financial_year=2026-27&customer_id=4&limit=20", "token-1", nil)
+	var list []domainInvoice.Invoice
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &list) != nil || len(list) != 1 || list[0].InvoiceNumber != "INV/2026-27/0001" {
+		t.Fatalf("unexpected list response %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doAPIV1(t, router, http.MethodGet, "/api/v1/invoices/1/pdf", "token-1", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" || rec.Body.String() != "%PDF-1.4" {
+		t.Fatalf("unexpected pdf response %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	rec = doAPIV1(t, router, http.MethodGet, "/api/v1/invoices/abc", "token-1", nil)
+	decodeAPIV1Error(t, rec, http.StatusBadRequest)
+}
+
+func TestServerAPIV1_LabelLookupTakesCodeFromQuery(t *testing.T) {
+	router := newTestServerAPIRouter(t, stubServerAPIApplication{
+		lookupLabelFn: func(input appLabel.LookupInput) (*appLabel.LotLookup, error) {
+			if input.Code != "LOT-20260101-001" || input.AuthToken != "token-1" {
+				t.Fatalf("unexpected lookup input: %+v", input)
+			}
+			return &appLabel.LotLookup{ItemSKU: "CUM-1", Unit: "kg", Balance: 25}, nil
+		},
+	})
+
+	rec := doAPIV1(t, router, http.MethodGet, "/api/v1/labels/lookup?code=LOT-20260101-001", "token-1", nil)
+	var found appLabel.LotLookup
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &found) != nil || found.Balance != 25 {
+		t.Fatalf("unexpected lookup response %d %s", rec.Code, rec.Body.String())
+	}
+}
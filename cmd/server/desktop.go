@@ -0,0 +1,228 @@
+//go:build !headless
+
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	stdruntime "runtime"
+	"time"
+
+	"masala_inventory_managment"
+	"masala_inventory_managment/internal/app"
+	appSys "masala_inventory_managment/internal/app/system"
+	domainSys "masala_inventory_managment/internal/domain/system"
+	infraSys "masala_inventory_managment/internal/infrastructure/system"
+
+	"github.com/wailsapp/wails/v2"
+	"github.com/wailsapp/wails/v2/pkg/options"
+	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/getlantern/systray"
+)
+
+//go:embed assets/icon.png
+var iconPNGData []byte
+
+//go:embed assets/icon.ico
+var iconICOData []byte
+
+const (
+	backgroundNotificationTitle = "Masala Inventory is still running"
+	backgroundNotificationBody  = "The server is now in the background. Use the tray icon to reopen or exit."
+)
+
+// runDesktop shows the server window and tray icon, returning once the window closes. A
+// build with the headless tag leaves this file, and with it Wails and the tray, out.
+func runDesktop(application *app.App, bindings []interface{}, monitorSvc *appSys.MonitorService, sysMonitor domainSys.SysMonitor) error {
+	appOptions := &options.App{
+		Title:             "Masala Inventory Server",
+		Width:             1024,
+		Height:            768,
+		Frameless:         true,
+		HideWindowOnClose: false, // Route close via OnBeforeClose for explicit logging + notifications
+		AssetServer: &assetserver.Options{
+			Assets: masala_inventory_managment.Assets,
+		},
+		BackgroundColour: &options.RGBA{R: 125, G: 17, B: 17, A: 1}, // Motaba Deep Maroon
+		OnStartup: func(ctx context.Context) {
+			application.Startup(ctx)
+			monitorSvc.Start(ctx)
+			runtime.WindowMaximise(ctx)
+			restoreServerWindow := func(source string) {
+				slog.Info("Window restore requested", "source", source)
+				runtime.WindowShow(ctx)
+				runtime.WindowUnminimise(ctx)
+				runtime.WindowSetAlwaysOnTop(ctx, true)
+				go func() {
+					time.Sleep(500 * time.Millisecond)
+					runtime.WindowSetAlwaysOnTop(ctx, false)
+				}()
+				if stdruntime.GOOS == "windows" {
+					if err := sysMonitor.FocusWindow("Masala Inventory Server"); err != nil {
+						slog.Warn("Failed to focus server window", "source", source, "error", err)
+					}
+				}
+			}
+			runtime.EventsOn(ctx, "app:request-hide-to-tray", func(optionalData ...interface{}) {
+				slog.Info("UI event", "action", "request-hide-to-tray")
+				runtime.WindowHide(ctx)
+				if err := infraSys.ShowNotification(backgroundNotificationTitle, backgroundNotificationBody); err != nil {
+					slog.Error("Failed to show notification", "error", err)
+				}
+				runtime.EventsEmit(ctx, "server-minimized", nil)
+			})
+			runtime.EventsOn(ctx, "app:request-minimize", func(optionalData ...interface{}) {
+				slog.Info("UI event", "action", "request-minimize")
+				// Minimize should remain a true OS minimize (taskbar), not hide-to-tray.
+				runtime.WindowMinimise(ctx)
+			})
+
+			// Initialize System Tray
+			// Note: We run this in a goroutine because Wails requires the main thread.
+			// This works on Windows but causes SIGABRT on Linux due to GTK loop conflict.
+			if stdruntime.GOOS != "linux" {
+				go func() {
+					systray.Run(func() {
+						systray.SetTitle("Masala Server")
+						systray.SetTooltip("Masala Inventory Server")
+						if stdruntime.GOOS == "windows" && len(iconICOData) > 0 {
+							systray.SetIcon(iconICOData)
+						} else if len(iconPNGData) > 0 {
+							systray.SetIcon(iconPNGData)
+						}
+
+						mOpen := systray.AddMenuItem("Open Dashboard", "Restore the server window")
+						systray.AddSeparator()
+						mQuit := systray.AddMenuItem("Exit Server", "Shutdown the server")
+
+						// Keep onReady non-blocking; process menu events in a dedicated goroutine.
+						go func() {
+							for {
+								select {
+								case <-ctx.Done():
+									return
+								case <-mOpen.ClickedCh:
+									slog.Info("Tray action", "action", "open-dashboard")
+									restoreServerWindow("tray-open")
+									runtime.EventsEmit(ctx, "app:request-open-dashboard")
+								case <-mQuit.ClickedCh:
+									slog.Info("Tray action", "action", "exit-server")
+									restoreServerWindow("tray-exit")
+									go func() {
+										time.Sleep(120 * time.Millisecond)
+										slog.Info("Tray action", "action", "emit-custom-quit-confirm")
+										runtime.EventsEmit(ctx, "app:request-quit-confirm")
+									}()
+								}
+							}
+						}()
+					}, func() {
+						// Systray cleanup
+					})
+				}()
+			} else {
+				slog.Warn("System Tray is disabled on Linux to prevent GTK main loop conflicts with Wails.")
+			}
+
+			if stdruntime.GOOS == "windows" {
+				go func() {
+					iconPath := filepath.Join("cmd", "server", "assets", "icon.ico")
+					for attempt := 1; attempt <= 40; attempt++ {
+						if err := infraSys.SetWindowIconFromFile("Masala Inventory Server", iconPath); err == nil {
+							slog.Info("Applied Windows window icon", "path", iconPath, "attempt", attempt)
+							return
+						}
+						time.Sleep(250 * time.Millisecond)
+					}
+					slog.Warn("Failed to apply Windows window icon", "path", iconPath)
+				}()
+			}
+
+			// Background watcher for cross-process focus pings (Linux/Unix support)
+			go func() {
+				pingFile := filepath.Join(os.TempDir(), "MasalaServerMutex.ping")
+				for {
+					if _, err := os.Stat(pingFile); err == nil {
+						_ = os.Remove(pingFile)
+						restoreServerWindow("single-instance-ping")
+					}
+					time.Sleep(500 * time.Millisecond)
+				}
+			}()
+
+			go func() {
+				// Poll window state at a lower cadence; this is enough for UX notifications
+				// and reduces repeated syscall work on long-running sessions.
+				ticker := time.NewTicker(3 * time.Second)
+				defer ticker.Stop()
+
+				initialized := false
+				lastMinimized := false
+				notifiedWhileMinimized := false
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						minimized := runtime.WindowIsMinimised(ctx)
+						if stdruntime.GOOS == "windows" {
+							// In steady minimized state we've already notified; skip extra window
+							// enumeration until a potential restore transition is observed.
+							if !lastMinimized || !notifiedWhileMinimized || !minimized {
+								if winMinimized, err := infraSys.WindowIsCurrentProcessMinimized(); err == nil {
+									minimized = winMinimized
+								}
+							} else {
+								minimized = true
+							}
+						}
+						if !initialized {
+							lastMinimized = minimized
+							initialized = true
+							continue
+						}
+						if minimized && !lastMinimized {
+							notifiedWhileMinimized = true
+						}
+						if !minimized {
+							notifiedWhileMinimized = false
+						}
+						lastMinimized = minimized
+					}
+				}
+			}()
+
+		},
+		OnShutdown: func(ctx context.Context) {
+			systray.Quit()
+		},
+		OnBeforeClose: func(ctx context.Context) bool {
+			if application.IsForceQuit() {
+				slog.Info("OnBeforeClose: Force quit detected, allowing close")
+				return false // Allow close
+			}
+			slog.Info("OnBeforeClose: Window close request received (likely title bar X)")
+			slog.Info("OnBeforeClose: Hiding window and backgrounding server")
+			runtime.WindowHide(ctx)
+			// AC #1: Notification bubble on minimize
+			if err := infraSys.ShowNotification(backgroundNotificationTitle, backgroundNotificationBody); err != nil {
+				slog.Error("Failed to show notification", "error", err)
+			}
+			runtime.EventsEmit(ctx, "server-minimized", nil)
+			return true // Prevent close, just hide
+		},
+		Bind: bindings,
+	}
+
+	if err := wails.Run(appOptions); err != nil {
+		return fmt.Errorf("wails run error: %w", err)
+	}
+
+	return nil
+}
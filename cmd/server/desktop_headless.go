@@ -0,0 +1,17 @@
+//go:build headless
+
+package main
+
+import (
+	"fmt"
+
+	"masala_inventory_managment/internal/app"
+	appSys "masala_inventory_managment/internal/app/system"
+	domainSys "masala_inventory_managment/internal/domain/system"
+)
+
+// runDesktop stands in for the window in a build made with the headless tag, which links
+// neither Wails nor the tray libraries. Such a build only serves with --headless.
+func runDesktop(*app.App, []interface{}, *appSys.MonitorService, domainSys.SysMonitor) error {
+	return fmt.Errorf("this server was built without its window; start it with %s", headlessArg)
+}
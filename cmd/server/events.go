@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+)
+
+// serverEventsKeepAliveInterval keeps idle streams from being dropped by proxies and NAT.
+var serverEventsKeepAliveInterval = 25 * time.Second
+
+// handleServerEvents streams committed domain events as server-sent events. The caller's
+// session is checked when the stream opens and again on every keep-alive, so a session that
+// expires or is revoked closes the stream, and only events their current role may see are sent.
+func handleServerEvents(application serverAPIApplication) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" {
+			writeServerError(w, http.StatusUnauthorized, "missing authentication token")
+			return
+		}
+		role, err := application.GetSessionRole(token)
+		if err != nil {
+			writeMappedServerError(w, "Server events session check failed", err)
+			return
+		}
+		if strings.TrimSpace(role) == "" {
+			writeServerError(w, http.StatusUnauthorized, "invalid or expired authentication token")
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeServerError(w, http.StatusInternalServerError, "streaming is not supported")
+			return
+		}
+		events, cancel, err := application.SubscribeEvents()
+		if err != nil {
+			writeServerError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, ": connected\n\n")
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(serverEventsKeepAliveInterval)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				current, err := application.GetSessionRole(token)
+				if err != nil || strings.TrimSpace(current) == "" {
+					slog.Info("Closing server events stream; session is no longer valid", "error", err)
+					_, _ = fmt.Fprint(w, "event: session_expired\ndata: {}\n\n")
+					flusher.Flush()
+					return
+				}
+				role = current
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if !event.VisibleTo(domainAuth.Role(role)) {
+					continue
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					slog.Error("Failed to encode server event", "type", event.Type, "error", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, data); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainEvents "masala_inventory_managment/internal/domain/events"
+)
+
+func TestServerEvents_RequiresSession(t *testing.T) {
+	stub := &stubServerAPIApplication{
+		getSessionRoleFn: func(string) (string, error) { return "", errors.New("invalid token") },
+	}
+	handler := newTestServerAPIRouter(t, stub)
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assertErrorStatusAndMessage(t, rec, http.StatusUnauthorized, "missing authentication token")
+
+	req = httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Authorization", "Bearer expired")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a rejected session, got %d", rec.Code)
+	}
+}
+
+func TestServerEvents_StreamsEventsVisibleToRole(t *testing.T) {
+	events := make(chan domainEvents.Event, 4)
+	cancelled := make(chan struct{})
+	stub := &stubServerAPIApplication{
+		getSessionRoleFn: func(token string) (string, error) {
+			if token != "operator-token" {
+				return "", errors.New("invalid token")
+			}
+			return string(domainAuth.RoleDataEntryOperator), nil
+		},
+		subscribeEventsFn: func() (<-chan domainEvents.Event, func(), error) {
+			return events, func() { close(cancelled) }, nil
+		},
+	}
+	server := httptest.NewServer(newTestServerAPIRouter(t, stub))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+	req.Header.Set("Authorization", "Bearer operator-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	events <- domainEvents.Event{Sequence: 1, Type: "UserRoleChanged", Roles: []domainAuth.Role{domainAuth.RoleAdmin}}
+	events <- domainEvents.Event{Sequence: 2, Type: domainEvents.TypeGrnCreated, EntityID: "7", Roles: []domainAuth.Role{domainAuth.RoleAdmin, domainAuth.RoleDataEntryOperator}}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for len(got) < 3 {
+		select {
+		case line := <-lines:
+			if strings.HasPrefix(line, "id:") || strings.HasPrefix(line, "event:") || strings.HasPrefix(line, "data:") {
+				got = append(got, line)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for event, got %v", got)
+		}
+	}
+	if got[0] != "id: 2" || got[1] != "event: GrnCreated" || !strings.Contains(got[2], `"entity_id":"7"`) {
+		t.Fatalf("expected only the operator-visible event, got %v", got)
+	}
+
+	resp.Body.Close()
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected subscription to end when the client disconnects")
+	}
+}
+
+func TestServerEvents_ClosesStreamWhenSessionExpires(t *testing.T) {
+	previous := serverEventsKeepAliveInterval
+	serverEventsKeepAliveInterval = 20 * time.Millisecond
+	defer func() { serverEventsKeepAliveInterval = previous }()
+
+	var expired atomic.Bool
+	cancelled := make(chan struct{})
+	stub := &stubServerAPIApplication{
+		getSessionRoleFn: func(token string) (string, error) {
+			if token != "operator-token" || expired.Load() {
+				return "", errors.New("invalid or expired authentication token")
+			}
+			return string(domainAuth.RoleDataEntryOperator), nil
+		},
+		subscribeEventsFn: func() (<-chan domainEvents.Event, func(), error) {
+			return make(chan domainEvents.Event), func() { close(cancelled) }, nil
+		},
+	}
+	server := httptest.NewServer(newTestServerAPIRouter(t, stub))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+	req.Header.Set("Authorization", "Bearer operator-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the stream to open, got %d", resp.StatusCode)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	timeout := time.After(2 * time.Second)
+	waitFor := func(want string) {
+		t.Helper()
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream closed before %q", want)
+				}
+				if line == want {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for %q", want)
+			}
+		}
+	}
+	waitFor(": ping")
+
+	expired.Store(true)
+	waitFor("event: session_expired")
+	for {
+		select {
+		case _, ok := <-lines:
+			if !ok {
+				select {
+				case <-cancelled:
+				case <-time.After(2 * time.Second):
+					t.Fatalf("expected the subscription to end with the stream")
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("expected the stream to close once the session expired")
+		}
+	}
+}
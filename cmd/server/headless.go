@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	appSys "masala_inventory_managment/internal/app/system"
+	infraSys "masala_inventory_managment/internal/infrastructure/system"
+)
+
+const (
+	headlessArg    = "--headless"
+	envLogFile     = "MASALA_LOG_FILE"
+	defaultLogFile = "masala_server.log"
+	// restartExitCode asks a service manager to start the server again, after a restore
+	// or a watchdog timeout, instead of relaunching it from a helper process.
+	restartExitCode = 75
+)
+
+func hasArg(args []string, want string) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveLogFilePath() string {
+	if raw := strings.TrimSpace(os.Getenv(envLogFile)); raw != "" {
+		return raw
+	}
+	return defaultLogFile
+}
+
+// logFile is the headless server's log. It is appended to, and reopened on SIGHUP so
+// logrotate can move it aside.
+type logFile struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openLogFile(path string) (*logFile, error) {
+	l := &logFile{path: path}
+	if err := l.Reopen(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Write(p)
+}
+
+// Reopen starts writing to a new file at the log path, if it has been moved.
+func (l *logFile) Reopen() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	l.mu.Lock()
+	previous := l.file
+	l.file = file
+	l.mu.Unlock()
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+// Close puts logging back on standard error, where a failure to start is reported, and
+// closes the file.
+func (l *logFile) Close() error {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// startHeadlessLogging sends slog, and the log package through it, to the log file.
+func startHeadlessLogging(path string) (*logFile, error) {
+	logs, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(logs, nil)))
+	return logs, nil
+}
+
+// serveHeadless keeps the server running without a window until ctx is cancelled by
+// SIGINT or SIGTERM; run's deferred calls then stop the API, ship the change journal and
+// close the database. It reports readiness, liveness and shutdown to systemd when started
+// as a Type=notify unit.
+func serveHeadless(ctx context.Context, monitorSvc *appSys.MonitorService, logs *logFile) error {
+	monitorSvc.StartHeadless(ctx)
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	var watchdogTicks <-chan time.Time
+	if interval := infraSys.ServiceWatchdogInterval(); interval > 0 {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		watchdogTicks = ticker.C
+	}
+
+	notifyServiceManager(infraSys.ServiceReady)
+	slog.Info("Headless server running", "pid", os.Getpid())
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Shutdown requested; stopping headless server")
+			notifyServiceManager(infraSys.ServiceStopping)
+			return nil
+		case <-hangups:
+			if err := logs.Reopen(); err != nil {
+				slog.Error("Failed to reopen log file", "error", err)
+			} else {
+				slog.Info("Log file reopened")
+			}
+		case <-watchdogTicks:
+			notifyServiceManager(infraSys.ServiceWatchdog)
+		}
+	}
+}
+
+func notifyServiceManager(state string) {
+	if _, err := infraSys.NotifyServiceManager(state); err != nil {
+		slog.Warn("Service manager notification failed", "state", state, "error", err)
+	}
+}
@@ -0,0 +1,97 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	appSys "masala_inventory_managment/internal/app/system"
+	infraSys "masala_inventory_managment/internal/infrastructure/system"
+)
+
+func TestServeHeadless_NotifiesSystemdAndReopensLogOnHangup(t *testing.T) {
+	dir, err := os.MkdirTemp("", "hl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "notify"), Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(dir, "notify"))
+	t.Setenv("WATCHDOG_USEC", "")
+	readState := func() string {
+		t.Helper()
+		buf := make([]byte, 64)
+		socket.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := socket.Read(buf)
+		if err != nil {
+			t.Fatalf("no notification: %v", err)
+		}
+		return string(buf[:n])
+	}
+
+	logPath := filepath.Join(dir, "server.log")
+	defaultLogger := slog.Default()
+	logs, err := startHeadlessLogging(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		logs.Close()
+		slog.SetDefault(defaultLogger)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	monitorSvc := appSys.NewMonitorService(infraSys.NewMonitor(), infraSys.NewWatchdog(10))
+	go func() { done <- serveHeadless(ctx, monitorSvc, logs) }()
+	if state := readState(); state != infraSys.ServiceReady {
+		t.Fatalf("expected %q first, got %q", infraSys.ServiceReady, state)
+	}
+
+	// logrotate moves the file and sends SIGHUP.
+	if err := os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if content, _ := os.ReadFile(logPath); strings.Contains(string(content), "Log file reopened") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the log file to be reopened at its path")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("serveHeadless: %v", err)
+	}
+	if state := readState(); state != infraSys.ServiceStopping {
+		t.Errorf("expected %q on shutdown, got %q", infraSys.ServiceStopping, state)
+	}
+	if rotated, _ := os.ReadFile(logPath + ".1"); !strings.Contains(string(rotated), "Headless server running") {
+		t.Errorf("expected earlier entries in the rotated file, got %q", rotated)
+	}
+}
+
+func TestHasArg(t *testing.T) {
+	if !hasArg([]string{"--headless"}, headlessArg) || hasArg([]string{"migrate", "status"}, headlessArg) {
+		t.Error("unexpected hasArg result")
+	}
+}
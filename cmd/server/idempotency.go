@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	domainIdempotency "masala_inventory_managment/internal/domain/idempotency"
+)
+
+const (
+	idempotencyKeyHeader       = "Idempotency-Key"
+	idempotentReplayHeader     = "Idempotent-Replayed"
+	envIdempotencyWindowHours  = "MASALA_IDEMPOTENCY_WINDOW_HOURS"
+	defaultIdempotencyWindow   = 24 * time.Hour
+	maxIdempotencyKeyLength    = 255
+	maxIdempotentRequestBytes  = 10 << 20
+	idempotencyKeyReusedCode   = "idempotency_key_reused"
+	idempotencyKeyInFlightCode = "idempotency_key_in_use"
+)
+
+// idempotencyGuard answers a retried create/record request from the stored response of the
+// first attempt, so a client that timed out waiting for a reply cannot apply the write twice.
+// Only successful responses are stored; a failed request may be retried with the same key.
+// Keys are scoped to the signed-in user, so one user cannot replay another user's response.
+type idempotencyGuard struct {
+	repo    domainIdempotency.Repository
+	window  time.Duration
+	now     func() time.Time
+	subject func(authToken string) (string, error)
+
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+}
+
+func newIdempotencyGuard(repo domainIdempotency.Repository, window time.Duration, subject func(authToken string) (string, error)) *idempotencyGuard {
+	if window <= 0 {
+		window = defaultIdempotencyWindow
+	}
+	return &idempotencyGuard{
+		repo:     repo,
+		window:   window,
+		now:      time.Now,
+		subject:  subject,
+		inFlight: make(map[string]struct{}),
+	}
+}
+
+func resolveIdempotencyWindow() time.Duration {
+	raw := strings.TrimSpace(os.Getenv(envIdempotencyWindowHours))
+	if raw == "" {
+		return defaultIdempotencyWindow
+	}
+	parsed, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || parsed == 0 {
+		return defaultIdempotencyWindow
+	}
+	return time.Duration(parsed) * time.Hour
+}
+
+// idempotentLegacyRoutes are the legacy routes that create or record something. Every write
+// route must be listed here, so a new one is not left unprotected by a missed naming rule.
+var idempotentLegacyRoutes = map[string]struct{}{
+	"/admin/create-user":                  {},
+	"/inventory/items/create":             {},
+	"/inventory/packaging/create":         {},
+	"/inventory/recipes/create":           {},
+	"/inventory/parties/create":           {},
+	"/inventory/grns/create":              {},
+	"/inventory/lots/movements/create":    {},
+	"/inventory/reconciliation/create":    {},
+	"/inventory/conversions/rules/create": {},
+	"/inventory/import":                   {},
+	"/invoices/create":                    {},
+}
+
+// isIdempotentRoute reports whether a request creates or records something: the listed
+// legacy routes and the /api/v1 POST routes other than sign-in.
+func isIdempotentRoute(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	if strings.HasPrefix(r.URL.Path, apiV1Prefix+"/") {
+		return !strings.HasPrefix(r.URL.Path, apiV1Prefix+"/auth/")
+	}
+	_, ok := idempotentLegacyRoutes[r.URL.Path]
+	return ok
+}
+
+func (g *idempotencyGuard) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+		if key == "" || !isIdempotentRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeIdempotencyError(w, r, http.StatusBadRequest, "validation_failed", "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			writeIdempotencyError(w, r, http.StatusBadRequest, "validation_failed", "invalid request payload")
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			writeIdempotencyError(w, r, http.StatusRequestEntityTooLarge, "payload_too_large", "request body is too large to be sent with an Idempotency-Key")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// A request without a valid session is left to the route, which refuses it; nothing
+		// is looked up or stored for it.
+		subject, err := g.subject(idempotencyAuthToken(r, body))
+		if err != nil || strings.TrimSpace(subject) == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key = scopeIdempotencyKey(subject, key)
+		requestHash := hashIdempotentRequest(r, body)
+
+		if !g.acquire(key) {
+			writeIdempotencyError(w, r, http.StatusConflict, idempotencyKeyInFlightCode, "a request with this Idempotency-Key is still being processed")
+			return
+		}
+		defer g.release(key)
+
+		now := g.now()
+		stored, err := g.repo.Get(key, now)
+		switch {
+		case err == nil:
+			if stored.RequestHash != requestHash {
+				writeIdempotencyError(w, r, http.StatusUnprocessableEntity, idempotencyKeyReusedCode, "Idempotency-Key was already used for a different request")
+				return
+			}
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			w.Header().Set(idempotentReplayHeader, "true")
+			w.WriteHeader(stored.StatusCode)
+			_, _ = w.Write(stored.ResponseBody)
+			return
+		case !errors.Is(err, domainIdempotency.ErrRecordNotFound):
+			slog.Error("Idempotency lookup failed", "error", err)
+			writeIdempotencyError(w, r, http.StatusInternalServerError, "internal_error", "failed to check Idempotency-Key")
+			return
+		}
+
+		recorder := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status < 200 || recorder.status >= 300 {
+			return
+		}
+
+		if err := g.repo.Save(&domainIdempotency.Record{
+			Key:          key,
+			RequestHash:  requestHash,
+			StatusCode:   recorder.status,
+			ContentType:  recorder.Header().Get("Content-Type"),
+			ResponseBody: recorder.body.Bytes(),
+			CreatedAt:    now,
+			ExpiresAt:    now.Add(g.window),
+		}); err != nil {
+			slog.Error("Failed to store idempotent response", "error", err)
+		}
+		if _, err := g.repo.DeleteExpired(now); err != nil {
+			slog.Warn("Failed to purge expired idempotency keys", "error", err)
+		}
+	})
+}
+
+func (g *idempotencyGuard) acquire(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, busy := g.inFlight[key]; busy {
+		return false
+	}
+	g.inFlight[key] = struct{}{}
+	return true
+}
+
+func (g *idempotencyGuard) release(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.inFlight, key)
+}
+
+// idempotencyAuthToken returns the session a request was sent with: the bearer token on
+// /api/v1 routes and auth_token in the body of the legacy routes.
+func idempotencyAuthToken(r *http.Request, body []byte) string {
+	if strings.HasPrefix(r.URL.Path, apiV1Prefix+"/") {
+		return bearerToken(r)
+	}
+	var payload struct {
+		AuthToken string `json:"auth_token"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(payload.AuthToken)
+}
+
+// scopeIdempotencyKey prefixes key with the user it belongs to. The length prefix keeps a
+// username containing the separator from colliding with another user's key.
+func scopeIdempotencyKey(subject, key string) string {
+	return strconv.Itoa(len(subject)) + ":" + subject + ":" + key
+}
+
+// hashIdempotentRequest identifies a request by route and body. The legacy routes carry the
+// session in auth_token, which is left out so a write replayed after signing in again still
+// matches its first attempt.
+func hashIdempotentRequest(r *http.Request, body []byte) string {
+	canonical := body
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err == nil {
+		delete(fields, "auth_token")
+		if encoded, err := json.Marshal(fields); err == nil {
+			canonical = encoded
+		}
+	}
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	sum.Write(canonical)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+func writeIdempotencyError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if strings.HasPrefix(r.URL.Path, apiV1Prefix+"/") {
+		writeAPIV1Error(w, status, apiV1ErrorBody{Code: code, Message: message})
+		return
+	}
+	writeServerError(w, status, message)
+}
+
+// idempotencyRecorder passes a response through while keeping a copy to store.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *idempotencyRecorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"masala_inventory_managment/internal/app"
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	domainIdempotency "masala_inventory_managment/internal/domain/idempotency"
+)
+
+type memoryIdempotencyRepository struct {
+	records map[string]domainIdempotency.Record
+}
+
+func (m *memoryIdempotencyRepository) Get(key string, now time.Time) (*domainIdempotency.Record, error) {
+	record, ok := m.records[key]
+	if !ok || !record.ExpiresAt.After(now) {
+		return nil, domainIdempotency.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (m *memoryIdempotencyRepository) Save(record *domainIdempotency.Record) error {
+	m.records[record.Key] = *record
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// idempotencyTestSubjects maps the sessions used in these tests to their users.
+func idempotencyTestSubjects(authToken string) (string, error) {
+	switch authToken {
+	case "token-a", "token-b":
+		return "alice", nil
+	case "token-c":
+		return "bob", nil
+	}
+	return "", errors.New("invalid session")
+}
+
+func newTestIdempotencyGuard() *idempotencyGuard {
+	return newIdempotencyGuard(&memoryIdempotencyRepository{records: map[string]domainIdempotency.Record{}}, time.Hour, idempotencyTestSubjects)
+}
+
+func postWithIdempotencyKey(handler http.Handler, path, key string, payload interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServerAPI_IdempotencyKeyReplaysStoredResponse(t *testing.T) {
+	calls := 0
+	stub := &stubServerAPIApplication{
+		createGRNFn: func(input appInventory.CreateGRNInput) (app.GRNResult, error) {
+			calls++
+			return app.GRNResult{ID: int64(calls), GRNNumber: input.GRNNumber}, nil
+		},
+	}
+	guard := newTestIdempotencyGuard()
+	handler := guard.wrap(newTestServerAPIRouter(t, stub))
+
+	first := postWithIdempotencyKey(handler, "/inventory/grns/create", "key-1", map[string]interface{}{"grn_number": "GRN-1", "auth_token": "token-a"})
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected first request to succeed, got %d (%s)", first.Code, first.Body.String())
+	}
+
+	// A retry after re-authenticating carries a new token but is the same write.
+	retry := postWithIdempotencyKey(handler, "/inventory/grns/create", "key-1", map[string]interface{}{"grn_number": "GRN-1", "auth_token": "token-b"})
+	if retry.Code != http.StatusOK || retry.Header().Get(idempotentReplayHeader) != "true" {
+		t.Fatalf("expected stored response to be replayed, got %d %v", retry.Code, retry.Header())
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Fatalf("expected identical body, got %q vs %q", retry.Body.String(), first.Body.String())
+	}
+	if calls != 1 {
+		t.Fatalf("expected GRN to be created once, got %d calls", calls)
+	}
+
+	mismatch := postWithIdempotencyKey(handler, "/inventory/grns/create", "key-1", map[string]interface{}{"grn_number": "GRN-2", "auth_token": "token-a"})
+	assertErrorStatusAndMessage(t, mismatch, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+
+	postWithIdempotencyKey(handler, "/inventory/grns/create", "key-2", map[string]interface{}{"grn_number": "GRN-2", "auth_token": "token-a"})
+	if calls != 2 {
+		t.Fatalf("expected a new key to create a new GRN, got %d calls", calls)
+	}
+}
+
+func TestServerAPI_IdempotencyKeyDoesNotStoreFailuresOrExpiredResponses(t *testing.T) {
+	fail := true
+	calls := 0
+	stub := &stubServerAPIApplication{
+		createGRNFn: func(input appInventory.CreateGRNInput) (app.GRNResult, error) {
+			calls++
+			if fail {
+				return app.GRNResult{}, &appInventory.ServiceError{Code: "validation_failed", Message: "validation failed"}
+			}
+			return app.GRNResult{ID: int64(calls)}, nil
+		},
+	}
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	guard := newTestIdempotencyGuard()
+	guard.now = func() time.Time { return now }
+	handler := guard.wrap(newTestServerAPIRouter(t, stub))
+
+	if rec := postWithIdempotencyKey(handler, "/inventory/grns/create", "key-1", map[string]interface{}{"grn_number": "GRN-1", "auth_token": "token-a"}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected validation failure, got %d", rec.Code)
+	}
+	fail = false
+	if rec := postWithIdempotencyKey(handler, "/inventory/grns/create", "key-1", map[string]interface{}{"grn_number": "GRN-1", "auth_token": "token-a"}); rec.Code != http.StatusOK || rec.Header().Get(idempotentReplayHeader) != "" {
+		t.Fatalf("expected failed request to be retried, got %d %v", rec.Code, rec.Header())
+	}
+
+	now = now.Add(2 * time.Hour)
+	postWithIdempotencyKey(handler, "/inventory/grns/create", "key-1", map[string]interface{}{"grn_number": "GRN-1", "auth_token": "token-a"})
+	if calls != 3 {
+		t.Fatalf("expected key to be forgotten after the window, got %d calls", calls)
+	}
+}
+
+func TestServerAPI_IdempotencyKeyReplaysOpeningStockImport(t *testing.T) {
+	commits := 0
+	stub := &stubServerAPIApplication{
+		importMasterDataFn: func(input appInventory.ImportInput) (appInventory.ImportReport, error) {
+			if input.Commit {
+				commits++
+			}
+			return appInventory.ImportReport{Kind: input.Kind, TotalRows: 1, ValidRows: 1, Committed: input.Commit, GRNNumbers: []string{"OPEN-1"}}, nil
+		},
+	}
+	guard := newTestIdempotencyGuard()
+	handler := guard.wrap(newTestServerAPIRouter(t, stub))
+
+	payload := map[string]interface{}{"kind": appInventory.ImportKindOpeningStock, "format": "csv", "content": []byte("item,qty\nCumin,10\n"), "commit": true, "auth_token": "token-a"}
+	first := postWithIdempotencyKey(handler, "/inventory/import", "import-1", payload)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected import to succeed, got %d (%s)", first.Code, first.Body.String())
+	}
+	retry := postWithIdempotencyKey(handler, "/inventory/import", "import-1", payload)
+	if retry.Code != http.StatusOK || retry.Header().Get(idempotentReplayHeader) != "true" {
+		t.Fatalf("expected the import response to be replayed, got %d %v", retry.Code, retry.Header())
+	}
+	if commits != 1 {
+		t.Fatalf("expected opening stock to be posted once, got %d commits", commits)
+	}
+}
+
+func TestServerAPI_IdempotencyKeyIsScopedToTheSignedInUser(t *testing.T) {
+	calls := 0
+	stub := &stubServerAPIApplication{
+		createGRNFn: func(input appInventory.CreateGRNInput) (app.GRNResult, error) {
+			calls++
+			return app.GRNResult{ID: int64(calls), GRNNumber: input.GRNNumber}, nil
+		},
+	}
+	guard := newTestIdempotencyGuard()
+	handler := guard.wrap(newTestServerAPIRouter(t, stub))
+
+	payload := map[string]interface{}{"grn_number": "GRN-1", "auth_token": "token-a"}
+	if rec := postWithIdempotencyKey(handler, "/inventory/grns/create", "shared-key", payload); rec.Code != http.StatusOK {
+		t.Fatalf("expected first request to succeed, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	// Another user sending the same key and body gets a write of their own, not alice's response.
+	payload["auth_token"] = "token-c"
+	other := postWithIdempotencyKey(handler, "/inventory/grns/create", "shared-key", payload)
+	if other.Code != http.StatusOK || other.Header().Get(idempotentReplayHeader) != "" {
+		t.Fatalf("expected bob's request to be processed, got %d %v", other.Code, other.Header())
+	}
+	if calls != 2 {
+		t.Fatalf("expected one GRN per user, got %d calls", calls)
+	}
+
+	// Without a valid session nothing is replayed; the request goes to the route as sent.
+	payload["auth_token"] = "expired"
+	anonymous := postWithIdempotencyKey(handler, "/inventory/grns/create", "shared-key", payload)
+	if anonymous.Header().Get(idempotentReplayHeader) != "" {
+		t.Fatalf("expected no replay for an unauthenticated request, got %v", anonymous.Header())
+	}
+	if calls != 3 {
+		t.Fatalf("expected the unauthenticated request to reach the route, got %d calls", calls)
+	}
+}
+
+func TestServerAPI_IdempotencyKeyRefusesOversizedBodies(t *testing.T) {
+	calls := 0
+	stub := &stubServerAPIApplication{
+		createGRNFn: func(input appInventory.CreateGRNInput) (app.GRNResult, error) {
+			calls++
+			return app.GRNResult{ID: 1}, nil
+		},
+	}
+	handler := newTestIdempotencyGuard().wrap(newTestServerAPIRouter(t, stub))
+
+	rec := postWithIdempotencyKey(handler, "/inventory/grns/create", "big", map[string]interface{}{
+		"grn_number": strings.Repeat("x", maxIdempotentRequestBytes),
+		"auth_token": "token-a",
+	})
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an oversized body, got %d", rec.Code)
+	}
+	if calls != 0 {
+		t.Fatalf("expected the oversized request not to reach the route, got %d calls", calls)
+	}
+}
+
+func TestIsIdempotentRoute(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodPost, "/inventory/lots/movements/create", true},
+		{http.MethodPost, "/inventory/conversions/convert", false},
+		{http.MethodPost, "/inventory/import", true},
+		{http.MethodPost, "/admin/create-user", true},
+		{http.MethodPost, "/inventory/items/update", false},
+		{http.MethodPost, "/inventory/items/list", false},
+		{http.MethodPost, "/api/v1/grns", true},
+		{http.MethodPost, "/api/v1/auth/login", false},
+		{http.MethodGet, "/api/v1/grns", false},
+	}
+	for _, tc := range cases {
+		if got := isIdempotentRoute(httptest.NewRequest(tc.method, tc.path, nil)); got != tc.want {
+			t.Fatalf("%s %s: expected %v, got %v", tc.method, tc.path, tc.want, got)
+		}
+	}
+}
@@ -4,52 +4,50 @@ import (
 	"bufio"
 	"context"
 	"crypto/rand"
-	_ "embed"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
 	"log/slog"
-	"masala_inventory_managment"
 	"masala_inventory_managment/internal/app"
 	appAdmin "masala_inventory_managment/internal/app/admin"
+	appAudit "masala_inventory_managment/internal/app/audit"
 	appAuth "masala_inventory_managment/internal/app/auth"
+	appExport "masala_inventory_managment/internal/app/export"
 	appInventory "masala_inventory_managment/internal/app/inventory"
+	appInvoice "masala_inventory_managment/internal/app/invoice"
+	appLabel "masala_inventory_managment/internal/app/label"
 	appLicenseMode "masala_inventory_managment/internal/app/licensemode"
 	appReport "masala_inventory_managment/internal/app/report"
 	appSys "masala_inventory_managment/internal/app/system"
+	appWebhook "masala_inventory_managment/internal/app/webhook"
+	domainAudit "masala_inventory_managment/internal/domain/audit"
 	domainAuth "masala_inventory_managment/internal/domain/auth"
 	domainBackup "masala_inventory_managment/internal/domain/backup"
 	infraAuth "masala_inventory_managment/internal/infrastructure/auth"
 	infraBackup "masala_inventory_managment/internal/infrastructure/backup"
 	"masala_inventory_managment/internal/infrastructure/db"
+	infraEvents "masala_inventory_managment/internal/infrastructure/events"
+	infraExport "masala_inventory_managment/internal/infrastructure/export"
 	"masala_inventory_managment/internal/infrastructure/license"
+	"masala_inventory_managment/internal/infrastructure/network"
 	infraSys "masala_inventory_managment/internal/infrastructure/system"
+	infraWebhook "masala_inventory_managment/internal/infrastructure/webhook"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
-
-	stdruntime "runtime"
-
-	"github.com/wailsapp/wails/v2"
-	"github.com/wailsapp/wails/v2/pkg/options"
-	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
-
-	"github.com/getlantern/systray"
 )
 
 // LicensePublicKey can be set via -ldflags "-X main.LicensePublicKey=..."
 var LicensePublicKey string
 
-//go:embed assets/icon.png
-var iconPNGData []byte
-
-//go:embed assets/icon.ico
-var iconICOData []byte
+// Version can be set via -ldflags "-X main.Version=..."; clients see it in LAN discovery.
+var Version = "dev"
 
 const (
 	envAppEnvironment            = "MASALA_APP_ENV"
@@ -57,23 +55,44 @@ const (
 	envLicensePublicKey          = "MASALA_LICENSE_PUBLIC_KEY"
 	envBootstrapAdminUsername    = "MASALA_BOOTSTRAP_ADMIN_USERNAME"
 	envBootstrapAdminPassword    = "MASALA_BOOTSTRAP_ADMIN_PASSWORD"
+	envPinTrustedClients         = "MASALA_PIN_TRUSTED_CLIENTS"
+	envPinSessionMinutes         = "MASALA_PIN_SESSION_MINUTES"
+	envCompanyName               = "MASALA_COMPANY_NAME"
+	envCompanyAddress            = "MASALA_COMPANY_ADDRESS"
+	envCompanyGSTIN              = "MASALA_COMPANY_GSTIN"
+	envCompanyPhone              = "MASALA_COMPANY_PHONE"
 	defaultBootstrapAdminUser    = "admin"
 	integrityRecoveryPrompt      = "⚠️ Database integrity issue detected. Restore from backup?"
 	missingDBRecoveryPrompt      = "No database found. Restore from latest backup?"
 	backupDiscoveryFailurePrompt = "⚠️ Recovery required, but backups could not be listed. Check backup directory permissions and retry restore."
+	hashChainRecoveryPrompt      = "⚠️ Stock ledger or audit log was modified outside the application. Restore from backup?"
 	relaunchHelperArg            = "--relaunch-helper"
+	verifyChainArg               = "--verify-chain"
+	migrateArg                   = "migrate"
+	migrationsDir                = "internal/infrastructure/db/migrations"
 	relaunchAttempts             = 12
 	envRelaunchWorkingDir        = "MASALA_RELAUNCH_WORKDIR"
 	envWatchdogIntervalSeconds   = "MASALA_WATCHDOG_INTERVAL_SECONDS"
 	envDisableWatchdogRelaunch   = "MASALA_TEST_DISABLE_WATCHDOG_RELAUNCH"
-	backgroundNotificationTitle  = "Masala Inventory is still running"
-	backgroundNotificationBody   = "The server is now in the background. Use the tray icon to reopen or exit."
 )
 
 const defaultLicensePublicKey = "ebe55ca92c5a7161a80ce7718c7567e2566a6f51fb564f191bee61cb7b29d776"
 
 type startupBackupLister interface {
-	ListBackups() ([]string, error)
+	ListBackups() ([]domainBackup.BackupInfo, error)
+}
+
+// listBackupPaths returns the archive paths offered in recovery mode, newest first.
+func listBackupPaths(backupService startupBackupLister) ([]string, error) {
+	backups, err := backupService.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(backups))
+	for _, b := range backups {
+		paths = append(paths, b.Path)
+	}
+	return paths, nil
 }
 
 type startupLicenseService interface {
@@ -198,6 +217,64 @@ func resolveWatchdogIntervalSeconds() uint32 {
 	return uint32(parsed)
 }
 
+// resolvePinTrustedClients returns the comma separated IPs/CIDRs allowed to use PIN login.
+func resolvePinTrustedClients() []string {
+	raw := strings.TrimSpace(os.Getenv(envPinTrustedClients))
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
+// resolveCompanyProfile returns the company details printed at the top of exports.
+func resolveCompanyProfile() infraExport.Company {
+	return infraExport.Company{
+		Name:    strings.TrimSpace(os.Getenv(envCompanyName)),
+		Address: strings.TrimSpace(os.Getenv(envCompanyAddress)),
+		GSTIN:   strings.TrimSpace(os.Getenv(envCompanyGSTIN)),
+		Phone:   strings.TrimSpace(os.Getenv(envCompanyPhone)),
+	}
+}
+
+// newBackupService builds the backup service for the database from the backup settings in
+// the environment.
+func newBackupService(dbManager *db.DatabaseManager) (*infraBackup.Service, error) {
+	backupConfig, err := infraBackup.ConfigFromEnv(dbManager.GetDBPath(), Version, resolveBackupHardwareID())
+	if err != nil {
+		return nil, err
+	}
+	logInfo := func(format string, v ...interface{}) {
+		slog.Info(fmt.Sprintf(format, v...), "component", "backup")
+	}
+	logError := func(format string, v ...interface{}) {
+		slog.Error(fmt.Sprintf(format, v...), "component", "backup")
+	}
+	return infraBackup.NewService(dbManager, backupConfig, logInfo, logError), nil
+}
+
+// resolveBackupHardwareID identifies this machine in backup manifests. Backups are still
+// taken when it cannot be read.
+func resolveBackupHardwareID() string {
+	hardwareID, err := license.GetHardwareID(license.NewHardwareProvider())
+	if err != nil {
+		slog.Warn("Hardware ID unavailable for backup manifests", "error", err, "component", "backup")
+		return ""
+	}
+	return hardwareID
+}
+
+func resolvePinSessionTTL() time.Duration {
+	raw := strings.TrimSpace(os.Getenv(envPinSessionMinutes))
+	if raw == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || parsed == 0 {
+		return 0
+	}
+	return time.Duration(parsed) * time.Minute
+}
+
 func resolveLicensePublicKey() string {
 	if envKey := strings.TrimSpace(os.Getenv(envLicensePublicKey)); envKey != "" {
 		return envKey
@@ -250,7 +327,7 @@ func determineRecoveryFromIntegrityCheck(integrityErr error, backupService start
 		return false, "", currentBackups, nil
 	}
 
-	backups, err := backupService.ListBackups()
+	backups, err := listBackupPaths(backupService)
 	if err != nil {
 		return true, integrityRecoveryPrompt, currentBackups, fmt.Errorf("failed to list backups for recovery mode: %w", err)
 	}
@@ -258,6 +335,25 @@ func determineRecoveryFromIntegrityCheck(integrityErr error, backupService start
 	return true, integrityRecoveryPrompt, backups, nil
 }
 
+// determineRecoveryFromHashChain enters recovery mode when the ledger/audit hash chain is broken.
+// Errors other than a chain break (e.g. the database could not be read) are returned as-is.
+func determineRecoveryFromHashChain(chainErr error, backupService startupBackupLister, currentBackups []string) (bool, string, []string, error) {
+	if chainErr == nil {
+		return false, "", currentBackups, nil
+	}
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(chainErr, &chainBreak) {
+		return false, "", currentBackups, chainErr
+	}
+
+	backups, err := listBackupPaths(backupService)
+	if err != nil {
+		return true, backupDiscoveryFailurePrompt, currentBackups, fmt.Errorf("failed to list backups for recovery mode: %w", err)
+	}
+
+	return true, hashChainRecoveryPrompt, backups, nil
+}
+
 func resolveStartupRecoveryState(dbPath string, backupService startupBackupLister, availableBackups []string, backupErr error, integrityErr error) (bool, string, []string, error) {
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 		if backupErr != nil {
@@ -367,15 +463,38 @@ func run() error {
 	if len(os.Args) > 1 && os.Args[1] == relaunchHelperArg {
 		return runRelaunchHelper(os.Args[2:])
 	}
+	if len(os.Args) > 1 && os.Args[1] == verifyChainArg {
+		return runVerifyChain(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == migrateArg {
+		return runMigrate(os.Args[2:], os.Stdout)
+	}
+
+	// Headless mode runs the API without the window and tray, e.g. as a systemd service.
+	headless := hasArg(os.Args[1:], headlessArg)
+	var headlessLogs *logFile
+	if headless {
+		logs, err := startHeadlessLogging(resolveLogFilePath())
+		if err != nil {
+			return err
+		}
+		defer logs.Close()
+		headlessLogs = logs
+	}
+	// A service manager restarts the server itself; a helper would be stopped along with it.
+	restartByExit := headless && infraSys.UnderServiceManager()
 
 	// Task 1: Single Instance Lock
 	pingFile := filepath.Join(os.TempDir(), "MasalaServerMutex.ping")
 	_ = os.Remove(pingFile) // Cleanup any stale pings from previous crashes
 	sysMonitor := infraSys.NewMonitor()
-	exists, err := sysMonitor.CheckMutex("MasalaServerMutex")
+	exists, err := sysMonitor.CheckMutex(infraSys.ServerInstanceMutex)
 	if err != nil {
 		slog.Error("Failed to check single instance mutex", "error", err)
 	}
+	if exists && headless {
+		return fmt.Errorf("another server instance is already running")
+	}
 	if exists {
 		slog.Info("Another instance is already running. Requesting focus and exiting.")
 		// We'll update FocusWindow to handle the event emission for Linux
@@ -401,6 +520,10 @@ func run() error {
 			slog.Warn("Watchdog relaunch disabled by environment; exiting for test harness")
 			os.Exit(90)
 		}
+		if restartByExit {
+			slog.Info("Exiting for the service manager to restart the server")
+			os.Exit(restartExitCode)
+		}
 		slog.Info("Attempting self-restart...")
 
 		if err := startRelaunchHelper(); err != nil {
@@ -505,13 +628,10 @@ func run() error {
 	defer appLicenseMode.SetWriteEnforcer(nil)
 
 	dbPath := "masala_inventory.db"
-	backupConfig := domainBackup.BackupConfig{
-		BackupPath:    "backups",
-		RetentionDays: 7,
-		ScheduleCron:  "0 2 * * *",
-	}
-	logInfo := func(format string, v ...interface{}) {
-		slog.Info(fmt.Sprintf(format, v...), "component", "backup")
+	dbManager := db.NewDatabaseManager(dbPath)
+	backupService, err := newBackupService(dbManager)
+	if err != nil {
+		return err
 	}
 	logError := func(format string, v ...interface{}) {
 		slog.Error(fmt.Sprintf(format, v...), "component", "backup")
@@ -520,15 +640,14 @@ func run() error {
 	var authService *appAuth.Service
 	var reportService *appReport.AppService
 	var adminService *appAdmin.Service
+	var webhookService *appWebhook.Service
 	recoveryMode := false
 	recoveryMessage := ""
 	availableBackups := []string{}
 
-	dbManager := db.NewDatabaseManager(dbPath)
-	backupService := infraBackup.NewService(dbManager, backupConfig, logInfo, logError)
 	if !lockoutMode {
 		backupErr := error(nil)
-		availableBackups, backupErr = backupService.ListBackups()
+		availableBackups, backupErr = listBackupPaths(backupService)
 		if backupErr != nil {
 			availableBackups = []string{}
 			slog.Error("Failed to list backups during startup", "error", backupErr)
@@ -569,11 +688,27 @@ func run() error {
 		}
 
 		if !recoveryMode {
-			migrator := db.NewMigrator(dbManager)
-			if err := migrator.RunMigrations(masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"); err != nil {
+			if _, err := migrateSchema(db.NewMigrator(dbManager), backupService); err != nil {
 				return fmt.Errorf("migration failed: %w", err)
 			}
 
+			if err := dbManager.SealLegacyHashChains(); err != nil {
+				return fmt.Errorf("failed to seal hash chains: %w", err)
+			}
+			if chainErr := dbManager.VerifyHashChains(); chainErr != nil {
+				startupRecoveryErr := error(nil)
+				recoveryMode, recoveryMessage, availableBackups, startupRecoveryErr = determineRecoveryFromHashChain(chainErr, backupService, availableBackups)
+				if !recoveryMode {
+					return fmt.Errorf("hash chain verification failed: %w", chainErr)
+				}
+				if startupRecoveryErr != nil {
+					slog.Error("Failed to refresh backups for recovery mode", "error", startupRecoveryErr)
+				}
+				slog.Error("Hash chain verification failed; entering recovery mode", "error", chainErr)
+			}
+		}
+
+		if !recoveryMode {
 			userRepo := db.NewSqliteUserRepository(dbManager.GetDB())
 			bcryptService := infraAuth.NewBcryptService()
 			jwtSecret, err := resolveJWTSecret()
@@ -582,6 +717,9 @@ func run() error {
 			}
 			tokenService := infraAuth.NewTokenService(jwtSecret)
 			authService = appAuth.NewService(userRepo, bcryptService, tokenService)
+			if err := authService.SetPinPolicy(resolvePinTrustedClients(), resolvePinSessionTTL()); err != nil {
+				return fmt.Errorf("invalid %s: %w", envPinTrustedClients, err)
+			}
 			application.SetAuthService(authService)
 			application.SetSessionRoleResolver(func(authToken string) (string, error) {
 				user, err := authService.CurrentUser(authToken)
@@ -592,22 +730,40 @@ func run() error {
 			})
 			reportService = appReport.NewAppService(authService)
 			adminService = appAdmin.NewService(authService, backupService, licenseSvc, logError)
+			adminService.SetHashChainVerifier(dbManager.VerifyHashChains)
 			inventoryRepo := db.NewSqliteInventoryRepository(dbManager.GetDB())
-			inventoryService := appInventory.NewService(inventoryRepo, func(authToken string) (domainAuth.Role, error) {
+			roleResolver := func(authToken string) (domainAuth.Role, error) {
 				user, err := authService.CurrentUser(authToken)
 				if err != nil {
 					return "", err
 				}
 				return user.Role, nil
-			}, func(authToken string) (string, error) {
+			}
+			subjectResolver := func(authToken string) (string, error) {
 				user, err := authService.CurrentUser(authToken)
 				if err != nil {
 					return "", err
 				}
 				return user.Username, nil
-			})
+			}
+			inventoryService := appInventory.NewService(inventoryRepo, roleResolver, subjectResolver)
+			eventBus := infraEvents.NewBus()
+			inventoryService.SetEventPublisher(eventBus)
+			application.SetEventBus(eventBus)
 			application.SetInventoryService(inventoryService)
 
+			webhookRepo := db.NewSqliteWebhookRepository(dbManager.GetDB())
+			webhookDispatcher := infraWebhook.NewDispatcher(webhookRepo)
+			inventoryService.SetEventOutbox(webhookDispatcher)
+			webhookDispatcher.Start()
+			defer webhookDispatcher.Stop()
+			webhookService = appWebhook.NewService(webhookRepo, authService, webhookDispatcher)
+			auditService := appAudit.NewService(db.NewSqliteAuditRepository(dbManager.GetDB()), authService)
+			application.SetAuditService(auditService)
+			application.SetExportService(appExport.NewService(inventoryService, auditService, reportService, resolveCompanyProfile()))
+			application.SetLabelService(appLabel.NewService(inventoryRepo, roleResolver))
+			application.SetInvoiceService(appInvoice.NewService(inventoryRepo, db.NewSqliteInvoiceRepository(dbManager.GetDB()), roleResolver, subjectResolver, resolveCompanyProfile()))
+
 			userCount, err := userRepo.Count()
 			if err != nil {
 				return fmt.Errorf("failed to count existing users: %w", err)
@@ -625,12 +781,44 @@ func run() error {
 				slog.Info("Existing users detected; bootstrap admin creation skipped", "users", userCount)
 			}
 
-			stopAuthAPIServer, err := startServerAuthAPIServer(application)
+			serverCerts := network.NewCertificateManager(filepath.Dir(dbPath))
+			if err := serverCerts.EnsureCertificate(); err != nil {
+				return fmt.Errorf("failed to prepare server certificate: %w", err)
+			}
+			slog.Info("Server API certificate ready", "fingerprint", serverCerts.Fingerprint())
+			adminService.SetServerCertificate(serverCerts.Fingerprint, func() (string, error) {
+				fingerprint, err := serverCerts.Rotate()
+				if err == nil {
+					slog.Warn("Server API certificate rotated; clients must re-pair", "fingerprint", fingerprint)
+				}
+				return fingerprint, err
+			})
+
+			stopAuthAPIServer, err := startServerAuthAPIServer(
+				application,
+				serverCerts.TLSConfig(),
+				newIdempotencyGuard(db.NewSqliteIdempotencyRepository(dbManager.GetDB()), resolveIdempotencyWindow(), subjectResolver),
+				newServerMetrics(serverMetricsSources{
+					dbPath:        dbPath,
+					backupStatus:  backupService.GetStatus,
+					listBackups:   backupService.ListBackups,
+					watchdogStats: watchdog.Stats,
+					diskFree:      func() (uint64, error) { return sysMonitor.GetDiskSpace(".") },
+					licenseStatus: licenseSvc.GetCurrentStatus,
+				}, resolveMetricsToken()),
+			)
 			if err != nil {
 				return fmt.Errorf("failed to start server auth API: %w", err)
 			}
 			defer stopAuthAPIServer()
 
+			stopDiscovery, err := startServerDiscoveryResponder(filepath.Dir(dbPath), serverCerts)
+			if err != nil {
+				slog.Warn("LAN discovery unavailable; clients must set MASALA_SERVER_PROBE_ADDR", "error", err)
+			} else {
+				defer stopDiscovery()
+			}
+
 			if err := backupService.StartScheduler(); err != nil {
 				slog.Error("Failed to start backup scheduler", "error", err, "component", "backup")
 			}
@@ -641,218 +829,57 @@ func run() error {
 	appSys.SetRecoveryMode(recoveryMode)
 	application.SetRecoveryState(recoveryMode, recoveryMessage, availableBackups)
 	application.SetLicenseLockoutState(lockoutMode, lockoutReason, lockoutMessage, lockoutHardwareID)
-	if recoveryMode {
-		application.SetRestoreHandler(func(backupPath string) error {
-			if err := backupService.Restore(backupPath); err != nil {
-				return err
-			}
+	// relaunch restarts the application once the database file has been replaced.
+	relaunch := func() error {
+		exitCode := 0
+		if restartByExit {
+			exitCode = restartExitCode
+		} else if err := startRelaunchHelper(); err != nil {
+			return err
+		}
 
-			if err := startRelaunchHelper(); err != nil {
-				return err
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			os.Exit(exitCode)
+		}()
+		return nil
+	}
+	if adminService != nil {
+		adminService.SetRelauncher(relaunch)
+	}
+	if recoveryMode {
+		relaunchAfterRestore := func(restoreErr error) error {
+			if restoreErr != nil {
+				return restoreErr
 			}
-
-			go func() {
-				time.Sleep(100 * time.Millisecond)
-				os.Exit(0)
-			}()
-			return nil
+			return relaunch()
+		}
+		application.SetRestoreHandler(func(backupPath string) error {
+			return relaunchAfterRestore(backupService.Restore(backupPath))
+		})
+		application.SetPassphraseRestoreHandler(func(backupPath, passphrase string) error {
+			return relaunchAfterRestore(backupService.RestoreWithPassphrase(backupPath, passphrase))
 		})
 	}
 
-	bindings := []interface{}{application}
-	if !recoveryMode && !lockoutMode {
-		bindings = append(bindings, authService, reportService, adminService)
-	}
-
-	// Initialize Wails Options
-	appOptions := &options.App{
-		Title:             "Masala Inventory Server",
-		Width:             1024,
-		Height:            768,
-		Frameless:         true,
-		HideWindowOnClose: false, // Route close via OnBeforeClose for explicit logging + notifications
-		AssetServer: &assetserver.Options{
-			Assets: masala_inventory_managment.Assets,
-		},
-		BackgroundColour: &options.RGBA{R: 125, G: 17, B: 17, A: 1}, // Motaba Deep Maroon
-		OnStartup: func(ctx context.Context) {
-			application.Startup(ctx)
-			monitorSvc.Start(ctx)
-			runtime.WindowMaximise(ctx)
-			restoreServerWindow := func(source string) {
-				slog.Info("Window restore requested", "source", source)
-				runtime.WindowShow(ctx)
-				runtime.WindowUnminimise(ctx)
-				runtime.WindowSetAlwaysOnTop(ctx, true)
-				go func() {
-					time.Sleep(500 * time.Millisecond)
-					runtime.WindowSetAlwaysOnTop(ctx, false)
-				}()
-				if stdruntime.GOOS == "windows" {
-					if err := sysMonitor.FocusWindow("Masala Inventory Server"); err != nil {
-						slog.Warn("Failed to focus server window", "source", source, "error", err)
-					}
-				}
-			}
-			runtime.EventsOn(ctx, "app:request-hide-to-tray", func(optionalData ...interface{}) {
-				slog.Info("UI event", "action", "request-hide-to-tray")
-				runtime.WindowHide(ctx)
-				if err := infraSys.ShowNotification(backgroundNotificationTitle, backgroundNotificationBody); err != nil {
-					slog.Error("Failed to show notification", "error", err)
-				}
-				runtime.EventsEmit(ctx, "server-minimized", nil)
-			})
-			runtime.EventsOn(ctx, "app:request-minimize", func(optionalData ...interface{}) {
-				slog.Info("UI event", "action", "request-minimize")
-				// Minimize should remain a true OS minimize (taskbar), not hide-to-tray.
-				runtime.WindowMinimise(ctx)
-			})
-
-			// Initialize System Tray
-			// Note: We run this in a goroutine because Wails requires the main thread.
-			// This works on Windows but causes SIGABRT on Linux due to GTK loop conflict.
-			if stdruntime.GOOS != "linux" {
-				go func() {
-					systray.Run(func() {
-						systray.SetTitle("Masala Server")
-						systray.SetTooltip("Masala Inventory Server")
-						if stdruntime.GOOS == "windows" && len(iconICOData) > 0 {
-							systray.SetIcon(iconICOData)
-						} else if len(iconPNGData) > 0 {
-							systray.SetIcon(iconPNGData)
-						}
-
-						mOpen := systray.AddMenuItem("Open Dashboard", "Restore the server window")
-						systray.AddSeparator()
-						mQuit := systray.AddMenuItem("Exit Server", "Shutdown the server")
-
-						// Keep onReady non-blocking; process menu events in a dedicated goroutine.
-						go func() {
-							for {
-								select {
-								case <-ctx.Done():
-									return
-								case <-mOpen.ClickedCh:
-									slog.Info("Tray action", "action", "open-dashboard")
-									restoreServerWindow("tray-open")
-									runtime.EventsEmit(ctx, "app:request-open-dashboard")
-								case <-mQuit.ClickedCh:
-									slog.Info("Tray action", "action", "exit-server")
-									restoreServerWindow("tray-exit")
-									go func() {
-										time.Sleep(120 * time.Millisecond)
-										slog.Info("Tray action", "action", "emit-custom-quit-confirm")
-										runtime.EventsEmit(ctx, "app:request-quit-confirm")
-									}()
-								}
-							}
-						}()
-					}, func() {
-						// Systray cleanup
-					})
-				}()
-			} else {
-				slog.Warn("System Tray is disabled on Linux to prevent GTK main loop conflicts with Wails.")
-			}
-
-			if stdruntime.GOOS == "windows" {
-				go func() {
-					iconPath := filepath.Join("cmd", "server", "assets", "icon.ico")
-					for attempt := 1; attempt <= 40; attempt++ {
-						if err := infraSys.SetWindowIconFromFile("Masala Inventory Server", iconPath); err == nil {
-							slog.Info("Applied Windows window icon", "path", iconPath, "attempt", attempt)
-							return
-						}
-						time.Sleep(250 * time.Millisecond)
-					}
-					slog.Warn("Failed to apply Windows window icon", "path", iconPath)
-				}()
-			}
-
-			// Background watcher for cross-process focus pings (Linux/Unix support)
-			go func() {
-				pingFile := filepath.Join(os.TempDir(), "MasalaServerMutex.ping")
-				for {
-					if _, err := os.Stat(pingFile); err == nil {
-						_ = os.Remove(pingFile)
-						restoreServerWindow("single-instance-ping")
-					}
-					time.Sleep(500 * time.Millisecond)
-				}
-			}()
-
-			go func() {
-				// Poll window state at a lower cadence; this is enough for UX notifications
-				// and reduces repeated syscall work on long-running sessions.
-				ticker := time.NewTicker(3 * time.Second)
-				defer ticker.Stop()
-
-				initialized := false
-				lastMinimized := false
-				notifiedWhileMinimized := false
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					case <-ticker.C:
-						minimized := runtime.WindowIsMinimised(ctx)
-						if stdruntime.GOOS == "windows" {
-							// In steady minimized state we've already notified; skip extra window
-							// enumeration until a potential restore transition is observed.
-							if !lastMinimized || !notifiedWhileMinimized || !minimized {
-								if winMinimized, err := infraSys.WindowIsCurrentProcessMinimized(); err == nil {
-									minimized = winMinimized
-								}
-							} else {
-								minimized = true
-							}
-						}
-						if !initialized {
-							lastMinimized = minimized
-							initialized = true
-							continue
-						}
-						if minimized && !lastMinimized {
-							notifiedWhileMinimized = true
-						}
-						if !minimized {
-							notifiedWhileMinimized = false
-						}
-						lastMinimized = minimized
-					}
-				}
-			}()
-
-		},
-		OnShutdown: func(ctx context.Context) {
-			systray.Quit()
-		},
-		OnBeforeClose: func(ctx context.Context) bool {
-			if application.IsForceQuit() {
-				slog.Info("OnBeforeClose: Force quit detected, allowing close")
-				return false // Allow close
-			}
-			slog.Info("OnBeforeClose: Window close request received (likely title bar X)")
-			slog.Info("OnBeforeClose: Hiding window and backgrounding server")
-			runtime.WindowHide(ctx)
-			// AC #1: Notification bubble on minimize
-			if err := infraSys.ShowNotification(backgroundNotificationTitle, backgroundNotificationBody); err != nil {
-				slog.Error("Failed to show notification", "error", err)
-			}
-			runtime.EventsEmit(ctx, "server-minimized", nil)
-			return true // Prevent close, just hide
-		},
-		Bind: bindings,
+	if headless {
+		if lockoutMode {
+			return fmt.Errorf("license lockout (%s): %s", lockoutReason, lockoutMessage)
+		}
+		if recoveryMode {
+			return fmt.Errorf("database needs recovery: %s; with the server stopped, restore a backup with masalactl backup restore", recoveryMessage)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return serveHeadless(ctx, monitorSvc, headlessLogs)
 	}
 
-	// Create application with options
-	err = wails.Run(appOptions)
-
-	if err != nil {
-		return fmt.Errorf("wails run error: %w", err)
+	bindings := []interface{}{application}
+	if !recoveryMode && !lockoutMode {
+		bindings = append(bindings, authService, reportService, adminService, webhookService)
 	}
 
-	return nil
+	return runDesktop(application, bindings, monitorSvc, sysMonitor)
 }
 
 func startRelaunchHelper() error {
@@ -878,6 +905,31 @@ func startRelaunchHelper() error {
 	return nil
 }
 
+// runVerifyChain walks the ledger/audit hash chain of a database file and reports the first broken link.
+// Usage: --verify-chain [path-to-db]
+func runVerifyChain(args []string) error {
+	dbPath := "masala_inventory.db"
+	if len(args) > 0 && strings.TrimSpace(args[0]) != "" {
+		dbPath = strings.TrimSpace(args[0])
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("database not found: %w", err)
+	}
+
+	dbManager := db.NewDatabaseManager(dbPath)
+	if err := dbManager.Connect(); err != nil {
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer dbManager.Close()
+
+	if err := dbManager.VerifyHashChains(); err != nil {
+		fmt.Printf("Hash chain verification FAILED for %s: %v\n", dbPath, err)
+		return err
+	}
+	fmt.Printf("Hash chain verification passed for %s\n", dbPath)
+	return nil
+}
+
 func runRelaunchHelper(forwardedArgs []string) error {
 	executable, err := os.Executable()
 	if err != nil {
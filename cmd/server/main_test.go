@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	domainBackup "masala_inventory_managment/internal/domain/backup"
 	"masala_inventory_managment/internal/infrastructure/license"
 )
 
@@ -15,11 +17,15 @@ type mockBackupLister struct {
 	err     error
 }
 
-func (m mockBackupLister) ListBackups() ([]string, error) {
+func (m mockBackupLister) ListBackups() ([]domainBackup.BackupInfo, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
-	return m.backups, nil
+	infos := make([]domainBackup.BackupInfo, 0, len(m.backups))
+	for _, path := range m.backups {
+		infos = append(infos, domainBackup.BackupInfo{Path: path})
+	}
+	return infos, nil
 }
 
 func TestResolveStartupRecoveryState_ConnectFailureWithExistingDBEntersRecovery(t *testing.T) {
@@ -120,3 +126,64 @@ func TestEvaluateStartupLicenseState_ClockTamperFailureBlocksStartup(t *testing.
 		t.Fatalf("expected tamper lockout message, got: %s", lockoutMessage)
 	}
 }
+
+// stubServerInstance stands in for the server's single-instance lock.
+func stubServerInstance(t *testing.T, running bool) {
+	t.Helper()
+	previous := lockServerInstance
+	lockServerInstance = func() (bool, error) { return running, nil }
+	t.Cleanup(func() { lockServerInstance = previous })
+}
+
+func TestRunMigrate_StatusAndDownTakeABackupFirst(t *testing.T) {
+	stubServerInstance(t, false)
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "masala_inventory.db")
+	var out bytes.Buffer
+	if err := runMigrate([]string{"--db", dbPath, "status"}, &out); err == nil {
+		t.Fatal("expected status on a missing database to fail")
+	}
+
+	if err := runMigrate([]string{"--db", dbPath, "up"}, &out); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "backups", "*.zip*")); len(backups) != 0 {
+		t.Errorf("expected no backup of a fresh database, got %v", backups)
+	}
+
+	if err := runMigrate([]string{"--db", dbPath, "down", "1"}, &out); err != nil {
+		t.Fatalf("migrate down: %v", err)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "backups", "*.zip*")); len(backups) != 1 {
+		t.Errorf("expected a backup before reverting, got %v", backups)
+	}
+
+	out.Reset()
+	if err := runMigrate([]string{"--db", dbPath, "status"}, &out); err != nil {
+		t.Fatalf("migrate status: %v", err)
+	}
+	if !strings.Contains(out.String(), "Dirty: no") || !strings.Contains(out.String(), "24 grn_created_by") {
+		t.Errorf("expected the reverted migration to be pending, got:\n%s", out.String())
+	}
+
+	for _, args := range [][]string{{"down"}, {"down", "one"}, {"sideways"}} {
+		if err := runMigrate(append([]string{"--db", dbPath}, args...), &out); err == nil {
+			t.Errorf("expected %v to be rejected", args)
+		}
+	}
+}
+
+func TestRunMigrate_RefusesWhileTheServerIsRunning(t *testing.T) {
+	stubServerInstance(t, true)
+	dbPath := filepath.Join(t.TempDir(), "masala_inventory.db")
+	var out bytes.Buffer
+	for _, args := range [][]string{{"up"}, {"down", "1"}, {"force", "3"}, {"status"}} {
+		err := runMigrate(append([]string{"--db", dbPath}, args...), &out)
+		if err == nil || !strings.Contains(err.Error(), "server is running") {
+			t.Errorf("%v: expected migrate to refuse while the server runs, got %v", args, err)
+		}
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("expected the database to be left alone, got %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	domainBackup "masala_inventory_managment/internal/domain/backup"
+	"masala_inventory_managment/internal/infrastructure/license"
+)
+
+const (
+	envMetricsToken = "MASALA_METRICS_TOKEN"
+	// metricsLicenseTTL limits how often a scrape re-reads the license, which reads the
+	// hardware ID and logs each validation.
+	metricsLicenseTTL = 5 * time.Minute
+	unmatchedRoute    = "unmatched"
+)
+
+// metricsLatencyBuckets are the upper bounds, in seconds, of the request latency histogram.
+var metricsLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var licenseStatuses = []license.LicenseStatus{license.StatusActive, license.StatusExpiring, license.StatusGracePeriod, license.StatusExpired}
+
+// serverMetricsSources are read on each scrape. Any of them may be nil.
+type serverMetricsSources struct {
+	dbPath        string
+	backupStatus  func() (*domainBackup.BackupStatus, error)
+	listBackups   func() ([]domainBackup.BackupInfo, error)
+	watchdogStats func() (time.Time, uint64)
+	diskFree      func() (uint64, error)
+	licenseStatus func() (license.StatusSnapshot, error)
+}
+
+type routeKey struct {
+	route  string
+	method string
+}
+
+type routeStats struct {
+	codes   map[int]uint64
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// serverMetrics counts requests through the API router and serves them, with the state of
+// the database, backups, watchdog, disk and license, in the Prometheus text format.
+type serverMetrics struct {
+	sources serverMetricsSources
+	token   string
+	now     func() time.Time
+
+	mu     sync.Mutex
+	routes map[routeKey]*routeStats
+
+	licenseMu        sync.Mutex
+	licenseCheckedAt time.Time
+	licenseSnapshot  license.StatusSnapshot
+	licenseErr       error
+}
+
+func newServerMetrics(sources serverMetricsSources, token string) *serverMetrics {
+	return &serverMetrics{
+		sources: sources,
+		token:   token,
+		now:     time.Now,
+		routes:  make(map[routeKey]*routeStats),
+	}
+}
+
+func resolveMetricsToken() string {
+	return strings.TrimSpace(os.Getenv(envMetricsToken))
+}
+
+// metricsRecorder remembers the status a handler wrote. It passes Flush through for the
+// event stream.
+type metricsRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *metricsRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *metricsRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *metricsRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *metricsRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// instrument records each request under the mux pattern that served it, so paths with IDs
+// in them share one series. It must wrap the mux directly: the mux sets the pattern on the
+// request it is given.
+func (m *serverMetrics) instrument(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := m.now()
+		recorder := &metricsRecorder{ResponseWriter: w}
+		defer func() {
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			// An aborted stream still counts, with the status already sent.
+			m.observe(r.Pattern, r.Method, status, m.now().Sub(started))
+		}()
+		mux.ServeHTTP(recorder, r)
+	})
+}
+
+func (m *serverMetrics) observe(pattern, method string, status int, elapsed time.Duration) {
+	route := pattern
+	if route == "" {
+		route = unmatchedRoute
+	}
+	key := routeKey{route: route, method: method}
+	seconds := elapsed.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.routes[key]
+	if !ok {
+		stats = &routeStats{codes: make(map[int]uint64), buckets: make([]uint64, len(metricsLatencyBuckets))}
+		m.routes[key] = stats
+	}
+	stats.codes[status]++
+	stats.count++
+	stats.sum += seconds
+	for i, bound := range metricsLatencyBuckets {
+		if seconds <= bound {
+			stats.buckets[i]++
+			break
+		}
+	}
+}
+
+// ServeHTTP serves /metrics. When MASALA_METRICS_TOKEN is set the scraper must send it as
+// a bearer token; without one only a scraper on the server machine itself is answered, since
+// the API listens on the LAN.
+func (m *serverMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeServerError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if m.token == "" {
+		if !isLoopbackRequest(r) {
+			writeServerError(w, http.StatusForbidden, "set MASALA_METRICS_TOKEN to scrape metrics from another machine")
+			return
+		}
+	} else if subtle.ConstantTimeCompare([]byte(bearerToken(r)), []byte(m.token)) != 1 {
+		writeServerError(w, http.StatusUnauthorized, "invalid metrics token")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := m.write(w); err != nil {
+		slog.Warn("Failed to write metrics", "error", err)
+	}
+}
+
+func isLoopbackRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// metricsWriter writes families in the Prometheus text exposition format.
+type metricsWriter struct {
+	*bufio.Writer
+}
+
+func (w metricsWriter) family(name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (w metricsWriter) sample(name string, value float64, labels ...string) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", labels[i], escapeLabelValue(labels[i+1]))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	w.WriteByte('\n')
+}
+
+func (w metricsWriter) gauge(name, help string, value float64, labels ...string) {
+	w.family(name, "gauge", help)
+	w.sample(name, value, labels...)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func (m *serverMetrics) write(out io.Writer) error {
+	w := metricsWriter{bufio.NewWriter(out)}
+	now := m.now()
+
+	w.gauge("masala_build_info", "Server version.", 1, "version", Version)
+	m.writeRequests(w)
+
+	if m.sources.dbPath != "" {
+		var dbSize, walSize int64
+		if info, err := os.Stat(m.sources.dbPath); err == nil {
+			dbSize = info.Size()
+		}
+		if info, err := os.Stat(m.sources.dbPath + "-wal"); err == nil {
+			walSize = info.Size()
+		}
+		w.gauge("masala_database_size_bytes", "Size of the database file.", float64(dbSize))
+		w.gauge("masala_database_wal_size_bytes", "Size of the database write-ahead log.", float64(walSize))
+	}
+
+	m.writeBackup(w, now)
+
+	if m.sources.watchdogStats != nil {
+		lastPing, pings := m.sources.watchdogStats()
+		w.family("masala_watchdog_pings_total", "counter", "Pings the watchdog has received from the monitor loop.")
+		w.sample("masala_watchdog_pings_total", float64(pings))
+		if !lastPing.IsZero() {
+			w.gauge("masala_watchdog_last_ping_timestamp_seconds", "When the watchdog was last pinged.", float64(lastPing.Unix()))
+		}
+	}
+
+	if m.sources.diskFree != nil {
+		if free, err := m.sources.diskFree(); err == nil {
+			w.gauge("masala_disk_free_bytes", "Free space on the disk holding the working directory.", float64(free))
+		}
+	}
+
+	m.writeLicense(w, now)
+	return w.Flush()
+}
+
+func (m *serverMetrics) writeRequests(w metricsWriter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]routeKey, 0, len(m.routes))
+	for key := range m.routes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	w.family("masala_http_requests_total", "counter", "Server API requests by route pattern, method and status code.")
+	for _, key := range keys {
+		stats := m.routes[key]
+		codes := make([]int, 0, len(stats.codes))
+		for code := range stats.codes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			w.sample("masala_http_requests_total", float64(stats.codes[code]), "route", key.route, "method", key.method, "code", strconv.Itoa(code))
+		}
+	}
+
+	w.family("masala_http_request_duration_seconds", "histogram", "Server API request latency by route pattern and method.")
+	for _, key := range keys {
+		stats := m.routes[key]
+		cumulative := uint64(0)
+		for i, bound := range metricsLatencyBuckets {
+			cumulative += stats.buckets[i]
+			w.sample("masala_http_request_duration_seconds_bucket", float64(cumulative), "route", key.route, "method", key.method, "le", strconv.FormatFloat(bound, 'g', -1, 64))
+		}
+		w.sample("masala_http_request_duration_seconds_bucket", float64(stats.count), "route", key.route, "method", key.method, "le", "+Inf")
+		w.sample("masala_http_request_duration_seconds_sum", stats.sum, "route", key.route, "method", key.method)
+		w.sample("masala_http_request_duration_seconds_count", float64(stats.count), "route", key.route, "method", key.method)
+	}
+}
+
+// writeBackup reports the last backup from GetStatus. Until the server has taken one since
+// it started, the newest archive in the backup folder stands in for it.
+func (m *serverMetrics) writeBackup(w metricsWriter, now time.Time) {
+	if m.sources.backupStatus == nil {
+		return
+	}
+	status, err := m.sources.backupStatus()
+	if err != nil || status == nil {
+		return
+	}
+	lastAt, success := status.LastBackupTime, status.Success
+	if lastAt.IsZero() && m.sources.listBackups != nil {
+		if backups, err := m.sources.listBackups(); err == nil {
+			for _, backup := range backups {
+				if backup.CreatedAt.After(lastAt) {
+					lastAt, success = backup.CreatedAt, true
+				}
+			}
+		}
+	}
+
+	w.gauge("masala_backup_running", "Whether a backup is in progress.", boolValue(status.IsRunning))
+	if !lastAt.IsZero() {
+		w.gauge("masala_backup_last_timestamp_seconds", "When the last backup finished.", float64(lastAt.Unix()))
+		w.gauge("masala_backup_age_seconds", "Time since the last backup.", now.Sub(lastAt).Seconds())
+		w.gauge("masala_backup_last_success", "Whether the last backup succeeded.", boolValue(success))
+	}
+	if !status.JournalShippedAt.IsZero() {
+		w.gauge("masala_backup_journal_shipped_timestamp_seconds", "When changes were last shipped to the backup folder.", float64(status.JournalShippedAt.Unix()))
+	}
+}
+
+func (m *serverMetrics) writeLicense(w metricsWriter, now time.Time) {
+	if m.sources.licenseStatus == nil {
+		return
+	}
+	m.licenseMu.Lock()
+	if m.licenseCheckedAt.IsZero() || now.Sub(m.licenseCheckedAt) >= metricsLicenseTTL {
+		m.licenseSnapshot, m.licenseErr = m.sources.licenseStatus()
+		m.licenseCheckedAt = now
+	}
+	snapshot, err := m.licenseSnapshot, m.licenseErr
+	m.licenseMu.Unlock()
+
+	w.gauge("masala_license_valid", "Whether the license validated; 0 for a hardware mismatch, clock tampering or a missing license.", boolValue(err == nil))
+	if err != nil {
+		return
+	}
+	w.family("masala_license_status", "gauge", "The license status, as 1 for the current one.")
+	for _, status := range licenseStatuses {
+		w.sample("masala_license_status", boolValue(snapshot.Status == status), "status", string(status))
+	}
+	if snapshot.ExpiresAt != "" {
+		w.gauge("masala_license_days_remaining", "Days until the license expires; negative in the grace period.", float64(snapshot.DaysRemaining))
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"masala_inventory_managment/internal/app"
+	domainBackup "masala_inventory_managment/internal/domain/backup"
+	"masala_inventory_managment/internal/infrastructure/license"
+)
+
+func TestServerMetrics_CountsRoutesAndReportsServerState(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "masala_inventory.db")
+	if err := os.WriteFile(dbPath, make([]byte, 4096), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dbPath+"-wal", make([]byte, 1024), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	licenseChecks := 0
+	metrics := newServerMetrics(serverMetricsSources{
+		dbPath: dbPath,
+		backupStatus: func() (*domainBackup.BackupStatus, error) {
+			return &domainBackup.BackupStatus{}, nil
+		},
+		listBackups: func() ([]domainBackup.BackupInfo, error) {
+			return []domainBackup.BackupInfo{
+				{Name: "old", CreatedAt: now.Add(-48 * time.Hour)},
+				{Name: "new", CreatedAt: now.Add(-2 * time.Hour)},
+			}, nil
+		},
+		watchdogStats: func() (time.Time, uint64) { return now.Add(-5 * time.Second), 42 },
+		diskFree:      func() (uint64, error) { return 123456789, nil },
+		licenseStatus: func() (license.StatusSnapshot, error) {
+			licenseChecks++
+			return license.StatusSnapshot{Status: license.StatusExpiring, DaysRemaining: 9, ExpiresAt: "2026-03-10"}, nil
+		},
+	}, "scrape-secret")
+	metrics.now = func() time.Time { return now }
+
+	mux := newTestServerAPIRouter(t, stubServerAPIApplication{
+		deleteUserFn: func(app.DeleteUserInput) error { return nil },
+	})
+	mux.Handle("/metrics", metrics)
+	router := metrics.instrument(mux)
+
+	for _, username := range []string{"alice", "bob"} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/"+username, nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a scrape without the token to be refused, got %d", rec.Code)
+	}
+
+	scrape := func() string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer scrape-secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("scrape failed: %d %s", rec.Code, rec.Body.String())
+		}
+		if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+			t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+		}
+		return rec.Body.String()
+	}
+	body := scrape()
+	for _, want := range []string{
+		`masala_http_requests_total{route="/api/v1/users/{username}",method="DELETE",code="204"} 2`,
+		`masala_http_requests_total{route="/health",method="GET",code="200"} 1`,
+		`masala_http_requests_total{route="/metrics",method="GET",code="401"} 1`,
+		`masala_http_request_duration_seconds_bucket{route="/api/v1/users/{username}",method="DELETE",le="0.005"} 2`,
+		`masala_http_request_duration_seconds_bucket{route="/api/v1/users/{username}",method="DELETE",le="+Inf"} 2`,
+		`masala_http_request_duration_seconds_count{route="/health",method="GET"} 1`,
+		"# TYPE masala_http_request_duration_seconds histogram",
+		"masala_database_size_bytes 4096",
+		"masala_database_wal_size_bytes 1024",
+		"masala_backup_age_seconds 7200",
+		"masala_backup_last_success 1",
+		"masala_backup_running 0",
+		"masala_watchdog_pings_total 42",
+		"masala_disk_free_bytes 1.23456789e+08",
+		`masala_license_status{status="expiring"} 1`,
+		`masala_license_status{status="active"} 0`,
+		"masala_license_days_remaining 9",
+		"masala_license_valid 1",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("expected %q in scrape:\n%s", want, body)
+		}
+	}
+
+	scrape()
+	if licenseChecks != 1 {
+		t.Errorf("expected the license status to be cached between scrapes, checked %d times", licenseChecks)
+	}
+	metrics.now = func() time.Time { return now.Add(metricsLicenseTTL) }
+	metrics.sources.licenseStatus = func() (license.StatusSnapshot, error) {
+		return license.StatusSnapshot{}, errors.New("hardware mismatch")
+	}
+	body = scrape()
+	if !strings.Contains(body, "masala_license_valid 0\n") || strings.Contains(body, "masala_license_status{") {
+		t.Errorf("expected an invalid license once the cache expired:\n%s", body)
+	}
+}
+
+func TestServerMetrics_WithoutATokenOnlyAnswersLoopbackScrapes(t *testing.T) {
+	metrics := newServerMetrics(serverMetricsSources{}, "")
+	for _, tc := range []struct {
+		remoteAddr string
+		want       int
+	}{
+		{"192.168.1.20:52000", http.StatusForbidden},
+		{"127.0.0.1:52000", http.StatusOK},
+		{"[::1]:52000", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = tc.remoteAddr
+		rec := httptest.NewRecorder()
+		metrics.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("scrape from %s: expected %d, got %d", tc.remoteAddr, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	if got := escapeLabelValue("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("unexpected escaping: %s", got)
+	}
+}
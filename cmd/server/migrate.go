@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"masala_inventory_managment"
+	infraBackup "masala_inventory_managment/internal/infrastructure/backup"
+	"masala_inventory_managment/internal/infrastructure/db"
+	infraSys "masala_inventory_managment/internal/infrastructure/system"
+)
+
+const migrateUsage = `usage: masala-server migrate [--db PATH] COMMAND
+
+Commands:
+  status          show the schema version, dirty flag and pending migrations
+  up              apply the pending migrations
+  down N          revert the last N migrations
+  force VERSION   record VERSION as the schema version after repairing a failed migration
+  dry-run         apply the pending migrations to a temporary copy of the database
+
+up and down take a backup first; restore it to undo the change.
+The server must be stopped first; migrate refuses to run while it is.`
+
+// lockServerInstance is replaced in tests, which must not contend for the real lock.
+var lockServerInstance = infraSys.LockServerInstance
+
+// migrateSchema applies the pending migrations, backing up a database that already has
+// a schema first. It returns the status from before migrating.
+func migrateSchema(migrator *db.Migrator, backupService *infraBackup.Service) (*db.MigrationStatus, error) {
+	migrator.BackupBeforeChanges(backupService.ExecuteBeforeMigration)
+	status, err := migrator.Status(masala_inventory_managment.MigrationAssets, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrator.RunMigrations(masala_inventory_managment.MigrationAssets, migrationsDir); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// runMigrate is the Admin "migrate" subcommand. It works on the database file directly,
+// so it refuses to run while the server is running.
+func runMigrate(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(migrateArg, flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() { fmt.Fprintln(out, migrateUsage) }
+	dbPath := flags.String("db", "masala_inventory.db", "database file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	command := flags.Arg(0)
+	var argument int
+	switch command {
+	case "status", "up", "dry-run":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("migrate %s takes no arguments\n%s", command, migrateUsage)
+		}
+	case "down", "force":
+		if flags.NArg() != 2 {
+			return fmt.Errorf("migrate %s needs a number\n%s", command, migrateUsage)
+		}
+		n, err := strconv.Atoi(flags.Arg(1))
+		if err != nil {
+			return fmt.Errorf("migrate %s: %q is not a number", command, flags.Arg(1))
+		}
+		argument = n
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+	running, err := lockServerInstance()
+	if err != nil {
+		return fmt.Errorf("could not check whether the server is running: %w", err)
+	}
+	if running {
+		return fmt.Errorf("the server is running; stop it before running migrate")
+	}
+	if command != "up" {
+		if _, err := os.Stat(*dbPath); err != nil {
+			return fmt.Errorf("database not found: %w", err)
+		}
+	}
+
+	dbManager := db.NewDatabaseManager(*dbPath)
+	if err := dbManager.Connect(); err != nil {
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer dbManager.Close()
+	migrator := db.NewMigrator(dbManager)
+	assets := masala_inventory_managment.MigrationAssets
+
+	switch command {
+	case "status":
+		status, err := migrator.Status(assets, migrationsDir)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(out, status)
+	case "dry-run":
+		status, err := migrator.DryRun(assets, migrationsDir)
+		if err != nil {
+			fmt.Fprintf(out, "Dry run FAILED on a copy of %s: %v\n", *dbPath, err)
+			return err
+		}
+		fmt.Fprintf(out, "Dry run passed: a copy of %s migrated to version %d. The database was not changed.\n", *dbPath, status.Version)
+	case "up":
+		backupService, err := newBackupService(dbManager)
+		if err != nil {
+			return err
+		}
+		before, err := migrateSchema(migrator, backupService)
+		if err != nil {
+			return err
+		}
+		if len(before.Pending) == 0 {
+			fmt.Fprintf(out, "Schema is up to date at version %d\n", before.Version)
+			return nil
+		}
+		fmt.Fprintf(out, "Applied %d migrations, now at version %d\n", len(before.Pending), before.Latest)
+	case "down":
+		backupService, err := newBackupService(dbManager)
+		if err != nil {
+			return err
+		}
+		migrator.BackupBeforeChanges(backupService.ExecuteBeforeMigration)
+		if err := migrator.Down(assets, migrationsDir, argument); err != nil {
+			return err
+		}
+		status, err := migrator.Status(assets, migrationsDir)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Reverted %d migrations, now at version %d\n", argument, status.Version)
+	case "force":
+		if err := migrator.Force(assets, migrationsDir, argument); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Schema version recorded as %d\n", argument)
+	}
+	return nil
+}
+
+func printMigrationStatus(out io.Writer, status *db.MigrationStatus) {
+	fmt.Fprintf(out, "Schema version: %d (latest %d)\n", status.Version, status.Latest)
+	if status.Dirty {
+		fmt.Fprintf(out, "Dirty: yes - migration %d failed part-way; repair it, then run \"migrate force VERSION\"\n", status.Version)
+	} else {
+		fmt.Fprintln(out, "Dirty: no")
+	}
+	if len(status.Pending) == 0 {
+		fmt.Fprintln(out, "Pending migrations: none")
+		return
+	}
+	fmt.Fprintln(out, "Pending migrations:")
+	for _, migration := range status.Pending {
+		fmt.Fprintf(out, "  %d %s\n", migration.Version, migration.Name)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	domainAudit "masala_inventory_managment/internal/domain/audit"
 	domainBackup "masala_inventory_managment/internal/domain/backup"
 	infraBackup "masala_inventory_managment/internal/infrastructure/backup"
 	"masala_inventory_managment/internal/infrastructure/db"
@@ -111,7 +112,7 @@ func TestStartupIntegrityFailurePath_EntersRecoveryMode(t *testing.T) {
 		func(string, ...interface{}) {},
 	)
 
-	availableBackups, backupErr := backupService.ListBackups()
+	availableBackups, backupErr := listBackupPaths(backupService)
 	if backupErr != nil {
 		t.Fatalf("unexpected backup listing error: %v", backupErr)
 	}
@@ -167,7 +168,7 @@ func TestStartupMalformedDatabasePath_EntersRecoveryMode(t *testing.T) {
 		func(string, ...interface{}) {},
 	)
 
-	availableBackups, backupErr := backupService.ListBackups()
+	availableBackups, backupErr := listBackupPaths(backupService)
 	if backupErr != nil {
 		t.Fatalf("unexpected backup listing error: %v", backupErr)
 	}
@@ -204,6 +205,38 @@ func TestStartupMalformedDatabasePath_EntersRecoveryMode(t *testing.T) {
 
 type failingBackupLister struct{}
 
-func (f *failingBackupLister) ListBackups() ([]string, error) {
+func (f *failingBackupLister) ListBackups() ([]domainBackup.BackupInfo, error) {
 	return nil, errors.New("backup listing failed")
 }
+
+func TestDetermineRecoveryFromHashChain_BreakEntersRecoveryMode(t *testing.T) {
+	backupDir := t.TempDir()
+	backupFile := filepath.Join(backupDir, "backup-2026-02-18T120000.zip")
+	if err := os.WriteFile(backupFile, []byte("dummy"), 0644); err != nil {
+		t.Fatalf("failed to create backup file: %v", err)
+	}
+
+	backupService := infraBackup.NewService(nil, domainBackup.BackupConfig{BackupPath: backupDir}, func(string, ...interface{}) {}, func(string, ...interface{}) {})
+	chainErr := &domainAudit.ChainBreak{Table: "stock_ledger", RowID: 7, Reason: "row content was modified"}
+	recoveryMode, recoveryMessage, backups, err := determineRecoveryFromHashChain(chainErr, backupService, nil)
+	if err != nil {
+		t.Fatalf("expected backup refresh to succeed, got error: %v", err)
+	}
+	if !recoveryMode || recoveryMessage != hashChainRecoveryPrompt {
+		t.Fatalf("expected hash chain recovery prompt, got mode=%v message=%s", recoveryMode, recoveryMessage)
+	}
+	if len(backups) != 1 || backups[0] != backupFile {
+		t.Fatalf("unexpected backups list: %#v", backups)
+	}
+}
+
+func TestDetermineRecoveryFromHashChain_ReadFailureIsReturned(t *testing.T) {
+	backupService := infraBackup.NewService(nil, domainBackup.BackupConfig{BackupPath: t.TempDir()}, func(string, ...interface{}) {}, func(string, ...interface{}) {})
+	recoveryMode, _, _, err := determineRecoveryFromHashChain(errors.New("database is locked"), backupService, nil)
+	if recoveryMode {
+		t.Fatalf("expected non-chain errors not to enter recovery mode")
+	}
+	if err == nil {
+		t.Fatalf("expected read failure to be returned")
+	}
+}
@@ -163,7 +163,7 @@ func runItemMasterPackagingScenario() error {
 		return fmt.Errorf("create packaging profile: %w", err)
 	}
 
-	items, err := svc.ListItems(appInventory.ListItemsInput{
+	items, _, err := svc.ListItems(appInventory.ListItemsInput{
 		ActiveOnly: true,
 		ItemType:   "PACKING_MATERIAL",
 		AuthToken:  probeAdminToken,
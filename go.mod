@@ -10,6 +10,7 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.34
 	github.com/wailsapp/wails/v2 v2.11.0
 	golang.org/x/crypto v0.35.0
+	golang.org/x/image v0.25.0
 	golang.org/x/sys v0.30.0
 	gopkg.in/toast.v1 v1.0.0-20180812000517-0a84660828b2
 )
@@ -1,7 +1,11 @@
 package admin
 
 import (
+	"errors"
+	"time"
+
 	appAuth "masala_inventory_managment/internal/app/auth"
+	domainAudit "masala_inventory_managment/internal/domain/audit"
 	domainAuth "masala_inventory_managment/internal/domain/auth"
 	"masala_inventory_managment/internal/domain/backup"
 	"masala_inventory_managment/internal/infrastructure/license"
@@ -14,12 +18,36 @@ type SystemStatus struct {
 	Backup       *backup.BackupStatus `json:"backup_status"`
 }
 
+// HashChainStatus is the outcome of verifying the ledger/audit hash chain.
+type HashChainStatus struct {
+	Intact bool   `json:"intact"`
+	Table  string `json:"table,omitempty"`
+	RowID  int64  `json:"row_id,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// BackupPassphraseResult reports how many existing archives were sealed under the new
+// passphrase.
+type BackupPassphraseResult struct {
+	Reencrypted int `json:"reencrypted"`
+}
+
+// ServerCertificateStatus identifies the TLS certificate the server API presents.
+// Clients compare Fingerprint with the one they paired with.
+type ServerCertificateStatus struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
 // Service provides admin-level application logic
 type Service struct {
-	authService    *appAuth.Service
-	backupService  backup.BackupService
-	licenseService *license.LicensingService
-	logError       func(string, ...interface{})
+	authService     *appAuth.Service
+	backupService   backup.BackupService
+	licenseService  *license.LicensingService
+	logError        func(string, ...interface{})
+	chainVerifier   func() error
+	certFingerprint func() string
+	certRotator     func() (string, error)
+	relaunch        func() error
 }
 
 // NewService creates a new admin application service
@@ -32,6 +60,78 @@ func NewService(auth *appAuth.Service, backup backup.BackupService, lic *license
 	}
 }
 
+// SetHashChainVerifier wires the function that walks the ledger/audit hash chain.
+func (s *Service) SetHashChainVerifier(verifier func() error) {
+	s.chainVerifier = verifier
+}
+
+// VerifyHashChains checks the stock ledger and audit log for rows edited outside the application.
+// Restricted to Admin role.
+func (s *Service) VerifyHashChains(token string) (*HashChainStatus, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if s.chainVerifier == nil {
+		return nil, errors.New("hash chain verification is not available")
+	}
+
+	err := s.chainVerifier()
+	if err == nil {
+		return &HashChainStatus{Intact: true}, nil
+	}
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(err, &chainBreak) {
+		return nil, err
+	}
+	return &HashChainStatus{
+		Intact: false,
+		Table:  chainBreak.Table,
+		RowID:  chainBreak.RowID,
+		Reason: chainBreak.Reason,
+	}, nil
+}
+
+// SetRelauncher wires the restart that follows replacing the database file, since the
+// running services hold connections to the file that was replaced.
+func (s *Service) SetRelauncher(relaunch func() error) {
+	s.relaunch = relaunch
+}
+
+// SetServerCertificate wires the server API certificate's fingerprint lookup and rotation.
+func (s *Service) SetServerCertificate(fingerprint func() string, rotate func() (string, error)) {
+	s.certFingerprint = fingerprint
+	s.certRotator = rotate
+}
+
+// GetServerCertificate returns the fingerprint clients should pair with.
+// Restricted to Admin role.
+func (s *Service) GetServerCertificate(token string) (*ServerCertificateStatus, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if s.certFingerprint == nil {
+		return nil, errors.New("server certificate is not available")
+	}
+	return &ServerCertificateStatus{Fingerprint: s.certFingerprint()}, nil
+}
+
+// RotateServerCertificate replaces the server API certificate. Paired clients reject the new
+// certificate until they are re-paired with the returned fingerprint.
+// Restricted to Admin role.
+func (s *Service) RotateServerCertificate(token string) (*ServerCertificateStatus, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if s.certRotator == nil {
+		return nil, errors.New("server certificate is not available")
+	}
+	fingerprint, err := s.certRotator()
+	if err != nil {
+		return nil, err
+	}
+	return &ServerCertificateStatus{Fingerprint: fingerprint}, nil
+}
+
 // GetSystemStatus returns the system status including license and backup info.
 // Restricted to Admin role.
 func (s *Service) GetSystemStatus(token string) (*SystemStatus, error) {
@@ -56,6 +156,96 @@ func (s *Service) GetSystemStatus(token string) (*SystemStatus, error) {
 	}, nil
 }
 
+// SetBackupPassphrase turns on backup encryption, or changes its passphrase when it is
+// already on; current is ignored the first time. Existing archives are re-encrypted, so
+// keep the new passphrase somewhere safe: a backup cannot be restored on another machine
+// without it.
+// Restricted to Admin role.
+func (s *Service) SetBackupPassphrase(token, current, next string) (*BackupPassphraseResult, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	count, err := s.backupService.SetEncryptionPassphrase(current, next)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupPassphraseResult{Reencrypted: count}, nil
+}
+
+// ListBackups returns the backup archives with their manifests and verification status.
+// Restricted to Admin role.
+func (s *Service) ListBackups(token string) ([]backup.BackupInfo, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.backupService.ListBackups()
+}
+
+// VerifyBackup test-restores one archive now rather than waiting for the nightly check.
+// Restricted to Admin role.
+func (s *Service) VerifyBackup(token, backupPath string) (*backup.Verification, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.backupService.Verify(backupPath)
+}
+
+// PreviewRestore compares a backup with the live database: its manifest, row counts,
+// newest GRN and stock movement, and the records that differ. Nothing is changed.
+// Restricted to Admin role.
+func (s *Service) PreviewRestore(token, backupPath string) (*backup.RestorePreview, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.backupService.PreviewRestore(backupPath)
+}
+
+// RestoreBackupEntities copies the chosen records, such as a deleted party, from a backup
+// into the live database without rolling back anything else. The restore is recorded in
+// the audit log under the caller.
+// Restricted to Admin role.
+func (s *Service) RestoreBackupEntities(token, backupPath string, entities []backup.EntityRef) error {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return err
+	}
+	user, err := s.authService.CurrentUser(token)
+	if err != nil {
+		return err
+	}
+	return s.backupService.RestoreEntities(backupPath, entities, user.Username)
+}
+
+// RestoreToTime rebuilds the database as it was at the given moment, from the newest
+// backup before it and the changes shipped since. Changes made after that moment are
+// undone, but stay in the backup folder, so a later moment can still be chosen. The
+// application restarts afterwards.
+// Restricted to Admin role.
+func (s *Service) RestoreToTime(token string, at time.Time) (*backup.PointInTimeRestore, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	result, err := s.backupService.RestoreToTime(at)
+	if err != nil {
+		return nil, err
+	}
+	if s.relaunch != nil {
+		if err := s.relaunch(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// ReplicateBackups copies archives to the secondary backup targets now, rather than
+// waiting for a failed target's next retry. Per-target results are in GetSystemStatus.
+// Restricted to Admin role.
+func (s *Service) ReplicateBackups(token string) error {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return err
+	}
+	return s.backupService.Replicate()
+}
+
 // TriggerBackup initiates a manual backup.
 // Restricted to Admin role.
 func (s *Service) TriggerBackup(token string) error {
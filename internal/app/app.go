@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -16,10 +17,16 @@ import (
 	"strings"
 	"time"
 
+	appAudit "masala_inventory_managment/internal/app/audit"
 	appAuth "masala_inventory_managment/internal/app/auth"
+	appExport "masala_inventory_managment/internal/app/export"
 	appInventory "masala_inventory_managment/internal/app/inventory"
+	appInvoice "masala_inventory_managment/internal/app/invoice"
+	appLabel "masala_inventory_managment/internal/app/label"
 	domainAuth "masala_inventory_managment/internal/domain/auth"
 	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	domainOffline "masala_inventory_managment/internal/domain/offline"
+	"masala_inventory_managment/internal/infrastructure/network"
 )
 
 type RecoveryState struct {
@@ -55,13 +62,21 @@ type App struct {
 	forceQuit             bool
 	recoveryState         RecoveryState
 	restoreHandler        func(string) error
+	passphraseRestore     func(string, string) error
 	licenseStatusProvider func() (LicenseStatus, error)
 	lockoutState          LicenseLockoutState
 	connectivityProbe     func() error
 	lockoutRetryHandler   func() (LockoutRetryResult, error)
 	inventoryService      *appInventory.Service
 	authService           *appAuth.Service
+	auditService          *appAudit.Service
+	exportService         *appExport.Service
+	invoiceService        *appInvoice.Service
+	labelService          *appLabel.Service
 	sessionRoleResolver   func(string) (string, error)
+	discovery             serverDiscovery
+	offline               offlineQueueState
+	events                eventStreamState
 }
 
 const (
@@ -70,6 +85,7 @@ const (
 	envLocalSingleMachine  = "MASALA_LOCAL_SINGLE_MACHINE_MODE"
 	serverProbeTimeout     = 1500 * time.Millisecond
 	serverAPITimeout       = 5 * time.Second
+	serverDownloadTimeout  = 30 * time.Minute
 )
 
 // NewApp creates a new App application struct
@@ -92,6 +108,9 @@ func NewApp(isServer bool) *App {
 // so we can call the runtime methods
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
+	if a.isServer {
+		a.forwardServerEvents(ctx)
+	}
 }
 
 // Context returns the app context
@@ -110,16 +129,36 @@ func (a *App) Greet(name string) (string, error) {
 // CheckServerReachability probes server reachability for client-mode connectivity status.
 // Behavior:
 //   - If MASALA_SERVER_PROBE_ADDR is set: use network probe for that target.
-//   - If not set: use local process probe by default (single-machine compatibility).
+//   - If a server was chosen via DiscoverServers/SelectServer: probe its last address, and if
+//     that fails rediscover it on the LAN by server ID and remember its new address.
+//   - Otherwise: use local process probe by default (single-machine compatibility).
 //   - Optional local-dev fallback can be enabled via MASALA_LOCAL_SINGLE_MACHINE_MODE=1
 //     to use process probing when explicit network probe fails.
+//   - Whenever the server is reachable, writes queued offline are replayed in the background.
 func (a *App) CheckServerReachability() (bool, error) {
 	if a.isServer {
 		return true, nil
 	}
+	reachable, err := a.probeServerReachability()
+	if reachable {
+		a.scheduleOfflineReplay()
+	}
+	return reachable, err
+}
+
+func (a *App) probeServerReachability() (bool, error) {
 
 	rawProbeAddr := strings.TrimSpace(os.Getenv(envServerProbeAddr))
 	if rawProbeAddr == "" {
+		if chosen, err := loadRememberedServer(); err == nil && chosen != nil {
+			if err := probeTCPAddress(chosen.Address); err == nil {
+				return true, nil
+			}
+			if address, ok := a.relocateRememberedServer(*chosen); ok {
+				return probeTCPAddress(address) == nil, nil
+			}
+			return false, nil
+		}
 		if a.connectivityProbe != nil {
 			if err := a.connectivityProbe(); err == nil {
 				return true, nil
@@ -290,6 +329,24 @@ func (a *App) RestoreBackup(backupPath string) error {
 	return a.restoreHandler(backupPath)
 }
 
+// SetPassphraseRestoreHandler configures the restore callback used by
+// RestoreBackupWithPassphrase.
+func (a *App) SetPassphraseRestoreHandler(handler func(backupPath, passphrase string) error) {
+	a.passphraseRestore = handler
+}
+
+// RestoreBackupWithPassphrase restores an encrypted backup whose key is not on this
+// machine, such as after the disk holding the database was replaced.
+func (a *App) RestoreBackupWithPassphrase(backupPath, passphrase string) error {
+	if !a.recoveryState.Enabled {
+		return fmt.Errorf("restore is only available in recovery mode")
+	}
+	if a.passphraseRestore == nil {
+		return fmt.Errorf("restore handler is not configured")
+	}
+	return a.passphraseRestore(backupPath, passphrase)
+}
+
 func (a *App) SetLicenseStatusProvider(provider func() (LicenseStatus, error)) {
 	if provider == nil {
 		a.licenseStatusProvider = func() (LicenseStatus, error) {
@@ -391,6 +448,12 @@ type ResetUserPasswordInput struct {
 	NewPassword string `json:"new_password"`
 }
 
+type SetUserPinInput struct {
+	AuthToken string `json:"auth_token"`
+	Username  string `json:"username"`
+	Pin       string `json:"pin"`
+}
+
 type DeleteUserInput struct {
 	AuthToken string `json:"auth_token"`
 	Username  string `json:"username"`
@@ -401,6 +464,7 @@ type UserAccountResult struct {
 	Username  string `json:"username"`
 	Role      string `json:"role"`
 	IsActive  bool   `json:"is_active"`
+	HasPin    bool   `json:"has_pin"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
@@ -418,7 +482,11 @@ func normalizeRole(raw string) (domainAuth.Role, error) {
 
 func (a *App) Login(username, password string) (AuthTokenResult, error) {
 	if !a.isServer && a.authService == nil {
-		return loginOverNetwork(strings.TrimSpace(username), password)
+		result, err := loginOverNetwork(strings.TrimSpace(username), password)
+		if err == nil {
+			a.rememberOfflineSession(result.Token)
+		}
+		return result, err
 	}
 	if a.authService == nil {
 		return AuthTokenResult{}, fmt.Errorf("auth service is not configured")
@@ -433,6 +501,35 @@ func (a *App) Login(username, password string) (AuthTokenResult, error) {
 	}, nil
 }
 
+// LoginWithPin opens a short operator session with a numeric PIN.
+// On the server machine the local window is treated as a loopback client.
+func (a *App) LoginWithPin(username, pin string) (AuthTokenResult, error) {
+	return a.LoginWithPinFrom(username, pin, "127.0.0.1")
+}
+
+// LoginWithPinFrom performs PIN login on behalf of the given client address.
+// The server API passes the remote address so trusted-terminal checks apply.
+func (a *App) LoginWithPinFrom(username, pin, clientAddr string) (AuthTokenResult, error) {
+	if !a.isServer && a.authService == nil {
+		result, err := loginWithPinOverNetwork(strings.TrimSpace(username), pin)
+		if err == nil {
+			a.rememberOfflineSession(result.Token)
+		}
+		return result, err
+	}
+	if a.authService == nil {
+		return AuthTokenResult{}, fmt.Errorf("auth service is not configured")
+	}
+	token, err := a.authService.LoginWithPin(strings.TrimSpace(username), pin, clientAddr)
+	if err != nil {
+		return AuthTokenResult{}, err
+	}
+	return AuthTokenResult{
+		Token:     token.Token,
+		ExpiresAt: token.ExpiresAt,
+	}, nil
+}
+
 type sessionRoleResponse struct {
 	Role string `json:"role"`
 }
@@ -458,6 +555,23 @@ func loginOverNetwork(username, password string) (AuthTokenResult, error) {
 	return result, nil
 }
 
+func loginWithPinOverNetwork(username, pin string) (AuthTokenResult, error) {
+	req := map[string]string{
+		"username": strings.TrimSpace(username),
+		"pin":      pin,
+	}
+
+	var result AuthTokenResult
+	if err := postToServerAPI("/auth/pin-login", req, &result); err != nil {
+		return AuthTokenResult{}, err
+	}
+
+	if strings.TrimSpace(result.Token) == "" {
+		return AuthTokenResult{}, fmt.Errorf("login did not return a session token")
+	}
+	return result, nil
+}
+
 func fetchSessionRoleOverNetwork(authToken string) (string, error) {
 	req := map[string]string{
 		"auth_token": strings.TrimSpace(authToken),
@@ -475,51 +589,138 @@ func fetchSessionRoleOverNetwork(authToken string) (string, error) {
 }
 
 func postToServerAPI(path string, payload interface{}, output interface{}) error {
+	body, err := postToServerAPIRaw(path, payload)
+	if err != nil {
+		return err
+	}
+
+	if output == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, output); err != nil {
+		return fmt.Errorf("failed to decode server response: %w", err)
+	}
+	return nil
+}
+
+// serverAPIStatusError is a non-2xx reply from the server API; Error returns the server's message.
+type serverAPIStatusError struct {
+	status  int
+	message string
+}
+
+func (e *serverAPIStatusError) Error() string {
+	return e.message
+}
+
+// serverUnreachableError means the request got no reply at all, so it may be retried later.
+type serverUnreachableError struct {
+	err error
+}
+
+func (e *serverUnreachableError) Error() string {
+	return "server request failed: " + e.err.Error()
+}
+
+func (e *serverUnreachableError) Unwrap() error {
+	return e.err
+}
+
+// isServerUnreachable reports transport failures. A pinned-certificate mismatch is not one:
+// the server answered, but it is not the server this client trusts.
+func isServerUnreachable(err error) bool {
+	var unreachable *serverUnreachableError
+	return errors.As(err, &unreachable) && !errors.Is(err, network.ErrFingerprintMismatch)
+}
+
+// postToServerAPIRaw posts a JSON payload and returns the raw response body.
+func postToServerAPIRaw(path string, payload interface{}) ([]byte, error) {
+	return sendServerAPIRequest(path, payload, "")
+}
+
+// sendServerAPIRequest posts a JSON payload, tagging it with an Idempotency-Key when given.
+func sendServerAPIRequest(path string, payload interface{}, idempotencyKey string) ([]byte, error) {
+	resp, err := openServerAPIResponse(path, payload, idempotencyKey, serverAPITimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server response: %w", err)
+	}
+	return responseBody, nil
+}
+
+// downloadFromServerAPI posts a JSON payload and copies the response body to w as it arrives.
+func downloadFromServerAPI(path string, payload interface{}, w io.Writer) (int64, error) {
+	resp, err := openServerAPIResponse(path, payload, "", serverDownloadTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	written, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return written, fmt.Errorf("failed to read server response: %w", err)
+	}
+	return written, nil
+}
+
+// openServerAPIResponse posts a JSON payload and returns a successful response for the caller
+// to read and close. Error replies are decoded into serverAPIStatusError.
+func openServerAPIResponse(path string, payload interface{}, idempotencyKey string, timeout time.Duration) (*http.Response, error) {
 	baseURL := resolveServerAPIBaseURL()
 	url := strings.TrimRight(baseURL, "/") + path
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to encode request: %w", err)
+		return nil, fmt.Errorf("failed to encode request: %w", err)
 	}
 
 	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to build request: %w", err)
+		return nil, fmt.Errorf("failed to build request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set(idempotencyKeyHeader, idempotencyKey)
+	}
 
-	client := &http.Client{Timeout: serverAPITimeout}
+	client, err := newServerAPIClient(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = timeout
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("server request failed: %w", err)
+		return nil, &serverUnreachableError{err: err}
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
 		var apiErr authAPIErrorResponse
 		if decodeErr := json.NewDecoder(resp.Body).Decode(&apiErr); decodeErr == nil {
 			msg := strings.TrimSpace(apiErr.Message)
 			if msg != "" {
-				return fmt.Errorf("%s", msg)
+				return nil, &serverAPIStatusError{status: resp.StatusCode, message: msg}
 			}
 		}
-		return fmt.Errorf("server request failed with status %d", resp.StatusCode)
-	}
-
-	if output == nil {
-		return nil
-	}
-	if err := json.NewDecoder(resp.Body).Decode(output); err != nil {
-		return fmt.Errorf("failed to decode server response: %w", err)
+		return nil, &serverAPIStatusError{status: resp.StatusCode, message: fmt.Sprintf("server request failed with status %d", resp.StatusCode)}
 	}
-	return nil
+	return resp, nil
 }
 
+// resolveServerAPIBaseURL defaults to HTTPS, which the server API always serves.
+// An explicit http:// address is honoured for development servers and tests.
 func resolveServerAPIBaseURL() string {
 	raw := strings.TrimSpace(os.Getenv(envServerProbeAddr))
 	if raw == "" {
-		return "http://" + defaultServerProbeAddr
+		if chosen, err := loadRememberedServer(); err == nil && chosen != nil {
+			return "https://" + chosen.Address
+		}
+		return "https://" + defaultServerProbeAddr
 	}
 
 	if strings.Contains(raw, "://") {
@@ -527,13 +728,13 @@ func resolveServerAPIBaseURL() string {
 		if err == nil && strings.TrimSpace(parsed.Host) != "" {
 			scheme := parsed.Scheme
 			if scheme == "" {
-				scheme = "http"
+				scheme = "https"
 			}
 			return scheme + "://" + strings.TrimSpace(parsed.Host)
 		}
 	}
 
-	return "http://" + raw
+	return "https://" + raw
 }
 
 func (a *App) CreateUser(input CreateUserInput) error {
@@ -579,6 +780,7 @@ func (a *App) ListUsers(input ListUsersInput) ([]UserAccountResult, error) {
 			Username:  user.Username,
 			Role:      string(user.Role),
 			IsActive:  user.IsActive,
+			HasPin:    user.HasPin(),
 			CreatedAt: user.CreatedAt.Format(time.RFC3339Nano),
 			UpdatedAt: user.UpdatedAt.Format(time.RFC3339Nano),
 		})
@@ -636,6 +838,21 @@ func (a *App) ResetUserPassword(input ResetUserPasswordInput) error {
 	)
 }
 
+func (a *App) SetUserPin(input SetUserPinInput) error {
+	if !a.isServer && a.authService == nil {
+		return postToServerAPI("/admin/users/pin", input, nil)
+	}
+	if a.authService == nil {
+		return fmt.Errorf("auth service is not configured")
+	}
+
+	return a.authService.SetUserPin(
+		strings.TrimSpace(input.AuthToken),
+		strings.TrimSpace(input.Username),
+		strings.TrimSpace(input.Pin),
+	)
+}
+
 func (a *App) DeleteUser(input DeleteUserInput) error {
 	if !a.isServer && a.authService == nil {
 		return postToServerAPI("/admin/users/delete", input, nil)
@@ -740,6 +957,7 @@ type LotStockMovementResult struct {
 	ReferenceID     string  `json:"reference_id"`
 	LotNumber       string  `json:"lot_number"`
 	Notes           string  `json:"notes"`
+	CreatedBy       string  `json:"created_by"`
 	CreatedAt       string  `json:"created_at"`
 }
 
@@ -754,6 +972,38 @@ type StockAdjustmentResult struct {
 	CreatedAt  string  `json:"created_at"`
 }
 
+// Page results carry one page of a list plus the cursor for the next page (empty on the last page)
+// and the total number of rows matching the filters.
+type ItemMasterPageResult struct {
+	Items      []ItemMasterResult `json:"items"`
+	NextCursor string             `json:"next_cursor"`
+	TotalCount int                `json:"total_count"`
+}
+
+type PartyPageResult struct {
+	Items      []PartyResult `json:"items"`
+	NextCursor string        `json:"next_cursor"`
+	TotalCount int           `json:"total_count"`
+}
+
+type MaterialLotPageResult struct {
+	Items      []MaterialLotResult `json:"items"`
+	NextCursor string              `json:"next_cursor"`
+	TotalCount int                 `json:"total_count"`
+}
+
+type LotStockMovementPageResult struct {
+	Items      []LotStockMovementResult `json:"items"`
+	NextCursor string                   `json:"next_cursor"`
+	TotalCount int                      `json:"total_count"`
+}
+
+type StockAdjustmentPageResult struct {
+	Items      []StockAdjustmentResult `json:"items"`
+	NextCursor string                  `json:"next_cursor"`
+	TotalCount int                     `json:"total_count"`
+}
+
 type UnitConversionRuleResult struct {
 	ID             int64   `json:"id"`
 	ItemID         *int64  `json:"item_id,omitempty"`
@@ -838,12 +1088,27 @@ func (a *App) ListItems(input appInventory.ListItemsInput) ([]ItemMasterResult,
 		}
 		return result, nil
 	}
+	page, err := a.ListItemsPage(input)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+func (a *App) ListItemsPage(input appInventory.ListItemsInput) (ItemMasterPageResult, error) {
+	if !a.isServer && a.inventoryService == nil {
+		var result ItemMasterPageResult
+		if err := postToServerAPI("/inventory/items/page", input, &result); err != nil {
+			return ItemMasterPageResult{}, err
+		}
+		return result, nil
+	}
 	if a.inventoryService == nil {
-		return nil, fmt.Errorf("inventory service is not configured")
+		return ItemMasterPageResult{}, fmt.Errorf("inventory service is not configured")
 	}
-	items, err := a.inventoryService.ListItems(input)
+	items, info, err := a.inventoryService.ListItems(input)
 	if err != nil {
-		return nil, err
+		return ItemMasterPageResult{}, err
 	}
 	result := make([]ItemMasterResult, 0, len(items))
 	for _, item := range items {
@@ -859,7 +1124,7 @@ func (a *App) ListItems(input appInventory.ListItemsInput) ([]ItemMasterResult,
 			UpdatedAt:    item.UpdatedAt.Format(time.RFC3339Nano),
 		})
 	}
-	return result, nil
+	return ItemMasterPageResult{Items: result, NextCursor: info.NextCursor, TotalCount: info.TotalCount}, nil
 }
 
 func (a *App) CreatePackagingProfile(input appInventory.CreatePackagingProfileInput) (PackagingProfileResult, error) {
@@ -1112,13 +1377,28 @@ func (a *App) ListParties(input appInventory.ListPartiesInput) ([]PartyResult, e
 		}
 		return result, nil
 	}
+	page, err := a.ListPartiesPage(input)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+func (a *App) ListPartiesPage(input appInventory.ListPartiesInput) (PartyPageResult, error) {
+	if !a.isServer && a.inventoryService == nil {
+		var result PartyPageResult
+		if err := postToServerAPI("/inventory/parties/page", input, &result); err != nil {
+			return PartyPageResult{}, err
+		}
+		return result, nil
+	}
 	if a.inventoryService == nil {
-		return nil, fmt.Errorf("inventory service is not configured")
+		return PartyPageResult{}, fmt.Errorf("inventory service is not configured")
 	}
 
-	parties, err := a.inventoryService.ListParties(input)
+	parties, info, err := a.inventoryService.ListParties(input)
 	if err != nil {
-		return nil, err
+		return PartyPageResult{}, err
 	}
 	result := make([]PartyResult, 0, len(parties))
 	for _, party := range parties {
@@ -1134,7 +1414,7 @@ func (a *App) ListParties(input appInventory.ListPartiesInput) ([]PartyResult, e
 			UpdatedAt:    party.UpdatedAt.Format(time.RFC3339Nano),
 		})
 	}
-	return result, nil
+	return PartyPageResult{Items: result, NextCursor: info.NextCursor, TotalCount: info.TotalCount}, nil
 }
 
 func (a *App) ListMaterialLots(input appInventory.ListMaterialLotsInput) ([]MaterialLotResult, error) {
@@ -1145,13 +1425,28 @@ func (a *App) ListMaterialLots(input appInventory.ListMaterialLotsInput) ([]Mate
 		}
 		return result, nil
 	}
+	page, err := a.ListMaterialLotsPage(input)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+func (a *App) ListMaterialLotsPage(input appInventory.ListMaterialLotsInput) (MaterialLotPageResult, error) {
+	if !a.isServer && a.inventoryService == nil {
+		var result MaterialLotPageResult
+		if err := postToServerAPI("/inventory/lots/page", input, &result); err != nil {
+			return MaterialLotPageResult{}, err
+		}
+		return result, nil
+	}
 	if a.inventoryService == nil {
-		return nil, fmt.Errorf("inventory service is not configured")
+		return MaterialLotPageResult{}, fmt.Errorf("inventory service is not configured")
 	}
 
-	lots, err := a.inventoryService.ListMaterialLots(input)
+	lots, info, err := a.inventoryService.ListMaterialLots(input)
 	if err != nil {
-		return nil, err
+		return MaterialLotPageResult{}, err
 	}
 	result := make([]MaterialLotResult, 0, len(lots))
 	for _, lot := range lots {
@@ -1170,13 +1465,13 @@ func (a *App) ListMaterialLots(input appInventory.ListMaterialLotsInput) ([]Mate
 			CreatedAt:        lot.CreatedAt.Format(time.RFC3339Nano),
 		})
 	}
-	return result, nil
+	return MaterialLotPageResult{Items: result, NextCursor: info.NextCursor, TotalCount: info.TotalCount}, nil
 }
 
 func (a *App) RecordLotStockMovement(input appInventory.RecordLotStockMovementInput) (LotStockMovementResult, error) {
 	if !a.isServer && a.inventoryService == nil {
 		var result LotStockMovementResult
-		if err := postToServerAPI("/inventory/lots/movements/create", input, &result); err != nil {
+		if err := a.postOrQueueOffline(domainOffline.OperationRecordLotMovement, input, &result); err != nil {
 			return LotStockMovementResult{}, err
 		}
 		return result, nil
@@ -1197,6 +1492,7 @@ func (a *App) RecordLotStockMovement(input appInventory.RecordLotStockMovementIn
 		ReferenceID:     movement.ReferenceID,
 		LotNumber:       movement.LotNumber,
 		Notes:           movement.Notes,
+		CreatedBy:       movement.CreatedBy,
 		CreatedAt:       movement.CreatedAt.Format(time.RFC3339Nano),
 	}, nil
 }
@@ -1209,13 +1505,28 @@ func (a *App) ListLotStockMovements(input appInventory.ListLotStockMovementsInpu
 		}
 		return result, nil
 	}
+	page, err := a.ListLotStockMovementsPage(input)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+func (a *App) ListLotStockMovementsPage(input appInventory.ListLotStockMovementsInput) (LotStockMovementPageResult, error) {
+	if !a.isServer && a.inventoryService == nil {
+		var result LotStockMovementPageResult
+		if err := postToServerAPI("/inventory/lots/movements/page", input, &result); err != nil {
+			return LotStockMovementPageResult{}, err
+		}
+		return result, nil
+	}
 	if a.inventoryService == nil {
-		return nil, fmt.Errorf("inventory service is not configured")
+		return LotStockMovementPageResult{}, fmt.Errorf("inventory service is not configured")
 	}
 
-	movements, err := a.inventoryService.ListLotStockMovements(input)
+	movements, info, err := a.inventoryService.ListLotStockMovements(input)
 	if err != nil {
-		return nil, err
+		return LotStockMovementPageResult{}, err
 	}
 	result := make([]LotStockMovementResult, 0, len(movements))
 	for _, movement := range movements {
@@ -1227,16 +1538,17 @@ func (a *App) ListLotStockMovements(input appInventory.ListLotStockMovementsInpu
 			ReferenceID:     movement.ReferenceID,
 			LotNumber:       movement.LotNumber,
 			Notes:           movement.Notes,
+			CreatedBy:       movement.CreatedBy,
 			CreatedAt:       movement.CreatedAt.Format(time.RFC3339Nano),
 		})
 	}
-	return result, nil
+	return LotStockMovementPageResult{Items: result, NextCursor: info.NextCursor, TotalCount: info.TotalCount}, nil
 }
 
 func (a *App) CreateGRN(input appInventory.CreateGRNInput) (GRNResult, error) {
 	if !a.isServer && a.inventoryService == nil {
 		var result GRNResult
-		if err := postToServerAPI("/inventory/grns/create", input, &result); err != nil {
+		if err := a.postOrQueueOffline(domainOffline.OperationCreateGRN, input, &result); err != nil {
 			return GRNResult{}, err
 		}
 		return result, nil
@@ -1272,7 +1584,7 @@ func (a *App) CreateGRN(input appInventory.CreateGRNInput) (GRNResult, error) {
 func (a *App) CreateStockAdjustment(input appInventory.CreateStockAdjustmentInput) (StockAdjustmentResult, error) {
 	if !a.isServer && a.inventoryService == nil {
 		var result StockAdjustmentResult
-		if err := postToServerAPI("/inventory/reconciliation/create", input, &result); err != nil {
+		if err := a.postOrQueueOffline(domainOffline.OperationCreateStockAdjustment, input, &result); err != nil {
 			return StockAdjustmentResult{}, err
 		}
 		return result, nil
@@ -1305,13 +1617,28 @@ func (a *App) ListStockAdjustments(input appInventory.ListStockAdjustmentsInput)
 		}
 		return result, nil
 	}
+	page, err := a.ListStockAdjustmentsPage(input)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+func (a *App) ListStockAdjustmentsPage(input appInventory.ListStockAdjustmentsInput) (StockAdjustmentPageResult, error) {
+	if !a.isServer && a.inventoryService == nil {
+		var result StockAdjustmentPageResult
+		if err := postToServerAPI("/inventory/reconciliation/page", input, &result); err != nil {
+			return StockAdjustmentPageResult{}, err
+		}
+		return result, nil
+	}
 	if a.inventoryService == nil {
-		return nil, fmt.Errorf("inventory service is not configured")
+		return StockAdjustmentPageResult{}, fmt.Errorf("inventory service is not configured")
 	}
 
-	adjustments, err := a.inventoryService.ListStockAdjustments(input)
+	adjustments, info, err := a.inventoryService.ListStockAdjustments(input)
 	if err != nil {
-		return nil, err
+		return StockAdjustmentPageResult{}, err
 	}
 	result := make([]StockAdjustmentResult, 0, len(adjustments))
 	for _, adj := range adjustments {
@@ -1326,7 +1653,7 @@ func (a *App) ListStockAdjustments(input appInventory.ListStockAdjustmentsInput)
 			CreatedAt:  adj.CreatedAt.Format(time.RFC3339Nano),
 		})
 	}
-	return result, nil
+	return StockAdjustmentPageResult{Items: result, NextCursor: info.NextCursor, TotalCount: info.TotalCount}, nil
 }
 
 func (a *App) GetItemStockBalance(input appInventory.GetItemStockBalanceInput) (float64, error) {
@@ -1343,6 +1670,26 @@ func (a *App) GetItemStockBalance(input appInventory.GetItemStockBalanceInput) (
 	return a.inventoryService.GetItemStockBalance(input)
 }
 
+// ImportMasterData validates an items, parties or opening stock CSV/XLSX file and, when
+// input.Commit is set and every row is valid, creates it all in one transaction. Admin only.
+func (a *App) ImportMasterData(input appInventory.ImportInput) (appInventory.ImportReport, error) {
+	if !a.isServer && a.inventoryService == nil {
+		var result appInventory.ImportReport
+		if err := postToServerAPI("/inventory/import", input, &result); err != nil {
+			return appInventory.ImportReport{}, err
+		}
+		return result, nil
+	}
+	if a.inventoryService == nil {
+		return appInventory.ImportReport{}, fmt.Errorf("inventory service is not configured")
+	}
+	report, err := a.inventoryService.ImportMasterData(input)
+	if err != nil {
+		return appInventory.ImportReport{}, err
+	}
+	return *report, nil
+}
+
 func (a *App) CreateUnitConversionRule(input appInventory.CreateUnitConversionRuleInput) (UnitConversionRuleResult, error) {
 	if !a.isServer && a.inventoryService == nil {
 		var result UnitConversionRuleResult
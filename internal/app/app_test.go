@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -36,6 +37,27 @@ func TestRestoreBackup_CallsHandlerInRecoveryMode(t *testing.T) {
 	}
 }
 
+func TestRestoreBackupWithPassphrase_PassesPassphraseInRecoveryMode(t *testing.T) {
+	a := NewApp(true)
+	a.SetPassphraseRestoreHandler(func(string, string) error { return nil })
+	if err := a.RestoreBackupWithPassphrase("backups/backup-1.zip.enc", "correct horse battery"); err == nil {
+		t.Fatal("expected restore to be rejected outside recovery mode")
+	}
+
+	a.SetRecoveryState(true, "recovery", []string{"backups/backup-1.zip.enc"})
+	called := false
+	a.SetPassphraseRestoreHandler(func(path, passphrase string) error {
+		called = path == "backups/backup-1.zip.enc" && passphrase == "correct horse battery"
+		return nil
+	})
+	if err := a.RestoreBackupWithPassphrase("backups/backup-1.zip.enc", "correct horse battery"); err != nil {
+		t.Fatalf("expected restore to succeed in recovery mode, got %v", err)
+	}
+	if !called {
+		t.Fatal("expected restore handler to be called with the passphrase")
+	}
+}
+
 func TestGetLicenseStatus_UsesConfiguredProvider(t *testing.T) {
 	a := NewApp(true)
 	a.SetLicenseStatusProvider(func() (LicenseStatus, error) {
@@ -294,9 +316,59 @@ func TestIsLocalSingleMachineModeEnabled(t *testing.T) {
 	}
 }
 
+func TestResolveServerAPIBaseURL_DefaultsToHTTPS(t *testing.T) {
+	t.Setenv(envServerProbeAddr, "")
+	if got := resolveServerAPIBaseURL(); got != "https://"+defaultServerProbeAddr {
+		t.Fatalf("expected https default, got %q", got)
+	}
+	t.Setenv(envServerProbeAddr, "10.0.0.5:8090")
+	if got := resolveServerAPIBaseURL(); got != "https://10.0.0.5:8090" {
+		t.Fatalf("expected bare address to use https, got %q", got)
+	}
+	t.Setenv(envServerProbeAddr, "http://10.0.0.5:8090")
+	if got := resolveServerAPIBaseURL(); got != "http://10.0.0.5:8090" {
+		t.Fatalf("expected explicit http scheme to be honoured, got %q", got)
+	}
+}
+
+func TestServerPairing_PinAndForget(t *testing.T) {
+	t.Setenv(envClientStateDir, t.TempDir())
+	t.Setenv(envServerProbeAddr, "10.0.0.5:8090")
+	application := NewApp(false)
+
+	if _, err := application.PairServer("not-a-fingerprint"); err == nil {
+		t.Fatalf("expected invalid fingerprint to be rejected")
+	}
+
+	fingerprint := strings.Repeat("ab", 32)
+	pairing, err := application.PairServer(fingerprint)
+	if err != nil {
+		t.Fatalf("PairServer failed: %v", err)
+	}
+	if !pairing.Paired || pairing.Server != "10.0.0.5:8090" || !strings.HasPrefix(pairing.Fingerprint, "AB:AB:") {
+		t.Fatalf("unexpected pairing: %+v", pairing)
+	}
+
+	if err := application.ForgetServerPairing(); err != nil {
+		t.Fatalf("ForgetServerPairing failed: %v", err)
+	}
+	pairing, err = application.GetServerPairing()
+	if err != nil || pairing.Paired {
+		t.Fatalf("expected pairing to be forgotten, got %+v (%v)", pairing, err)
+	}
+}
+
 func TestMain(m *testing.M) {
 	// Ensure tests don't inherit machine-specific probe settings.
 	_ = os.Unsetenv(envServerProbeAddr)
 	_ = os.Unsetenv(envLocalSingleMachine)
-	os.Exit(m.Run())
+	// Keep paired servers and remembered choices out of the developer's real config dir.
+	stateDir, err := os.MkdirTemp("", "masala-client-state")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv(envClientStateDir, stateDir)
+	code := m.Run()
+	_ = os.RemoveAll(stateDir)
+	os.Exit(code)
 }
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appAuth "masala_inventory_managment/internal/app/auth"
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+)
+
+const (
+	defaultListLimit = 500
+	maxListLimit     = 5000
+	maxExportRows    = 100000
+)
+
+// ListAuditLogInput filters the audit log. Dates accept RFC3339 or YYYY-MM-DD;
+// a date-only To value includes the whole day. BeforeID pages past the last entry seen.
+type ListAuditLogInput struct {
+	AuthToken  string `json:"auth_token"`
+	Actor      string `json:"actor"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	BeforeID   int64  `json:"before_id"`
+	Limit      int    `json:"limit"`
+}
+
+// Service exposes the audit log to Admin users.
+type Service struct {
+	repo        domainAudit.Repository
+	authService *appAuth.Service
+}
+
+// NewService creates a new audit application service.
+func NewService(repo domainAudit.Repository, auth *appAuth.Service) *Service {
+	return &Service{
+		repo:        repo,
+		authService: auth,
+	}
+}
+
+// ListEntries returns audit entries, newest first. Restricted to Admin.
+func (s *Service) ListEntries(input ListAuditLogInput) ([]domainAudit.Entry, error) {
+	if err := s.authService.CheckPermission(input.AuthToken, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	filter, err := buildFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultListLimit
+	case filter.Limit > maxListLimit:
+		filter.Limit = maxListLimit
+	}
+	return s.repo.List(filter)
+}
+
+// ExportCSV renders the filtered audit log as CSV. Restricted to Admin.
+func (s *Service) ExportCSV(input ListAuditLogInput) ([]byte, error) {
+	if err := s.authService.CheckPermission(input.AuthToken, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	filter, err := buildFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 || filter.Limit > maxExportRows {
+		filter.Limit = maxExportRows
+	}
+
+	entries, err := s.repo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeCSV(entries)
+}
+
+// EncodeCSV writes audit entries with a header row.
+func EncodeCSV(entries []domainAudit.Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write([]string{"id", "created_at", "actor", "action", "entity_type", "entity_id", "before_json", "after_json"}); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		record := []string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+			entry.Actor,
+			entry.Action,
+			entry.EntityType,
+			entry.EntityID,
+			entry.BeforeJSON,
+			entry.AfterJSON,
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func buildFilter(input ListAuditLogInput) (domainAudit.ListFilter, error) {
+	from, _, err := parseAuditDate(input.From)
+	if err != nil {
+		return domainAudit.ListFilter{}, fmt.Errorf("invalid from date: %w", err)
+	}
+	to, dateOnly, err := parseAuditDate(input.To)
+	if err != nil {
+		return domainAudit.ListFilter{}, fmt.Errorf("invalid to date: %w", err)
+	}
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return domainAudit.ListFilter{}, fmt.Errorf("invalid date range: from must be before to")
+	}
+
+	return domainAudit.ListFilter{
+		Actor:      strings.TrimSpace(input.Actor),
+		Action:     strings.TrimSpace(input.Action),
+		EntityType: strings.TrimSpace(input.EntityType),
+		EntityID:   strings.TrimSpace(input.EntityID),
+		From:       from,
+		To:         to,
+		BeforeID:   input.BeforeID,
+		Limit:      input.Limit,
+	}, nil
+}
+
+func parseAuditDate(raw string) (time.Time, bool, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, false, nil
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return parsed, true, nil
+}
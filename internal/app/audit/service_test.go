@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+)
+
+func TestBuildFilter_DateOnlyToIncludesWholeDay(t *testing.T) {
+	filter, err := buildFilter(ListAuditLogInput{From: "2026-03-01", To: "2026-03-31", Action: "update"})
+	if err != nil {
+		t.Fatalf("buildFilter failed: %v", err)
+	}
+	expectedTo := time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)
+	if !filter.To.Equal(expectedTo) {
+		t.Fatalf("expected exclusive end %v, got %v", expectedTo, filter.To)
+	}
+	if filter.Action != "update" {
+		t.Fatalf("expected action to pass through, got %q", filter.Action)
+	}
+
+	if _, err := buildFilter(ListAuditLogInput{From: "2026-03-31", To: "2026-03-01"}); err == nil || !strings.Contains(err.Error(), "invalid date range") {
+		t.Fatalf("expected invalid range error, got %v", err)
+	}
+	if _, err := buildFilter(ListAuditLogInput{From: "yesterday"}); err == nil || !strings.Contains(err.Error(), "invalid from date") {
+		t.Fatalf("expected invalid from date error, got %v", err)
+	}
+}
+
+func TestEncodeCSV_EscapesSnapshots(t *testing.T) {
+	content, err := EncodeCSV([]domainAudit.Entry{{
+		ID:         3,
+		Actor:      "admin",
+		Action:     domainAudit.ActionUpdate,
+		EntityType: "party",
+		EntityID:   "9",
+		BeforeJSON: `{"name":"Old, Traders"}`,
+		AfterJSON:  `{"name":"New Traders"}`,
+		CreatedAt:  time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+	}})
+	if err != nil {
+		t.Fatalf("EncodeCSV failed: %v", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse csv: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header and one row, got %d rows", len(records))
+	}
+	if records[1][6] != `{"name":"Old, Traders"}` {
+		t.Fatalf("expected before snapshot to round-trip, got %q", records[1][6])
+	}
+}
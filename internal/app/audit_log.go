@@ -0,0 +1,76 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	appAudit "masala_inventory_managment/internal/app/audit"
+)
+
+type AuditLogEntryResult struct {
+	ID         int64  `json:"id"`
+	Actor      string `json:"actor"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	BeforeJSON string `json:"before_json"`
+	AfterJSON  string `json:"after_json"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func (a *App) SetAuditService(service *appAudit.Service) {
+	a.auditService = service
+}
+
+// ListAuditLog returns filtered audit entries, newest first. Admin only.
+func (a *App) ListAuditLog(input appAudit.ListAuditLogInput) ([]AuditLogEntryResult, error) {
+	if !a.isServer && a.auditService == nil {
+		var result []AuditLogEntryResult
+		if err := postToServerAPI("/admin/audit/list", input, &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if a.auditService == nil {
+		return nil, fmt.Errorf("audit service is not configured")
+	}
+
+	entries, err := a.auditService.ListEntries(input)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]AuditLogEntryResult, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, AuditLogEntryResult{
+			ID:         entry.ID,
+			Actor:      entry.Actor,
+			Action:     entry.Action,
+			EntityType: entry.EntityType,
+			EntityID:   entry.EntityID,
+			BeforeJSON: entry.BeforeJSON,
+			AfterJSON:  entry.AfterJSON,
+			CreatedAt:  entry.CreatedAt.Format(time.RFC3339Nano),
+		})
+	}
+	return result, nil
+}
+
+// ExportAuditLogCSV returns the filtered audit log as CSV text. Admin only.
+func (a *App) ExportAuditLogCSV(input appAudit.ListAuditLogInput) (string, error) {
+	if !a.isServer && a.auditService == nil {
+		body, err := postToServerAPIRaw("/admin/audit/export", input)
+		if err != nil {
+			return "", err
+		}
+		return string(body), nil
+	}
+	if a.auditService == nil {
+		return "", fmt.Errorf("audit service is not configured")
+	}
+
+	content, err := a.auditService.ExportCSV(input)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
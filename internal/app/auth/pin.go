@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	infraAuth "masala_inventory_managment/internal/infrastructure/auth"
+)
+
+const (
+	defaultPinSessionTTL = 15 * time.Minute
+	minPinLength         = 4
+	maxPinLength         = 8
+	maxPinFailures       = 5
+	pinLockoutDuration   = 5 * time.Minute
+
+	ErrPinLoginDisabled  = "forbidden: pin login is not enabled on this server"
+	ErrPinUntrustedHost  = "forbidden: pin login is not allowed from this terminal"
+	ErrPinOperatorOnly   = "forbidden: pin login is limited to operator accounts"
+	ErrPinTemporaryBlock = "forbidden: too many failed pin attempts; try again later"
+)
+
+// pinPolicy holds the server-side PIN login configuration.
+type pinPolicy struct {
+	trustedClients []*net.IPNet
+	sessionTTL     time.Duration
+}
+
+type pinFailureState struct {
+	count       int
+	lockedUntil time.Time
+}
+
+// pinFailureTracker throttles repeated wrong PINs per username; PINs are short
+// enough that unlimited guesses would make them trivial to brute force.
+type pinFailureTracker struct {
+	mu       sync.Mutex
+	failures map[string]*pinFailureState
+}
+
+func newPinFailureTracker() *pinFailureTracker {
+	return &pinFailureTracker{failures: make(map[string]*pinFailureState)}
+}
+
+func (t *pinFailureTracker) isLocked(username string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.failures[strings.ToLower(username)]
+	return ok && now.Before(state.lockedUntil)
+}
+
+func (t *pinFailureTracker) recordFailure(username string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := strings.ToLower(username)
+	state, ok := t.failures[key]
+	if !ok {
+		state = &pinFailureState{}
+		t.failures[key] = state
+	}
+	state.count++
+	if state.count >= maxPinFailures {
+		state.count = 0
+		state.lockedUntil = now.Add(pinLockoutDuration)
+	}
+}
+
+func (t *pinFailureTracker) reset(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, strings.ToLower(username))
+}
+
+// ParseTrustedClients converts a list of IP addresses or CIDR ranges into networks.
+func ParseTrustedClients(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, raw := range entries {
+		entry := strings.TrimSpace(raw)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted client range %q: %w", entry, err)
+			}
+			networks = append(networks, network)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid trusted client address %q", entry)
+		}
+		bits := 128
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+			bits = 32
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return networks, nil
+}
+
+// SetPinPolicy configures which client machines may use PIN login and how long PIN sessions last.
+// An empty trusted client list disables PIN login entirely.
+func (s *Service) SetPinPolicy(trustedClients []string, sessionTTL time.Duration) error {
+	networks, err := ParseTrustedClients(trustedClients)
+	if err != nil {
+		return err
+	}
+	if sessionTTL <= 0 {
+		sessionTTL = defaultPinSessionTTL
+	}
+	s.pinPolicy = pinPolicy{
+		trustedClients: networks,
+		sessionTTL:     sessionTTL,
+	}
+	return nil
+}
+
+func (s *Service) isTrustedPinClient(clientAddr string) bool {
+	host := strings.TrimSpace(clientAddr)
+	if splitHost, _, err := net.SplitHostPort(host); err == nil {
+		host = splitHost
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip == nil {
+		return false
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, network := range s.pinPolicy.trustedClients {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginWithPin opens a short operator session using a numeric PIN.
+// It is only accepted from trusted client machines and only for DataEntryOperator accounts.
+func (s *Service) LoginWithPin(username, pin, clientAddr string) (*domainAuth.AuthToken, error) {
+	normalizedUsername := strings.TrimSpace(username)
+	if len(s.pinPolicy.trustedClients) == 0 {
+		return nil, errors.New(ErrPinLoginDisabled)
+	}
+	if !s.isTrustedPinClient(clientAddr) {
+		slog.Warn("Auth pin login rejected", "username", normalizedUsername, "client", clientAddr, "reason", "untrusted-client")
+		return nil, errors.New(ErrPinUntrustedHost)
+	}
+	if normalizedUsername == "" {
+		return nil, errors.New("invalid credentials")
+	}
+
+	now := time.Now()
+	if s.pinFailures.isLocked(normalizedUsername, now) {
+		slog.Warn("Auth pin login rejected", "username", normalizedUsername, "client", clientAddr, "reason", "locked-out")
+		return nil, errors.New(ErrPinTemporaryBlock)
+	}
+
+	user, err := s.userRepo.FindByUsername(normalizedUsername)
+	if err != nil {
+		slog.Error("Auth pin login lookup failed", "username", normalizedUsername, "error", err)
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil || !user.HasPin() {
+		s.pinFailures.recordFailure(normalizedUsername, now)
+		slog.Warn("Auth pin login failed", "username", normalizedUsername, "client", clientAddr, "reason", "no-pin")
+		return nil, errors.New("invalid credentials")
+	}
+
+	// The PIN is checked first and every rejection looks the same to the caller, so the
+	// endpoint does not reveal which PIN holders are disabled or not operators.
+	reason := ""
+	switch {
+	case s.bcryptService.CheckPasswordHash(pin, user.PinHash) != nil:
+		reason = "pin-mismatch"
+	case !user.IsActive:
+		reason = "account-disabled"
+	case user.Role != domainAuth.RoleDataEntryOperator:
+		reason = "not-operator"
+	}
+	if reason != "" {
+		s.pinFailures.recordFailure(normalizedUsername, now)
+		slog.Warn("Auth pin login failed", "username", normalizedUsername, "client", clientAddr, "reason", reason)
+		return nil, errors.New("invalid credentials")
+	}
+	s.pinFailures.reset(normalizedUsername)
+
+	token, err := s.tokenService.GenerateTokenWithTTL(user, s.pinPolicy.sessionTTL, infraAuth.AuthMethodPin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	slog.Info("Auth pin login succeeded", "username", normalizedUsername, "client", clientAddr)
+	return token, nil
+}
+
+// SetUserPin sets or clears (empty pin) the operator PIN of a user. Restricted to Admin.
+func (s *Service) SetUserPin(token, username, pin string) error {
+	actor, err := s.CurrentUser(token)
+	if err != nil {
+		return err
+	}
+	if actor.Role != domainAuth.RoleAdmin {
+		return errors.New("forbidden: insufficient permissions")
+	}
+
+	targetUsername := strings.TrimSpace(username)
+	if targetUsername == "" {
+		return errors.New("username is required")
+	}
+
+	target, err := s.userRepo.FindByUsername(targetUsername)
+	if err != nil {
+		return fmt.Errorf("lookup failed: %w", err)
+	}
+	if target == nil {
+		return errors.New("user not found")
+	}
+
+	pinHash := ""
+	if pin != "" {
+		if target.Role != domainAuth.RoleDataEntryOperator {
+			return errors.New(ErrPinOperatorOnly)
+		}
+		if err := validatePin(pin); err != nil {
+			return err
+		}
+		pinHash, err = s.bcryptService.HashPassword(pin)
+		if err != nil {
+			return fmt.Errorf("hashing failed: %w", err)
+		}
+	}
+
+	if err := s.userRepo.WithActor(actor.Username).UpdatePinHash(targetUsername, pinHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("failed to update pin: %w", err)
+	}
+	s.pinFailures.reset(targetUsername)
+	return nil
+}
+
+func validatePin(pin string) error {
+	if len(pin) < minPinLength || len(pin) > maxPinLength {
+		return fmt.Errorf("invalid pin: must be %d to %d digits", minPinLength, maxPinLength)
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("invalid pin: must contain digits only")
+		}
+	}
+	return nil
+}
@@ -16,6 +16,8 @@ type Service struct {
 	userRepo      domainAuth.UserRepository
 	bcryptService *infraAuth.BcryptService
 	tokenService  *infraAuth.TokenService
+	pinPolicy     pinPolicy
+	pinFailures   *pinFailureTracker
 }
 
 const (
@@ -28,6 +30,8 @@ func NewService(repo domainAuth.UserRepository, bcrypt *infraAuth.BcryptService,
 		userRepo:      repo,
 		bcryptService: bcrypt,
 		tokenService:  token,
+		pinPolicy:     pinPolicy{sessionTTL: defaultPinSessionTTL},
+		pinFailures:   newPinFailureTracker(),
 	}
 }
 
@@ -86,10 +90,16 @@ func (s *Service) CreateUser(token, username, password string, role domainAuth.R
 	}
 
 	// If users exist, enforce permission check
+	writer := s.userRepo
 	if count > 0 {
 		if err := s.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
 			return err
 		}
+		actor, err := s.CurrentUser(token)
+		if err != nil {
+			return err
+		}
+		writer = s.userRepo.WithActor(actor.Username)
 	}
 
 	existingUser, err := s.userRepo.FindByUsername(normalizedUsername)
@@ -106,7 +116,7 @@ func (s *Service) CreateUser(token, username, password string, role domainAuth.R
 	}
 
 	newUser := domainAuth.NewUser(normalizedUsername, hashedPassword, role)
-	if err := s.userRepo.Save(newUser); err != nil {
+	if err := writer.Save(newUser); err != nil {
 		return fmt.Errorf("save failed: %w", err)
 	}
 	return nil
@@ -154,7 +164,7 @@ func (s *Service) SetUserActive(token, username string, isActive bool) error {
 		}
 	}
 
-	if err := s.userRepo.SetActive(targetUsername, isActive); err != nil {
+	if err := s.userRepo.WithActor(actor.Username).SetActive(targetUsername, isActive); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return errors.New("user not found")
 		}
@@ -193,7 +203,7 @@ func (s *Service) ResetUserPassword(token, username, newPassword string) error {
 	if err != nil {
 		return fmt.Errorf("hashing failed: %w", err)
 	}
-	if err := s.userRepo.UpdatePasswordHash(targetUsername, hashedPassword); err != nil {
+	if err := s.userRepo.WithActor(actor.Username).UpdatePasswordHash(targetUsername, hashedPassword); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return errors.New("user not found")
 		}
@@ -232,7 +242,7 @@ func (s *Service) DeleteUser(token, username string) error {
 		}
 	}
 
-	if err := s.userRepo.DeleteByUsername(targetUsername); err != nil {
+	if err := s.userRepo.WithActor(actor.Username).DeleteByUsername(targetUsername); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return errors.New("user not found")
 		}
@@ -15,6 +15,10 @@ type mockUserRepo struct {
 	users map[string]*domainAuth.User
 }
 
+func (m *mockUserRepo) WithActor(actor string) domainAuth.UserRepository {
+	return m
+}
+
 func (m *mockUserRepo) Save(user *domainAuth.User) error {
 	if user.CreatedAt.IsZero() {
 		user.CreatedAt = time.Now()
@@ -81,6 +85,16 @@ func (m *mockUserRepo) UpdatePasswordHash(username, passwordHash string) error {
 	return nil
 }
 
+func (m *mockUserRepo) UpdatePinHash(username, pinHash string) error {
+	user, ok := m.users[username]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.PinHash = pinHash
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
 func (m *mockUserRepo) DeleteByUsername(username string) error {
 	if _, ok := m.users[username]; !ok {
 		return sql.ErrNoRows
@@ -309,3 +323,114 @@ func TestService_ResetPassword(t *testing.T) {
 		t.Fatalf("expected login with reset password to succeed, got %v", err)
 	}
 }
+
+func TestService_LoginWithPin(t *testing.T) {
+	bcrypt := infraAuth.NewBcryptService()
+	tokenSvc := infraAuth.NewTokenService("test-secret")
+	repo := &mockUserRepo{users: make(map[string]*domainAuth.User)}
+	svc := auth.NewService(repo, bcrypt, tokenSvc)
+
+	seedUser(t, repo, bcrypt, "admin1", "admin-pass-1", domainAuth.RoleAdmin, true)
+	seedUser(t, repo, bcrypt, "operator1", "operator-pass", domainAuth.RoleDataEntryOperator, true)
+
+	adminToken, err := tokenSvc.GenerateToken(&domainAuth.User{Username: "admin1", Role: domainAuth.RoleAdmin})
+	if err != nil {
+		t.Fatalf("failed to create admin token: %v", err)
+	}
+
+	if err := svc.SetUserPin(adminToken.Token, "operator1", "12ab"); err == nil || !strings.Contains(err.Error(), "invalid pin") {
+		t.Fatalf("expected invalid pin error, got %v", err)
+	}
+	if err := svc.SetUserPin(adminToken.Token, "admin1", "1234"); err == nil || !strings.Contains(err.Error(), "operator accounts") {
+		t.Fatalf("expected admin pin to be rejected, got %v", err)
+	}
+	if err := svc.SetUserPin(adminToken.Token, "operator1", "4821"); err != nil {
+		t.Fatalf("expected pin set success, got %v", err)
+	}
+
+	if _, err := svc.LoginWithPin("operator1", "4821", "192.168.1.20:51000"); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Fatalf("expected pin login to be disabled without trusted clients, got %v", err)
+	}
+
+	if err := svc.SetPinPolicy([]string{"192.168.1.0/24"}, 10*time.Minute); err != nil {
+		t.Fatalf("failed to set pin policy: %v", err)
+	}
+
+	if _, err := svc.LoginWithPin("operator1", "4821", "10.0.0.5:51000"); err == nil || !strings.Contains(err.Error(), "not allowed from this terminal") {
+		t.Fatalf("expected untrusted client rejection, got %v", err)
+	}
+
+	token, err := svc.LoginWithPin("operator1", "4821", "192.168.1.20:51000")
+	if err != nil {
+		t.Fatalf("expected pin login success, got %v", err)
+	}
+	if remaining := time.Until(time.Unix(token.ExpiresAt, 0)); remaining > 10*time.Minute || remaining < 9*time.Minute {
+		t.Fatalf("expected short pin session, got expiry in %v", remaining)
+	}
+	if _, err := svc.CurrentUser(token.Token); err != nil {
+		t.Fatalf("expected pin session to be valid, got %v", err)
+	}
+}
+
+func TestService_LoginWithPinDoesNotRevealAccountState(t *testing.T) {
+	bcrypt := infraAuth.NewBcryptService()
+	tokenSvc := infraAuth.NewTokenService("test-secret")
+	repo := &mockUserRepo{users: make(map[string]*domainAuth.User)}
+	svc := auth.NewService(repo, bcrypt, tokenSvc)
+
+	seedUser(t, repo, bcrypt, "disabled1", "operator-pass", domainAuth.RoleDataEntryOperator, false)
+	seedUser(t, repo, bcrypt, "admin1", "admin-pass-1", domainAuth.RoleAdmin, true)
+	pinHash, err := bcrypt.HashPassword("4821")
+	if err != nil {
+		t.Fatalf("failed to hash pin: %v", err)
+	}
+	repo.users["disabled1"].PinHash = pinHash
+	repo.users["admin1"].PinHash = pinHash
+	if err := svc.SetPinPolicy([]string{"127.0.0.1"}, 0); err != nil {
+		t.Fatalf("failed to set pin policy: %v", err)
+	}
+
+	for _, username := range []string{"disabled1", "admin1"} {
+		for _, pin := range []string{"0000", "4821"} {
+			if _, err := svc.LoginWithPin(username, pin, "127.0.0.1:40000"); err == nil || err.Error() != "invalid credentials" {
+				t.Fatalf("%s with pin %s: expected invalid credentials, got %v", username, pin, err)
+			}
+		}
+	}
+
+	// Every rejection counts toward the lockout, even with the right PIN.
+	for i := 0; i < 3; i++ {
+		svc.LoginWithPin("admin1", "4821", "127.0.0.1:40000")
+	}
+	if _, err := svc.LoginWithPin("admin1", "4821", "127.0.0.1:40000"); err == nil || !strings.Contains(err.Error(), "too many failed pin attempts") {
+		t.Fatalf("expected lockout after repeated rejections, got %v", err)
+	}
+}
+
+func TestService_LoginWithPinLocksOutAfterRepeatedFailures(t *testing.T) {
+	bcrypt := infraAuth.NewBcryptService()
+	tokenSvc := infraAuth.NewTokenService("test-secret")
+	repo := &mockUserRepo{users: make(map[string]*domainAuth.User)}
+	svc := auth.NewService(repo, bcrypt, tokenSvc)
+
+	seedUser(t, repo, bcrypt, "operator1", "operator-pass", domainAuth.RoleDataEntryOperator, true)
+	pinHash, err := bcrypt.HashPassword("4821")
+	if err != nil {
+		t.Fatalf("failed to hash pin: %v", err)
+	}
+	repo.users["operator1"].PinHash = pinHash
+
+	if err := svc.SetPinPolicy([]string{"127.0.0.1"}, 0); err != nil {
+		t.Fatalf("failed to set pin policy: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := svc.LoginWithPin("operator1", "0000", "127.0.0.1:40000"); err == nil {
+			t.Fatalf("expected wrong pin attempt %d to fail", i+1)
+		}
+	}
+
+	if _, err := svc.LoginWithPin("operator1", "4821", "127.0.0.1:40000"); err == nil || !strings.Contains(err.Error(), "too many failed pin attempts") {
+		t.Fatalf("expected lockout after repeated failures, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	infraEvents "masala_inventory_managment/internal/infrastructure/events"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	eventStreamPath = "/events"
+	// eventStreamStatusName is emitted to the frontend when the client's live stream connects
+	// or drops, so screens can show that they may be stale.
+	eventStreamStatusName  = "events:stream-status"
+	eventStreamMinBackoff  = time.Second
+	eventStreamMaxBackoff  = 30 * time.Second
+	eventStreamMaxLineSize = 1 << 20
+)
+
+type EventStreamStatus struct {
+	Connected bool   `json:"connected"`
+	Error     string `json:"error,omitempty"`
+}
+
+// eventStreamState holds the server's bus, or the client's connection to the server's /events.
+type eventStreamState struct {
+	mu     sync.Mutex
+	bus    *infraEvents.Bus
+	cancel context.CancelFunc
+	emit   func(name string, data interface{})
+}
+
+// SetEventBus connects the server to the bus its services publish on. The /events endpoint
+// and the server's own window both read from it.
+func (a *App) SetEventBus(bus *infraEvents.Bus) {
+	a.events.mu.Lock()
+	defer a.events.mu.Unlock()
+	a.events.bus = bus
+}
+
+// SubscribeEvents returns committed domain events from now on, until cancel is called.
+func (a *App) SubscribeEvents() (<-chan domainEvents.Event, func(), error) {
+	a.events.mu.Lock()
+	bus := a.events.bus
+	a.events.mu.Unlock()
+	if bus == nil {
+		return nil, nil, fmt.Errorf("event stream is not available")
+	}
+	ch, cancel := bus.Subscribe(infraEvents.DefaultSubscriberBuffer)
+	return ch, cancel, nil
+}
+
+// emitFrontendEvent forwards to the Wails runtime. Each domain event is emitted under its
+// type name, e.g. "GrnCreated".
+func (a *App) emitFrontendEvent(name string, data interface{}) {
+	a.events.mu.Lock()
+	emit := a.events.emit
+	a.events.mu.Unlock()
+	if emit != nil {
+		emit(name, data)
+		return
+	}
+	if a.ctx != nil {
+		wailsRuntime.EventsEmit(a.ctx, name, data)
+	}
+}
+
+// forwardServerEvents relays the server's own bus to its window for as long as the app runs.
+func (a *App) forwardServerEvents(ctx context.Context) {
+	events, cancel, err := a.SubscribeEvents()
+	if err != nil {
+		return
+	}
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				a.emitFrontendEvent(event.Type, event)
+			}
+		}
+	}()
+}
+
+// StartEventStream subscribes this client to the server's live changes with the signed-in
+// session and forwards them to the frontend, reconnecting until StopEventStream is called or
+// the session is rejected. Starting again replaces the previous stream, e.g. after signing in
+// as someone else. On the server the window already receives events directly.
+func (a *App) StartEventStream(authToken string) error {
+	if a.isServer {
+		return nil
+	}
+	token := strings.TrimSpace(authToken)
+	if token == "" {
+		return fmt.Errorf("auth token is required")
+	}
+
+	parent := a.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	a.events.mu.Lock()
+	if a.events.cancel != nil {
+		a.events.cancel()
+	}
+	a.events.cancel = cancel
+	a.events.mu.Unlock()
+
+	go a.runEventStream(ctx, token)
+	return nil
+}
+
+// StopEventStream ends the client's live stream, e.g. on sign out.
+func (a *App) StopEventStream() {
+	a.events.mu.Lock()
+	defer a.events.mu.Unlock()
+	if a.events.cancel != nil {
+		a.events.cancel()
+		a.events.cancel = nil
+	}
+}
+
+func (a *App) runEventStream(ctx context.Context, token string) {
+	backoff := eventStreamMinBackoff
+	for {
+		connected, err := a.readEventStream(ctx, token)
+		if ctx.Err() != nil {
+			return
+		}
+		status := EventStreamStatus{Connected: false}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		a.emitFrontendEvent(eventStreamStatusName, status)
+		var statusErr *serverAPIStatusError
+		if errors.As(err, &statusErr) && statusErr.status == http.StatusUnauthorized {
+			return
+		}
+
+		if connected {
+			backoff = eventStreamMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > eventStreamMaxBackoff {
+			backoff = eventStreamMaxBackoff
+		}
+	}
+}
+
+// readEventStream holds one /events connection open, reporting whether it got as far as
+// receiving the stream.
+func (a *App) readEventStream(ctx context.Context, token string) (bool, error) {
+	baseURL := resolveServerAPIBaseURL()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+eventStreamPath, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client, err := newServerAPIClient(baseURL)
+	if err != nil {
+		return false, err
+	}
+	// The stream stays open indefinitely; cancellation comes from ctx instead.
+	client.Timeout = 0
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr authAPIErrorResponse
+		message := fmt.Sprintf("event stream failed with status %d", resp.StatusCode)
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && strings.TrimSpace(apiErr.Message) != "" {
+			message = strings.TrimSpace(apiErr.Message)
+		}
+		return false, &serverAPIStatusError{status: resp.StatusCode, message: message}
+	}
+	a.emitFrontendEvent(eventStreamStatusName, EventStreamStatus{Connected: true})
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), eventStreamMaxLineSize)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				var event domainEvents.Event
+				if err := json.Unmarshal([]byte(data.String()), &event); err == nil && event.Type != "" {
+					a.emitFrontendEvent(event.Type, event)
+				}
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, fmt.Errorf("event stream closed by server")
+}
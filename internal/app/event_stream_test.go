@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	domainEvents "masala_inventory_managment/internal/domain/events"
+)
+
+func TestStartEventStream_ForwardsServerEventsToFrontend(t *testing.T) {
+	var gotAuth string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gotAuth = r.Header.Get("Authorization")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		fmt.Fprint(w, "id: 3\nevent: GrnCreated\ndata: {\"sequence\":3,\"type\":\"GrnCreated\",\"entity_type\":\"grn\",\"entity_id\":\"12\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	t.Setenv(envClientStateDir, t.TempDir())
+	t.Setenv(envServerProbeAddr, server.URL)
+
+	a := NewApp(false)
+	emitted := make(chan string, 8)
+	received := make(chan domainEvents.Event, 1)
+	a.events.emit = func(name string, data interface{}) {
+		emitted <- name
+		if event, ok := data.(domainEvents.Event); ok {
+			received <- event
+		}
+	}
+
+	if err := a.StartEventStream(""); err == nil {
+		t.Fatalf("expected a session to be required")
+	}
+	if err := a.StartEventStream("client-token"); err != nil {
+		t.Fatalf("StartEventStream failed: %v", err)
+	}
+	defer a.StopEventStream()
+
+	select {
+	case event := <-received:
+		if event.Type != domainEvents.TypeGrnCreated || event.EntityID != "12" {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for forwarded event")
+	}
+	if first := <-emitted; first != eventStreamStatusName {
+		t.Fatalf("expected connection status before events, got %q", first)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gotAuth != "Bearer client-token" {
+		t.Fatalf("expected session to be sent as bearer token, got %q", gotAuth)
+	}
+}
@@ -0,0 +1,552 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	appAudit "masala_inventory_managment/internal/app/audit"
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	domainReport "masala_inventory_managment/internal/domain/report"
+	infraExport "masala_inventory_managment/internal/infrastructure/export"
+)
+
+// Datasets that can be exported.
+const (
+	DatasetItems            = "items"
+	DatasetParties          = "parties"
+	DatasetLots             = "lots"
+	DatasetLotMovements     = "lot_movements"
+	DatasetStockAdjustments = "stock_adjustments"
+	DatasetStockSummary     = "stock_summary"
+	DatasetStockValuation   = "stock_valuation"
+	DatasetAuditLog         = "audit_log"
+)
+
+// exportPageSize is how many rows are read from the database at a time while streaming.
+const exportPageSize = 500
+
+// ExportInput selects a dataset, a format and the same filters the matching list screen
+// offers. Filters that do not apply to the dataset are ignored.
+type ExportInput struct {
+	Dataset    string `json:"dataset"`
+	Format     string `json:"format"`
+	Search     string `json:"search"`
+	ActiveOnly bool   `json:"active_only"`
+	ItemType   string `json:"item_type"`
+	PartyType  string `json:"party_type"`
+	ItemID     *int64 `json:"item_id,omitempty"`
+	Supplier   string `json:"supplier"`
+	LotNumber  string `json:"lot_number"`
+	SortBy     string `json:"sort_by"`
+	SortOrder  string `json:"sort_order"`
+	Actor      string `json:"actor"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	AuthToken  string `json:"auth_token"`
+}
+
+// Service renders lists and reports as files. Every row is read through the inventory, audit
+// and report services, so exports follow the same permissions as the screens they come from.
+type Service struct {
+	inventory *appInventory.Service
+	audit     *appAudit.Service
+	reports   domainReport.Service
+	company   infraExport.Company
+	pageSize  int
+	now       func() time.Time
+}
+
+func NewService(inventory *appInventory.Service, audit *appAudit.Service, reports domainReport.Service, company infraExport.Company) *Service {
+	return &Service{
+		inventory: inventory,
+		audit:     audit,
+		reports:   reports,
+		company:   company,
+		pageSize:  exportPageSize,
+		now:       time.Now,
+	}
+}
+
+// pageFunc returns the next page of rows and whether more follow.
+type pageFunc func() ([][]string, bool, error)
+
+type dataset struct {
+	title   string
+	columns []infraExport.Column
+	pages   func(s *Service, input ExportInput) pageFunc
+}
+
+var datasets = map[string]dataset{
+	DatasetItems: {
+		title: "Items",
+		columns: []infraExport.Column{
+			{Title: "SKU"}, {Title: "Name", Width: 2}, {Title: "Type"}, {Title: "Subtype"}, {Title: "Base Unit"},
+			{Title: "Minimum Stock", Numeric: true}, {Title: "Active"}, {Title: "Updated"},
+		},
+		pages: func(s *Service, input ExportInput) pageFunc {
+			return s.itemPages(input, func(item domainInventory.Item) ([]string, error) {
+				return []string{
+					item.SKU, item.Name, string(item.ItemType), item.ItemSubtype, item.BaseUnit,
+					formatQuantity(item.MinimumStock), formatBool(item.IsActive), formatTime(item.UpdatedAt),
+				}, nil
+			})
+		},
+	},
+	DatasetStockSummary: {
+		title: "Stock Summary",
+		columns: []infraExport.Column{
+			{Title: "SKU"}, {Title: "Name", Width: 2}, {Title: "Type"}, {Title: "Base Unit"},
+			{Title: "Balance", Numeric: true}, {Title: "Minimum Stock", Numeric: true}, {Title: "Below Minimum"},
+		},
+		pages: func(s *Service, input ExportInput) pageFunc {
+			return s.itemPages(input, func(item domainInventory.Item) ([]string, error) {
+				balance, err := s.inventory.GetItemStockBalance(appInventory.GetItemStockBalanceInput{ItemID: item.ID, AuthToken: input.AuthToken})
+				if err != nil {
+					return nil, err
+				}
+				return []string{
+					item.SKU, item.Name, string(item.ItemType), item.BaseUnit,
+					formatQuantity(balance), formatQuantity(item.MinimumStock), formatBool(item.MinimumStock > 0 && balance < item.MinimumStock),
+				}, nil
+			})
+		},
+	},
+	DatasetStockValuation: {
+		title: "Stock Valuation",
+		columns: []infraExport.Column{
+			{Title: "Currency"}, {Title: "Total Value", Numeric: true},
+		},
+		pages: (*Service).valuationPages,
+	},
+	DatasetParties: {
+		title: "Parties",
+		columns: []infraExport.Column{
+			{Title: "Type"}, {Title: "Name", Width: 2}, {Title: "Phone"}, {Title: "Email", Width: 1.5},
+			{Title: "Address", Width: 2.5}, {Title: "Lead Time Days", Numeric: true}, {Title: "Active"},
+		},
+		pages: (*Service).partyPages,
+	},
+	DatasetLots: {
+		title: "Material Lots",
+		columns: []infraExport.Column{
+			{Title: "Lot Number", Width: 1.5}, {Title: "GRN", Width: 1.5}, {Title: "SKU"}, {Title: "Item", Width: 1.5},
+			{Title: "Supplier", Width: 1.5}, {Title: "Source"}, {Title: "Qty Received", Numeric: true},
+			{Title: "Unit Cost", Numeric: true}, {Title: "Received"},
+		},
+		pages: (*Service).lotPages,
+	},
+	DatasetLotMovements: {
+		title: "Lot Movements",
+		columns: []infraExport.Column{
+			{Title: "Date"}, {Title: "Lot Number", Width: 1.5}, {Title: "SKU"}, {Title: "Item", Width: 1.5},
+			{Title: "Type"}, {Title: "Quantity", Numeric: true}, {Title: "Reference"}, {Title: "Notes", Width: 2}, {Title: "By"},
+		},
+		pages: (*Service).movementPages,
+	},
+	DatasetStockAdjustments: {
+		title: "Stock Adjustments",
+		columns: []infraExport.Column{
+			{Title: "Date"}, {Title: "SKU"}, {Title: "Item", Width: 1.5}, {Title: "Lot ID", Numeric: true},
+			{Title: "Qty Change", Numeric: true}, {Title: "Reason"}, {Title: "Notes", Width: 2}, {Title: "By"},
+		},
+		pages: (*Service).adjustmentPages,
+	},
+	DatasetAuditLog: {
+		title: "Audit Log",
+		columns: []infraExport.Column{
+			{Title: "Time"}, {Title: "Actor"}, {Title: "Action"}, {Title: "Entity"}, {Title: "Entity ID"},
+			{Title: "Before", Width: 3}, {Title: "After", Width: 3},
+		},
+		pages: (*Service).auditPages,
+	},
+}
+
+// Export is a prepared file. Its first page has already been read, so authorisation and
+// filter errors come back from Open before anything is written to the client.
+type Export struct {
+	FileName    string
+	ContentType string
+
+	format string
+	doc    infraExport.Document
+	first  [][]string
+	more   bool
+	next   pageFunc
+}
+
+// Open validates the request and reads the first page of the dataset.
+func (s *Service) Open(input ExportInput) (*Export, error) {
+	format, err := infraExport.NormalizeFormat(input.Format)
+	if err != nil {
+		return nil, validationError("format", err.Error())
+	}
+	name := strings.ToLower(strings.TrimSpace(input.Dataset))
+	definition, ok := datasets[name]
+	if !ok {
+		return nil, validationError("dataset", fmt.Sprintf("unknown dataset %q", input.Dataset))
+	}
+	if s.inventory == nil || (name == DatasetAuditLog && s.audit == nil) || (name == DatasetStockValuation && s.reports == nil) {
+		return nil, errors.New("export service is not configured")
+	}
+
+	next := definition.pages(s, input)
+	rows, more, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	return &Export{
+		FileName:    fmt.Sprintf("%s_%s.%s", name, now.Format("2006-01-02"), format),
+		ContentType: infraExport.ContentType(format),
+		format:      format,
+		doc: infraExport.Document{
+			Company:     s.company,
+			Title:       definition.title,
+			Subtitle:    describeFilters(name, input),
+			GeneratedAt: now,
+			Columns:     definition.columns,
+		},
+		first: rows,
+		more:  more,
+		next:  next,
+	}, nil
+}
+
+// Write streams the whole dataset to w, one page at a time. An error part-way through
+// leaves w holding a truncated file.
+func (e *Export) Write(w io.Writer) error {
+	writer, err := infraExport.NewWriter(e.format, w, e.doc)
+	if err != nil {
+		return err
+	}
+	rows, more := e.first, e.more
+	for {
+		for _, row := range rows {
+			if err := writer.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		if !more {
+			break
+		}
+		if rows, more, err = e.next(); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func (s *Service) itemPages(input ExportInput, row func(domainInventory.Item) ([]string, error)) pageFunc {
+	cursor := ""
+	return func() ([][]string, bool, error) {
+		items, info, err := s.inventory.ListItems(appInventory.ListItemsInput{
+			ActiveOnly: input.ActiveOnly,
+			ItemType:   input.ItemType,
+			Search:     input.Search,
+			Limit:      s.pageSize,
+			Cursor:     cursor,
+			SortBy:     input.SortBy,
+			SortOrder:  input.SortOrder,
+			AuthToken:  input.AuthToken,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		rows := make([][]string, 0, len(items))
+		for _, item := range items {
+			cells, err := row(item)
+			if err != nil {
+				return nil, false, err
+			}
+			rows = append(rows, cells)
+		}
+		cursor = info.NextCursor
+		return rows, cursor != "", nil
+	}
+}
+
+func (s *Service) partyPages(input ExportInput) pageFunc {
+	cursor := ""
+	return func() ([][]string, bool, error) {
+		parties, info, err := s.inventory.ListParties(appInventory.ListPartiesInput{
+			ActiveOnly: input.ActiveOnly,
+			PartyType:  input.PartyType,
+			Search:     input.Search,
+			Limit:      s.pageSize,
+			Cursor:     cursor,
+			SortBy:     input.SortBy,
+			SortOrder:  input.SortOrder,
+			AuthToken:  input.AuthToken,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		rows := make([][]string, 0, len(parties))
+		for _, party := range parties {
+			leadTime := ""
+			if party.LeadTimeDays != nil {
+				leadTime = strconv.Itoa(*party.LeadTimeDays)
+			}
+			rows = append(rows, []string{
+				string(party.PartyType), party.Name, party.Phone, party.Email, party.Address, leadTime, formatBool(party.IsActive),
+			})
+		}
+		cursor = info.NextCursor
+		return rows, cursor != "", nil
+	}
+}
+
+func (s *Service) lotPages(input ExportInput) pageFunc {
+	cursor := ""
+	items := s.newItemIndex(input.AuthToken)
+	return func() ([][]string, bool, error) {
+		lots, info, err := s.inventory.ListMaterialLots(appInventory.ListMaterialLotsInput{
+			ItemID:     input.ItemID,
+			Supplier:   input.Supplier,
+			LotNumber:  input.LotNumber,
+			ActiveOnly: input.ActiveOnly,
+			Search:     input.Search,
+			Limit:      s.pageSize,
+			Cursor:     cursor,
+			SortBy:     input.SortBy,
+			SortOrder:  input.SortOrder,
+			AuthToken:  input.AuthToken,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		rows := make([][]string, 0, len(lots))
+		for _, lot := range lots {
+			item, err := items.get(lot.ItemID)
+			if err != nil {
+				return nil, false, err
+			}
+			rows = append(rows, []string{
+				lot.LotNumber, lot.GRNNumber, item.SKU, item.Name, lot.SupplierName, lot.SourceType,
+				formatQuantity(lot.QuantityReceived), formatQuantity(lot.UnitCost), formatTime(lot.CreatedAt),
+			})
+		}
+		cursor = info.NextCursor
+		return rows, cursor != "", nil
+	}
+}
+
+func (s *Service) movementPages(input ExportInput) pageFunc {
+	cursor := ""
+	items := s.newItemIndex(input.AuthToken)
+	return func() ([][]string, bool, error) {
+		movements, info, err := s.inventory.ListLotStockMovements(appInventory.ListLotStockMovementsInput{
+			LotNumber: input.LotNumber,
+			Limit:     s.pageSize,
+			Cursor:    cursor,
+			SortBy:    input.SortBy,
+			SortOrder: input.SortOrder,
+			AuthToken: input.AuthToken,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		rows := make([][]string, 0, len(movements))
+		for _, movement := range movements {
+			item, err := items.get(movement.ItemID)
+			if err != nil {
+				return nil, false, err
+			}
+			rows = append(rows, []string{
+				formatTime(movement.CreatedAt), movement.LotNumber, item.SKU, item.Name, movement.TransactionType,
+				formatQuantity(movement.Quantity), movement.ReferenceID, movement.Notes, movement.CreatedBy,
+			})
+		}
+		cursor = info.NextCursor
+		return rows, cursor != "", nil
+	}
+}
+
+func (s *Service) adjustmentPages(input ExportInput) pageFunc {
+	cursor := ""
+	items := s.newItemIndex(input.AuthToken)
+	itemID := int64(0)
+	if input.ItemID != nil {
+		itemID = *input.ItemID
+	}
+	return func() ([][]string, bool, error) {
+		adjustments, info, err := s.inventory.ListStockAdjustments(appInventory.ListStockAdjustmentsInput{
+			ItemID:    itemID,
+			Limit:     s.pageSize,
+			Cursor:    cursor,
+			SortBy:    input.SortBy,
+			SortOrder: input.SortOrder,
+			AuthToken: input.AuthToken,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		rows := make([][]string, 0, len(adjustments))
+		for _, adjustment := range adjustments {
+			item, err := items.get(adjustment.ItemID)
+			if err != nil {
+				return nil, false, err
+			}
+			lotID := ""
+			if adjustment.LotID != nil {
+				lotID = strconv.FormatInt(*adjustment.LotID, 10)
+			}
+			rows = append(rows, []string{
+				formatTime(adjustment.CreatedAt), item.SKU, item.Name, lotID, formatQuantity(adjustment.QtyDelta),
+				adjustment.ReasonCode, adjustment.Notes, adjustment.CreatedBy,
+			})
+		}
+		cursor = info.NextCursor
+		return rows, cursor != "", nil
+	}
+}
+
+// valuationPages reads the stock valuation report, which is restricted to Admins.
+func (s *Service) valuationPages(input ExportInput) pageFunc {
+	return func() ([][]string, bool, error) {
+		valuation, err := s.reports.GetValuation(input.AuthToken)
+		if err != nil {
+			return nil, false, err
+		}
+		return [][]string{{valuation.Currency, strconv.FormatFloat(valuation.TotalValue, 'f', 2, 64)}}, false, nil
+	}
+}
+
+// auditPages walks the audit log newest first, continuing after the last entry of each page.
+func (s *Service) auditPages(input ExportInput) pageFunc {
+	beforeID := int64(0)
+	return func() ([][]string, bool, error) {
+		entries, err := s.audit.ListEntries(appAudit.ListAuditLogInput{
+			AuthToken:  input.AuthToken,
+			Actor:      input.Actor,
+			Action:     input.Action,
+			EntityType: input.EntityType,
+			From:       input.From,
+			To:         input.To,
+			BeforeID:   beforeID,
+			Limit:      s.pageSize,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		rows := make([][]string, 0, len(entries))
+		for _, entry := range entries {
+			rows = append(rows, auditRow(entry))
+		}
+		if len(entries) > 0 {
+			beforeID = entries[len(entries)-1].ID
+		}
+		return rows, len(entries) == s.pageSize, nil
+	}
+}
+
+func auditRow(entry domainAudit.Entry) []string {
+	return []string{
+		formatTime(entry.CreatedAt), entry.Actor, entry.Action, entry.EntityType, entry.EntityID, entry.BeforeJSON, entry.AfterJSON,
+	}
+}
+
+// itemIndex resolves item IDs to SKU and name for ledgers that only store the ID. Items are
+// master data, so they are read once, a page at a time, on first use.
+type itemIndex struct {
+	service *Service
+	token   string
+	items   map[int64]domainInventory.Item
+}
+
+func (s *Service) newItemIndex(token string) *itemIndex {
+	return &itemIndex{service: s, token: token}
+}
+
+func (x *itemIndex) get(id int64) (domainInventory.Item, error) {
+	if x.items == nil {
+		items := make(map[int64]domainInventory.Item)
+		cursor := ""
+		for {
+			page, info, err := x.service.inventory.ListItems(appInventory.ListItemsInput{Limit: x.service.pageSize, Cursor: cursor, AuthToken: x.token})
+			if err != nil {
+				return domainInventory.Item{}, err
+			}
+			for _, item := range page {
+				items[item.ID] = item
+			}
+			if cursor = info.NextCursor; cursor == "" {
+				break
+			}
+		}
+		x.items = items
+	}
+	return x.items[id], nil
+}
+
+// describeFilters lists the filters that narrowed the export, for the document header.
+func describeFilters(dataset string, input ExportInput) string {
+	filters := make([]string, 0, 8)
+	add := func(label, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			filters = append(filters, label+": "+value)
+		}
+	}
+	switch dataset {
+	case DatasetItems, DatasetStockSummary:
+		add("Type", input.ItemType)
+		add("Search", input.Search)
+	case DatasetParties:
+		add("Type", input.PartyType)
+		add("Search", input.Search)
+	case DatasetLots:
+		add("Supplier", input.Supplier)
+		add("Lot", input.LotNumber)
+		add("Search", input.Search)
+	case DatasetLotMovements:
+		add("Lot", input.LotNumber)
+	case DatasetAuditLog:
+		add("Actor", input.Actor)
+		add("Action", input.Action)
+		add("Entity", input.EntityType)
+		add("From", input.From)
+		add("To", input.To)
+	}
+	if input.ItemID != nil && (dataset == DatasetLots || dataset == DatasetStockAdjustments) {
+		add("Item ID", strconv.FormatInt(*input.ItemID, 10))
+	}
+	if input.ActiveOnly && dataset != DatasetLotMovements && dataset != DatasetStockAdjustments && dataset != DatasetAuditLog && dataset != DatasetStockValuation {
+		filters = append(filters, "Active only")
+	}
+	return strings.Join(filters, "; ")
+}
+
+func validationError(field, message string) error {
+	return &appInventory.ServiceError{
+		Code:    "validation_failed",
+		Message: message,
+		Fields:  []appInventory.FieldError{{Field: field, Message: message}},
+	}
+}
+
+func formatQuantity(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func formatBool(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
+
+func formatTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,230 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	masala_inventory_managment "masala_inventory_managment"
+	appAuth "masala_inventory_managment/internal/app/auth"
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	appLicenseMode "masala_inventory_managment/internal/app/licensemode"
+	appReport "masala_inventory_managment/internal/app/report"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	infraAuth "masala_inventory_managment/internal/infrastructure/auth"
+	"masala_inventory_managment/internal/infrastructure/db"
+	infraExport "masala_inventory_managment/internal/infrastructure/export"
+)
+
+const exportTestTokenSecret = "export-test-secret"
+
+func setupExportService(t *testing.T) (*Service, *db.SqliteInventoryRepository) {
+	t.Helper()
+	appLicenseMode.SetWriteEnforcer(nil)
+
+	manager := db.NewDatabaseManager(filepath.Join(t.TempDir(), "export_test.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("failed to connect to db: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+	if err := db.NewMigrator(manager).RunMigrations(masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	repo := db.NewSqliteInventoryRepository(manager.GetDB())
+	inventory := appInventory.NewService(repo, func(token string) (domainAuth.Role, error) {
+		switch token {
+		case "admin-token":
+			return domainAuth.RoleAdmin, nil
+		case "operator-token":
+			return domainAuth.RoleDataEntryOperator, nil
+		default:
+			return "", errors.New("invalid token")
+		}
+	}, nil)
+
+	// The valuation report checks roles through the auth service, against real user rows.
+	users := db.NewSqliteUserRepository(manager.GetDB())
+	for _, user := range []*domainAuth.User{
+		{Username: "admin", PasswordHash: "x", Role: domainAuth.RoleAdmin, IsActive: true},
+		{Username: "operator", PasswordHash: "x", Role: domainAuth.RoleDataEntryOperator, IsActive: true},
+	} {
+		if err := users.Save(user); err != nil {
+			t.Fatalf("failed to seed user %s: %v", user.Username, err)
+		}
+	}
+	reports := appReport.NewAppService(appAuth.NewService(users, infraAuth.NewBcryptService(), infraAuth.NewTokenService(exportTestTokenSecret)))
+
+	svc := NewService(inventory, nil, reports, infraExport.Company{Name: "Shree Masala Works", GSTIN: "27ABCDE1234F1Z5"})
+	svc.now = func() time.Time { return time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local) }
+	return svc, repo
+}
+
+func seedStock(t *testing.T, repo *db.SqliteInventoryRepository) {
+	t.Helper()
+	master := &domainInventory.MasterDataImport{
+		CreatedBy: "admin",
+		Items: []domainInventory.Item{
+			{SKU: "CUM-1", Name: "Cumin", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", MinimumStock: 100, IsActive: true},
+			{SKU: "COR-1", Name: "Coriander", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true},
+			{SKU: "CHL-1", Name: "Chilli", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true},
+			{SKU: "PCH-1", Name: "Pouch", ItemType: domainInventory.ItemTypePackingMaterial, BaseUnit: "pcs", IsActive: true},
+			{SKU: "OLD-1", Name: "Retired Blend", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: false},
+		},
+		Parties: []domainInventory.Party{
+			{PartyType: domainInventory.PartyTypeSupplier, Name: "Hill Farms", Phone: "98450", Address: "Unjha, Gujarat", IsActive: true},
+		},
+	}
+	if err := repo.ImportMasterData(master); err != nil {
+		t.Fatalf("failed to seed master data: %v", err)
+	}
+	stock := &domainInventory.MasterDataImport{
+		CreatedBy: "admin",
+		OpeningStock: []domainInventory.OpeningStockLine{
+			{ItemID: master.Items[0].ID, SupplierID: master.Parties[0].ID, Quantity: 40, UnitCost: 180},
+		},
+	}
+	if err := repo.ImportMasterData(stock); err != nil {
+		t.Fatalf("failed to seed opening stock: %v", err)
+	}
+}
+
+func TestService_ExportStreamsEveryPage(t *testing.T) {
+	svc, repo := setupExportService(t)
+	svc.pageSize = 2
+	seedStock(t, repo)
+
+	export, err := svc.Open(ExportInput{Dataset: "items", Format: "CSV", ActiveOnly: true, SortBy: "sku", AuthToken: "operator-token"})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if export.FileName != "items_2026-10-18.csv" || export.ContentType != "text/csv; charset=utf-8" {
+		t.Fatalf("unexpected file: %s (%s)", export.FileName, export.ContentType)
+	}
+	var buf bytes.Buffer
+	if err := export.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	content := buf.String()
+	for _, want := range []string{"Shree Masala Works\n", "GSTIN: 27ABCDE1234F1Z5\n", "Items\n", "Active only\n", "SKU,Name,Type"} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected export to contain %q:\n%s", want, content)
+		}
+	}
+	order := []string{"CHL-1,", "COR-1,", "CUM-1,", "PCH-1,"}
+	last := -1
+	for _, sku := range order {
+		index := strings.Index(content, sku)
+		if index < 0 || index < last {
+			t.Fatalf("expected %s after the previous page, got:\n%s", sku, content)
+		}
+		last = index
+	}
+	if strings.Contains(content, "OLD-1") {
+		t.Fatalf("expected the inactive item to be filtered out:\n%s", content)
+	}
+}
+
+func TestService_ExportResolvesItemsAndBalances(t *testing.T) {
+	svc, repo := setupExportService(t)
+	seedStock(t, repo)
+
+	lots := writeExport(t, svc, ExportInput{Dataset: "lots", Format: "csv", AuthToken: "admin-token"})
+	if !strings.Contains(lots, ",CUM-1,Cumin,Hill Farms,OPENING_BALANCE,40,180,") {
+		t.Fatalf("expected the lot row to carry item and supplier names:\n%s", lots)
+	}
+
+	summary := writeExport(t, svc, ExportInput{Dataset: "stock_summary", Format: "csv", ItemType: "RAW", ActiveOnly: true, AuthToken: "admin-token"})
+	if !strings.Contains(summary, "Type: RAW; Active only\n") {
+		t.Fatalf("expected the filters in the header:\n%s", summary)
+	}
+	if !strings.Contains(summary, "CUM-1,Cumin,RAW,kg,40,100,Yes\n") || !strings.Contains(summary, "COR-1,Coriander,RAW,kg,0,0,No\n") {
+		t.Fatalf("unexpected stock summary:\n%s", summary)
+	}
+}
+
+func TestService_ExportStockSummaryCountsDispatchedStock(t *testing.T) {
+	svc, repo := setupExportService(t)
+	seedStock(t, repo)
+
+	lots, _, err := repo.ListMaterialLots(domainInventory.MaterialLotListFilter{})
+	if err != nil || len(lots) != 1 {
+		t.Fatalf("expected the opening stock lot, got %+v (%v)", lots, err)
+	}
+	if err := repo.RecordLotStockMovement(&domainInventory.StockLedgerMovement{LotNumber: lots[0].LotNumber, TransactionType: "OUT", Quantity: 15, ReferenceID: "DSP-1"}); err != nil {
+		t.Fatalf("RecordLotStockMovement failed: %v", err)
+	}
+
+	summary := writeExport(t, svc, ExportInput{Dataset: "stock_summary", Format: "csv", ItemType: "RAW", ActiveOnly: true, AuthToken: "admin-token"})
+	if !strings.Contains(summary, "CUM-1,Cumin,RAW,kg,25,100,Yes\n") {
+		t.Fatalf("expected the dispatched quantity to leave the balance:\n%s", summary)
+	}
+}
+
+func TestService_ExportStockValuationIsAdminOnly(t *testing.T) {
+	svc, _ := setupExportService(t)
+	tokens := infraAuth.NewTokenService(exportTestTokenSecret)
+	token := func(username string, role domainAuth.Role) string {
+		t.Helper()
+		issued, err := tokens.GenerateToken(&domainAuth.User{Username: username, Role: role})
+		if err != nil {
+			t.Fatalf("GenerateToken failed: %v", err)
+		}
+		return issued.Token
+	}
+
+	valuation := writeExport(t, svc, ExportInput{Dataset: "stock_valuation", Format: "csv", ActiveOnly: true, AuthToken: token("admin", domainAuth.RoleAdmin)})
+	if !strings.Contains(valuation, "Stock Valuation\n") || !strings.Contains(valuation, "Currency,Total Value\n") || !strings.Contains(valuation, "INR,125000.50\n") {
+		t.Fatalf("unexpected stock valuation:\n%s", valuation)
+	}
+	if strings.Contains(valuation, "Active only") {
+		t.Fatalf("expected no list filters in the valuation header:\n%s", valuation)
+	}
+
+	if _, err := svc.Open(ExportInput{Dataset: "stock_valuation", Format: "pdf", AuthToken: token("operator", domainAuth.RoleDataEntryOperator)}); err == nil || !strings.HasPrefix(err.Error(), "forbidden") {
+		t.Fatalf("expected an operator to be refused the valuation, got %v", err)
+	}
+}
+
+func TestService_ExportRejectsBeforeWritingAnything(t *testing.T) {
+	svc, _ := setupExportService(t)
+
+	cases := []struct {
+		name  string
+		input ExportInput
+		code  string
+	}{
+		{name: "unknown dataset", input: ExportInput{Dataset: "recipes", AuthToken: "admin-token"}, code: "validation_failed"},
+		{name: "unknown format", input: ExportInput{Dataset: "items", Format: "ods", AuthToken: "admin-token"}, code: "validation_failed"},
+		{name: "signed out", input: ExportInput{Dataset: "items", Format: "pdf", AuthToken: "expired"}, code: "unauthorized"},
+	}
+	for _, tc := range cases {
+		_, err := svc.Open(tc.input)
+		var serviceErr *appInventory.ServiceError
+		if !errors.As(err, &serviceErr) || serviceErr.Code != tc.code {
+			t.Fatalf("%s: expected %s, got %v", tc.name, tc.code, err)
+		}
+	}
+
+	if _, err := svc.Open(ExportInput{Dataset: "audit_log", AuthToken: "admin-token"}); err == nil {
+		t.Fatalf("expected the audit log export to need the audit service")
+	}
+}
+
+func writeExport(t *testing.T, svc *Service, input ExportInput) string {
+	t.Helper()
+	export, err := svc.Open(input)
+	if err != nil {
+		t.Fatalf("Open(%s) failed: %v", input.Dataset, err)
+	}
+	var buf bytes.Buffer
+	if err := export.Write(&buf); err != nil {
+		t.Fatalf("Write(%s) failed: %v", input.Dataset, err)
+	}
+	return buf.String()
+}
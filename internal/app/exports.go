@@ -0,0 +1,114 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appExport "masala_inventory_managment/internal/app/export"
+	infraExport "masala_inventory_managment/internal/infrastructure/export"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ExportToFileInput saves an export on this machine. When Path is empty a save dialog asks
+// where to put it.
+type ExportToFileInput struct {
+	Export appExport.ExportInput `json:"export"`
+	Path   string                `json:"path"`
+}
+
+// ExportFileResult describes a saved export. Path is empty when the save dialog was cancelled.
+type ExportFileResult struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+func (a *App) SetExportService(service *appExport.Service) {
+	a.exportService = service
+}
+
+// OpenExport prepares an export for the server API to stream to a client. Server only.
+func (a *App) OpenExport(input appExport.ExportInput) (*appExport.Export, error) {
+	if a.exportService == nil {
+		return nil, fmt.Errorf("export service is not configured")
+	}
+	return a.exportService.Open(input)
+}
+
+// ExportToFile renders a list or report as CSV, XLSX or PDF and saves it. Clients stream the
+// file from the server straight to disk, so large ledgers are never held in memory.
+func (a *App) ExportToFile(input ExportToFileInput) (ExportFileResult, error) {
+	path := strings.TrimSpace(input.Path)
+	if path == "" {
+		chosen, err := a.chooseExportPath(input.Export)
+		if err != nil || chosen == "" {
+			return ExportFileResult{}, err
+		}
+		path = chosen
+	}
+
+	var export *appExport.Export
+	if a.isServer || a.exportService != nil {
+		opened, err := a.OpenExport(input.Export)
+		if err != nil {
+			return ExportFileResult{}, err
+		}
+		export = opened
+	}
+
+	// Write beside the destination and rename, so a failed export never leaves a partial file
+	// under the chosen name.
+	file, err := os.CreateTemp(filepath.Dir(path), ".export-*")
+	if err != nil {
+		return ExportFileResult{}, fmt.Errorf("failed to create export file: %w", err)
+	}
+	tempPath := file.Name()
+	defer os.Remove(tempPath)
+
+	var written int64
+	if export != nil {
+		counter := &countingFileWriter{file: file}
+		err = export.Write(counter)
+		written = counter.written
+	} else {
+		written, err = downloadFromServerAPI("/exports", input.Export, file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return ExportFileResult{}, err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return ExportFileResult{}, fmt.Errorf("failed to save export: %w", err)
+	}
+	return ExportFileResult{Path: path, Bytes: written}, nil
+}
+
+func (a *App) chooseExportPath(input appExport.ExportInput) (string, error) {
+	if a.ctx == nil {
+		return "", fmt.Errorf("export path is required")
+	}
+	format, err := infraExport.NormalizeFormat(input.Format)
+	if err != nil {
+		return "", err
+	}
+	return wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Save export",
+		DefaultFilename: strings.TrimSpace(input.Dataset) + "." + format,
+		Filters:         []wailsRuntime.FileFilter{{DisplayName: strings.ToUpper(format), Pattern: "*." + format}},
+	})
+}
+
+type countingFileWriter struct {
+	file    *os.File
+	written int64
+}
+
+func (c *countingFileWriter) Write(p []byte) (int, error) {
+	n, err := c.file.Write(p)
+	c.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	appExport "masala_inventory_managment/internal/app/export"
+	appInvoice "masala_inventory_managment/internal/app/invoice"
+	appLabel "masala_inventory_managment/internal/app/label"
+)
+
+func TestExportToFile_ClientMode_StreamsServerResponseToDisk(t *testing.T) {
+	server := newTestHTTPServerOrSkip(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/exports" {
+			t.Fatalf("expected /exports path, got %s", r.URL.Path)
+		}
+		var input appExport.ExportInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Dataset != "lots" || input.AuthToken != "operator-token" {
+			t.Fatalf("unexpected export request %+v (%v)", input, err)
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_, _ = w.Write([]byte("Lot Number\nLOT-1\n"))
+	}))
+	defer server.Close()
+	t.Setenv(envServerProbeAddr, server.URL)
+
+	path := filepath.Join(t.TempDir(), "lots.csv")
+	result, err := NewApp(false).ExportToFile(ExportToFileInput{
+		Export: appExport.ExportInput{Dataset: "lots", Format: "csv", AuthToken: "operator-token"},
+		Path:   path,
+	})
+	if err != nil {
+		t.Fatalf("ExportToFile failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "Lot Number\nLOT-1\n" {
+		t.Fatalf("unexpected saved export %q (%v)", content, err)
+	}
+	if result.Path != path || result.Bytes != int64(len(content)) {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestExportToFile_ClientMode_LeavesNoFileWhenServerRefuses(t *testing.T) {
+	server := newTestHTTPServerOrSkip(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "forbidden: admin role required"})
+	}))
+	defer server.Close()
+	t.Setenv(envServerProbeAddr, server.URL)
+
+	dir := t.TempDir()
+	_, err := NewApp(false).ExportToFile(ExportToFileInput{
+		Export: appExport.ExportInput{Dataset: "audit_log", Format: "pdf", AuthToken: "operator-token"},
+		Path:   filepath.Join(dir, "audit.pdf"),
+	})
+	if err == nil || err.Error() != "forbidden: admin role required" {
+		t.Fatalf("expected the server's message, got %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("expected no file to be left behind, found %d", len(entries))
+	}
+}
+
+func TestSaveInvoicePDF_ClientMode_DownloadsStoredInvoice(t *testing.T) {
+	server := newTestHTTPServerOrSkip(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/invoices/pdf" {
+			t.Fatalf("expected /invoices/pdf path, got %s", r.URL.Path)
+		}
+		var input appInvoice.InvoiceIDInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.ID != 7 || input.AuthToken != "operator-token" {
+			t.Fatalf("unexpected invoice request %+v (%v)", input, err)
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4\n%%EOF\n"))
+	}))
+	defer server.Close()
+	t.Setenv(envServerProbeAddr, server.URL)
+
+	path := filepath.Join(t.TempDir(), "INV-2026-27-0007.pdf")
+	result, err := NewApp(false).SaveInvoicePDF(SaveInvoicePDFInput{
+		Invoice: appInvoice.InvoiceIDInput{ID: 7, AuthToken: "operator-token"},
+		Path:    path,
+	})
+	if err != nil {
+		t.Fatalf("SaveInvoicePDF failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "%PDF-1.4\n%%EOF\n" || result.Bytes != int64(len(content)) {
+		t.Fatalf("unexpected saved invoice %q (%v)", content, err)
+	}
+}
+
+func TestLookupLabel_ClientMode_ResolvesThroughServer(t *testing.T) {
+	server := newTestHTTPServerOrSkip(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/labels/lookup" {
+			t.Fatalf("expected /labels/lookup path, got %s", r.URL.Path)
+		}
+		var input appLabel.LookupInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Code != "LOT-20260101-001" {
+			t.Fatalf("unexpected lookup request %+v (%v)", input, err)
+		}
+		_ = json.NewEncoder(w).Encode(appLabel.LotLookup{ItemSKU: "CUM-1", Balance: 25})
+	}))
+	defer server.Close()
+	t.Setenv(envServerProbeAddr, server.URL)
+
+	found, err := NewApp(false).LookupLabel(appLabel.LookupInput{Code: "LOT-20260101-001", AuthToken: "operator-token"})
+	if err != nil || found.ItemSKU != "CUM-1" || found.Balance != 25 {
+		t.Fatalf("unexpected lookup %+v (%v)", found, err)
+	}
+}
@@ -0,0 +1,419 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	"masala_inventory_managment/internal/infrastructure/spreadsheet"
+)
+
+const (
+	ImportKindItems        = "items"
+	ImportKindParties      = "parties"
+	ImportKindOpeningStock = "opening_stock"
+
+	maxImportRows = 20000
+)
+
+// importColumns are the header names each kind of file accepts; required columns must be present.
+var importColumns = map[string][]struct {
+	name     string
+	required bool
+}{
+	ImportKindItems: {
+		{name: "sku", required: true},
+		{name: "name", required: true},
+		{name: "item_type", required: true},
+		{name: "base_unit", required: true},
+		{name: "item_subtype"},
+		{name: "minimum_stock"},
+		{name: "is_active"},
+	},
+	ImportKindParties: {
+		{name: "party_type", required: true},
+		{name: "name", required: true},
+		{name: "phone"},
+		{name: "email"},
+		{name: "address"},
+		{name: "lead_time_days"},
+		{name: "is_active"},
+	},
+	ImportKindOpeningStock: {
+		{name: "sku", required: true},
+		{name: "supplier", required: true},
+		{name: "quantity", required: true},
+		{name: "unit_cost"},
+	},
+}
+
+// ImportInput is one uploaded file. Content is base64 in JSON. Without Commit the file is only
+// validated; with Commit it is written in one transaction, and only if every row is valid.
+type ImportInput struct {
+	Kind      string `json:"kind"`
+	Format    string `json:"format"`
+	FileName  string `json:"file_name"`
+	Content   []byte `json:"content"`
+	Commit    bool   `json:"commit"`
+	AuthToken string `json:"auth_token"`
+}
+
+// ImportRowError is a problem with one row; Row is the spreadsheet row number, the header being row 1.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportReport struct {
+	Kind       string           `json:"kind"`
+	Format     string           `json:"format"`
+	TotalRows  int              `json:"total_rows"`
+	ValidRows  int              `json:"valid_rows"`
+	Errors     []ImportRowError `json:"errors"`
+	Committed  bool             `json:"committed"`
+	GRNNumbers []string         `json:"grn_numbers,omitempty"`
+}
+
+type importRow struct {
+	number  int
+	cells   []string
+	columns map[string]int
+}
+
+func (r importRow) value(column string) string {
+	index, ok := r.columns[column]
+	if !ok || index >= len(r.cells) {
+		return ""
+	}
+	return strings.TrimSpace(r.cells[index])
+}
+
+func importValidationError(field, message string) error {
+	return &ServiceError{
+		Code:    "validation_failed",
+		Message: "import validation failed",
+		Fields:  []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// ImportMasterData validates an items, parties or opening stock file row by row and, when
+// asked to commit a file with no errors, creates everything it describes. Restricted to Admin.
+func (s *Service) ImportMasterData(input ImportInput) (*ImportReport, error) {
+	if err := s.requireMasterWriteAccess(input.AuthToken); err != nil {
+		return nil, err
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(input.Kind))
+	columns, ok := importColumns[kind]
+	if !ok {
+		return nil, importValidationError("kind", "kind must be items, parties or opening_stock")
+	}
+	format, err := spreadsheet.ResolveFormat(input.Format, input.FileName)
+	if err != nil {
+		return nil, importValidationError("format", err.Error())
+	}
+	if len(input.Content) == 0 {
+		return nil, importValidationError("content", "file is empty")
+	}
+	rows, err := spreadsheet.ReadRows(format, input.Content)
+	if err != nil {
+		return nil, importValidationError("content", err.Error())
+	}
+	if len(rows) < 2 {
+		return nil, importValidationError("content", "file must have a header row and at least one data row")
+	}
+	if len(rows)-1 > maxImportRows {
+		return nil, importValidationError("content", fmt.Sprintf("file has more than %d rows", maxImportRows))
+	}
+
+	positions := make(map[string]int)
+	for i, header := range rows[0].Cells {
+		name := strings.ToLower(strings.TrimSpace(header))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		if _, duplicate := positions[name]; name != "" && !duplicate {
+			positions[name] = i
+		}
+	}
+	for _, column := range columns {
+		if _, found := positions[column.name]; column.required && !found {
+			return nil, importValidationError("content", fmt.Sprintf("missing required column %q", column.name))
+		}
+	}
+
+	data := make([]importRow, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		data = append(data, importRow{number: row.Number, cells: row.Cells, columns: positions})
+	}
+
+	report := &ImportReport{Kind: kind, Format: format, TotalRows: len(data), Errors: []ImportRowError{}}
+	batch := &domainInventory.MasterDataImport{
+		CreatedBy: s.resolveSubject(input.AuthToken),
+		CreatedAt: time.Now().UTC(),
+	}
+	switch kind {
+	case ImportKindItems:
+		err = s.validateItemRows(data, batch, report)
+	case ImportKindParties:
+		err = s.validatePartyRows(data, batch, report)
+	case ImportKindOpeningStock:
+		err = s.validateOpeningStockRows(data, batch, report)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	invalidRows := make(map[int]struct{})
+	for _, rowErr := range report.Errors {
+		invalidRows[rowErr.Row] = struct{}{}
+	}
+	report.ValidRows = report.TotalRows - len(invalidRows)
+	if !input.Commit || len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	if err := s.writeRepo(input.AuthToken).ImportMasterData(batch); err != nil {
+		return nil, mapImportPersistenceError(err)
+	}
+	report.Committed = true
+
+	for i := range batch.Parties {
+		s.publish(domainEvents.TypePartyCreated, "party", batch.Parties[i].ID, input.AuthToken, batch.Parties[i])
+	}
+	for i := range batch.Items {
+		s.publish(domainEvents.TypeItemCreated, "item", batch.Items[i].ID, input.AuthToken, batch.Items[i])
+	}
+	for i := range batch.GRNs {
+		report.GRNNumbers = append(report.GRNNumbers, batch.GRNs[i].GRNNumber)
+		s.publish(domainEvents.TypeGrnCreated, "grn", batch.GRNs[i].ID, input.AuthToken, batch.GRNs[i])
+	}
+	return report, nil
+}
+
+func (s *Service) validateItemRows(rows []importRow, batch *domainInventory.MasterDataImport, report *ImportReport) error {
+	existing, _, err := s.repo.ListItems(domainInventory.ItemListFilter{})
+	if err != nil {
+		return err
+	}
+	existingSKUs := make(map[string]struct{}, len(existing))
+	for _, item := range existing {
+		existingSKUs[strings.ToLower(strings.TrimSpace(item.SKU))] = struct{}{}
+	}
+	seenSKUs := make(map[string]int)
+
+	for _, row := range rows {
+		rowErrors := make([]ImportRowError, 0)
+		item := domainInventory.Item{
+			SKU:         row.value("sku"),
+			Name:        row.value("name"),
+			ItemType:    domainInventory.ParseItemType(row.value("item_type")),
+			BaseUnit:    row.value("base_unit"),
+			ItemSubtype: row.value("item_subtype"),
+		}
+		if minimum, ok := parseImportFloat(row, "minimum_stock", &rowErrors); ok {
+			item.MinimumStock = minimum
+		}
+		item.IsActive = parseImportBool(row, "is_active", &rowErrors)
+
+		if err := item.ValidateMasterContract(); err != nil {
+			rowErrors = append(rowErrors, importRowErrorFrom(row.number, err))
+		}
+		key := strings.ToLower(item.SKU)
+		switch {
+		case item.SKU == "":
+			rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "sku", Message: "sku is required"})
+		case seenSKUs[key] > 0:
+			rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "sku", Message: fmt.Sprintf("sku duplicates row %d", seenSKUs[key])})
+		default:
+			seenSKUs[key] = row.number
+			if _, found := existingSKUs[key]; found {
+				rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "sku", Message: "sku already exists"})
+			}
+		}
+
+		report.Errors = append(report.Errors, rowErrors...)
+		if len(rowErrors) == 0 {
+			batch.Items = append(batch.Items, item)
+		}
+	}
+	return nil
+}
+
+func (s *Service) validatePartyRows(rows []importRow, batch *domainInventory.MasterDataImport, report *ImportReport) error {
+	existing, _, err := s.repo.ListParties(domainInventory.PartyListFilter{})
+	if err != nil {
+		return err
+	}
+	partyKey := func(partyType domainInventory.PartyType, name string) string {
+		return string(partyType) + "\x00" + strings.ToLower(strings.TrimSpace(name))
+	}
+	existingParties := make(map[string]struct{}, len(existing))
+	for _, party := range existing {
+		existingParties[partyKey(party.PartyType, party.Name)] = struct{}{}
+	}
+	seenParties := make(map[string]int)
+
+	for _, row := range rows {
+		rowErrors := make([]ImportRowError, 0)
+		party := domainInventory.Party{
+			PartyType: domainInventory.ParsePartyType(row.value("party_type")),
+			Name:      row.value("name"),
+			Phone:     row.value("phone"),
+			Email:     row.value("email"),
+			Address:   row.value("address"),
+		}
+		if raw := row.value("lead_time_days"); raw != "" {
+			days, err := strconv.Atoi(raw)
+			if err != nil {
+				rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "lead_time_days", Message: "lead_time_days must be a whole number"})
+			} else {
+				party.LeadTimeDays = &days
+			}
+		}
+		party.IsActive = parseImportBool(row, "is_active", &rowErrors)
+
+		if err := party.Validate(); err != nil {
+			rowErrors = append(rowErrors, importRowErrorFrom(row.number, err))
+		} else {
+			key := partyKey(party.PartyType, party.Name)
+			if first := seenParties[key]; first > 0 {
+				rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "name", Message: fmt.Sprintf("party duplicates row %d", first)})
+			} else {
+				seenParties[key] = row.number
+				if _, found := existingParties[key]; found {
+					rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "name", Message: "party already exists for this type"})
+				}
+			}
+		}
+
+		report.Errors = append(report.Errors, rowErrors...)
+		if len(rowErrors) == 0 {
+			batch.Parties = append(batch.Parties, party)
+		}
+	}
+	return nil
+}
+
+// validateOpeningStockRows resolves each row's sku and supplier name against the items and
+// parties already in the system, so those files must be imported first.
+func (s *Service) validateOpeningStockRows(rows []importRow, batch *domainInventory.MasterDataImport, report *ImportReport) error {
+	items, _, err := s.repo.ListItems(domainInventory.ItemListFilter{})
+	if err != nil {
+		return err
+	}
+	itemsBySKU := make(map[string]domainInventory.Item, len(items))
+	for _, item := range items {
+		itemsBySKU[strings.ToLower(strings.TrimSpace(item.SKU))] = item
+	}
+	suppliers, _, err := s.repo.ListParties(domainInventory.PartyListFilter{PartyType: domainInventory.PartyTypeSupplier})
+	if err != nil {
+		return err
+	}
+	suppliersByName := make(map[string]domainInventory.Party, len(suppliers))
+	for _, supplier := range suppliers {
+		if supplier.PartyType == domainInventory.PartyTypeSupplier {
+			suppliersByName[strings.ToLower(strings.TrimSpace(supplier.Name))] = supplier
+		}
+	}
+
+	for _, row := range rows {
+		rowErrors := make([]ImportRowError, 0)
+		line := domainInventory.OpeningStockLine{}
+
+		sku := row.value("sku")
+		item, found := itemsBySKU[strings.ToLower(sku)]
+		switch {
+		case sku == "":
+			rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "sku", Message: "sku is required"})
+		case !found:
+			rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "sku", Message: fmt.Sprintf("no item has sku %q", sku)})
+		case !item.IsActive:
+			rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "sku", Message: "item is inactive"})
+		case item.ItemType == domainInventory.ItemTypeFinishedGood:
+			rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "sku", Message: "opening stock lots must be RAW, PACKING_MATERIAL, or BULK_POWDER items"})
+		default:
+			line.ItemID = item.ID
+		}
+
+		supplierName := row.value("supplier")
+		supplier, found := suppliersByName[strings.ToLower(supplierName)]
+		switch {
+		case supplierName == "":
+			rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "supplier", Message: "supplier is required"})
+		case !found:
+			rowErrors = append(rowErrors, ImportRowError{Row: row.number, Field: "supplier", Message: fmt.Sprintf("no supplier is named %q", supplierName)})
+		default:
+			line.SupplierID = supplier.ID
+		}
+
+		quantity, quantityOK := parseImportFloat(row, "quantity", &rowErrors)
+		unitCost, unitCostOK := parseImportFloat(row, "unit_cost", &rowErrors)
+		line.Quantity, line.UnitCost = quantity, unitCost
+		if quantityOK && unitCostOK && line.ItemID > 0 && line.SupplierID > 0 {
+			if err := line.Validate(); err != nil {
+				rowErrors = append(rowErrors, importRowErrorFrom(row.number, err))
+			}
+		}
+
+		report.Errors = append(report.Errors, rowErrors...)
+		if len(rowErrors) == 0 {
+			batch.OpeningStock = append(batch.OpeningStock, line)
+		}
+	}
+	return nil
+}
+
+// parseImportFloat reads an optional number; blank is zero.
+func parseImportFloat(row importRow, column string, rowErrors *[]ImportRowError) (float64, bool) {
+	raw := strings.ReplaceAll(row.value(column), ",", "")
+	if raw == "" {
+		return 0, true
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		*rowErrors = append(*rowErrors, ImportRowError{Row: row.number, Field: column, Message: column + " must be a number"})
+		return 0, false
+	}
+	return value, true
+}
+
+// parseImportBool reads an optional yes/no column; blank means true, as new records are active.
+func parseImportBool(row importRow, column string, rowErrors *[]ImportRowError) bool {
+	switch strings.ToLower(row.value(column)) {
+	case "", "true", "yes", "y", "1", "active":
+		return true
+	case "false", "no", "n", "0", "inactive":
+		return false
+	default:
+		*rowErrors = append(*rowErrors, ImportRowError{Row: row.number, Field: column, Message: column + " must be true or false"})
+		return true
+	}
+}
+
+func importRowErrorFrom(row int, err error) ImportRowError {
+	var serviceErr *ServiceError
+	if errors.As(mapValidationError(err), &serviceErr) && len(serviceErr.Fields) > 0 {
+		return ImportRowError{Row: row, Field: serviceErr.Fields[0].Field, Message: serviceErr.Fields[0].Message}
+	}
+	return ImportRowError{Row: row, Message: err.Error()}
+}
+
+// mapImportPersistenceError covers rows that became invalid between validation and commit,
+// e.g. an item with the same sku created meanwhile.
+func mapImportPersistenceError(err error) error {
+	lowered := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(lowered, "unique constraint failed: items.sku"):
+		return &ServiceError{Code: "conflict", Message: "import conflicts with existing data: " + err.Error(), Fields: []FieldError{{Field: "sku", Message: "duplicate sku"}}}
+	case strings.Contains(lowered, "unique constraint failed"):
+		return &ServiceError{Code: "conflict", Message: "import conflicts with existing data: " + err.Error()}
+	case strings.Contains(lowered, "invalid grn line item"), strings.Contains(lowered, "foreign key constraint failed"):
+		return &ServiceError{Code: "conflict", Message: "an item or supplier in the import changed during validation; run the dry run again"}
+	default:
+		return mapValidationError(err)
+	}
+}
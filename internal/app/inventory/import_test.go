@@ -0,0 +1,165 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+
+	appLicenseMode "masala_inventory_managment/internal/app/licensemode"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+)
+
+func TestService_ImportMasterData_DryRunReportsEveryInvalidRow(t *testing.T) {
+	appLicenseMode.SetWriteEnforcer(nil)
+	repo := &fakeInventoryRepo{items: []domainInventory.Item{{ID: 1, SKU: "CUM-1", Name: "Cumin", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg"}}}
+	svc := NewService(repo, fixedRoleResolver(domainAuth.RoleAdmin, nil), nil)
+
+	content := "SKU,Name,Item Type,Base Unit,Minimum Stock\n" +
+		"COR-1,Coriander,raw,kg,5\n" +
+		"cum-1,Cumin again,RAW,kg,\n" +
+		"CHL-1,Chilli,SPICE,kg,\n" +
+		"COR-1,Coriander twice,RAW,kg,\n" +
+		"PCH-1,Pouch,PACKING_MATERIAL,pcs,lots\n"
+	report, err := svc.ImportMasterData(ImportInput{Kind: "items", FileName: "items.csv", Content: []byte(content), Commit: true, AuthToken: "admin-token"})
+	if err != nil {
+		t.Fatalf("ImportMasterData failed: %v", err)
+	}
+
+	if report.TotalRows != 5 || report.ValidRows != 1 || report.Committed {
+		t.Fatalf("unexpected report totals: %+v", report)
+	}
+	expected := []ImportRowError{
+		{Row: 3, Field: "sku", Message: "sku already exists"},
+		{Row: 4, Field: "item_type", Message: "unsupported item type: SPICE"},
+		{Row: 5, Field: "sku", Message: "sku duplicates row 2"},
+		{Row: 6, Field: "minimum_stock", Message: "minimum_stock must be a number"},
+	}
+	if len(report.Errors) != len(expected) {
+		t.Fatalf("expected %d row errors, got %+v", len(expected), report.Errors)
+	}
+	for i, want := range expected {
+		if report.Errors[i] != want {
+			t.Fatalf("row error %d: expected %+v, got %+v", i, want, report.Errors[i])
+		}
+	}
+	if repo.lastImport != nil {
+		t.Fatalf("expected nothing to be written when rows are invalid")
+	}
+}
+
+func TestService_ImportMasterData_CommitWritesPartiesAndPublishes(t *testing.T) {
+	appLicenseMode.SetWriteEnforcer(nil)
+	repo := &fakeInventoryRepo{parties: []domainInventory.Party{{ID: 1, PartyType: domainInventory.PartyTypeSupplier, Name: "Spice Co", Phone: "1"}}}
+	publisher := &recordingPublisher{}
+	svc := NewService(repo, fixedRoleResolver(domainAuth.RoleAdmin, nil), func(string) (string, error) { return "admin", nil })
+	svc.SetEventPublisher(publisher)
+
+	content := "party_type,name,phone,email,lead_time_days,is_active\n" +
+		"supplier,Hill Farms,98450,,7,yes\n" +
+		"CUSTOMER,Spice Co,,orders@spice.example,,no\n"
+
+	dryRun, err := svc.ImportMasterData(ImportInput{Kind: "parties", Format: "csv", Content: []byte(content), AuthToken: "admin-token"})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if dryRun.ValidRows != 2 || len(dryRun.Errors) != 0 || dryRun.Committed || repo.lastImport != nil {
+		t.Fatalf("expected a clean dry run that writes nothing, got %+v", dryRun)
+	}
+
+	report, err := svc.ImportMasterData(ImportInput{Kind: "parties", Format: "csv", Content: []byte(content), Commit: true, AuthToken: "admin-token"})
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if !report.Committed || repo.lastImport == nil || len(repo.lastImport.Parties) != 2 {
+		t.Fatalf("expected both parties to be committed, got %+v", report)
+	}
+	hill := repo.lastImport.Parties[0]
+	if hill.PartyType != domainInventory.PartyTypeSupplier || hill.LeadTimeDays == nil || *hill.LeadTimeDays != 7 || !hill.IsActive {
+		t.Fatalf("unexpected first party: %+v", hill)
+	}
+	if repo.lastImport.Parties[1].IsActive {
+		t.Fatalf("expected is_active=no to import an inactive party")
+	}
+	if repo.lastImport.CreatedBy != "admin" {
+		t.Fatalf("expected import to be attributed to admin, got %q", repo.lastImport.CreatedBy)
+	}
+	if len(publisher.events) != 2 || publisher.events[0].Type != domainEvents.TypePartyCreated {
+		t.Fatalf("expected a PartyCreated event per party, got %+v", publisher.events)
+	}
+}
+
+func TestService_ImportMasterData_OpeningStockResolvesSKUAndSupplier(t *testing.T) {
+	appLicenseMode.SetWriteEnforcer(nil)
+	repo := &fakeInventoryRepo{
+		items: []domainInventory.Item{
+			{ID: 5, SKU: "CUM-1", ItemType: domainInventory.ItemTypeRaw, IsActive: true},
+			{ID: 6, SKU: "FG-1", ItemType: domainInventory.ItemTypeFinishedGood, IsActive: true},
+		},
+		parties: []domainInventory.Party{
+			{ID: 9, PartyType: domainInventory.PartyTypeSupplier, Name: "Hill Farms"},
+			{ID: 10, PartyType: domainInventory.PartyTypeCustomer, Name: "Retail Mart"},
+		},
+	}
+	svc := NewService(repo, fixedRoleResolver(domainAuth.RoleAdmin, nil), nil)
+
+	content := "sku,supplier,quantity,unit_cost\n" +
+		"cum-1,hill farms,\"1,250.5\",180\n" +
+		"FG-1,Hill Farms,10,0\n" +
+		"CUM-1,Retail Mart,10,0\n" +
+		"CUM-1,Hill Farms,0,0\n"
+	report, err := svc.ImportMasterData(ImportInput{Kind: "opening_stock", FileName: "stock.csv", Content: []byte(content), AuthToken: "admin-token"})
+	if err != nil {
+		t.Fatalf("ImportMasterData failed: %v", err)
+	}
+	if report.ValidRows != 1 || len(report.Errors) != 3 {
+		t.Fatalf("expected one valid row and three errors, got %+v", report)
+	}
+	fields := []string{report.Errors[0].Field, report.Errors[1].Field, report.Errors[2].Field}
+	if fields[0] != "sku" || fields[1] != "supplier" || fields[2] != "quantity" {
+		t.Fatalf("unexpected error fields: %+v", report.Errors)
+	}
+
+	report, err = svc.ImportMasterData(ImportInput{Kind: "opening_stock", FileName: "stock.csv", Content: []byte("sku,supplier,quantity,unit_cost\ncum-1,hill farms,\"1,250.5\",180\n"), Commit: true, AuthToken: "admin-token"})
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if !report.Committed || len(repo.lastImport.OpeningStock) != 1 {
+		t.Fatalf("expected committed opening stock, got %+v", report)
+	}
+	line := repo.lastImport.OpeningStock[0]
+	if line.ItemID != 5 || line.SupplierID != 9 || line.Quantity != 1250.5 || line.UnitCost != 180 {
+		t.Fatalf("unexpected opening stock line: %+v", line)
+	}
+}
+
+func TestService_ImportMasterData_RejectsBadFilesAndNonAdmins(t *testing.T) {
+	appLicenseMode.SetWriteEnforcer(nil)
+	svc := NewService(&fakeInventoryRepo{}, fixedRoleResolver(domainAuth.RoleAdmin, nil), nil)
+
+	cases := []struct {
+		name  string
+		input ImportInput
+		field string
+	}{
+		{name: "unknown kind", input: ImportInput{Kind: "recipes", FileName: "r.csv", Content: []byte("a\n1\n")}, field: "kind"},
+		{name: "unknown format", input: ImportInput{Kind: "items", FileName: "items.ods", Content: []byte("a\n1\n")}, field: "format"},
+		{name: "header only", input: ImportInput{Kind: "items", FileName: "items.csv", Content: []byte("sku,name,item_type,base_unit\n")}, field: "content"},
+		{name: "missing column", input: ImportInput{Kind: "items", FileName: "items.csv", Content: []byte("sku,name,base_unit\nA,B,kg\n")}, field: "content"},
+	}
+	for _, tc := range cases {
+		tc.input.AuthToken = "admin-token"
+		_, err := svc.ImportMasterData(tc.input)
+		var serviceErr *ServiceError
+		if !errors.As(err, &serviceErr) || serviceErr.Code != "validation_failed" || serviceErr.Fields[0].Field != tc.field {
+			t.Fatalf("%s: expected validation error on %s, got %v", tc.name, tc.field, err)
+		}
+	}
+
+	operator := NewService(&fakeInventoryRepo{}, fixedRoleResolver(domainAuth.RoleDataEntryOperator, nil), nil)
+	_, err := operator.ImportMasterData(ImportInput{Kind: "items", FileName: "items.csv", Content: []byte("sku\nA\n"), AuthToken: "operator-token"})
+	var serviceErr *ServiceError
+	if !errors.As(err, &serviceErr) || serviceErr.Code != "forbidden" {
+		t.Fatalf("expected operator to be forbidden, got %v", err)
+	}
+}
@@ -3,12 +3,15 @@ package inventory
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
 	appLicenseMode "masala_inventory_managment/internal/app/licensemode"
 	domainAuth "masala_inventory_managment/internal/domain/auth"
 	domainErrors "masala_inventory_managment/internal/domain/errors"
+	domainEvents "masala_inventory_managment/internal/domain/events"
 	domainInventory "masala_inventory_managment/internal/domain/inventory"
 )
 
@@ -18,6 +21,8 @@ type Service struct {
 	repo             domainInventory.Repository
 	roleResolver     func(authToken string) (domainAuth.Role, error)
 	subjectResolver  func(authToken string) (string, error)
+	publisher        domainEvents.Publisher
+	outbox           domainEvents.Outbox
 }
 
 type FieldError struct {
@@ -63,6 +68,10 @@ type ListItemsInput struct {
 	ActiveOnly bool   `json:"active_only"`
 	ItemType   string `json:"item_type"`
 	Search     string `json:"search"`
+	Limit      int    `json:"limit"`
+	Cursor     string `json:"cursor"`
+	SortBy     string `json:"sort_by"`
+	SortOrder  string `json:"sort_order"`
 	AuthToken  string `json:"auth_token"`
 }
 
@@ -149,6 +158,10 @@ type ListPartiesInput struct {
 	ActiveOnly bool   `json:"active_only"`
 	PartyType  string `json:"party_type"`
 	Search     string `json:"search"`
+	Limit      int    `json:"limit"`
+	Cursor     string `json:"cursor"`
+	SortBy     string `json:"sort_by"`
+	SortOrder  string `json:"sort_order"`
 	AuthToken  string `json:"auth_token"`
 }
 
@@ -174,6 +187,10 @@ type ListMaterialLotsInput struct {
 	GRNNumber  string `json:"grn_number"`
 	ActiveOnly bool   `json:"active_only"`
 	Search     string `json:"search"`
+	Limit      int    `json:"limit"`
+	Cursor     string `json:"cursor"`
+	SortBy     string `json:"sort_by"`
+	SortOrder  string `json:"sort_order"`
 	AuthToken  string `json:"auth_token"`
 }
 
@@ -187,8 +204,13 @@ type RecordLotStockMovementInput struct {
 }
 
 type ListLotStockMovementsInput struct {
-	LotNumber string `json:"lot_number"`
-	AuthToken string `json:"auth_token"`
+	LotNumber   string `json:"lot_number"`
+	ReferenceID string `json:"reference_id"`
+	Limit       int    `json:"limit"`
+	Cursor      string `json:"cursor"`
+	SortBy      string `json:"sort_by"`
+	SortOrder   string `json:"sort_order"`
+	AuthToken   string `json:"auth_token"`
 }
 
 type CreateStockAdjustmentInput struct {
@@ -202,6 +224,10 @@ type CreateStockAdjustmentInput struct {
 
 type ListStockAdjustmentsInput struct {
 	ItemID    int64  `json:"item_id"`
+	Limit     int    `json:"limit"`
+	Cursor    string `json:"cursor"`
+	SortBy    string `json:"sort_by"`
+	SortOrder string `json:"sort_order"`
 	AuthToken string `json:"auth_token"`
 }
 
@@ -245,6 +271,80 @@ func NewService(repo domainInventory.Repository, roleResolver func(authToken str
 	}
 }
 
+// SetEventPublisher publishes an event after each committed write; nil disables events.
+func (s *Service) SetEventPublisher(publisher domainEvents.Publisher) {
+	s.publisher = publisher
+}
+
+// SetEventOutbox stores each event in outbox before the write that produced it returns, so
+// deliveries owed for a committed change are not left to a subscriber that may fall behind.
+func (s *Service) SetEventOutbox(outbox domainEvents.Outbox) {
+	s.outbox = outbox
+}
+
+// inventoryEventRoles are the roles that can read inventory data, and so may see its changes.
+var inventoryEventRoles = []domainAuth.Role{domainAuth.RoleAdmin, domainAuth.RoleDataEntryOperator}
+
+func (s *Service) publish(eventType, entityType string, entityID int64, authToken string, data interface{}) {
+	if s.publisher == nil && s.outbox == nil {
+		return
+	}
+	event := domainEvents.Event{
+		Type:       eventType,
+		EntityType: entityType,
+		EntityID:   strconv.FormatInt(entityID, 10),
+		Actor:      s.resolveSubject(authToken),
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+		Roles:      inventoryEventRoles,
+	}
+	if s.outbox != nil {
+		// The write is already committed, so failing it now would invite a duplicate retry.
+		if err := s.outbox.Enqueue(event); err != nil {
+			slog.Error("Failed to store event in outbox", "type", eventType, "entity_id", event.EntityID, "error", err)
+		}
+	}
+	if s.publisher != nil {
+		s.publisher.Publish(event)
+	}
+}
+
+// LowStockAlert is the data of a StockBelowMinimum event.
+type LowStockAlert struct {
+	ItemID       int64   `json:"item_id"`
+	SKU          string  `json:"sku"`
+	Name         string  `json:"name"`
+	BaseUnit     string  `json:"base_unit"`
+	Balance      float64 `json:"balance"`
+	MinimumStock float64 `json:"minimum_stock"`
+}
+
+// publishIfBelowMinimum announces an item whose stock a write has just taken below its minimum.
+// Only the crossing is announced, so further issues from an already-low item stay quiet.
+func (s *Service) publishIfBelowMinimum(itemID int64, delta float64, authToken string) {
+	if (s.publisher == nil && s.outbox == nil) || delta >= 0 {
+		return
+	}
+	item, err := s.repo.GetItem(itemID)
+	if err != nil || item.MinimumStock <= 0 {
+		return
+	}
+	balance, err := s.repo.GetItemStockBalance(itemID)
+	if err != nil {
+		return
+	}
+	if balance < item.MinimumStock && balance-delta >= item.MinimumStock {
+		s.publish(domainEvents.TypeStockBelowMinimum, "item", itemID, authToken, LowStockAlert{
+			ItemID:       item.ID,
+			SKU:          item.SKU,
+			Name:         item.Name,
+			BaseUnit:     item.BaseUnit,
+			Balance:      balance,
+			MinimumStock: item.MinimumStock,
+		})
+	}
+}
+
 func (s *Service) resolveSubject(authToken string) string {
 	if s.subjectResolver == nil {
 		return "unknown"
@@ -256,6 +356,11 @@ func (s *Service) resolveSubject(authToken string) string {
 	return strings.TrimSpace(subject)
 }
 
+// writeRepo scopes repository writes to the caller so the audit log records who made them.
+func (s *Service) writeRepo(authToken string) domainInventory.Repository {
+	return s.repo.WithActor(s.resolveSubject(authToken))
+}
+
 func (s *Service) resolveRole(authToken string) (domainAuth.Role, error) {
 	token := strings.TrimSpace(authToken)
 	if token == "" {
@@ -381,6 +486,14 @@ func mapValidationError(err error) error {
 		return &ServiceError{Code: "validation_failed", Message: "grn validation failed", Fields: []FieldError{{Field: "lines.quantity_received", Message: domainInventory.ErrGRNLineQuantity.Error()}}}
 	case errors.Is(err, domainInventory.ErrGRNLineUnitPrice):
 		return &ServiceError{Code: "validation_failed", Message: "grn validation failed", Fields: []FieldError{{Field: "lines.unit_price", Message: domainInventory.ErrGRNLineUnitPrice.Error()}}}
+	case errors.Is(err, domainInventory.ErrOpeningStockItemRequired):
+		return &ServiceError{Code: "validation_failed", Message: "opening stock validation failed", Fields: []FieldError{{Field: "item_id", Message: domainInventory.ErrOpeningStockItemRequired.Error()}}}
+	case errors.Is(err, domainInventory.ErrOpeningStockSupplierRequired):
+		return &ServiceError{Code: "validation_failed", Message: "opening stock validation failed", Fields: []FieldError{{Field: "supplier_id", Message: domainInventory.ErrOpeningStockSupplierRequired.Error()}}}
+	case errors.Is(err, domainInventory.ErrOpeningStockQuantity):
+		return &ServiceError{Code: "validation_failed", Message: "opening stock validation failed", Fields: []FieldError{{Field: "quantity", Message: domainInventory.ErrOpeningStockQuantity.Error()}}}
+	case errors.Is(err, domainInventory.ErrOpeningStockUnitCost):
+		return &ServiceError{Code: "validation_failed", Message: "opening stock validation failed", Fields: []FieldError{{Field: "unit_cost", Message: domainInventory.ErrOpeningStockUnitCost.Error()}}}
 	case errors.Is(err, domainInventory.ErrLotNumberRequired):
 		return &ServiceError{Code: "validation_failed", Message: "lot movement validation failed", Fields: []FieldError{{Field: "lot_number", Message: domainInventory.ErrLotNumberRequired.Error()}}}
 	case errors.Is(err, domainInventory.ErrMovementTypeInvalid):
@@ -415,11 +528,23 @@ func mapValidationError(err error) error {
 		return &ServiceError{Code: "validation_failed", Message: "conversion rule not found for requested unit pair", Fields: []FieldError{{Field: "conversion_rule", Message: domainInventory.ErrConversionRuleNotFound.Error()}}}
 	case errors.Is(err, domainInventory.ErrConversionRuleMismatch):
 		return &ServiceError{Code: "validation_failed", Message: "conversion rule does not match source/target units", Fields: []FieldError{{Field: "conversion_rule", Message: domainInventory.ErrConversionRuleMismatch.Error()}}}
+	case errors.Is(err, domainInventory.ErrPageLimitInvalid):
+		return &ServiceError{Code: "validation_failed", Message: "page request validation failed", Fields: []FieldError{{Field: "limit", Message: domainInventory.ErrPageLimitInvalid.Error()}}}
+	case errors.Is(err, domainInventory.ErrSortKeyInvalid):
+		return &ServiceError{Code: "validation_failed", Message: "page request validation failed", Fields: []FieldError{{Field: "sort_by", Message: domainInventory.ErrSortKeyInvalid.Error()}}}
+	case errors.Is(err, domainInventory.ErrSortOrderInvalid):
+		return &ServiceError{Code: "validation_failed", Message: "page request validation failed", Fields: []FieldError{{Field: "sort_order", Message: domainInventory.ErrSortOrderInvalid.Error()}}}
+	case errors.Is(err, domainInventory.ErrCursorInvalid):
+		return &ServiceError{Code: "validation_failed", Message: "page request validation failed", Fields: []FieldError{{Field: "cursor", Message: domainInventory.ErrCursorInvalid.Error()}}}
 	default:
 		return err
 	}
 }
 
+func pageRequest(limit int, cursor, sortBy, sortOrder string) domainInventory.PageRequest {
+	return domainInventory.PageRequest{Limit: limit, Cursor: cursor, SortBy: sortBy, SortOrder: sortOrder}
+}
+
 func mapConversionPersistenceError(err error) error {
 	if err == nil {
 		return nil
@@ -574,9 +699,10 @@ func (s *Service) CreateItemMaster(input CreateItemInput) (*domainInventory.Item
 	if err := item.ValidateMasterContract(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.CreateItem(item); err != nil {
+	if err := s.writeRepo(input.AuthToken).CreateItem(item); err != nil {
 		return nil, mapValidationError(err)
 	}
+	s.publish(domainEvents.TypeItemCreated, "item", item.ID, input.AuthToken, item)
 	return item, nil
 }
 
@@ -615,25 +741,31 @@ func (s *Service) UpdateItemMaster(input UpdateItemInput) (*domainInventory.Item
 		IsActive:     input.IsActive,
 		UpdatedAt:    updatedAt,
 	}
-	if err := s.repo.UpdateItem(item); err != nil {
+	if err := s.writeRepo(input.AuthToken).UpdateItem(item); err != nil {
 		if errors.Is(err, domainErrors.ErrConcurrencyConflict) {
 			return nil, errors.New(ErrRecordModified)
 		}
 		return nil, mapValidationError(err)
 	}
+	s.publish(domainEvents.TypeItemUpdated, "item", item.ID, input.AuthToken, item)
 	return item, nil
 }
 
-func (s *Service) ListItems(input ListItemsInput) ([]domainInventory.Item, error) {
+func (s *Service) ListItems(input ListItemsInput) ([]domainInventory.Item, domainInventory.PageInfo, error) {
 	if err := s.requireReadAccess(input.AuthToken); err != nil {
-		return nil, err
+		return nil, domainInventory.PageInfo{}, err
 	}
 	filter := domainInventory.ItemListFilter{
 		ActiveOnly: input.ActiveOnly,
 		ItemType:   domainInventory.ParseItemType(input.ItemType),
 		Search:     strings.TrimSpace(input.Search),
+		Page:       pageRequest(input.Limit, input.Cursor, input.SortBy, input.SortOrder),
+	}
+	items, info, err := s.repo.ListItems(filter)
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, mapValidationError(err)
 	}
-	return s.repo.ListItems(filter)
+	return items, info, nil
 }
 
 func (s *Service) CreatePackagingProfile(input CreatePackagingProfileInput) (*domainInventory.PackagingProfile, error) {
@@ -655,9 +787,10 @@ func (s *Service) CreatePackagingProfile(input CreatePackagingProfileInput) (*do
 	if err := profile.Validate(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.CreatePackagingProfile(profile); err != nil {
+	if err := s.writeRepo(input.AuthToken).CreatePackagingProfile(profile); err != nil {
 		return nil, mapValidationError(err)
 	}
+	s.publish(domainEvents.TypePackagingProfileAdded, "packaging_profile", profile.ID, input.AuthToken, profile)
 	return profile, nil
 }
 
@@ -697,9 +830,10 @@ func (s *Service) CreateRecipe(input CreateRecipeInput) (*domainInventory.Recipe
 	if err := recipe.Validate(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.CreateRecipe(recipe); err != nil {
+	if err := s.writeRepo(input.AuthToken).CreateRecipe(recipe); err != nil {
 		return nil, mapRecipePersistenceError(err)
 	}
+	s.publish(domainEvents.TypeRecipeCreated, "recipe", recipe.ID, input.AuthToken, recipe)
 	return recipe, nil
 }
 
@@ -738,12 +872,13 @@ func (s *Service) UpdateRecipe(input UpdateRecipeInput) (*domainInventory.Recipe
 	if err := recipe.Validate(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.UpdateRecipe(recipe); err != nil {
+	if err := s.writeRepo(input.AuthToken).UpdateRecipe(recipe); err != nil {
 		if errors.Is(err, domainErrors.ErrConcurrencyConflict) {
 			return nil, errors.New(ErrRecordModified)
 		}
 		return nil, mapRecipePersistenceError(err)
 	}
+	s.publish(domainEvents.TypeRecipeUpdated, "recipe", recipe.ID, input.AuthToken, recipe)
 	return recipe, nil
 }
 
@@ -776,9 +911,10 @@ func (s *Service) CreateParty(input CreatePartyInput) (*domainInventory.Party, e
 	if err := party.Validate(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.CreateParty(party); err != nil {
+	if err := s.writeRepo(input.AuthToken).CreateParty(party); err != nil {
 		return nil, mapPartyPersistenceError(err)
 	}
+	s.publish(domainEvents.TypePartyCreated, "party", party.ID, input.AuthToken, party)
 	return party, nil
 }
 
@@ -810,26 +946,32 @@ func (s *Service) UpdateParty(input UpdatePartyInput) (*domainInventory.Party, e
 	if err := party.Validate(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.UpdateParty(party); err != nil {
+	if err := s.writeRepo(input.AuthToken).UpdateParty(party); err != nil {
 		if errors.Is(err, domainErrors.ErrConcurrencyConflict) {
 			return nil, errors.New(ErrRecordModified)
 		}
 		return nil, mapPartyPersistenceError(err)
 	}
+	s.publish(domainEvents.TypePartyUpdated, "party", party.ID, input.AuthToken, party)
 	return party, nil
 }
 
-func (s *Service) ListParties(input ListPartiesInput) ([]domainInventory.Party, error) {
+func (s *Service) ListParties(input ListPartiesInput) ([]domainInventory.Party, domainInventory.PageInfo, error) {
 	if err := s.requireReadAccess(input.AuthToken); err != nil {
-		return nil, err
+		return nil, domainInventory.PageInfo{}, err
 	}
 
 	filter := domainInventory.PartyListFilter{
 		ActiveOnly: input.ActiveOnly,
 		PartyType:  domainInventory.ParsePartyType(input.PartyType),
 		Search:     strings.TrimSpace(input.Search),
+		Page:       pageRequest(input.Limit, input.Cursor, input.SortBy, input.SortOrder),
+	}
+	parties, info, err := s.repo.ListParties(filter)
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, mapValidationError(err)
 	}
-	return s.repo.ListParties(filter)
+	return parties, info, nil
 }
 
 func (s *Service) CreateGRNRecord(input CreateGRNInput) (*domainInventory.GRN, error) {
@@ -841,6 +983,7 @@ func (s *Service) CreateGRNRecord(input CreateGRNInput) (*domainInventory.GRN, e
 		SupplierID: input.SupplierID,
 		InvoiceNo:  input.InvoiceNo,
 		Notes:        input.Notes,
+		CreatedBy:  s.resolveSubject(input.AuthToken),
 		Lines:        make([]domainInventory.GRNLine, 0, len(input.Lines)),
 	}
 	for i, line := range input.Lines {
@@ -854,15 +997,16 @@ func (s *Service) CreateGRNRecord(input CreateGRNInput) (*domainInventory.GRN, e
 	if err := grn.Validate(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.CreateGRN(grn); err != nil {
+	if err := s.writeRepo(input.AuthToken).CreateGRN(grn); err != nil {
 		return nil, mapGRNPersistenceError(err)
 	}
+	s.publish(domainEvents.TypeGrnCreated, "grn", grn.ID, input.AuthToken, grn)
 	return grn, nil
 }
 
-func (s *Service) ListMaterialLots(input ListMaterialLotsInput) ([]domainInventory.MaterialLot, error) {
+func (s *Service) ListMaterialLots(input ListMaterialLotsInput) ([]domainInventory.MaterialLot, domainInventory.PageInfo, error) {
 	if err := s.requireReadAccess(input.AuthToken); err != nil {
-		return nil, err
+		return nil, domainInventory.PageInfo{}, err
 	}
 
 	filter := domainInventory.MaterialLotListFilter{
@@ -872,8 +1016,13 @@ func (s *Service) ListMaterialLots(input ListMaterialLotsInput) ([]domainInvento
 		GRNNumber:  strings.TrimSpace(input.GRNNumber),
 		ActiveOnly: input.ActiveOnly,
 		Search:     strings.TrimSpace(input.Search),
+		Page:       pageRequest(input.Limit, input.Cursor, input.SortBy, input.SortOrder),
 	}
-	return s.repo.ListMaterialLots(filter)
+	lots, info, err := s.repo.ListMaterialLots(filter)
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, mapValidationError(err)
+	}
+	return lots, info, nil
 }
 
 func (s *Service) RecordLotStockMovement(input RecordLotStockMovementInput) (*domainInventory.StockLedgerMovement, error) {
@@ -887,24 +1036,34 @@ func (s *Service) RecordLotStockMovement(input RecordLotStockMovementInput) (*do
 		Quantity:        input.Quantity,
 		ReferenceID:     input.ReferenceID,
 		Notes:           input.Notes,
+		CreatedBy:       s.resolveSubject(input.AuthToken),
 	}
 	if err := movement.ValidateNonInbound(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.RecordLotStockMovement(movement); err != nil {
+	if err := s.writeRepo(input.AuthToken).RecordLotStockMovement(movement); err != nil {
 		return nil, mapLotMovementPersistenceError(err)
 	}
+	s.publish(domainEvents.TypeLotMovementRecorded, "stock_movement", movement.ID, input.AuthToken, movement)
+	// OUT and ADJUSTMENT movements both take stock out of the lot.
+	s.publishIfBelowMinimum(movement.ItemID, -movement.Quantity, input.AuthToken)
 	return movement, nil
 }
 
-func (s *Service) ListLotStockMovements(input ListLotStockMovementsInput) ([]domainInventory.StockLedgerMovement, error) {
+func (s *Service) ListLotStockMovements(input ListLotStockMovementsInput) ([]domainInventory.StockLedgerMovement, domainInventory.PageInfo, error) {
 	if err := s.requireReadAccess(input.AuthToken); err != nil {
-		return nil, err
+		return nil, domainInventory.PageInfo{}, err
 	}
 	filter := domainInventory.StockLedgerMovementListFilter{
-		LotNumber: strings.TrimSpace(input.LotNumber),
+		LotNumber:   strings.TrimSpace(input.LotNumber),
+		ReferenceID: strings.TrimSpace(input.ReferenceID),
+		Page:        pageRequest(input.Limit, input.Cursor, input.SortBy, input.SortOrder),
 	}
-	return s.repo.ListLotStockMovements(filter)
+	movements, info, err := s.repo.ListLotStockMovements(filter)
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, mapValidationError(err)
+	}
+	return movements, info, nil
 }
 
 func (s *Service) CreateUnitConversionRule(input CreateUnitConversionRuleInput) (*domainInventory.UnitConversionRule, error) {
@@ -929,9 +1088,10 @@ func (s *Service) CreateUnitConversionRule(input CreateUnitConversionRuleInput)
 	if err := rule.Validate(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.CreateUnitConversionRule(rule); err != nil {
+	if err := s.writeRepo(input.AuthToken).CreateUnitConversionRule(rule); err != nil {
 		return nil, mapConversionPersistenceError(err)
 	}
+	s.publish(domainEvents.TypeConversionRuleAdded, "unit_conversion_rule", rule.ID, input.AuthToken, rule)
 	return rule, nil
 }
 
@@ -1077,17 +1237,27 @@ func (s *Service) CreateStockAdjustment(input CreateStockAdjustmentInput) (*doma
 	if err := adj.Validate(); err != nil {
 		return nil, mapValidationError(err)
 	}
-	if err := s.repo.CreateStockAdjustment(adj); err != nil {
+	if err := s.writeRepo(input.AuthToken).CreateStockAdjustment(adj); err != nil {
 		return nil, err
 	}
+	s.publish(domainEvents.TypeAdjustmentPosted, "stock_adjustment", adj.ID, input.AuthToken, adj)
+	s.publishIfBelowMinimum(adj.ItemID, adj.QtyDelta, input.AuthToken)
 	return adj, nil
 }
 
-func (s *Service) ListStockAdjustments(input ListStockAdjustmentsInput) ([]domainInventory.StockAdjustment, error) {
+func (s *Service) ListStockAdjustments(input ListStockAdjustmentsInput) ([]domainInventory.StockAdjustment, domainInventory.PageInfo, error) {
 	if err := s.requireReadAccess(input.AuthToken); err != nil {
-		return nil, err
+		return nil, domainInventory.PageInfo{}, err
+	}
+	filter := domainInventory.StockAdjustmentListFilter{
+		ItemID: input.ItemID,
+		Page:   pageRequest(input.Limit, input.Cursor, input.SortBy, input.SortOrder),
+	}
+	adjustments, info, err := s.repo.ListStockAdjustments(filter)
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, mapValidationError(err)
 	}
-	return s.repo.ListStockAdjustments(input.ItemID)
+	return adjustments, info, nil
 }
 
 func (s *Service) GetItemStockBalance(input GetItemStockBalanceInput) (float64, error) {
@@ -3,12 +3,14 @@ package inventory
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
 	appLicenseMode "masala_inventory_managment/internal/app/licensemode"
 	domainAuth "masala_inventory_managment/internal/domain/auth"
 	domainErrors "masala_inventory_managment/internal/domain/errors"
+	domainEvents "masala_inventory_managment/internal/domain/events"
 	domainInventory "masala_inventory_managment/internal/domain/inventory"
 )
 
@@ -26,6 +28,7 @@ type fakeInventoryRepo struct {
 	createConversionErr    error
 	findConversionErr      error
 	createStockAdjErr      error
+	importErr              error
 	items                  []domainInventory.Item
 	profiles               []domainInventory.PackagingProfile
 	recipes                []domainInventory.Recipe
@@ -37,10 +40,26 @@ type fakeInventoryRepo struct {
 	lastCreatedGRN         *domainInventory.GRN
 	lastLotMovement        *domainInventory.StockLedgerMovement
 	lastCreatedStockAdj    *domainInventory.StockAdjustment
+	lastImport             *domainInventory.MasterDataImport
 	stockAdjBalance        float64
+	lastActor              string
+}
+
+func (f *fakeInventoryRepo) WithActor(actor string) domainInventory.Repository {
+	f.lastActor = actor
+	return f
 }
 
 func (f *fakeInventoryRepo) CreateItem(*domainInventory.Item) error   { return f.createItemErr }
+func (f *fakeInventoryRepo) GetItem(id int64) (*domainInventory.Item, error) {
+	for i := range f.items {
+		if f.items[i].ID == id {
+			item := f.items[i]
+			return &item, nil
+		}
+	}
+	return nil, domainInventory.ErrItemNotFound
+}
 func (f *fakeInventoryRepo) CreateBatch(*domainInventory.Batch) error { return f.createBatchErr }
 func (f *fakeInventoryRepo) CreateGRN(grn *domainInventory.GRN) error {
 	if f.createGRNErr != nil {
@@ -58,8 +77,8 @@ func (f *fakeInventoryRepo) CreateGRN(grn *domainInventory.GRN) error {
 func (f *fakeInventoryRepo) UpdateItem(*domainInventory.Item) error   { return f.updateItemErr }
 func (f *fakeInventoryRepo) UpdateBatch(*domainInventory.Batch) error { return f.updateBatchErr }
 func (f *fakeInventoryRepo) UpdateGRN(*domainInventory.GRN) error     { return f.updateGRNErr }
-func (f *fakeInventoryRepo) ListItems(domainInventory.ItemListFilter) ([]domainInventory.Item, error) {
-	return f.items, nil
+func (f *fakeInventoryRepo) ListItems(domainInventory.ItemListFilter) ([]domainInventory.Item, domainInventory.PageInfo, error) {
+	return f.items, domainInventory.PageInfo{TotalCount: len(f.items)}, nil
 }
 func (f *fakeInventoryRepo) CreatePackagingProfile(*domainInventory.PackagingProfile) error {
 	return nil
@@ -117,8 +136,17 @@ func (f *fakeInventoryRepo) UpdateParty(party *domainInventory.Party) error {
 	}
 	return domainErrors.ErrConcurrencyConflict
 }
-func (f *fakeInventoryRepo) ListParties(domainInventory.PartyListFilter) ([]domainInventory.Party, error) {
-	return f.parties, nil
+func (f *fakeInventoryRepo) GetParty(id int64) (*domainInventory.Party, error) {
+	for i := range f.parties {
+		if f.parties[i].ID == id {
+			party := f.parties[i]
+			return &party, nil
+		}
+	}
+	return nil, domainInventory.ErrPartyNotFound
+}
+func (f *fakeInventoryRepo) ListParties(domainInventory.PartyListFilter) ([]domainInventory.Party, domainInventory.PageInfo, error) {
+	return f.parties, domainInventory.PageInfo{TotalCount: len(f.parties)}, nil
 }
 func (f *fakeInventoryRepo) CreateUnitConversionRule(rule *domainInventory.UnitConversionRule) error {
 	if f.createConversionErr != nil {
@@ -163,8 +191,30 @@ func (f *fakeInventoryRepo) FindUnitConversionRule(lookup domainInventory.UnitCo
 func (f *fakeInventoryRepo) ListUnitConversionRules(domainInventory.UnitConversionRuleFilter) ([]domainInventory.UnitConversionRule, error) {
 	return f.conversionRules, nil
 }
-func (f *fakeInventoryRepo) ListMaterialLots(domainInventory.MaterialLotListFilter) ([]domainInventory.MaterialLot, error) {
-	return f.materialLots, nil
+func (f *fakeInventoryRepo) ListMaterialLots(domainInventory.MaterialLotListFilter) ([]domainInventory.MaterialLot, domainInventory.PageInfo, error) {
+	return f.materialLots, domainInventory.PageInfo{TotalCount: len(f.materialLots)}, nil
+}
+func (f *fakeInventoryRepo) GetMaterialLot(lotNumber string) (*domainInventory.MaterialLot, error) {
+	for _, lot := range f.materialLots {
+		if lot.LotNumber == lotNumber {
+			copyLot := lot
+			return &copyLot, nil
+		}
+	}
+	return nil, domainInventory.ErrLotNotFound
+}
+func (f *fakeInventoryRepo) GetLotStockBalance(lotNumber string) (float64, error) {
+	lot, err := f.GetMaterialLot(lotNumber)
+	if err != nil {
+		return 0, err
+	}
+	balance := lot.QuantityReceived
+	for _, movement := range f.lotMovements {
+		if movement.LotNumber == lotNumber {
+			balance -= movement.Quantity
+		}
+	}
+	return balance, nil
 }
 func (f *fakeInventoryRepo) RecordLotStockMovement(movement *domainInventory.StockLedgerMovement) error {
 	if movement == nil {
@@ -177,17 +227,17 @@ func (f *fakeInventoryRepo) RecordLotStockMovement(movement *domainInventory.Sto
 	movement.ID = copyMovement.ID
 	return nil
 }
-func (f *fakeInventoryRepo) ListLotStockMovements(filter domainInventory.StockLedgerMovementListFilter) ([]domainInventory.StockLedgerMovement, error) {
-	if strings.TrimSpace(filter.LotNumber) == "" {
-		return nil, domainInventory.ErrLotNumberRequired
+func (f *fakeInventoryRepo) ListLotStockMovements(filter domainInventory.StockLedgerMovementListFilter) ([]domainInventory.StockLedgerMovement, domainInventory.PageInfo, error) {
+	if strings.TrimSpace(filter.LotNumber) == "" && strings.TrimSpace(filter.ReferenceID) == "" {
+		return nil, domainInventory.PageInfo{}, domainInventory.ErrLotNumberRequired
 	}
 	results := make([]domainInventory.StockLedgerMovement, 0)
 	for _, movement := range f.lotMovements {
-		if movement.LotNumber == filter.LotNumber {
+		if (filter.LotNumber == "" || movement.LotNumber == filter.LotNumber) && (filter.ReferenceID == "" || movement.ReferenceID == filter.ReferenceID) {
 			results = append(results, movement)
 		}
 	}
-	return results, nil
+	return results, domainInventory.PageInfo{TotalCount: len(results)}, nil
 }
 
 func (f *fakeInventoryRepo) CreateStockAdjustment(adj *domainInventory.StockAdjustment) error {
@@ -204,20 +254,37 @@ func (f *fakeInventoryRepo) CreateStockAdjustment(adj *domainInventory.StockAdju
 	return nil
 }
 
-func (f *fakeInventoryRepo) ListStockAdjustments(itemID int64) ([]domainInventory.StockAdjustment, error) {
+func (f *fakeInventoryRepo) ListStockAdjustments(filter domainInventory.StockAdjustmentListFilter) ([]domainInventory.StockAdjustment, domainInventory.PageInfo, error) {
 	results := make([]domainInventory.StockAdjustment, 0)
 	for _, adj := range f.stockAdjustments {
-		if adj.ItemID == itemID {
+		if adj.ItemID == filter.ItemID {
 			results = append(results, adj)
 		}
 	}
-	return results, nil
+	return results, domainInventory.PageInfo{TotalCount: len(results)}, nil
 }
 
 func (f *fakeInventoryRepo) GetItemStockBalance(itemID int64) (float64, error) {
 	return f.stockAdjBalance, nil
 }
 
+func (f *fakeInventoryRepo) ImportMasterData(batch *domainInventory.MasterDataImport) error {
+	if f.importErr != nil {
+		return f.importErr
+	}
+	for i := range batch.Parties {
+		batch.Parties[i].ID = int64(100 + i)
+	}
+	for i := range batch.Items {
+		batch.Items[i].ID = int64(200 + i)
+	}
+	for i := range batch.OpeningStock {
+		batch.OpeningStock[i].LotNumber = fmt.Sprintf("LOT-TEST-%03d", i+1)
+	}
+	f.lastImport = batch
+	return nil
+}
+
 func fixedRoleResolver(role domainAuth.Role, err error) func(string) (domainAuth.Role, error) {
 	return func(_ string) (domainAuth.Role, error) {
 		return role, err
@@ -454,7 +521,7 @@ func TestService_ListMaterialLots_OperatorAllowed(t *testing.T) {
 		},
 	}, fixedRoleResolver(domainAuth.RoleDataEntryOperator, nil), nil)
 
-	lots, err := svc.ListMaterialLots(ListMaterialLotsInput{
+	lots, _, err := svc.ListMaterialLots(ListMaterialLotsInput{
 		AuthToken: "operator-token",
 		Search:    "20260227",
 	})
@@ -530,7 +597,7 @@ func TestService_CreateItemMaster_ValidationErrorPayload(t *testing.T) {
 
 func TestService_ListItems_ForbiddenRole(t *testing.T) {
 	svc := NewService(&fakeInventoryRepo{}, fixedRoleResolver(domainAuth.Role("Viewer"), nil), nil)
-	_, err := svc.ListItems(ListItemsInput{AuthToken: "viewer-token"})
+	_, _, err := svc.ListItems(ListItemsInput{AuthToken: "viewer-token"})
 	if err == nil {
 		t.Fatalf("expected forbidden error")
 	}
@@ -820,7 +887,7 @@ func TestService_ListParties_ReadAllowedForOperator(t *testing.T) {
 		},
 	}, fixedRoleResolver(domainAuth.RoleDataEntryOperator, nil), nil)
 
-	result, err := svc.ListParties(ListPartiesInput{AuthToken: "operator-token", ActiveOnly: true})
+	result, _, err := svc.ListParties(ListPartiesInput{AuthToken: "operator-token", ActiveOnly: true})
 	if err != nil {
 		t.Fatalf("expected success, got %v", err)
 	}
@@ -1031,6 +1098,9 @@ func TestService_CreateStockAdjustment_HappyPath(t *testing.T) {
 	if repo.lastCreatedStockAdj == nil {
 		t.Fatal("expected lastCreatedStockAdj to be set")
 	}
+	if repo.lastActor != "operator-user" {
+		t.Fatalf("expected audit actor operator-user, got %q", repo.lastActor)
+	}
 }
 
 func TestService_CreateStockAdjustment_MissingReasonCode(t *testing.T) {
@@ -1110,3 +1180,118 @@ func TestService_CreateStockAdjustment_ForbiddenRole(t *testing.T) {
 		t.Fatalf("expected forbidden ServiceError, got %v", err)
 	}
 }
+
+type recordingPublisher struct {
+	events []domainEvents.Event
+}
+
+func (p *recordingPublisher) Publish(event domainEvents.Event) {
+	p.events = append(p.events, event)
+}
+
+func TestService_PublishesEventsOnlyAfterCommittedWrites(t *testing.T) {
+	appLicenseMode.SetWriteEnforcer(nil)
+	repo := &fakeInventoryRepo{}
+	publisher := &recordingPublisher{}
+	svc := NewService(repo, fixedRoleResolver(domainAuth.RoleDataEntryOperator, nil), func(string) (string, error) { return "operator", nil })
+	svc.SetEventPublisher(publisher)
+
+	grnInput := CreateGRNInput{
+		GRNNumber:  "GRN-EVT-1",
+		SupplierID: 1,
+		AuthToken:  "operator-token",
+		Lines:      []GRNLineInput{{ItemID: 10, QuantityReceived: 5}},
+	}
+	if _, err := svc.CreateGRNRecord(grnInput); err != nil {
+		t.Fatalf("CreateGRNRecord failed: %v", err)
+	}
+	if len(publisher.events) != 1 {
+		t.Fatalf("expected one event, got %+v", publisher.events)
+	}
+	event := publisher.events[0]
+	if event.Type != domainEvents.TypeGrnCreated || event.EntityID != "1" || event.Actor != "operator" {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	if !event.VisibleTo(domainAuth.RoleAdmin) || !event.VisibleTo(domainAuth.RoleDataEntryOperator) || event.VisibleTo("Auditor") {
+		t.Fatalf("expected inventory event to be visible to inventory readers only: %+v", event.Roles)
+	}
+
+	repo.createGRNErr = errors.New("disk full")
+	if _, err := svc.CreateGRNRecord(grnInput); err == nil {
+		t.Fatalf("expected failed write")
+	}
+	if len(publisher.events) != 1 {
+		t.Fatalf("expected no event for a failed write, got %+v", publisher.events)
+	}
+}
+
+type recordingOutbox struct {
+	events []domainEvents.Event
+	err    error
+}
+
+func (o *recordingOutbox) Enqueue(event domainEvents.Event) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.events = append(o.events, event)
+	return nil
+}
+
+func TestService_StoresEventsInOutboxBeforeReturning(t *testing.T) {
+	appLicenseMode.SetWriteEnforcer(nil)
+	repo := &fakeInventoryRepo{
+		items:           []domainInventory.Item{{ID: 5, SKU: "CUM-1", Name: "Cumin", BaseUnit: "kg", MinimumStock: 10}},
+		stockAdjBalance: 8,
+	}
+	outbox := &recordingOutbox{}
+	svc := NewService(repo, fixedRoleResolver(domainAuth.RoleAdmin, nil), func(string) (string, error) { return "admin", nil })
+	svc.SetEventOutbox(outbox)
+
+	if _, err := svc.CreateStockAdjustment(CreateStockAdjustmentInput{ItemID: 5, QtyDelta: -4, ReasonCode: "Damage", AuthToken: "admin-token"}); err != nil {
+		t.Fatalf("CreateStockAdjustment failed: %v", err)
+	}
+	if len(outbox.events) != 2 || outbox.events[0].Type != domainEvents.TypeAdjustmentPosted || outbox.events[1].Type != domainEvents.TypeStockBelowMinimum {
+		t.Fatalf("expected the adjustment and low stock events in the outbox without a publisher, got %+v", outbox.events)
+	}
+	if outbox.events[0].Actor != "admin" || outbox.events[0].OccurredAt.IsZero() {
+		t.Fatalf("expected a complete event, got %+v", outbox.events[0])
+	}
+
+	// The stock change is already committed; an outbox failure is logged, not reported as a failed write.
+	outbox.err = errors.New("database is locked")
+	if _, err := svc.CreateStockAdjustment(CreateStockAdjustmentInput{ItemID: 5, QtyDelta: -1, ReasonCode: "Damage", AuthToken: "admin-token"}); err != nil {
+		t.Fatalf("expected the committed adjustment to succeed, got %v", err)
+	}
+}
+
+func TestService_PublishesLowStockWhenAdjustmentCrossesMinimum(t *testing.T) {
+	appLicenseMode.SetWriteEnforcer(nil)
+	repo := &fakeInventoryRepo{
+		items:           []domainInventory.Item{{ID: 5, SKU: "CUM-1", Name: "Cumin", BaseUnit: "kg", MinimumStock: 10}},
+		stockAdjBalance: 8,
+	}
+	publisher := &recordingPublisher{}
+	svc := NewService(repo, fixedRoleResolver(domainAuth.RoleAdmin, nil), nil)
+	svc.SetEventPublisher(publisher)
+
+	input := CreateStockAdjustmentInput{ItemID: 5, QtyDelta: -4, ReasonCode: "Damage", AuthToken: "admin-token"}
+	if _, err := svc.CreateStockAdjustment(input); err != nil {
+		t.Fatalf("CreateStockAdjustment failed: %v", err)
+	}
+	if len(publisher.events) != 2 || publisher.events[1].Type != domainEvents.TypeStockBelowMinimum {
+		t.Fatalf("expected adjustment and low stock events, got %+v", publisher.events)
+	}
+	alert, ok := publisher.events[1].Data.(LowStockAlert)
+	if !ok || alert.Balance != 8 || alert.MinimumStock != 10 {
+		t.Fatalf("unexpected low stock data: %+v", publisher.events[1].Data)
+	}
+
+	repo.stockAdjBalance = 6
+	if _, err := svc.CreateStockAdjustment(CreateStockAdjustmentInput{ItemID: 5, QtyDelta: -2, ReasonCode: "Damage", AuthToken: "admin-token"}); err != nil {
+		t.Fatalf("CreateStockAdjustment failed: %v", err)
+	}
+	if len(publisher.events) != 3 {
+		t.Fatalf("expected no repeat alert while already below minimum, got %+v", publisher.events)
+	}
+}
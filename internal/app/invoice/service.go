@@ -0,0 +1,360 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	appLicenseMode "masala_inventory_managment/internal/app/licensemode"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+	infraExport "masala_inventory_managment/internal/infrastructure/export"
+	infraInvoice "masala_inventory_managment/internal/infrastructure/invoice"
+)
+
+const dispatchMovementType = "OUT"
+
+// LineInput prices one dispatched item. Items carry no tax master data, so the HSN code and
+// GST rate are given with the price.
+type LineInput struct {
+	ItemID    int64   `json:"item_id"`
+	UnitPrice float64 `json:"unit_price"`
+	HSNCode   string  `json:"hsn_code"`
+	GSTRate   float64 `json:"gst_rate"`
+}
+
+// GenerateInvoiceInput invoices a dispatch: the OUT lot movements recorded with
+// DispatchReference as their reference. PlaceOfSupply is a GST state code; when empty it is
+// taken from CustomerGSTIN. InvoiceDate is YYYY-MM-DD and defaults to today.
+type GenerateInvoiceInput struct {
+	DispatchReference string      `json:"dispatch_reference"`
+	CustomerID        int64       `json:"customer_id"`
+	CustomerGSTIN     string      `json:"customer_gstin"`
+	PlaceOfSupply     string      `json:"place_of_supply"`
+	InvoiceDate       string      `json:"invoice_date"`
+	Lines             []LineInput `json:"lines"`
+	Notes             string      `json:"notes"`
+	AuthToken         string      `json:"auth_token"`
+}
+
+type InvoiceIDInput struct {
+	ID        int64  `json:"id"`
+	AuthToken string `json:"auth_token"`
+}
+
+type ListInvoicesInput struct {
+	FinancialYear string `json:"financial_year"`
+	CustomerID    int64  `json:"customer_id"`
+	Limit         int    `json:"limit"`
+	AuthToken     string `json:"auth_token"`
+}
+
+// Document is a stored invoice PDF, ready to save or send.
+type Document struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Service issues GST tax invoices for dispatches. Admin and Data Entry Operator may issue
+// and reprint invoices.
+type Service struct {
+	inventory       domainInventory.Repository
+	invoices        domainInvoice.Repository
+	roleResolver    func(authToken string) (domainAuth.Role, error)
+	subjectResolver func(authToken string) (string, error)
+	company         infraExport.Company
+	render          func(*domainInvoice.Invoice) ([]byte, error)
+	now             func() time.Time
+}
+
+func NewService(
+	inventory domainInventory.Repository,
+	invoices domainInvoice.Repository,
+	roleResolver func(authToken string) (domainAuth.Role, error),
+	subjectResolver func(authToken string) (string, error),
+	company infraExport.Company,
+) *Service {
+	return &Service{
+		inventory:       inventory,
+		invoices:        invoices,
+		roleResolver:    roleResolver,
+		subjectResolver: subjectResolver,
+		company:         company,
+		render:          infraInvoice.Render,
+		now:             time.Now,
+	}
+}
+
+// GenerateInvoice issues the next invoice of the financial year for a dispatch and stores it
+// with its PDF. A dispatch can be invoiced once.
+func (s *Service) GenerateInvoice(input GenerateInvoiceInput) (*domainInvoice.Invoice, error) {
+	if err := s.requireAccess(input.AuthToken); err != nil {
+		return nil, err
+	}
+	if err := appLicenseMode.RequireWriteAccess(); err != nil {
+		return nil, err
+	}
+
+	reference := strings.TrimSpace(input.DispatchReference)
+	if reference == "" {
+		return nil, validationError("dispatch_reference", domainInvoice.ErrDispatchRequired)
+	}
+	seller, err := s.seller()
+	if err != nil {
+		return nil, err
+	}
+	buyer, err := s.buyer(input)
+	if err != nil {
+		return nil, err
+	}
+	placeOfSupply, err := resolvePlaceOfSupply(input.PlaceOfSupply, buyer)
+	if err != nil {
+		return nil, err
+	}
+	invoiceDate, err := s.invoiceDate(input.InvoiceDate)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.dispatchLines(reference, input.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	supplyType := domainInvoice.SupplyInterState
+	if placeOfSupply == seller.StateCode {
+		supplyType = domainInvoice.SupplyIntraState
+	}
+	inv := &domainInvoice.Invoice{
+		FinancialYear:     domainInvoice.FinancialYear(invoiceDate),
+		InvoiceDate:       invoiceDate,
+		DispatchReference: reference,
+		CustomerID:        input.CustomerID,
+		Seller:            seller,
+		Buyer:             buyer,
+		PlaceOfSupply:     placeOfSupply,
+		SupplyType:        supplyType,
+		Lines:             lines,
+		Notes:             strings.TrimSpace(input.Notes),
+		CreatedBy:         s.resolveSubject(input.AuthToken),
+	}
+	inv.Calculate()
+
+	if err := s.invoices.Create(inv, s.render); err != nil {
+		if errors.Is(err, domainInvoice.ErrDispatchAlreadyInvoiced) {
+			return nil, &appInventory.ServiceError{Code: "conflict", Message: strings.Replace(err.Error(), "dispatch", "dispatch "+reference, 1)}
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (s *Service) GetInvoice(input InvoiceIDInput) (*domainInvoice.Invoice, error) {
+	if err := s.requireAccess(input.AuthToken); err != nil {
+		return nil, err
+	}
+	return s.invoices.Get(input.ID)
+}
+
+// ListInvoices returns the invoice register, newest first.
+func (s *Service) ListInvoices(input ListInvoicesInput) ([]domainInvoice.Invoice, error) {
+	if err := s.requireAccess(input.AuthToken); err != nil {
+		return nil, err
+	}
+	return s.invoices.List(domainInvoice.ListFilter{
+		FinancialYear: strings.TrimSpace(input.FinancialYear),
+		CustomerID:    input.CustomerID,
+		Limit:         input.Limit,
+	})
+}
+
+// InvoicePDF returns the PDF stored when the invoice was issued, so every reprint is
+// identical to the original.
+func (s *Service) InvoicePDF(input InvoiceIDInput) (*Document, error) {
+	if err := s.requireAccess(input.AuthToken); err != nil {
+		return nil, err
+	}
+	inv, data, err := s.invoices.GetPDF(input.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{
+		FileName:    strings.ReplaceAll(inv.InvoiceNumber, "/", "-") + ".pdf",
+		ContentType: "application/pdf",
+		Data:        data,
+	}, nil
+}
+
+func (s *Service) seller() (domainInvoice.Party, error) {
+	gstin, err := domainInvoice.NormalizeGSTIN(s.company.GSTIN)
+	if err != nil || gstin == "" {
+		return domainInvoice.Party{}, domainInvoice.ErrSellerGSTINRequired
+	}
+	return domainInvoice.Party{
+		Name:      strings.TrimSpace(s.company.Name),
+		Address:   strings.TrimSpace(s.company.Address),
+		Phone:     strings.TrimSpace(s.company.Phone),
+		GSTIN:     gstin,
+		StateCode: gstin[:2],
+	}, nil
+}
+
+func (s *Service) buyer(input GenerateInvoiceInput) (domainInvoice.Party, error) {
+	if input.CustomerID <= 0 {
+		return domainInvoice.Party{}, validationError("customer_id", domainInvoice.ErrCustomerRequired)
+	}
+	party, err := s.inventory.GetParty(input.CustomerID)
+	if errors.Is(err, domainInventory.ErrPartyNotFound) {
+		return domainInvoice.Party{}, validationError("customer_id", domainInventory.ErrPartyNotFound)
+	}
+	if err != nil {
+		return domainInvoice.Party{}, err
+	}
+	if party.PartyType != domainInventory.PartyTypeCustomer {
+		return domainInvoice.Party{}, validationError("customer_id", domainInvoice.ErrCustomerNotCustomer)
+	}
+	gstin, err := domainInvoice.NormalizeGSTIN(input.CustomerGSTIN)
+	if err != nil {
+		return domainInvoice.Party{}, validationError("customer_gstin", err)
+	}
+	buyer := domainInvoice.Party{Name: party.Name, Address: party.Address, Phone: party.Phone, GSTIN: gstin}
+	if gstin != "" {
+		buyer.StateCode = gstin[:2]
+	}
+	return buyer, nil
+}
+
+// resolvePlaceOfSupply uses the given state code, or the buyer's GSTIN state. An unregistered
+// buyer is billed in the place of supply.
+func resolvePlaceOfSupply(requested string, buyer domainInvoice.Party) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		if buyer.StateCode == "" {
+			return "", validationError("place_of_supply", domainInvoice.ErrPlaceOfSupplyRequired)
+		}
+		return buyer.StateCode, nil
+	}
+	code, err := domainInvoice.NormalizeStateCode(requested)
+	if err != nil {
+		return "", validationError("place_of_supply", err)
+	}
+	return code, nil
+}
+
+func (s *Service) invoiceDate(value string) (time.Time, error) {
+	now := s.now()
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, now.Location())
+	if err != nil {
+		return time.Time{}, validationError("invoice_date", errors.New("invoice_date must be YYYY-MM-DD"))
+	}
+	return date, nil
+}
+
+// dispatchLines totals the dispatch's OUT movements per item, in the order items were
+// dispatched, and prices each from the matching input line.
+func (s *Service) dispatchLines(reference string, priced []LineInput) ([]domainInvoice.Line, error) {
+	movements, _, err := s.inventory.ListLotStockMovements(domainInventory.StockLedgerMovementListFilter{
+		ReferenceID: reference,
+		Page:        domainInventory.PageRequest{SortBy: "created_at", SortOrder: domainInventory.SortOrderAsc},
+	})
+	if err != nil {
+		return nil, err
+	}
+	quantities := make(map[int64]float64)
+	order := make([]int64, 0)
+	for _, movement := range movements {
+		if movement.TransactionType != dispatchMovementType {
+			continue
+		}
+		if _, seen := quantities[movement.ItemID]; !seen {
+			order = append(order, movement.ItemID)
+		}
+		quantities[movement.ItemID] += movement.Quantity
+	}
+	if len(order) == 0 {
+		return nil, validationError("dispatch_reference", domainInvoice.ErrDispatchNotFound)
+	}
+
+	prices := make(map[int64]LineInput, len(priced))
+	for _, line := range priced {
+		if _, dispatched := quantities[line.ItemID]; !dispatched {
+			return nil, validationError("lines", fmt.Errorf("%w: item %d", domainInvoice.ErrLineItemNotDispatched, line.ItemID))
+		}
+		line.HSNCode = strings.TrimSpace(line.HSNCode)
+		switch {
+		case line.UnitPrice < 0:
+			return nil, validationError("lines", domainInvoice.ErrLineUnitPriceInvalid)
+		case !domainInvoice.ValidHSN(line.HSNCode):
+			return nil, validationError("lines", domainInvoice.ErrLineHSNInvalid)
+		case !domainInvoice.ValidGSTRate(line.GSTRate):
+			return nil, validationError("lines", domainInvoice.ErrLineGSTRateInvalid)
+		}
+		prices[line.ItemID] = line
+	}
+
+	lines := make([]domainInvoice.Line, 0, len(order))
+	for _, itemID := range order {
+		price, ok := prices[itemID]
+		if !ok {
+			return nil, validationError("lines", fmt.Errorf("%w: item %d", domainInvoice.ErrLineItemUnpriced, itemID))
+		}
+		item, err := s.inventory.GetItem(itemID)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, domainInvoice.Line{
+			ItemID:      itemID,
+			SKU:         item.SKU,
+			Description: item.Name,
+			HSNCode:     price.HSNCode,
+			Quantity:    quantities[itemID],
+			Unit:        item.BaseUnit,
+			UnitPrice:   price.UnitPrice,
+			GSTRate:     price.GSTRate,
+		})
+	}
+	return lines, nil
+}
+
+func (s *Service) requireAccess(authToken string) error {
+	token := strings.TrimSpace(authToken)
+	if token == "" {
+		return &appInventory.ServiceError{Code: "unauthorized", Message: "missing authentication token"}
+	}
+	if s.roleResolver == nil {
+		return &appInventory.ServiceError{Code: "unauthorized", Message: "authentication resolver is not configured"}
+	}
+	role, err := s.roleResolver(token)
+	if err != nil {
+		return &appInventory.ServiceError{Code: "unauthorized", Message: "invalid or expired authentication token"}
+	}
+	if role != domainAuth.RoleAdmin && role != domainAuth.RoleDataEntryOperator {
+		return &appInventory.ServiceError{Code: "forbidden", Message: "role is not allowed to issue invoices"}
+	}
+	return nil
+}
+
+func (s *Service) resolveSubject(authToken string) string {
+	if s.subjectResolver == nil {
+		return "unknown"
+	}
+	subject, err := s.subjectResolver(authToken)
+	if err != nil || strings.TrimSpace(subject) == "" {
+		return "unknown"
+	}
+	return strings.TrimSpace(subject)
+}
+
+func validationError(field string, err error) error {
+	return &appInventory.ServiceError{
+		Code:    "validation_failed",
+		Message: "invoice validation failed",
+		Fields:  []appInventory.FieldError{{Field: field, Message: err.Error()}},
+	}
+}
@@ -0,0 +1,241 @@
+package invoice
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	masala_inventory_managment "masala_inventory_managment"
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	appLicenseMode "masala_inventory_managment/internal/app/licensemode"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+	"masala_inventory_managment/internal/infrastructure/db"
+	infraExport "masala_inventory_managment/internal/infrastructure/export"
+)
+
+type fixture struct {
+	svc       *Service
+	repo      *db.SqliteInventoryRepository
+	customer  int64
+	supplier  int64
+	cumin     int64
+	coriander int64
+}
+
+func setupInvoiceService(t *testing.T) *fixture {
+	t.Helper()
+	appLicenseMode.SetWriteEnforcer(nil)
+
+	manager := db.NewDatabaseManager(filepath.Join(t.TempDir(), "invoice_test.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("failed to connect to db: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+	if err := db.NewMigrator(manager).RunMigrations(masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	repo := db.NewSqliteInventoryRepository(manager.GetDB())
+	svc := NewService(repo, db.NewSqliteInvoiceRepository(manager.GetDB()), func(token string) (domainAuth.Role, error) {
+		switch token {
+		case "admin-token":
+			return domainAuth.RoleAdmin, nil
+		case "operator-token":
+			return domainAuth.RoleDataEntryOperator, nil
+		default:
+			return "", errors.New("invalid token")
+		}
+	}, func(token string) (string, error) {
+		return strings.TrimSuffix(token, "-token"), nil
+	}, infraExport.Company{Name: "Shree Masala Works", Address: "Plot 4, MIDC, Nashik", GSTIN: "27ABCDE1234F1Z5"})
+	svc.now = func() time.Time { return time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local) }
+
+	master := &domainInventory.MasterDataImport{
+		CreatedBy: "admin",
+		Items: []domainInventory.Item{
+			{SKU: "CUM-1", Name: "Cumin", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true},
+			{SKU: "COR-1", Name: "Coriander", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true},
+		},
+		Parties: []domainInventory.Party{
+			{PartyType: domainInventory.PartyTypeSupplier, Name: "Hill Farms", Phone: "98450", IsActive: true},
+			{PartyType: domainInventory.PartyTypeCustomer, Name: "Spice Mart", Phone: "98220", Address: "12 Market Road, Indore", IsActive: true},
+		},
+	}
+	if err := repo.ImportMasterData(master); err != nil {
+		t.Fatalf("failed to seed master data: %v", err)
+	}
+	f := &fixture{
+		svc: svc, repo: repo,
+		cumin: master.Items[0].ID, coriander: master.Items[1].ID,
+		supplier: master.Parties[0].ID, customer: master.Parties[1].ID,
+	}
+	stock := &domainInventory.MasterDataImport{
+		CreatedBy: "admin",
+		OpeningStock: []domainInventory.OpeningStockLine{
+			{ItemID: f.cumin, SupplierID: f.supplier, Quantity: 100, UnitCost: 180},
+			{ItemID: f.coriander, SupplierID: f.supplier, Quantity: 100, UnitCost: 90},
+		},
+	}
+	if err := repo.ImportMasterData(stock); err != nil {
+		t.Fatalf("failed to seed opening stock: %v", err)
+	}
+	return f
+}
+
+// dispatch records OUT movements from each item's lot under reference.
+func (f *fixture) dispatch(t *testing.T, reference string, quantities map[int64][]float64) {
+	t.Helper()
+	for itemID, parts := range quantities {
+		id := itemID
+		lots, _, err := f.repo.ListMaterialLots(domainInventory.MaterialLotListFilter{ItemID: &id})
+		if err != nil || len(lots) == 0 {
+			t.Fatalf("no lot for item %d (%v)", itemID, err)
+		}
+		for _, quantity := range parts {
+			movement := &domainInventory.StockLedgerMovement{
+				LotNumber: lots[0].LotNumber, TransactionType: "OUT", Quantity: quantity, ReferenceID: reference, CreatedBy: "admin",
+			}
+			if err := f.repo.RecordLotStockMovement(movement); err != nil {
+				t.Fatalf("failed to record dispatch: %v", err)
+			}
+		}
+	}
+}
+
+func TestService_GenerateInvoiceFromDispatch(t *testing.T) {
+	f := setupInvoiceService(t)
+	f.dispatch(t, "DSP-1", map[int64][]float64{f.cumin: {10, 2.5}, f.coriander: {4}})
+
+	inv, err := f.svc.GenerateInvoice(GenerateInvoiceInput{
+		DispatchReference: " DSP-1 ",
+		CustomerID:        f.customer,
+		PlaceOfSupply:     "27",
+		Lines: []LineInput{
+			{ItemID: f.cumin, UnitPrice: 420, HSNCode: "0909", GSTRate: 5},
+			{ItemID: f.coriander, UnitPrice: 150.5, HSNCode: "0909", GSTRate: 5},
+		},
+		AuthToken: "operator-token",
+	})
+	if err != nil {
+		t.Fatalf("GenerateInvoice failed: %v", err)
+	}
+	if inv.InvoiceNumber != "INV/2026-27/0001" || inv.SupplyType != domainInvoice.SupplyIntraState || inv.CreatedBy != "operator" {
+		t.Fatalf("unexpected invoice: %+v", inv)
+	}
+	if len(inv.Lines) != 2 {
+		t.Fatalf("expected one line per dispatched item, got %+v", inv.Lines)
+	}
+	var cumin domainInvoice.Line
+	for _, line := range inv.Lines {
+		if line.ItemID == f.cumin {
+			cumin = line
+		}
+	}
+	if cumin.Quantity != 12.5 || cumin.SKU != "CUM-1" || cumin.Unit != "kg" || cumin.TaxableValue != 5250 {
+		t.Fatalf("expected the two cumin movements to be combined, got %+v", cumin)
+	}
+	// 5250 + 602 taxable, 5% split into CGST and SGST, rounded to the rupee.
+	if inv.TaxableValue != 5852 || inv.CGST != 146.3 || inv.SGST != 146.3 || inv.IGST != 0 || inv.GrandTotal != 6145 || inv.RoundOff != 0.4 {
+		t.Fatalf("unexpected totals: %+v", inv)
+	}
+	if inv.Buyer.Address != "12 Market Road, Indore" || inv.Seller.StateCode != "27" {
+		t.Fatalf("unexpected parties: %+v / %+v", inv.Buyer, inv.Seller)
+	}
+
+	doc, err := f.svc.InvoicePDF(InvoiceIDInput{ID: inv.ID, AuthToken: "admin-token"})
+	if err != nil {
+		t.Fatalf("InvoicePDF failed: %v", err)
+	}
+	if doc.FileName != "INV-2026-27-0001.pdf" || !bytes.HasPrefix(doc.Data, []byte("%PDF-")) {
+		t.Fatalf("unexpected document %s (%d bytes)", doc.FileName, len(doc.Data))
+	}
+	again, err := f.svc.InvoicePDF(InvoiceIDInput{ID: inv.ID, AuthToken: "operator-token"})
+	if err != nil || !bytes.Equal(again.Data, doc.Data) {
+		t.Fatalf("expected the reprint to be the stored document (%v)", err)
+	}
+
+	_, err = f.svc.GenerateInvoice(GenerateInvoiceInput{
+		DispatchReference: "DSP-1", CustomerID: f.customer, PlaceOfSupply: "27",
+		Lines:     []LineInput{{ItemID: f.cumin, UnitPrice: 1, HSNCode: "0909", GSTRate: 5}, {ItemID: f.coriander, UnitPrice: 1, HSNCode: "0909", GSTRate: 5}},
+		AuthToken: "admin-token",
+	})
+	var serviceErr *appInventory.ServiceError
+	if !errors.As(err, &serviceErr) || serviceErr.Code != "conflict" || !strings.Contains(serviceErr.Message, "DSP-1 has already been invoiced as INV/2026-27/0001") {
+		t.Fatalf("expected a conflict naming the earlier invoice, got %v", err)
+	}
+}
+
+func TestService_GenerateInvoiceChargesIGSTForOtherStates(t *testing.T) {
+	f := setupInvoiceService(t)
+	f.dispatch(t, "DSP-2", map[int64][]float64{f.cumin: {10}})
+
+	inv, err := f.svc.GenerateInvoice(GenerateInvoiceInput{
+		DispatchReference: "DSP-2",
+		CustomerID:        f.customer,
+		CustomerGSTIN:     "23aaacs1234k1z9",
+		InvoiceDate:       "2027-03-31",
+		Lines:             []LineInput{{ItemID: f.cumin, UnitPrice: 99.99, HSNCode: "09093129", GSTRate: 5}},
+		AuthToken:         "admin-token",
+	})
+	if err != nil {
+		t.Fatalf("GenerateInvoice failed: %v", err)
+	}
+	if inv.PlaceOfSupply != "23" || inv.SupplyType != domainInvoice.SupplyInterState || inv.Buyer.GSTIN != "23AAACS1234K1Z9" {
+		t.Fatalf("expected the place of supply from the buyer's GSTIN, got %+v", inv)
+	}
+	if inv.IGST != 50 || inv.CGST != 0 || inv.GrandTotal != 1050 || inv.RoundOff != 0.1 {
+		t.Fatalf("unexpected totals: %+v", inv)
+	}
+	if inv.FinancialYear != "2026-27" || inv.AmountInWords != "Rupees One Thousand Fifty Only" {
+		t.Fatalf("unexpected invoice: %+v", inv)
+	}
+
+	list, err := f.svc.ListInvoices(ListInvoicesInput{CustomerID: f.customer, AuthToken: "operator-token"})
+	if err != nil || len(list) != 1 || list[0].ID != inv.ID {
+		t.Fatalf("unexpected register %+v (%v)", list, err)
+	}
+}
+
+func TestService_GenerateInvoiceRejectsIncompleteRequests(t *testing.T) {
+	f := setupInvoiceService(t)
+	f.dispatch(t, "DSP-3", map[int64][]float64{f.cumin: {5}, f.coriander: {5}})
+	priced := []LineInput{
+		{ItemID: f.cumin, UnitPrice: 100, HSNCode: "0909", GSTRate: 5},
+		{ItemID: f.coriander, UnitPrice: 100, HSNCode: "0909", GSTRate: 5},
+	}
+
+	cases := []struct {
+		name  string
+		input GenerateInvoiceInput
+		code  string
+		field string
+	}{
+		{name: "signed out", input: GenerateInvoiceInput{DispatchReference: "DSP-3", AuthToken: "expired"}, code: "unauthorized"},
+		{name: "unknown dispatch", input: GenerateInvoiceInput{DispatchReference: "DSP-404", CustomerID: f.customer, PlaceOfSupply: "27", Lines: priced, AuthToken: "admin-token"}, code: "validation_failed", field: "dispatch_reference"},
+		{name: "supplier as buyer", input: GenerateInvoiceInput{DispatchReference: "DSP-3", CustomerID: f.supplier, PlaceOfSupply: "27", Lines: priced, AuthToken: "admin-token"}, code: "validation_failed", field: "customer_id"},
+		{name: "no place of supply", input: GenerateInvoiceInput{DispatchReference: "DSP-3", CustomerID: f.customer, Lines: priced, AuthToken: "admin-token"}, code: "validation_failed", field: "place_of_supply"},
+		{name: "unpriced item", input: GenerateInvoiceInput{DispatchReference: "DSP-3", CustomerID: f.customer, PlaceOfSupply: "27", Lines: priced[:1], AuthToken: "admin-token"}, code: "validation_failed", field: "lines"},
+		{name: "bad hsn", input: GenerateInvoiceInput{DispatchReference: "DSP-3", CustomerID: f.customer, PlaceOfSupply: "27", Lines: []LineInput{priced[0], {ItemID: f.coriander, UnitPrice: 1, HSNCode: "09", GSTRate: 5}}, AuthToken: "admin-token"}, code: "validation_failed", field: "lines"},
+		{name: "bad rate", input: GenerateInvoiceInput{DispatchReference: "DSP-3", CustomerID: f.customer, PlaceOfSupply: "27", Lines: []LineInput{priced[0], {ItemID: f.coriander, UnitPrice: 1, HSNCode: "0909", GSTRate: 7}}, AuthToken: "admin-token"}, code: "validation_failed", field: "lines"},
+	}
+	for _, tc := range cases {
+		_, err := f.svc.GenerateInvoice(tc.input)
+		var serviceErr *appInventory.ServiceError
+		if !errors.As(err, &serviceErr) || serviceErr.Code != tc.code {
+			t.Fatalf("%s: expected %s, got %v", tc.name, tc.code, err)
+		}
+		if tc.field != "" && (len(serviceErr.Fields) != 1 || serviceErr.Fields[0].Field != tc.field) {
+			t.Fatalf("%s: expected a %s field error, got %+v", tc.name, tc.field, serviceErr.Fields)
+		}
+	}
+
+	f.svc.company.GSTIN = ""
+	if _, err := f.svc.GenerateInvoice(GenerateInvoiceInput{DispatchReference: "DSP-3", CustomerID: f.customer, PlaceOfSupply: "27", Lines: priced, AuthToken: "admin-token"}); !errors.Is(err, domainInvoice.ErrSellerGSTINRequired) {
+		t.Fatalf("expected a missing company GSTIN to stop invoicing, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appInvoice "masala_inventory_managment/internal/app/invoice"
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// SaveInvoicePDFInput saves an issued invoice on this machine. When Path is empty a save
+// dialog asks where to put it.
+type SaveInvoicePDFInput struct {
+	Invoice appInvoice.InvoiceIDInput `json:"invoice"`
+	Path    string                    `json:"path"`
+}
+
+func (a *App) SetInvoiceService(service *appInvoice.Service) {
+	a.invoiceService = service
+}
+
+// GenerateInvoice issues the next tax invoice for a dispatch and stores its PDF.
+func (a *App) GenerateInvoice(input appInvoice.GenerateInvoiceInput) (*domainInvoice.Invoice, error) {
+	if !a.isServer && a.invoiceService == nil {
+		var result domainInvoice.Invoice
+		if err := postToServerAPI("/invoices/create", input, &result); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+	if a.invoiceService == nil {
+		return nil, fmt.Errorf("invoice service is not configured")
+	}
+	return a.invoiceService.GenerateInvoice(input)
+}
+
+func (a *App) GetInvoice(input appInvoice.InvoiceIDInput) (*domainInvoice.Invoice, error) {
+	if !a.isServer && a.invoiceService == nil {
+		var result domainInvoice.Invoice
+		if err := postToServerAPI("/invoices/get", input, &result); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+	if a.invoiceService == nil {
+		return nil, fmt.Errorf("invoice service is not configured")
+	}
+	return a.invoiceService.GetInvoice(input)
+}
+
+func (a *App) ListInvoices(input appInvoice.ListInvoicesInput) ([]domainInvoice.Invoice, error) {
+	if !a.isServer && a.invoiceService == nil {
+		var result []domainInvoice.Invoice
+		if err := postToServerAPI("/invoices/list", input, &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if a.invoiceService == nil {
+		return nil, fmt.Errorf("invoice service is not configured")
+	}
+	return a.invoiceService.ListInvoices(input)
+}
+
+// InvoicePDF returns the stored PDF of an issued invoice for the server API. Server only.
+func (a *App) InvoicePDF(input appInvoice.InvoiceIDInput) (*appInvoice.Document, error) {
+	if a.invoiceService == nil {
+		return nil, fmt.Errorf("invoice service is not configured")
+	}
+	return a.invoiceService.InvoicePDF(input)
+}
+
+// SaveInvoicePDF saves the stored PDF of an issued invoice for printing. The file is the
+// one kept when the invoice was issued, so reprints match the original.
+func (a *App) SaveInvoicePDF(input SaveInvoicePDFInput) (ExportFileResult, error) {
+	var document *appInvoice.Document
+	if a.isServer || a.invoiceService != nil {
+		opened, err := a.InvoicePDF(input.Invoice)
+		if err != nil {
+			return ExportFileResult{}, err
+		}
+		document = opened
+	}
+
+	path := strings.TrimSpace(input.Path)
+	if path == "" {
+		name := fmt.Sprintf("invoice-%d.pdf", input.Invoice.ID)
+		if document != nil {
+			name = document.FileName
+		}
+		chosen, err := a.chooseDocumentPath("Save invoice", name)
+		if err != nil || chosen == "" {
+			return ExportFileResult{}, err
+		}
+		path = chosen
+	}
+
+	var data []byte
+	if document != nil {
+		data = document.Data
+	}
+	return saveServerDocument(path, data, "/invoices/pdf", input.Invoice)
+}
+
+// saveServerDocument writes a rendered document to path. When data is nil the document is
+// downloaded from the server route instead. It is written beside the destination and
+// renamed, so a failure never leaves a partial file under the chosen name.
+func saveServerDocument(path string, data []byte, route string, payload interface{}) (ExportFileResult, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+	if err != nil {
+		return ExportFileResult{}, fmt.Errorf("failed to create file: %w", err)
+	}
+	tempPath := file.Name()
+	defer os.Remove(tempPath)
+
+	var written int64
+	if data != nil {
+		written, err = io.Copy(file, bytes.NewReader(data))
+	} else {
+		written, err = downloadFromServerAPI(route, payload, file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return ExportFileResult{}, err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return ExportFileResult{}, fmt.Errorf("failed to save file: %w", err)
+	}
+	return ExportFileResult{Path: path, Bytes: written}, nil
+}
+
+// chooseDocumentPath asks where to save a document, offering name and a filter for its
+// extension.
+func (a *App) chooseDocumentPath(title, name string) (string, error) {
+	if a.ctx == nil {
+		return "", fmt.Errorf("%s path is required", strings.ToLower(strings.TrimPrefix(title, "Save ")))
+	}
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	return wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           title,
+		DefaultFilename: name,
+		Filters:         []wailsRuntime.FileFilter{{DisplayName: strings.ToUpper(ext), Pattern: "*." + ext}},
+	})
+}
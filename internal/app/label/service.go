@@ -0,0 +1,259 @@
+package label
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	infraLabel "masala_inventory_managment/internal/infrastructure/label"
+)
+
+const (
+	maxCopies = 500
+	maxLabels = 1000
+)
+
+// LabelInput asks for labels for one lot. Quantity overrides the quantity received, for
+// finished packs labelled with their net quantity; BestBefore is YYYY-MM-DD and optional.
+type LabelInput struct {
+	LotNumber  string   `json:"lot_number"`
+	Quantity   *float64 `json:"quantity"`
+	Copies     int      `json:"copies"`
+	BestBefore string   `json:"best_before"`
+}
+
+// PrintLabelsInput renders labels for one or more lots. Symbology is code128 (default) or
+// qr, Format pdf (default) or png, Size one of 50x25, 75x50, 100x50 (default) or 100x150 mm,
+// and DPI 203 (default) or 300.
+type PrintLabelsInput struct {
+	Labels    []LabelInput `json:"labels"`
+	Symbology string       `json:"symbology"`
+	Format    string       `json:"format"`
+	Size      string       `json:"size"`
+	DPI       int          `json:"dpi"`
+	AuthToken string       `json:"auth_token"`
+}
+
+// Document is a rendered label file.
+type Document struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+type LookupInput struct {
+	Code      string `json:"code"`
+	AuthToken string `json:"auth_token"`
+}
+
+// LotLookup is what a scanned label resolves to.
+type LotLookup struct {
+	Lot      domainInventory.MaterialLot `json:"lot"`
+	ItemSKU  string                      `json:"item_sku"`
+	ItemName string                      `json:"item_name"`
+	Unit     string                      `json:"unit"`
+	Balance  float64                     `json:"balance"`
+}
+
+// Service prints lot labels and resolves scanned codes. Labels encode the lot number, so a
+// scan gives back exactly what nextLotNumberTx issued.
+type Service struct {
+	inventory    domainInventory.Repository
+	roleResolver func(authToken string) (domainAuth.Role, error)
+}
+
+func NewService(inventory domainInventory.Repository, roleResolver func(authToken string) (domainAuth.Role, error)) *Service {
+	return &Service{inventory: inventory, roleResolver: roleResolver}
+}
+
+func (s *Service) PrintLabels(input PrintLabelsInput) (*Document, error) {
+	if err := s.requireAccess(input.AuthToken); err != nil {
+		return nil, err
+	}
+	opts, err := infraLabel.Options{
+		Symbology: input.Symbology,
+		Format:    input.Format,
+		Size:      input.Size,
+		DPI:       input.DPI,
+	}.Normalize()
+	if err != nil {
+		return nil, validationError(optionField(err), err)
+	}
+	if len(input.Labels) == 0 {
+		return nil, validationError("labels", errors.New("at least one lot is required"))
+	}
+
+	var labels []infraLabel.Label
+	for i, requested := range input.Labels {
+		field := fmt.Sprintf("labels[%d]", i)
+		l, err := s.label(requested)
+		if err != nil {
+			if errors.Is(err, domainInventory.ErrLotNotFound) || errors.Is(err, domainInventory.ErrLotNumberRequired) {
+				return nil, validationError(field+".lot_number", err)
+			}
+			var fieldErr *labelFieldError
+			if errors.As(err, &fieldErr) {
+				return nil, validationError(field+"."+fieldErr.field, fieldErr.err)
+			}
+			return nil, err
+		}
+		copies := requested.Copies
+		if copies == 0 {
+			copies = 1
+		}
+		if copies < 0 || copies > maxCopies {
+			return nil, validationError(field+".copies", fmt.Errorf("copies must be between 1 and %d", maxCopies))
+		}
+		if len(labels)+copies > maxLabels {
+			return nil, validationError("labels", fmt.Errorf("at most %d labels can be printed at once", maxLabels))
+		}
+		for n := 0; n < copies; n++ {
+			labels = append(labels, l)
+		}
+	}
+	if opts.Format == infraLabel.FormatPNG && len(labels) != 1 {
+		return nil, validationError("format", infraLabel.ErrPNGSingleLabel)
+	}
+
+	data, err := infraLabel.Render(labels, opts)
+	if err != nil {
+		if errors.Is(err, infraLabel.ErrCodeTooLong) {
+			return nil, validationError("size", err)
+		}
+		return nil, err
+	}
+	name := "labels"
+	if len(input.Labels) == 1 {
+		name = labels[0].Code + "-label"
+	}
+	return &Document{FileName: name + "." + opts.Format, ContentType: opts.ContentType(), Data: data}, nil
+}
+
+// Lookup resolves a scanned code to its lot and what remains of it. Codes are matched
+// without regard to case or surrounding whitespace, and a scanner's symbology identifier
+// prefix (such as ]C0 or ]Q1) is ignored.
+func (s *Service) Lookup(input LookupInput) (*LotLookup, error) {
+	if err := s.requireAccess(input.AuthToken); err != nil {
+		return nil, err
+	}
+	code := NormalizeCode(input.Code)
+	if code == "" {
+		return nil, validationError("code", domainInventory.ErrLotNumberRequired)
+	}
+	lot, err := s.inventory.GetMaterialLot(code)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.inventory.GetItem(lot.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := s.inventory.GetLotStockBalance(lot.LotNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &LotLookup{Lot: *lot, ItemSKU: item.SKU, ItemName: item.Name, Unit: item.BaseUnit, Balance: balance}, nil
+}
+
+// NormalizeCode turns scanner output into a lot number.
+func NormalizeCode(code string) string {
+	code = strings.TrimSpace(code)
+	if len(code) > 3 && code[0] == ']' {
+		code = code[3:]
+	}
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+type labelFieldError struct {
+	field string
+	err   error
+}
+
+func (e *labelFieldError) Error() string { return e.err.Error() }
+
+func (s *Service) label(input LabelInput) (infraLabel.Label, error) {
+	lotNumber := NormalizeCode(input.LotNumber)
+	if lotNumber == "" {
+		return infraLabel.Label{}, domainInventory.ErrLotNumberRequired
+	}
+	lot, err := s.inventory.GetMaterialLot(lotNumber)
+	if err != nil {
+		return infraLabel.Label{}, err
+	}
+	item, err := s.inventory.GetItem(lot.ItemID)
+	if err != nil {
+		return infraLabel.Label{}, err
+	}
+
+	quantity := lot.QuantityReceived
+	if input.Quantity != nil {
+		if *input.Quantity <= 0 {
+			return infraLabel.Label{}, &labelFieldError{field: "quantity", err: errors.New("quantity must be greater than zero")}
+		}
+		quantity = *input.Quantity
+	}
+	var bestBefore time.Time
+	if value := strings.TrimSpace(input.BestBefore); value != "" {
+		bestBefore, err = time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return infraLabel.Label{}, &labelFieldError{field: "best_before", err: errors.New("best_before must be YYYY-MM-DD")}
+		}
+	}
+
+	name := item.Name
+	if item.SKU != "" {
+		name += " (" + item.SKU + ")"
+	}
+	return infraLabel.Label{
+		Code:       lot.LotNumber,
+		Item:       name,
+		Quantity:   strings.TrimSpace(strconv.FormatFloat(quantity, 'f', -1, 64) + " " + item.BaseUnit),
+		GRN:        lot.GRNNumber,
+		Received:   lot.CreatedAt.Local(),
+		BestBefore: bestBefore,
+	}, nil
+}
+
+func optionField(err error) string {
+	switch {
+	case errors.Is(err, infraLabel.ErrUnsupportedSymbology):
+		return "symbology"
+	case errors.Is(err, infraLabel.ErrUnsupportedFormat):
+		return "format"
+	case errors.Is(err, infraLabel.ErrUnsupportedDPI):
+		return "dpi"
+	default:
+		return "size"
+	}
+}
+
+func (s *Service) requireAccess(authToken string) error {
+	token := strings.TrimSpace(authToken)
+	if token == "" {
+		return &appInventory.ServiceError{Code: "unauthorized", Message: "missing authentication token"}
+	}
+	if s.roleResolver == nil {
+		return &appInventory.ServiceError{Code: "unauthorized", Message: "authentication resolver is not configured"}
+	}
+	role, err := s.roleResolver(token)
+	if err != nil {
+		return &appInventory.ServiceError{Code: "unauthorized", Message: "invalid or expired authentication token"}
+	}
+	if role != domainAuth.RoleAdmin && role != domainAuth.RoleDataEntryOperator {
+		return &appInventory.ServiceError{Code: "forbidden", Message: "role is not allowed to print labels"}
+	}
+	return nil
+}
+
+func validationError(field string, err error) error {
+	return &appInventory.ServiceError{
+		Code:    "validation_failed",
+		Message: "label validation failed",
+		Fields:  []appInventory.FieldError{{Field: field, Message: err.Error()}},
+	}
+}
@@ -0,0 +1,159 @@
+package label
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	masala_inventory_managment "masala_inventory_managment"
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+func setupLabelService(t *testing.T) (*Service, *db.SqliteInventoryRepository, []string) {
+	t.Helper()
+	manager := db.NewDatabaseManager(filepath.Join(t.TempDir(), "label_test.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("failed to connect to db: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+	if err := db.NewMigrator(manager).RunMigrations(masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	repo := db.NewSqliteInventoryRepository(manager.GetDB())
+	master := &domainInventory.MasterDataImport{
+		CreatedBy: "admin",
+		Items: []domainInventory.Item{
+			{SKU: "CUM-1", Name: "Cumin", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true},
+			{SKU: "GM-BULK", Name: "Garam Masala", ItemType: domainInventory.ItemTypeBulkPowder, BaseUnit: "kg", IsActive: true},
+		},
+		Parties: []domainInventory.Party{
+			{PartyType: domainInventory.PartyTypeSupplier, Name: "Hill Farms", Phone: "98450", IsActive: true},
+		},
+	}
+	if err := repo.ImportMasterData(master); err != nil {
+		t.Fatalf("failed to seed master data: %v", err)
+	}
+	stock := &domainInventory.MasterDataImport{
+		CreatedBy: "admin",
+		OpeningStock: []domainInventory.OpeningStockLine{
+			{ItemID: master.Items[0].ID, SupplierID: master.Parties[0].ID, Quantity: 40, UnitCost: 180},
+			{ItemID: master.Items[1].ID, SupplierID: master.Parties[0].ID, Quantity: 200, UnitCost: 45},
+		},
+	}
+	if err := repo.ImportMasterData(stock); err != nil {
+		t.Fatalf("failed to seed opening stock: %v", err)
+	}
+	var lots []string
+	for _, line := range stock.OpeningStock {
+		id := line.ItemID
+		found, _, err := repo.ListMaterialLots(domainInventory.MaterialLotListFilter{ItemID: &id})
+		if err != nil || len(found) != 1 {
+			t.Fatalf("expected one lot per item (%v)", err)
+		}
+		lots = append(lots, found[0].LotNumber)
+	}
+
+	svc := NewService(repo, func(token string) (domainAuth.Role, error) {
+		if token == "operator-token" {
+			return domainAuth.RoleDataEntryOperator, nil
+		}
+		return "", errors.New("invalid token")
+	})
+	return svc, repo, lots
+}
+
+func TestService_PrintLabelsForSeveralLots(t *testing.T) {
+	svc, _, lots := setupLabelService(t)
+	pack := 0.1
+
+	doc, err := svc.PrintLabels(PrintLabelsInput{
+		Labels: []LabelInput{
+			{LotNumber: lots[0]},
+			{LotNumber: lots[1], Quantity: &pack, Copies: 3, BestBefore: "2027-06-30"},
+		},
+		Symbology: "qr",
+		Size:      "50x25",
+		AuthToken: "operator-token",
+	})
+	if err != nil {
+		t.Fatalf("PrintLabels failed: %v", err)
+	}
+	if doc.FileName != "labels.pdf" || doc.ContentType != "application/pdf" {
+		t.Fatalf("unexpected document %s %s", doc.FileName, doc.ContentType)
+	}
+	if count := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(doc.Data); count == nil || string(count[1]) != "4" {
+		t.Fatalf("expected one page per label and copy, got %q", count)
+	}
+
+	single, err := svc.PrintLabels(PrintLabelsInput{
+		Labels:    []LabelInput{{LotNumber: " " + lots[0] + " "}},
+		Format:    "PNG",
+		AuthToken: "operator-token",
+	})
+	if err != nil {
+		t.Fatalf("PrintLabels failed: %v", err)
+	}
+	if single.FileName != lots[0]+"-label.png" || single.ContentType != "image/png" || !bytes.HasPrefix(single.Data, []byte("\x89PNG")) {
+		t.Fatalf("unexpected document %s %s", single.FileName, single.ContentType)
+	}
+}
+
+func TestService_PrintLabelsRejectsBadRequests(t *testing.T) {
+	svc, _, lots := setupLabelService(t)
+	zero := 0.0
+
+	cases := []struct {
+		input PrintLabelsInput
+		code  string
+		field string
+	}{
+		{PrintLabelsInput{Labels: []LabelInput{{LotNumber: lots[0]}}}, "unauthorized", ""},
+		{PrintLabelsInput{AuthToken: "operator-token"}, "validation_failed", "labels"},
+		{PrintLabelsInput{Labels: []LabelInput{{LotNumber: "LOT-19990101-001"}}, AuthToken: "operator-token"}, "validation_failed", "labels[0].lot_number"},
+		{PrintLabelsInput{Labels: []LabelInput{{LotNumber: lots[0], Copies: 501}}, AuthToken: "operator-token"}, "validation_failed", "labels[0].copies"},
+		{PrintLabelsInput{Labels: []LabelInput{{LotNumber: lots[0], Quantity: &zero}}, AuthToken: "operator-token"}, "validation_failed", "labels[0].quantity"},
+		{PrintLabelsInput{Labels: []LabelInput{{LotNumber: lots[0], BestBefore: "30/06/2027"}}, AuthToken: "operator-token"}, "validation_failed", "labels[0].best_before"},
+		{PrintLabelsInput{Labels: []LabelInput{{LotNumber: lots[0], Copies: 2}}, Format: "png", AuthToken: "operator-token"}, "validation_failed", "format"},
+		{PrintLabelsInput{Labels: []LabelInput{{LotNumber: lots[0]}}, Size: "4x6", AuthToken: "operator-token"}, "validation_failed", "size"},
+	}
+	for _, tc := range cases {
+		_, err := svc.PrintLabels(tc.input)
+		var serviceErr *appInventory.ServiceError
+		if !errors.As(err, &serviceErr) || serviceErr.Code != tc.code {
+			t.Fatalf("expected %s for %+v, got %v", tc.code, tc.input, err)
+		}
+		if tc.field != "" && (len(serviceErr.Fields) != 1 || serviceErr.Fields[0].Field != tc.field) {
+			t.Fatalf("expected a %s field error, got %+v", tc.field, serviceErr.Fields)
+		}
+	}
+}
+
+func TestService_LookupResolvesScannedCodeToLotBalance(t *testing.T) {
+	svc, repo, lots := setupLabelService(t)
+	if err := repo.RecordLotStockMovement(&domainInventory.StockLedgerMovement{LotNumber: lots[0], TransactionType: "OUT", Quantity: 15}); err != nil {
+		t.Fatalf("RecordLotStockMovement failed: %v", err)
+	}
+
+	// A scanner set to send its symbology identifier, in lower case, with a trailing newline.
+	found, err := svc.Lookup(LookupInput{Code: "]C0" + lowercase(lots[0]) + "\n", AuthToken: "operator-token"})
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if found.Lot.LotNumber != lots[0] || found.ItemSKU != "CUM-1" || found.Unit != "kg" || found.Balance != 25 {
+		t.Fatalf("unexpected lookup: %+v", found)
+	}
+
+	if _, err := svc.Lookup(LookupInput{Code: "LOT-19990101-001", AuthToken: "operator-token"}); !errors.Is(err, domainInventory.ErrLotNotFound) {
+		t.Fatalf("expected ErrLotNotFound, got %v", err)
+	}
+}
+
+func lowercase(s string) string {
+	return string(bytes.ToLower([]byte(s)))
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	appLabel "masala_inventory_managment/internal/app/label"
+)
+
+// SaveLabelsInput saves rendered labels on this machine for the label printer. When Path is
+// empty a save dialog asks where to put them.
+type SaveLabelsInput struct {
+	Labels appLabel.PrintLabelsInput `json:"labels"`
+	Path   string                    `json:"path"`
+}
+
+func (a *App) SetLabelService(service *appLabel.Service) {
+	a.labelService = service
+}
+
+// PrintLabels renders lot labels for the server API. Server only.
+func (a *App) PrintLabels(input appLabel.PrintLabelsInput) (*appLabel.Document, error) {
+	if a.labelService == nil {
+		return nil, fmt.Errorf("label service is not configured")
+	}
+	return a.labelService.PrintLabels(input)
+}
+
+// SaveLabels renders lot labels as PDF or PNG and saves them.
+func (a *App) SaveLabels(input SaveLabelsInput) (ExportFileResult, error) {
+	var document *appLabel.Document
+	if a.isServer || a.labelService != nil {
+		rendered, err := a.PrintLabels(input.Labels)
+		if err != nil {
+			return ExportFileResult{}, err
+		}
+		document = rendered
+	}
+
+	path := strings.TrimSpace(input.Path)
+	if path == "" {
+		name := "labels.pdf"
+		if document != nil {
+			name = document.FileName
+		} else if strings.EqualFold(strings.TrimSpace(input.Labels.Format), "png") {
+			name = "labels.png"
+		}
+		chosen, err := a.chooseDocumentPath("Save labels", name)
+		if err != nil || chosen == "" {
+			return ExportFileResult{}, err
+		}
+		path = chosen
+	}
+
+	var data []byte
+	if document != nil {
+		data = document.Data
+	}
+	return saveServerDocument(path, data, "/labels", input.Labels)
+}
+
+// LookupLabel resolves a scanned label to its lot and remaining balance.
+func (a *App) LookupLabel(input appLabel.LookupInput) (*appLabel.LotLookup, error) {
+	if !a.isServer && a.labelService == nil {
+		var result appLabel.LotLookup
+		if err := postToServerAPI("/labels/lookup", input, &result); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+	if a.labelService == nil {
+		return nil, fmt.Errorf("label service is not configured")
+	}
+	return a.labelService.Lookup(input)
+}
@@ -0,0 +1,374 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	domainOffline "masala_inventory_managment/internal/domain/offline"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+const (
+	offlineQueueFileName = "offline_queue.db"
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	OfflineConflictRetry   = "retry"
+	OfflineConflictDiscard = "discard"
+)
+
+// offlineOperationPaths maps queued operations to the server API route that applies them.
+var offlineOperationPaths = map[string]string{
+	domainOffline.OperationCreateGRN:             "/inventory/grns/create",
+	domainOffline.OperationRecordLotMovement:     "/inventory/lots/movements/create",
+	domainOffline.OperationCreateStockAdjustment: "/inventory/reconciliation/create",
+}
+
+// OfflineQueuedError is returned by a client write that could not reach the server and was
+// saved to the local queue instead. It will be sent automatically once the server is back.
+type OfflineQueuedError struct {
+	EntryID        int64
+	IdempotencyKey string
+}
+
+func (e *OfflineQueuedError) Error() string {
+	return "server unreachable; saved offline and will be sent when the server is back"
+}
+
+type OfflineQueueEntryResult struct {
+	ID             int64           `json:"id"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	Operation      string          `json:"operation"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      string          `json:"created_at"`
+}
+
+type OfflineReplayResult struct {
+	Sent      int `json:"sent"`
+	Conflicts int `json:"conflicts"`
+	Pending   int `json:"pending"`
+}
+
+// ResolveOfflineConflictInput resolves a rejected write. Retrying requires the corrected
+// request body in Payload; resending the body the server already rejected would only fail again.
+type ResolveOfflineConflictInput struct {
+	ID      int64           `json:"id"`
+	Action  string          `json:"action"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// offlineQueueState opens the client's queue database on first use. Queued payloads are stored
+// without a session token; the latest session is kept in memory only and attached on replay.
+type offlineQueueState struct {
+	once      sync.Once
+	repo      domainOffline.Repository
+	err       error
+	replayMu  sync.Mutex
+	sessionMu sync.Mutex
+	authToken string
+}
+
+func offlineQueuePath() string {
+	return filepath.Join(resolveClientStateDir(), offlineQueueFileName)
+}
+
+func (a *App) offlineQueue() (domainOffline.Repository, error) {
+	a.offline.once.Do(func() {
+		if a.offline.repo != nil {
+			return
+		}
+		manager := db.NewDatabaseManager(offlineQueuePath())
+		if err := manager.Connect(); err != nil {
+			a.offline.err = fmt.Errorf("failed to open offline queue: %w", err)
+			return
+		}
+		a.offline.repo, a.offline.err = db.NewSqliteOfflineQueueRepository(manager.GetDB())
+	})
+	return a.offline.repo, a.offline.err
+}
+
+// rememberOfflineSession keeps the latest session token so queued writes can be replayed
+// without storing it on disk.
+func (a *App) rememberOfflineSession(authToken string) {
+	authToken = strings.TrimSpace(authToken)
+	if authToken == "" {
+		return
+	}
+	a.offline.sessionMu.Lock()
+	a.offline.authToken = authToken
+	a.offline.sessionMu.Unlock()
+}
+
+func (a *App) offlineSession() string {
+	a.offline.sessionMu.Lock()
+	defer a.offline.sessionMu.Unlock()
+	return a.offline.authToken
+}
+
+func newIdempotencyKey() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// postOrQueueOffline sends a client write, falling back to the offline queue when the server
+// cannot be reached. While older writes are still queued, new ones join the back of the queue
+// so the server applies them in the order they were made.
+func (a *App) postOrQueueOffline(operation string, payload interface{}, output interface{}) error {
+	path, ok := offlineOperationPaths[operation]
+	if !ok {
+		return fmt.Errorf("unsupported offline operation %q", operation)
+	}
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode offline write: %w", err)
+	}
+	a.rememberOfflineSession(authTokenOf(encoded))
+
+	if a.hasPendingOfflineWrites() {
+		if _, err := a.ReplayOfflineQueue(""); err != nil || a.hasPendingOfflineWrites() {
+			return a.enqueueOffline(operation, key, encoded, errors.New("earlier offline writes are still queued"))
+		}
+	}
+
+	body, err := sendServerAPIRequest(path, json.RawMessage(encoded), key)
+	if err != nil {
+		if isServerUnreachable(err) {
+			return a.enqueueOffline(operation, key, encoded, err)
+		}
+		return err
+	}
+	if output == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, output); err != nil {
+		return fmt.Errorf("failed to decode server response: %w", err)
+	}
+	return nil
+}
+
+func (a *App) enqueueOffline(operation, key string, encoded []byte, cause error) error {
+	repo, err := a.offlineQueue()
+	if err != nil {
+		return cause
+	}
+	entry := &domainOffline.Entry{IdempotencyKey: key, Operation: operation, Payload: string(withoutAuthToken(encoded))}
+	if err := repo.Enqueue(entry); err != nil {
+		return fmt.Errorf("%v; saving offline also failed: %w", cause, err)
+	}
+	return &OfflineQueuedError{EntryID: entry.ID, IdempotencyKey: key}
+}
+
+// hasPendingOfflineWrites avoids creating the queue database on clients that never needed it.
+func (a *App) hasPendingOfflineWrites() bool {
+	if a.offline.repo == nil {
+		if _, err := os.Stat(offlineQueuePath()); err != nil {
+			return false
+		}
+	}
+	repo, err := a.offlineQueue()
+	if err != nil {
+		return false
+	}
+	pending, err := repo.List(domainOffline.StatusPending)
+	return err == nil && len(pending) > 0
+}
+
+func (a *App) scheduleOfflineReplay() {
+	if !a.hasPendingOfflineWrites() {
+		return
+	}
+	go func() {
+		if _, err := a.ReplayOfflineQueue(""); err != nil {
+			fmt.Fprintf(os.Stderr, "offline queue replay failed: %v\n", err)
+		}
+	}()
+}
+
+// ReplayOfflineQueue sends queued writes oldest first. Each keeps its idempotency key, so a write
+// the server already applied is not applied twice. The server's rejections become conflicts for the
+// user to resolve; a lost connection or an expired session stops the replay until the next attempt.
+// Each write is sent with the current session: a non-empty authToken, e.g. after signing in again,
+// or else the one from the client's latest write. Without a session nothing is sent.
+func (a *App) ReplayOfflineQueue(authToken string) (OfflineReplayResult, error) {
+	repo, err := a.offlineQueue()
+	if err != nil {
+		return OfflineReplayResult{}, err
+	}
+	a.rememberOfflineSession(authToken)
+	a.offline.replayMu.Lock()
+	defer a.offline.replayMu.Unlock()
+
+	var result OfflineReplayResult
+	entries, err := repo.List(domainOffline.StatusPending)
+	if err != nil {
+		return result, err
+	}
+
+	session := a.offlineSession()
+	for _, entry := range entries {
+		if session == "" {
+			break
+		}
+		payload, err := withAuthToken(json.RawMessage(entry.Payload), session)
+		if err != nil {
+			return result, err
+		}
+
+		_, sendErr := sendServerAPIRequest(offlineOperationPaths[entry.Operation], payload, entry.IdempotencyKey)
+		if sendErr == nil {
+			if err := repo.Delete(entry.ID); err != nil {
+				return result, err
+			}
+			result.Sent++
+			continue
+		}
+
+		var statusErr *serverAPIStatusError
+		switch {
+		case errors.As(sendErr, &statusErr) && statusErr.status == http.StatusUnauthorized:
+			_ = repo.RecordAttempt(entry.ID, "sign in again to send queued changes: "+sendErr.Error())
+		case errors.As(sendErr, &statusErr) && statusErr.status < http.StatusInternalServerError:
+			if err := repo.MarkConflict(entry.ID, sendErr.Error()); err != nil {
+				return result, err
+			}
+			result.Conflicts++
+			continue
+		default:
+			_ = repo.RecordAttempt(entry.ID, sendErr.Error())
+		}
+		break
+	}
+
+	pending, err := repo.List(domainOffline.StatusPending)
+	if err != nil {
+		return result, err
+	}
+	result.Pending = len(pending)
+	return result, nil
+}
+
+// ListOfflineQueue returns every write still owed to the server, pending and conflicted.
+func (a *App) ListOfflineQueue() ([]OfflineQueueEntryResult, error) {
+	return a.listOfflineEntries("")
+}
+
+// ListOfflineConflicts returns queued writes the server rejected.
+func (a *App) ListOfflineConflicts() ([]OfflineQueueEntryResult, error) {
+	return a.listOfflineEntries(domainOffline.StatusConflict)
+}
+
+// ResolveOfflineConflict either puts a rejected write back in the queue with the body the user
+// corrected, keeping its place and idempotency key, or discards it.
+func (a *App) ResolveOfflineConflict(input ResolveOfflineConflictInput) error {
+	repo, err := a.offlineQueue()
+	if err != nil {
+		return err
+	}
+	entry, err := repo.Get(input.ID)
+	if err != nil {
+		return err
+	}
+	if entry.Status != domainOffline.StatusConflict {
+		return fmt.Errorf("offline queue entry %d is not in conflict", input.ID)
+	}
+	switch strings.ToLower(strings.TrimSpace(input.Action)) {
+	case OfflineConflictRetry:
+		if len(input.Payload) == 0 {
+			return fmt.Errorf("retrying offline queue entry %d requires the corrected payload", input.ID)
+		}
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(input.Payload, &fields); err != nil {
+			return fmt.Errorf("corrected payload is not a JSON object: %w", err)
+		}
+		corrected := withoutAuthToken(input.Payload)
+		if string(corrected) == string(withoutAuthToken(json.RawMessage(entry.Payload))) {
+			return fmt.Errorf("offline queue entry %d was rejected with this payload; correct it before retrying", input.ID)
+		}
+		return repo.Requeue(entry.ID, string(corrected))
+	case OfflineConflictDiscard:
+		return repo.Delete(entry.ID)
+	default:
+		return fmt.Errorf("invalid action: must be %s or %s", OfflineConflictRetry, OfflineConflictDiscard)
+	}
+}
+
+func (a *App) listOfflineEntries(status string) ([]OfflineQueueEntryResult, error) {
+	repo, err := a.offlineQueue()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := repo.List(status)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]OfflineQueueEntryResult, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, OfflineQueueEntryResult{
+			ID:             entry.ID,
+			IdempotencyKey: entry.IdempotencyKey,
+			Operation:      entry.Operation,
+			Status:         entry.Status,
+			Attempts:       entry.Attempts,
+			LastError:      entry.LastError,
+			Payload:        withoutAuthToken(json.RawMessage(entry.Payload)),
+			CreatedAt:      entry.CreatedAt.Format(time.RFC3339Nano),
+		})
+	}
+	return result, nil
+}
+
+func withAuthToken(payload json.RawMessage, authToken string) (json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("queued payload is not a JSON object: %w", err)
+	}
+	token, err := json.Marshal(strings.TrimSpace(authToken))
+	if err != nil {
+		return nil, err
+	}
+	fields["auth_token"] = token
+	return json.Marshal(fields)
+}
+
+// authTokenOf returns the session token a request body carries, if any.
+func authTokenOf(payload json.RawMessage) string {
+	var body struct {
+		AuthToken string `json:"auth_token"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return ""
+	}
+	return body.AuthToken
+}
+
+// withoutAuthToken keeps session tokens out of the queue database and what the UI displays.
+func withoutAuthToken(payload json.RawMessage) json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return payload
+	}
+	delete(fields, "auth_token")
+	cleaned, err := json.Marshal(fields)
+	if err != nil {
+		return payload
+	}
+	return cleaned
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	appInventory "masala_inventory_managment/internal/app/inventory"
+)
+
+func TestCreateGRN_QueuesOfflineAndReplaysWithIdempotencyKey(t *testing.T) {
+	t.Setenv(envClientStateDir, t.TempDir())
+	t.Setenv(envServerProbeAddr, "http://127.0.0.1:1")
+
+	a := NewApp(false)
+	input := appInventory.CreateGRNInput{GRNNumber: "GRN-OFF-1", SupplierID: 7, AuthToken: "stale-token"}
+	_, err := a.CreateGRN(input)
+	var queued *OfflineQueuedError
+	if !errors.As(err, &queued) || queued.IdempotencyKey == "" {
+		t.Fatalf("expected write to be queued offline, got %v", err)
+	}
+
+	entries, err := a.ListOfflineQueue()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one queued entry, got %+v (%v)", entries, err)
+	}
+	if strings.Contains(string(entries[0].Payload), "stale-token") {
+		t.Fatalf("expected auth token to be hidden from listed payload: %s", entries[0].Payload)
+	}
+	repo, err := a.offlineQueue()
+	if err != nil {
+		t.Fatalf("offlineQueue failed: %v", err)
+	}
+	stored, err := repo.Get(entries[0].ID)
+	if err != nil || strings.Contains(stored.Payload, "auth_token") {
+		t.Fatalf("expected auth token not to be persisted, got %+v (%v)", stored, err)
+	}
+
+	var mu sync.Mutex
+	var gotKey, gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		gotKey = r.Header.Get(idempotencyKeyHeader)
+		gotToken, _ = body["auth_token"].(string)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"id":1,"grn_number":"GRN-OFF-1"}`))
+	}))
+	defer server.Close()
+	t.Setenv(envServerProbeAddr, server.URL)
+
+	result, err := a.ReplayOfflineQueue("fresh-token")
+	if err != nil || result.Sent != 1 || result.Pending != 0 {
+		t.Fatalf("unexpected replay result %+v (%v)", result, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gotKey != queued.IdempotencyKey {
+		t.Fatalf("expected replay to reuse idempotency key %q, got %q", queued.IdempotencyKey, gotKey)
+	}
+	if gotToken != "fresh-token" {
+		t.Fatalf("expected replay to use the new session, got %q", gotToken)
+	}
+	if remaining, _ := a.ListOfflineQueue(); len(remaining) != 0 {
+		t.Fatalf("expected sent entry to be removed, got %+v", remaining)
+	}
+}
+
+func TestReplayOfflineQueue_RejectedWriteBecomesConflict(t *testing.T) {
+	t.Setenv(envClientStateDir, t.TempDir())
+	t.Setenv(envServerProbeAddr, "http://127.0.0.1:1")
+
+	a := NewApp(false)
+	for _, number := range []string{"GRN-DUP", "GRN-NEXT"} {
+		if _, err := a.CreateGRN(appInventory.CreateGRNInput{GRNNumber: number}); err == nil {
+			t.Fatalf("expected %s to be queued", number)
+		}
+	}
+
+	var mu sync.Mutex
+	var sent []appInventory.CreateGRNInput
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input appInventory.CreateGRNInput
+		_ = json.NewDecoder(r.Body).Decode(&input)
+		mu.Lock()
+		sent = append(sent, input)
+		mu.Unlock()
+		if input.GRNNumber == "GRN-DUP" {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"message":"grn number already exists"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	t.Setenv(envServerProbeAddr, server.URL)
+
+	result, err := a.ReplayOfflineQueue("")
+	if err != nil || result.Sent != 0 || result.Pending != 2 || len(sent) != 0 {
+		t.Fatalf("expected nothing to be sent without a session, got %+v (%v), sent %+v", result, err, sent)
+	}
+	result, err = a.ReplayOfflineQueue("session-token")
+	if err != nil || result.Sent != 1 || result.Conflicts != 1 {
+		t.Fatalf("unexpected replay result %+v (%v)", result, err)
+	}
+
+	conflicts, err := a.ListOfflineConflicts()
+	if err != nil || len(conflicts) != 1 || conflicts[0].LastError != "grn number already exists" {
+		t.Fatalf("unexpected conflicts %+v (%v)", conflicts, err)
+	}
+	if err := a.ResolveOfflineConflict(ResolveOfflineConflictInput{ID: conflicts[0].ID, Action: "ignore"}); err == nil {
+		t.Fatalf("expected unknown action to be rejected")
+	}
+	if err := a.ResolveOfflineConflict(ResolveOfflineConflictInput{ID: conflicts[0].ID, Action: OfflineConflictRetry}); err == nil {
+		t.Fatalf("expected retry without a corrected payload to be rejected")
+	}
+	if err := a.ResolveOfflineConflict(ResolveOfflineConflictInput{ID: conflicts[0].ID, Action: OfflineConflictRetry, Payload: conflicts[0].Payload}); err == nil {
+		t.Fatalf("expected retry with the rejected payload to be rejected")
+	}
+	corrected := json.RawMessage(`{"grn_number":"GRN-DUP-2","auth_token":"pasted-token"}`)
+	if err := a.ResolveOfflineConflict(ResolveOfflineConflictInput{ID: conflicts[0].ID, Action: OfflineConflictRetry, Payload: corrected}); err != nil {
+		t.Fatalf("ResolveOfflineConflict retry failed: %v", err)
+	}
+	result, err = a.ReplayOfflineQueue("")
+	if err != nil || result.Sent != 1 || result.Pending != 0 {
+		t.Fatalf("unexpected replay result after retry %+v (%v)", result, err)
+	}
+	mu.Lock()
+	last := sent[len(sent)-1]
+	mu.Unlock()
+	if last.GRNNumber != "GRN-DUP-2" || last.AuthToken != "session-token" {
+		t.Fatalf("expected the corrected write to be resent with the current session, got %+v", last)
+	}
+	if remaining, _ := a.ListOfflineQueue(); len(remaining) != 0 {
+		t.Fatalf("expected sent entries to be removed, got %+v", remaining)
+	}
+}
+
+func TestResolveOfflineConflict_DiscardRemovesTheWrite(t *testing.T) {
+	t.Setenv(envClientStateDir, t.TempDir())
+	t.Setenv(envServerProbeAddr, "http://127.0.0.1:1")
+
+	a := NewApp(false)
+	if _, err := a.CreateGRN(appInventory.CreateGRNInput{GRNNumber: "GRN-DUP", AuthToken: "session-token"}); err == nil {
+		t.Fatalf("expected write to be queued")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"message":"grn number already exists"}`))
+	}))
+	defer server.Close()
+	t.Setenv(envServerProbeAddr, server.URL)
+
+	if result, err := a.ReplayOfflineQueue(""); err != nil || result.Conflicts != 1 {
+		t.Fatalf("expected the remembered session to be used, got %+v (%v)", result, err)
+	}
+	conflicts, err := a.ListOfflineConflicts()
+	if err != nil || len(conflicts) != 1 {
+		t.Fatalf("unexpected conflicts %+v (%v)", conflicts, err)
+	}
+	if err := a.ResolveOfflineConflict(ResolveOfflineConflictInput{ID: conflicts[0].ID, Action: OfflineConflictDiscard}); err != nil {
+		t.Fatalf("ResolveOfflineConflict failed: %v", err)
+	}
+	if remaining, _ := a.ListOfflineQueue(); len(remaining) != 0 {
+		t.Fatalf("expected discarded conflict to be removed, got %+v", remaining)
+	}
+}
@@ -14,7 +14,8 @@ type mockUserRepo struct {
 	user *domainAuth.User
 }
 
-func (m *mockUserRepo) Save(user *domainAuth.User) error { return nil }
+func (m *mockUserRepo) WithActor(actor string) domainAuth.UserRepository { return m }
+func (m *mockUserRepo) Save(user *domainAuth.User) error                 { return nil }
 func (m *mockUserRepo) FindByUsername(username string) (*domainAuth.User, error) {
 	return m.user, nil
 }
@@ -34,6 +35,9 @@ func (m *mockUserRepo) SetActive(username string, isActive bool) error {
 func (m *mockUserRepo) UpdatePasswordHash(username, passwordHash string) error {
 	return sql.ErrNoRows
 }
+func (m *mockUserRepo) UpdatePinHash(username, pinHash string) error {
+	return sql.ErrNoRows
+}
 func (m *mockUserRepo) DeleteByUsername(username string) error { return sql.ErrNoRows }
 func (m *mockUserRepo) CountActiveAdmins() (int, error)        { return 0, nil }
 
@@ -0,0 +1,185 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"masala_inventory_managment/internal/infrastructure/network"
+)
+
+const (
+	serverChoiceFileName = "server_choice.json"
+	discoveryTimeout     = 1500 * time.Millisecond
+)
+
+// DiscoveredServerResult is a server that answered a LAN discovery probe.
+type DiscoveredServerResult struct {
+	ServerID    string `json:"server_id"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Address     string `json:"address"`
+	Fingerprint string `json:"fingerprint"`
+	Selected    bool   `json:"selected"`
+}
+
+// rememberedServer is the client's chosen server. It is keyed by server ID so a new DHCP
+// address can be picked up by rediscovery.
+type rememberedServer struct {
+	ServerID string `json:"server_id"`
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+}
+
+// serverDiscovery caches the last discovery so SelectServer can refer to a listed server.
+type serverDiscovery struct {
+	mu       sync.Mutex
+	discover func() ([]network.ServerAnnouncement, error)
+	last     []network.ServerAnnouncement
+}
+
+func defaultServerDiscoverer() ([]network.ServerAnnouncement, error) {
+	return network.Discover(network.DefaultDiscoveryPort, discoveryTimeout)
+}
+
+func (a *App) runServerDiscovery() ([]network.ServerAnnouncement, error) {
+	a.discovery.mu.Lock()
+	defer a.discovery.mu.Unlock()
+	discover := a.discovery.discover
+	if discover == nil {
+		discover = defaultServerDiscoverer
+	}
+	found, err := discover()
+	if err != nil {
+		return nil, err
+	}
+	a.discovery.last = found
+	return found, nil
+}
+
+// DiscoverServers lists servers answering on the LAN, marking the remembered one.
+func (a *App) DiscoverServers() ([]DiscoveredServerResult, error) {
+	found, err := a.runServerDiscovery()
+	if err != nil {
+		return nil, err
+	}
+	chosen, _ := loadRememberedServer()
+	result := make([]DiscoveredServerResult, 0, len(found))
+	for _, server := range found {
+		result = append(result, toDiscoveredServerResult(server, chosen != nil && chosen.ServerID == server.ServerID))
+	}
+	return result, nil
+}
+
+// SelectServer remembers a server from the last DiscoverServers call as this client's server.
+func (a *App) SelectServer(serverID string) (DiscoveredServerResult, error) {
+	serverID = strings.TrimSpace(serverID)
+	a.discovery.mu.Lock()
+	var selected *network.ServerAnnouncement
+	for i := range a.discovery.last {
+		if a.discovery.last[i].ServerID == serverID {
+			selected = &a.discovery.last[i]
+			break
+		}
+	}
+	a.discovery.mu.Unlock()
+	if selected == nil {
+		return DiscoveredServerResult{}, fmt.Errorf("server not found; run discovery again")
+	}
+
+	if err := saveRememberedServer(rememberedServer{ServerID: selected.ServerID, Name: selected.Name, Address: selected.Address}); err != nil {
+		return DiscoveredServerResult{}, err
+	}
+	return toDiscoveredServerResult(*selected, true), nil
+}
+
+// GetSelectedServer returns the remembered server, or nil if none was chosen.
+func (a *App) GetSelectedServer() (*DiscoveredServerResult, error) {
+	chosen, err := loadRememberedServer()
+	if err != nil || chosen == nil {
+		return nil, err
+	}
+	return &DiscoveredServerResult{ServerID: chosen.ServerID, Name: chosen.Name, Address: chosen.Address, Selected: true}, nil
+}
+
+// ForgetSelectedServer clears the remembered server; the client falls back to the default address.
+func (a *App) ForgetSelectedServer() error {
+	err := os.Remove(filepath.Join(resolveClientStateDir(), serverChoiceFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// relocateRememberedServer looks for the remembered server at a new address, e.g. after a DHCP
+// lease change. The certificate pin moves with it so the new address is not trusted afresh.
+func (a *App) relocateRememberedServer(chosen rememberedServer) (string, bool) {
+	found, err := a.runServerDiscovery()
+	if err != nil {
+		return "", false
+	}
+	for _, server := range found {
+		if server.ServerID != chosen.ServerID {
+			continue
+		}
+		if server.Address != chosen.Address {
+			pins := serverPinStore()
+			if pinned, err := pins.Get(chosen.Address); err == nil && pinned != "" {
+				if err := pins.Set(server.Address, pinned); err != nil {
+					return "", false
+				}
+			}
+			chosen.Address = server.Address
+			if err := saveRememberedServer(chosen); err != nil {
+				return "", false
+			}
+		}
+		return server.Address, true
+	}
+	return "", false
+}
+
+func toDiscoveredServerResult(server network.ServerAnnouncement, selected bool) DiscoveredServerResult {
+	return DiscoveredServerResult{
+		ServerID:    server.ServerID,
+		Name:        server.Name,
+		Version:     server.Version,
+		Address:     server.Address,
+		Fingerprint: server.Fingerprint,
+		Selected:    selected,
+	}
+}
+
+func loadRememberedServer() (*rememberedServer, error) {
+	data, err := os.ReadFile(filepath.Join(resolveClientStateDir(), serverChoiceFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var chosen rememberedServer
+	if err := json.Unmarshal(data, &chosen); err != nil {
+		return nil, fmt.Errorf("remembered server file is corrupt: %w", err)
+	}
+	if strings.TrimSpace(chosen.Address) == "" {
+		return nil, nil
+	}
+	return &chosen, nil
+}
+
+func saveRememberedServer(chosen rememberedServer) error {
+	dir := resolveClientStateDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(chosen, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, serverChoiceFileName), data, 0600)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"masala_inventory_managment/internal/infrastructure/network"
+)
+
+func TestDiscoverServers_SelectIsRemembered(t *testing.T) {
+	t.Setenv(envClientStateDir, t.TempDir())
+	t.Setenv(envServerProbeAddr, "")
+
+	a := NewApp(false)
+	a.discovery.discover = func() ([]network.ServerAnnouncement, error) {
+		return []network.ServerAnnouncement{
+			{ServerID: "srv-a", Name: "office-pc", Version: "1.4.0", Address: "192.168.1.20:8090"},
+			{ServerID: "srv-b", Name: "godown-pc", Version: "1.4.0", Address: "192.168.1.31:8090"},
+		}, nil
+	}
+
+	servers, err := a.DiscoverServers()
+	if err != nil || len(servers) != 2 {
+		t.Fatalf("expected two servers, got %+v (%v)", servers, err)
+	}
+	if _, err := a.SelectServer("srv-missing"); err == nil {
+		t.Fatalf("expected unknown server to be rejected")
+	}
+	if _, err := a.SelectServer("srv-b"); err != nil {
+		t.Fatalf("SelectServer failed: %v", err)
+	}
+
+	selected, err := a.GetSelectedServer()
+	if err != nil || selected == nil || selected.Name != "godown-pc" {
+		t.Fatalf("unexpected selected server: %+v (%v)", selected, err)
+	}
+	if got := resolveServerAPIBaseURL(); got != "https://192.168.1.31:8090" {
+		t.Fatalf("expected API calls to target the chosen server, got %q", got)
+	}
+
+	servers, _ = a.DiscoverServers()
+	if servers[0].Selected || !servers[1].Selected {
+		t.Fatalf("expected only the chosen server to be marked selected: %+v", servers)
+	}
+
+	if err := a.ForgetSelectedServer(); err != nil {
+		t.Fatalf("ForgetSelectedServer failed: %v", err)
+	}
+	if got := resolveServerAPIBaseURL(); got != "https://"+defaultServerProbeAddr {
+		t.Fatalf("expected default address after forgetting, got %q", got)
+	}
+}
+
+func TestCheckServerReachability_FollowsRememberedServerToNewAddress(t *testing.T) {
+	t.Setenv(envClientStateDir, t.TempDir())
+	t.Setenv(envServerProbeAddr, "")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("skipping network socket test in restricted runtime: %v", err)
+	}
+	defer listener.Close()
+	newAddress := listener.Addr().String()
+
+	const oldAddress = "127.0.0.1:1"
+	if err := saveRememberedServer(rememberedServer{ServerID: "srv-a", Name: "office-pc", Address: oldAddress}); err != nil {
+		t.Fatalf("saveRememberedServer failed: %v", err)
+	}
+	fingerprint := strings.Repeat("CD", 32)
+	if err := serverPinStore().Set(oldAddress, fingerprint); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+
+	a := NewApp(false)
+	a.connectivityProbe = func() error { t.Fatalf("process probe should not run when a server is remembered"); return nil }
+	a.discovery.discover = func() ([]network.ServerAnnouncement, error) {
+		return []network.ServerAnnouncement{{ServerID: "srv-a", Name: "office-pc", Address: newAddress}}, nil
+	}
+
+	connected, err := a.CheckServerReachability()
+	if err != nil || !connected {
+		t.Fatalf("expected rediscovered server to be reachable, got %v (%v)", connected, err)
+	}
+
+	chosen, err := loadRememberedServer()
+	if err != nil || chosen == nil || chosen.Address != newAddress {
+		t.Fatalf("expected remembered address to move to %s, got %+v (%v)", newAddress, chosen, err)
+	}
+	if pinned, _ := serverPinStore().Get(newAddress); pinned != network.NormalizeFingerprint(fingerprint) {
+		t.Fatalf("expected certificate pin to follow the server, got %q", pinned)
+	}
+}
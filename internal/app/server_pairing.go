@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"masala_inventory_managment/internal/infrastructure/network"
+)
+
+const (
+	envClientStateDir  = "MASALA_CLIENT_STATE_DIR"
+	clientStateDirName = "MasalaInventory"
+	serverPinsFileName = "server_pins.json"
+)
+
+// ServerPairingResult describes which server certificate this client trusts.
+type ServerPairingResult struct {
+	Server      string `json:"server"`
+	Fingerprint string `json:"fingerprint"`
+	Paired      bool   `json:"paired"`
+}
+
+// resolveClientStateDir is where a client keeps local state such as paired server fingerprints.
+func resolveClientStateDir() string {
+	if dir := strings.TrimSpace(os.Getenv(envClientStateDir)); dir != "" {
+		return dir
+	}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(configDir, clientStateDirName)
+	}
+	return "."
+}
+
+func serverPinStore() *network.PinStore {
+	return network.NewPinStore(filepath.Join(resolveClientStateDir(), serverPinsFileName))
+}
+
+// newServerAPIClient pins HTTPS servers to the certificate seen on first pairing.
+func newServerAPIClient(baseURL string) (*http.Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server address: %w", err)
+	}
+	client := &http.Client{Timeout: serverAPITimeout}
+	if parsed.Scheme == "https" {
+		client.Transport = &http.Transport{TLSClientConfig: serverPinStore().ClientTLSConfig(parsed.Host)}
+	}
+	return client, nil
+}
+
+func currentServerHost() (string, error) {
+	parsed, err := url.Parse(resolveServerAPIBaseURL())
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("invalid server address")
+	}
+	return parsed.Host, nil
+}
+
+// GetServerPairing reports the certificate fingerprint pinned for the configured server.
+func (a *App) GetServerPairing() (ServerPairingResult, error) {
+	host, err := currentServerHost()
+	if err != nil {
+		return ServerPairingResult{}, err
+	}
+	fingerprint, err := serverPinStore().Get(host)
+	if err != nil {
+		return ServerPairingResult{}, err
+	}
+	return ServerPairingResult{Server: host, Fingerprint: fingerprint, Paired: fingerprint != ""}, nil
+}
+
+// PairServer pins the configured server to the fingerprint shown on the server console.
+// Used after an admin rotates the server certificate.
+func (a *App) PairServer(fingerprint string) (ServerPairingResult, error) {
+	host, err := currentServerHost()
+	if err != nil {
+		return ServerPairingResult{}, err
+	}
+	if err := serverPinStore().Set(host, fingerprint); err != nil {
+		return ServerPairingResult{}, err
+	}
+	return a.GetServerPairing()
+}
+
+// ForgetServerPairing drops the pin so the next connection trusts whatever certificate the
+// server presents.
+func (a *App) ForgetServerPairing() error {
+	host, err := currentServerHost()
+	if err != nil {
+		return err
+	}
+	return serverPinStore().Forget(host)
+}
@@ -22,6 +22,7 @@ type MonitorService struct {
 	diskThreshold uint64
 	forcedDisk    uint64
 	disablePings  bool
+	headless      bool
 }
 
 const (
@@ -64,6 +65,13 @@ func (s *MonitorService) Start(ctx context.Context) {
 	go s.runDiskMonitor()
 }
 
+// StartHeadless starts the same loops for a server without a window. Low disk space is
+// only logged, since there is no window to notify and ctx is not a Wails context.
+func (s *MonitorService) StartHeadless(ctx context.Context) {
+	s.headless = true
+	s.Start(ctx)
+}
+
 func (s *MonitorService) runDiskMonitor() {
 	slog.Info("MonitorService: Background loop started")
 	diskTicker := time.NewTicker(30 * time.Minute) // AC #3 specifies every 30 minutes
@@ -102,6 +110,9 @@ func (s *MonitorService) checkDiskSpace() {
 	if space < s.diskThreshold {
 		availableMB := float64(space) / (1024 * 1024)
 		slog.Warn("Low disk space alert!", "available_mb", fmt.Sprintf("%.2f", availableMB))
+		if s.headless {
+			return
+		}
 
 		// AC #3: Notification bubble on low disk space
 		_ = s.sysMonitor.ShowNotification("Low Disk Space", fmt.Sprintf("Only %.2f MB available on disk.", availableMB))
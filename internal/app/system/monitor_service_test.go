@@ -100,3 +100,21 @@ func TestMonitorService_WatchdogIntegration(t *testing.T) {
 	// Let's just ensure it runs for a bit.
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestMonitorService_HeadlessLowDiskOnlyLogs(t *testing.T) {
+	mockSys := &MockSysMonitor{
+		diskSpace: 400 * 1024 * 1024, // 400MB (Below 500MB threshold)
+	}
+	svc := NewMonitorService(mockSys, infraSys.NewWatchdog(10))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A plain context would make Wails EventsEmit exit the process.
+	svc.StartHeadless(ctx)
+	time.Sleep(100 * time.Millisecond)
+	svc.checkDiskSpace()
+
+	if len(mockSys.notifications) > 0 {
+		t.Errorf("Expected no notification without a window, got %v", mockSys.notifications)
+	}
+}
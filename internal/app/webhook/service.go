@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	appAuth "masala_inventory_managment/internal/app/auth"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	domainWebhook "masala_inventory_managment/internal/domain/webhook"
+)
+
+// TypeWebhookTest is sent by SendTest so an Admin can check a receiver without waiting for
+// a real change.
+const TypeWebhookTest = "WebhookTest"
+
+const defaultDeliveryLimit = 200
+
+// CreateWebhookInput registers a receiver. A secret is generated when none is given.
+type CreateWebhookInput struct {
+	AuthToken  string   `json:"auth_token"`
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+type UpdateWebhookInput struct {
+	AuthToken    string   `json:"auth_token"`
+	ID           int64    `json:"id"`
+	Name         string   `json:"name"`
+	URL          string   `json:"url"`
+	EventTypes   []string `json:"event_types"`
+	IsActive     bool     `json:"is_active"`
+	RotateSecret bool     `json:"rotate_secret"`
+}
+
+type WebhookIDInput struct {
+	AuthToken string `json:"auth_token"`
+	ID        int64  `json:"id"`
+}
+
+type ListDeliveriesInput struct {
+	AuthToken      string `json:"auth_token"`
+	SubscriptionID int64  `json:"subscription_id"`
+	Limit          int    `json:"limit"`
+}
+
+// WebhookResult carries the secret only when it was just created or rotated; receivers need
+// it to check signatures and it is not shown again.
+type WebhookResult struct {
+	domainWebhook.Subscription
+	Secret string `json:"secret,omitempty"`
+}
+
+// TestSender queues a delivery to one subscription.
+type TestSender interface {
+	EnqueueFor(subscriptionID int64, event domainEvents.Event) error
+}
+
+// Service manages webhook subscriptions. Restricted to Admin.
+type Service struct {
+	repo        domainWebhook.Repository
+	authService *appAuth.Service
+	sender      TestSender
+}
+
+func NewService(repo domainWebhook.Repository, auth *appAuth.Service, sender TestSender) *Service {
+	return &Service{
+		repo:        repo,
+		authService: auth,
+		sender:      sender,
+	}
+}
+
+// ListEventTypes returns the event types a webhook can subscribe to. "*" subscribes to all.
+func (s *Service) ListEventTypes(token string) ([]string, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return append([]string(nil), domainEvents.Types...), nil
+}
+
+func (s *Service) CreateWebhook(input CreateWebhookInput) (*WebhookResult, error) {
+	if err := s.authService.CheckPermission(input.AuthToken, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	secret := input.Secret
+	if secret == "" {
+		generated, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+	sub := &domainWebhook.Subscription{
+		Name:       input.Name,
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		Secret:     secret,
+		IsActive:   true,
+	}
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateSubscription(sub); err != nil {
+		return nil, err
+	}
+	return &WebhookResult{Subscription: *sub, Secret: sub.Secret}, nil
+}
+
+func (s *Service) UpdateWebhook(input UpdateWebhookInput) (*WebhookResult, error) {
+	if err := s.authService.CheckPermission(input.AuthToken, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.GetSubscription(input.ID)
+	if err != nil {
+		return nil, err
+	}
+	sub.Name = input.Name
+	sub.URL = input.URL
+	sub.EventTypes = input.EventTypes
+	sub.IsActive = input.IsActive
+	if input.RotateSecret {
+		if sub.Secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateSubscription(sub); err != nil {
+		return nil, err
+	}
+	result := &WebhookResult{Subscription: *sub}
+	if input.RotateSecret {
+		result.Secret = sub.Secret
+	}
+	return result, nil
+}
+
+func (s *Service) ListWebhooks(token string) ([]WebhookResult, error) {
+	if err := s.authService.CheckPermission(token, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	subs, err := s.repo.ListSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]WebhookResult, 0, len(subs))
+	for _, sub := range subs {
+		results = append(results, WebhookResult{Subscription: sub})
+	}
+	return results, nil
+}
+
+// DeleteWebhook removes a subscription and drops its undelivered events; its delivery log is kept.
+func (s *Service) DeleteWebhook(input WebhookIDInput) error {
+	if err := s.authService.CheckPermission(input.AuthToken, domainAuth.RoleAdmin); err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(input.ID)
+}
+
+// SendTest queues a WebhookTest event for one subscription, even one not subscribed to it.
+func (s *Service) SendTest(input WebhookIDInput) error {
+	if err := s.authService.CheckPermission(input.AuthToken, domainAuth.RoleAdmin); err != nil {
+		return err
+	}
+	if s.sender == nil {
+		return errors.New("webhook delivery is not available")
+	}
+	sub, err := s.repo.GetSubscription(input.ID)
+	if err != nil {
+		return err
+	}
+	user, err := s.authService.CurrentUser(input.AuthToken)
+	if err != nil {
+		return err
+	}
+	return s.sender.EnqueueFor(sub.ID, domainEvents.Event{
+		Type:       TypeWebhookTest,
+		EntityType: "webhook",
+		EntityID:   strconv.FormatInt(sub.ID, 10),
+		Actor:      user.Username,
+		Data:       map[string]string{"message": "Test delivery from Masala Inventory"},
+	})
+}
+
+// ListDeliveries returns the delivery log, newest first.
+func (s *Service) ListDeliveries(input ListDeliveriesInput) ([]domainWebhook.Delivery, error) {
+	if err := s.authService.CheckPermission(input.AuthToken, domainAuth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	return s.repo.ListDeliveries(domainWebhook.DeliveryFilter{SubscriptionID: input.SubscriptionID, Limit: limit})
+}
+
+func validateSubscription(sub *domainWebhook.Subscription) error {
+	if err := sub.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	known := make(map[string]bool, len(domainEvents.Types)+1)
+	known["*"] = true
+	for _, eventType := range domainEvents.Types {
+		known[eventType] = true
+	}
+	for _, eventType := range sub.EventTypes {
+		if !known[eventType] {
+			return fmt.Errorf("validation failed: unknown event type %q", eventType)
+		}
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
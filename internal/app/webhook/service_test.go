@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	domainWebhook "masala_inventory_managment/internal/domain/webhook"
+)
+
+func TestValidateSubscription(t *testing.T) {
+	valid := func() *domainWebhook.Subscription {
+		return &domainWebhook.Subscription{
+			Name:       " accounts ",
+			URL:        "http://192.168.1.50:9000/hooks",
+			EventTypes: []string{domainEvents.TypeGrnCreated, domainEvents.TypeGrnCreated, " "},
+			Secret:     "0123456789abcdef",
+		}
+	}
+
+	sub := valid()
+	if err := validateSubscription(sub); err != nil {
+		t.Fatalf("expected valid subscription, got %v", err)
+	}
+	if sub.Name != "accounts" || len(sub.EventTypes) != 1 {
+		t.Fatalf("expected name trimmed and event types de-duplicated: %+v", sub)
+	}
+
+	cases := map[string]func(*domainWebhook.Subscription){
+		"url must be":             func(s *domainWebhook.Subscription) { s.URL = "ftp://example.com" },
+		"at least one event":      func(s *domainWebhook.Subscription) { s.EventTypes = nil },
+		"unknown event type":      func(s *domainWebhook.Subscription) { s.EventTypes = []string{"Nope"} },
+		"secret must be at least": func(s *domainWebhook.Subscription) { s.Secret = "short" },
+		"name is required":        func(s *domainWebhook.Subscription) { s.Name = "" },
+	}
+	for want, mutate := range cases {
+		sub := valid()
+		mutate(sub)
+		err := validateSubscription(sub)
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.HasPrefix(err.Error(), "validation failed") {
+			t.Fatalf("expected %q validation error, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionCreate        = "CREATE"
+	ActionUpdate        = "UPDATE"
+	ActionDelete        = "DELETE"
+	ActionSetActive     = "SET_ACTIVE"
+	ActionUpdateRole    = "UPDATE_ROLE"
+	ActionResetPassword = "RESET_PASSWORD"
+	ActionSetPin        = "SET_PIN"
+	ActionRestore       = "RESTORE"
+)
+
+// SystemActor is recorded when a change is not attributable to a signed-in user.
+const SystemActor = "system"
+
+// Entry is a single append-only audit log row.
+type Entry struct {
+	ID         int64     `json:"id"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	BeforeJSON string    `json:"before_json"`
+	AfterJSON  string    `json:"after_json"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ListFilter narrows audit log queries. Zero values are ignored.
+type ListFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	From       time.Time
+	To         time.Time
+	// BeforeID continues a newest-first listing after the entry with this ID.
+	BeforeID int64
+	Limit    int
+}
+
+// ChainBreak reports the first row at which the stock ledger or audit log hash chain no
+// longer verifies.
+type ChainBreak struct {
+	Table  string
+	RowID  int64
+	Reason string
+}
+
+func (e *ChainBreak) Error() string {
+	return fmt.Sprintf("hash chain broken in %s at row %d: %s", e.Table, e.RowID, e.Reason)
+}
+
+// Repository provides read access to the audit log. Writes happen inside the
+// owning repository's transaction and are never exposed as a separate API.
+type Repository interface {
+	List(filter ListFilter) ([]Entry, error)
+}
@@ -2,6 +2,9 @@ package auth
 
 // UserRepository defines the persistence interface for users.
 type UserRepository interface {
+	// WithActor returns a repository whose writes are attributed to actor in the audit log.
+	WithActor(actor string) UserRepository
+
 	Save(user *User) error
 	FindByUsername(username string) (*User, error)
 	Count() (int, error)
@@ -9,6 +12,7 @@ type UserRepository interface {
 	UpdateRole(username string, role Role) error
 	SetActive(username string, isActive bool) error
 	UpdatePasswordHash(username, passwordHash string) error
+	UpdatePinHash(username, pinHash string) error
 	DeleteByUsername(username string) error
 	CountActiveAdmins() (int, error)
 }
@@ -9,10 +9,12 @@ type AuthToken struct {
 // AuthService defines the business logic including authentication and user management.
 type AuthService interface {
 	Login(username, password string) (*AuthToken, error)
+	LoginWithPin(username, pin, clientAddr string) (*AuthToken, error)
 	CreateUser(token, username, password string, role Role) error
 	ListUsers(token string) ([]User, error)
 	SetUserActive(token, username string, isActive bool) error
 	UpdateUserRole(token, username string, role Role) error
 	ResetUserPassword(token, username, newPassword string) error
+	SetUserPin(token, username, pin string) error
 	DeleteUser(token, username string) error
 }
@@ -15,6 +15,7 @@ type User struct {
 	ID           string    `json:"id"`
 	Username     string    `json:"username"`
 	PasswordHash string    `json:"-"` // Never expose password hash in JSON
+	PinHash      string    `json:"-"` // Optional operator PIN; empty means PIN login is disabled
 	Role         Role      `json:"role"`
 	IsActive     bool      `json:"is_active"`
 	CreatedAt    time.Time `json:"created_at"`
@@ -33,3 +34,8 @@ func NewUser(username, passwordHash string, role Role) *User {
 		UpdatedAt:    now,
 	}
 }
+
+// HasPin reports whether the user has an operator PIN configured.
+func (u *User) HasPin() bool {
+	return u != nil && u.PinHash != ""
+}
@@ -6,7 +6,81 @@ import "time"
 type BackupConfig struct {
 	BackupPath    string
 	RetentionDays int
-	ScheduleCron  string // e.g., "0 2 * * *"
+	// ScheduleCron is the single nightly schedule used when Schedules is empty.
+	ScheduleCron string // e.g., "0 2 * * *"
+	// Schedules are named cron schedules, each keeping its backups for its own
+	// RetentionDays. Manual backups use RetentionDays above.
+	Schedules []Schedule
+	// KeyPath is where the archive encryption key is kept once an Admin sets a
+	// passphrase. It must not be inside BackupPath.
+	KeyPath string
+	// VerifyCron schedules the job that test-restores unverified archives and the
+	// newest one.
+	VerifyCron string
+	// AppVersion and HardwareID are recorded in each archive's manifest.
+	AppVersion string
+	HardwareID string
+	// Targets receive a copy of every archive, so a disk failure does not take the
+	// backups with the database.
+	Targets []TargetConfig
+	// JournalInterval is how often changes since the last backup are shipped to the
+	// journal folder inside BackupPath, for restoring to a point in time. A negative
+	// interval turns shipping off.
+	JournalInterval time.Duration
+}
+
+// Backup target kinds.
+const (
+	TargetLocal = "local"
+	TargetSMB   = "smb"
+	TargetSFTP  = "sftp"
+)
+
+// TargetConfig is a secondary destination for archives. Path is a folder for local
+// targets, a UNC path or mounted share for SMB, and the remote folder for SFTP.
+// HostKey is the server's SHA256 key fingerprint, as ssh-keygen -l prints it; SFTP
+// targets are refused without it. Either Password or KeyFile authenticates.
+type TargetConfig struct {
+	Name          string
+	Kind          string
+	Path          string
+	Host          string
+	Port          int
+	User          string
+	Password      string
+	KeyFile       string
+	HostKey       string
+	RetentionDays int
+}
+
+// TargetStatus reports replication to one target. Pending counts archives not yet
+// copied there; after a failure NextRetry says when copying is tried again.
+type TargetStatus struct {
+	Name          string
+	Kind          string
+	RetentionDays int
+	LastUpload    time.Time
+	LastFile      string
+	Success       bool
+	Message       string
+	Pending       int
+	Failures      int
+	NextRetry     time.Time
+}
+
+// Schedule is a named cron expression (minute hour day-of-month month day-of-week).
+type Schedule struct {
+	Name          string
+	Cron          string
+	RetentionDays int
+}
+
+// ScheduleStatus reports when a schedule next runs.
+type ScheduleStatus struct {
+	Name          string
+	Cron          string
+	RetentionDays int
+	NextRun       time.Time
 }
 
 // BackupStatus represents the state of the last backup operation
@@ -17,4 +91,115 @@ type BackupStatus struct {
 	Success        bool
 	Message        string
 	IsRunning      bool
+	Encrypted      bool
+	Schedules      []ScheduleStatus
+	Targets        []TargetStatus
+	// JournalShippedAt is when changes were last shipped to the backup folder.
+	JournalShippedAt time.Time
+}
+
+// Manifest describes the database inside a backup archive, where it is stored as
+// manifest.json.
+type Manifest struct {
+	FormatVersion  int              `json:"format_version"`
+	CreatedAt      time.Time        `json:"created_at"`
+	Schedule       string           `json:"schedule,omitempty"`
+	AppVersion     string           `json:"app_version"`
+	SchemaVersion  uint             `json:"schema_version"`
+	SchemaDirty    bool             `json:"schema_dirty"`
+	HardwareID     string           `json:"hardware_id"`
+	DatabaseSHA256 string           `json:"database_sha256"`
+	DatabaseSize   int64            `json:"database_size"`
+	TableCounts    map[string]int64 `json:"table_counts"`
+	// JournalGeneration and JournalSeq place the copy in the change journal: replaying
+	// that generation's changes after JournalSeq brings it forward in time.
+	JournalGeneration string `json:"journal_generation,omitempty"`
+	JournalSeq        int64  `json:"journal_seq,omitempty"`
+}
+
+// Verification statuses of a backup archive.
+const (
+	VerificationUnverified = "UNVERIFIED"
+	VerificationPassed     = "PASSED"
+	VerificationFailed     = "FAILED"
+)
+
+// Verification is the outcome of test-restoring an archive.
+type Verification struct {
+	Status    string
+	CheckedAt time.Time
+	Message   string
+}
+
+// BackupInfo describes one archive in the backup folder. Manifest is nil for archives
+// made before manifests were written, or encrypted ones not yet verified.
+type BackupInfo struct {
+	Path         string
+	Name         string
+	CreatedAt    time.Time
+	Schedule     string
+	Size         int64
+	Encrypted    bool
+	Manifest     *Manifest
+	Verification Verification
+}
+
+// Kinds of record RestoreEntities can copy out of a backup. They match the entity types
+// in the audit log.
+const (
+	EntityParty            = "party"
+	EntityItem             = "item"
+	EntityRecipe           = "recipe"
+	EntityPackagingProfile = "packaging_profile"
+)
+
+// How a record differs between a backup and the live database.
+const (
+	EntityDeleted = "DELETED" // in the backup only
+	EntityChanged = "CHANGED"
+	EntityAdded   = "ADDED" // in the live database only
+)
+
+// EntityRef names one record, such as a party or an item with its details.
+type EntityRef struct {
+	Kind string
+	ID   int64
+}
+
+// EntityDiff is a record that differs between a backup and the live database. Label is
+// its name, SKU or code, whichever copy it was read from.
+type EntityDiff struct {
+	Kind   string
+	ID     int64
+	Label  string
+	Change string
+}
+
+// TableCount compares a table's rows in a backup with the live database.
+type TableCount struct {
+	Table  string
+	Backup int64
+	Live   int64
+}
+
+// RestorePreview describes what restoring an archive would bring back, read from a
+// copy of it without touching the live database.
+type RestorePreview struct {
+	Name                 string
+	Manifest             *Manifest
+	Tables               []TableCount
+	BackupNewestGRN      time.Time
+	LiveNewestGRN        time.Time
+	BackupNewestMovement time.Time
+	LiveNewestMovement   time.Time
+	Entities             []EntityDiff
+}
+
+// PointInTimeRestore reports a restore to a moment: the backup it started from, the
+// journalled changes replayed onto it, and the time of the last one, which is the
+// moment the database now reflects.
+type PointInTimeRestore struct {
+	Backup      string
+	Replayed    int
+	RecoveredTo time.Time
 }
@@ -1,5 +1,7 @@
 package backup
 
+import "time"
+
 // BackupService defines the interface for backup operations
 type BackupService interface {
 	// Execute performs an immediate backup
@@ -18,9 +20,50 @@ type BackupService interface {
 	// Returns the number of files pruned and any error
 	Prune() (int, error)
 
-	// ListBackups returns available backup archive paths.
-	ListBackups() ([]string, error)
+	// ListBackups returns available backup archives, newest first.
+	ListBackups() ([]BackupInfo, error)
+
+	// Verify test-restores an archive and records the outcome.
+	Verify(backupPath string) (*Verification, error)
 
 	// Restore restores the database from a backup archive path.
 	Restore(backupPath string) error
+
+	// RestoreWithPassphrase restores an archive encrypted under the given passphrase.
+	RestoreWithPassphrase(backupPath, passphrase string) error
+
+	// PreviewRestore compares an archive with the live database without changing it.
+	PreviewRestore(backupPath string) (*RestorePreview, error)
+
+	// RestoreEntities copies the chosen records from an archive into the live database,
+	// leaving everything else as it is. actor is recorded in the audit log.
+	RestoreEntities(backupPath string, entities []EntityRef, actor string) error
+
+	// RestoreToTime restores the newest backup taken before at and replays the journalled
+	// changes made after it, up to at.
+	RestoreToTime(at time.Time) (*PointInTimeRestore, error)
+
+	// SetEncryptionPassphrase enables archive encryption or changes its passphrase,
+	// re-encrypting existing archives. Returns the number of archives rewritten.
+	SetEncryptionPassphrase(current, next string) (int, error)
+
+	// Replicate copies archives to the configured targets and prunes them there.
+	Replicate() error
+}
+
+// Target is a secondary destination archives are copied to. Names are archive file
+// names; a target holds no folders of its own.
+type Target interface {
+	// List returns the archive names held by the target.
+	List() ([]string, error)
+
+	// Upload copies the local file at localPath to the target as name. A partly
+	// written copy is never left under name.
+	Upload(localPath, name string) error
+
+	// Remove deletes an archive from the target.
+	Remove(name string) error
+
+	// Close releases any connection the target holds. It may be used again after.
+	Close() error
 }
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"time"
+
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+)
+
+// Event types published after a change is committed.
+const (
+	TypeItemCreated           = "ItemCreated"
+	TypeItemUpdated           = "ItemUpdated"
+	TypePartyCreated          = "PartyCreated"
+	TypePartyUpdated          = "PartyUpdated"
+	TypeRecipeCreated         = "RecipeCreated"
+	TypeRecipeUpdated         = "RecipeUpdated"
+	TypeGrnCreated            = "GrnCreated"
+	TypeLotMovementRecorded   = "LotMovementRecorded"
+	TypeAdjustmentPosted      = "AdjustmentPosted"
+	TypeStockBelowMinimum     = "StockBelowMinimum"
+	TypeConversionRuleAdded   = "ConversionRuleAdded"
+	TypePackagingProfileAdded = "PackagingProfileAdded"
+)
+
+// Types lists every event type, e.g. for choosing webhook subscriptions.
+var Types = []string{
+	TypeItemCreated,
+	TypeItemUpdated,
+	TypePartyCreated,
+	TypePartyUpdated,
+	TypeRecipeCreated,
+	TypeRecipeUpdated,
+	TypeGrnCreated,
+	TypeLotMovementRecorded,
+	TypeAdjustmentPosted,
+	TypeStockBelowMinimum,
+	TypeConversionRuleAdded,
+	TypePackagingProfileAdded,
+}
+
+// Event is a committed domain change. Roles lists who may receive it; an empty list means
+// any signed-in user.
+type Event struct {
+	Sequence   uint64            `json:"sequence,omitempty"`
+	Type       string            `json:"type"`
+	EntityType string            `json:"entity_type"`
+	EntityID   string            `json:"entity_id"`
+	Actor      string            `json:"actor"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Data       interface{}       `json:"data,omitempty"`
+	Roles      []domainAuth.Role `json:"-"`
+}
+
+// VisibleTo reports whether a user with role may receive the event.
+func (e Event) VisibleTo(role domainAuth.Role) bool {
+	if len(e.Roles) == 0 {
+		return role != ""
+	}
+	for _, allowed := range e.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Publisher delivers events to subscribers. Publish must not block the caller.
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Outbox stores events that must not be lost, such as webhook deliveries. Unlike Publish,
+// Enqueue blocks until the event is stored, so a busy subscriber cannot drop it.
+type Outbox interface {
+	Enqueue(event Event) error
+}
@@ -0,0 +1,29 @@
+package idempotency
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrRecordNotFound = errors.New("idempotency record not found")
+
+// Record is the stored outcome of a request made with an Idempotency-Key. RequestHash
+// identifies the request body so a key reused for a different request can be refused.
+type Record struct {
+	Key          string    `json:"key"`
+	RequestHash  string    `json:"request_hash"`
+	StatusCode   int       `json:"status_code"`
+	ContentType  string    `json:"content_type"`
+	ResponseBody []byte    `json:"response_body"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Repository stores records until they expire.
+type Repository interface {
+	// Get returns the unexpired record for key, or ErrRecordNotFound.
+	Get(key string, now time.Time) (*Record, error)
+	// Save stores a record, replacing an expired one with the same key.
+	Save(record *Record) error
+	DeleteExpired(now time.Time) (int64, error)
+}
@@ -19,6 +19,7 @@ const (
 var (
 	ErrItemNameRequired              = errors.New("item name is required")
 	ErrItemTypeRequired              = errors.New("item type is required")
+	ErrItemNotFound                  = errors.New("item not found")
 	ErrBaseUnitRequired              = errors.New("base unit is required")
 	ErrUnsupportedItemType           = errors.New("unsupported item type")
 	ErrProfileNameRequired           = errors.New("packaging profile name is required")
@@ -33,6 +34,7 @@ var (
 	ErrGRNLineQuantity               = errors.New("grn line quantity must be greater than zero")
 	ErrGRNLineUnitPrice              = errors.New("grn line unit price must not be negative")
 	ErrLotNumberRequired             = errors.New("lot number is required")
+	ErrLotNotFound                   = errors.New("lot not found")
 	ErrMovementTypeInvalid           = errors.New("movement type must be OUT or ADJUSTMENT")
 	ErrMovementQtyInvalid            = errors.New("movement quantity must be greater than zero")
 	ErrStockAdjReasonCodeRequired    = errors.New("reason_code is required")
@@ -182,6 +184,7 @@ type GRN struct {
 	InvoiceNo  string    `json:"invoice_no"`
 	Notes      string    `json:"notes"`
 	Lines      []GRNLine `json:"lines"`
+	CreatedBy  string    `json:"created_by"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -218,6 +221,7 @@ type MaterialLotListFilter struct {
 	GRNNumber  string
 	ActiveOnly bool
 	Search     string
+	Page       PageRequest
 }
 
 type StockLedgerMovement struct {
@@ -228,11 +232,16 @@ type StockLedgerMovement struct {
 	ReferenceID     string    `json:"reference_id"`
 	LotNumber       string    `json:"lot_number"`
 	Notes           string    `json:"notes"`
+	CreatedBy       string    `json:"created_by"`
 	CreatedAt       time.Time `json:"created_at"`
 }
 
+// StockLedgerMovementListFilter needs a lot number, a reference or both. Movements sharing a
+// reference, such as the OUT movements of one dispatch, can be listed across lots.
 type StockLedgerMovementListFilter struct {
-	LotNumber string
+	LotNumber   string
+	ReferenceID string
+	Page        PageRequest
 }
 
 func (m *StockLedgerMovement) ValidateNonInbound() error {
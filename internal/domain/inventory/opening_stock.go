@@ -0,0 +1,61 @@
+package inventory
+
+import (
+	"errors"
+	"time"
+)
+
+// Lot source types recorded in material_lots.source_type.
+const (
+	LotSourceSupplierGRN    = "SUPPLIER_GRN"
+	LotSourceOpeningBalance = "OPENING_BALANCE"
+)
+
+var (
+	ErrOpeningStockItemRequired     = errors.New("opening stock item id is required")
+	ErrOpeningStockSupplierRequired = errors.New("opening stock supplier id is required")
+	ErrOpeningStockQuantity         = errors.New("opening stock quantity must be greater than zero")
+	ErrOpeningStockUnitCost         = errors.New("opening stock unit cost must not be negative")
+)
+
+// OpeningStockLine is stock already on hand when a unit starts using the system. It is
+// received like a GRN line, so it gets a lot number and traceability like any other lot.
+type OpeningStockLine struct {
+	ItemID     int64   `json:"item_id"`
+	SupplierID int64   `json:"supplier_id"`
+	Quantity   float64 `json:"quantity"`
+	UnitCost   float64 `json:"unit_cost"`
+	LotNumber  string  `json:"lot_number"`
+}
+
+func (l *OpeningStockLine) Validate() error {
+	if l == nil {
+		return errors.New("opening stock line is nil")
+	}
+	if l.ItemID <= 0 {
+		return ErrOpeningStockItemRequired
+	}
+	if l.SupplierID <= 0 {
+		return ErrOpeningStockSupplierRequired
+	}
+	if l.Quantity <= 0 {
+		return ErrOpeningStockQuantity
+	}
+	if l.UnitCost < 0 {
+		return ErrOpeningStockUnitCost
+	}
+	return nil
+}
+
+// MasterDataImport is one confirmed bulk import. The repository writes all of it in a single
+// transaction: either every row is created or none is. Opening stock is received on one GRN
+// per supplier, returned in GRNs.
+type MasterDataImport struct {
+	Items        []Item
+	Parties      []Party
+	OpeningStock []OpeningStockLine
+	CreatedBy    string
+	CreatedAt    time.Time
+
+	GRNs []GRN
+}
@@ -0,0 +1,35 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOpeningStockLineValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		line *OpeningStockLine
+		err  error
+	}{
+		{name: "valid", line: &OpeningStockLine{ItemID: 1, SupplierID: 2, Quantity: 10, UnitCost: 0}},
+		{name: "missing item", line: &OpeningStockLine{SupplierID: 2, Quantity: 10}, err: ErrOpeningStockItemRequired},
+		{name: "missing supplier", line: &OpeningStockLine{ItemID: 1, Quantity: 10}, err: ErrOpeningStockSupplierRequired},
+		{name: "zero quantity", line: &OpeningStockLine{ItemID: 1, SupplierID: 2}, err: ErrOpeningStockQuantity},
+		{name: "negative cost", line: &OpeningStockLine{ItemID: 1, SupplierID: 2, Quantity: 10, UnitCost: -1}, err: ErrOpeningStockUnitCost},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.line.Validate()
+			if tc.err == nil {
+				if err != nil {
+					t.Fatalf("expected valid line, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
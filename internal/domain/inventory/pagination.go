@@ -0,0 +1,59 @@
+package inventory
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	SortOrderAsc  = "ASC"
+	SortOrderDesc = "DESC"
+
+	// MaxPageLimit caps a single page so one request cannot pull an entire table over the LAN.
+	MaxPageLimit = 500
+)
+
+var (
+	ErrPageLimitInvalid = errors.New("limit must be between 0 and 500")
+	ErrSortKeyInvalid   = errors.New("sort_by is not a supported sort key")
+	ErrSortOrderInvalid = errors.New("sort_order must be ASC or DESC")
+	ErrCursorInvalid    = errors.New("cursor is invalid or does not match the requested sort")
+)
+
+// PageRequest selects one page of a list. A zero Limit returns every row after Cursor.
+// Cursor is the opaque NextCursor of the previous page; SortBy/SortOrder must not change between pages.
+type PageRequest struct {
+	Limit     int
+	Cursor    string
+	SortBy    string
+	SortOrder string
+}
+
+// PageInfo describes where a page sits in the full, filtered result set.
+type PageInfo struct {
+	NextCursor string
+	TotalCount int
+}
+
+// Normalize trims and upper-cases the sort options.
+func (p *PageRequest) Normalize() {
+	if p == nil {
+		return
+	}
+	p.Cursor = strings.TrimSpace(p.Cursor)
+	p.SortBy = strings.ToLower(strings.TrimSpace(p.SortBy))
+	p.SortOrder = strings.ToUpper(strings.TrimSpace(p.SortOrder))
+}
+
+// Validate checks the limit and sort order; sort keys are validated by the repository per list.
+func (p PageRequest) Validate() error {
+	if p.Limit < 0 || p.Limit > MaxPageLimit {
+		return ErrPageLimitInvalid
+	}
+	switch p.SortOrder {
+	case "", SortOrderAsc, SortOrderDesc:
+		return nil
+	default:
+		return ErrSortOrderInvalid
+	}
+}
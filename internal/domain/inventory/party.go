@@ -16,6 +16,7 @@ const (
 )
 
 var (
+	ErrPartyNotFound           = errors.New("party not found")
 	ErrPartyTypeRequired       = errors.New("party_type is required")
 	ErrPartyTypeUnsupported    = errors.New("unsupported party_type")
 	ErrPartyNameRequired       = errors.New("party name is required")
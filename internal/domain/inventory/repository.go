@@ -4,6 +4,7 @@ type ItemListFilter struct {
 	ActiveOnly bool
 	ItemType   ItemType
 	Search     string
+	Page       PageRequest
 }
 
 type PackagingProfileListFilter struct {
@@ -22,12 +23,23 @@ type PartyListFilter struct {
 	ActiveOnly bool
 	PartyType  PartyType
 	Search     string
+	Page       PageRequest
+}
+
+type StockAdjustmentListFilter struct {
+	ItemID int64
+	Page   PageRequest
 }
 
 type Repository interface {
+	// WithActor returns a repository whose writes are attributed to actor in the audit log.
+	WithActor(actor string) Repository
+
 	CreateItem(item *Item) error
 	UpdateItem(item *Item) error
-	ListItems(filter ItemListFilter) ([]Item, error)
+	// GetItem returns ErrItemNotFound when no item has the id.
+	GetItem(id int64) (*Item, error)
+	ListItems(filter ItemListFilter) ([]Item, PageInfo, error)
 
 	CreatePackagingProfile(profile *PackagingProfile) error
 	ListPackagingProfiles(filter PackagingProfileListFilter) ([]PackagingProfile, error)
@@ -38,7 +50,9 @@ type Repository interface {
 
 	CreateParty(party *Party) error
 	UpdateParty(party *Party) error
-	ListParties(filter PartyListFilter) ([]Party, error)
+	// GetParty returns ErrPartyNotFound when no party has the id.
+	GetParty(id int64) (*Party, error)
+	ListParties(filter PartyListFilter) ([]Party, PageInfo, error)
 
 	CreateUnitConversionRule(rule *UnitConversionRule) error
 	FindUnitConversionRule(lookup UnitConversionLookup) (*UnitConversionRule, error)
@@ -48,12 +62,21 @@ type Repository interface {
 	UpdateBatch(batch *Batch) error
 
 	CreateGRN(grn *GRN) error
-	ListMaterialLots(filter MaterialLotListFilter) ([]MaterialLot, error)
+	ListMaterialLots(filter MaterialLotListFilter) ([]MaterialLot, PageInfo, error)
+	// GetMaterialLot returns ErrLotNotFound when no lot has the number.
+	GetMaterialLot(lotNumber string) (*MaterialLot, error)
+	// GetLotStockBalance is what remains of a lot: its IN movements less its OUT and
+	// ADJUSTMENT movements, plus stock adjustments booked against it.
+	GetLotStockBalance(lotNumber string) (float64, error)
 	RecordLotStockMovement(movement *StockLedgerMovement) error
-	ListLotStockMovements(filter StockLedgerMovementListFilter) ([]StockLedgerMovement, error)
+	ListLotStockMovements(filter StockLedgerMovementListFilter) ([]StockLedgerMovement, PageInfo, error)
 	UpdateGRN(grn *GRN) error
 
 	CreateStockAdjustment(adj *StockAdjustment) error
-	ListStockAdjustments(itemID int64) ([]StockAdjustment, error)
+	ListStockAdjustments(filter StockAdjustmentListFilter) ([]StockAdjustment, PageInfo, error)
 	GetItemStockBalance(itemID int64) (float64, error)
+
+	// ImportMasterData creates every item, party and opening stock line of batch in one
+	// transaction, so a failed import leaves nothing behind.
+	ImportMasterData(batch *MasterDataImport) error
 }
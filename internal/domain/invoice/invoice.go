@@ -0,0 +1,325 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Supply types decide how GST is charged: CGST and SGST in halves within the seller's state,
+// IGST across states.
+const (
+	SupplyIntraState = "INTRA_STATE"
+	SupplyInterState = "INTER_STATE"
+)
+
+// NumberPrefix starts every invoice number. With the financial year and a four-digit sequence
+// the number stays within the 16 characters GST allows.
+const NumberPrefix = "INV"
+
+var (
+	ErrInvoiceNotFound         = errors.New("invoice not found")
+	ErrDispatchAlreadyInvoiced = errors.New("dispatch has already been invoiced")
+	ErrDispatchRequired        = errors.New("dispatch reference is required")
+	ErrDispatchNotFound        = errors.New("no dispatch movements found for the reference")
+	ErrCustomerRequired        = errors.New("customer is required")
+	ErrCustomerNotCustomer     = errors.New("invoice party must be a customer")
+	ErrPlaceOfSupplyRequired   = errors.New("place of supply is required when the customer has no GSTIN")
+	ErrPlaceOfSupplyInvalid    = errors.New("place of supply must be a GST state code")
+	ErrGSTINInvalid            = errors.New("gstin must be 15 characters starting with a state code")
+	ErrSellerGSTINRequired     = errors.New("company GSTIN is required to issue tax invoices")
+	ErrLineItemUnpriced        = errors.New("every dispatched item needs a price line")
+	ErrLineItemNotDispatched   = errors.New("price line item is not part of the dispatch")
+	ErrLineUnitPriceInvalid    = errors.New("unit price must not be negative")
+	ErrLineHSNInvalid          = errors.New("hsn code must be 4, 6 or 8 digits")
+	ErrLineGSTRateInvalid      = errors.New("gst rate must be one of 0, 0.25, 3, 5, 12, 18 or 28")
+)
+
+// Party is the seller or buyer as printed on the invoice. It is copied into the invoice so
+// later edits to the party master do not change issued invoices.
+type Party struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	Phone     string `json:"phone"`
+	GSTIN     string `json:"gstin"`
+	StateCode string `json:"state_code"`
+}
+
+// Line is one item of the invoice. Amounts are in rupees, already rounded to paise.
+type Line struct {
+	ItemID       int64   `json:"item_id"`
+	SKU          string  `json:"sku"`
+	Description  string  `json:"description"`
+	HSNCode      string  `json:"hsn_code"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	UnitPrice    float64 `json:"unit_price"`
+	TaxableValue float64 `json:"taxable_value"`
+	GSTRate      float64 `json:"gst_rate"`
+	CGST         float64 `json:"cgst"`
+	SGST         float64 `json:"sgst"`
+	IGST         float64 `json:"igst"`
+	Total        float64 `json:"total"`
+}
+
+// Invoice is an issued GST tax invoice. It is immutable once stored; the PDF rendered at issue
+// time is kept beside it so a reprint is byte-for-byte the original.
+type Invoice struct {
+	ID                int64     `json:"id"`
+	InvoiceNumber     string    `json:"invoice_number"`
+	FinancialYear     string    `json:"financial_year"`
+	Sequence          int       `json:"sequence"`
+	InvoiceDate       time.Time `json:"invoice_date"`
+	DispatchReference string    `json:"dispatch_reference"`
+	CustomerID        int64     `json:"customer_id"`
+	Seller            Party     `json:"seller"`
+	Buyer             Party     `json:"buyer"`
+	PlaceOfSupply     string    `json:"place_of_supply"`
+	SupplyType        string    `json:"supply_type"`
+	Lines             []Line    `json:"lines"`
+	TaxableValue      float64   `json:"taxable_value"`
+	CGST              float64   `json:"cgst"`
+	SGST              float64   `json:"sgst"`
+	IGST              float64   `json:"igst"`
+	RoundOff          float64   `json:"round_off"`
+	GrandTotal        float64   `json:"grand_total"`
+	AmountInWords     string    `json:"amount_in_words"`
+	Notes             string    `json:"notes"`
+	CreatedBy         string    `json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// ListFilter narrows the invoice register. Zero values are ignored.
+type ListFilter struct {
+	FinancialYear string
+	CustomerID    int64
+	Limit         int
+}
+
+type Repository interface {
+	// Create assigns the next number of the invoice's financial year, renders it and stores
+	// both in one transaction, so numbers have no gaps and every stored invoice has its PDF.
+	Create(invoice *Invoice, render func(*Invoice) ([]byte, error)) error
+	Get(id int64) (*Invoice, error)
+	// GetPDF returns the document rendered when the invoice was issued.
+	GetPDF(id int64) (*Invoice, []byte, error)
+	List(filter ListFilter) ([]Invoice, error)
+}
+
+// FinancialYear returns the Indian financial year (April to March) containing t, as "2026-27".
+func FinancialYear(t time.Time) string {
+	start := t.Year()
+	if t.Month() < time.April {
+		start--
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100)
+}
+
+// Number formats an invoice number, e.g. INV/2026-27/0001.
+func Number(financialYear string, sequence int) string {
+	return fmt.Sprintf("%s/%s/%04d", NumberPrefix, financialYear, sequence)
+}
+
+var (
+	gstinPattern = regexp.MustCompile(`^[0-9]{2}[A-Z0-9]{13}$`)
+	hsnPattern   = regexp.MustCompile(`^([0-9]{4}|[0-9]{6}|[0-9]{8})$`)
+)
+
+// NormalizeGSTIN upper-cases and checks a GSTIN. An empty GSTIN is allowed (unregistered buyer).
+func NormalizeGSTIN(gstin string) (string, error) {
+	gstin = strings.ToUpper(strings.TrimSpace(gstin))
+	if gstin == "" {
+		return "", nil
+	}
+	if !gstinPattern.MatchString(gstin) || StateName(gstin[:2]) == "" {
+		return "", ErrGSTINInvalid
+	}
+	return gstin, nil
+}
+
+// NormalizeStateCode accepts "7" or "07" and returns the two-digit code.
+func NormalizeStateCode(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 1 {
+		code = "0" + code
+	}
+	if StateName(code) == "" {
+		return "", ErrPlaceOfSupplyInvalid
+	}
+	return code, nil
+}
+
+// ValidHSN reports whether code is a 4, 6 or 8 digit HSN code.
+func ValidHSN(code string) bool {
+	return hsnPattern.MatchString(code)
+}
+
+var gstRates = []float64{0, 0.25, 3, 5, 12, 18, 28}
+
+// ValidGSTRate reports whether rate is a GST slab.
+func ValidGSTRate(rate float64) bool {
+	for _, slab := range gstRates {
+		if rate == slab {
+			return true
+		}
+	}
+	return false
+}
+
+// stateNames lists GST state codes. 25 and 28 are kept for invoices issued before the
+// Dadra/Daman and Andhra Pradesh reorganisations.
+var stateNames = map[string]string{
+	"01": "Jammu and Kashmir", "02": "Himachal Pradesh", "03": "Punjab", "04": "Chandigarh",
+	"05": "Uttarakhand", "06": "Haryana", "07": "Delhi", "08": "Rajasthan", "09": "Uttar Pradesh",
+	"10": "Bihar", "11": "Sikkim", "12": "Arunachal Pradesh", "13": "Nagaland", "14": "Manipur",
+	"15": "Mizoram", "16": "Tripura", "17": "Meghalaya", "18": "Assam", "19": "West Bengal",
+	"20": "Jharkhand", "21": "Odisha", "22": "Chhattisgarh", "23": "Madhya Pradesh", "24": "Gujarat",
+	"25": "Daman and Diu", "26": "Dadra and Nagar Haveli and Daman and Diu", "27": "Maharashtra",
+	"28": "Andhra Pradesh (Old)", "29": "Karnataka", "30": "Goa", "31": "Lakshadweep", "32": "Kerala",
+	"33": "Tamil Nadu", "34": "Puducherry", "35": "Andaman and Nicobar Islands", "36": "Telangana",
+	"37": "Andhra Pradesh", "38": "Ladakh", "97": "Other Territory",
+}
+
+// StateName returns the state for a two-digit GST state code, or "" when unknown.
+func StateName(code string) string {
+	return stateNames[code]
+}
+
+// Totals are the invoice sums, in paise.
+type Totals struct {
+	Taxable  int64
+	CGST     int64
+	SGST     int64
+	IGST     int64
+	RoundOff int64
+	Grand    int64
+}
+
+// Calculate prices every line and totals the invoice. Tax is computed per line and rounded to
+// the paisa; intra-state tax is split into equal CGST and SGST halves, each rounded. The grand
+// total is rounded to the nearest rupee and the difference shown as round-off.
+func (inv *Invoice) Calculate() {
+	var totals Totals
+	for i := range inv.Lines {
+		line := &inv.Lines[i]
+		taxable := toPaise(line.Quantity * line.UnitPrice)
+		var cgst, sgst, igst int64
+		if inv.SupplyType == SupplyIntraState {
+			cgst = roundPaise(float64(taxable) * line.GSTRate / 200)
+			sgst = cgst
+		} else {
+			igst = roundPaise(float64(taxable) * line.GSTRate / 100)
+		}
+		line.TaxableValue = fromPaise(taxable)
+		line.CGST, line.SGST, line.IGST = fromPaise(cgst), fromPaise(sgst), fromPaise(igst)
+		line.Total = fromPaise(taxable + cgst + sgst + igst)
+
+		totals.Taxable += taxable
+		totals.CGST += cgst
+		totals.SGST += sgst
+		totals.IGST += igst
+	}
+	exact := totals.Taxable + totals.CGST + totals.SGST + totals.IGST
+	totals.Grand = roundPaise(float64(exact)/100) * 100
+	totals.RoundOff = totals.Grand - exact
+
+	inv.TaxableValue = fromPaise(totals.Taxable)
+	inv.CGST, inv.SGST, inv.IGST = fromPaise(totals.CGST), fromPaise(totals.SGST), fromPaise(totals.IGST)
+	inv.RoundOff = fromPaise(totals.RoundOff)
+	inv.GrandTotal = fromPaise(totals.Grand)
+	inv.AmountInWords = AmountInWords(totals.Grand)
+}
+
+// roundPaise rounds half away from zero, as GST rounding rules expect.
+func roundPaise(v float64) int64 {
+	return int64(math.Round(v))
+}
+
+func toPaise(rupees float64) int64 {
+	// Round through a fixed precision first so 0.1*3 does not become 29.999... paise.
+	return roundPaise(math.Round(rupees*1e6) / 1e4)
+}
+
+func fromPaise(paise int64) float64 {
+	return float64(paise) / 100
+}
+
+// FormatAmount formats paise-precise rupees with Indian digit grouping, e.g. 1,23,456.50.
+func FormatAmount(rupees float64) string {
+	paise := toPaise(rupees)
+	sign := ""
+	if paise < 0 {
+		sign = "-"
+		paise = -paise
+	}
+	whole := fmt.Sprintf("%d", paise/100)
+	if len(whole) > 3 {
+		head, tail := whole[:len(whole)-3], whole[len(whole)-3:]
+		groups := make([]string, 0, len(head)/2+1)
+		for len(head) > 2 {
+			groups = append([]string{head[len(head)-2:]}, groups...)
+			head = head[:len(head)-2]
+		}
+		groups = append([]string{head}, groups...)
+		whole = strings.Join(groups, ",") + "," + tail
+	}
+	return fmt.Sprintf("%s%s.%02d", sign, whole, paise%100)
+}
+
+var (
+	ones = []string{"", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten",
+		"Eleven", "Twelve", "Thirteen", "Fourteen", "Fifteen", "Sixteen", "Seventeen", "Eighteen", "Nineteen"}
+	tens = []string{"", "", "Twenty", "Thirty", "Forty", "Fifty", "Sixty", "Seventy", "Eighty", "Ninety"}
+)
+
+// AmountInWords spells an amount in paise the way Indian invoices do, in crore, lakh and
+// thousand: 123456789 becomes "Rupees Twelve Lakh Thirty Four Thousand Five Hundred Sixty
+// Seven and Eighty Nine Paise Only".
+func AmountInWords(paise int64) string {
+	if paise < 0 {
+		return "Minus " + AmountInWords(-paise)
+	}
+	rupees, rest := paise/100, paise%100
+	words := "Rupees " + indianWords(rupees)
+	if rupees == 0 {
+		words = "Rupees Zero"
+	}
+	if rest > 0 {
+		words += " and " + belowHundred(rest) + " Paise"
+	}
+	return words + " Only"
+}
+
+func indianWords(n int64) string {
+	parts := make([]string, 0, 6)
+	if crore := n / 10000000; crore > 0 {
+		parts = append(parts, indianWords(crore)+" Crore")
+		n %= 10000000
+	}
+	for _, scale := range []struct {
+		value int64
+		name  string
+	}{{100000, "Lakh"}, {1000, "Thousand"}, {100, "Hundred"}} {
+		if count := n / scale.value; count > 0 {
+			parts = append(parts, belowHundred(count)+" "+scale.name)
+			n %= scale.value
+		}
+	}
+	if n > 0 {
+		parts = append(parts, belowHundred(n))
+	}
+	return strings.Join(parts, " ")
+}
+
+func belowHundred(n int64) string {
+	if n < 20 {
+		return ones[n]
+	}
+	if n%10 == 0 {
+		return tens[n/10]
+	}
+	return tens[n/10] + " " + ones[n%10]
+}
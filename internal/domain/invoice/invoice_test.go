@@ -0,0 +1,109 @@
+package invoice
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFinancialYear_StartsInApril(t *testing.T) {
+	cases := map[time.Time]string{
+		time.Date(2026, time.March, 31, 23, 59, 0, 0, time.Local): "2025-26",
+		time.Date(2026, time.April, 1, 0, 0, 0, 0, time.Local):    "2026-27",
+		time.Date(2099, time.December, 1, 0, 0, 0, 0, time.Local): "2099-00",
+	}
+	for at, want := range cases {
+		if got := FinancialYear(at); got != want {
+			t.Fatalf("FinancialYear(%s) = %s, want %s", at.Format("2006-01-02"), got, want)
+		}
+	}
+	if got := Number("2026-27", 7); got != "INV/2026-27/0007" || len(got) > 16 {
+		t.Fatalf("unexpected invoice number %q", got)
+	}
+}
+
+func TestCalculate_SplitsIntraStateTaxIntoHalves(t *testing.T) {
+	inv := &Invoice{SupplyType: SupplyIntraState, Lines: []Line{
+		{Quantity: 12.5, UnitPrice: 183.33, GSTRate: 5},
+		{Quantity: 3, UnitPrice: 0.1, GSTRate: 18},
+	}}
+	inv.Calculate()
+
+	// 12.5 x 183.33 = 2291.625 -> 2291.63; 2.5% of it is 57.29 each way.
+	first := inv.Lines[0]
+	if first.TaxableValue != 2291.63 || first.CGST != 57.29 || first.SGST != 57.29 || first.IGST != 0 || first.Total != 2406.21 {
+		t.Fatalf("unexpected first line: %+v", first)
+	}
+	second := inv.Lines[1]
+	if second.TaxableValue != 0.3 || second.CGST != 0.03 || second.SGST != 0.03 {
+		t.Fatalf("unexpected second line: %+v", second)
+	}
+	if inv.TaxableValue != 2291.93 || inv.CGST != 57.32 || inv.SGST != 57.32 {
+		t.Fatalf("unexpected totals: %+v", inv)
+	}
+	if inv.GrandTotal != 2407 || inv.RoundOff != 0.43 {
+		t.Fatalf("expected 2406.57 to round up to 2407, got %.2f (round off %.2f)", inv.GrandTotal, inv.RoundOff)
+	}
+	if inv.AmountInWords != "Rupees Two Thousand Four Hundred Seven Only" {
+		t.Fatalf("unexpected amount in words %q", inv.AmountInWords)
+	}
+}
+
+func TestCalculate_ChargesIGSTAcrossStates(t *testing.T) {
+	inv := &Invoice{SupplyType: SupplyInterState, Lines: []Line{{Quantity: 10, UnitPrice: 99.9, GSTRate: 12}}}
+	inv.Calculate()
+
+	if inv.IGST != 119.88 || inv.CGST != 0 || inv.SGST != 0 {
+		t.Fatalf("unexpected tax: %+v", inv)
+	}
+	if inv.GrandTotal != 1119 || inv.RoundOff != 0.12 {
+		t.Fatalf("expected 1118.88 to round to 1119, got %.2f (%.2f)", inv.GrandTotal, inv.RoundOff)
+	}
+}
+
+func TestAmountInWords_UsesLakhAndCrore(t *testing.T) {
+	cases := map[int64]string{
+		0:            "Rupees Zero Only",
+		5:            "Rupees Zero and Five Paise Only",
+		123456789:    "Rupees Twelve Lakh Thirty Four Thousand Five Hundred Sixty Seven and Eighty Nine Paise Only",
+		100000000000: "Rupees One Hundred Crore Only",
+		1100000:      "Rupees Eleven Thousand Only",
+		2500000000:   "Rupees Two Crore Fifty Lakh Only",
+	}
+	for paise, want := range cases {
+		if got := AmountInWords(paise); got != want {
+			t.Fatalf("AmountInWords(%d) = %q, want %q", paise, got, want)
+		}
+	}
+}
+
+func TestFormatAmount_GroupsDigitsTheIndianWay(t *testing.T) {
+	cases := map[float64]string{0: "0.00", 999.5: "999.50", 1000: "1,000.00", 123456.78: "1,23,456.78", -12345678: "-1,23,45,678.00"}
+	for amount, want := range cases {
+		if got := FormatAmount(amount); got != want {
+			t.Fatalf("FormatAmount(%v) = %q, want %q", amount, got, want)
+		}
+	}
+}
+
+func TestNormalizeGSTINAndStateCode(t *testing.T) {
+	if gstin, err := NormalizeGSTIN(" 27abcde1234f1z5 "); err != nil || gstin != "27ABCDE1234F1Z5" {
+		t.Fatalf("unexpected gstin %q (%v)", gstin, err)
+	}
+	for _, bad := range []string{"99ABCDE1234F1Z5", "27ABCDE1234F1Z", "27-BCDE1234F1Z5"} {
+		if _, err := NormalizeGSTIN(bad); err != ErrGSTINInvalid {
+			t.Fatalf("expected %q to be rejected, got %v", bad, err)
+		}
+	}
+	if code, err := NormalizeStateCode("7"); err != nil || code != "07" {
+		t.Fatalf("unexpected state code %q (%v)", code, err)
+	}
+	if _, err := NormalizeStateCode("40"); err != ErrPlaceOfSupplyInvalid {
+		t.Fatalf("expected an unknown state code to be rejected, got %v", err)
+	}
+	if !ValidHSN("0909") || !ValidHSN("09093129") || ValidHSN("09093") {
+		t.Fatalf("unexpected HSN validation")
+	}
+	if !ValidGSTRate(5) || ValidGSTRate(7) {
+		t.Fatalf("unexpected GST rate validation")
+	}
+}
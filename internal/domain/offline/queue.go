@@ -0,0 +1,50 @@
+package offline
+
+import (
+	"errors"
+	"time"
+)
+
+// Operations a client may queue while the server is unreachable.
+const (
+	OperationCreateGRN             = "CREATE_GRN"
+	OperationRecordLotMovement     = "RECORD_LOT_MOVEMENT"
+	OperationCreateStockAdjustment = "CREATE_STOCK_ADJUSTMENT"
+)
+
+// Entry states. Sent entries are deleted, so only work still owed to the server is stored.
+const (
+	StatusPending  = "PENDING"
+	StatusConflict = "CONFLICT"
+)
+
+var ErrEntryNotFound = errors.New("offline queue entry not found")
+
+// Entry is one write captured on a client while the server was unreachable. Payload is the
+// JSON request body; IdempotencyKey lets the server recognise a replay it already applied.
+type Entry struct {
+	ID             int64     `json:"id"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	Operation      string    `json:"operation"`
+	Payload        string    `json:"payload"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Repository persists the queue in enqueue order.
+type Repository interface {
+	Enqueue(entry *Entry) error
+	// List returns entries with the given status, oldest first; an empty status returns all.
+	List(status string) ([]Entry, error)
+	Get(id int64) (*Entry, error)
+	// RecordAttempt bumps the attempt count and stores why the entry is still owed.
+	RecordAttempt(id int64, lastError string) error
+	MarkConflict(id int64, reason string) error
+	// Requeue stores the corrected payload of a conflicted entry and makes it pending again,
+	// keeping its place in the queue.
+	Requeue(id int64, payload string) error
+	Delete(id int64) error
+}
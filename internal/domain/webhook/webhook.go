@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Outbox entry states. PENDING entries are retried until they are DELIVERED or run out of
+// attempts and become FAILED.
+const (
+	OutboxPending   = "PENDING"
+	OutboxDelivered = "DELIVERED"
+	OutboxFailed    = "FAILED"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrNameRequired         = errors.New("webhook name is required")
+	ErrURLInvalid           = errors.New("webhook url must be an absolute http or https url")
+	ErrEventTypesRequired   = errors.New("at least one event type is required")
+	ErrSecretTooShort       = errors.New("webhook secret must be at least 16 characters")
+)
+
+const MinSecretLength = 16
+
+// Subscription sends the listed event types to URL, signed with Secret.
+type Subscription struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"-"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (s *Subscription) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.URL = strings.TrimSpace(s.URL)
+	if s.Name == "" {
+		return ErrNameRequired
+	}
+	parsed, err := url.Parse(s.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrURLInvalid
+	}
+	types := make([]string, 0, len(s.EventTypes))
+	seen := make(map[string]bool, len(s.EventTypes))
+	for _, eventType := range s.EventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if eventType == "" || seen[eventType] {
+			continue
+		}
+		seen[eventType] = true
+		types = append(types, eventType)
+	}
+	if len(types) == 0 {
+		return ErrEventTypesRequired
+	}
+	s.EventTypes = types
+	if len(s.Secret) < MinSecretLength {
+		return ErrSecretTooShort
+	}
+	return nil
+}
+
+// Wants reports whether the subscription receives eventType.
+func (s Subscription) Wants(eventType string) bool {
+	if !s.IsActive {
+		return false
+	}
+	for _, wanted := range s.EventTypes {
+		if wanted == eventType || wanted == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboxEntry is one event owed to one subscription. Payload is the exact body that is signed
+// and sent on every attempt.
+type OutboxEntry struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int64     `json:"subscription_id"`
+	EventType      string    `json:"event_type"`
+	Payload        string    `json:"payload"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Delivery records one attempt to deliver an outbox entry.
+type Delivery struct {
+	ID             int64     `json:"id"`
+	OutboxID       int64     `json:"outbox_id"`
+	SubscriptionID int64     `json:"subscription_id"`
+	EventType      string    `json:"event_type"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     int       `json:"status_code"`
+	Success        bool      `json:"success"`
+	Error          string    `json:"error"`
+	DurationMs     int64     `json:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at"`
+}
+
+// DeliveryFilter narrows the delivery log. Zero values are ignored.
+type DeliveryFilter struct {
+	SubscriptionID int64
+	Limit          int
+}
+
+type Repository interface {
+	CreateSubscription(sub *Subscription) error
+	UpdateSubscription(sub *Subscription) error
+	GetSubscription(id int64) (*Subscription, error)
+	ListSubscriptions() ([]Subscription, error)
+	DeleteSubscription(id int64) error
+
+	// Enqueue adds an outbox entry per subscription, in one transaction.
+	Enqueue(entries []OutboxEntry) error
+	// DueOutbox returns pending entries whose next attempt is due, oldest first.
+	DueOutbox(now time.Time, limit int) ([]OutboxEntry, error)
+	// RecordDelivery logs an attempt and moves the outbox entry to its next state.
+	RecordDelivery(delivery *Delivery, status string, nextAttemptAt time.Time) error
+	ListDeliveries(filter DeliveryFilter) ([]Delivery, error)
+}
@@ -21,16 +21,31 @@ func NewTokenService(secretKey string) *TokenService {
 	}
 }
 
+// DefaultTokenTTL is the lifetime of a password-based session.
+const DefaultTokenTTL = 24 * time.Hour
+
+// AuthMethodPin marks sessions that were opened with an operator PIN.
+const AuthMethodPin = "pin"
+
 // CustomClaims extends jwt.RegisteredClaims to include role information.
 type CustomClaims struct {
 	Role        string `json:"role"`
 	UserVersion int64  `json:"user_version,omitempty"`
+	AuthMethod  string `json:"auth_method,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateToken generates a signed JWT for the user.
 func (s *TokenService) GenerateToken(user *auth.User) (*auth.AuthToken, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Token valid for 24 hours
+	return s.GenerateTokenWithTTL(user, DefaultTokenTTL, "")
+}
+
+// GenerateTokenWithTTL generates a signed JWT with a custom lifetime and auth method claim.
+func (s *TokenService) GenerateTokenWithTTL(user *auth.User, ttl time.Duration, authMethod string) (*auth.AuthToken, error) {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	expirationTime := time.Now().Add(ttl)
 	userVersion := user.UpdatedAt.UTC().UnixNano()
 	if userVersion <= 0 {
 		userVersion = time.Now().UTC().UnixNano()
@@ -38,6 +53,7 @@ func (s *TokenService) GenerateToken(user *auth.User) (*auth.AuthToken, error) {
 	claims := &CustomClaims{
 		Role:        string(user.Role),
 		UserVersion: userVersion,
+		AuthMethod:  authMethod,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   user.Username,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// Monday 2 March 2026, 10:17.
+	from := time.Date(2026, 3, 2, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 2 * * *", time.Date(2026, 3, 3, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 2, 10, 30, 0, 0, time.UTC)},
+		{"0 9-18 * * MON-SAT", time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC)},
+		{"30 9-18/4 * * 1-5", time.Date(2026, 3, 2, 13, 30, 0, 0, time.UTC)},
+		{"5,10 8 * * sun", time.Date(2026, 3, 8, 8, 5, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 3 1 */3 *", time.Date(2026, 4, 1, 3, 0, 0, 0, time.UTC)},
+		{"0 0 29 FEB *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"17 10 * * *", time.Date(2026, 3, 3, 10, 17, 0, 0, time.UTC)},
+		{"10/20 11 * * *", time.Date(2026, 3, 2, 11, 10, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 15th or any Friday, whichever is first.
+		{"0 0 15 * FRI", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		// A "*" day of week leaves only the day of month.
+		{"0 0 15 * *", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCronNext_DaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	schedule, err := ParseCron("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 02:30 does not exist on 8 March 2026, so the next run is the following day.
+	got := schedule.Next(time.Date(2026, 3, 7, 12, 0, 0, 0, loc))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("next = %v, want %v", got, want)
+	}
+
+	hourly, err := ParseCron("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The clocks fall back at 02:00 on 1 November 2026; 01:00 EST follows 01:00 EDT.
+	first := time.Date(2026, 11, 1, 1, 0, 0, 0, loc)
+	next := hourly.Next(first)
+	if next.Sub(first) != time.Hour || next.Hour() != 1 {
+		t.Errorf("next after %v = %v, want the repeated 01:00", first, next)
+	}
+}
+
+func TestParseCron_Rejects(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 2 * *",
+		"0 2 * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 31 FEB *",
+		"@reboot",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
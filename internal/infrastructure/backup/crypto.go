@@ -0,0 +1,399 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Encrypted archives are the backup zip sealed with AES-256-GCM in chunks:
+//
+//	header: magic(8) version(1) time(4) memory(4) threads(1) salt(16) check(16) nonce(8)
+//	chunk:  last(1) length(4) ciphertext(length)
+//
+// The key is derived from the Admin passphrase with Argon2id using the salt and cost in
+// the header, so an archive can be opened with its passphrase on any machine. check lets a
+// wrong passphrase be reported before anything is decrypted. Each chunk's nonce is the
+// header nonce followed by the chunk number, and the header and last flag are
+// authenticated with every chunk, so reordered, truncated or spliced archives fail.
+const (
+	encryptedMagic   = "MASALABK"
+	encryptedVersion = 1
+	encryptedChunk   = 64 * 1024
+	saltSize         = 16
+	checkSize        = 16
+	noncePrefixSize  = 8
+	headerSize       = len(encryptedMagic) + 1 + 4 + 4 + 1 + saltSize + checkSize + noncePrefixSize
+
+	// MinPassphraseLength keeps passphrases out of reach of offline guessing at the
+	// Argon2id cost below.
+	MinPassphraseLength = 12
+)
+
+var (
+	ErrWrongPassphrase    = errors.New("backup passphrase is incorrect")
+	ErrBackupKeyMissing   = errors.New("backup is encrypted; its passphrase is required")
+	ErrBackupCorrupted    = errors.New("encrypted backup is damaged or has been modified")
+	ErrPassphraseTooShort = fmt.Errorf("backup passphrase must be at least %d characters", MinPassphraseLength)
+)
+
+// kdfParams is the Argon2id cost. Memory is in KiB.
+type kdfParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// defaultKDF follows the RFC 9106 second recommendation, sized for a shop-floor PC.
+var defaultKDF = kdfParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// Limits on the cost read from an archive or key file. The cost is not authenticated until
+// the key is derived, so without them a damaged or crafted header could make the restore
+// allocate gigabytes or spin for hours before the passphrase is even checked.
+const (
+	maxKDFTime    = 16
+	maxKDFMemory  = 1 << 20 // 1 GiB
+	maxKDFThreads = 64
+)
+
+// valid reports whether the cost is one Argon2id accepts and within the limits above.
+func (p kdfParams) valid() bool {
+	return p.Time >= 1 && p.Time <= maxKDFTime &&
+		p.Threads >= 1 && p.Threads <= maxKDFThreads &&
+		p.Memory >= 8*uint32(p.Threads) && p.Memory <= maxKDFMemory
+}
+
+// backupKey is a derived archive key with the salt and cost it came from.
+type backupKey struct {
+	params kdfParams
+	salt   []byte
+	key    []byte
+}
+
+func newBackupKey(passphrase string) (*backupKey, error) {
+	if len([]rune(passphrase)) < MinPassphraseLength {
+		return nil, ErrPassphraseTooShort
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return deriveBackupKey(passphrase, salt, defaultKDF), nil
+}
+
+func deriveBackupKey(passphrase string, salt []byte, params kdfParams) *backupKey {
+	return &backupKey{
+		params: params,
+		salt:   append([]byte(nil), salt...),
+		key:    argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, 32),
+	}
+}
+
+// check is the key check value stored in archive headers.
+func (k *backupKey) check() []byte {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write([]byte("masala backup key check"))
+	return mac.Sum(nil)[:checkSize]
+}
+
+// matches reports whether passphrase derives this key.
+func (k *backupKey) matches(passphrase string) bool {
+	return hmac.Equal(deriveBackupKey(passphrase, k.salt, k.params).key, k.key)
+}
+
+type keyFile struct {
+	Version int       `json:"version"`
+	KDF     kdfParams `json:"kdf"`
+	Salt    string    `json:"salt"`
+	Key     string    `json:"key"`
+}
+
+// loadBackupKey reads the derived key kept beside the database. A missing file means
+// backups are not encrypted.
+func loadBackupKey(path string) (*backupKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("backup key file %s is invalid: %w", path, err)
+	}
+	salt, saltErr := base64.StdEncoding.DecodeString(file.Salt)
+	key, keyErr := base64.StdEncoding.DecodeString(file.Key)
+	if file.Version != encryptedVersion || saltErr != nil || keyErr != nil || len(salt) != saltSize || len(key) != 32 || !file.KDF.valid() {
+		return nil, fmt.Errorf("backup key file %s is invalid", path)
+	}
+	return &backupKey{params: file.KDF, salt: salt, key: key}, nil
+}
+
+// saveBackupKey writes the key readable by the service account only. Written beside the
+// destination and renamed, so a crash never leaves a half-written key.
+func saveBackupKey(path string, k *backupKey) error {
+	data, err := json.MarshalIndent(keyFile{
+		Version: encryptedVersion,
+		KDF:     k.params,
+		Salt:    base64.StdEncoding.EncodeToString(k.salt),
+		Key:     base64.StdEncoding.EncodeToString(k.key),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// encryptedHeader is the parsed header of an encrypted archive.
+type encryptedHeader struct {
+	raw    []byte
+	params kdfParams
+	salt   []byte
+	check  []byte
+	nonce  []byte
+}
+
+func readEncryptedHeader(r io.Reader) (*encryptedHeader, error) {
+	raw := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, ErrBackupCorrupted
+	}
+	if string(raw[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, fmt.Errorf("not an encrypted backup")
+	}
+	p := raw[len(encryptedMagic):]
+	if p[0] != encryptedVersion {
+		return nil, fmt.Errorf("unsupported encrypted backup version %d", p[0])
+	}
+	h := &encryptedHeader{raw: raw}
+	h.params = kdfParams{Time: binary.BigEndian.Uint32(p[1:5]), Memory: binary.BigEndian.Uint32(p[5:9]), Threads: p[9]}
+	if !h.params.valid() {
+		return nil, ErrBackupCorrupted
+	}
+	p = p[10:]
+	h.salt, p = p[:saltSize], p[saltSize:]
+	h.check, p = p[:checkSize], p[checkSize:]
+	h.nonce = p[:noncePrefixSize]
+	return h, nil
+}
+
+// isEncryptedArchive reports whether the file at path starts with the encrypted header.
+func isEncryptedArchive(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(encryptedMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, nil
+	}
+	return string(magic) == encryptedMagic, nil
+}
+
+// keyFor returns the key that opens an archive: k when it was used, otherwise one
+// derived from passphrase. The key check is compared before any data is decrypted.
+func (h *encryptedHeader) keyFor(k *backupKey, passphrase string) (*backupKey, error) {
+	if k != nil && hmac.Equal(h.check, k.check()) {
+		return k, nil
+	}
+	if passphrase == "" {
+		return nil, ErrBackupKeyMissing
+	}
+	derived := deriveBackupKey(passphrase, h.salt, h.params)
+	if !hmac.Equal(h.check, derived.check()) {
+		return nil, ErrWrongPassphrase
+	}
+	return derived, nil
+}
+
+func chunkAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, n uint32) []byte {
+	nonce := make([]byte, noncePrefixSize+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], n)
+	return nonce
+}
+
+func chunkAAD(header []byte, last bool) []byte {
+	aad := append([]byte(nil), header...)
+	if last {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+// encryptStream seals src into dst under k.
+func encryptStream(dst io.Writer, src io.Reader, k *backupKey) error {
+	aead, err := chunkAEAD(k.key)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, 0, headerSize)
+	header = append(header, encryptedMagic...)
+	header = append(header, encryptedVersion)
+	header = binary.BigEndian.AppendUint32(header, k.params.Time)
+	header = binary.BigEndian.AppendUint32(header, k.params.Memory)
+	header = append(header, k.params.Threads)
+	header = append(header, k.salt...)
+	header = append(header, k.check()...)
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	header = append(header, prefix...)
+
+	w := bufio.NewWriter(dst)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	// Read one chunk ahead so the last one can be flagged.
+	r := bufio.NewReaderSize(src, encryptedChunk)
+	buf := make([]byte, encryptedChunk)
+	for n := uint32(0); ; n++ {
+		size, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := err != nil
+		if !last {
+			_, peekErr := r.Peek(1)
+			last = peekErr == io.EOF
+		}
+		sealed := aead.Seal(nil, chunkNonce(prefix, n), buf[:size], chunkAAD(header, last))
+		flag := byte(0)
+		if last {
+			flag = 1
+		}
+		if err := w.WriteByte(flag); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.BigEndian, uint32(len(sealed))); err != nil {
+			return err
+		}
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return w.Flush()
+		}
+	}
+}
+
+// decryptStream opens an archive sealed by encryptStream. The key comes from k or
+// passphrase as in keyFor.
+func decryptStream(dst io.Writer, src io.Reader, k *backupKey, passphrase string) error {
+	r := bufio.NewReader(src)
+	header, err := readEncryptedHeader(r)
+	if err != nil {
+		return err
+	}
+	key, err := header.keyFor(k, passphrase)
+	if err != nil {
+		return err
+	}
+	aead, err := chunkAEAD(key.key)
+	if err != nil {
+		return err
+	}
+
+	for n := uint32(0); ; n++ {
+		var meta [5]byte
+		if _, err := io.ReadFull(r, meta[:]); err != nil {
+			return ErrBackupCorrupted
+		}
+		last := meta[0] == 1
+		size := binary.BigEndian.Uint32(meta[1:])
+		if meta[0] > 1 || size > encryptedChunk+uint32(aead.Overhead()) {
+			return ErrBackupCorrupted
+		}
+		sealed := make([]byte, size)
+		if _, err := io.ReadFull(r, sealed); err != nil {
+			return ErrBackupCorrupted
+		}
+		plain, err := aead.Open(nil, chunkNonce(header.nonce, n), sealed, chunkAAD(header.raw, last))
+		if err != nil {
+			return ErrBackupCorrupted
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			if _, err := r.ReadByte(); err != io.EOF {
+				return ErrBackupCorrupted
+			}
+			return nil
+		}
+	}
+}
+
+// encryptFile seals src into dst, writing beside dst and renaming so an interrupted run
+// never leaves a partial archive under the final name.
+func encryptFile(src, dst string, k *backupKey) error {
+	return transformFile(src, dst, func(w io.Writer, r io.Reader) error {
+		return encryptStream(w, r, k)
+	})
+}
+
+func decryptFile(src, dst string, k *backupKey, passphrase string) error {
+	return transformFile(src, dst, func(w io.Writer, r io.Reader) error {
+		return decryptStream(w, r, k, passphrase)
+	})
+}
+
+func transformFile(src, dst string, transform func(io.Writer, io.Reader) error) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = transform(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
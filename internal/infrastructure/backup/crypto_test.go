@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Full-cost Argon2id makes every derivation take a noticeable fraction of a second.
+	defaultKDF = kdfParams{Time: 1, Memory: 1024, Threads: 1}
+	os.Exit(m.Run())
+}
+
+func TestEncryptStream_RoundTrip(t *testing.T) {
+	key, err := newBackupKey("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, encryptedChunk - 1, encryptedChunk, encryptedChunk + 1, 3*encryptedChunk + 17} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		var sealed bytes.Buffer
+		if err := encryptStream(&sealed, bytes.NewReader(plain), key); err != nil {
+			t.Fatalf("size %d: encrypt: %v", size, err)
+		}
+		if size > 64 && bytes.Contains(sealed.Bytes(), plain[:64]) {
+			t.Fatalf("size %d: plaintext visible in archive", size)
+		}
+
+		var opened bytes.Buffer
+		if err := decryptStream(&opened, bytes.NewReader(sealed.Bytes()), key, ""); err != nil {
+			t.Fatalf("size %d: decrypt with key: %v", size, err)
+		}
+		if !bytes.Equal(opened.Bytes(), plain) {
+			t.Fatalf("size %d: round trip mismatch", size)
+		}
+
+		opened.Reset()
+		if err := decryptStream(&opened, bytes.NewReader(sealed.Bytes()), nil, "correct horse battery"); err != nil {
+			t.Fatalf("size %d: decrypt with passphrase: %v", size, err)
+		}
+		if !bytes.Equal(opened.Bytes(), plain) {
+			t.Fatalf("size %d: passphrase round trip mismatch", size)
+		}
+	}
+}
+
+func TestDecryptStream_RejectsWrongKeyAndTampering(t *testing.T) {
+	key, err := newBackupKey("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := make([]byte, 2*encryptedChunk+100)
+	rand.Read(plain)
+	var sealed bytes.Buffer
+	if err := encryptStream(&sealed, bytes.NewReader(plain), key); err != nil {
+		t.Fatal(err)
+	}
+	archive := sealed.Bytes()
+
+	var out bytes.Buffer
+	if err := decryptStream(&out, bytes.NewReader(archive), nil, "wrong horse battery"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("wrong passphrase: got %v", err)
+	}
+	if err := decryptStream(&out, bytes.NewReader(archive), nil, ""); !errors.Is(err, ErrBackupKeyMissing) {
+		t.Errorf("no key: got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("a rejected key wrote %d bytes", out.Len())
+	}
+
+	flipped := append([]byte(nil), archive...)
+	flipped[headerSize+5+1000] ^= 1
+	if err := decryptStream(&out, bytes.NewReader(flipped), key, ""); !errors.Is(err, ErrBackupCorrupted) {
+		t.Errorf("flipped bit: got %v", err)
+	}
+
+	// Dropping the last chunk leaves a chunk not flagged as last at the end.
+	chunk := 5 + encryptedChunk + 16
+	truncated := archive[:headerSize+2*chunk]
+	if err := decryptStream(&out, bytes.NewReader(truncated), key, ""); !errors.Is(err, ErrBackupCorrupted) {
+		t.Errorf("truncated: got %v", err)
+	}
+
+	// Swapping the first two chunks breaks their nonces.
+	swapped := append([]byte(nil), archive[:headerSize]...)
+	swapped = append(swapped, archive[headerSize+chunk:headerSize+2*chunk]...)
+	swapped = append(swapped, archive[headerSize:headerSize+chunk]...)
+	swapped = append(swapped, archive[headerSize+2*chunk:]...)
+	if err := decryptStream(&out, bytes.NewReader(swapped), key, ""); !errors.Is(err, ErrBackupCorrupted) {
+		t.Errorf("reordered: got %v", err)
+	}
+
+	if err := decryptStream(&out, bytes.NewReader(append(append([]byte(nil), archive...), 0)), key, ""); !errors.Is(err, ErrBackupCorrupted) {
+		t.Errorf("trailing data: got %v", err)
+	}
+}
+
+func TestDecryptStream_RejectsOutOfRangeKDFCost(t *testing.T) {
+	key, err := newBackupKey("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sealed bytes.Buffer
+	if err := encryptStream(&sealed, bytes.NewReader([]byte("backup")), key); err != nil {
+		t.Fatal(err)
+	}
+	archive := sealed.Bytes()
+
+	// time(4) memory(4) threads(1) follow the magic and version.
+	costAt := len(encryptedMagic) + 1
+	for name, params := range map[string]kdfParams{
+		"zero time":      {Time: 0, Memory: 1024, Threads: 1},
+		"zero memory":    {Time: 1, Memory: 0, Threads: 1},
+		"zero threads":   {Time: 1, Memory: 1024, Threads: 0},
+		"huge time":      {Time: math.MaxUint32, Memory: 1024, Threads: 1},
+		"huge memory":    {Time: 1, Memory: math.MaxUint32, Threads: 1},
+		"huge threads":   {Time: 1, Memory: 1024, Threads: math.MaxUint8},
+		"memory/threads": {Time: 1, Memory: 8, Threads: 4},
+	} {
+		crafted := append([]byte(nil), archive...)
+		binary.BigEndian.PutUint32(crafted[costAt:], params.Time)
+		binary.BigEndian.PutUint32(crafted[costAt+4:], params.Memory)
+		crafted[costAt+8] = params.Threads
+		var out bytes.Buffer
+		if err := decryptStream(&out, bytes.NewReader(crafted), nil, "correct horse battery"); !errors.Is(err, ErrBackupCorrupted) {
+			t.Errorf("%s: expected ErrBackupCorrupted, got %v", name, err)
+		}
+	}
+}
+
+func TestNewBackupKey_RequiresLongPassphrase(t *testing.T) {
+	if _, err := newBackupKey("short"); !errors.Is(err, ErrPassphraseTooShort) {
+		t.Fatalf("expected ErrPassphraseTooShort, got %v", err)
+	}
+}
+
+func TestBackupKeyFile_RoundTrip(t *testing.T) {
+	path := t.TempDir() + "/backup.key"
+	if k, err := loadBackupKey(path); k != nil || err != nil {
+		t.Fatalf("missing key file: got %v, %v", k, err)
+	}
+	key, err := newBackupKey("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveBackupKey(path, key); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 && os.PathSeparator == '/' {
+		t.Errorf("key file is readable by others: %v", perm)
+	}
+	loaded, err := loadBackupKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.key, key.key) || !loaded.matches("correct horse battery") || loaded.matches("wrong horse battery") {
+		t.Fatal("loaded key does not match the saved one")
+	}
+
+	if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadBackupKey(path); err == nil {
+		t.Fatal("expected an invalid key file to be rejected")
+	}
+
+	key.params.Memory = math.MaxUint32
+	if err := saveBackupKey(path, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadBackupKey(path); err == nil {
+		t.Fatal("expected a key file with an out-of-range cost to be rejected")
+	}
+}
@@ -0,0 +1,83 @@
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	domainBackup "masala_inventory_managment/internal/domain/backup"
+)
+
+func TestResolveBackupSchedules(t *testing.T) {
+	t.Setenv(EnvSchedules, "working-hours|0 9-18 * * MON-SAT|2; nightly|0 2 * * *")
+	schedules, err := ResolveSchedules()
+	if err != nil {
+		t.Fatalf("ResolveSchedules: %v", err)
+	}
+	if len(schedules) != 2 {
+		t.Fatalf("expected 2 schedules, got %+v", schedules)
+	}
+	if schedules[0].Name != "working-hours" || schedules[0].Cron != "0 9-18 * * MON-SAT" || schedules[0].RetentionDays != 2 {
+		t.Errorf("unexpected first schedule: %+v", schedules[0])
+	}
+	if schedules[1].Name != "nightly" || schedules[1].RetentionDays != 0 {
+		t.Errorf("unexpected second schedule: %+v", schedules[1])
+	}
+
+	for _, raw := range []string{
+		"nightly",
+		"nightly|0 2 * *|30",
+		"nightly|0 2 * * *|0",
+		"Nightly|0 2 * * *|30",
+		"nightly|0 2 * * *;nightly|0 3 * * *",
+	} {
+		t.Setenv(EnvSchedules, raw)
+		if _, err := ResolveSchedules(); err == nil {
+			t.Errorf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestResolveBackupTargets(t *testing.T) {
+	t.Setenv(EnvTargets, "usb|file:///E:/masala|14; nas|smb:///mnt/nas/masala; offsite|sftp://backup:pw@example.com:2222/srv/masala?host_key=SHA256:ab+cd/ef|60")
+	targets, err := ResolveTargets()
+	if err != nil {
+		t.Fatalf("ResolveTargets: %v", err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("expected 3 targets, got %+v", targets)
+	}
+	if usb := targets[0]; usb.Name != "usb" || usb.Kind != domainBackup.TargetLocal || usb.Path != filepath.FromSlash("E:/masala") || usb.RetentionDays != 14 {
+		t.Errorf("unexpected local target: %+v", usb)
+	}
+	if nas := targets[1]; nas.Kind != domainBackup.TargetSMB || nas.Path != filepath.FromSlash("/mnt/nas/masala") || nas.RetentionDays != 0 {
+		t.Errorf("unexpected SMB target: %+v", nas)
+	}
+	offsite := targets[2]
+	if offsite.Kind != domainBackup.TargetSFTP || offsite.Host != "example.com" || offsite.Port != 2222 || offsite.User != "backup" ||
+		offsite.Password != "pw" || offsite.Path != "/srv/masala" || offsite.HostKey != "SHA256:ab+cd/ef" || offsite.RetentionDays != 60 {
+		t.Errorf("unexpected SFTP target: %+v", offsite)
+	}
+
+	for _, raw := range []string{
+		"usb",
+		"usb|ftp://host/backups",
+		"usb|file:///E:/masala|0",
+		"offsite|sftp://backup:pw@example.com/srv",
+		"usb|file:///a;usb|file:///b",
+	} {
+		t.Setenv(EnvTargets, raw)
+		if _, err := ResolveTargets(); err == nil {
+			t.Errorf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestResolveBackupJournalInterval(t *testing.T) {
+	for raw, want := range map[string]time.Duration{"": 0, "30": 30 * time.Second, "0": -1, "soon": 0} {
+		t.Setenv(EnvJournalSeconds, raw)
+		if got := ResolveJournalInterval(); got != want {
+			t.Errorf("%q: expected %v, got %v", raw, want, got)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"masala_inventory_managment/internal/domain/backup"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+// Shipped change journal entries live in the backup folder as segments, one folder per
+// journal generation: journal/<generation>/<first seq>-<last seq>.jsonl, one entry per
+// line, sealed as ".jsonl.enc" once a passphrase is set.
+const (
+	journalFolder          = "journal"
+	segmentExt             = ".jsonl"
+	sealedSegmentExt       = ".jsonl.enc"
+	segmentRows            = 5000
+	defaultJournalInterval = 10 * time.Second
+)
+
+// journalSegment is one shipped file of journal entries.
+type journalSegment struct {
+	path        string
+	first, last int64
+	sealed      bool
+}
+
+// shipJournal moves unshipped change journal entries from the database to the backup
+// folder. Entries are deleted from the database only once their segment is on disk.
+func (s *Service) shipJournal() error {
+	s.journalMu.Lock()
+	defer s.journalMu.Unlock()
+
+	conn := s.dbManager.GetDB()
+	if conn == nil {
+		return nil
+	}
+	for {
+		entries, err := db.ReadJournal(conn, segmentRows)
+		if err != nil {
+			return fmt.Errorf("failed to read change journal: %w", err)
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		for i := range entries {
+			if entries[i].Generation != entries[0].Generation {
+				entries = entries[:i]
+				break
+			}
+		}
+		if err := s.writeSegment(entries); err != nil {
+			return fmt.Errorf("failed to ship change journal: %w", err)
+		}
+		if err := db.TrimJournal(conn, entries[len(entries)-1].Seq); err != nil {
+			return fmt.Errorf("failed to trim shipped change journal: %w", err)
+		}
+		s.mu.Lock()
+		s.journalShippedAt = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+func (s *Service) writeSegment(entries []db.JournalEntry) error {
+	s.mu.Lock()
+	key, keyErr := s.key, s.keyErr
+	s.mu.Unlock()
+	if keyErr != nil {
+		// As with backups, changes are held back rather than written in the clear.
+		return fmt.Errorf("backup encryption key unavailable: %w", keyErr)
+	}
+
+	dir := filepath.Join(s.config.BackupPath, journalFolder, entries[0].Generation)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	stem := filepath.Join(dir, fmt.Sprintf("%020d-%020d", entries[0].Seq, entries[len(entries)-1].Seq))
+	tmp := stem + segmentExt + ".part"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err = encoder.Encode(entry); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if key != nil {
+		err := encryptFile(tmp, stem+sealedSegmentExt, key)
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, stem+segmentExt)
+}
+
+// runJournalShipping ships the change journal every JournalInterval. StopScheduler ships
+// the last changes itself, before the database is closed. A failure is logged when it
+// starts and when it clears, not on every attempt.
+func (s *Service) runJournalShipping(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.config.JournalInterval)
+	defer ticker.Stop()
+	failing := ""
+	for {
+		select {
+		case <-ticker.C:
+			err := s.shipJournal()
+			switch {
+			case err != nil && err.Error() != failing:
+				failing = err.Error()
+				s.logError("Change journal shipping failed: %v", err)
+			case err == nil && failing != "":
+				failing = ""
+				s.logInfo("Change journal shipping resumed")
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// journalSegments lists a generation's shipped segments in sequence order.
+func (s *Service) journalSegments(generation string) ([]journalSegment, error) {
+	dir := filepath.Join(s.config.BackupPath, journalFolder, generation)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var segments []journalSegment
+	for _, f := range files {
+		segment, ok := parseSegmentName(f.Name())
+		if f.IsDir() || !ok {
+			continue
+		}
+		segment.path = filepath.Join(dir, f.Name())
+		segments = append(segments, segment)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].first < segments[j].first })
+	return segments, nil
+}
+
+func parseSegmentName(name string) (journalSegment, bool) {
+	segment := journalSegment{sealed: strings.HasSuffix(name, sealedSegmentExt)}
+	stem := strings.TrimSuffix(name, sealedSegmentExt)
+	if !segment.sealed {
+		if !strings.HasSuffix(name, segmentExt) {
+			return segment, false
+		}
+		stem = strings.TrimSuffix(name, segmentExt)
+	}
+	first, last, ok := strings.Cut(stem, "-")
+	if !ok {
+		return segment, false
+	}
+	var err error
+	if segment.first, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return segment, false
+	}
+	if segment.last, err = strconv.ParseInt(last, 10, 64); err != nil || segment.last < segment.first {
+		return segment, false
+	}
+	return segment, true
+}
+
+// readSegment returns a segment's entries, decrypting it into scratch when it is sealed.
+func (s *Service) readSegment(segment journalSegment, scratch string) ([]db.JournalEntry, error) {
+	path := segment.path
+	if segment.sealed {
+		s.mu.Lock()
+		key := s.key
+		s.mu.Unlock()
+		path = filepath.Join(scratch, filepath.Base(segment.path)+".plain")
+		if err := decryptFile(segment.path, path, key, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(segment.path), err)
+		}
+		defer os.Remove(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []db.JournalEntry
+	decoder := json.NewDecoder(f)
+	for {
+		var entry db.JournalEntry
+		err := decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(segment.path), err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// journalSince collects a generation's entries after seq up to and including at. It
+// stops at the first entry after at, so nothing is replayed out of order, and fails
+// when a segment is missing, since skipping changes would rebuild a database that
+// never existed.
+func (s *Service) journalSince(generation string, seq int64, at time.Time, scratch string) ([]db.JournalEntry, error) {
+	segments, err := s.journalSegments(generation)
+	if err != nil {
+		return nil, err
+	}
+	next := seq + 1
+	var entries []db.JournalEntry
+	for _, segment := range segments {
+		if segment.last < next {
+			continue
+		}
+		if segment.first > next {
+			return nil, fmt.Errorf("change journal is missing changes %d to %d", next, segment.first-1)
+		}
+		read, err := s.readSegment(segment, scratch)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range read {
+			if entry.Seq < next {
+				continue
+			}
+			if entry.ChangedAt.After(at) {
+				return entries, nil
+			}
+			entries = append(entries, entry)
+			next = entry.Seq + 1
+		}
+		next = max(next, segment.last+1)
+	}
+	return entries, nil
+}
+
+// RestoreToTime rebuilds the database as it was at the moment at. The newest backup
+// taken at or before then is restored and the journalled changes made after it, up to
+// at, are replayed onto it. Later changes stay in the backup folder, so a later moment
+// can still be chosen afterwards. The result starts a new journal generation and is
+// backed up straight away, as the backups before it no longer lead to it.
+func (s *Service) RestoreToTime(at time.Time) (*backup.PointInTimeRestore, error) {
+	// The live database may be the reason for the restore, so failing to ship its last
+	// changes does not stop it; those changes just cannot be replayed.
+	if err := s.shipJournal(); err != nil {
+		s.logError("Failed to ship change journal before restoring: %v", err)
+	}
+	result, err := s.restoreToTime(at)
+	if err != nil {
+		return nil, err
+	}
+	s.logInfo("Restored %s and replayed %d changes up to %s", result.Backup, result.Replayed, result.RecoveredTo.Format(time.RFC3339))
+	if err := s.Execute(); err != nil {
+		s.logError("Backup after point-in-time restore failed: %v", err)
+	}
+	return result, nil
+}
+
+func (s *Service) restoreToTime(at time.Time) (*backup.PointInTimeRestore, error) {
+	s.journalMu.Lock()
+	defer s.journalMu.Unlock()
+
+	base, err := s.journalBase(at)
+	if err != nil {
+		return nil, err
+	}
+	scratch, copyPath, manifest, err := s.extractCopy(base.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+	if manifest == nil || manifest.JournalGeneration == "" {
+		return nil, fmt.Errorf("backup %s has no change journal position", base.Name)
+	}
+
+	entries, err := s.journalSince(manifest.JournalGeneration, manifest.JournalSeq, at, scratch)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.ReplayJournal(copyPath, entries); err != nil {
+		return nil, err
+	}
+	if err := s.replaceDatabase(copyPath); err != nil {
+		return nil, err
+	}
+	if err := s.dbManager.RestartChangeJournal(); err != nil {
+		return nil, fmt.Errorf("database restored, but the change journal could not be restarted: %w", err)
+	}
+
+	result := &backup.PointInTimeRestore{Backup: base.Name, Replayed: len(entries), RecoveredTo: manifest.CreatedAt}
+	if len(entries) > 0 {
+		result.RecoveredTo = entries[len(entries)-1].ChangedAt
+	}
+	return result, nil
+}
+
+// journalBase picks the newest backup taken at or before at that the journal can bring
+// forward: one whose manifest places it in a journal generation and that has not
+// failed verification.
+func (s *Service) journalBase(at time.Time) (*backup.BackupInfo, error) {
+	backups, err := s.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	var base *backup.BackupInfo
+	for i := range backups {
+		candidate := &backups[i]
+		if candidate.Manifest == nil || candidate.Manifest.JournalGeneration == "" ||
+			candidate.Verification.Status == backup.VerificationFailed || candidate.Manifest.CreatedAt.After(at) {
+			continue
+		}
+		if base == nil || candidate.Manifest.CreatedAt.After(base.Manifest.CreatedAt) {
+			base = candidate
+		}
+	}
+	if base == nil {
+		return nil, fmt.Errorf("no backup with a change journal was taken before %s", at.Format(time.RFC3339))
+	}
+	return base, nil
+}
+
+// pruneJournal removes shipped changes no retained backup can use: generations, other
+// than the current one, that no backup starts from, and segments wholly before the
+// oldest backup of their generation. It returns the number of files and folders removed.
+func (s *Service) pruneJournal() (int, error) {
+	root := filepath.Join(s.config.BackupPath, journalFolder)
+	generations, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	current := ""
+	if conn := s.dbManager.GetDB(); conn != nil {
+		if current, _, err = db.JournalPosition(conn); err != nil {
+			return 0, err
+		}
+	}
+	s.catalogMu.Lock()
+	catalog := s.loadCatalog()
+	s.catalogMu.Unlock()
+	oldest := map[string]int64{}
+	for _, entry := range catalog {
+		m := entry.Manifest
+		if m == nil || m.JournalGeneration == "" {
+			continue
+		}
+		if seq, ok := oldest[m.JournalGeneration]; !ok || m.JournalSeq < seq {
+			oldest[m.JournalGeneration] = m.JournalSeq
+		}
+	}
+
+	s.journalMu.Lock()
+	defer s.journalMu.Unlock()
+	removed := 0
+	for _, generation := range generations {
+		name := generation.Name()
+		if !generation.IsDir() {
+			continue
+		}
+		seq, used := oldest[name]
+		if !used {
+			if name == current {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(root, name)); err != nil {
+				s.logError("Failed to delete change journal %s: %v", name, err)
+				continue
+			}
+			removed++
+			s.logInfo("Pruned change journal generation %s", name)
+			continue
+		}
+		segments, err := s.journalSegments(name)
+		if err != nil {
+			return removed, err
+		}
+		for _, segment := range segments {
+			if segment.last > seq {
+				continue
+			}
+			if err := os.Remove(segment.path); err != nil {
+				s.logError("Failed to delete change journal segment %s: %v", filepath.Base(segment.path), err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// reencryptJournal seals every shipped segment under newKey, as SetEncryptionPassphrase
+// does for archives. It returns the number of segments rewritten and those it could not.
+func (s *Service) reencryptJournal(oldKey *backupKey, passphrase string, newKey *backupKey) (int, []string) {
+	root := filepath.Join(s.config.BackupPath, journalFolder)
+	generations, err := os.ReadDir(root)
+	if err != nil {
+		return 0, nil
+	}
+	rewritten := 0
+	var failed []string
+	for _, generation := range generations {
+		if !generation.IsDir() {
+			continue
+		}
+		segments, err := s.journalSegments(generation.Name())
+		if err != nil {
+			failed = append(failed, generation.Name())
+			continue
+		}
+		for _, segment := range segments {
+			if err := reencryptSegment(segment, oldKey, passphrase, newKey); err != nil {
+				s.logError("Failed to re-encrypt change journal segment %s: %v", filepath.Base(segment.path), err)
+				failed = append(failed, filepath.Join(journalFolder, generation.Name(), filepath.Base(segment.path)))
+				continue
+			}
+			rewritten++
+		}
+	}
+	return rewritten, failed
+}
+
+func reencryptSegment(segment journalSegment, oldKey *backupKey, passphrase string, newKey *backupKey) error {
+	source := segment.path
+	if segment.sealed {
+		source = segment.path + ".plain"
+		if err := decryptFile(segment.path, source, oldKey, passphrase); err != nil {
+			return err
+		}
+		defer os.Remove(source)
+	}
+	target := strings.TrimSuffix(strings.TrimSuffix(segment.path, sealedSegmentExt), segmentExt) + sealedSegmentExt
+	if err := encryptFile(source, target, newKey); err != nil {
+		return err
+	}
+	if source == segment.path {
+		return os.Remove(segment.path)
+	}
+	return nil
+}
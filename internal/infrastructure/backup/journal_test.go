@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+func TestRestoreToTime_ReplaysShippedChangesUpToTheMoment(t *testing.T) {
+	svc, manager := newMigratedFixture(t)
+	repo := db.NewSqliteInventoryRepository(manager.GetDB())
+	createParty := func(name string) {
+		t.Helper()
+		if err := repo.CreateParty(&domainInventory.Party{PartyType: domainInventory.PartyTypeSupplier, Name: name, Phone: "0484-2370001", IsActive: true}); err != nil {
+			t.Fatalf("CreateParty failed: %v", err)
+		}
+	}
+
+	createParty("Kerala Spice Traders")
+	if err := svc.shipJournal(); err != nil {
+		t.Fatalf("shipJournal failed: %v", err)
+	}
+	journalDir := filepath.Join(svc.config.BackupPath, journalFolder)
+	if segments, _ := filepath.Glob(filepath.Join(journalDir, "*", "*"+segmentExt)); len(segments) != 1 {
+		t.Fatalf("expected one shipped segment, got %v", segments)
+	}
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(journalDir, "*", "*")); len(segments) != 0 {
+		t.Errorf("expected the segment inside the backup to be pruned, got %v", segments)
+	}
+	// The backup taken after the restore must not share the base's name.
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+	createParty("Erode Turmeric Co")
+	if err := svc.shipJournal(); err != nil {
+		t.Fatalf("shipJournal failed: %v", err)
+	}
+	// Segments shipped before a passphrase is set are sealed along with the archives.
+	if _, err := svc.SetEncryptionPassphrase("", "correct horse battery staple"); err != nil {
+		t.Fatalf("SetEncryptionPassphrase failed: %v", err)
+	}
+	if plain, _ := filepath.Glob(filepath.Join(journalDir, "*", "*"+segmentExt)); len(plain) != 0 {
+		t.Errorf("expected no plain segments left, got %v", plain)
+	}
+	time.Sleep(20 * time.Millisecond)
+	moment := time.Now()
+	time.Sleep(20 * time.Millisecond)
+	createParty("Guntur Chilli House")
+	if status, _ := svc.GetStatus(); status.JournalShippedAt.IsZero() {
+		t.Error("expected the last shipment in the status")
+	}
+
+	// RestoreToTime ships the last change itself before restoring.
+	result, err := svc.RestoreToTime(moment)
+	if err != nil {
+		t.Fatalf("RestoreToTime failed: %v", err)
+	}
+	if result.Replayed == 0 || result.RecoveredTo.After(moment) || !strings.HasPrefix(result.Backup, "backup-") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	// The restore reconnects, so the old handle is closed.
+	repo = db.NewSqliteInventoryRepository(manager.GetDB())
+	names := map[string]bool{}
+	rows, err := manager.GetDB().Query("SELECT name FROM parties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names[name] = true
+	}
+	rows.Close()
+	if !names["Kerala Spice Traders"] || !names["Erode Turmeric Co"] || names["Guntur Chilli House"] || len(names) != 2 {
+		t.Errorf("expected the parties as they were at the moment, got %v", names)
+	}
+	if err := manager.VerifyHashChains(); err != nil {
+		t.Errorf("restore broke the hash chain: %v", err)
+	}
+	generations, _ := os.ReadDir(journalDir)
+	if len(generations) != 1 {
+		t.Errorf("expected the changes after the moment to stay in the old generation, got %d generations", len(generations))
+	}
+
+	// The restored database journals into a new generation.
+	createParty("Salem Masala Mills")
+	entries, err := db.ReadJournal(manager.GetDB(), 10)
+	if err != nil || len(entries) == 0 || entries[0].Generation == generations[0].Name() {
+		t.Errorf("expected new changes in a new generation: %+v, %v", entries, err)
+	}
+}
+
+func TestRestoreToTime_RefusesGapsInTheJournal(t *testing.T) {
+	svc, manager := newMigratedFixture(t)
+	repo := db.NewSqliteInventoryRepository(manager.GetDB())
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	for _, name := range []string{"Kerala Spice Traders", "Erode Turmeric Co"} {
+		if err := repo.CreateParty(&domainInventory.Party{PartyType: domainInventory.PartyTypeSupplier, Name: name, Phone: "0484-2370001", IsActive: true}); err != nil {
+			t.Fatalf("CreateParty failed: %v", err)
+		}
+		if err := svc.shipJournal(); err != nil {
+			t.Fatalf("shipJournal failed: %v", err)
+		}
+	}
+	segments, _ := filepath.Glob(filepath.Join(svc.config.BackupPath, journalFolder, "*", "*"+segmentExt))
+	if len(segments) != 2 {
+		t.Fatalf("expected two segments, got %v", segments)
+	}
+	if err := os.Remove(segments[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := svc.RestoreToTime(time.Now())
+	if err == nil || !strings.Contains(err.Error(), "missing changes") {
+		t.Fatalf("expected the missing segment to stop the restore, got %v", err)
+	}
+	var parties int
+	if err := manager.GetDB().QueryRow("SELECT COUNT(*) FROM parties").Scan(&parties); err != nil || parties != 2 {
+		t.Errorf("expected the live database untouched: %d, %v", parties, err)
+	}
+
+	if _, err := svc.RestoreToTime(time.Now().Add(-time.Hour)); err == nil {
+		t.Error("expected no backup before the moment to be an error")
+	}
+}
@@ -0,0 +1,392 @@
+package backup
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"masala_inventory_managment/internal/domain/backup"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+const (
+	manifestEntry   = "manifest.json"
+	databaseEntry   = "masala_inventory.db"
+	manifestVersion = 1
+
+	// catalogFile keeps each archive's manifest and last verification, so encrypted
+	// archives can be listed without decrypting them. It holds no database contents.
+	catalogFile = "catalog.json"
+)
+
+// buildManifest describes the database copy at path.
+func buildManifest(path string, createdAt time.Time, schedule, appVersion, hardwareID string) (*backup.Manifest, error) {
+	sum, size, err := fileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := openReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	manifest := &backup.Manifest{
+		FormatVersion:  manifestVersion,
+		CreatedAt:      createdAt.UTC(),
+		Schedule:       schedule,
+		AppVersion:     appVersion,
+		HardwareID:     hardwareID,
+		DatabaseSHA256: sum,
+		DatabaseSize:   size,
+	}
+	manifest.SchemaVersion, manifest.SchemaDirty, err = schemaVersion(conn)
+	if err != nil {
+		return nil, err
+	}
+	manifest.TableCounts, err = tableCounts(conn)
+	if err != nil {
+		return nil, err
+	}
+	manifest.JournalGeneration, manifest.JournalSeq, err = db.JournalPosition(conn)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func openReadOnly(path string) (*sql.DB, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sql.Open("sqlite3", "file:"+filepath.ToSlash(abs)+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	conn.SetMaxOpenConns(1)
+	return conn, nil
+}
+
+func fileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// schemaVersion reads golang-migrate's schema_migrations table. A database without it
+// reports version 0.
+func schemaVersion(conn *sql.DB) (uint, bool, error) {
+	var exists int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists); err != nil {
+		return 0, false, err
+	}
+	if exists == 0 {
+		return 0, false, nil
+	}
+	var version int64
+	var dirty bool
+	err := conn.QueryRow("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
+
+func tableCounts(conn *sql.DB) (map[string]int64, error) {
+	rows, err := conn.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		var n int64
+		if err := conn.QueryRow(`SELECT COUNT(*) FROM "` + strings.ReplaceAll(table, `"`, `""`) + `"`).Scan(&n); err != nil {
+			return nil, fmt.Errorf("count %s: %w", table, err)
+		}
+		counts[table] = n
+	}
+	return counts, nil
+}
+
+// readManifest returns the manifest in an open archive, or nil for archives made before
+// manifests were written.
+func readManifest(r *zip.Reader) (*backup.Manifest, error) {
+	for _, f := range r.File {
+		if f.Name != manifestEntry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		var manifest backup.Manifest
+		if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("manifest is invalid: %w", err)
+		}
+		return &manifest, nil
+	}
+	return nil, nil
+}
+
+// checkDatabase compares an extracted database with its manifest and runs SQLite's
+// integrity check. It returns why the copy cannot be trusted, or "" when it can.
+func checkDatabase(path string, manifest *backup.Manifest) (string, error) {
+	if manifest != nil {
+		sum, size, err := fileSHA256(path)
+		if err != nil {
+			return "", err
+		}
+		if sum != manifest.DatabaseSHA256 || size != manifest.DatabaseSize {
+			return "database checksum does not match the manifest", nil
+		}
+	}
+
+	conn, err := openReadOnly(path)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	var result string
+	if err := conn.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Sprintf("integrity check failed: %v", err), nil
+	}
+	if result != "ok" {
+		return "integrity check failed: " + result, nil
+	}
+	if manifest == nil {
+		return "", nil
+	}
+
+	version, dirty, err := schemaVersion(conn)
+	if err != nil {
+		return fmt.Sprintf("schema version unreadable: %v", err), nil
+	}
+	if version != manifest.SchemaVersion || dirty != manifest.SchemaDirty {
+		return fmt.Sprintf("schema version %d does not match the manifest's %d", version, manifest.SchemaVersion), nil
+	}
+	counts, err := tableCounts(conn)
+	if err != nil {
+		return fmt.Sprintf("row counts unreadable: %v", err), nil
+	}
+	var mismatched []string
+	for table, want := range manifest.TableCounts {
+		if got, ok := counts[table]; !ok || got != want {
+			mismatched = append(mismatched, fmt.Sprintf("%s (%d, manifest %d)", table, got, want))
+		}
+	}
+	for table := range counts {
+		if _, ok := manifest.TableCounts[table]; !ok {
+			mismatched = append(mismatched, table+" (not in manifest)")
+		}
+	}
+	if len(mismatched) > 0 {
+		sort.Strings(mismatched)
+		return "row counts differ: " + strings.Join(mismatched, ", "), nil
+	}
+	return "", nil
+}
+
+type catalogEntry struct {
+	Manifest     *backup.Manifest    `json:"manifest,omitempty"`
+	Verification backup.Verification `json:"verification"`
+}
+
+// catalogKey names an archive independently of encryption, so entries survive
+// re-encryption.
+func catalogKey(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, encryptedExt), archiveExt)
+}
+
+func (s *Service) loadCatalog() map[string]catalogEntry {
+	catalog := map[string]catalogEntry{}
+	data, err := os.ReadFile(filepath.Join(s.config.BackupPath, catalogFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.logError("Failed to read backup catalog: %v", err)
+		}
+		return catalog
+	}
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		// The catalog only caches what the archives hold; start again.
+		s.logError("Backup catalog is invalid and will be rebuilt: %v", err)
+		return map[string]catalogEntry{}
+	}
+	return catalog
+}
+
+// updateCatalog applies update to the catalog and writes it back when update reports a
+// change.
+func (s *Service) updateCatalog(update func(map[string]catalogEntry) bool) {
+	s.catalogMu.Lock()
+	defer s.catalogMu.Unlock()
+
+	catalog := s.loadCatalog()
+	if !update(catalog) {
+		return
+	}
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err == nil {
+		path := filepath.Join(s.config.BackupPath, catalogFile)
+		if err = os.WriteFile(path+".tmp", data, 0600); err == nil {
+			err = os.Rename(path+".tmp", path)
+		}
+	}
+	if err != nil {
+		s.logError("Failed to write backup catalog: %v", err)
+	}
+}
+
+// Verify test-restores an archive: it is decrypted and unzipped to a scratch folder
+// beside the key file, outside the backup folder, its checksum and row counts are
+// compared with the manifest, and SQLite's integrity check is run. The outcome is
+// recorded for ListBackups. An error means the archive could not be found.
+func (s *Service) Verify(backupPath string) (*backup.Verification, error) {
+	absPath, err := s.resolveBackupPath(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return nil, fmt.Errorf("backup not found: %s", filepath.Base(absPath))
+	}
+
+	s.verifyMu.Lock()
+	defer s.verifyMu.Unlock()
+
+	manifest, problem, err := s.testRestore(absPath)
+	result := backup.Verification{Status: backup.VerificationPassed, CheckedAt: time.Now().UTC()}
+	switch {
+	case err != nil:
+		result.Status, result.Message = backup.VerificationFailed, err.Error()
+	case problem != "":
+		result.Status, result.Message = backup.VerificationFailed, problem
+	case manifest == nil:
+		result.Message = "archive has no manifest; only the integrity check was run"
+	}
+
+	name := filepath.Base(absPath)
+	s.updateCatalog(func(catalog map[string]catalogEntry) bool {
+		entry := catalog[catalogKey(name)]
+		if manifest != nil {
+			entry.Manifest = manifest
+		}
+		entry.Verification = result
+		catalog[catalogKey(name)] = entry
+		return true
+	})
+	if result.Status == backup.VerificationFailed {
+		s.logError("Backup %s failed verification: %s", name, result.Message)
+	} else {
+		s.logInfo("Backup %s verified", name)
+	}
+	return &result, nil
+}
+
+func (s *Service) testRestore(absPath string) (*backup.Manifest, string, error) {
+	scratch, err := os.MkdirTemp(filepath.Dir(s.config.KeyPath), ".verify-*")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(scratch)
+
+	manifest, dbPath, err := s.extractArchive(absPath, scratch)
+	if err != nil {
+		return manifest, "", err
+	}
+	problem, err := checkDatabase(dbPath, manifest)
+	return manifest, problem, err
+}
+
+// openArchive returns a path to the plain zip of an archive, decrypting encrypted ones
+// to decryptedPath.
+func (s *Service) openArchive(absPath, decryptedPath, passphrase string) (string, error) {
+	encrypted, err := isEncryptedArchive(absPath)
+	if err != nil {
+		return "", err
+	}
+	if !encrypted {
+		return absPath, nil
+	}
+	if err := decryptFile(absPath, decryptedPath, s.key, passphrase); err != nil {
+		return "", err
+	}
+	return decryptedPath, nil
+}
+
+func extractDatabase(r *zip.Reader, dst string) error {
+	for _, f := range r.File {
+		if filepath.Base(f.Name) != databaseEntry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, rc); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	}
+	return fmt.Errorf("backup does not contain %s", databaseEntry)
+}
+
+// verifyPending verifies every archive not yet verified, and the newest one again so a
+// fault in the latest backup is noticed even when it has already passed.
+func (s *Service) verifyPending() {
+	backups, err := s.ListBackups()
+	if err != nil {
+		s.logError("Scheduled verification could not list backups: %v", err)
+		return
+	}
+	for i, info := range backups {
+		if i > 0 && info.Verification.Status != backup.VerificationUnverified {
+			continue
+		}
+		if _, err := s.Verify(info.Path); err != nil {
+			s.logError("Scheduled verification of %s failed: %v", info.Name, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"masala_inventory_managment/internal/domain/backup"
+)
+
+func newVerifyFixture(t *testing.T) (*Service, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	dbDir := filepath.Join(tmpDir, "db")
+	if err := os.MkdirAll(dbDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manager := createDummyDB(t, dbDir)
+	t.Cleanup(func() { manager.Close() })
+	if _, err := manager.GetDB().Exec("CREATE TABLE schema_migrations (version uint64, dirty bool); INSERT INTO schema_migrations VALUES (20, 0)"); err != nil {
+		t.Fatal(err)
+	}
+
+	backupDir := filepath.Join(tmpDir, "backups")
+	svc := NewService(manager, backup.BackupConfig{
+		BackupPath: backupDir,
+		KeyPath:    filepath.Join(dbDir, "backup.key"),
+		AppVersion: "1.4.0",
+		HardwareID: "bios:disk",
+	}, noOpLog, noOpLog)
+	return svc, backupDir
+}
+
+func TestExecute_WritesManifestAndVerifies(t *testing.T) {
+	svc, _ := newVerifyFixture(t)
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	backups, err := svc.ListBackups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("ListBackups: %+v, %v", backups, err)
+	}
+	info := backups[0]
+	if info.Verification.Status != backup.VerificationUnverified || info.Size == 0 || info.CreatedAt.IsZero() {
+		t.Errorf("unexpected listing: %+v", info)
+	}
+	m := info.Manifest
+	if m == nil {
+		t.Fatal("expected the manifest in the listing")
+	}
+	if m.SchemaVersion != 20 || m.SchemaDirty || m.AppVersion != "1.4.0" || m.HardwareID != "bios:disk" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+	if m.TableCounts["test"] != 1 || m.TableCounts["schema_migrations"] != 1 {
+		t.Errorf("unexpected table counts: %v", m.TableCounts)
+	}
+
+	result, err := svc.Verify(info.Name)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Status != backup.VerificationPassed || result.CheckedAt.IsZero() {
+		t.Fatalf("expected verification to pass, got %+v", result)
+	}
+	backups, _ = svc.ListBackups()
+	if backups[0].Verification.Status != backup.VerificationPassed {
+		t.Errorf("verification was not recorded: %+v", backups[0].Verification)
+	}
+}
+
+func TestVerify_DetectsMismatchedArchive(t *testing.T) {
+	svc, backupDir := newVerifyFixture(t)
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	backups, _ := svc.ListBackups()
+	good := backups[0]
+
+	// Same database, but a manifest that claims another row.
+	manifest := *good.Manifest
+	manifest.TableCounts = map[string]int64{"test": 2, "schema_migrations": 1}
+	tampered := filepath.Join(backupDir, "backup-2020-01-01T000000.zip")
+	rewriteArchive(t, good.Path, tampered, &manifest)
+
+	result, err := svc.Verify(tampered)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Status != backup.VerificationFailed || !strings.Contains(result.Message, "row counts differ: test (1, manifest 2)") {
+		t.Fatalf("expected a row count failure, got %+v", result)
+	}
+
+	manifest = *good.Manifest
+	manifest.DatabaseSHA256 = strings.Repeat("0", 64)
+	rewriteArchive(t, good.Path, tampered, &manifest)
+	result, _ = svc.Verify(tampered)
+	if result.Status != backup.VerificationFailed || !strings.Contains(result.Message, "checksum") {
+		t.Fatalf("expected a checksum failure, got %+v", result)
+	}
+	if err := svc.Restore(tampered); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected restore to refuse a mismatched archive, got %v", err)
+	}
+
+	if _, err := svc.Verify("backup-1999-01-01T000000.zip"); err == nil {
+		t.Fatal("expected an error for a missing archive")
+	}
+}
+
+func TestVerify_EncryptedArchiveKeepsCatalogAcrossReencryption(t *testing.T) {
+	svc, _ := newVerifyFixture(t)
+	if _, err := svc.SetEncryptionPassphrase("", "first passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	backups, _ := svc.ListBackups()
+	if !backups[0].Encrypted || backups[0].Manifest == nil {
+		t.Fatalf("expected an encrypted archive listed with its manifest, got %+v", backups[0])
+	}
+	if result, err := svc.Verify(backups[0].Path); err != nil || result.Status != backup.VerificationPassed {
+		t.Fatalf("Verify: %+v, %v", result, err)
+	}
+
+	if _, err := svc.SetEncryptionPassphrase("first passphrase", "second passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	backups, _ = svc.ListBackups()
+	if backups[0].Manifest == nil || backups[0].Verification.Status != backup.VerificationPassed {
+		t.Errorf("catalog entry lost on re-encryption: %+v", backups[0])
+	}
+}
+
+func TestVerifyPending_ChecksUnverifiedAndNewest(t *testing.T) {
+	svc, _ := newVerifyFixture(t)
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			time.Sleep(1100 * time.Millisecond) // backup names have one-second resolution
+		}
+		if err := svc.Execute(); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	backups, _ := svc.ListBackups()
+	old := backups[2]
+	first, err := svc.Verify(old.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc.verifyPending()
+	backups, _ = svc.ListBackups()
+	for _, info := range backups {
+		if info.Verification.Status != backup.VerificationPassed {
+			t.Errorf("%s not verified: %+v", info.Name, info.Verification)
+		}
+	}
+	if !backups[2].Verification.CheckedAt.Equal(first.CheckedAt) {
+		t.Errorf("an already verified older archive was checked again")
+	}
+}
+
+func TestPrune_RemovesCatalogEntries(t *testing.T) {
+	svc, backupDir := newVerifyFixture(t)
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	backups, _ := svc.ListBackups()
+	old := "backup-" + time.Now().AddDate(0, 0, -30).Format(backupTimestampLayout) + ".zip"
+	if err := os.Rename(backups[0].Path, filepath.Join(backupDir, old)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Verify(old); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := svc.Prune(); err != nil || n != 1 {
+		t.Fatalf("Prune: %d, %v", n, err)
+	}
+	data, err := os.ReadFile(filepath.Join(backupDir, catalogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var catalog map[string]catalogEntry
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := catalog[catalogKey(old)]; ok {
+		t.Errorf("catalog still lists the pruned archive")
+	}
+}
+
+// rewriteArchive copies the database from src into a new archive at dst with manifest.
+func rewriteArchive(t *testing.T, src, dst string, manifest *backup.Manifest) {
+	t.Helper()
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	dbCopy := filepath.Join(t.TempDir(), databaseEntry)
+	if err := extractDatabase(&r.Reader, dbCopy); err != nil {
+		t.Fatal(err)
+	}
+	svc := &Service{}
+	if err := svc.zipFile(dbCopy, dst, manifest); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,192 @@
+package backup
+
+import (
+	"archive/zip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"masala_inventory_managment/internal/domain/backup"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+// PreviewRestore opens a copy of an archive read-only and compares it with the live
+// database: row counts per table, the newest GRN and stock movement in each, and the
+// parties, items, recipes and packaging profiles that differ. Nothing live is changed.
+func (s *Service) PreviewRestore(backupPath string) (*backup.RestorePreview, error) {
+	scratch, copyPath, manifest, err := s.extractCopy(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+
+	conn, err := openReadOnly(copyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	live := s.dbManager.GetDB()
+
+	preview := &backup.RestorePreview{Name: filepath.Base(backupPath), Manifest: manifest}
+	backupCounts, err := tableCounts(conn)
+	if err != nil {
+		return nil, fmt.Errorf("backup row counts: %w", err)
+	}
+	liveCounts, err := tableCounts(live)
+	if err != nil {
+		return nil, fmt.Errorf("live row counts: %w", err)
+	}
+	preview.Tables = compareCounts(backupCounts, liveCounts)
+
+	if preview.BackupNewestGRN, err = newestCreated(conn, "grns"); err != nil {
+		return nil, err
+	}
+	if preview.LiveNewestGRN, err = newestCreated(live, "grns"); err != nil {
+		return nil, err
+	}
+	if preview.BackupNewestMovement, err = newestCreated(conn, "stock_ledger"); err != nil {
+		return nil, err
+	}
+	if preview.LiveNewestMovement, err = newestCreated(live, "stock_ledger"); err != nil {
+		return nil, err
+	}
+
+	if preview.Entities, err = db.DiffEntities(conn, live); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// RestoreEntities copies the chosen records from an archive into the live database,
+// leaving the rest of it as it is. The archive must be at the live schema version, so
+// its rows fit the live tables.
+func (s *Service) RestoreEntities(backupPath string, entities []backup.EntityRef, actor string) error {
+	scratch, copyPath, manifest, err := s.extractCopy(backupPath)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+
+	live := s.dbManager.GetDB()
+	liveVersion, _, err := schemaVersion(live)
+	if err != nil {
+		return err
+	}
+	backupVersion := uint(0)
+	if manifest != nil {
+		backupVersion = manifest.SchemaVersion
+	} else {
+		conn, err := openReadOnly(copyPath)
+		if err != nil {
+			return err
+		}
+		backupVersion, _, err = schemaVersion(conn)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if backupVersion != liveVersion {
+		return fmt.Errorf("backup is at schema version %d but the database is at %d; restore it in full instead", backupVersion, liveVersion)
+	}
+
+	if err := db.RestoreEntities(live, copyPath, entities, actor); err != nil {
+		return err
+	}
+	s.logInfo("Restored %d records from %s", len(entities), filepath.Base(backupPath))
+	return nil
+}
+
+// extractCopy decrypts and unzips an archive's database into a new scratch folder beside
+// the key file, checking it against the manifest. The caller removes the folder.
+func (s *Service) extractCopy(backupPath string) (string, string, *backup.Manifest, error) {
+	absPath, err := s.resolveBackupPath(backupPath)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return "", "", nil, fmt.Errorf("backup not found: %s", filepath.Base(absPath))
+	}
+	scratch, err := os.MkdirTemp(filepath.Dir(s.config.KeyPath), ".restore-*")
+	if err != nil {
+		return "", "", nil, err
+	}
+	manifest, copyPath, err := s.extractArchive(absPath, scratch)
+	if err != nil {
+		os.RemoveAll(scratch)
+		return "", "", nil, err
+	}
+	if manifest != nil {
+		sum, _, err := fileSHA256(copyPath)
+		if err != nil {
+			os.RemoveAll(scratch)
+			return "", "", nil, err
+		}
+		if sum != manifest.DatabaseSHA256 {
+			os.RemoveAll(scratch)
+			return "", "", nil, fmt.Errorf("backup database checksum does not match its manifest")
+		}
+	}
+	return scratch, copyPath, manifest, nil
+}
+
+// extractArchive decrypts an archive when needed and writes its database to scratch,
+// returning the manifest, if any, and the database's path.
+func (s *Service) extractArchive(absPath, scratch string) (*backup.Manifest, string, error) {
+	archivePath, err := s.openArchive(absPath, filepath.Join(scratch, "archive.zip"), "")
+	if err != nil {
+		return nil, "", err
+	}
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+
+	manifest, err := readManifest(&r.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	dbPath := filepath.Join(scratch, databaseEntry)
+	if err := extractDatabase(&r.Reader, dbPath); err != nil {
+		return manifest, "", err
+	}
+	return manifest, dbPath, nil
+}
+
+func compareCounts(backupCounts, liveCounts map[string]int64) []backup.TableCount {
+	tables := make([]backup.TableCount, 0, len(backupCounts))
+	for table, n := range backupCounts {
+		tables = append(tables, backup.TableCount{Table: table, Backup: n, Live: liveCounts[table]})
+	}
+	for table, n := range liveCounts {
+		if _, ok := backupCounts[table]; !ok {
+			tables = append(tables, backup.TableCount{Table: table, Live: n})
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Table < tables[j].Table })
+	return tables
+}
+
+// newestCreated returns the latest created_at in table, or the zero time when the table
+// is empty or missing.
+func newestCreated(conn *sql.DB, table string) (time.Time, error) {
+	var exists int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&exists); err != nil || exists == 0 {
+		return time.Time{}, err
+	}
+	// Ordering rather than MAX keeps the column's type, so the driver returns a time.
+	var newest sql.NullTime
+	err := conn.QueryRow("SELECT created_at FROM " + table + " WHERE created_at IS NOT NULL ORDER BY created_at DESC LIMIT 1").Scan(&newest)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("newest %s: %w", table, err)
+	}
+	return newest.Time, nil
+}
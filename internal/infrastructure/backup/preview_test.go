@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"masala_inventory_managment"
+	"masala_inventory_managment/internal/domain/backup"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+// newMigratedFixture is a backup service over a database with the application schema.
+func newMigratedFixture(t *testing.T) (*Service, *db.DatabaseManager) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	manager := db.NewDatabaseManager(filepath.Join(tmpDir, "masala_inventory.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	if err := db.NewMigrator(manager).RunMigrations(masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	svc := NewService(manager, backup.BackupConfig{
+		BackupPath: filepath.Join(tmpDir, "backups"),
+		KeyPath:    filepath.Join(tmpDir, "backup.key"),
+	}, noOpLog, noOpLog)
+	return svc, manager
+}
+
+func TestPreviewRestore_ComparesWithLiveAndRestoresChosenParty(t *testing.T) {
+	svc, manager := newMigratedFixture(t)
+	repo := db.NewSqliteInventoryRepository(manager.GetDB())
+	supplier := &domainInventory.Party{PartyType: domainInventory.PartyTypeSupplier, Name: "Erode Turmeric Co", Phone: "0424-2255001", IsActive: true}
+	if err := repo.CreateParty(supplier); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	backups, err := svc.ListBackups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("ListBackups: %+v, %v", backups, err)
+	}
+	name := backups[0].Name
+
+	if _, err := manager.GetDB().Exec("DELETE FROM parties WHERE id = ?", supplier.ID); err != nil {
+		t.Fatal(err)
+	}
+	item := &domainInventory.Item{SKU: "RAW-TUR-1", Name: "Turmeric Fingers", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true}
+	if err := repo.CreateItem(item); err != nil {
+		t.Fatalf("CreateItem failed: %v", err)
+	}
+
+	preview, err := svc.PreviewRestore(name)
+	if err != nil {
+		t.Fatalf("PreviewRestore failed: %v", err)
+	}
+	if preview.Manifest == nil || preview.Name != name {
+		t.Errorf("expected the manifest in the preview: %+v", preview)
+	}
+	counts := map[string]backup.TableCount{}
+	for _, table := range preview.Tables {
+		counts[table.Table] = table
+	}
+	if c := counts["parties"]; c.Backup != 1 || c.Live != 0 {
+		t.Errorf("unexpected parties count: %+v", c)
+	}
+	if c := counts["items"]; c.Backup != 0 || c.Live != 1 {
+		t.Errorf("unexpected items count: %+v", c)
+	}
+	if !preview.BackupNewestGRN.IsZero() || !preview.LiveNewestGRN.IsZero() {
+		t.Errorf("expected no GRN dates without GRNs: %+v", preview)
+	}
+	changes := map[string]string{}
+	for _, diff := range preview.Entities {
+		changes[diff.Kind+":"+diff.Label] = diff.Change
+	}
+	if changes["party:Erode Turmeric Co"] != backup.EntityDeleted || changes["item:RAW-TUR-1 Turmeric Fingers"] != backup.EntityAdded || len(changes) != 2 {
+		t.Errorf("unexpected record differences: %v", changes)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(svc.config.KeyPath), ".restore-*")); len(leftovers) > 0 {
+		t.Errorf("preview left scratch files behind: %v", leftovers)
+	}
+
+	if err := svc.RestoreEntities(name, []backup.EntityRef{{Kind: backup.EntityParty, ID: supplier.ID}}, "admin"); err != nil {
+		t.Fatalf("RestoreEntities failed: %v", err)
+	}
+	var restored string
+	if err := manager.GetDB().QueryRow("SELECT name FROM parties WHERE id = ?", supplier.ID).Scan(&restored); err != nil || restored != supplier.Name {
+		t.Errorf("supplier not restored: %q, %v", restored, err)
+	}
+	var items int
+	if err := manager.GetDB().QueryRow("SELECT COUNT(*) FROM items").Scan(&items); err != nil || items != 1 {
+		t.Errorf("the item added after the backup was lost: %d, %v", items, err)
+	}
+}
+
+func TestRestoreEntities_RejectsOtherSchemaVersion(t *testing.T) {
+	svc, manager := newMigratedFixture(t)
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	backups, _ := svc.ListBackups()
+	if _, err := manager.GetDB().Exec("UPDATE schema_migrations SET version = version + 1"); err != nil {
+		t.Fatal(err)
+	}
+	err := svc.RestoreEntities(backups[0].Name, []backup.EntityRef{{Kind: backup.EntityParty, ID: 1}}, "admin")
+	if err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Errorf("expected a schema version mismatch, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package backup
 
 import (
 	"archive/zip"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -15,12 +16,39 @@ import (
 	"masala_inventory_managment/internal/infrastructure/db"
 )
 
+// backupTimestampLayout is the timestamp in backup file names. Scheduled backups append
+// "-<schedule>" after it, which Prune uses to apply that schedule's retention.
+const backupTimestampLayout = "2006-01-02T150405"
+
+// Backup archives are zips, sealed as ".zip.enc" once a passphrase is set.
+const (
+	archiveExt   = ".zip"
+	encryptedExt = ".zip.enc"
+)
+
+// scheduled is a configured schedule with its parsed expression.
+type scheduled struct {
+	backup.Schedule
+	cron *CronSchedule
+}
+
 // Service implements backup.BackupService
 type Service struct {
 	dbManager        *db.DatabaseManager
 	config           backup.BackupConfig
+	schedules        []scheduled
+	verifyCron       *CronSchedule
+	key              *backupKey
+	keyErr           error
 	status           backup.BackupStatus
 	mu               sync.Mutex
+	catalogMu        sync.Mutex
+	verifyMu         sync.Mutex
+	replicas         []*replica
+	replicateMu      sync.Mutex
+	replicateNow     chan struct{}
+	journalMu        sync.Mutex
+	journalShippedAt time.Time
 	running          bool
 	schedulerRunning bool
 	stopChan         chan struct{}
@@ -43,16 +71,88 @@ func NewService(
 		config.RetentionDays = 7
 	}
 	if config.ScheduleCron == "" {
-		config.ScheduleCron = "0 2 * * *" // Note: we are using simple ticker for now, this is placeholder
+		config.ScheduleCron = "0 2 * * *"
+	}
+	if config.KeyPath == "" {
+		config.KeyPath = "backup.key"
+	}
+	if config.VerifyCron == "" {
+		config.VerifyCron = "30 3 * * *"
+	}
+	if config.JournalInterval == 0 {
+		config.JournalInterval = defaultJournalInterval
+	}
+	if len(config.Schedules) == 0 {
+		config.Schedules = []backup.Schedule{{Name: "nightly", Cron: config.ScheduleCron, RetentionDays: config.RetentionDays}}
+	}
+
+	s := &Service{
+		dbManager:    dbManager,
+		config:       config,
+		replicateNow: make(chan struct{}, 1),
+		logInfo:      logInfo,
+		logError:     logError,
+	}
+	for _, schedule := range config.Schedules {
+		parsed, err := ParseSchedule(schedule)
+		if err != nil {
+			// Callers validate schedules up front with ParseSchedule; one that slips through
+			// is left out rather than stopping the other schedules.
+			logError("Ignoring backup schedule: %v", err)
+			continue
+		}
+		if schedule.RetentionDays <= 0 {
+			schedule.RetentionDays = config.RetentionDays
+		}
+		s.schedules = append(s.schedules, scheduled{Schedule: schedule, cron: parsed})
+	}
+	for _, target := range config.Targets {
+		if target.RetentionDays <= 0 {
+			target.RetentionDays = config.RetentionDays
+		}
+		opened, err := NewTarget(target)
+		if err != nil {
+			logError("Ignoring backup target: %v", err)
+			continue
+		}
+		s.replicas = append(s.replicas, &replica{
+			config: target,
+			target: opened,
+			status: backup.TargetStatus{Name: target.Name, Kind: target.Kind, RetentionDays: target.RetentionDays},
+		})
+	}
+	if parsed, err := ParseCron(config.VerifyCron); err != nil {
+		logError("Ignoring backup verification schedule: %v", err)
+	} else {
+		s.verifyCron = parsed
+	}
+	s.key, s.keyErr = loadBackupKey(config.KeyPath)
+	if s.keyErr != nil {
+		// Backups stop rather than silently falling back to plain archives.
+		logError("Backup encryption key unavailable: %v", s.keyErr)
 	}
+	return s
+}
 
-	return &Service{
-		dbManager: dbManager,
-		config:    config,
-		stopChan:  make(chan struct{}),
-		logInfo:   logInfo,
-		logError:  logError,
+// ParseSchedule validates a schedule's name and parses its cron expression. Names are
+// lower-case letters, digits and hyphens, since they become part of backup file names.
+func ParseSchedule(schedule backup.Schedule) (*CronSchedule, error) {
+	if schedule.Name == "" {
+		return nil, fmt.Errorf("backup schedule name is required")
+	}
+	for _, r := range schedule.Name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return nil, fmt.Errorf("backup schedule name %q must use lower-case letters, digits and hyphens", schedule.Name)
+		}
+	}
+	if schedule.RetentionDays < 0 {
+		return nil, fmt.Errorf("backup schedule %q retention must not be negative", schedule.Name)
+	}
+	parsed, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("backup schedule %q: %w", schedule.Name, err)
 	}
+	return parsed, nil
 }
 
 // Execute performs an immediate backup
@@ -60,6 +160,22 @@ func NewService(
 // To run in background, caller should use a goroutine.
 // However, to enforce non-overlapping, we use a mutex inside.
 func (s *Service) Execute() error {
+	return s.execute("")
+}
+
+// PreMigrationLabel marks the backups taken before a schema change, in place of a
+// schedule name. They are kept for RetentionDays, like manual backups.
+const PreMigrationLabel = "pre-migration"
+
+// ExecuteBeforeMigration takes a backup labelled PreMigrationLabel, so the schema change
+// that follows can be undone by restoring it.
+func (s *Service) ExecuteBeforeMigration() error {
+	return s.execute(PreMigrationLabel)
+}
+
+// execute performs a backup on behalf of the named schedule, or a manual one when
+// schedule is empty.
+func (s *Service) execute(schedule string) error {
 	s.mu.Lock()
 	if s.running {
 		s.mu.Unlock()
@@ -74,6 +190,11 @@ func (s *Service) Execute() error {
 		s.mu.Unlock()
 	}()
 
+	if s.keyErr != nil {
+		return fmt.Errorf("backup encryption key unavailable: %w", s.keyErr)
+	}
+	key := s.key
+
 	s.logInfo("Starting database backup...")
 
 	startTime := time.Now()
@@ -85,10 +206,13 @@ func (s *Service) Execute() error {
 	}
 
 	// Generate filename
-	timestamp := startTime.Format("2006-01-02T150405")
+	timestamp := startTime.Format(backupTimestampLayout)
 	baseName := fmt.Sprintf("backup-%s", timestamp)
+	if schedule != "" {
+		baseName += "-" + schedule
+	}
 	tempDbCopy := filepath.Join(s.config.BackupPath, baseName+".db")
-	zipPath := filepath.Join(s.config.BackupPath, baseName+".zip")
+	zipPath := filepath.Join(s.config.BackupPath, baseName+archiveExt)
 
 	// 1. Create a copy of the SQLite DB
 	dbPath := s.dbManager.GetDBPath()
@@ -109,20 +233,44 @@ func (s *Service) Execute() error {
 
 	defer os.Remove(tempDbCopy) // Cleanup temp DB file
 
-	// 2. Zip the DB file
-	if err := s.zipFile(tempDbCopy, zipPath, "masala_inventory.db"); err != nil {
+	// 2. Describe the copy, then zip it with its manifest
+	manifest, err := buildManifest(tempDbCopy, startTime, schedule, s.config.AppVersion, s.config.HardwareID)
+	if err != nil {
+		s.recordStatus(startTime, "", 0, false, fmt.Sprintf("manifest failed: %v", err))
+		return err
+	}
+	if err := s.zipFile(tempDbCopy, zipPath, manifest); err != nil {
 		s.recordStatus(startTime, "", 0, false, fmt.Sprintf("zip failed: %v", err))
 		return err
 	}
 
+	// 3. Seal it when a passphrase is set; the plain zip never outlives the backup.
+	if key != nil {
+		sealedPath := filepath.Join(s.config.BackupPath, baseName+encryptedExt)
+		err := encryptFile(zipPath, sealedPath, key)
+		os.Remove(zipPath)
+		if err != nil {
+			s.recordStatus(startTime, "", 0, false, fmt.Sprintf("encryption failed: %v", err))
+			return err
+		}
+		zipPath = sealedPath
+	}
+
 	// Get file info for status
 	info, err := os.Stat(zipPath)
 	size := int64(0)
 	if err == nil {
 		size = info.Size()
 	}
+	s.updateCatalog(func(catalog map[string]catalogEntry) bool {
+		catalog[baseName] = catalogEntry{
+			Manifest:     manifest,
+			Verification: backup.Verification{Status: backup.VerificationUnverified},
+		}
+		return true
+	})
 
-	// 3. Prune old backups
+	// 4. Prune old backups
 	prunedCount, err := s.Prune()
 	if err != nil {
 		s.logError("Pruning failed: %v", err)
@@ -133,6 +281,7 @@ func (s *Service) Execute() error {
 
 	s.logInfo("Backup completed successfully: %s", zipPath)
 	s.recordStatus(startTime, zipPath, size, true, "Backup successful")
+	s.requestReplication()
 	return nil
 }
 
@@ -191,8 +340,8 @@ func (s *Service) copyDatabaseFile(src, dst string) error {
 	return nil
 }
 
-// zipFile creates a zip archive containing the source file
-func (s *Service) zipFile(srcPath, zipPath, entryName string) error {
+// zipFile creates a zip archive containing the database copy and its manifest
+func (s *Service) zipFile(srcPath, zipPath string, manifest *backup.Manifest) error {
 	zipFile, err := os.Create(zipPath)
 	if err != nil {
 		return err
@@ -200,7 +349,6 @@ func (s *Service) zipFile(srcPath, zipPath, entryName string) error {
 	defer zipFile.Close()
 
 	archive := zip.NewWriter(zipFile)
-	defer archive.Close()
 
 	fileToZip, err := os.Open(srcPath)
 	if err != nil {
@@ -209,7 +357,7 @@ func (s *Service) zipFile(srcPath, zipPath, entryName string) error {
 	defer fileToZip.Close()
 
 	// Add file to zip
-	w, err := archive.Create(entryName)
+	w, err := archive.Create(databaseEntry)
 	if err != nil {
 		return err
 	}
@@ -218,56 +366,116 @@ func (s *Service) zipFile(srcPath, zipPath, entryName string) error {
 		return err
 	}
 
-	return nil
+	w, err = archive.Create(manifestEntry)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return zipFile.Close()
 }
 
-// Prune removes old backups based on retention policy
+// Prune removes old backups based on retention policy. Scheduled backups are kept for
+// their schedule's RetentionDays; manual backups, and those of schedules no longer
+// configured, for the service's RetentionDays.
 func (s *Service) Prune() (int, error) {
 	files, err := os.ReadDir(s.config.BackupPath)
 	if err != nil {
 		return 0, err
 	}
 
-	var backups []os.DirEntry
-	for _, f := range files {
-		if !f.IsDir() && strings.HasPrefix(f.Name(), "backup-") && strings.HasSuffix(f.Name(), ".zip") {
-			backups = append(backups, f)
-		}
+	retention := make(map[string]int, len(s.schedules))
+	for _, schedule := range s.schedules {
+		retention[schedule.Name] = schedule.RetentionDays
 	}
 
-	// Sort by name (which acts as timestamp sort due to YYYY-MM-DD format)
-	// We want to KEEP the newest, delete the oldest.
-	// RetentionDays = 7 means keep backups from the last 7 days.
-	// Actually, "backups older than 7 days".
-
-	threshold := time.Now().AddDate(0, 0, -s.config.RetentionDays)
+	now := time.Now()
 	pruned := 0
-
-	for _, f := range backups {
-		// Parse timestamp from name: backup-2026-02-13T020000.zip
+	var removed []string
+	for _, f := range files {
 		name := f.Name()
-		tsStr := strings.TrimSuffix(strings.TrimPrefix(name, "backup-"), ".zip")
-
-		ts, err := time.Parse("2006-01-02T150405", tsStr)
-		if err != nil {
-			// Try without time if legacy format? assumed T format for now
+		if f.IsDir() || !isBackupName(name) {
+			continue
+		}
+		// Parse timestamp from name: backup-2026-02-13T020000[-nightly].zip[.enc]
+		ts, schedule, ok := parseBackupName(name)
+		if !ok {
 			continue
 		}
+		days, ok := retention[schedule]
+		if !ok {
+			days = s.config.RetentionDays
+		}
 
-		if ts.Before(threshold) {
+		if ts.Before(now.AddDate(0, 0, -days)) {
 			path := filepath.Join(s.config.BackupPath, name)
 			if err := os.Remove(path); err != nil {
 				s.logError("Failed to delete old backup %s: %v", name, err)
 			} else {
 				pruned++
+				removed = append(removed, catalogKey(name))
 				s.logInfo("Pruned old backup: %s", name)
 			}
 		}
 	}
+	if len(removed) > 0 {
+		s.updateCatalog(func(catalog map[string]catalogEntry) bool {
+			changed := false
+			for _, key := range removed {
+				if _, ok := catalog[key]; ok {
+					delete(catalog, key)
+					changed = true
+				}
+			}
+			return changed
+		})
+	}
+	if n, err := s.pruneJournal(); err != nil {
+		s.logError("Failed to prune change journal: %v", err)
+	} else if n > 0 {
+		s.logInfo("Pruned %d change journal files no backup needs", n)
+	}
 
 	return pruned, nil
 }
 
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, "backup-") && (strings.HasSuffix(name, archiveExt) || strings.HasSuffix(name, encryptedExt))
+}
+
+// parseBackupName splits a backup file name into its timestamp and schedule. The
+// schedule is empty for manual backups.
+func parseBackupName(name string) (time.Time, string, bool) {
+	stem := strings.TrimPrefix(name, "backup-")
+	if strings.HasSuffix(stem, encryptedExt) {
+		stem = strings.TrimSuffix(stem, encryptedExt)
+	} else {
+		stem = strings.TrimSuffix(stem, archiveExt)
+	}
+	if len(stem) < len(backupTimestampLayout) {
+		return time.Time{}, "", false
+	}
+	ts, err := time.Parse(backupTimestampLayout, stem[:len(backupTimestampLayout)])
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	rest := stem[len(backupTimestampLayout):]
+	if rest == "" {
+		return ts, "", true
+	}
+	if !strings.HasPrefix(rest, "-") {
+		return time.Time{}, "", false
+	}
+	return ts, rest[1:], true
+}
+
 // GetStatus returns the status of the last backup
 func (s *Service) GetStatus() (*backup.BackupStatus, error) {
 	s.mu.Lock()
@@ -275,9 +483,27 @@ func (s *Service) GetStatus() (*backup.BackupStatus, error) {
 	// Return a copy
 	stat := s.status
 	stat.IsRunning = s.running
+	stat.Encrypted = s.key != nil
+	stat.Schedules = s.scheduleStatus(time.Now())
+	stat.Targets = s.targetStatus()
+	stat.JournalShippedAt = s.journalShippedAt
 	return &stat, nil
 }
 
+// scheduleStatus reports each schedule's next run after now.
+func (s *Service) scheduleStatus(now time.Time) []backup.ScheduleStatus {
+	statuses := make([]backup.ScheduleStatus, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		statuses = append(statuses, backup.ScheduleStatus{
+			Name:          schedule.Name,
+			Cron:          schedule.Cron,
+			RetentionDays: schedule.RetentionDays,
+			NextRun:       schedule.cron.Next(now),
+		})
+	}
+	return statuses
+}
+
 func (s *Service) recordStatus(t time.Time, path string, size int64, success bool, msg string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -298,42 +524,91 @@ func (s *Service) StartScheduler() error {
 		return fmt.Errorf("scheduler already running")
 	}
 	s.schedulerRunning = true
+	stop := make(chan struct{})
+	s.stopChan = stop
 	s.mu.Unlock()
 
-	go s.runSchedule()
+	go s.runSchedule(stop)
+	if s.verifyCron != nil {
+		go s.runVerifySchedule(stop)
+	}
+	if len(s.replicas) > 0 {
+		go s.runReplication(stop)
+	}
+	if s.config.JournalInterval > 0 {
+		go s.runJournalShipping(stop)
+	}
 	s.logInfo("Backup scheduler started")
 	return nil
 }
 
-func (s *Service) runSchedule() {
-	// Parse schedule time from config if strictly needed, but for MVP let's target 02:00 Local
-	targetHour := 2
-	targetMinute := 0
-
+func (s *Service) runSchedule(stop <-chan struct{}) {
 	for {
 		now := time.Now()
-		nextRun := time.Date(now.Year(), now.Month(), now.Day(), targetHour, targetMinute, 0, 0, now.Location())
-
-		if !nextRun.After(now) {
-			nextRun = nextRun.Add(24 * time.Hour)
+		nextRun, schedule := s.nextScheduledRun(now)
+		if schedule == nil {
+			s.logError("No valid backup schedules; scheduler idle")
+			<-stop
+			s.logInfo("Scheduler stopped")
+			return
 		}
 
 		duration := nextRun.Sub(now)
-		s.logInfo("Next scheduled backup in %v at %v", duration, nextRun)
+		s.logInfo("Next scheduled backup (%s) in %v at %v", schedule.Name, duration, nextRun)
 
 		select {
 		case <-time.After(duration):
 			// Time to run
-			if err := s.Execute(); err != nil {
-				s.logError("Scheduled backup failed: %v", err)
+			if err := s.execute(schedule.Name); err != nil {
+				s.logError("Scheduled backup %s failed: %v", schedule.Name, err)
 			}
-		case <-s.stopChan:
+		case <-stop:
 			s.logInfo("Scheduler stopped")
 			return
 		}
 	}
 }
 
+// runVerifySchedule test-restores archives at VerifyCron. It is kept apart from the
+// backup schedules so a long verification never delays a backup.
+func (s *Service) runVerifySchedule(stop <-chan struct{}) {
+	for {
+		now := time.Now()
+		nextRun := s.verifyCron.Next(now)
+		if nextRun.IsZero() {
+			return
+		}
+		select {
+		case <-time.After(nextRun.Sub(now)):
+			s.verifyPending()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// nextScheduledRun returns the earliest run of any schedule after now. When several
+// schedules fall due together one backup is taken, for the schedule that keeps its
+// backups longest, so an hourly and a nightly schedule do not both run at 02:00.
+func (s *Service) nextScheduledRun(now time.Time) (time.Time, *scheduled) {
+	var next time.Time
+	var due *scheduled
+	for i := range s.schedules {
+		schedule := &s.schedules[i]
+		run := schedule.cron.Next(now)
+		if run.IsZero() {
+			continue
+		}
+		switch {
+		case due == nil || run.Before(next):
+			next, due = run, schedule
+		case run.Equal(next) && schedule.RetentionDays > due.RetentionDays:
+			due = schedule
+		}
+	}
+	return next, due
+}
+
 // StopScheduler stops the backup scheduler gracefully
 func (s *Service) StopScheduler() error {
 	s.mu.Lock()
@@ -342,109 +617,150 @@ func (s *Service) StopScheduler() error {
 		return nil
 	}
 	s.schedulerRunning = false
+	// Closing reaches every loop, even one busy with a backup.
+	close(s.stopChan)
 	s.mu.Unlock()
 
-	select {
-	case s.stopChan <- struct{}{}:
-	default:
+	if s.config.JournalInterval > 0 {
+		if err := s.shipJournal(); err != nil {
+			s.logError("Change journal shipping failed: %v", err)
+		}
 	}
+
 	s.logInfo("Backup scheduler stopped")
 	return nil
 }
 
-// ListBackups returns available backup zip files sorted newest-first.
-func (s *Service) ListBackups() ([]string, error) {
+// ListBackups returns available backup archives, plain and encrypted, sorted
+// newest-first, with their manifests and last verification.
+func (s *Service) ListBackups() ([]backup.BackupInfo, error) {
 	files, err := os.ReadDir(s.config.BackupPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []string{}, nil
+			return []backup.BackupInfo{}, nil
 		}
 		return nil, err
 	}
 
-	backups := make([]string, 0)
+	s.catalogMu.Lock()
+	catalog := s.loadCatalog()
+	s.catalogMu.Unlock()
+
+	backups := make([]backup.BackupInfo, 0)
 	for _, f := range files {
-		if f.IsDir() {
+		name := f.Name()
+		if f.IsDir() || !isBackupName(name) {
 			continue
 		}
-		name := f.Name()
-		if strings.HasPrefix(name, "backup-") && strings.HasSuffix(name, ".zip") {
-			backups = append(backups, filepath.Join(s.config.BackupPath, name))
+		info := backup.BackupInfo{
+			Path:         filepath.Join(s.config.BackupPath, name),
+			Name:         name,
+			Encrypted:    strings.HasSuffix(name, encryptedExt),
+			Verification: backup.Verification{Status: backup.VerificationUnverified},
+		}
+		if created, schedule, ok := parseBackupName(name); ok {
+			info.CreatedAt, info.Schedule = created, schedule
+		}
+		if stat, err := f.Info(); err == nil {
+			info.Size = stat.Size()
+		}
+		if entry, ok := catalog[catalogKey(name)]; ok {
+			info.Manifest = entry.Manifest
+			if entry.Verification.Status != "" {
+				info.Verification = entry.Verification
+			}
+		}
+		if info.Manifest == nil && !info.Encrypted {
+			// Archives copied in from elsewhere: the manifest is in the zip's directory.
+			if r, err := zip.OpenReader(info.Path); err == nil {
+				info.Manifest, _ = readManifest(&r.Reader)
+				r.Close()
+			}
 		}
+		backups = append(backups, info)
 	}
-	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
 	return backups, nil
 }
 
-// Restore extracts a backup archive and replaces the active database file.
+// Restore extracts a backup archive and replaces the active database file. Encrypted
+// archives open with the current key; older ones need RestoreWithPassphrase.
 func (s *Service) Restore(backupPath string) error {
-	if backupPath == "" {
-		return fmt.Errorf("backup path is required")
-	}
+	return s.restore(backupPath, "")
+}
 
-	// Allow passing just the file name from UI.
-	candidate := backupPath
-	if !filepath.IsAbs(candidate) {
-		candidate = filepath.Join(s.config.BackupPath, filepath.Base(candidate))
-	}
+// RestoreWithPassphrase restores an encrypted archive sealed under passphrase, such as
+// one made before the passphrase changed or copied from another machine. The key check
+// rejects a wrong passphrase before the database is touched.
+func (s *Service) RestoreWithPassphrase(backupPath, passphrase string) error {
+	return s.restore(backupPath, passphrase)
+}
 
-	absCandidate, err := filepath.Abs(candidate)
+func (s *Service) restore(backupPath, passphrase string) error {
+	absCandidate, err := s.resolveBackupPath(backupPath)
 	if err != nil {
 		return err
 	}
-	absBackupDir, err := filepath.Abs(s.config.BackupPath)
-	if err != nil {
+
+	dbPath := s.dbManager.GetDBPath()
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
 		return err
 	}
-	relPath, err := filepath.Rel(absBackupDir, absCandidate)
+	archivePath, err := s.openArchive(absCandidate, dbPath+".restore_zip", passphrase)
 	if err != nil {
 		return err
 	}
-	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) || filepath.IsAbs(relPath) {
-		return fmt.Errorf("backup path must be inside backup directory")
+	if archivePath != absCandidate {
+		defer os.Remove(archivePath)
 	}
 
-	r, err := zip.OpenReader(absCandidate)
+	r, err := zip.OpenReader(archivePath)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
 
-	var sourceFile *zip.File
-	for _, f := range r.File {
-		if filepath.Base(f.Name) == "masala_inventory.db" {
-			sourceFile = f
-			break
-		}
-	}
-	if sourceFile == nil {
-		return fmt.Errorf("backup does not contain masala_inventory.db")
-	}
-
-	rc, err := sourceFile.Open()
+	manifest, err := readManifest(&r.Reader)
 	if err != nil {
 		return err
 	}
-	defer rc.Close()
-
-	dbPath := s.dbManager.GetDBPath()
-	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-		return err
-	}
 
 	tmpPath := dbPath + ".restore_tmp"
-	dst, err := os.Create(tmpPath)
-	if err != nil {
+	if err := extractDatabase(&r.Reader, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
 		return err
 	}
-	if _, err := io.Copy(dst, rc); err != nil {
-		_ = dst.Close()
-		return err
+	// Check the copy before the live database is touched.
+	if manifest != nil {
+		sum, _, err := fileSHA256(tmpPath)
+		if err != nil {
+			_ = os.Remove(tmpPath)
+			return err
+		}
+		if sum != manifest.DatabaseSHA256 {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("backup database checksum does not match its manifest")
+		}
+	}
+
+	// Changes still in the live database are shipped first, so a restore to a point in
+	// time can still reach them afterwards.
+	if err := s.shipJournal(); err != nil {
+		s.logError("Failed to ship change journal before restoring: %v", err)
 	}
-	if err := dst.Close(); err != nil {
+	s.journalMu.Lock()
+	defer s.journalMu.Unlock()
+	if err := s.replaceDatabase(tmpPath); err != nil {
 		return err
 	}
+	// The restored file carries the journal of the history it was copied from.
+	return s.dbManager.RestartChangeJournal()
+}
 
+// replaceDatabase swaps the database file for the one at tmpPath and reconnects.
+// tmpPath must be on the database's volume, as it is renamed into place.
+func (s *Service) replaceDatabase(tmpPath string) error {
+	dbPath := s.dbManager.GetDBPath()
 	_ = s.dbManager.Close()
 
 	// Remove sidecar files from previous DB state so stale WAL/SHM cannot
@@ -466,3 +782,141 @@ func (s *Service) Restore(backupPath string) error {
 
 	return s.dbManager.Connect()
 }
+
+// resolveBackupPath returns the absolute path of an archive, which must be inside the
+// backup folder. A bare file name, as the UI passes, is looked up there.
+func (s *Service) resolveBackupPath(backupPath string) (string, error) {
+	if backupPath == "" {
+		return "", fmt.Errorf("backup path is required")
+	}
+
+	// Allow passing just the file name from UI.
+	candidate := backupPath
+	if !filepath.IsAbs(candidate) {
+		candidate = filepath.Join(s.config.BackupPath, filepath.Base(candidate))
+	}
+
+	absCandidate, err := filepath.Abs(candidate)
+	if err != nil {
+		return "", err
+	}
+	absBackupDir, err := filepath.Abs(s.config.BackupPath)
+	if err != nil {
+		return "", err
+	}
+	relPath, err := filepath.Rel(absBackupDir, absCandidate)
+	if err != nil {
+		return "", err
+	}
+	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) || filepath.IsAbs(relPath) {
+		return "", fmt.Errorf("backup path must be inside backup directory")
+	}
+	return absCandidate, nil
+}
+
+// SetEncryptionPassphrase turns on archive encryption, or changes its passphrase when it
+// is already on, in which case current must be the passphrase in use. Every archive in
+// the backup folder is then sealed under the new key: plain ones are encrypted and ones
+// under an older key are re-encrypted, so the old passphrase stops opening any of them.
+// Archives that current cannot open are left as they are and reported in the error.
+// It returns the number of archives rewritten.
+func (s *Service) SetEncryptionPassphrase(current, next string) (int, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("backup already in progress")
+	}
+	if s.key != nil && !s.key.matches(current) {
+		s.mu.Unlock()
+		return 0, ErrWrongPassphrase
+	}
+	// Holding the running flag keeps scheduled backups out while archives are rewritten,
+	// and the journal lock keeps segments from being shipped under the old key.
+	s.running = true
+	oldKey := s.key
+	s.mu.Unlock()
+	s.journalMu.Lock()
+	defer s.journalMu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	newKey, err := newBackupKey(next)
+	if err != nil {
+		return 0, err
+	}
+	// The key is saved first: if rewriting stops part-way, the remaining archives still
+	// open with the old passphrase.
+	if err := saveBackupKey(s.config.KeyPath, newKey); err != nil {
+		return 0, fmt.Errorf("failed to save backup key: %w", err)
+	}
+	s.mu.Lock()
+	s.key, s.keyErr = newKey, nil
+	s.mu.Unlock()
+	s.logInfo("Backup encryption passphrase set")
+
+	files, err := os.ReadDir(s.config.BackupPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	rewritten := 0
+	var failed []string
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !isBackupName(name) {
+			continue
+		}
+		if err := s.reencrypt(name, oldKey, current, newKey); err != nil {
+			s.logError("Failed to re-encrypt backup %s: %v", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		rewritten++
+	}
+	segments, failedSegments := s.reencryptJournal(oldKey, current, newKey)
+	if segments > 0 {
+		s.logInfo("Re-encrypted %d change journal segments", segments)
+	}
+	failed = append(failed, failedSegments...)
+	if rewritten > 0 {
+		// Copies on the targets are still sealed under the old key.
+		s.mu.Lock()
+		for _, r := range s.replicas {
+			r.reupload = true
+		}
+		s.mu.Unlock()
+		s.requestReplication()
+	}
+	if len(failed) > 0 {
+		return rewritten, fmt.Errorf("%d backups could not be re-encrypted: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return rewritten, nil
+}
+
+// reencrypt seals one archive under newKey, decrypting it first when it is encrypted.
+func (s *Service) reencrypt(name string, oldKey *backupKey, passphrase string, newKey *backupKey) error {
+	path := filepath.Join(s.config.BackupPath, name)
+	source := path
+	if strings.HasSuffix(name, encryptedExt) {
+		source = path + ".plain"
+		if err := decryptFile(path, source, oldKey, passphrase); err != nil {
+			return err
+		}
+		defer os.Remove(source)
+	}
+	target := strings.TrimSuffix(path, encryptedExt)
+	target = strings.TrimSuffix(target, archiveExt) + encryptedExt
+	if err := encryptFile(source, target, newKey); err != nil {
+		return err
+	}
+	if source == path {
+		return os.Remove(path)
+	}
+	return nil
+}
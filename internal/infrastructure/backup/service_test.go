@@ -2,10 +2,12 @@ package backup
 
 import (
 	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -62,16 +64,15 @@ func TestExecute(t *testing.T) {
 		t.Errorf("Execute failed: %v", err)
 	}
 
-	// Verify zip exists
-	files, err := os.ReadDir(backupDir)
+	// Verify zip exists, beside the catalog
+	files, err := filepath.Glob(filepath.Join(backupDir, "backup-*.zip"))
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(files) != 1 {
-		t.Errorf("Expected 1 backup file, got %d", len(files))
+		t.Fatalf("Expected 1 backup file, got %d", len(files))
 	}
-	zipName := files[0].Name()
-	zipPath := filepath.Join(backupDir, zipName)
+	zipPath := files[0]
 
 	// Verify zip content
 	r, err := zip.OpenReader(zipPath)
@@ -80,12 +81,19 @@ func TestExecute(t *testing.T) {
 	}
 	defer r.Close()
 
-	if len(r.File) != 1 {
-		t.Errorf("Expected 1 file in zip, got %d", len(r.File))
+	if len(r.File) != 2 {
+		t.Errorf("Expected database and manifest in zip, got %d files", len(r.File))
 	}
 	if r.File[0].Name != "masala_inventory.db" {
 		t.Errorf("Expected masala_inventory.db in zip, got %s", r.File[0].Name)
 	}
+	manifest, err := readManifest(&r.Reader)
+	if err != nil || manifest == nil {
+		t.Fatalf("Expected a manifest in zip, got %v (%v)", manifest, err)
+	}
+	if manifest.TableCounts["test"] != 1 || len(manifest.DatabaseSHA256) != 64 {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
 
 	// Verify status
 	status, _ := svc.GetStatus()
@@ -248,8 +256,8 @@ func TestListBackups_SortedNewestFirst(t *testing.T) {
 	}
 
 	wantFirst := filepath.Join(tmpDir, "backup-2026-02-15T020000.zip")
-	if got[0] != wantFirst {
-		t.Fatalf("expected newest backup first %s, got %s", wantFirst, got[0])
+	if got[0].Path != wantFirst {
+		t.Fatalf("expected newest backup first %s, got %s", wantFirst, got[0].Path)
 	}
 }
 
@@ -446,3 +454,192 @@ func createZipWithFile(zipPath, srcPath, entryName string) error {
 	_, err = io.Copy(entry, src)
 	return err
 }
+
+func TestPrune_UsesScheduleRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	svc := NewService(nil, backup.BackupConfig{
+		BackupPath:    tmpDir,
+		RetentionDays: 7,
+		Schedules: []backup.Schedule{
+			{Name: "hourly", Cron: "0 9-18 * * MON-SAT", RetentionDays: 2},
+			{Name: "nightly", Cron: "0 2 * * *", RetentionDays: 30},
+		},
+	}, noOpLog, noOpLog)
+
+	stamp := func(days int) string {
+		return time.Now().AddDate(0, 0, -days).Format("2006-01-02T150405")
+	}
+	keep := []string{
+		"backup-" + stamp(1) + "-hourly.zip",
+		"backup-" + stamp(10) + "-nightly.zip",
+		"backup-" + stamp(5) + ".zip",
+		"backup-" + stamp(5) + "-retired.zip",
+	}
+	drop := []string{
+		"backup-" + stamp(3) + "-hourly.zip",
+		"backup-" + stamp(40) + "-nightly.zip",
+		"backup-" + stamp(10) + ".zip",
+		"backup-" + stamp(10) + "-retired.zip",
+	}
+	for _, name := range append(append([]string{}, keep...), drop...) {
+		createDummyFile(t, tmpDir, name)
+	}
+
+	n, err := svc.Prune()
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if n != len(drop) {
+		t.Errorf("Expected %d pruned files, got %d", len(drop), n)
+	}
+	for _, name := range keep {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); err != nil {
+			t.Errorf("%s was pruned: %v", name, err)
+		}
+	}
+	for _, name := range drop {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was kept", name)
+		}
+	}
+}
+
+func TestNextScheduledRun_PrefersLongestRetentionWhenDueTogether(t *testing.T) {
+	svc := NewService(nil, backup.BackupConfig{
+		BackupPath: t.TempDir(),
+		Schedules: []backup.Schedule{
+			{Name: "hourly", Cron: "0 * * * *", RetentionDays: 2},
+			{Name: "nightly", Cron: "0 2 * * *", RetentionDays: 30},
+		},
+	}, noOpLog, noOpLog)
+
+	next, schedule := svc.nextScheduledRun(time.Date(2026, 3, 2, 0, 30, 0, 0, time.UTC))
+	if schedule == nil || schedule.Name != "hourly" || !next.Equal(time.Date(2026, 3, 2, 1, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected hourly at 01:00, got %v %+v", next, schedule)
+	}
+	next, schedule = svc.nextScheduledRun(time.Date(2026, 3, 2, 1, 30, 0, 0, time.UTC))
+	if schedule == nil || schedule.Name != "nightly" || !next.Equal(time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected nightly at 02:00, got %v %+v", next, schedule)
+	}
+}
+
+func TestGetStatus_ReportsScheduleNextRuns(t *testing.T) {
+	svc := NewService(nil, backup.BackupConfig{
+		BackupPath: t.TempDir(),
+		Schedules: []backup.Schedule{
+			{Name: "hourly", Cron: "@hourly", RetentionDays: 2},
+			{Name: "bad", Cron: "0 25 * * *"},
+		},
+	}, noOpLog, noOpLog)
+
+	status, err := svc.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if len(status.Schedules) != 1 {
+		t.Fatalf("Expected only the valid schedule, got %+v", status.Schedules)
+	}
+	got := status.Schedules[0]
+	if got.Name != "hourly" || got.RetentionDays != 2 || got.NextRun.Minute() != 0 || got.NextRun.Sub(time.Now()) > time.Hour {
+		t.Errorf("Unexpected schedule status: %+v", got)
+	}
+}
+
+func TestNewService_DefaultsToNightlySchedule(t *testing.T) {
+	svc := NewService(nil, backup.BackupConfig{BackupPath: t.TempDir(), RetentionDays: 5}, noOpLog, noOpLog)
+
+	status, _ := svc.GetStatus()
+	if len(status.Schedules) != 1 {
+		t.Fatalf("Expected one default schedule, got %+v", status.Schedules)
+	}
+	got := status.Schedules[0]
+	if got.Name != "nightly" || got.Cron != "0 2 * * *" || got.RetentionDays != 5 || got.NextRun.Hour() != 2 {
+		t.Errorf("Unexpected default schedule: %+v", got)
+	}
+}
+
+func TestEncryptedBackup_RestoreAndPassphraseChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbDir := filepath.Join(tmpDir, "db")
+	backupDir := filepath.Join(tmpDir, "backups")
+	if err := os.MkdirAll(dbDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manager := createDummyDB(t, dbDir)
+	defer manager.Close()
+
+	config := backup.BackupConfig{BackupPath: backupDir, KeyPath: filepath.Join(dbDir, "backup.key")}
+	svc := NewService(manager, config, noOpLog, noOpLog)
+
+	// A plain backup taken before encryption is turned on.
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	n, err := svc.SetEncryptionPassphrase("", "first passphrase")
+	if err != nil || n != 1 {
+		t.Fatalf("SetEncryptionPassphrase: %d, %v", n, err)
+	}
+	time.Sleep(1100 * time.Millisecond) // backup names have one-second resolution
+	if err := svc.Execute(); err != nil {
+		t.Fatalf("encrypted Execute failed: %v", err)
+	}
+
+	backups, err := svc.ListBackups()
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("ListBackups: %v, %v", backups, err)
+	}
+	for _, info := range backups {
+		path := info.Path
+		if !strings.HasSuffix(path, ".zip.enc") || !info.Encrypted {
+			t.Errorf("%s is not encrypted", path)
+		}
+		if encrypted, _ := isEncryptedArchive(path); !encrypted {
+			t.Errorf("%s has no encrypted header", path)
+		}
+	}
+	if status, _ := svc.GetStatus(); !status.Encrypted || status.FilePath != backups[0].Path {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	if _, err := manager.GetDB().Exec("UPDATE test SET name = 'changed'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Restore(backups[0].Path); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	var name string
+	if err := manager.GetDB().QueryRow("SELECT name FROM test LIMIT 1").Scan(&name); err != nil || name != "foo" {
+		t.Fatalf("expected restored value foo, got %q (%v)", name, err)
+	}
+
+	if _, err := svc.SetEncryptionPassphrase("wrong passphrase", "second passphrase"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if n, err := svc.SetEncryptionPassphrase("first passphrase", "second passphrase"); err != nil || n != 2 {
+		t.Fatalf("changing passphrase: %d, %v", n, err)
+	}
+
+	// A machine without the key file needs the current passphrase; the old one no longer opens anything.
+	fresh := NewService(manager, backup.BackupConfig{BackupPath: backupDir, KeyPath: filepath.Join(tmpDir, "missing.key")}, noOpLog, noOpLog)
+	if err := fresh.Restore(backups[1].Path); !errors.Is(err, ErrBackupKeyMissing) {
+		t.Fatalf("expected ErrBackupKeyMissing, got %v", err)
+	}
+	if err := fresh.RestoreWithPassphrase(backups[1].Path, "first passphrase"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase for the old passphrase, got %v", err)
+	}
+	if err := fresh.RestoreWithPassphrase(backups[1].Path, "second passphrase"); err != nil {
+		t.Fatalf("RestoreWithPassphrase failed: %v", err)
+	}
+}
+
+func TestNewService_InvalidKeyFileStopsBackups(t *testing.T) {
+	tmpDir := t.TempDir()
+	keyPath := filepath.Join(tmpDir, "backup.key")
+	if err := os.WriteFile(keyPath, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(nil, backup.BackupConfig{BackupPath: filepath.Join(tmpDir, "backups"), KeyPath: keyPath}, noOpLog, noOpLog)
+	if err := svc.Execute(); err == nil || !strings.Contains(err.Error(), "backup encryption key unavailable") {
+		t.Fatalf("expected backups to stop without a readable key, got %v", err)
+	}
+}
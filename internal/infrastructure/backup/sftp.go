@@ -0,0 +1,404 @@
+package backup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+)
+
+// sftpClient speaks the parts of SFTP version 3 (draft-ietf-secsh-filexfer-02) that
+// replication needs: listing a folder, writing, renaming and removing files. One request
+// is in flight at a time.
+type sftpClient struct {
+	mu     sync.Mutex
+	w      io.WriteCloser
+	r      io.Reader
+	nextID uint32
+}
+
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpWrite    = 6
+	sftpOpenDir  = 11
+	sftpReadDir  = 12
+	sftpRemove   = 13
+	sftpMkdir    = 14
+	sftpStat     = 17
+	sftpRename   = 18
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpName     = 104
+	sftpAttrs    = 105
+	sftpProtocol = 3
+
+	sftpFlagWrite  = 0x02
+	sftpFlagCreate = 0x08
+	sftpFlagTrunc  = 0x10
+
+	sftpAttrSize        = 0x00000001
+	sftpAttrUIDGID      = 0x00000002
+	sftpAttrPermissions = 0x00000004
+	sftpAttrACModTime   = 0x00000008
+	sftpAttrExtended    = 0x80000000
+
+	sftpOK         = 0
+	sftpEOF        = 1
+	sftpNoSuchFile = 2
+
+	// sftpChunk is the write size every server accepts.
+	sftpChunk = 32 * 1024
+	// sftpMaxPacket bounds replies, so a broken server cannot make us allocate at will.
+	sftpMaxPacket = 1 << 20
+)
+
+// sftpStatusError is a failure reported by the server.
+type sftpStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *sftpStatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("sftp: %s (code %d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("sftp: status %d", e.Code)
+}
+
+func isSFTPNotExist(err error) bool {
+	var status *sftpStatusError
+	return errors.As(err, &status) && status.Code == sftpNoSuchFile
+}
+
+// newSFTPClient runs the version handshake over a subsystem channel.
+func newSFTPClient(w io.WriteCloser, r io.Reader) (*sftpClient, error) {
+	c := &sftpClient{w: w, r: r}
+	var init sftpBuffer
+	init.byte(sftpInit)
+	init.uint32(sftpProtocol)
+	if err := c.send(init); err != nil {
+		return nil, err
+	}
+	kind, payload, err := c.receive()
+	if err != nil {
+		return nil, err
+	}
+	if kind != sftpVersion {
+		return nil, fmt.Errorf("sftp: expected version reply, got packet type %d", kind)
+	}
+	version, _, err := takeUint32(payload)
+	if err != nil {
+		return nil, err
+	}
+	if version < sftpProtocol {
+		return nil, fmt.Errorf("sftp: server speaks version %d, need %d", version, sftpProtocol)
+	}
+	return c, nil
+}
+
+func (c *sftpClient) Close() error {
+	return c.w.Close()
+}
+
+// ReadDir returns the names in a remote folder.
+func (c *sftpClient) ReadDir(dir string) ([]string, error) {
+	handle, err := c.handleRequest(sftpOpenDir, func(b *sftpBuffer) { b.string(dir) })
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+
+	var names []string
+	for {
+		kind, payload, err := c.request(sftpReadDir, func(b *sftpBuffer) { b.string(handle) })
+		if err != nil {
+			return nil, err
+		}
+		if kind == sftpStatus {
+			if err := statusError(payload); err != nil {
+				var status *sftpStatusError
+				if errors.As(err, &status) && status.Code == sftpEOF {
+					return names, nil
+				}
+				return nil, err
+			}
+			return names, nil
+		}
+		if kind != sftpName {
+			return nil, fmt.Errorf("sftp: unexpected reply %d to readdir", kind)
+		}
+		count, rest, err := takeUint32(payload)
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			var name string
+			if name, rest, err = takeString(rest); err != nil {
+				return nil, err
+			}
+			if _, rest, err = takeString(rest); err != nil { // long name
+				return nil, err
+			}
+			if rest, err = skipAttrs(rest); err != nil {
+				return nil, err
+			}
+			if name != "." && name != ".." {
+				names = append(names, name)
+			}
+		}
+	}
+}
+
+// WriteFile copies src to a new remote file, replacing any existing one.
+func (c *sftpClient) WriteFile(remote string, src io.Reader) error {
+	handle, err := c.handleRequest(sftpOpen, func(b *sftpBuffer) {
+		b.string(remote)
+		b.uint32(sftpFlagWrite | sftpFlagCreate | sftpFlagTrunc)
+		b.uint32(0) // no attributes
+	})
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, sftpChunk)
+	var offset uint64
+	for {
+		n, readErr := io.ReadFull(src, buf)
+		if n > 0 {
+			chunk := buf[:n]
+			if err := c.statusRequest(sftpWrite, func(b *sftpBuffer) {
+				b.string(handle)
+				b.uint64(offset)
+				b.bytes(chunk)
+			}); err != nil {
+				c.closeHandle(handle)
+				return err
+			}
+			offset += uint64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			c.closeHandle(handle)
+			return readErr
+		}
+	}
+	// A failed close can mean the data never reached the disk.
+	return c.closeHandle(handle)
+}
+
+func (c *sftpClient) Remove(remote string) error {
+	return c.statusRequest(sftpRemove, func(b *sftpBuffer) { b.string(remote) })
+}
+
+// Rename moves a file. Version 3 servers refuse to overwrite, so callers remove the
+// target first.
+func (c *sftpClient) Rename(from, to string) error {
+	return c.statusRequest(sftpRename, func(b *sftpBuffer) {
+		b.string(from)
+		b.string(to)
+	})
+}
+
+// MkdirAll creates dir and any missing parents.
+func (c *sftpClient) MkdirAll(dir string) error {
+	if dir == "" || dir == "." || dir == "/" {
+		return nil
+	}
+	kind, payload, err := c.request(sftpStat, func(b *sftpBuffer) { b.string(dir) })
+	if err != nil {
+		return err
+	}
+	if kind == sftpAttrs {
+		return nil
+	}
+	if err := statusError(payload); err != nil && !isSFTPNotExist(err) {
+		return err
+	}
+	if err := c.MkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	return c.statusRequest(sftpMkdir, func(b *sftpBuffer) {
+		b.string(dir)
+		b.uint32(0)
+	})
+}
+
+func (c *sftpClient) closeHandle(handle string) error {
+	return c.statusRequest(sftpClose, func(b *sftpBuffer) { b.string(handle) })
+}
+
+func (c *sftpClient) handleRequest(kind byte, build func(*sftpBuffer)) (string, error) {
+	reply, payload, err := c.request(kind, build)
+	if err != nil {
+		return "", err
+	}
+	if reply == sftpStatus {
+		if err := statusError(payload); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("sftp: no handle returned")
+	}
+	if reply != sftpHandle {
+		return "", fmt.Errorf("sftp: unexpected reply %d", reply)
+	}
+	handle, _, err := takeString(payload)
+	return handle, err
+}
+
+func (c *sftpClient) statusRequest(kind byte, build func(*sftpBuffer)) error {
+	reply, payload, err := c.request(kind, build)
+	if err != nil {
+		return err
+	}
+	if reply != sftpStatus {
+		return fmt.Errorf("sftp: unexpected reply %d", reply)
+	}
+	return statusError(payload)
+}
+
+// request sends one packet and returns the reply's type and payload after the id.
+func (c *sftpClient) request(kind byte, build func(*sftpBuffer)) (byte, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	id := c.nextID
+	var b sftpBuffer
+	b.byte(kind)
+	b.uint32(id)
+	build(&b)
+	if err := c.send(b); err != nil {
+		return 0, nil, err
+	}
+	reply, payload, err := c.receive()
+	if err != nil {
+		return 0, nil, err
+	}
+	gotID, payload, err := takeUint32(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	if gotID != id {
+		return 0, nil, fmt.Errorf("sftp: reply for request %d, expected %d", gotID, id)
+	}
+	return reply, payload, nil
+}
+
+func (c *sftpClient) send(b sftpBuffer) error {
+	packet := make([]byte, 4, 4+len(b))
+	binary.BigEndian.PutUint32(packet, uint32(len(b)))
+	_, err := c.w.Write(append(packet, b...))
+	return err
+}
+
+func (c *sftpClient) receive() (byte, []byte, error) {
+	return readSFTPPacket(c.r)
+}
+
+func readSFTPPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp: bad packet length %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+func statusError(payload []byte) error {
+	code, rest, err := takeUint32(payload)
+	if err != nil {
+		return err
+	}
+	if code == sftpOK {
+		return nil
+	}
+	message, _, _ := takeString(rest)
+	return &sftpStatusError{Code: code, Message: message}
+}
+
+// sftpBuffer builds a packet body.
+type sftpBuffer []byte
+
+func (b *sftpBuffer) byte(v byte) { *b = append(*b, v) }
+func (b *sftpBuffer) uint32(v uint32) {
+	*b = binary.BigEndian.AppendUint32(*b, v)
+}
+func (b *sftpBuffer) uint64(v uint64) {
+	*b = binary.BigEndian.AppendUint64(*b, v)
+}
+func (b *sftpBuffer) bytes(v []byte) {
+	b.uint32(uint32(len(v)))
+	*b = append(*b, v...)
+}
+func (b *sftpBuffer) string(v string) { b.bytes([]byte(v)) }
+
+var errSFTPShort = errors.New("sftp: packet too short")
+
+func takeUint32(p []byte) (uint32, []byte, error) {
+	if len(p) < 4 {
+		return 0, nil, errSFTPShort
+	}
+	return binary.BigEndian.Uint32(p), p[4:], nil
+}
+
+func takeString(p []byte) (string, []byte, error) {
+	n, p, err := takeUint32(p)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint32(len(p)) < n {
+		return "", nil, errSFTPShort
+	}
+	return string(p[:n]), p[n:], nil
+}
+
+func skipAttrs(p []byte) ([]byte, error) {
+	flags, p, err := takeUint32(p)
+	if err != nil {
+		return nil, err
+	}
+	skip := 0
+	if flags&sftpAttrSize != 0 {
+		skip += 8
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		skip += 8
+	}
+	if flags&sftpAttrPermissions != 0 {
+		skip += 4
+	}
+	if flags&sftpAttrACModTime != 0 {
+		skip += 8
+	}
+	if len(p) < skip {
+		return nil, errSFTPShort
+	}
+	p = p[skip:]
+	if flags&sftpAttrExtended != 0 {
+		count, rest, err := takeUint32(p)
+		if err != nil {
+			return nil, err
+		}
+		p = rest
+		for i := uint32(0); i < 2*count; i++ {
+			if _, p, err = takeString(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
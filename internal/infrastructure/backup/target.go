@@ -0,0 +1,520 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"masala_inventory_managment/internal/domain/backup"
+)
+
+const (
+	// partialExt marks a copy still being written, so an interrupted upload is never
+	// taken for an archive.
+	partialExt = ".part"
+
+	// A failed target is retried after retryBaseDelay, doubling per failure up to
+	// retryMaxDelay.
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = time.Hour
+
+	sftpDialTimeout = 30 * time.Second
+)
+
+// replica is a configured target and its replication state.
+type replica struct {
+	config backup.TargetConfig
+	target backup.Target
+	status backup.TargetStatus
+	// reupload copies every archive again on the next run, after archives are
+	// re-encrypted under a new passphrase.
+	reupload bool
+}
+
+// NewTarget opens the target described by config. Nothing is contacted until the target
+// is first used.
+func NewTarget(config backup.TargetConfig) (backup.Target, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("backup target %q needs a path", config.Name)
+	}
+	switch config.Kind {
+	case backup.TargetLocal:
+		return &folderTarget{dir: config.Path, create: true}, nil
+	case backup.TargetSMB:
+		// Creating the folder would hide an unmounted share behind a local folder of
+		// the same name.
+		return &folderTarget{dir: config.Path}, nil
+	case backup.TargetSFTP:
+		return newSFTPTarget(config)
+	default:
+		return nil, fmt.Errorf("backup target %q has unknown kind %q", config.Name, config.Kind)
+	}
+}
+
+// ValidateTarget checks a target's configuration without contacting it.
+func ValidateTarget(config backup.TargetConfig) error {
+	if config.Name == "" {
+		return fmt.Errorf("backup target name is required")
+	}
+	if config.RetentionDays < 0 {
+		return fmt.Errorf("backup target %q retention must not be negative", config.Name)
+	}
+	_, err := NewTarget(config)
+	return err
+}
+
+// folderTarget copies archives to a folder: a second local disk, or a network share
+// mounted or reached by UNC path.
+type folderTarget struct {
+	dir    string
+	create bool
+}
+
+func (t *folderTarget) ready() error {
+	if t.create {
+		return os.MkdirAll(t.dir, 0755)
+	}
+	info, err := os.Stat(t.dir)
+	if err != nil {
+		return fmt.Errorf("share not reachable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a folder", t.dir)
+	}
+	return nil
+}
+
+func (t *folderTarget) List() ([]string, error) {
+	if err := t.ready(); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func (t *folderTarget) Upload(localPath, name string) error {
+	if err := t.ready(); err != nil {
+		return err
+	}
+	dst := filepath.Join(t.dir, name)
+	if err := copyFile(localPath, dst+partialExt); err != nil {
+		os.Remove(dst + partialExt)
+		return err
+	}
+	// Shares may refuse to rename over an existing file.
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(dst+partialExt, dst)
+}
+
+func (t *folderTarget) Remove(name string) error {
+	return os.Remove(filepath.Join(t.dir, name))
+}
+
+func (t *folderTarget) Close() error { return nil }
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := out.ReadFrom(in); err != nil {
+		out.Close()
+		return err
+	}
+	// Sync so a copy reported as uploaded survives the share's host losing power.
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// sftpTarget copies archives to a folder on an SSH server. It connects on first use and
+// keeps the connection until Close.
+type sftpTarget struct {
+	addr   string
+	dir    string
+	config *ssh.ClientConfig
+
+	conn   *ssh.Client
+	client *sftpClient
+}
+
+func newSFTPTarget(config backup.TargetConfig) (*sftpTarget, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("backup target %q needs a host", config.Name)
+	}
+	if config.User == "" {
+		return nil, fmt.Errorf("backup target %q needs a user", config.Name)
+	}
+	if !strings.HasPrefix(config.HostKey, "SHA256:") {
+		return nil, fmt.Errorf("backup target %q needs the server's SHA256 host key fingerprint", config.Name)
+	}
+
+	var auth []ssh.AuthMethod
+	if config.KeyFile != "" {
+		pem, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("backup target %q key file: %w", config.Name, err)
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("backup target %q key file: %w", config.Name, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if config.Password != "" {
+		auth = append(auth, ssh.Password(config.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("backup target %q needs a password or key file", config.Name)
+	}
+
+	port := config.Port
+	if port == 0 {
+		port = 22
+	}
+	want := config.HostKey
+	return &sftpTarget{
+		addr: net.JoinHostPort(config.Host, strconv.Itoa(port)),
+		dir:  config.Path,
+		config: &ssh.ClientConfig{
+			User: config.User,
+			Auth: auth,
+			HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+				if got := ssh.FingerprintSHA256(key); got != want {
+					return fmt.Errorf("host key %s does not match %s", got, want)
+				}
+				return nil
+			},
+			Timeout: sftpDialTimeout,
+		},
+	}, nil
+}
+
+func (t *sftpTarget) connect() (*sftpClient, error) {
+	if t.client != nil {
+		return t.client, nil
+	}
+	conn, err := ssh.Dial("tcp", t.addr, t.config)
+	if err != nil {
+		return nil, err
+	}
+	session, err := conn.NewSession()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client, err := newSFTPClient(w, r)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := client.MkdirAll(t.dir); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	t.conn, t.client = conn, client
+	return client, nil
+}
+
+// do runs op on a connection, dropping the connection when op fails so the next use
+// starts afresh.
+func (t *sftpTarget) do(op func(*sftpClient) error) error {
+	client, err := t.connect()
+	if err != nil {
+		return err
+	}
+	if err := op(client); err != nil {
+		t.Close()
+		return err
+	}
+	return nil
+}
+
+func (t *sftpTarget) List() ([]string, error) {
+	var names []string
+	err := t.do(func(c *sftpClient) error {
+		var err error
+		names, err = c.ReadDir(t.dir)
+		return err
+	})
+	return names, err
+}
+
+func (t *sftpTarget) Upload(localPath, name string) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst := path.Join(t.dir, name)
+	return t.do(func(c *sftpClient) error {
+		if err := c.WriteFile(dst+partialExt, src); err != nil {
+			return err
+		}
+		if err := c.Remove(dst); err != nil && !isSFTPNotExist(err) {
+			return err
+		}
+		return c.Rename(dst+partialExt, dst)
+	})
+}
+
+func (t *sftpTarget) Remove(name string) error {
+	return t.do(func(c *sftpClient) error { return c.Remove(path.Join(t.dir, name)) })
+}
+
+func (t *sftpTarget) Close() error {
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn, t.client = nil, nil
+	return err
+}
+
+// Replicate copies each archive still within a target's retention to that target when
+// it is missing there, and removes archives past the target's retention from it. Every
+// target is attempted; the error names those that failed, which are retried with
+// backoff while the scheduler runs.
+func (s *Service) Replicate() error {
+	s.replicateMu.Lock()
+	defer s.replicateMu.Unlock()
+
+	var failed []string
+	for _, r := range s.replicas {
+		if err := s.replicateTo(r, time.Now()); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", r.config.Name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("replication failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func (s *Service) replicateTo(r *replica, now time.Time) error {
+	defer r.target.Close()
+
+	s.mu.Lock()
+	reupload := r.reupload
+	s.mu.Unlock()
+
+	local, err := s.localArchives()
+	if err != nil {
+		return s.recordReplication(r, now, "", 0, err)
+	}
+	cutoff := now.AddDate(0, 0, -r.config.RetentionDays)
+	keep := make(map[string]bool)
+	for _, name := range local {
+		if ts, _, _ := parseBackupName(name); !ts.Before(cutoff) {
+			keep[name] = true
+		}
+	}
+
+	remote, err := r.target.List()
+	if err != nil {
+		return s.recordReplication(r, now, "", len(keep), err)
+	}
+	held := make(map[string]bool, len(remote))
+	for _, name := range remote {
+		held[name] = true
+	}
+	var wanted []string
+	for name := range keep {
+		if reupload || !held[name] {
+			wanted = append(wanted, name)
+		}
+	}
+
+	// Newest first, so the latest backup is off the machine soonest.
+	sort.Sort(sort.Reverse(sort.StringSlice(wanted)))
+	lastFile := ""
+	for i, name := range wanted {
+		if err := r.target.Upload(filepath.Join(s.config.BackupPath, name), name); err != nil {
+			return s.recordReplication(r, now, lastFile, len(wanted)-i, fmt.Errorf("upload %s: %w", name, err))
+		}
+		s.logInfo("Copied backup %s to %s", name, r.config.Name)
+		if lastFile == "" {
+			lastFile = name
+		}
+	}
+
+	// An archive is removed from the target once it is past the target's retention, or
+	// once it has been replaced by a copy under another name, such as a plain archive
+	// that has since been encrypted.
+	keys := make(map[string]bool, len(keep))
+	for name := range keep {
+		keys[catalogKey(name)] = true
+	}
+	var errs []error
+	for _, name := range remote {
+		if !isBackupName(name) || keep[name] {
+			continue
+		}
+		ts, _, ok := parseBackupName(name)
+		if !ok || (!ts.Before(cutoff) && !keys[catalogKey(name)]) {
+			continue
+		}
+		if err := r.target.Remove(name); err != nil {
+			errs = append(errs, fmt.Errorf("remove %s: %w", name, err))
+			continue
+		}
+		s.logInfo("Pruned backup %s from %s", name, r.config.Name)
+	}
+	return s.recordReplication(r, now, lastFile, 0, errors.Join(errs...))
+}
+
+// localArchives returns the archive names in the backup folder.
+func (s *Service) localArchives() ([]string, error) {
+	files, err := os.ReadDir(s.config.BackupPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && isBackupName(f.Name()) {
+			if _, _, ok := parseBackupName(f.Name()); ok {
+				names = append(names, f.Name())
+			}
+		}
+	}
+	return names, nil
+}
+
+// recordReplication stores the outcome of a run against r and returns err.
+func (s *Service) recordReplication(r *replica, now time.Time, lastFile string, pending int, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lastFile != "" {
+		r.status.LastUpload = now
+		r.status.LastFile = lastFile
+	}
+	r.status.Pending = pending
+	if err != nil {
+		r.status.Success = false
+		r.status.Message = err.Error()
+		r.status.Failures++
+		r.status.NextRetry = now.Add(retryDelay(r.status.Failures))
+		s.logError("Replication to %s failed (attempt %d): %v", r.config.Name, r.status.Failures, err)
+		return err
+	}
+	r.status.Success = true
+	r.status.Message = "Up to date"
+	r.status.Failures = 0
+	r.status.NextRetry = time.Time{}
+	r.reupload = false
+	return nil
+}
+
+func retryDelay(failures int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < failures && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// targetStatus returns a copy of each target's status. The caller holds s.mu.
+func (s *Service) targetStatus() []backup.TargetStatus {
+	statuses := make([]backup.TargetStatus, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		statuses = append(statuses, r.status)
+	}
+	return statuses
+}
+
+// nextRetry returns the earliest retry due among failed targets, or the zero time.
+func (s *Service) nextRetry() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next time.Time
+	for _, r := range s.replicas {
+		if !r.status.NextRetry.IsZero() && (next.IsZero() || r.status.NextRetry.Before(next)) {
+			next = r.status.NextRetry
+		}
+	}
+	return next
+}
+
+// requestReplication wakes the replication loop without waiting for it.
+func (s *Service) requestReplication() {
+	select {
+	case s.replicateNow <- struct{}{}:
+	default:
+	}
+}
+
+// runReplication copies archives to the targets when the scheduler starts, after each
+// backup, and when a failed target's retry falls due.
+func (s *Service) runReplication(stop <-chan struct{}) {
+	for {
+		_ = s.Replicate()
+
+		var retry <-chan time.Time
+		var timer *time.Timer
+		if next := s.nextRetry(); !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			retry = timer.C
+		}
+		select {
+		case <-s.replicateNow:
+		case <-retry:
+		case <-stop:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package backup
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"masala_inventory_managment/internal/domain/backup"
+)
+
+func TestReplicate_FolderTargetRetentionAndStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	backupDir := filepath.Join(tmpDir, "backups")
+	targetDir := filepath.Join(tmpDir, "offsite")
+	mkdirs(t, backupDir, targetDir)
+	now := time.Now()
+	recent := "backup-" + now.Add(-time.Hour).Format(backupTimestampLayout) + ".zip"
+	older := "backup-" + now.AddDate(0, 0, -5).Format(backupTimestampLayout) + ".zip"
+	createDummyFile(t, backupDir, recent)
+	createDummyFile(t, backupDir, older)
+	// Past the target's retention: removed from the target, left alone locally.
+	expired := "backup-" + now.AddDate(0, 0, -4).Format(backupTimestampLayout) + "-nightly.zip"
+	createDummyFile(t, targetDir, expired)
+
+	svc := NewService(nil, backup.BackupConfig{
+		BackupPath:    backupDir,
+		RetentionDays: 30,
+		KeyPath:       filepath.Join(tmpDir, "backup.key"),
+		Targets: []backup.TargetConfig{
+			{Name: "usb", Kind: backup.TargetLocal, Path: targetDir, RetentionDays: 3},
+		},
+	}, noOpLog, noOpLog)
+
+	if err := svc.Replicate(); err != nil {
+		t.Fatalf("Replicate failed: %v", err)
+	}
+	if got := dirNames(t, targetDir); strings.Join(got, ",") != recent {
+		t.Errorf("expected only %s on the target, got %v", recent, got)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, older)); err != nil {
+		t.Errorf("local archive outside the target's retention was touched: %v", err)
+	}
+
+	status, _ := svc.GetStatus()
+	if len(status.Targets) != 1 {
+		t.Fatalf("expected one target status, got %+v", status.Targets)
+	}
+	ts := status.Targets[0]
+	if !ts.Success || ts.LastFile != recent || ts.LastUpload.IsZero() || ts.Pending != 0 || ts.RetentionDays != 3 {
+		t.Errorf("unexpected target status: %+v", ts)
+	}
+}
+
+func TestReplicate_UnreachableShareRetriesWithBackoff(t *testing.T) {
+	tmpDir := t.TempDir()
+	backupDir := filepath.Join(tmpDir, "backups")
+	share := filepath.Join(tmpDir, "nas", "backups")
+	mkdirs(t, backupDir)
+	createDummyFile(t, backupDir, "backup-"+time.Now().Format(backupTimestampLayout)+".zip")
+
+	svc := NewService(nil, backup.BackupConfig{
+		BackupPath: backupDir,
+		KeyPath:    filepath.Join(tmpDir, "backup.key"),
+		Targets:    []backup.TargetConfig{{Name: "nas", Kind: backup.TargetSMB, Path: share}},
+	}, noOpLog, noOpLog)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+		if err := svc.Replicate(); err == nil {
+			t.Fatal("expected replication to an unmounted share to fail")
+		}
+		if _, err := os.Stat(share); !os.IsNotExist(err) {
+			t.Fatal("an SMB target must not create the share folder locally")
+		}
+		status, _ := svc.GetStatus()
+		ts := status.Targets[0]
+		if ts.Success || ts.Failures != attempt || ts.Pending != 1 {
+			t.Fatalf("attempt %d: unexpected status %+v", attempt, ts)
+		}
+		if wait := ts.NextRetry.Sub(before); wait < retryDelay(attempt) || wait > retryDelay(attempt)+time.Minute {
+			t.Errorf("attempt %d: retry in %v, want about %v", attempt, wait, retryDelay(attempt))
+		}
+	}
+
+	// The share comes back: the next run copies the archive and clears the failures.
+	if err := os.MkdirAll(share, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Replicate(); err != nil {
+		t.Fatalf("Replicate failed after the share returned: %v", err)
+	}
+	status, _ := svc.GetStatus()
+	if ts := status.Targets[0]; !ts.Success || ts.Failures != 0 || !ts.NextRetry.IsZero() {
+		t.Errorf("expected a clean status, got %+v", ts)
+	}
+	if len(dirNames(t, share)) != 1 {
+		t.Errorf("expected the archive on the share, got %v", dirNames(t, share))
+	}
+}
+
+func TestRetryDelay_DoublesUpToLimit(t *testing.T) {
+	cases := map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute, 7: time.Hour, 50: time.Hour}
+	for failures, want := range cases {
+		if got := retryDelay(failures); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestNewTarget_RejectsIncompleteConfig(t *testing.T) {
+	cases := []backup.TargetConfig{
+		{Name: "x", Kind: backup.TargetLocal},
+		{Name: "x", Kind: "ftp", Path: "/b"},
+		{Name: "x", Kind: backup.TargetSFTP, Path: "/b", Host: "h", User: "u", Password: "p"},
+		{Name: "x", Kind: backup.TargetSFTP, Path: "/b", Host: "h", User: "u", HostKey: "SHA256:abc"},
+	}
+	for _, config := range cases {
+		if _, err := NewTarget(config); err == nil {
+			t.Errorf("expected %+v to be rejected", config)
+		}
+	}
+	if err := ValidateTarget(backup.TargetConfig{Kind: backup.TargetLocal, Path: "/b"}); err == nil {
+		t.Error("expected a target without a name to be rejected")
+	}
+}
+
+func TestReplicate_SFTPTarget(t *testing.T) {
+	root := t.TempDir()
+	server := startSFTPServer(t, root, "backup", "s3cret")
+
+	tmpDir := t.TempDir()
+	backupDir := filepath.Join(tmpDir, "backups")
+	name := "backup-" + time.Now().Format(backupTimestampLayout) + ".zip"
+	mkdirs(t, backupDir, filepath.Join(root, "srv", "masala"))
+	// Larger than one write so the transfer is chunked.
+	payload := make([]byte, 3*sftpChunk+17)
+	rand.Read(payload)
+	if err := os.WriteFile(filepath.Join(backupDir, name), payload, 0644); err != nil {
+		t.Fatal(err)
+	}
+	expired := "backup-" + time.Now().AddDate(0, 0, -30).Format(backupTimestampLayout) + ".zip"
+	createDummyFile(t, filepath.Join(root, "srv", "masala"), expired)
+
+	target := backup.TargetConfig{
+		Name: "offsite", Kind: backup.TargetSFTP, Path: "/srv/masala",
+		Host: server.host, Port: server.port, User: "backup", Password: "s3cret",
+		HostKey: server.fingerprint, RetentionDays: 14,
+	}
+	svc := NewService(nil, backup.BackupConfig{
+		BackupPath: backupDir,
+		KeyPath:    filepath.Join(tmpDir, "backup.key"),
+		Targets:    []backup.TargetConfig{target},
+	}, noOpLog, noOpLog)
+
+	if err := svc.Replicate(); err != nil {
+		t.Fatalf("Replicate failed: %v", err)
+	}
+	remoteDir := filepath.Join(root, "srv", "masala")
+	if got := dirNames(t, remoteDir); strings.Join(got, ",") != name {
+		t.Fatalf("expected only %s on the server, got %v", name, got)
+	}
+	got, err := os.ReadFile(filepath.Join(remoteDir, name))
+	if err != nil || string(got) != string(payload) {
+		t.Fatalf("uploaded copy differs from the archive (err %v)", err)
+	}
+
+	// A second run finds the archive already there.
+	if err := svc.Replicate(); err != nil {
+		t.Fatalf("second Replicate failed: %v", err)
+	}
+	status, _ := svc.GetStatus()
+	if ts := status.Targets[0]; !ts.Success || ts.LastFile != name {
+		t.Errorf("unexpected status: %+v", ts)
+	}
+
+	// A server presenting another key is refused.
+	target.Name, target.HostKey = "impostor", "SHA256:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+	other := NewService(nil, backup.BackupConfig{
+		BackupPath: backupDir,
+		KeyPath:    filepath.Join(tmpDir, "backup.key"),
+		Targets:    []backup.TargetConfig{target},
+	}, noOpLog, noOpLog)
+	if err := other.Replicate(); err == nil || !strings.Contains(err.Error(), "host key") {
+		t.Errorf("expected a host key mismatch, got %v", err)
+	}
+}
+
+func mkdirs(t *testing.T, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sftpStandIn is an SSH server on localhost that serves root over the sftp subsystem.
+// Remote paths are taken relative to root.
+type sftpStandIn struct {
+	host        string
+	port        int
+	fingerprint string
+}
+
+func startSFTPServer(t *testing.T, root, user, password string) sftpStandIn {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if meta.User() == user && string(pass) == password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("access denied")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config, root)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return sftpStandIn{host: addr.IP.String(), port: addr.Port, fingerprint: ssh.FingerprintSHA256(signer.PublicKey())}
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig, root string) {
+	defer conn.Close()
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && len(req.Payload) >= 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					go func() {
+						serveSFTP(channel, root)
+						channel.Close()
+					}()
+				}
+			}
+		}()
+	}
+}
+
+// serveSFTP answers the requests sftpClient makes, enough to stand in for a server.
+func serveSFTP(rw io.ReadWriter, root string) {
+	local := func(p string) string { return filepath.Join(root, filepath.FromSlash(p)) }
+	files := map[string]*os.File{}
+	dirs := map[string][]string{}
+	nextHandle := 0
+
+	reply := func(kind byte, id uint32, build func(*sftpBuffer)) {
+		var b sftpBuffer
+		b.byte(kind)
+		b.uint32(id)
+		build(&b)
+		packet := binary.BigEndian.AppendUint32(nil, uint32(len(b)))
+		rw.Write(append(packet, b...))
+	}
+	status := func(id uint32, err error) {
+		code := uint32(sftpOK)
+		message := ""
+		switch {
+		case os.IsNotExist(err):
+			code, message = sftpNoSuchFile, err.Error()
+		case err == io.EOF:
+			code = sftpEOF
+		case err != nil:
+			code, message = 4, err.Error()
+		}
+		reply(sftpStatus, id, func(b *sftpBuffer) {
+			b.uint32(code)
+			b.string(message)
+			b.string("")
+		})
+	}
+	handle := func(id uint32) string {
+		nextHandle++
+		h := strconv.Itoa(nextHandle)
+		reply(sftpHandle, id, func(b *sftpBuffer) { b.string(h) })
+		return h
+	}
+
+	for {
+		kind, payload, err := readSFTPPacket(rw)
+		if err != nil {
+			return
+		}
+		if kind == sftpInit {
+			var b sftpBuffer
+			b.byte(sftpVersion)
+			b.uint32(sftpProtocol)
+			rw.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(b))), b...))
+			continue
+		}
+		id, p, _ := takeUint32(payload)
+		first, p, _ := takeString(p)
+		switch kind {
+		case sftpOpen:
+			f, err := os.OpenFile(local(first), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				status(id, err)
+				continue
+			}
+			files[handle(id)] = f
+		case sftpWrite:
+			offset := binary.BigEndian.Uint64(p)
+			data, _, _ := takeString(p[8:])
+			_, err := files[first].WriteAt([]byte(data), int64(offset))
+			status(id, err)
+		case sftpClose:
+			var err error
+			if f, ok := files[first]; ok {
+				err = f.Close()
+				delete(files, first)
+			}
+			delete(dirs, first)
+			status(id, err)
+		case sftpOpenDir:
+			entries, err := os.ReadDir(local(first))
+			if err != nil {
+				status(id, err)
+				continue
+			}
+			names := []string{".", ".."}
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			dirs[handle(id)] = names
+		case sftpReadDir:
+			names := dirs[first]
+			if len(names) == 0 {
+				status(id, io.EOF)
+				continue
+			}
+			// Two per reply, so the client has to keep reading.
+			batch := names[:min(2, len(names))]
+			dirs[first] = names[len(batch):]
+			reply(sftpName, id, func(b *sftpBuffer) {
+				b.uint32(uint32(len(batch)))
+				for _, name := range batch {
+					b.string(name)
+					b.string("-rw-r--r-- 1 backup backup " + name)
+					b.uint32(sftpAttrSize | sftpAttrPermissions)
+					b.uint64(0)
+					b.uint32(0644)
+				}
+			})
+		case sftpRemove:
+			status(id, os.Remove(local(first)))
+		case sftpRename:
+			to, _, _ := takeString(p)
+			if _, err := os.Stat(local(to)); err == nil {
+				status(id, fmt.Errorf("target exists"))
+				continue
+			}
+			status(id, os.Rename(local(first), local(to)))
+		case sftpMkdir:
+			status(id, os.Mkdir(local(first), 0755))
+		case sftpStat:
+			if _, err := os.Stat(local(first)); err != nil {
+				status(id, err)
+				continue
+			}
+			reply(sftpAttrs, id, func(b *sftpBuffer) { b.uint32(0) })
+		default:
+			status(id, fmt.Errorf("unsupported request %d", kind))
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCode128Patterns_AreWellFormed(t *testing.T) {
+	seen := map[string]int{}
+	for value, pattern := range code128Patterns {
+		modules, bars := 0, 0
+		for i, width := range pattern {
+			modules += int(width - '0')
+			if i%2 == 0 {
+				bars += int(width - '0')
+			}
+		}
+		want := 11
+		if value == code128Stop {
+			want = 13
+		}
+		if modules != want || bars%2 != 0 {
+			t.Fatalf("pattern %d %q has %d modules and %d bar modules", value, pattern, modules, bars)
+		}
+		if previous, ok := seen[pattern[:6]]; ok {
+			t.Fatalf("patterns %d and %d are the same", previous, value)
+		}
+		seen[pattern[:6]] = value
+	}
+}
+
+func TestCode128_RoundTripsAndPacksDigits(t *testing.T) {
+	for _, data := range []string{"LOT-20260101-001", "1234", "123", "OB-20260101-002", "a b~", "LOT-20261231-1234"} {
+		modules, err := Code128(data)
+		if err != nil {
+			t.Fatalf("Code128(%q) failed: %v", data, err)
+		}
+		if !modules[0] || !modules[len(modules)-1] {
+			t.Fatalf("expected %q to start and end with a bar", data)
+		}
+		if got := decodeCode128(t, modules); got != data {
+			t.Fatalf("expected %q to decode back, got %q", data, got)
+		}
+	}
+
+	lot, _ := Code128("LOT-20260101-001")
+	plain, _ := Code128("LOT-ABCDEFGH-001")
+	if len(lot) >= len(plain) {
+		t.Fatalf("expected the date digits to be packed, got %d modules against %d", len(lot), len(plain))
+	}
+
+	if _, err := Code128("LOT\n1"); err != ErrUnsupportedCharacter {
+		t.Fatalf("expected control characters to be refused, got %v", err)
+	}
+	if _, err := Code128(""); err == nil {
+		t.Fatalf("expected empty data to be refused")
+	}
+}
+
+// decodeCode128 reads the symbol values back from the modules, checks the checksum and
+// returns the text, so the encoder is checked against the pattern table rather than itself.
+func decodeCode128(t *testing.T, modules []bool) string {
+	t.Helper()
+	lookup := map[string]int{}
+	for value, pattern := range code128Patterns {
+		lookup[pattern] = value
+	}
+	var values []int
+	for i := 0; i < len(modules); {
+		var widths strings.Builder
+		width := 0
+		for j := i; j < len(modules) && widths.Len() < 7; j++ {
+			width++
+			if j+1 == len(modules) || modules[j+1] != modules[j] {
+				widths.WriteByte(byte('0' + width))
+				width = 0
+				if widths.Len() == 6 {
+					if value, ok := lookup[widths.String()]; ok && value != code128Stop {
+						values = append(values, value)
+						i = j + 1
+						break
+					}
+				}
+				if widths.Len() == 7 {
+					if lookup[widths.String()] != code128Stop || j+1 != len(modules) {
+						t.Fatalf("unexpected symbol %q at module %d", widths.String(), i)
+					}
+					i = len(modules)
+				}
+			}
+		}
+	}
+
+	checksum := values[0]
+	for i := 1; i < len(values)-1; i++ {
+		checksum += i * values[i]
+	}
+	if checksum%103 != values[len(values)-1] {
+		t.Fatalf("checksum mismatch")
+	}
+
+	var out strings.Builder
+	codeC := values[0] == code128StartC
+	for _, value := range values[1 : len(values)-1] {
+		switch {
+		case value == code128ShiftC:
+			codeC = true
+		case value == code128ShiftB:
+			codeC = false
+		case codeC:
+			out.WriteByte(byte('0' + value/10))
+			out.WriteByte(byte('0' + value%10))
+		default:
+			out.WriteByte(byte(value + 32))
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,398 @@
+package barcode
+
+import "errors"
+
+var ErrDataTooLong = errors.New("data is too long for a QR code")
+
+// qrVersions lists, for versions 1 to 6 at error correction level M, the number of blocks,
+// data codewords per block and error correction codewords per block. Every block of these
+// versions has the same length, and none needs version information, which keeps the
+// encoder small; 106 bytes is plenty for a lot number.
+var qrVersions = [...]struct {
+	blocks, data, ec int
+}{
+	{1, 16, 10},
+	{1, 28, 16},
+	{1, 44, 26},
+	{2, 32, 18},
+	{2, 43, 24},
+	{4, 27, 16},
+}
+
+// qrFormatLevelM is the two error correction level bits of the format information.
+const qrFormatLevelM = 0
+
+// QRCode is an encoded QR symbol. Callers add the quiet zone of four modules.
+type QRCode struct {
+	Version int
+	Size    int
+	Mask    int
+	modules [][]bool
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (q *QRCode) Dark(x, y int) bool {
+	return q.modules[y][x]
+}
+
+// QR encodes data in byte mode at error correction level M, choosing the smallest version
+// that fits and the mask with the lowest penalty.
+func QR(data []byte) (*QRCode, error) {
+	if len(data) == 0 {
+		return nil, errors.New("barcode data is required")
+	}
+	version := 0
+	for i, v := range qrVersions {
+		// 4 bits of mode and 8 bits of length precede the data.
+		if 12+8*len(data) <= 8*v.blocks*v.data {
+			version = i + 1
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrDataTooLong
+	}
+
+	codewords := qrCodewords(data, version)
+	var best *qrBuilder
+	bestPenalty := 0
+	for mask := 0; mask < 8; mask++ {
+		code := newQRCode(version)
+		code.drawCodewords(codewords)
+		code.applyMask(mask)
+		code.drawFormat(mask)
+		if penalty := code.penalty(); best == nil || penalty < bestPenalty {
+			best, bestPenalty = code, penalty
+		}
+	}
+	return best.QRCode, nil
+}
+
+// qrCodewords builds the data codewords, adds error correction to each block and
+// interleaves the blocks.
+func qrCodewords(data []byte, version int) []byte {
+	v := qrVersions[version-1]
+	capacity := v.blocks * v.data * 8
+
+	bits := make([]bool, 0, capacity)
+	appendBits := func(value, count int) {
+		for i := count - 1; i >= 0; i-- {
+			bits = append(bits, value>>uint(i)&1 == 1)
+		}
+	}
+	appendBits(0x4, 4)
+	appendBits(len(data), 8)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+
+	dataCodewords := make([]byte, 0, capacity/8)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 0x80 >> uint(j)
+			}
+		}
+		dataCodewords = append(dataCodewords, b)
+	}
+	for pad := byte(0xEC); len(dataCodewords) < capacity/8; pad ^= 0xEC ^ 0x11 {
+		dataCodewords = append(dataCodewords, pad)
+	}
+
+	divisor := reedSolomonDivisor(v.ec)
+	blocks := make([][]byte, v.blocks)
+	ecBlocks := make([][]byte, v.blocks)
+	for i := range blocks {
+		blocks[i] = dataCodewords[i*v.data : (i+1)*v.data]
+		ecBlocks[i] = reedSolomonRemainder(blocks[i], divisor)
+	}
+
+	out := make([]byte, 0, v.blocks*(v.data+v.ec))
+	for i := 0; i < v.data; i++ {
+		for _, block := range blocks {
+			out = append(out, block[i])
+		}
+	}
+	for i := 0; i < v.ec; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+type qrBuilder struct {
+	*QRCode
+	function [][]bool
+}
+
+func newQRCode(version int) *qrBuilder {
+	size := 17 + 4*version
+	b := &qrBuilder{
+		QRCode:   &QRCode{Version: version, Size: size},
+		function: make([][]bool, size),
+	}
+	b.modules = make([][]bool, size)
+	for i := range b.modules {
+		b.modules[i] = make([]bool, size)
+		b.function[i] = make([]bool, size)
+	}
+
+	for i := 0; i < size; i++ {
+		b.set(6, i, i%2 == 0)
+		b.set(i, 6, i%2 == 0)
+	}
+	b.drawFinder(3, 3)
+	b.drawFinder(size-4, 3)
+	b.drawFinder(3, size-4)
+	if version > 1 {
+		b.drawAlignment(size-7, size-7)
+	}
+	// Reserve the format areas so data skips them; drawFormat fills them in.
+	b.drawFormat(0)
+	return b
+}
+
+func (b *qrBuilder) set(x, y int, dark bool) {
+	b.modules[y][x] = dark
+	b.function[y][x] = true
+}
+
+func (b *qrBuilder) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= b.Size || y < 0 || y >= b.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			b.set(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+func (b *qrBuilder) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			b.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormat writes both copies of the 15-bit format information and the dark module.
+func (b *qrBuilder) drawFormat(mask int) {
+	bits := qrFormatBits(mask)
+	bit := func(i int) bool { return bits>>uint(i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		b.set(8, i, bit(i))
+	}
+	b.set(8, 7, bit(6))
+	b.set(8, 8, bit(7))
+	b.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		b.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		b.set(b.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		b.set(8, b.Size-15+i, bit(i))
+	}
+	b.set(8, b.Size-8, true)
+}
+
+// qrFormatBits returns the BCH-protected, masked format information for level M.
+func qrFormatBits(mask int) int {
+	data := qrFormatLevelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawCodewords places the codewords in the zigzag order of the specification, two columns
+// at a time from the bottom right, skipping function modules.
+func (b *qrBuilder) drawCodewords(codewords []byte) {
+	i := 0
+	for right := b.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < b.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = b.Size - 1 - vert
+				}
+				if b.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				b.modules[y][x] = codewords[i>>3]>>uint(7-i&7)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+func (b *qrBuilder) applyMask(mask int) {
+	for y := 0; y < b.Size; y++ {
+		for x := 0; x < b.Size; x++ {
+			if b.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				b.modules[y][x] = !b.modules[y][x]
+			}
+		}
+	}
+	b.Mask = mask
+}
+
+// penalty scores the symbol with the four rules of the specification; lower is easier to
+// scan.
+func (b *qrBuilder) penalty() int {
+	size := b.Size
+	score := 0
+	finderLike := func(line []bool, i int) bool {
+		pattern := [...]bool{true, false, true, true, true, false, true}
+		for k, want := range pattern {
+			if line[i+k] != want {
+				return false
+			}
+		}
+		light := func(from, to int) bool {
+			for k := from; k < to; k++ {
+				if k >= 0 && k < len(line) && line[k] {
+					return false
+				}
+			}
+			return true
+		}
+		return light(i-4, i) || light(i+7, i+11)
+	}
+
+	for pass := 0; pass < 2; pass++ {
+		for a := 0; a < size; a++ {
+			line := make([]bool, size)
+			for c := 0; c < size; c++ {
+				if pass == 0 {
+					line[c] = b.modules[a][c]
+				} else {
+					line[c] = b.modules[c][a]
+				}
+			}
+			run := 1
+			for c := 1; c <= size; c++ {
+				if c < size && line[c] == line[c-1] {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			for c := 0; c+7 <= size; c++ {
+				if finderLike(line, c) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if b.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				c := b.modules[y][x]
+				if b.modules[y][x+1] == c && b.modules[y+1][x] == c && b.modules[y+1][x+1] == c {
+					score += 3
+				}
+			}
+		}
+	}
+	percent := dark * 100 / (size * size)
+	score += abs(percent-50) / 5 * 10
+	return score
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree, highest
+// coefficient first and the leading 1 omitted.
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>uint(i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
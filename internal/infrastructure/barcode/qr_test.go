@@ -0,0 +1,149 @@
+package barcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQRFormatBits_MatchSpecification(t *testing.T) {
+	// Level M with mask 0 is 101010000010010 in the format information table.
+	if got := qrFormatBits(0); got != 0b101010000010010 {
+		t.Fatalf("unexpected format bits %015b", got)
+	}
+}
+
+func TestReedSolomon_MatchesPublishedExample(t *testing.T) {
+	// "HELLO WORLD" as a 1-M symbol.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(got, want) {
+		t.Fatalf("unexpected error correction codewords %v", got)
+	}
+}
+
+func TestQR_EncodesReadableSymbols(t *testing.T) {
+	for _, data := range []string{"LOT-20260101-001", strings.Repeat("A", 40), strings.Repeat("x", 106)} {
+		code, err := QR([]byte(data))
+		if err != nil {
+			t.Fatalf("QR(%d bytes) failed: %v", len(data), err)
+		}
+		if code.Size != 17+4*code.Version {
+			t.Fatalf("unexpected size %d for version %d", code.Size, code.Version)
+		}
+		if got := readQR(t, code); got != data {
+			t.Fatalf("expected %q to read back, got %q", data, got)
+		}
+	}
+
+	if code, _ := QR([]byte("LOT-20260101-001")); code.Version != 2 {
+		t.Fatalf("expected a lot number to fit version 2, got %d", code.Version)
+	}
+	if _, err := QR(bytes.Repeat([]byte("x"), 107)); err != ErrDataTooLong {
+		t.Fatalf("expected data beyond version 6 to be refused, got %v", err)
+	}
+}
+
+// readQR decodes a symbol the way a scanner would: it reads the mask from the format
+// information, unmasks the data modules, checks every block's error correction and parses
+// the byte-mode payload.
+func readQR(t *testing.T, code *QRCode) string {
+	t.Helper()
+	layout := newQRCode(code.Version)
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 7; x++ {
+			if code.Dark(x, y) != code.Dark(code.Size-7+x, y) || code.Dark(x, y) != code.Dark(x, code.Size-7+y) {
+				t.Fatalf("finder patterns differ at %d,%d", x, y)
+			}
+		}
+	}
+
+	format := 0
+	for i := 14; i >= 9; i-- {
+		format = format<<1 | boolBit(code.Dark(14-i, 8))
+	}
+	format = format<<1 | boolBit(code.Dark(7, 8))
+	format = format<<1 | boolBit(code.Dark(8, 8))
+	format = format<<1 | boolBit(code.Dark(8, 7))
+	for i := 5; i >= 0; i-- {
+		format = format<<1 | boolBit(code.Dark(8, i))
+	}
+	mask := (format ^ 0x5412) >> 10 & 7
+	if qrFormatBits(mask) != format || mask != code.Mask {
+		t.Fatalf("format information %015b does not decode", format)
+	}
+
+	unmasked := &qrBuilder{QRCode: &QRCode{Version: code.Version, Size: code.Size, modules: make([][]bool, code.Size)}, function: layout.function}
+	for y := range unmasked.modules {
+		unmasked.modules[y] = make([]bool, code.Size)
+		for x := range unmasked.modules[y] {
+			unmasked.modules[y][x] = code.Dark(x, y)
+		}
+	}
+	unmasked.applyMask(mask)
+
+	v := qrVersions[code.Version-1]
+	raw := make([]byte, v.blocks*(v.data+v.ec))
+	i := 0
+	for right := code.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < code.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = code.Size - 1 - vert
+				}
+				if layout.function[y][x] || i >= len(raw)*8 {
+					continue
+				}
+				if unmasked.modules[y][x] {
+					raw[i>>3] |= 0x80 >> uint(i&7)
+				}
+				i++
+			}
+		}
+	}
+
+	var payload []byte
+	for b := 0; b < v.blocks; b++ {
+		block := make([]byte, 0, v.data+v.ec)
+		for k := 0; k < v.data; k++ {
+			block = append(block, raw[k*v.blocks+b])
+		}
+		for k := 0; k < v.ec; k++ {
+			block = append(block, raw[v.data*v.blocks+k*v.blocks+b])
+		}
+		// Every root of the generator must be a root of the received codeword.
+		root := byte(1)
+		for k := 0; k < v.ec; k++ {
+			var syndrome byte
+			for _, c := range block {
+				syndrome = gfMultiply(syndrome, root) ^ c
+			}
+			if syndrome != 0 {
+				t.Fatalf("block %d has a non-zero syndrome", b)
+			}
+			root = gfMultiply(root, 0x02)
+		}
+		payload = append(payload, block[:v.data]...)
+	}
+
+	if payload[0]>>4 != 0x4 {
+		t.Fatalf("expected byte mode, got %x", payload[0]>>4)
+	}
+	length := int(payload[0]&0x0F)<<4 | int(payload[1]>>4)
+	out := make([]byte, length)
+	for k := range out {
+		out[k] = payload[1+k]<<4 | payload[2+k]>>4
+	}
+	return string(out)
+}
+
+func boolBit(dark bool) int {
+	if dark {
+		return 1
+	}
+	return 0
+}
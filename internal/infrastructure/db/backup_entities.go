@@ -0,0 +1,378 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+	domainBackup "masala_inventory_managment/internal/domain/backup"
+)
+
+// restoreSchema is the name a backup copy is attached under while records are restored.
+const restoreSchema = "restore_source"
+
+// restorableEntity is a record that can be copied out of a backup on its own: a row and
+// the child rows that belong to it.
+type restorableEntity struct {
+	kind     string
+	table    string
+	labels   []string
+	children []auditChild
+}
+
+// restorableEntities is in restore order, so an item is back before a recipe or
+// packaging profile that uses it.
+var restorableEntities = []restorableEntity{
+	{kind: domainBackup.EntityParty, table: "parties", labels: []string{"name"}},
+	{
+		kind:   domainBackup.EntityItem,
+		table:  "items",
+		labels: []string{"sku", "name"},
+		children: []auditChild{
+			{table: "raw_item_details", foreignKey: "item_id"},
+			{table: "bulk_powder_item_details", foreignKey: "item_id"},
+			{table: "packing_material_item_details", foreignKey: "item_id"},
+			{table: "finished_good_item_details", foreignKey: "item_id"},
+			{table: "unit_conversions", foreignKey: "item_id"},
+		},
+	},
+	{
+		kind:     domainBackup.EntityPackagingProfile,
+		table:    "packaging_profiles",
+		labels:   []string{"name"},
+		children: []auditChild{{table: "packaging_profile_components", foreignKey: "profile_id"}},
+	},
+	{
+		kind:     domainBackup.EntityRecipe,
+		table:    "recipes",
+		labels:   []string{"recipe_code"},
+		children: []auditChild{{table: "recipe_components", foreignKey: "recipe_id"}},
+	},
+}
+
+func restorableEntityByKind(kind string) (restorableEntity, int, bool) {
+	for i, entity := range restorableEntities {
+		if entity.kind == kind {
+			return entity, i, true
+		}
+	}
+	return restorableEntity{}, 0, false
+}
+
+// DiffEntities lists the restorable records that differ between a backup copy and the
+// live database, including their child rows: records deleted since the backup, changed
+// since, and added since.
+func DiffEntities(backupDB, liveDB *sql.DB) ([]domainBackup.EntityDiff, error) {
+	var diffs []domainBackup.EntityDiff
+	for _, entity := range restorableEntities {
+		// A backup from before a table existed has nothing of that kind to restore.
+		present, err := tablesExist(entity, backupDB, liveDB)
+		if err != nil {
+			return nil, err
+		}
+		if !present {
+			continue
+		}
+		before, err := loadEntities(backupDB, "", entity, 0)
+		if err != nil {
+			return nil, fmt.Errorf("read %s from backup: %w", entity.table, err)
+		}
+		live, err := loadEntities(liveDB, "", entity, 0)
+		if err != nil {
+			return nil, fmt.Errorf("read live %s: %w", entity.table, err)
+		}
+
+		ids := make([]int64, 0, len(before)+len(live))
+		for id := range before {
+			ids = append(ids, id)
+		}
+		for id := range live {
+			if _, ok := before[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		for _, id := range ids {
+			old, inBackup := before[id]
+			current, inLive := live[id]
+			diff := domainBackup.EntityDiff{Kind: entity.kind, ID: id}
+			switch {
+			case !inLive:
+				diff.Change, diff.Label = domainBackup.EntityDeleted, entityLabel(entity, old)
+			case !inBackup:
+				diff.Change, diff.Label = domainBackup.EntityAdded, entityLabel(entity, current)
+			default:
+				same, err := sameSnapshot(old, current)
+				if err != nil {
+					return nil, err
+				}
+				if same {
+					continue
+				}
+				diff.Change, diff.Label = domainBackup.EntityChanged, entityLabel(entity, current)
+			}
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+// RestoreEntities copies the chosen records, with their child rows, from the database
+// file at backupPath into liveDB in one transaction. A record that still exists is put
+// back as it was in the backup; one deleted since is recreated under its old ID. Stock
+// movements and other records are left alone. Each record restored is written to the
+// audit log under actor.
+func RestoreEntities(liveDB *sql.DB, backupPath string, refs []domainBackup.EntityRef, actor string) error {
+	if len(refs) == 0 {
+		return fmt.Errorf("no records chosen to restore")
+	}
+	refs = append([]domainBackup.EntityRef(nil), refs...)
+	for _, ref := range refs {
+		if _, _, ok := restorableEntityByKind(ref.Kind); !ok {
+			return fmt.Errorf("%q records cannot be restored on their own", ref.Kind)
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		_, a, _ := restorableEntityByKind(refs[i].Kind)
+		_, b, _ := restorableEntityByKind(refs[j].Kind)
+		return a < b
+	})
+
+	ctx := context.Background()
+	// ATTACH applies to one connection and cannot run inside a transaction.
+	conn, err := liveDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS "+restoreSchema, "file:"+filepath.ToSlash(backupPath)+"?mode=ro"); err != nil {
+		return fmt.Errorf("failed to open backup copy: %w", err)
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE "+restoreSchema)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	scope := auditScope{actor: actor}
+	for _, ref := range refs {
+		entity, _, _ := restorableEntityByKind(ref.Kind)
+		if err := restoreEntityTx(tx, scope, entity, ref.ID); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("restore %s %d: %w", ref.Kind, ref.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func restoreEntityTx(tx *sql.Tx, scope auditScope, entity restorableEntity, id int64) error {
+	ctx := context.Background()
+	var found int
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s.%s WHERE id = ?", restoreSchema, entity.table), id).Scan(&found); err != nil {
+		return err
+	}
+	if found == 0 {
+		return fmt.Errorf("not in the backup")
+	}
+	before, err := entitySnapshotJSON(tx, entity, id)
+	if err != nil {
+		return err
+	}
+
+	columns, err := sharedColumns(tx, entity.table)
+	if err != nil {
+		return err
+	}
+	list := strings.Join(columns, ", ")
+	updates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if column != "id" {
+			updates = append(updates, column+" = excluded."+column)
+		}
+	}
+	// An upsert rather than INSERT OR REPLACE: replacing deletes the row first, which
+	// would cascade to rows elsewhere that reference it.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO main.%[1]s (%[2]s) SELECT %[2]s FROM %[3]s.%[1]s WHERE id = ? ON CONFLICT(id) DO UPDATE SET %[4]s",
+		entity.table, list, restoreSchema, strings.Join(updates, ", "),
+	), id); err != nil {
+		return err
+	}
+
+	for _, child := range entity.children {
+		columns, err := sharedColumns(tx, child.table)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM main.%s WHERE %s = ?", child.table, child.foreignKey), id); err != nil {
+			return err
+		}
+		list := strings.Join(columns, ", ")
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			"INSERT INTO main.%[1]s (%[2]s) SELECT %[2]s FROM %[3]s.%[1]s WHERE %[4]s = ?",
+			child.table, list, restoreSchema, child.foreignKey,
+		), id); err != nil {
+			return err
+		}
+	}
+
+	after, err := entitySnapshotJSON(tx, entity, id)
+	if err != nil {
+		return err
+	}
+	return insertAuditTx(tx, scope, domainAudit.ActionRestore, entity.kind, strconv.FormatInt(id, 10), before, after)
+}
+
+// sharedColumns returns the columns a table has both live and in the attached backup.
+func sharedColumns(tx *sql.Tx, table string) ([]string, error) {
+	live, err := tableColumns(tx, "main", table)
+	if err != nil {
+		return nil, err
+	}
+	backup, err := tableColumns(tx, restoreSchema, table)
+	if err != nil {
+		return nil, err
+	}
+	inBackup := make(map[string]bool, len(backup))
+	for _, column := range backup {
+		inBackup[column] = true
+	}
+	var shared []string
+	for _, column := range live {
+		if inBackup[column] {
+			shared = append(shared, column)
+		}
+	}
+	if len(shared) == 0 {
+		return nil, fmt.Errorf("table %s is missing from the backup", table)
+	}
+	return shared, nil
+}
+
+func tableColumns(q rowQueryer, schema, table string) ([]string, error) {
+	rows, err := queryRowMaps(q, fmt.Sprintf("PRAGMA %s.table_info(%s)", schema, table))
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if name, ok := row["name"].(string); ok {
+			columns = append(columns, name)
+		}
+	}
+	return columns, nil
+}
+
+// tablesExist reports whether an entity's tables are in every database.
+func tablesExist(entity restorableEntity, dbs ...*sql.DB) (bool, error) {
+	tables := []string{entity.table}
+	for _, child := range entity.children {
+		tables = append(tables, child.table)
+	}
+	for _, conn := range dbs {
+		for _, table := range tables {
+			var n int
+			if err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n); err != nil {
+				return false, err
+			}
+			if n == 0 {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// loadEntities returns snapshots of one record, or of every record when id is 0, keyed
+// by ID. Child rows are listed under their table's name, as in audit snapshots.
+func loadEntities(q rowQueryer, schema string, entity restorableEntity, id int64) (map[int64]map[string]interface{}, error) {
+	prefix := ""
+	if schema != "" {
+		prefix = schema + "."
+	}
+	filter := func(column string) (string, []interface{}) {
+		if id == 0 {
+			return "", nil
+		}
+		return " WHERE " + column + " = ?", []interface{}{id}
+	}
+
+	where, args := filter("id")
+	rows, err := queryRowMaps(q, "SELECT * FROM "+prefix+entity.table+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	entities := make(map[int64]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		key, ok := row["id"].(int64)
+		if !ok {
+			continue
+		}
+		for _, child := range entity.children {
+			row[child.table] = []map[string]interface{}{}
+		}
+		entities[key] = row
+	}
+
+	for _, child := range entity.children {
+		where, args := filter(child.foreignKey)
+		childRows, err := queryRowMaps(q, "SELECT * FROM "+prefix+child.table+where+" ORDER BY rowid", args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, childRow := range childRows {
+			parentID, ok := childRow[child.foreignKey].(int64)
+			if !ok {
+				continue
+			}
+			if parent, ok := entities[parentID]; ok {
+				parent[child.table] = append(parent[child.table].([]map[string]interface{}), childRow)
+			}
+		}
+	}
+	return entities, nil
+}
+
+// entitySnapshotJSON returns the audit snapshot of a live record, or "" when it does not
+// exist.
+func entitySnapshotJSON(tx *sql.Tx, entity restorableEntity, id int64) (string, error) {
+	entities, err := loadEntities(tx, "main", entity, id)
+	if err != nil {
+		return "", err
+	}
+	snapshot, ok := entities[id]
+	if !ok {
+		return "", nil
+	}
+	encoded, err := json.Marshal(snapshot)
+	return string(encoded), err
+}
+
+func sameSnapshot(a, b map[string]interface{}) (bool, error) {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return string(left) == string(right), nil
+}
+
+func entityLabel(entity restorableEntity, row map[string]interface{}) string {
+	parts := make([]string, 0, len(entity.labels))
+	for _, column := range entity.labels {
+		if value, ok := row[column].(string); ok && value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
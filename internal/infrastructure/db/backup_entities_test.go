@@ -0,0 +1,158 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+	domainBackup "masala_inventory_managment/internal/domain/backup"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+)
+
+func TestRestoreEntities_BringsBackChosenRecordsOnly(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	live := manager.GetDB()
+
+	supplier := &domainInventory.Party{PartyType: domainInventory.PartyTypeSupplier, Name: "Kerala Spice Traders", Phone: "0484-2370001", IsActive: true}
+	if err := repo.CreateParty(supplier); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+	item := &domainInventory.Item{SKU: "RAW-PEP-1", Name: "Black Pepper", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true}
+	if err := repo.CreateItem(item); err != nil {
+		t.Fatalf("CreateItem failed: %v", err)
+	}
+
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	if _, err := live.Exec("VACUUM INTO ?", backupPath); err != nil {
+		t.Fatalf("VACUUM INTO failed: %v", err)
+	}
+
+	// After the backup: the supplier is deleted, the item is renamed and loses its
+	// details, and a customer is added.
+	mustExec(t, live, "DELETE FROM parties WHERE id = ?", supplier.ID)
+	mustExec(t, live, "UPDATE items SET name = 'Pepper (old stock)' WHERE id = ?", item.ID)
+	mustExec(t, live, "DELETE FROM raw_item_details WHERE item_id = ?", item.ID)
+	customer := &domainInventory.Party{PartyType: domainInventory.PartyTypeCustomer, Name: "Hotel Saravana", Phone: "044-24345555", IsActive: true}
+	if err := repo.CreateParty(customer); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+
+	backupDB, err := sql.Open("sqlite3", "file:"+filepath.ToSlash(backupPath)+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backupDB.Close()
+	diffs, err := DiffEntities(backupDB, live)
+	if err != nil {
+		t.Fatalf("DiffEntities failed: %v", err)
+	}
+	want := map[domainBackup.EntityRef]string{
+		{Kind: domainBackup.EntityParty, ID: supplier.ID}: domainBackup.EntityDeleted,
+		{Kind: domainBackup.EntityParty, ID: customer.ID}: domainBackup.EntityAdded,
+		{Kind: domainBackup.EntityItem, ID: item.ID}:      domainBackup.EntityChanged,
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %d differences, got %+v", len(want), diffs)
+	}
+	for _, diff := range diffs {
+		if change := want[domainBackup.EntityRef{Kind: diff.Kind, ID: diff.ID}]; change != diff.Change {
+			t.Errorf("unexpected difference %+v", diff)
+		}
+	}
+
+	err = RestoreEntities(live, backupPath, []domainBackup.EntityRef{
+		{Kind: domainBackup.EntityItem, ID: item.ID},
+		{Kind: domainBackup.EntityParty, ID: supplier.ID},
+	}, "admin")
+	if err != nil {
+		t.Fatalf("RestoreEntities failed: %v", err)
+	}
+
+	var name string
+	if err := live.QueryRow("SELECT name FROM parties WHERE id = ?", supplier.ID).Scan(&name); err != nil || name != supplier.Name {
+		t.Errorf("supplier not restored: %q, %v", name, err)
+	}
+	if err := live.QueryRow("SELECT name FROM items WHERE id = ?", item.ID).Scan(&name); err != nil || name != "Black Pepper" {
+		t.Errorf("item not restored: %q, %v", name, err)
+	}
+	assertItemDetailsRow(t, manager, "raw_item_details", item.ID)
+	var customers int
+	if err := live.QueryRow("SELECT COUNT(*) FROM parties WHERE id = ?", customer.ID).Scan(&customers); err != nil || customers != 1 {
+		t.Errorf("a record added after the backup was lost: %d, %v", customers, err)
+	}
+
+	entries, err := NewSqliteAuditRepository(live).List(domainAudit.ListFilter{Action: domainAudit.ActionRestore})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 restore audit entries, got %+v", entries)
+	}
+	for _, entry := range entries {
+		if entry.Actor != "admin" || entry.AfterJSON == "" {
+			t.Errorf("unexpected audit entry: %+v", entry)
+		}
+		if entry.EntityType == domainBackup.EntityParty && (entry.EntityID != strconv.FormatInt(supplier.ID, 10) || entry.BeforeJSON != "") {
+			t.Errorf("expected the supplier to be recorded as recreated: %+v", entry)
+		}
+	}
+	if err := manager.VerifyHashChains(); err != nil {
+		t.Errorf("restore broke the audit hash chain: %v", err)
+	}
+}
+
+func TestRestoreEntities_RollsBackOnConflict(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	live := manager.GetDB()
+
+	supplier := &domainInventory.Party{PartyType: domainInventory.PartyTypeSupplier, Name: "Kerala Spice Traders", Phone: "0484-2370001", IsActive: true}
+	if err := repo.CreateParty(supplier); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+	item := &domainInventory.Item{SKU: "RAW-CLV-1", Name: "Cloves", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true}
+	if err := repo.CreateItem(item); err != nil {
+		t.Fatalf("CreateItem failed: %v", err)
+	}
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	if _, err := live.Exec("VACUUM INTO ?", backupPath); err != nil {
+		t.Fatalf("VACUUM INTO failed: %v", err)
+	}
+
+	// The supplier was deleted and entered again, so it now has another ID.
+	mustExec(t, live, "UPDATE items SET name = 'Cloves (renamed)' WHERE id = ?", item.ID)
+	mustExec(t, live, "DELETE FROM parties WHERE id = ?", supplier.ID)
+	if err := repo.CreateParty(&domainInventory.Party{PartyType: domainInventory.PartyTypeSupplier, Name: "Kerala Spice Traders", Phone: "0484-2370001", IsActive: true}); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+
+	err := RestoreEntities(live, backupPath, []domainBackup.EntityRef{
+		{Kind: domainBackup.EntityItem, ID: item.ID},
+		{Kind: domainBackup.EntityParty, ID: supplier.ID},
+	}, "admin")
+	if err == nil {
+		t.Fatal("expected the duplicate supplier name to stop the restore")
+	}
+	var name string
+	if err := live.QueryRow("SELECT name FROM items WHERE id = ?", item.ID).Scan(&name); err != nil || name != "Cloves (renamed)" {
+		t.Errorf("expected the whole restore to roll back, item is %q (%v)", name, err)
+	}
+
+	for _, refs := range [][]domainBackup.EntityRef{
+		nil,
+		{{Kind: "stock_movement", ID: 1}},
+		{{Kind: domainBackup.EntityItem, ID: 9999}},
+	} {
+		if err := RestoreEntities(live, backupPath, refs, "admin"); err == nil {
+			t.Errorf("expected %+v to be rejected", refs)
+		}
+	}
+}
+
+func mustExec(t *testing.T, conn *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := conn.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
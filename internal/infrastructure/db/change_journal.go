@@ -0,0 +1,512 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The change journal records every row change made to the application tables, so the
+// backup service can ship the changes made since the last backup and replay them onto
+// it. Triggers write the journal; they are generated from the live schema rather than
+// kept in migrations, so tables added later are covered without touching this file.
+const (
+	journalTable         = "change_journal"
+	journalMetaTable     = "change_journal_meta"
+	journalTriggerPrefix = "change_journal_"
+)
+
+// Journal operations.
+const (
+	JournalInsert = "I"
+	JournalUpdate = "U"
+	JournalDelete = "D"
+)
+
+// JournalEntry is one journalled row change. Key holds the row's primary key before the
+// change and Row its columns after it; Row is empty for deletes. BLOB values are written
+// as {"blob": hex} and REAL values as {"real": text}, so both replay exactly.
+type JournalEntry struct {
+	Seq        int64           `json:"seq"`
+	Generation string          `json:"generation"`
+	ChangedAt  time.Time       `json:"changed_at"`
+	Table      string          `json:"table"`
+	Op         string          `json:"op"`
+	Key        json.RawMessage `json:"key"`
+	Row        json.RawMessage `json:"row,omitempty"`
+}
+
+// InstallChangeJournal (re)creates the journal triggers for every application table.
+// The journal starts a new generation when there is none yet or the schema version has
+// changed since it was recorded, since entries journalled under one schema cannot be
+// replayed onto another. Without the journal table it only removes stale triggers.
+func (m *DatabaseManager) InstallChangeJournal() error {
+	if m.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	return runInTx(m.db, func(tx *sql.Tx) error {
+		return installChangeJournalTx(tx, false)
+	})
+}
+
+// RestartChangeJournal starts a new journal generation and discards unshipped entries.
+// It is called after the database file has been replaced, when the journal inside it
+// belongs to the history the file was copied from.
+func (m *DatabaseManager) RestartChangeJournal() error {
+	if m.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	return runInTx(m.db, func(tx *sql.Tx) error {
+		return installChangeJournalTx(tx, true)
+	})
+}
+
+// DropChangeJournalTriggers removes the journal triggers, leaving the journal itself.
+// Migrations run without them; InstallChangeJournal puts them back for the new schema.
+func (m *DatabaseManager) DropChangeJournalTriggers() error {
+	if m.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	return runInTx(m.db, dropJournalTriggersTx)
+}
+
+func installChangeJournalTx(tx *sql.Tx, restart bool) error {
+	if err := dropJournalTriggersTx(tx); err != nil {
+		return err
+	}
+	installed, err := tableExists(tx, journalTable)
+	if err != nil || !installed {
+		return err
+	}
+
+	version, err := migratedVersion(tx)
+	if err != nil {
+		return err
+	}
+	var recordedVersion int64
+	err = tx.QueryRow("SELECT schema_version FROM " + journalMetaTable + " WHERE id = 1").Scan(&recordedVersion)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read change journal generation: %w", err)
+	}
+	if restart || errors.Is(err, sql.ErrNoRows) || recordedVersion != version {
+		if restart {
+			if _, err := tx.Exec("DELETE FROM " + journalTable); err != nil {
+				return fmt.Errorf("failed to clear change journal: %w", err)
+			}
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO "+journalMetaTable+" (id, generation, schema_version) VALUES (1, ?, ?) "+
+				"ON CONFLICT(id) DO UPDATE SET generation = excluded.generation, schema_version = excluded.schema_version",
+			newJournalGeneration(), version,
+		); err != nil {
+			return fmt.Errorf("failed to start change journal generation: %w", err)
+		}
+	}
+
+	tables, err := journalledTables(tx)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		columns, keys, err := journalColumns(tx, table)
+		if err != nil {
+			return err
+		}
+		for _, statement := range journalTriggers(table, columns, keys) {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("failed to install change journal on %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func dropJournalTriggersTx(tx *sql.Tx) error {
+	names, err := queryStrings(tx, `SELECT name FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'change\_journal\_%' ESCAPE '\'`)
+	if err != nil {
+		return fmt.Errorf("failed to list change journal triggers: %w", err)
+	}
+	for _, name := range names {
+		if _, err := tx.Exec("DROP TRIGGER IF EXISTS " + quoteIdent(name)); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// newJournalGeneration names a generation by the moment it started, so generations
+// sort in the order they were made.
+func newJournalGeneration() string {
+	return time.Now().UTC().Format("20060102T150405.000000000Z")
+}
+
+// journalledTables lists the application tables: everything except SQLite's own
+// tables, the migration version and the journal itself.
+func journalledTables(q rowQueryer) ([]string, error) {
+	return queryStrings(q, `SELECT name FROM sqlite_master WHERE type = 'table'
+		AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		AND name NOT IN ('schema_migrations', '`+journalTable+`', '`+journalMetaTable+`')
+		ORDER BY name`)
+}
+
+// journalColumns returns a table's columns and its primary key columns in key order.
+// A table without a primary key is keyed by rowid.
+func journalColumns(q rowQueryer, table string) ([]string, []string, error) {
+	rows, err := queryRowMaps(q, fmt.Sprintf("PRAGMA table_info(%s)", quoteIdent(table)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	columns := make([]string, 0, len(rows))
+	keyOrder := map[string]int64{}
+	var keys []string
+	for _, row := range rows {
+		name, _ := row["name"].(string)
+		columns = append(columns, name)
+		if pk, _ := row["pk"].(int64); pk > 0 {
+			keyOrder[name] = pk
+			keys = append(keys, name)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyOrder[keys[i]] < keyOrder[keys[j]] })
+	if len(keys) == 0 {
+		keys = []string{"rowid"}
+	}
+	return columns, keys, nil
+}
+
+func journalTriggers(table string, columns, keys []string) []string {
+	trigger := func(event, op, keyRow, dataRow string) string {
+		data := "NULL"
+		if dataRow != "" {
+			data = journalObject(dataRow, columns)
+		}
+		return fmt.Sprintf(`CREATE TRIGGER %s AFTER %s ON %s BEGIN
+	INSERT INTO %s (generation, table_name, op, row_key, row_data)
+	VALUES ((SELECT generation FROM %s WHERE id = 1), %s, '%s', %s, %s);
+END`,
+			quoteIdent(journalTriggerPrefix+table+"_"+strings.ToLower(event)), event, quoteIdent(table),
+			journalTable, journalMetaTable, quoteLiteral(table), op, journalObject(keyRow, keys), data)
+	}
+	return []string{
+		trigger("INSERT", JournalInsert, "NEW", "NEW"),
+		trigger("UPDATE", JournalUpdate, "OLD", "NEW"),
+		trigger("DELETE", JournalDelete, "OLD", ""),
+	}
+}
+
+// journalObject builds the json_object expression for the named columns of row (NEW or
+// OLD). BLOBs and REALs are wrapped so neither is altered by the trip through JSON.
+func journalObject(row string, columns []string) string {
+	args := make([]string, 0, 2*len(columns))
+	for _, column := range columns {
+		value := row + "." + quoteIdent(column)
+		if column == "rowid" {
+			value = row + ".rowid"
+		}
+		args = append(args, quoteLiteral(column), fmt.Sprintf(
+			"CASE typeof(%[1]s) WHEN 'blob' THEN json_object('blob', hex(%[1]s)) WHEN 'real' THEN json_object('real', printf('%%!.17g', %[1]s)) ELSE %[1]s END",
+			value,
+		))
+	}
+	return "json_object(" + strings.Join(args, ", ") + ")"
+}
+
+// JournalPosition returns the journal generation a database is in and the sequence
+// number of the last change it contains. A database without a journal returns "" and 0.
+func JournalPosition(conn *sql.DB) (string, int64, error) {
+	installed, err := tableExists(conn, journalMetaTable)
+	if err != nil || !installed {
+		return "", 0, err
+	}
+	var generation string
+	err = conn.QueryRow("SELECT generation FROM " + journalMetaTable + " WHERE id = 1").Scan(&generation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	// AUTOINCREMENT keeps the highest sequence handed out, even once those rows are
+	// shipped and deleted.
+	var seq int64
+	err = conn.QueryRow("SELECT seq FROM sqlite_sequence WHERE name = ?", journalTable).Scan(&seq)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", 0, err
+	}
+	return generation, seq, nil
+}
+
+// ReadJournal returns up to limit unshipped entries, oldest first.
+func ReadJournal(conn *sql.DB, limit int) ([]JournalEntry, error) {
+	installed, err := tableExists(conn, journalTable)
+	if err != nil || !installed {
+		return nil, err
+	}
+	rows, err := conn.Query(
+		"SELECT seq, generation, changed_at, table_name, op, row_key, COALESCE(row_data, '') FROM "+journalTable+" ORDER BY seq LIMIT ?",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []JournalEntry
+	for rows.Next() {
+		var entry JournalEntry
+		var changedAt, key, row string
+		if err := rows.Scan(&entry.Seq, &entry.Generation, &changedAt, &entry.Table, &entry.Op, &key, &row); err != nil {
+			return nil, err
+		}
+		if entry.ChangedAt, err = time.Parse(time.RFC3339Nano, changedAt); err != nil {
+			return nil, fmt.Errorf("change journal entry %d: %w", entry.Seq, err)
+		}
+		entry.Key = json.RawMessage(key)
+		if row != "" {
+			entry.Row = json.RawMessage(row)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// TrimJournal deletes the entries up to and including seq once they are shipped.
+func TrimJournal(conn *sql.DB, seq int64) error {
+	_, err := conn.Exec("DELETE FROM "+journalTable+" WHERE seq <= ?", seq)
+	return err
+}
+
+// ReplayJournal applies entries, in order, to the database file at path, which must be
+// at the schema they were journalled under. The file's own triggers and foreign keys
+// are off while it runs, since the entries already hold every change those caused. The
+// replay is one transaction: it applies completely or not at all.
+func ReplayJournal(path string, entries []JournalEntry) error {
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+	if _, err := conn.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+
+	return runInTx(conn, func(tx *sql.Tx) error {
+		triggers, err := queryRowMapsTx(tx, "SELECT name, sql FROM sqlite_master WHERE type = 'trigger' ORDER BY name")
+		if err != nil {
+			return err
+		}
+		for _, trigger := range triggers {
+			if _, err := tx.Exec("DROP TRIGGER " + quoteIdent(trigger["name"].(string))); err != nil {
+				return err
+			}
+		}
+
+		columns := map[string]map[string]bool{}
+		for _, entry := range entries {
+			known, ok := columns[entry.Table]
+			if !ok {
+				names, _, err := journalColumns(tx, entry.Table)
+				if err != nil {
+					return err
+				}
+				if len(names) == 0 {
+					return fmt.Errorf("change journal entry %d: no table %s", entry.Seq, entry.Table)
+				}
+				known = map[string]bool{"rowid": true}
+				for _, name := range names {
+					known[name] = true
+				}
+				columns[entry.Table] = known
+			}
+			if err := replayEntryTx(tx, entry, known); err != nil {
+				return fmt.Errorf("change journal entry %d (%s %s): %w", entry.Seq, entry.Op, entry.Table, err)
+			}
+		}
+
+		for _, trigger := range triggers {
+			if _, err := tx.Exec(trigger["sql"].(string)); err != nil {
+				return fmt.Errorf("failed to recreate trigger %s: %w", trigger["name"], err)
+			}
+		}
+		return nil
+	})
+}
+
+func replayEntryTx(tx *sql.Tx, entry JournalEntry, known map[string]bool) error {
+	key, err := decodeJournalRow(entry.Key, known)
+	if err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("entry has no key")
+	}
+	var row map[string]interface{}
+	if entry.Op != JournalDelete {
+		if row, err = decodeJournalRow(entry.Row, known); err != nil {
+			return err
+		}
+		if len(row) == 0 {
+			return fmt.Errorf("entry has no row")
+		}
+	}
+
+	table := quoteIdent(entry.Table)
+	var result sql.Result
+	switch entry.Op {
+	case JournalInsert:
+		names := sortedKeys(row)
+		if _, isColumn := row["rowid"]; !isColumn {
+			if rowid, ok := key["rowid"]; ok {
+				// Tables without a primary key keep their rowid so later entries find the row.
+				row["rowid"] = rowid
+				names = append(names, "rowid")
+			}
+		}
+		quoted := make([]string, len(names))
+		args := make([]interface{}, len(names))
+		for i, name := range names {
+			quoted[i], args[i] = quoteIdent(name), row[name]
+		}
+		result, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			table, strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")), args...)
+	case JournalUpdate:
+		names := sortedKeys(row)
+		sets := make([]string, len(names))
+		args := make([]interface{}, 0, len(names)+len(key))
+		for i, name := range names {
+			sets[i] = quoteIdent(name) + " = ?"
+			args = append(args, row[name])
+		}
+		where, whereArgs := keyClause(key)
+		result, err = tx.Exec(fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(sets, ", "), where), append(args, whereArgs...)...)
+	case JournalDelete:
+		where, whereArgs := keyClause(key)
+		result, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), whereArgs...)
+	default:
+		return fmt.Errorf("unknown operation %q", entry.Op)
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 1 {
+		return fmt.Errorf("expected to change one row with key %s, changed %d", entry.Key, n)
+	}
+	return nil
+}
+
+// decodeJournalRow turns a journalled row back into column values, rejecting columns
+// the table does not have.
+func decodeJournalRow(raw json.RawMessage, known map[string]bool) (map[string]interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var row map[string]interface{}
+	if err := decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+	for name, value := range row {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown column %s", name)
+		}
+		decoded, err := decodeJournalValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", name, err)
+		}
+		row[name] = decoded
+	}
+	return row, nil
+}
+
+func decodeJournalValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		if blob, ok := v["blob"].(string); ok {
+			return hex.DecodeString(blob)
+		}
+		if real, ok := v["real"].(string); ok {
+			return strconv.ParseFloat(real, 64)
+		}
+		return nil, fmt.Errorf("unexpected object value")
+	default:
+		return v, nil
+	}
+}
+
+func keyClause(key map[string]interface{}) (string, []interface{}) {
+	names := sortedKeys(key)
+	terms := make([]string, len(names))
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		terms[i], args[i] = quoteIdent(name)+" = ?", key[name]
+	}
+	return strings.Join(terms, " AND "), args
+}
+
+func sortedKeys(row map[string]interface{}) []string {
+	names := make([]string, 0, len(row))
+	for name := range row {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func tableExists(q rowQueryer, table string) (bool, error) {
+	names, err := queryStrings(q, "SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table)
+	return len(names) > 0, err
+}
+
+// migratedVersion returns golang-migrate's schema version, or 0 before any migration.
+func migratedVersion(q rowQueryer) (int64, error) {
+	exists, err := tableExists(q, "schema_migrations")
+	if err != nil || !exists {
+		return 0, err
+	}
+	rows, err := queryRowMaps(q, "SELECT version FROM schema_migrations LIMIT 1")
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	version, _ := rows[0]["version"].(int64)
+	return version, nil
+}
+
+func queryStrings(q rowQueryer, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
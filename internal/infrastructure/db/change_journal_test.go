@@ -0,0 +1,149 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+)
+
+func TestChangeJournal_ReplayRebuildsLaterChangesExactly(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	live := manager.GetDB()
+
+	supplier := &domainInventory.Party{PartyType: domainInventory.PartyTypeSupplier, Name: "Kerala Spice Traders", Phone: "0484-2370001", IsActive: true}
+	if err := repo.CreateParty(supplier); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+	generation, seq, err := JournalPosition(live)
+	if err != nil || generation == "" || seq == 0 {
+		t.Fatalf("expected the party to be journalled: %q %d %v", generation, seq, err)
+	}
+	basePath := filepath.Join(t.TempDir(), "base.db")
+	if _, err := live.Exec("VACUUM INTO ?", basePath); err != nil {
+		t.Fatalf("VACUUM INTO failed: %v", err)
+	}
+
+	// After the base copy: an insert with a REAL that JSON would round, an update, a
+	// delete, and a BLOB in a table keyed by text.
+	item := &domainInventory.Item{SKU: "RAW-CAR-1", Name: "Cardamom", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true}
+	if err := repo.CreateItem(item); err != nil {
+		t.Fatalf("CreateItem failed: %v", err)
+	}
+	mustExec(t, live, "INSERT INTO unit_conversions (item_id, from_unit, to_unit, factor) VALUES (?, 'bag', 'kg', 0.1 + 0.2)", item.ID)
+	mustExec(t, live, "UPDATE parties SET name = 'Kerala Spice Traders Pvt Ltd' WHERE id = ?", supplier.ID)
+	mustExec(t, live, `INSERT INTO idempotency_keys (idempotency_key, request_hash, status_code, response_body, created_at, expires_at)
+		VALUES ('grn-7', 'h', 201, x'00ff7b7d', '2026-10-18 09:00:00+00:00', '2026-10-19 09:00:00+00:00')`)
+	mustExec(t, live, "DELETE FROM parties WHERE id = ?", supplier.ID)
+
+	entries, err := ReadJournal(live, 1000)
+	if err != nil {
+		t.Fatalf("ReadJournal failed: %v", err)
+	}
+	var later []JournalEntry
+	for _, entry := range entries {
+		if entry.Generation != generation {
+			t.Fatalf("entry %d is in generation %q, expected %q", entry.Seq, entry.Generation, generation)
+		}
+		if entry.Seq > seq {
+			later = append(later, entry)
+		}
+	}
+	if len(later) == 0 || later[len(later)-1].Op != JournalDelete {
+		t.Fatalf("expected the later changes ending in the delete, got %+v", later)
+	}
+
+	if err := ReplayJournal(basePath, later); err != nil {
+		t.Fatalf("ReplayJournal failed: %v", err)
+	}
+	// The copy is verified with the key of the database it was taken from.
+	key, err := os.ReadFile(filepath.Join(filepath.Dir(manager.GetDBPath()), HashChainKeyFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(basePath), HashChainKeyFileName), key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	replayed := NewDatabaseManager(basePath)
+	if err := replayed.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer replayed.Close()
+	tables, err := journalledTables(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		query := fmt.Sprintf("SELECT * FROM %s ORDER BY rowid", quoteIdent(table))
+		want, err := queryRowMaps(live, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := queryRowMaps(replayed.GetDB(), query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s differs after replay:\nlive:     %v\nreplayed: %v", table, want, got)
+		}
+	}
+	if err := replayed.VerifyHashChains(); err != nil {
+		t.Errorf("replay broke the audit hash chain: %v", err)
+	}
+	var triggers int
+	if err := replayed.GetDB().QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'audit_log_no_update'").Scan(&triggers); err != nil || triggers != 1 {
+		t.Errorf("expected the table triggers back after replay: %d, %v", triggers, err)
+	}
+
+	// A replay that does not fit the file is rolled back whole.
+	if err := ReplayJournal(basePath, later); err == nil {
+		t.Error("expected replaying the same changes twice to fail")
+	}
+}
+
+func TestChangeJournal_RestartStartsNewGeneration(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	live := manager.GetDB()
+	before, _, err := JournalPosition(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateParty(&domainInventory.Party{PartyType: domainInventory.PartyTypeCustomer, Name: "Hotel Saravana", Phone: "044-24345555", IsActive: true}); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+
+	if err := manager.InstallChangeJournal(); err != nil {
+		t.Fatalf("InstallChangeJournal failed: %v", err)
+	}
+	if again, _, _ := JournalPosition(live); again != before {
+		t.Errorf("reinstalling at the same schema changed the generation: %q to %q", before, again)
+	}
+	if entries, _ := ReadJournal(live, 10); len(entries) == 0 {
+		t.Error("reinstalling discarded unshipped entries")
+	}
+
+	if err := manager.RestartChangeJournal(); err != nil {
+		t.Fatalf("RestartChangeJournal failed: %v", err)
+	}
+	after, _, err := JournalPosition(live)
+	if err != nil || after == before {
+		t.Errorf("expected a new generation, still %q (%v)", after, err)
+	}
+	if entries, _ := ReadJournal(live, 10); len(entries) != 0 {
+		t.Errorf("expected the old generation's entries to be discarded, got %d", len(entries))
+	}
+	mustExec(t, live, "UPDATE parties SET phone = '044-24345556'")
+	entries, err := ReadJournal(live, 10)
+	if err != nil || len(entries) != 1 || entries[0].Generation != after || entries[0].Op != JournalUpdate {
+		t.Errorf("expected the update journalled in the new generation: %+v, %v", entries, err)
+	}
+	if err := TrimJournal(live, entries[0].Seq); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := ReadJournal(live, 10); len(entries) != 0 {
+		t.Errorf("expected shipped entries to be trimmed, got %d", len(entries))
+	}
+}
@@ -0,0 +1,419 @@
+package db
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+)
+
+// HashChainKeyFileName is kept next to the database file. Row hashes are HMACs under this
+// key, so someone who can edit the database but not read the key cannot rebuild the chain
+// over edited rows. It must travel with the database when the data folder is moved.
+const HashChainKeyFileName = "hash_chain.key"
+
+// hashChain describes an append-only table whose rows are linked by prev_hash/row_hash.
+// Only the listed columns contribute to a row's hash.
+type hashChain struct {
+	table   string
+	columns []string
+}
+
+var (
+	stockLedgerChain = hashChain{
+		table:   "stock_ledger",
+		columns: []string{"id", "item_id", "transaction_type", "quantity", "reference_id", "lot_number", "notes", "created_by", "created_at"},
+	}
+	auditLogChain = hashChain{
+		table:   "audit_log",
+		columns: []string{"id", "actor", "action", "entity_type", "entity_id", "before_json", "after_json", "created_at"},
+	}
+
+	hashChains = []hashChain{stockLedgerChain, auditLogChain}
+)
+
+// hashChainKeys caches keys by key file path; the file is read once per process.
+var hashChainKeys sync.Map
+
+// hashChainKeyPath finds the key file for the database q is connected to. Repositories
+// only hold a connection or transaction, so the path is asked of SQLite.
+func hashChainKeyPath(q rowQueryer) (string, error) {
+	rows, err := q.QueryContext(context.Background(), "SELECT file FROM pragma_database_list WHERE name = 'main'")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate database file: %w", err)
+	}
+	defer rows.Close()
+	var file string
+	if rows.Next() {
+		if err := rows.Scan(&file); err != nil {
+			return "", fmt.Errorf("failed to locate database file: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to locate database file: %w", err)
+	}
+	if file == "" {
+		return "", errors.New("hash chains need a database file to keep their key beside")
+	}
+	return filepath.Join(filepath.Dir(file), HashChainKeyFileName), nil
+}
+
+// hashChainKey returns the key for the database q is connected to. When create is set a
+// missing key file is created; otherwise a missing file returns fs.ErrNotExist.
+func hashChainKey(q rowQueryer, create bool) ([]byte, error) {
+	path, err := hashChainKeyPath(q)
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := hashChainKeys.Load(path); ok {
+		return cached.([]byte), nil
+	}
+
+	key, err := readHashChainKey(path)
+	if errors.Is(err, fs.ErrNotExist) && create {
+		key, err = createHashChainKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := hashChainKeys.LoadOrStore(path, key)
+	return cached.([]byte), nil
+}
+
+func readHashChainKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) < sha256.Size {
+		return nil, fmt.Errorf("hash chain key %s is not valid", path)
+	}
+	return key, nil
+}
+
+// createHashChainKey writes a new random key. If another process created the file first,
+// its key is used instead.
+func createHashChainKey(path string) ([]byte, error) {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate hash chain key: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		return readHashChainKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hash chain key: %w", err)
+	}
+	_, err = file.WriteString(hex.EncodeToString(key) + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("failed to write hash chain key: %w", err)
+	}
+	slog.Info("Created hash chain key", "path", path)
+	return key, nil
+}
+
+// chainRow is a row's stored hashes plus its canonical content.
+type chainRow struct {
+	id       int64
+	prevHash sql.NullString
+	rowHash  sql.NullString
+	content  []interface{}
+}
+
+func (c hashChain) selectColumns() string {
+	return strings.Join(c.columns, ", ") + ", prev_hash, row_hash"
+}
+
+func scanChainRow(c hashChain, scanner interface{ Scan(...interface{}) error }) (chainRow, error) {
+	values := make([]interface{}, len(c.columns))
+	pointers := make([]interface{}, len(c.columns)+2)
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	var row chainRow
+	pointers[len(c.columns)] = &row.prevHash
+	pointers[len(c.columns)+1] = &row.rowHash
+	if err := scanner.Scan(pointers...); err != nil {
+		return chainRow{}, err
+	}
+	for i := range values {
+		values[i] = normalizeAuditValue(values[i])
+	}
+	id, ok := values[0].(int64)
+	if !ok {
+		return chainRow{}, fmt.Errorf("%s: unexpected id type %T", c.table, values[0])
+	}
+	row.id = id
+	row.content = values
+	return row, nil
+}
+
+// computeChainHash hashes the previous link together with the canonical row content, as an
+// HMAC under key. A nil key gives the plain SHA-256 that chains were sealed with before
+// they were keyed; it is only used to verify such a chain before resealing it.
+func computeChainHash(key []byte, prevHash string, content []interface{}) (string, error) {
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	var sum hash.Hash
+	if key == nil {
+		sum = sha256.New()
+	} else {
+		sum = hmac.New(sha256.New, key)
+	}
+	sum.Write([]byte(prevHash))
+	sum.Write([]byte("\n"))
+	sum.Write(encoded)
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// sealChainRowTx links a freshly inserted row to its predecessor inside the caller's transaction.
+func sealChainRowTx(tx *sql.Tx, c hashChain, id int64) error {
+	key, err := hashChainKey(tx, true)
+	if err != nil {
+		return err
+	}
+	var prevHash sql.NullString
+	err = tx.QueryRowContext(
+		context.Background(),
+		fmt.Sprintf("SELECT row_hash FROM %s WHERE id < ? ORDER BY id DESC LIMIT 1", c.table),
+		id,
+	).Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read previous %s hash: %w", c.table, err)
+	}
+
+	row, err := scanChainRow(c, tx.QueryRowContext(
+		context.Background(),
+		fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", c.selectColumns(), c.table),
+		id,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to read %s row %d for hashing: %w", c.table, id, err)
+	}
+
+	rowHash, err := computeChainHash(key, prevHash.String, row.content)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(
+		context.Background(),
+		fmt.Sprintf("UPDATE %s SET prev_hash = ?, row_hash = ? WHERE id = ?", c.table),
+		prevHash.String,
+		rowHash,
+		id,
+	); err != nil {
+		return fmt.Errorf("failed to seal %s row %d: %w", c.table, id, err)
+	}
+	return nil
+}
+
+// SealLegacyHashChains hashes rows written before the hash chain existed. Each table is
+// sealed at most once and then recorded in hash_chain_seals; from then on a row without a
+// hash is left for VerifyHashChains to report, so clearing the hashes cannot rebuild a
+// chain over edited rows.
+//
+// A chain sealed before chains were keyed is resealed under the key once, but only if its
+// plain SHA-256 links still verify. A broken one stays unkeyed for VerifyHashChains to
+// report where it breaks.
+func (m *DatabaseManager) SealLegacyHashChains() error {
+	if m.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	for _, c := range hashChains {
+		sealedRows := 0
+		err := runInTx(m.db, func(tx *sql.Tx) error {
+			var keyed bool
+			err := tx.QueryRowContext(
+				context.Background(),
+				"SELECT keyed FROM hash_chain_seals WHERE table_name = ?",
+				c.table,
+			).Scan(&keyed)
+			marked := err == nil
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to read %s seal marker: %w", c.table, err)
+			}
+			if keyed {
+				return nil
+			}
+
+			var total, sealed int
+			if err := tx.QueryRowContext(
+				context.Background(),
+				fmt.Sprintf("SELECT COUNT(*), COUNT(row_hash) FROM %s", c.table),
+			).Scan(&total, &sealed); err != nil {
+				return fmt.Errorf("failed to inspect %s hash chain: %w", c.table, err)
+			}
+
+			reseal := false
+			switch {
+			case total == 0 || !marked && sealed == 0:
+				reseal = true
+			case sealed == 0:
+				// Cleared after sealing: reported, never rebuilt.
+			default:
+				key, err := hashChainKey(tx, true)
+				if err != nil {
+					return err
+				}
+				if verifyHashChain(tx, c, key) == nil {
+					keyed = true
+				} else {
+					reseal = verifyHashChain(tx, c, nil) == nil
+				}
+			}
+			if reseal {
+				ids, err := queryChainIDs(tx, c)
+				if err != nil {
+					return err
+				}
+				for _, id := range ids {
+					if err := sealChainRowTx(tx, c, id); err != nil {
+						return err
+					}
+				}
+				sealedRows = len(ids)
+				keyed = true
+			}
+
+			if _, err := tx.ExecContext(
+				context.Background(),
+				`INSERT INTO hash_chain_seals (table_name, keyed) VALUES (?, ?)
+				 ON CONFLICT(table_name) DO UPDATE SET keyed = excluded.keyed`,
+				c.table,
+				keyed,
+			); err != nil {
+				return fmt.Errorf("failed to record %s seal marker: %w", c.table, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to seal legacy %s rows: %w", c.table, err)
+		}
+		if sealedRows > 0 {
+			slog.Warn("Sealed legacy rows into hash chain", "table", c.table, "rows", sealedRows)
+		}
+	}
+	return nil
+}
+
+func queryChainIDs(tx *sql.Tx, c hashChain) ([]int64, error) {
+	rows, err := tx.QueryContext(context.Background(), fmt.Sprintf("SELECT id FROM %s ORDER BY id ASC", c.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// VerifyHashChains walks every chained table in id order and returns a
+// *domainAudit.ChainBreak for the first row whose stored hashes do not match its content
+// or predecessor. A table without a seal marker holds only rows sealed under the key.
+func (m *DatabaseManager) VerifyHashChains() error {
+	if m.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	for _, c := range hashChains {
+		keyed := true
+		err := m.db.QueryRow("SELECT keyed FROM hash_chain_seals WHERE table_name = ?", c.table).Scan(&keyed)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to read %s seal marker: %w", c.table, err)
+		}
+
+		var key []byte
+		if keyed {
+			key, err = hashChainKey(m.db, false)
+			if errors.Is(err, fs.ErrNotExist) {
+				if err := missingHashChainKey(m.db, c); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := verifyHashChain(m.db, c, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingHashChainKey reports a keyed chain whose key file is gone as broken at its first
+// row, since nothing in it can be verified. An empty table is not broken.
+func missingHashChainKey(db *sql.DB, c hashChain) error {
+	var first sql.NullInt64
+	if err := db.QueryRow(fmt.Sprintf("SELECT MIN(id) FROM %s", c.table)).Scan(&first); err != nil {
+		return fmt.Errorf("failed to read %s hash chain: %w", c.table, err)
+	}
+	if !first.Valid {
+		return nil
+	}
+	return &domainAudit.ChainBreak{Table: c.table, RowID: first.Int64, Reason: "the hash chain key " + HashChainKeyFileName + " is missing"}
+}
+
+// verifyHashChain checks one table under key; a nil key checks a chain sealed before
+// chains were keyed.
+func verifyHashChain(q rowQueryer, c hashChain, key []byte) error {
+	rows, err := q.QueryContext(context.Background(), fmt.Sprintf("SELECT %s FROM %s ORDER BY id ASC", c.selectColumns(), c.table))
+	if err != nil {
+		return fmt.Errorf("failed to read %s hash chain: %w", c.table, err)
+	}
+	defer rows.Close()
+
+	expectedPrev := ""
+	for rows.Next() {
+		row, err := scanChainRow(c, rows)
+		if err != nil {
+			return fmt.Errorf("failed to read %s hash chain: %w", c.table, err)
+		}
+		if !row.rowHash.Valid || row.rowHash.String == "" {
+			return &domainAudit.ChainBreak{Table: c.table, RowID: row.id, Reason: "row is not sealed"}
+		}
+		if row.prevHash.String != expectedPrev {
+			return &domainAudit.ChainBreak{Table: c.table, RowID: row.id, Reason: "previous hash does not match; a row was removed or reordered"}
+		}
+		computed, err := computeChainHash(key, row.prevHash.String, row.content)
+		if err != nil {
+			return err
+		}
+		if computed != row.rowHash.String {
+			return &domainAudit.ChainBreak{Table: c.table, RowID: row.id, Reason: "row content was modified"}
+		}
+		expectedPrev = row.rowHash.String
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s hash chain: %w", c.table, err)
+	}
+	return nil
+}
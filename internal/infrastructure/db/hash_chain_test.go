@@ -0,0 +1,316 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+)
+
+func seedHashChainRows(t *testing.T, repo *SqliteInventoryRepository) []int64 {
+	t.Helper()
+	rawID := createTestInventoryItem(t, repo, domainInventory.ItemTypeRaw, "RAW-HC-1", "Chain Cumin", "kg")
+	supplierID := createTestParty(t, repo, "Chain Supplier")
+
+	grn := &domainInventory.GRN{
+		GRNNumber:  "GRN-HC-1",
+		SupplierID: supplierID,
+		Lines: []domainInventory.GRNLine{
+			{LineNo: 1, ItemID: rawID, QuantityReceived: 40},
+		},
+	}
+	if err := repo.CreateGRN(grn); err != nil {
+		t.Fatalf("CreateGRN failed: %v", err)
+	}
+
+	ids := make([]int64, 0, 3)
+	for _, ref := range []string{"PK-HC-1", "PK-HC-2"} {
+		movement := &domainInventory.StockLedgerMovement{
+			LotNumber:       grn.Lines[0].LotNumber,
+			TransactionType: "OUT",
+			Quantity:        5,
+			ReferenceID:     ref,
+		}
+		if err := repo.WithActor("operator-a").RecordLotStockMovement(movement); err != nil {
+			t.Fatalf("RecordLotStockMovement failed: %v", err)
+		}
+		ids = append(ids, movement.ID)
+	}
+	return ids
+}
+
+func TestHashChain_IntactAfterNormalWrites(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	seedHashChainRows(t, repo)
+
+	if err := manager.VerifyHashChains(); err != nil {
+		t.Fatalf("expected intact chain, got %v", err)
+	}
+
+	var unsealed int
+	if err := manager.GetDB().QueryRow("SELECT COUNT(*) FROM stock_ledger WHERE row_hash IS NULL").Scan(&unsealed); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if unsealed != 0 {
+		t.Fatalf("expected every ledger row to be sealed, got %d unsealed", unsealed)
+	}
+}
+
+func TestHashChain_DetectsEditedLedgerRow(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	ids := seedHashChainRows(t, repo)
+
+	if _, err := manager.GetDB().Exec("UPDATE stock_ledger SET quantity = 1 WHERE id = ?", ids[0]); err != nil {
+		t.Fatalf("tamper failed: %v", err)
+	}
+
+	err := manager.VerifyHashChains()
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(err, &chainBreak) {
+		t.Fatalf("expected ChainBreak, got %v", err)
+	}
+	if chainBreak.Table != "stock_ledger" || chainBreak.RowID != ids[0] {
+		t.Fatalf("expected break at stock_ledger row %d, got %+v", ids[0], chainBreak)
+	}
+}
+
+func TestHashChain_DetectsDeletedLedgerRow(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	ids := seedHashChainRows(t, repo)
+
+	if _, err := manager.GetDB().Exec("DELETE FROM stock_ledger WHERE id = ?", ids[0]); err != nil {
+		t.Fatalf("tamper failed: %v", err)
+	}
+
+	err := manager.VerifyHashChains()
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(err, &chainBreak) {
+		t.Fatalf("expected ChainBreak, got %v", err)
+	}
+	if chainBreak.RowID != ids[1] {
+		t.Fatalf("expected break at the row following the deleted one (%d), got %+v", ids[1], chainBreak)
+	}
+}
+
+func TestHashChain_DetectsEditedAuditRow(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	seedHashChainRows(t, repo)
+
+	conn := manager.GetDB()
+	if _, err := conn.Exec("DROP TRIGGER audit_log_no_update"); err != nil {
+		t.Fatalf("drop trigger failed: %v", err)
+	}
+	if _, err := conn.Exec("UPDATE audit_log SET actor = 'someone-else' WHERE id = (SELECT MIN(id) FROM audit_log)"); err != nil {
+		t.Fatalf("tamper failed: %v", err)
+	}
+
+	err := manager.VerifyHashChains()
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(err, &chainBreak) {
+		t.Fatalf("expected ChainBreak, got %v", err)
+	}
+	if chainBreak.Table != "audit_log" {
+		t.Fatalf("expected audit_log break, got %+v", chainBreak)
+	}
+}
+
+func TestHashChain_SealsLegacyRowsOnce(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	seedHashChainRows(t, repo)
+
+	conn := manager.GetDB()
+	if _, err := conn.Exec("UPDATE stock_ledger SET prev_hash = NULL, row_hash = NULL"); err != nil {
+		t.Fatalf("reset failed: %v", err)
+	}
+	if err := manager.VerifyHashChains(); err == nil {
+		t.Fatalf("expected unsealed legacy rows to fail verification")
+	}
+
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+	if err := manager.VerifyHashChains(); err != nil {
+		t.Fatalf("expected intact chain after sealing, got %v", err)
+	}
+
+	if _, err := conn.Exec("UPDATE stock_ledger SET notes = 'edited' WHERE id = (SELECT MAX(id) FROM stock_ledger)"); err != nil {
+		t.Fatalf("tamper failed: %v", err)
+	}
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+	if err := manager.VerifyHashChains(); err == nil {
+		t.Fatalf("expected resealing to leave an existing chain untouched")
+	}
+}
+
+func TestHashChain_ClearedHashesAreNotResealed(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	seedHashChainRows(t, repo)
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+
+	// Clearing every hash and editing a row must not produce a fresh chain on the next start.
+	conn := manager.GetDB()
+	if _, err := conn.Exec("UPDATE stock_ledger SET prev_hash = NULL, row_hash = NULL"); err != nil {
+		t.Fatalf("reset failed: %v", err)
+	}
+	if _, err := conn.Exec("UPDATE stock_ledger SET notes = 'edited' WHERE id = (SELECT MIN(id) FROM stock_ledger)"); err != nil {
+		t.Fatalf("tamper failed: %v", err)
+	}
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+
+	err := manager.VerifyHashChains()
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(err, &chainBreak) {
+		t.Fatalf("expected ChainBreak, got %v", err)
+	}
+	if chainBreak.Table != "stock_ledger" || chainBreak.Reason != "row is not sealed" {
+		t.Fatalf("expected an unsealed stock_ledger row, got %+v", chainBreak)
+	}
+}
+
+// rewriteChain recomputes every link of c with key, as someone editing the file directly
+// would; a nil key writes the plain SHA-256 chains that predate the key.
+func rewriteChain(t *testing.T, conn *sql.DB, c hashChain, key []byte) {
+	t.Helper()
+	rows, err := conn.Query(fmt.Sprintf("SELECT %s FROM %s ORDER BY id ASC", c.selectColumns(), c.table))
+	if err != nil {
+		t.Fatalf("read chain failed: %v", err)
+	}
+	var chain []chainRow
+	for rows.Next() {
+		row, err := scanChainRow(c, rows)
+		if err != nil {
+			t.Fatalf("scan chain failed: %v", err)
+		}
+		chain = append(chain, row)
+	}
+	rows.Close()
+
+	prev := ""
+	for _, row := range chain {
+		rowHash, err := computeChainHash(key, prev, row.content)
+		if err != nil {
+			t.Fatalf("hash failed: %v", err)
+		}
+		if _, err := conn.Exec(fmt.Sprintf("UPDATE %s SET prev_hash = ?, row_hash = ? WHERE id = ?", c.table), prev, rowHash, row.id); err != nil {
+			t.Fatalf("rewrite failed: %v", err)
+		}
+		prev = rowHash
+	}
+}
+
+func TestHashChain_RecomputedHashesWithoutTheKeyDoNotVerify(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	ids := seedHashChainRows(t, repo)
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+
+	conn := manager.GetDB()
+	if _, err := conn.Exec("UPDATE stock_ledger SET quantity = 1 WHERE id = ?", ids[0]); err != nil {
+		t.Fatalf("tamper failed: %v", err)
+	}
+	rewriteChain(t, conn, stockLedgerChain, nil)
+
+	err := manager.VerifyHashChains()
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(err, &chainBreak) || chainBreak.Table != "stock_ledger" {
+		t.Fatalf("expected a stock_ledger break, got %v", err)
+	}
+}
+
+func TestHashChain_ResealsAnIntactUnkeyedChainUnderTheKey(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	ids := seedHashChainRows(t, repo)
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+
+	// As left by a release that sealed with plain SHA-256.
+	conn := manager.GetDB()
+	rewriteChain(t, conn, stockLedgerChain, nil)
+	if _, err := conn.Exec("UPDATE hash_chain_seals SET keyed = 0 WHERE table_name = 'stock_ledger'"); err != nil {
+		t.Fatalf("reset marker failed: %v", err)
+	}
+	if err := manager.VerifyHashChains(); err != nil {
+		t.Fatalf("expected the unkeyed chain to verify, got %v", err)
+	}
+	var legacyHash string
+	if err := conn.QueryRow("SELECT row_hash FROM stock_ledger WHERE id = ?", ids[0]).Scan(&legacyHash); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+	var unkeyed int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM hash_chain_seals WHERE keyed = 0").Scan(&unkeyed); err != nil || unkeyed != 0 {
+		t.Fatalf("expected every chain to be keyed, %d are not (%v)", unkeyed, err)
+	}
+	var keyedHash string
+	if err := conn.QueryRow("SELECT row_hash FROM stock_ledger WHERE id = ?", ids[0]).Scan(&keyedHash); err != nil {
+		t.Fatal(err)
+	}
+	if keyedHash == legacyHash {
+		t.Fatalf("expected the row to be resealed under the key")
+	}
+	if err := manager.VerifyHashChains(); err != nil {
+		t.Fatalf("expected intact chain after resealing, got %v", err)
+	}
+}
+
+func TestHashChain_BrokenUnkeyedChainIsNotResealed(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	ids := seedHashChainRows(t, repo)
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+	conn := manager.GetDB()
+	rewriteChain(t, conn, stockLedgerChain, nil)
+	if _, err := conn.Exec("UPDATE hash_chain_seals SET keyed = 0 WHERE table_name = 'stock_ledger'"); err != nil {
+		t.Fatalf("reset marker failed: %v", err)
+	}
+	if _, err := conn.Exec("UPDATE stock_ledger SET quantity = 1 WHERE id = ?", ids[1]); err != nil {
+		t.Fatalf("tamper failed: %v", err)
+	}
+
+	if err := manager.SealLegacyHashChains(); err != nil {
+		t.Fatalf("SealLegacyHashChains failed: %v", err)
+	}
+	err := manager.VerifyHashChains()
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(err, &chainBreak) || chainBreak.RowID != ids[1] || chainBreak.Reason != "row content was modified" {
+		t.Fatalf("expected the edited row %d to be reported, got %v", ids[1], err)
+	}
+}
+
+func TestHashChain_MissingKeyIsReportedAsABreak(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	seedHashChainRows(t, repo)
+
+	keyPath := filepath.Join(filepath.Dir(manager.GetDBPath()), HashChainKeyFileName)
+	if err := os.Remove(keyPath); err != nil {
+		t.Fatalf("remove key failed: %v", err)
+	}
+	hashChainKeys.Delete(keyPath)
+
+	err := manager.VerifyHashChains()
+	var chainBreak *domainAudit.ChainBreak
+	if !errors.As(err, &chainBreak) || !strings.Contains(chainBreak.Reason, HashChainKeyFileName) {
+		t.Fatalf("expected the missing key to be reported, got %v", err)
+	}
+	if _, err := os.Stat(keyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected verification not to create a new key, got %v", err)
+	}
+}
@@ -2,17 +2,39 @@ package db
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
 // Migrator handles database schema migrations
 type Migrator struct {
-	manager *DatabaseManager
+	manager      *DatabaseManager
+	beforeChange func() error
+}
+
+// Migration is one schema migration in the embedded set.
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// MigrationStatus is the schema version a database is at and the migrations it has not
+// had yet. Dirty means a migration failed part-way: the schema has to be repaired by
+// hand and the version it is really at recorded with Force before migrating again.
+type MigrationStatus struct {
+	Version uint
+	Dirty   bool
+	Latest  uint
+	Pending []Migration
 }
 
 // NewMigrator creates a new instance of Migrator
@@ -22,16 +44,23 @@ func NewMigrator(manager *DatabaseManager) *Migrator {
 	}
 }
 
-// RunMigrations applies all pending migrations from the embedded file system
-func (m *Migrator) RunMigrations(fs embed.FS, directory string) error {
+// BackupBeforeChanges sets backup to run before RunMigrations applies anything to a
+// database that already has a schema, and before Down. Nothing is changed when it fails.
+func (m *Migrator) BackupBeforeChanges(backup func() error) {
+	m.beforeChange = backup
+}
+
+// open prepares golang-migrate over the manager's connection. The instance is not
+// closed by callers, since closing it would close the shared connection.
+func (m *Migrator) open(fs embed.FS, directory string) (*migrate.Migrate, source.Driver, error) {
 	sourceDriver, err := iofs.New(fs, directory)
 	if err != nil {
-		return fmt.Errorf("failed to create source driver: %w", err)
+		return nil, nil, fmt.Errorf("failed to create source driver: %w", err)
 	}
 
 	dbDriver, err := sqlite3.WithInstance(m.manager.GetDB(), &sqlite3.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to create database driver: %w", err)
+		return nil, nil, fmt.Errorf("failed to create database driver: %w", err)
 	}
 
 	migrations, err := migrate.NewWithInstance(
@@ -39,7 +68,32 @@ func (m *Migrator) RunMigrations(fs embed.FS, directory string) error {
 		"sqlite3", dbDriver,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		return nil, nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	return migrations, sourceDriver, nil
+}
+
+// RunMigrations applies all pending migrations from the embedded file system. The change
+// journal's triggers are taken down while they run and rebuilt for the new schema after.
+func (m *Migrator) RunMigrations(fs embed.FS, directory string) error {
+	if m.beforeChange != nil {
+		status, err := m.Status(fs, directory)
+		if err != nil {
+			return err
+		}
+		if len(status.Pending) > 0 && status.Version > 0 {
+			if err := m.beforeChange(); err != nil {
+				return fmt.Errorf("backup before schema change failed, nothing was migrated: %w", err)
+			}
+		}
+	}
+	migrations, _, err := m.open(fs, directory)
+	if err != nil {
+		return err
+	}
+
+	if err := m.manager.DropChangeJournalTriggers(); err != nil {
+		return err
 	}
 
 	// Apply migrations
@@ -47,6 +101,120 @@ func (m *Migrator) RunMigrations(fs embed.FS, directory string) error {
 		return fmt.Errorf("failed to apply migrations: %w", err)
 	}
 
+	if err := m.manager.InstallChangeJournal(); err != nil {
+		return fmt.Errorf("failed to install change journal: %w", err)
+	}
+
 	log.Println("Migrations applied successfully or no changes needed")
 	return nil
 }
+
+// Status reports the database's schema version and the migrations still to apply.
+func (m *Migrator) Status(fs embed.FS, directory string) (*MigrationStatus, error) {
+	migrations, sourceDriver, err := m.open(fs, directory)
+	if err != nil {
+		return nil, err
+	}
+	status := &MigrationStatus{}
+	status.Version, status.Dirty, err = migrations.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	available, err := listMigrations(sourceDriver)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range available {
+		status.Latest = migration.Version
+		if migration.Version > status.Version {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	return status, nil
+}
+
+func listMigrations(sourceDriver source.Driver) ([]Migration, error) {
+	var migrations []Migration
+	version, err := sourceDriver.First()
+	for err == nil {
+		r, name, readErr := sourceDriver.ReadUp(version)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+		}
+		r.Close()
+		migrations = append(migrations, Migration{Version: version, Name: name})
+		version, err = sourceDriver.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	return migrations, nil
+}
+
+// Down reverts the last steps migrations. Like RunMigrations it rebuilds the change
+// journal's triggers for the resulting schema.
+func (m *Migrator) Down(fs embed.FS, directory string, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("number of migrations to revert must be positive")
+	}
+	if m.beforeChange != nil {
+		if err := m.beforeChange(); err != nil {
+			return fmt.Errorf("backup before schema change failed, nothing was reverted: %w", err)
+		}
+	}
+	migrations, _, err := m.open(fs, directory)
+	if err != nil {
+		return err
+	}
+	if err := m.manager.DropChangeJournalTriggers(); err != nil {
+		return err
+	}
+	if err := migrations.Steps(-steps); err != nil {
+		return fmt.Errorf("failed to revert migrations: %w", err)
+	}
+	if err := m.manager.InstallChangeJournal(); err != nil {
+		return fmt.Errorf("failed to install change journal: %w", err)
+	}
+	return nil
+}
+
+// Force records version as the schema version and clears the dirty flag without
+// running any migration, once a failed migration has been repaired by hand. A version
+// of -1 records that no migration has been applied.
+func (m *Migrator) Force(fs embed.FS, directory string, version int) error {
+	migrations, _, err := m.open(fs, directory)
+	if err != nil {
+		return err
+	}
+	if err := migrations.Force(version); err != nil {
+		return fmt.Errorf("failed to force schema version %d: %w", version, err)
+	}
+	return nil
+}
+
+// DryRun applies the pending migrations to a temporary copy of the database and reports
+// the copy's status afterwards. The database itself is not changed.
+func (m *Migrator) DryRun(fs embed.FS, directory string) (*MigrationStatus, error) {
+	scratch, err := os.MkdirTemp("", "masala-migrate-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+
+	copyPath := filepath.Join(scratch, filepath.Base(m.manager.GetDBPath()))
+	if _, err := m.manager.GetDB().Exec("VACUUM INTO ?", copyPath); err != nil {
+		return nil, fmt.Errorf("failed to copy database: %w", err)
+	}
+	copyManager := NewDatabaseManager(copyPath)
+	if err := copyManager.Connect(); err != nil {
+		return nil, err
+	}
+	defer copyManager.Close()
+
+	copyMigrator := NewMigrator(copyManager)
+	if err := copyMigrator.RunMigrations(fs, directory); err != nil {
+		return nil, err
+	}
+	return copyMigrator.Status(fs, directory)
+}
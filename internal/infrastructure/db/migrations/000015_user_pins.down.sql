@@ -0,0 +1,2 @@
+ALTER TABLE stock_ledger DROP COLUMN created_by;
+ALTER TABLE users DROP COLUMN pin_hash;
@@ -0,0 +1,9 @@
+-- Operator PIN login for shared shop-floor terminals.
+-- pin_hash is optional; NULL means PIN login is disabled for the user.
+-- stock_ledger.created_by records the operator behind each movement.
+
+ALTER TABLE users
+    ADD COLUMN pin_hash TEXT;
+
+ALTER TABLE stock_ledger
+    ADD COLUMN created_by TEXT;
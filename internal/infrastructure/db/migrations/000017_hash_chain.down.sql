@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS audit_log_no_update;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update
+BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+ALTER TABLE audit_log DROP COLUMN row_hash;
+ALTER TABLE audit_log DROP COLUMN prev_hash;
+ALTER TABLE stock_ledger DROP COLUMN row_hash;
+ALTER TABLE stock_ledger DROP COLUMN prev_hash;
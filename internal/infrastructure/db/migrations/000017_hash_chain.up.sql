@@ -0,0 +1,33 @@
+-- Tamper-evident hash chain on stock_ledger and audit_log.
+-- row_hash = sha256(prev_hash || canonical row content); prev_hash links to the previous row.
+-- Rows written before this migration are sealed once at startup.
+
+ALTER TABLE stock_ledger
+    ADD COLUMN prev_hash TEXT;
+
+ALTER TABLE stock_ledger
+    ADD COLUMN row_hash TEXT;
+
+ALTER TABLE audit_log
+    ADD COLUMN prev_hash TEXT;
+
+ALTER TABLE audit_log
+    ADD COLUMN row_hash TEXT;
+
+-- Audit rows stay append-only; the only permitted update is sealing an unsealed row.
+DROP TRIGGER IF EXISTS audit_log_no_update;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update
+BEFORE UPDATE ON audit_log
+WHEN OLD.row_hash IS NOT NULL
+    OR NEW.id IS NOT OLD.id
+    OR NEW.actor IS NOT OLD.actor
+    OR NEW.action IS NOT OLD.action
+    OR NEW.entity_type IS NOT OLD.entity_type
+    OR NEW.entity_id IS NOT OLD.entity_id
+    OR NEW.before_json IS NOT OLD.before_json
+    OR NEW.after_json IS NOT OLD.after_json
+    OR NEW.created_at IS NOT OLD.created_at
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1,14 @@
+-- Responses to create/record API calls, keyed by the client's Idempotency-Key header
+-- so a retried request is answered from here instead of being applied twice.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key TEXT     PRIMARY KEY,
+    request_hash    TEXT     NOT NULL,
+    status_code     INTEGER  NOT NULL,
+    content_type    TEXT     NOT NULL DEFAULT '',
+    response_body   BLOB     NOT NULL,
+    created_at      DATETIME NOT NULL,
+    expires_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
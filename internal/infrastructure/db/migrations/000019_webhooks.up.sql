@@ -0,0 +1,42 @@
+-- Outbound webhooks: Admin-managed subscriptions, a persistent outbox so deliveries survive
+-- restarts and are retried, and a log of every delivery attempt.
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        TEXT     NOT NULL,
+    url         TEXT     NOT NULL,
+    event_types TEXT     NOT NULL,
+    secret      TEXT     NOT NULL,
+    is_active   BOOLEAN  NOT NULL DEFAULT 1,
+    created_at  DATETIME NOT NULL,
+    updated_at  DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    subscription_id INTEGER  NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_type      TEXT     NOT NULL,
+    payload         TEXT     NOT NULL,
+    status          TEXT     NOT NULL DEFAULT 'PENDING',
+    attempts        INTEGER  NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_error      TEXT     NOT NULL DEFAULT '',
+    created_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_due ON webhook_outbox(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    outbox_id       INTEGER  NOT NULL,
+    subscription_id INTEGER  NOT NULL,
+    event_type      TEXT     NOT NULL,
+    attempt         INTEGER  NOT NULL,
+    status_code     INTEGER  NOT NULL DEFAULT 0,
+    success         BOOLEAN  NOT NULL DEFAULT 0,
+    error           TEXT     NOT NULL DEFAULT '',
+    duration_ms     INTEGER  NOT NULL DEFAULT 0,
+    attempted_at    DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, attempted_at);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS invoices_no_delete;
+DROP TRIGGER IF EXISTS invoices_no_update;
+DROP INDEX IF EXISTS idx_invoices_customer;
+DROP TABLE IF EXISTS invoices;
@@ -0,0 +1,32 @@
+-- GST tax invoices. data holds the invoice exactly as issued and pdf the document rendered at
+-- issue time, so a reprint is the original. Numbers run per financial year without gaps.
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+    invoice_number     TEXT     NOT NULL UNIQUE,
+    financial_year     TEXT     NOT NULL,
+    sequence           INTEGER  NOT NULL,
+    invoice_date       DATETIME NOT NULL,
+    dispatch_reference TEXT     NOT NULL UNIQUE,
+    customer_id        INTEGER  NOT NULL REFERENCES parties(id),
+    grand_total        REAL     NOT NULL,
+    data               TEXT     NOT NULL,
+    pdf                BLOB     NOT NULL,
+    created_by         TEXT     NOT NULL DEFAULT '',
+    created_at         DATETIME NOT NULL,
+    UNIQUE (financial_year, sequence)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_customer ON invoices(customer_id, id);
+
+CREATE TRIGGER IF NOT EXISTS invoices_no_update
+BEFORE UPDATE ON invoices
+BEGIN
+    SELECT RAISE(ABORT, 'issued invoices cannot be changed');
+END;
+
+CREATE TRIGGER IF NOT EXISTS invoices_no_delete
+BEFORE DELETE ON invoices
+BEGIN
+    SELECT RAISE(ABORT, 'issued invoices cannot be deleted');
+END;
@@ -0,0 +1,3 @@
+-- The journal triggers are dropped by the application when it finds no journal table.
+DROP TABLE IF EXISTS change_journal_meta;
+DROP TABLE IF EXISTS change_journal;
@@ -0,0 +1,21 @@
+-- Row changes recorded by triggers the application installs at startup, one set per
+-- table, so changes made since the last backup can be shipped to the backup folder and
+-- replayed onto it. Shipped rows are deleted; seq keeps counting because of AUTOINCREMENT.
+-- The generation changes whenever the database starts a new history, such as after a
+-- restore or a schema upgrade, so journals of different histories are never mixed.
+
+CREATE TABLE IF NOT EXISTS change_journal (
+    seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+    generation TEXT    NOT NULL,
+    changed_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    table_name TEXT    NOT NULL,
+    op         TEXT    NOT NULL CHECK (op IN ('I', 'U', 'D')),
+    row_key    TEXT    NOT NULL,
+    row_data   TEXT
+);
+
+CREATE TABLE IF NOT EXISTS change_journal_meta (
+    id             INTEGER PRIMARY KEY CHECK (id = 1),
+    generation     TEXT    NOT NULL,
+    schema_version INTEGER NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS hash_chain_seals;
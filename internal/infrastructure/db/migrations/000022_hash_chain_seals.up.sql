@@ -0,0 +1,8 @@
+-- Records which hash-chained tables have had their legacy rows sealed. Sealing happens
+-- once per table; afterwards an unsealed row is reported as a broken chain instead of
+-- being hashed, so clearing the hashes cannot be used to rebuild a chain over edited rows.
+
+CREATE TABLE IF NOT EXISTS hash_chain_seals (
+    table_name TEXT PRIMARY KEY,
+    sealed_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
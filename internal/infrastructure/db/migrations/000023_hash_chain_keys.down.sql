@@ -0,0 +1 @@
+ALTER TABLE hash_chain_seals DROP COLUMN keyed;
@@ -0,0 +1,5 @@
+-- Hash chains are sealed with an HMAC under a key kept outside the database. Tables
+-- sealed before that keep keyed = 0 until the application has verified their plain
+-- SHA-256 chain and resealed it under the key.
+
+ALTER TABLE hash_chain_seals ADD COLUMN keyed INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE grns DROP COLUMN created_by;
@@ -0,0 +1,4 @@
+-- Records who received each GRN, alongside stock_ledger.created_by on its IN movements.
+
+ALTER TABLE grns
+    ADD COLUMN created_by TEXT;
@@ -1,9 +1,11 @@
 package db
 
 import (
+	"errors"
 	"masala_inventory_managment"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -62,3 +64,141 @@ func TestMigrator_RunMigrations_Integration(t *testing.T) {
 		t.Errorf("Default admin role not found: %v", err)
 	}
 }
+
+func TestMigrator_StatusDownAndForce(t *testing.T) {
+	manager := NewDatabaseManager(filepath.Join(t.TempDir(), "migration_test.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer manager.Close()
+	migrator := NewMigrator(manager)
+	assets, dir := masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"
+
+	status, err := migrator.Status(assets, dir)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Version != 0 || status.Latest == 0 || len(status.Pending) != int(status.Latest) || status.Pending[0].Name != "initial_schema" {
+		t.Fatalf("unexpected status of an empty database: %+v", status)
+	}
+
+	if err := migrator.RunMigrations(assets, dir); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if status, _ = migrator.Status(assets, dir); status.Version != status.Latest || status.Dirty || len(status.Pending) != 0 {
+		t.Fatalf("unexpected status after migrating: %+v", status)
+	}
+	latest := status.Latest
+
+	// Revert back past 000021_change_journal.
+	steps := int(latest) - 20
+	if err := migrator.Down(assets, dir, steps); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	status, _ = migrator.Status(assets, dir)
+	if status.Version != 20 || len(status.Pending) != steps || status.Pending[steps-1].Version != latest {
+		t.Fatalf("unexpected status after reverting %d migrations: %+v", steps, status)
+	}
+	var triggers int
+	if err := manager.GetDB().QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'change_journal_%'").Scan(&triggers); err != nil || triggers != 0 {
+		t.Errorf("expected no journal triggers once the journal is reverted: %d, %v", triggers, err)
+	}
+	if _, err := manager.GetDB().Exec("UPDATE roles SET name = name"); err != nil {
+		t.Errorf("writes fail after reverting the journal: %v", err)
+	}
+	if err := migrator.Down(assets, dir, 0); err == nil {
+		t.Error("expected reverting zero migrations to be rejected")
+	}
+
+	// A migration that failed part-way leaves the version dirty until it is forced.
+	if _, err := manager.GetDB().Exec("UPDATE schema_migrations SET version = 21, dirty = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.RunMigrations(assets, dir); err == nil {
+		t.Fatal("expected a dirty schema to stop migrations")
+	}
+	if err := migrator.Force(assets, dir, 20); err != nil {
+		t.Fatalf("Force failed: %v", err)
+	}
+	if err := migrator.RunMigrations(assets, dir); err != nil {
+		t.Fatalf("Failed to migrate after forcing: %v", err)
+	}
+	if status, _ = migrator.Status(assets, dir); status.Version != latest || status.Dirty {
+		t.Errorf("unexpected status after forcing and migrating: %+v", status)
+	}
+}
+
+func TestMigrator_BackupBeforeChanges(t *testing.T) {
+	manager := NewDatabaseManager(filepath.Join(t.TempDir(), "migration_test.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer manager.Close()
+	migrator := NewMigrator(manager)
+	assets, dir := masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"
+	backups := 0
+	backupErr := error(nil)
+	migrator.BackupBeforeChanges(func() error {
+		backups++
+		return backupErr
+	})
+
+	if err := migrator.RunMigrations(assets, dir); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if err := migrator.RunMigrations(assets, dir); err != nil {
+		t.Fatalf("Failed to re-run migrations: %v", err)
+	}
+	if backups != 0 {
+		t.Errorf("expected no backup of a new or up-to-date database, got %d", backups)
+	}
+
+	backupErr = errors.New("disk full")
+	if err := migrator.Down(assets, dir, 1); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected the failed backup to stop the revert, got %v", err)
+	}
+	status, _ := migrator.Status(assets, dir)
+	if len(status.Pending) != 0 {
+		t.Fatalf("expected nothing reverted, got %+v", status)
+	}
+
+	backupErr = nil
+	if err := migrator.Down(assets, dir, 1); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	backupErr = errors.New("disk full")
+	if err := migrator.RunMigrations(assets, dir); err == nil {
+		t.Fatal("expected the failed backup to stop migrations")
+	}
+	if status, _ = migrator.Status(assets, dir); len(status.Pending) != 1 || backups != 3 {
+		t.Errorf("expected nothing migrated after three backups, got %+v after %d", status, backups)
+	}
+}
+
+func TestMigrator_DryRunLeavesDatabaseUnchanged(t *testing.T) {
+	manager := NewDatabaseManager(filepath.Join(t.TempDir(), "migration_test.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer manager.Close()
+	migrator := NewMigrator(manager)
+	assets, dir := masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"
+	if err := migrator.RunMigrations(assets, dir); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if err := migrator.Down(assets, dir, 1); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+
+	result, err := migrator.DryRun(assets, dir)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if result.Version != result.Latest || len(result.Pending) != 0 {
+		t.Errorf("expected the copy fully migrated: %+v", result)
+	}
+	status, _ := migrator.Status(assets, dir)
+	if status.Version != result.Latest-1 || len(status.Pending) != 1 {
+		t.Errorf("expected the database itself unchanged: %+v", status)
+	}
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+)
+
+// sortColumn is a selectable sort key. expr is used both in ORDER BY and in keyset comparisons.
+// Numeric keys are carried in the cursor as numbers; all others as their stored text.
+type sortColumn struct {
+	expr        string
+	numeric     bool
+	defaultDesc bool
+}
+
+// pageCursor is the decoded form of PageInfo.NextCursor: the sort key value and id of the last row returned.
+type pageCursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d"`
+	Value  any    `json:"v"`
+	ID     int64  `json:"i"`
+}
+
+// pageQuery is a validated PageRequest bound to one list's sort columns.
+type pageQuery struct {
+	sortBy   string
+	column   sortColumn
+	idColumn string
+	desc     bool
+	limit    int
+	after    *pageCursor
+}
+
+func resolvePageQuery(page domainInventory.PageRequest, columns map[string]sortColumn, defaultSort, idColumn string) (pageQuery, error) {
+	page.Normalize()
+	if err := page.Validate(); err != nil {
+		return pageQuery{}, err
+	}
+
+	sortBy := page.SortBy
+	if sortBy == "" {
+		sortBy = defaultSort
+	}
+	column, ok := columns[sortBy]
+	if !ok {
+		return pageQuery{}, domainInventory.ErrSortKeyInvalid
+	}
+
+	desc := column.defaultDesc
+	switch page.SortOrder {
+	case domainInventory.SortOrderAsc:
+		desc = false
+	case domainInventory.SortOrderDesc:
+		desc = true
+	}
+
+	query := pageQuery{sortBy: sortBy, column: column, idColumn: idColumn, desc: desc, limit: page.Limit}
+	if page.Cursor != "" {
+		cursor, err := decodePageCursor(page.Cursor)
+		if err != nil || cursor.SortBy != sortBy || cursor.Desc != desc {
+			return pageQuery{}, domainInventory.ErrCursorInvalid
+		}
+		query.after = cursor
+	}
+	return query, nil
+}
+
+func decodePageCursor(raw string) (*pageCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func encodePageCursor(cursor pageCursor) string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// sortValueColumn selects the raw stored sort key so the cursor replays SQLite's own comparison
+// (DATETIME columns would otherwise be re-formatted by the driver).
+func (p pageQuery) sortValueColumn() string {
+	expr := strings.TrimSuffix(p.column.expr, " COLLATE NOCASE")
+	if p.column.numeric {
+		return expr
+	}
+	return "CAST(" + expr + " AS TEXT)"
+}
+
+// keysetClause restricts rows to those strictly after the cursor in sort order.
+func (p pageQuery) keysetClause() (string, []any) {
+	if p.after == nil {
+		return "", nil
+	}
+	op := ">"
+	if p.desc {
+		op = "<"
+	}
+	clause := "(" + p.column.expr + " " + op + " ? OR (" + p.column.expr + " = ? AND " + p.idColumn + " " + op + " ?))"
+	return clause, []any{p.after.Value, p.after.Value, p.after.ID}
+}
+
+func (p pageQuery) orderBy() string {
+	direction := "ASC"
+	if p.desc {
+		direction = "DESC"
+	}
+	return " ORDER BY " + p.column.expr + " " + direction + ", " + p.idColumn + " " + direction
+}
+
+// queryPage counts the filtered rows, then reads one page. scanRow must scan the selected columns
+// followed by the sort value column and return the row id.
+func queryPage(
+	db *sql.DB,
+	page pageQuery,
+	selectColumns, from string,
+	clauses []string,
+	args []any,
+	scanRow func(rows *sql.Rows, sortValue *any) (int64, error),
+) (domainInventory.PageInfo, error) {
+	var info domainInventory.PageInfo
+
+	where := ""
+	if len(clauses) > 0 {
+		where = " WHERE " + strings.Join(clauses, " AND ")
+	}
+	if err := db.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM "+from+where, args...).Scan(&info.TotalCount); err != nil {
+		return info, err
+	}
+
+	pageClauses := clauses
+	pageArgs := append([]any{}, args...)
+	if clause, keysetArgs := page.keysetClause(); clause != "" {
+		pageClauses = append(append([]string{}, clauses...), clause)
+		pageArgs = append(pageArgs, keysetArgs...)
+	}
+	statement := "SELECT " + selectColumns + ", " + page.sortValueColumn() + " FROM " + from
+	if len(pageClauses) > 0 {
+		statement += " WHERE " + strings.Join(pageClauses, " AND ")
+	}
+	statement += page.orderBy()
+	if page.limit > 0 {
+		// One extra row tells us whether another page exists.
+		statement += " LIMIT ?"
+		pageArgs = append(pageArgs, page.limit+1)
+	}
+
+	rows, err := db.QueryContext(context.Background(), statement, pageArgs...)
+	if err != nil {
+		return info, err
+	}
+	defer rows.Close()
+
+	var (
+		count     int
+		lastID    int64
+		lastValue any
+	)
+	for rows.Next() {
+		if page.limit > 0 && count == page.limit {
+			info.NextCursor = encodePageCursor(pageCursor{SortBy: page.sortBy, Desc: page.desc, Value: normalizeAuditValue(lastValue), ID: lastID})
+			break
+		}
+		id, err := scanRow(rows, &lastValue)
+		if err != nil {
+			return info, err
+		}
+		lastID = id
+		count++
+	}
+	return info, rows.Err()
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+)
+
+func collectItemPages(t *testing.T, repo *SqliteInventoryRepository, page domainInventory.PageRequest) ([]string, int, int) {
+	t.Helper()
+	names := make([]string, 0)
+	pages := 0
+	total := 0
+	for {
+		items, info, err := repo.ListItems(domainInventory.ItemListFilter{Page: page})
+		if err != nil {
+			t.Fatalf("ListItems failed: %v", err)
+		}
+		pages++
+		total = info.TotalCount
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		if info.NextCursor == "" {
+			return names, pages, total
+		}
+		if pages > 10 {
+			t.Fatalf("pagination did not terminate")
+		}
+		page.Cursor = info.NextCursor
+	}
+}
+
+func TestListItems_PagesThroughAllRowsInSortOrder(t *testing.T) {
+	repo, _ := setupInventoryRepo(t)
+	for i, name := range []string{"Cumin", "anise", "Bay Leaf", "Dill", "Elaichi"} {
+		createTestInventoryItem(t, repo, domainInventory.ItemTypeRaw, "SKU-PG-"+string(rune('1'+i)), name, "kg")
+	}
+
+	names, pages, total := collectItemPages(t, repo, domainInventory.PageRequest{Limit: 2})
+	if total != 5 || pages != 3 {
+		t.Fatalf("expected 5 rows over 3 pages, got %d rows over %d pages", total, pages)
+	}
+	expected := []string{"anise", "Bay Leaf", "Cumin", "Dill", "Elaichi"}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected case-insensitive name order %v, got %v", expected, names)
+		}
+	}
+
+	names, _, _ = collectItemPages(t, repo, domainInventory.PageRequest{Limit: 2, SortBy: "created_at", SortOrder: "desc"})
+	if len(names) != 5 {
+		t.Fatalf("expected every row exactly once when sort values tie, got %v", names)
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			t.Fatalf("row %q returned twice: %v", name, names)
+		}
+		seen[name] = true
+	}
+}
+
+func TestListItems_RejectsInvalidPageRequests(t *testing.T) {
+	repo, _ := setupInventoryRepo(t)
+	createTestInventoryItem(t, repo, domainInventory.ItemTypeRaw, "SKU-PG-A", "Ajwain", "kg")
+	createTestInventoryItem(t, repo, domainInventory.ItemTypeRaw, "SKU-PG-B", "Badi Elaichi", "kg")
+
+	_, info, err := repo.ListItems(domainInventory.ItemListFilter{Page: domainInventory.PageRequest{Limit: 1}})
+	if err != nil || info.NextCursor == "" {
+		t.Fatalf("expected a next cursor, got %+v (%v)", info, err)
+	}
+
+	cases := []struct {
+		page domainInventory.PageRequest
+		want error
+	}{
+		{domainInventory.PageRequest{Limit: 1, Cursor: info.NextCursor, SortOrder: "DESC"}, domainInventory.ErrCursorInvalid},
+		{domainInventory.PageRequest{Limit: 1, Cursor: "not-a-cursor"}, domainInventory.ErrCursorInvalid},
+		{domainInventory.PageRequest{SortBy: "minimum_stock"}, domainInventory.ErrSortKeyInvalid},
+		{domainInventory.PageRequest{SortOrder: "SIDEWAYS"}, domainInventory.ErrSortOrderInvalid},
+		{domainInventory.PageRequest{Limit: domainInventory.MaxPageLimit + 1}, domainInventory.ErrPageLimitInvalid},
+	}
+	for _, tc := range cases {
+		if _, _, err := repo.ListItems(domainInventory.ItemListFilter{Page: tc.page}); !errors.Is(err, tc.want) {
+			t.Fatalf("page %+v: expected %v, got %v", tc.page, tc.want, err)
+		}
+	}
+}
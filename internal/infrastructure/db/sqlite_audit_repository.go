@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+)
+
+// redactedAuditColumns are never copied into audit snapshots.
+var redactedAuditColumns = map[string]bool{
+	"password_hash": true,
+	"pin_hash":      true,
+}
+
+// auditChild describes a child table whose rows are embedded in a parent snapshot.
+type auditChild struct {
+	table      string
+	foreignKey string
+}
+
+// auditScope carries the actor for audit rows written by a repository.
+type auditScope struct {
+	actor string
+}
+
+func (s auditScope) resolvedActor() string {
+	actor := strings.TrimSpace(s.actor)
+	if actor == "" {
+		return domainAudit.SystemActor
+	}
+	return actor
+}
+
+// runInTx executes fn inside a transaction, committing only when fn succeeds.
+func runInTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// snapshotRowTx returns a JSON snapshot of a single row (plus optional child rows).
+// It returns an empty string when the row does not exist.
+func snapshotRowTx(tx *sql.Tx, table, keyColumn string, key interface{}, children ...auditChild) (string, error) {
+	rows, err := queryRowMapsTx(tx, fmt.Sprintf("SELECT * FROM %s WHERE %s = ?", table, keyColumn), key)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	snapshot := rows[0]
+	for _, child := range children {
+		childRows, err := queryRowMapsTx(tx, fmt.Sprintf("SELECT * FROM %s WHERE %s = ? ORDER BY id ASC", child.table, child.foreignKey), snapshot["id"])
+		if err != nil {
+			return "", err
+		}
+		snapshot[child.table] = childRows
+	}
+
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// rowQueryer is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type rowQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryRowMapsTx(tx *sql.Tx, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	return queryRowMaps(tx, query, args...)
+}
+
+func queryRowMaps(q rowQueryer, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := q.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if redactedAuditColumns[column] {
+				if values[i] != nil {
+					row[column] = "[redacted]"
+				}
+				continue
+			}
+			row[column] = normalizeAuditValue(values[i])
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func normalizeAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// insertAuditTx appends an audit row inside the caller's transaction.
+func insertAuditTx(tx *sql.Tx, scope auditScope, action, entityType, entityID, before, after string) error {
+	res, err := tx.ExecContext(
+		context.Background(),
+		`INSERT INTO audit_log (actor, action, entity_type, entity_id, before_json, after_json, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		scope.resolvedActor(),
+		action,
+		entityType,
+		entityID,
+		nullIfEmpty(before),
+		nullIfEmpty(after),
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := sealChainRowTx(tx, auditLogChain, id); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// SqliteAuditRepository reads the audit log.
+type SqliteAuditRepository struct {
+	db *sql.DB
+}
+
+func NewSqliteAuditRepository(db *sql.DB) *SqliteAuditRepository {
+	return &SqliteAuditRepository{db: db}
+}
+
+func (r *SqliteAuditRepository) List(filter domainAudit.ListFilter) ([]domainAudit.Entry, error) {
+	args := make([]any, 0, 6)
+	clauses := make([]string, 0, 6)
+	if actor := strings.TrimSpace(filter.Actor); actor != "" {
+		clauses = append(clauses, "actor = ? COLLATE NOCASE")
+		args = append(args, actor)
+	}
+	if action := strings.TrimSpace(filter.Action); action != "" {
+		clauses = append(clauses, "action = ?")
+		args = append(args, strings.ToUpper(action))
+	}
+	if entityType := strings.TrimSpace(filter.EntityType); entityType != "" {
+		clauses = append(clauses, "entity_type = ?")
+		args = append(args, entityType)
+	}
+	if entityID := strings.TrimSpace(filter.EntityID); entityID != "" {
+		clauses = append(clauses, "entity_id = ?")
+		args = append(args, entityID)
+	}
+	if !filter.From.IsZero() {
+		clauses = append(clauses, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		clauses = append(clauses, "created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.BeforeID > 0 {
+		clauses = append(clauses, "(created_at, id) < (SELECT created_at, id FROM audit_log WHERE id = ?)")
+		args = append(args, filter.BeforeID)
+	}
+
+	statement := `SELECT id, actor, action, entity_type, entity_id, COALESCE(before_json, ''), COALESCE(after_json, ''), created_at FROM audit_log`
+	if len(clauses) > 0 {
+		statement += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	statement += " ORDER BY created_at DESC, id DESC"
+	if filter.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(context.Background(), statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]domainAudit.Entry, 0)
+	for rows.Next() {
+		var entry domainAudit.Entry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Actor,
+			&entry.Action,
+			&entry.EntityType,
+			&entry.EntityID,
+			&entry.BeforeJSON,
+			&entry.AfterJSON,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	domainAudit "masala_inventory_managment/internal/domain/audit"
+	"masala_inventory_managment/internal/domain/auth"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+)
+
+func TestSqliteAuditRepository_RecordsInventoryChangesWithActor(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	auditRepo := NewSqliteAuditRepository(manager.GetDB())
+
+	item := &domainInventory.Item{
+		SKU:      "RAW-AUD-1",
+		Name:     "Audit Cumin",
+		ItemType: domainInventory.ItemTypeRaw,
+		BaseUnit: "kg",
+		IsActive: true,
+	}
+	if err := repo.WithActor("operator-a").CreateItem(item); err != nil {
+		t.Fatalf("CreateItem failed: %v", err)
+	}
+
+	item.Name = "Audit Cumin Seeds"
+	if err := repo.WithActor("admin-b").UpdateItem(item); err != nil {
+		t.Fatalf("UpdateItem failed: %v", err)
+	}
+
+	entries, err := auditRepo.List(domainAudit.ListFilter{EntityType: "item", EntityID: strconv.FormatInt(item.ID, 10)})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(entries))
+	}
+
+	update, create := entries[0], entries[1]
+	if create.Actor != "operator-a" || create.Action != domainAudit.ActionCreate || create.BeforeJSON != "" {
+		t.Fatalf("unexpected create entry: %#v", create)
+	}
+	if !strings.Contains(create.AfterJSON, "Audit Cumin") {
+		t.Fatalf("expected create snapshot to contain item name, got %s", create.AfterJSON)
+	}
+	if update.Actor != "admin-b" || update.Action != domainAudit.ActionUpdate {
+		t.Fatalf("unexpected update entry: %#v", update)
+	}
+	if strings.Contains(update.BeforeJSON, "Cumin Seeds") || !strings.Contains(update.AfterJSON, "Cumin Seeds") {
+		t.Fatalf("expected before/after snapshots to differ, got before=%s after=%s", update.BeforeJSON, update.AfterJSON)
+	}
+
+	byActor, err := auditRepo.List(domainAudit.ListFilter{Actor: "admin-b"})
+	if err != nil {
+		t.Fatalf("List by actor failed: %v", err)
+	}
+	if len(byActor) != 1 {
+		t.Fatalf("expected 1 entry for admin-b, got %d", len(byActor))
+	}
+
+	older, err := auditRepo.List(domainAudit.ListFilter{EntityType: "item", BeforeID: update.ID})
+	if err != nil {
+		t.Fatalf("List before id failed: %v", err)
+	}
+	if len(older) != 1 || older[0].ID != create.ID {
+		t.Fatalf("expected paging past the update to return only the create entry, got %#v", older)
+	}
+}
+
+func TestSqliteAuditRepository_FailedWriteLeavesNoAuditRow(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	auditRepo := NewSqliteAuditRepository(manager.GetDB())
+
+	item := &domainInventory.Item{
+		SKU:      "RAW-AUD-2",
+		Name:     "Audit Pepper",
+		ItemType: domainInventory.ItemTypeRaw,
+		BaseUnit: "kg",
+		IsActive: true,
+	}
+	if err := repo.CreateItem(item); err != nil {
+		t.Fatalf("CreateItem failed: %v", err)
+	}
+
+	stale := *item
+	stale.UpdatedAt = stale.UpdatedAt.Add(-1)
+	stale.Name = "Stale Pepper"
+	if err := repo.WithActor("admin").UpdateItem(&stale); err == nil {
+		t.Fatalf("expected concurrency conflict")
+	}
+
+	entries, err := auditRepo.List(domainAudit.ListFilter{Action: domainAudit.ActionUpdate})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no audit entries for rolled back update, got %d", len(entries))
+	}
+}
+
+func TestSqliteAuditRepository_UserChangesAreRedactedAndAppendOnly(t *testing.T) {
+	_, manager := setupInventoryRepo(t)
+	userRepo := NewSqliteUserRepository(manager.GetDB())
+	auditRepo := NewSqliteAuditRepository(manager.GetDB())
+
+	if err := userRepo.WithActor("admin").Save(auth.NewUser("packer", "secret_hash", auth.RoleDataEntryOperator)); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := userRepo.WithActor("admin").UpdatePasswordHash("packer", "rotated_hash"); err != nil {
+		t.Fatalf("UpdatePasswordHash failed: %v", err)
+	}
+
+	entries, err := auditRepo.List(domainAudit.ListFilter{EntityType: "user", EntityID: "packer"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 user audit entries, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.BeforeJSON+entry.AfterJSON, "rotated_hash") || strings.Contains(entry.BeforeJSON+entry.AfterJSON, "secret_hash") {
+			t.Fatalf("expected password hash to be redacted, got %#v", entry)
+		}
+	}
+	if entries[0].Action != domainAudit.ActionResetPassword {
+		t.Fatalf("expected latest action RESET_PASSWORD, got %s", entries[0].Action)
+	}
+
+	if _, err := manager.GetDB().Exec("DELETE FROM audit_log"); err == nil {
+		t.Fatalf("expected audit_log delete to be rejected")
+	}
+	if _, err := manager.GetDB().Exec("UPDATE audit_log SET actor = 'someone-else'"); err == nil {
+		t.Fatalf("expected audit_log update to be rejected")
+	}
+}
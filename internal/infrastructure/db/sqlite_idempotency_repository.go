@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	domainIdempotency "masala_inventory_managment/internal/domain/idempotency"
+)
+
+type SqliteIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewSqliteIdempotencyRepository(db *sql.DB) *SqliteIdempotencyRepository {
+	return &SqliteIdempotencyRepository{db: db}
+}
+
+func (r *SqliteIdempotencyRepository) Get(key string, now time.Time) (*domainIdempotency.Record, error) {
+	var record domainIdempotency.Record
+	err := r.db.QueryRowContext(
+		context.Background(),
+		`SELECT idempotency_key, request_hash, status_code, content_type, response_body, created_at, expires_at
+		 FROM idempotency_keys WHERE idempotency_key = ? AND expires_at > ?`,
+		key, now.UTC(),
+	).Scan(
+		&record.Key,
+		&record.RequestHash,
+		&record.StatusCode,
+		&record.ContentType,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domainIdempotency.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *SqliteIdempotencyRepository) Save(record *domainIdempotency.Record) error {
+	if record == nil {
+		return fmt.Errorf("idempotency record is nil")
+	}
+	body := record.ResponseBody
+	if body == nil {
+		body = []byte{}
+	}
+	// An expired row may still be present; the key is free again once its window has passed.
+	_, err := r.db.ExecContext(
+		context.Background(),
+		`INSERT INTO idempotency_keys (idempotency_key, request_hash, status_code, content_type, response_body, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(idempotency_key) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status_code = excluded.status_code,
+			content_type = excluded.content_type,
+			response_body = excluded.response_body,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+		 WHERE idempotency_keys.expires_at <= excluded.created_at`,
+		record.Key, record.RequestHash, record.StatusCode, record.ContentType, body,
+		record.CreatedAt.UTC(), record.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+func (r *SqliteIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(context.Background(), `DELETE FROM idempotency_keys WHERE expires_at <= ?`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	domainIdempotency "masala_inventory_managment/internal/domain/idempotency"
+)
+
+func TestIdempotencyRepository_StoresUntilExpiry(t *testing.T) {
+	_, manager := setupInventoryRepo(t)
+	repo := NewSqliteIdempotencyRepository(manager.GetDB())
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	record := &domainIdempotency.Record{
+		Key:          "key-1",
+		RequestHash:  "hash-a",
+		StatusCode:   200,
+		ContentType:  "application/json",
+		ResponseBody: []byte(`{"id":1}`),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(time.Hour),
+	}
+	if err := repo.Save(record); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	got, err := repo.Get("key-1", now.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.RequestHash != "hash-a" || string(got.ResponseBody) != `{"id":1}` || got.ContentType != "application/json" {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+
+	// An unexpired key is never overwritten.
+	if err := repo.Save(&domainIdempotency.Record{Key: "key-1", RequestHash: "hash-b", StatusCode: 201, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got, _ := repo.Get("key-1", now); got.RequestHash != "hash-a" {
+		t.Fatalf("expected original record to be kept, got %+v", got)
+	}
+
+	later := now.Add(2 * time.Hour)
+	if _, err := repo.Get("key-1", later); !errors.Is(err, domainIdempotency.ErrRecordNotFound) {
+		t.Fatalf("expected expired record to be hidden, got %v", err)
+	}
+	if err := repo.Save(&domainIdempotency.Record{Key: "key-1", RequestHash: "hash-c", StatusCode: 201, CreatedAt: later, ExpiresAt: later.Add(time.Hour)}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got, _ := repo.Get("key-1", later); got == nil || got.RequestHash != "hash-c" {
+		t.Fatalf("expected expired key to be reusable, got %+v", got)
+	}
+
+	if err := repo.Save(&domainIdempotency.Record{Key: "key-2", RequestHash: "h", StatusCode: 200, CreatedAt: now, ExpiresAt: now.Add(time.Minute)}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	deleted, err := repo.DeleteExpired(later)
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected one expired key to be purged, got %d (%v)", deleted, err)
+	}
+}
@@ -9,12 +9,14 @@ import (
 	"strings"
 	"time"
 
+	domainAudit "masala_inventory_managment/internal/domain/audit"
 	domainErrors "masala_inventory_managment/internal/domain/errors"
 	domainInventory "masala_inventory_managment/internal/domain/inventory"
 )
 
 type SqliteInventoryRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	audit auditScope
 }
 
 func itemDetailsTable(itemType domainInventory.ItemType) string {
@@ -32,7 +34,7 @@ func itemDetailsTable(itemType domainInventory.ItemType) string {
 	}
 }
 
-func (r *SqliteInventoryRepository) ensureItemDetailsRow(item *domainInventory.Item) error {
+func ensureItemDetailsRowTx(tx *sql.Tx, item *domainInventory.Item) error {
 	if item == nil || item.ID <= 0 {
 		return nil
 	}
@@ -43,7 +45,7 @@ func (r *SqliteInventoryRepository) ensureItemDetailsRow(item *domainInventory.I
 	}
 
 	statement := fmt.Sprintf("INSERT OR IGNORE INTO %s (item_id) VALUES (?)", table)
-	_, err := r.db.ExecContext(context.Background(), statement, item.ID)
+	_, err := tx.ExecContext(context.Background(), statement, item.ID)
 	return err
 }
 
@@ -51,6 +53,22 @@ func NewSqliteInventoryRepository(db *sql.DB) *SqliteInventoryRepository {
 	return &SqliteInventoryRepository{db: db}
 }
 
+// WithActor returns a repository whose writes are attributed to actor in the audit log.
+func (r *SqliteInventoryRepository) WithActor(actor string) domainInventory.Repository {
+	scoped := *r
+	scoped.audit = auditScope{actor: actor}
+	return &scoped
+}
+
+// auditTx snapshots the row after the change and appends the audit entry in tx.
+func (r *SqliteInventoryRepository) auditTx(tx *sql.Tx, action, entityType, table string, id int64, before string, children ...auditChild) error {
+	after, err := snapshotRowTx(tx, table, "id", id, children...)
+	if err != nil {
+		return err
+	}
+	return insertAuditTx(tx, r.audit, action, entityType, strconv.FormatInt(id, 10), before, after)
+}
+
 func (r *SqliteInventoryRepository) CreateItem(item *domainInventory.Item) error {
 	if err := item.ValidateMasterContract(); err != nil {
 		return err
@@ -63,7 +81,13 @@ func (r *SqliteInventoryRepository) CreateItem(item *domainInventory.Item) error
 		item.UpdatedAt = item.CreatedAt
 	}
 
-	res, err := r.db.ExecContext(
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		return r.insertItemTx(tx, item)
+	})
+}
+
+func (r *SqliteInventoryRepository) insertItemTx(tx *sql.Tx, item *domainInventory.Item) error {
+	res, err := tx.ExecContext(
 		context.Background(),
 		`INSERT INTO items (sku, name, category, unit, item_type, base_unit, item_subtype, minimum_stock, is_active, created_at, updated_at)
 		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
@@ -79,7 +103,10 @@ func (r *SqliteInventoryRepository) CreateItem(item *domainInventory.Item) error
 	}
 	item.ID = id
 
-	return r.ensureItemDetailsRow(item)
+	if err := ensureItemDetailsRowTx(tx, item); err != nil {
+		return err
+	}
+	return r.auditTx(tx, domainAudit.ActionCreate, "item", "items", item.ID, "")
 }
 
 func (r *SqliteInventoryRepository) UpdateItem(item *domainInventory.Item) error {
@@ -87,33 +114,55 @@ func (r *SqliteInventoryRepository) UpdateItem(item *domainInventory.Item) error
 		return err
 	}
 
-	res, err := r.db.ExecContext(
-		context.Background(),
-		`UPDATE items
-		 SET sku = ?, name = ?, category = ?, unit = ?, item_type = ?, base_unit = ?, item_subtype = ?, minimum_stock = ?, is_active = ?, updated_at = STRFTIME('%Y-%m-%dT%H:%M:%fZ', 'now')
-		 WHERE id = ? AND updated_at = ?`,
-		item.SKU, item.Name, item.Category, item.Unit, string(item.ItemType), item.BaseUnit, item.ItemSubtype, item.MinimumStock, item.IsActive, item.ID, item.UpdatedAt,
-	)
-	if err != nil {
-		return err
-	}
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		before, err := snapshotRowTx(tx, "items", "id", item.ID)
+		if err != nil {
+			return err
+		}
 
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return domainErrors.ErrConcurrencyConflict
-	}
+		res, err := tx.ExecContext(
+			context.Background(),
+			`UPDATE items
+			 SET sku = ?, name = ?, category = ?, unit = ?, item_type = ?, base_unit = ?, item_subtype = ?, minimum_stock = ?, is_active = ?, updated_at = STRFTIME('%Y-%m-%dT%H:%M:%fZ', 'now')
+			 WHERE id = ? AND updated_at = ?`,
+			item.SKU, item.Name, item.Category, item.Unit, string(item.ItemType), item.BaseUnit, item.ItemSubtype, item.MinimumStock, item.IsActive, item.ID, item.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	if err := r.db.QueryRowContext(context.Background(), "SELECT updated_at FROM items WHERE id = ?", item.ID).Scan(&item.UpdatedAt); err != nil {
-		return err
-	}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return domainErrors.ErrConcurrencyConflict
+		}
 
-	return r.ensureItemDetailsRow(item)
+		if err := tx.QueryRowContext(context.Background(), "SELECT updated_at FROM items WHERE id = ?", item.ID).Scan(&item.UpdatedAt); err != nil {
+			return err
+		}
+
+		if err := ensureItemDetailsRowTx(tx, item); err != nil {
+			return err
+		}
+		return r.auditTx(tx, domainAudit.ActionUpdate, "item", "items", item.ID, before)
+	})
+}
+
+var itemSortColumns = map[string]sortColumn{
+	"name":       {expr: "name COLLATE NOCASE"},
+	"sku":        {expr: "sku COLLATE NOCASE"},
+	"created_at": {expr: "created_at"},
+	"updated_at": {expr: "updated_at"},
 }
 
-func (r *SqliteInventoryRepository) ListItems(filter domainInventory.ItemListFilter) ([]domainInventory.Item, error) {
+func (r *SqliteInventoryRepository) ListItems(filter domainInventory.ItemListFilter) ([]domainInventory.Item, domainInventory.PageInfo, error) {
+	page, err := resolvePageQuery(filter.Page, itemSortColumns, "name", "id")
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, err
+	}
+
 	args := make([]any, 0, 3)
 	clauses := make([]string, 0, 3)
 	if filter.ActiveOnly {
@@ -129,44 +178,72 @@ func (r *SqliteInventoryRepository) ListItems(filter domainInventory.ItemListFil
 		args = append(args, query, query)
 	}
 
-	statement := `SELECT id, sku, name, category, unit, item_type, base_unit, COALESCE(item_subtype, ''), minimum_stock, is_active, created_at, updated_at FROM items`
-	if len(clauses) > 0 {
-		statement += " WHERE " + strings.Join(clauses, " AND ")
+	items := make([]domainInventory.Item, 0)
+	info, err := queryPage(r.db, page,
+		`id, sku, name, category, unit, item_type, base_unit, COALESCE(item_subtype, ''), minimum_stock, is_active, created_at, updated_at`,
+		"items", clauses, args,
+		func(rows *sql.Rows, sortValue *any) (int64, error) {
+			var item domainInventory.Item
+			var itemType string
+			if err := rows.Scan(
+				&item.ID,
+				&item.SKU,
+				&item.Name,
+				&item.Category,
+				&item.Unit,
+				&itemType,
+				&item.BaseUnit,
+				&item.ItemSubtype,
+				&item.MinimumStock,
+				&item.IsActive,
+				&item.CreatedAt,
+				&item.UpdatedAt,
+				sortValue,
+			); err != nil {
+				return 0, err
+			}
+			item.ItemType = domainInventory.ParseItemType(itemType)
+			item.NormalizeMasterFields()
+			items = append(items, item)
+			return item.ID, nil
+		})
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, err
 	}
-	statement += " ORDER BY name COLLATE NOCASE ASC"
+	return items, info, nil
+}
 
-	rows, err := r.db.QueryContext(context.Background(), statement, args...)
+func (r *SqliteInventoryRepository) GetItem(id int64) (*domainInventory.Item, error) {
+	var item domainInventory.Item
+	var itemType string
+	err := r.db.QueryRowContext(
+		context.Background(),
+		`SELECT id, sku, name, category, unit, item_type, base_unit, COALESCE(item_subtype, ''), minimum_stock, is_active, created_at, updated_at
+		 FROM items WHERE id = ?`,
+		id,
+	).Scan(
+		&item.ID,
+		&item.SKU,
+		&item.Name,
+		&item.Category,
+		&item.Unit,
+		&itemType,
+		&item.BaseUnit,
+		&item.ItemSubtype,
+		&item.MinimumStock,
+		&item.IsActive,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domainInventory.ErrItemNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	items := make([]domainInventory.Item, 0)
-	for rows.Next() {
-		var item domainInventory.Item
-		var itemType string
-		if err := rows.Scan(
-			&item.ID,
-			&item.SKU,
-			&item.Name,
-			&item.Category,
-			&item.Unit,
-			&itemType,
-			&item.BaseUnit,
-			&item.ItemSubtype,
-			&item.MinimumStock,
-			&item.IsActive,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		item.ItemType = domainInventory.ParseItemType(itemType)
-		item.NormalizeMasterFields()
-		items = append(items, item)
-	}
-
-	return items, rows.Err()
+	item.ItemType = domainInventory.ParseItemType(itemType)
+	item.NormalizeMasterFields()
+	return &item, nil
 }
 
 func (r *SqliteInventoryRepository) CreateBatch(batch *domainInventory.Batch) error {
@@ -177,45 +254,57 @@ func (r *SqliteInventoryRepository) CreateBatch(batch *domainInventory.Batch) er
 		batch.UpdatedAt = batch.CreatedAt
 	}
 
-	res, err := r.db.ExecContext(
-		context.Background(),
-		`INSERT INTO batches (batch_number, item_id, quantity, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?)`,
-		batch.BatchNumber, batch.ItemID, batch.Quantity, batch.CreatedAt, batch.UpdatedAt,
-	)
-	if err != nil {
-		return err
-	}
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			context.Background(),
+			`INSERT INTO batches (batch_number, item_id, quantity, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?)`,
+			batch.BatchNumber, batch.ItemID, batch.Quantity, batch.CreatedAt, batch.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	id, err := res.LastInsertId()
-	if err != nil {
-		return err
-	}
-	batch.ID = id
-	return nil
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		batch.ID = id
+		return r.auditTx(tx, domainAudit.ActionCreate, "batch", "batches", batch.ID, "")
+	})
 }
 
 func (r *SqliteInventoryRepository) UpdateBatch(batch *domainInventory.Batch) error {
-	res, err := r.db.ExecContext(
-		context.Background(),
-		`UPDATE batches
-		 SET batch_number = ?, item_id = ?, quantity = ?, updated_at = STRFTIME('%Y-%m-%dT%H:%M:%fZ', 'now')
-		 WHERE id = ? AND updated_at = ?`,
-		batch.BatchNumber, batch.ItemID, batch.Quantity, batch.ID, batch.UpdatedAt,
-	)
-	if err != nil {
-		return err
-	}
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		before, err := snapshotRowTx(tx, "batches", "id", batch.ID)
+		if err != nil {
+			return err
+		}
 
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return domainErrors.ErrConcurrencyConflict
-	}
+		res, err := tx.ExecContext(
+			context.Background(),
+			`UPDATE batches
+			 SET batch_number = ?, item_id = ?, quantity = ?, updated_at = STRFTIME('%Y-%m-%dT%H:%M:%fZ', 'now')
+			 WHERE id = ? AND updated_at = ?`,
+			batch.BatchNumber, batch.ItemID, batch.Quantity, batch.ID, batch.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	return r.db.QueryRowContext(context.Background(), "SELECT updated_at FROM batches WHERE id = ?", batch.ID).Scan(&batch.UpdatedAt)
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return domainErrors.ErrConcurrencyConflict
+		}
+
+		if err := tx.QueryRowContext(context.Background(), "SELECT updated_at FROM batches WHERE id = ?", batch.ID).Scan(&batch.UpdatedAt); err != nil {
+			return err
+		}
+		return r.auditTx(tx, domainAudit.ActionUpdate, "batch", "batches", batch.ID, before)
+	})
 }
 
 func (r *SqliteInventoryRepository) CreatePackagingProfile(profile *domainInventory.PackagingProfile) error {
@@ -282,6 +371,10 @@ func (r *SqliteInventoryRepository) CreatePackagingProfile(profile *domainInvent
 		component.ProfileID = profileID
 	}
 
+	if err = r.auditTx(tx, domainAudit.ActionCreate, "packaging_profile", "packaging_profiles", profileID, "", auditChild{table: "packaging_profile_components", foreignKey: "profile_id"}); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -515,6 +608,10 @@ func (r *SqliteInventoryRepository) CreateRecipe(recipe *domainInventory.Recipe)
 		component.RecipeID = recipeID
 	}
 
+	if err := r.auditTx(tx, domainAudit.ActionCreate, "recipe", "recipes", recipeID, "", auditChild{table: "recipe_components", foreignKey: "recipe_id"}); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -545,6 +642,11 @@ func (r *SqliteInventoryRepository) UpdateRecipe(recipe *domainInventory.Recipe)
 		return err
 	}
 
+	before, err := snapshotRowTx(tx, "recipes", "id", recipe.ID, auditChild{table: "recipe_components", foreignKey: "recipe_id"})
+	if err != nil {
+		return err
+	}
+
 	res, err := tx.ExecContext(
 		context.Background(),
 		`UPDATE recipes
@@ -602,6 +704,10 @@ func (r *SqliteInventoryRepository) UpdateRecipe(recipe *domainInventory.Recipe)
 		return err
 	}
 
+	if err := r.auditTx(tx, domainAudit.ActionUpdate, "recipe", "recipes", recipe.ID, before, auditChild{table: "recipe_components", foreignKey: "recipe_id"}); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -752,6 +858,18 @@ func (r *SqliteInventoryRepository) CreateParty(party *domainInventory.Party) er
 		}
 	}()
 
+	if err := r.insertPartyTx(tx, party); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+func (r *SqliteInventoryRepository) insertPartyTx(tx *sql.Tx, party *domainInventory.Party) error {
 	res, err := tx.ExecContext(
 		context.Background(),
 		`INSERT INTO parties (party_type, name, phone, email, address, lead_time_days, is_active, created_at, updated_at)
@@ -776,11 +894,7 @@ func (r *SqliteInventoryRepository) CreateParty(party *domainInventory.Party) er
 	}
 	party.ID = id
 
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-	committed = true
-	return nil
+	return r.auditTx(tx, domainAudit.ActionCreate, "party", "parties", party.ID, "")
 }
 
 func (r *SqliteInventoryRepository) UpdateParty(party *domainInventory.Party) error {
@@ -802,6 +916,11 @@ func (r *SqliteInventoryRepository) UpdateParty(party *domainInventory.Party) er
 		}
 	}()
 
+	before, err := snapshotRowTx(tx, "parties", "id", party.ID)
+	if err != nil {
+		return err
+	}
+
 	res, err := tx.ExecContext(
 		context.Background(),
 		`UPDATE parties
@@ -832,6 +951,10 @@ func (r *SqliteInventoryRepository) UpdateParty(party *domainInventory.Party) er
 		return err
 	}
 
+	if err := r.auditTx(tx, domainAudit.ActionUpdate, "party", "parties", party.ID, before); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -839,7 +962,56 @@ func (r *SqliteInventoryRepository) UpdateParty(party *domainInventory.Party) er
 	return nil
 }
 
-func (r *SqliteInventoryRepository) ListParties(filter domainInventory.PartyListFilter) ([]domainInventory.Party, error) {
+var partySortColumns = map[string]sortColumn{
+	"name":       {expr: "name COLLATE NOCASE"},
+	"created_at": {expr: "created_at"},
+	"updated_at": {expr: "updated_at"},
+}
+
+func (r *SqliteInventoryRepository) GetParty(id int64) (*domainInventory.Party, error) {
+	var (
+		partyType    string
+		leadTimeDays sql.NullInt64
+		party        domainInventory.Party
+	)
+	err := r.db.QueryRowContext(
+		context.Background(),
+		`SELECT id, party_type, name, phone, email, address, lead_time_days, is_active, created_at, updated_at
+		 FROM parties WHERE id = ?`,
+		id,
+	).Scan(
+		&party.ID,
+		&partyType,
+		&party.Name,
+		&party.Phone,
+		&party.Email,
+		&party.Address,
+		&leadTimeDays,
+		&party.IsActive,
+		&party.CreatedAt,
+		&party.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domainInventory.ErrPartyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	party.PartyType = domainInventory.ParsePartyType(partyType)
+	if leadTimeDays.Valid {
+		lead := int(leadTimeDays.Int64)
+		party.LeadTimeDays = &lead
+	}
+	party.Normalize()
+	return &party, nil
+}
+
+func (r *SqliteInventoryRepository) ListParties(filter domainInventory.PartyListFilter) ([]domainInventory.Party, domainInventory.PageInfo, error) {
+	page, err := resolvePageQuery(filter.Page, partySortColumns, "name", "id")
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, err
+	}
+
 	args := make([]any, 0, 4)
 	clauses := make([]string, 0, 3)
 	if filter.ActiveOnly {
@@ -855,49 +1027,44 @@ func (r *SqliteInventoryRepository) ListParties(filter domainInventory.PartyList
 		args = append(args, search, search, search)
 	}
 
-	query := `SELECT id, party_type, name, phone, email, address, lead_time_days, is_active, created_at, updated_at
-		FROM parties`
-	if len(clauses) > 0 {
-		query += " WHERE " + strings.Join(clauses, " AND ")
-	}
-	query += " ORDER BY name COLLATE NOCASE ASC"
-
-	rows, err := r.db.QueryContext(context.Background(), query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	parties := make([]domainInventory.Party, 0)
-	for rows.Next() {
-		var (
-			partyType    string
-			leadTimeDays sql.NullInt64
-			party        domainInventory.Party
-		)
-		if err := rows.Scan(
-			&party.ID,
-			&partyType,
-			&party.Name,
-			&party.Phone,
-			&party.Email,
-			&party.Address,
-			&leadTimeDays,
-			&party.IsActive,
-			&party.CreatedAt,
-			&party.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		party.PartyType = domainInventory.ParsePartyType(partyType)
-		if leadTimeDays.Valid {
-			lead := int(leadTimeDays.Int64)
-			party.LeadTimeDays = &lead
-		}
-		party.Normalize()
-		parties = append(parties, party)
+	info, err := queryPage(r.db, page,
+		`id, party_type, name, phone, email, address, lead_time_days, is_active, created_at, updated_at`,
+		"parties", clauses, args,
+		func(rows *sql.Rows, sortValue *any) (int64, error) {
+			var (
+				partyType    string
+				leadTimeDays sql.NullInt64
+				party        domainInventory.Party
+			)
+			if err := rows.Scan(
+				&party.ID,
+				&partyType,
+				&party.Name,
+				&party.Phone,
+				&party.Email,
+				&party.Address,
+				&leadTimeDays,
+				&party.IsActive,
+				&party.CreatedAt,
+				&party.UpdatedAt,
+				sortValue,
+			); err != nil {
+				return 0, err
+			}
+			party.PartyType = domainInventory.ParsePartyType(partyType)
+			if leadTimeDays.Valid {
+				lead := int(leadTimeDays.Int64)
+				party.LeadTimeDays = &lead
+			}
+			party.Normalize()
+			parties = append(parties, party)
+			return party.ID, nil
+		})
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, err
 	}
-	return parties, rows.Err()
+	return parties, info, nil
 }
 
 func normalizeOptionalItemID(itemID *int64) interface{} {
@@ -918,30 +1085,32 @@ func (r *SqliteInventoryRepository) CreateUnitConversionRule(rule *domainInvento
 		rule.UpdatedAt = rule.CreatedAt
 	}
 
-	result, err := r.db.ExecContext(
-		context.Background(),
-		`INSERT INTO unit_conversions (item_id, from_unit, to_unit, factor, precision_scale, rounding_mode, is_active, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		normalizeOptionalItemID(rule.ItemID),
-		rule.FromUnit,
-		rule.ToUnit,
-		rule.Factor,
-		rule.PrecisionScale,
-		string(rule.RoundingMode),
-		rule.IsActive,
-		rule.CreatedAt,
-		rule.UpdatedAt,
-	)
-	if err != nil {
-		return err
-	}
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			context.Background(),
+			`INSERT INTO unit_conversions (item_id, from_unit, to_unit, factor, precision_scale, rounding_mode, is_active, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			normalizeOptionalItemID(rule.ItemID),
+			rule.FromUnit,
+			rule.ToUnit,
+			rule.Factor,
+			rule.PrecisionScale,
+			string(rule.RoundingMode),
+			rule.IsActive,
+			rule.CreatedAt,
+			rule.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
-	rule.ID = id
-	return nil
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		rule.ID = id
+		return r.auditTx(tx, domainAudit.ActionCreate, "unit_conversion_rule", "unit_conversions", rule.ID, "")
+	})
 }
 
 func (r *SqliteInventoryRepository) FindUnitConversionRule(lookup domainInventory.UnitConversionLookup) (*domainInventory.UnitConversionRule, error) {
@@ -1145,11 +1314,25 @@ func (r *SqliteInventoryRepository) CreateGRN(grn *domainInventory.GRN) error {
 		}
 	}()
 
+	if err := r.insertGRNTx(tx, grn, domainInventory.LotSourceSupplierGRN); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// insertGRNTx writes the GRN and receives each line into a new lot of sourceType, with its
+// IN ledger entry.
+func (r *SqliteInventoryRepository) insertGRNTx(tx *sql.Tx, grn *domainInventory.GRN, sourceType string) error {
 	res, err := tx.ExecContext(
 		context.Background(),
-		`INSERT INTO grns (grn_number, supplier_id, invoice_no, notes, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		grn.GRNNumber, grn.SupplierID, grn.InvoiceNo, grn.Notes, grn.CreatedAt, grn.UpdatedAt,
+		`INSERT INTO grns (grn_number, supplier_id, invoice_no, notes, created_by, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		grn.GRNNumber, grn.SupplierID, grn.InvoiceNo, grn.Notes, grn.CreatedBy, grn.CreatedAt, grn.UpdatedAt,
 	)
 	if err != nil {
 		return err
@@ -1195,7 +1378,7 @@ func (r *SqliteInventoryRepository) CreateGRN(grn *domainInventory.GRN) error {
 				context.Background(),
 				`INSERT INTO material_lots (lot_number, grn_id, grn_line_id, grn_number, item_id, supplier_id, quantity_received, source_type, unit_cost, created_at)
 				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-				lotNumber, grn.ID, line.ID, grn.GRNNumber, line.ItemID, grn.SupplierID, line.QuantityReceived, "SUPPLIER_GRN", line.UnitPrice, grn.CreatedAt,
+				lotNumber, grn.ID, line.ID, grn.GRNNumber, line.ItemID, grn.SupplierID, line.QuantityReceived, sourceType, line.UnitPrice, grn.CreatedAt,
 			)
 			if lotErr == nil {
 				line.LotNumber = lotNumber
@@ -1210,29 +1393,46 @@ func (r *SqliteInventoryRepository) CreateGRN(grn *domainInventory.GRN) error {
 			return fmt.Errorf("failed to allocate lot number after retries")
 		}
 
-		if _, err := tx.ExecContext(
+		ledgerRes, err := tx.ExecContext(
 			context.Background(),
-			`INSERT INTO stock_ledger (item_id, transaction_type, quantity, reference_id, lot_number, notes, created_at)
-			 VALUES (?, 'IN', ?, ?, ?, ?, ?)`,
+			`INSERT INTO stock_ledger (item_id, transaction_type, quantity, reference_id, lot_number, notes, created_by, created_at)
+			 VALUES (?, 'IN', ?, ?, ?, ?, ?, ?)`,
 			line.ItemID,
 			line.QuantityReceived,
 			grn.GRNNumber,
 			line.LotNumber,
 			grn.Notes,
+			grn.CreatedBy,
 			grn.CreatedAt,
-		); err != nil {
+		)
+		if err != nil {
+			return err
+		}
+		ledgerID, err := ledgerRes.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if err := sealChainRowTx(tx, stockLedgerChain, ledgerID); err != nil {
 			return err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-	committed = true
-	return nil
+	return r.auditTx(tx, domainAudit.ActionCreate, "grn", "grns", grn.ID, "", auditChild{table: "grn_lines", foreignKey: "grn_id"})
+}
+
+var materialLotSortColumns = map[string]sortColumn{
+	"created_at":        {expr: "ml.created_at", defaultDesc: true},
+	"lot_number":        {expr: "ml.lot_number COLLATE NOCASE"},
+	"grn_number":        {expr: "ml.grn_number COLLATE NOCASE"},
+	"quantity_received": {expr: "ml.quantity_received", numeric: true},
 }
 
-func (r *SqliteInventoryRepository) ListMaterialLots(filter domainInventory.MaterialLotListFilter) ([]domainInventory.MaterialLot, error) {
+func (r *SqliteInventoryRepository) ListMaterialLots(filter domainInventory.MaterialLotListFilter) ([]domainInventory.MaterialLot, domainInventory.PageInfo, error) {
+	page, err := resolvePageQuery(filter.Page, materialLotSortColumns, "created_at", "ml.id")
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, err
+	}
+
 	args := make([]any, 0, 8)
 	clauses := make([]string, 0, 8)
 
@@ -1261,44 +1461,98 @@ func (r *SqliteInventoryRepository) ListMaterialLots(filter domainInventory.Mate
 		clauses = append(clauses, "i.is_active = 1")
 	}
 
-	query := `SELECT ml.id, ml.lot_number, ml.grn_id, ml.grn_line_id, ml.grn_number, ml.item_id, ml.supplier_id, p.name, ml.quantity_received, ml.source_type, ml.unit_cost, ml.created_at
-		FROM material_lots ml
+	lots := make([]domainInventory.MaterialLot, 0)
+	info, err := queryPage(r.db, page,
+		`ml.id, ml.lot_number, ml.grn_id, ml.grn_line_id, ml.grn_number, ml.item_id, ml.supplier_id, p.name, ml.quantity_received, ml.source_type, ml.unit_cost, ml.created_at`,
+		`material_lots ml
 		INNER JOIN items i ON i.id = ml.item_id
-		INNER JOIN parties p ON p.id = ml.supplier_id`
-	if len(clauses) > 0 {
-		query += " WHERE " + strings.Join(clauses, " AND ")
+		INNER JOIN parties p ON p.id = ml.supplier_id`,
+		clauses, args,
+		func(rows *sql.Rows, sortValue *any) (int64, error) {
+			var lot domainInventory.MaterialLot
+			if err := rows.Scan(
+				&lot.ID,
+				&lot.LotNumber,
+				&lot.GRNID,
+				&lot.GRNLineID,
+				&lot.GRNNumber,
+				&lot.ItemID,
+				&lot.SupplierID,
+				&lot.SupplierName,
+				&lot.QuantityReceived,
+				&lot.SourceType,
+				&lot.UnitCost,
+				&lot.CreatedAt,
+				sortValue,
+			); err != nil {
+				return 0, err
+			}
+			lots = append(lots, lot)
+			return lot.ID, nil
+		})
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, err
 	}
-	query += " ORDER BY ml.created_at DESC, ml.id DESC"
+	return lots, info, nil
+}
 
-	rows, err := r.db.QueryContext(context.Background(), query, args...)
+func (r *SqliteInventoryRepository) GetMaterialLot(lotNumber string) (*domainInventory.MaterialLot, error) {
+	var lot domainInventory.MaterialLot
+	err := r.db.QueryRowContext(
+		context.Background(),
+		`SELECT ml.id, ml.lot_number, ml.grn_id, ml.grn_line_id, ml.grn_number, ml.item_id, ml.supplier_id, p.name, ml.quantity_received, ml.source_type, ml.unit_cost, ml.created_at
+		 FROM material_lots ml
+		 INNER JOIN parties p ON p.id = ml.supplier_id
+		 WHERE ml.lot_number = ?`,
+		strings.TrimSpace(lotNumber),
+	).Scan(
+		&lot.ID,
+		&lot.LotNumber,
+		&lot.GRNID,
+		&lot.GRNLineID,
+		&lot.GRNNumber,
+		&lot.ItemID,
+		&lot.SupplierID,
+		&lot.SupplierName,
+		&lot.QuantityReceived,
+		&lot.SourceType,
+		&lot.UnitCost,
+		&lot.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domainInventory.ErrLotNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return &lot, nil
+}
 
-	lots := make([]domainInventory.MaterialLot, 0)
-	for rows.Next() {
-		var lot domainInventory.MaterialLot
-		if err := rows.Scan(
-			&lot.ID,
-			&lot.LotNumber,
-			&lot.GRNID,
-			&lot.GRNLineID,
-			&lot.GRNNumber,
-			&lot.ItemID,
-			&lot.SupplierID,
-			&lot.SupplierName,
-			&lot.QuantityReceived,
-			&lot.SourceType,
-			&lot.UnitCost,
-			&lot.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		lots = append(lots, lot)
+func (r *SqliteInventoryRepository) GetLotStockBalance(lotNumber string) (float64, error) {
+	var (
+		lotID   int64
+		balance float64
+	)
+	lotNumber = strings.TrimSpace(lotNumber)
+	err := r.db.QueryRowContext(
+		context.Background(),
+		`SELECT id FROM material_lots WHERE lot_number = ?`,
+		lotNumber,
+	).Scan(&lotID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, domainInventory.ErrLotNotFound
 	}
-
-	return lots, rows.Err()
+	if err != nil {
+		return 0, err
+	}
+	err = r.db.QueryRowContext(
+		context.Background(),
+		`SELECT
+		  COALESCE((SELECT SUM(CASE WHEN transaction_type = 'IN' THEN quantity ELSE -quantity END) FROM stock_ledger WHERE lot_number = ?), 0)
+		+ COALESCE((SELECT SUM(qty_delta) FROM stock_adjustments WHERE lot_id = ?), 0)`,
+		lotNumber, lotID,
+	).Scan(&balance)
+	return balance, err
 }
 
 func (r *SqliteInventoryRepository) RecordLotStockMovement(movement *domainInventory.StockLedgerMovement) error {
@@ -1309,102 +1563,131 @@ func (r *SqliteInventoryRepository) RecordLotStockMovement(movement *domainInven
 		movement.CreatedAt = time.Now().UTC()
 	}
 
-	var itemID int64
-	err := r.db.QueryRowContext(
-		context.Background(),
-		`SELECT item_id
-		 FROM material_lots
-		 WHERE lot_number = ?`,
-		movement.LotNumber,
-	).Scan(&itemID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("lot not found: %s", movement.LotNumber)
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		var itemID int64
+		err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT item_id
+			 FROM material_lots
+			 WHERE lot_number = ?`,
+			movement.LotNumber,
+		).Scan(&itemID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("lot not found: %s", movement.LotNumber)
+			}
+			return err
 		}
-		return err
-	}
-	movement.ItemID = itemID
+		movement.ItemID = itemID
 
-	res, err := r.db.ExecContext(
-		context.Background(),
-		`INSERT INTO stock_ledger (item_id, transaction_type, quantity, reference_id, lot_number, notes, created_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		movement.ItemID,
-		movement.TransactionType,
-		movement.Quantity,
-		movement.ReferenceID,
-		movement.LotNumber,
-		movement.Notes,
-		movement.CreatedAt,
-	)
-	if err != nil {
-		return err
-	}
-	movement.ID, _ = res.LastInsertId()
-	return nil
+		res, err := tx.ExecContext(
+			context.Background(),
+			`INSERT INTO stock_ledger (item_id, transaction_type, quantity, reference_id, lot_number, notes, created_by, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			movement.ItemID,
+			movement.TransactionType,
+			movement.Quantity,
+			movement.ReferenceID,
+			movement.LotNumber,
+			movement.Notes,
+			movement.CreatedBy,
+			movement.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		movement.ID, _ = res.LastInsertId()
+		if err := sealChainRowTx(tx, stockLedgerChain, movement.ID); err != nil {
+			return err
+		}
+		return r.auditTx(tx, domainAudit.ActionCreate, "stock_movement", "stock_ledger", movement.ID, "")
+	})
 }
 
-func (r *SqliteInventoryRepository) ListLotStockMovements(filter domainInventory.StockLedgerMovementListFilter) ([]domainInventory.StockLedgerMovement, error) {
-	lot := strings.TrimSpace(filter.LotNumber)
-	if lot == "" {
-		return nil, domainInventory.ErrLotNumberRequired
-	}
+var stockMovementSortColumns = map[string]sortColumn{
+	"created_at": {expr: "created_at"},
+	"quantity":   {expr: "quantity", numeric: true},
+}
 
-	rows, err := r.db.QueryContext(
-		context.Background(),
-		`SELECT id, item_id, transaction_type, quantity, reference_id, lot_number, notes, created_at
-		 FROM stock_ledger
-		 WHERE lot_number = ?
-		 ORDER BY created_at ASC, id ASC`,
-		lot,
-	)
+func (r *SqliteInventoryRepository) ListLotStockMovements(filter domainInventory.StockLedgerMovementListFilter) ([]domainInventory.StockLedgerMovement, domainInventory.PageInfo, error) {
+	clauses := make([]string, 0, 2)
+	args := make([]any, 0, 2)
+	if lot := strings.TrimSpace(filter.LotNumber); lot != "" {
+		clauses = append(clauses, "lot_number = ?")
+		args = append(args, lot)
+	}
+	if reference := strings.TrimSpace(filter.ReferenceID); reference != "" {
+		clauses = append(clauses, "reference_id = ?")
+		args = append(args, reference)
+	}
+	if len(clauses) == 0 {
+		return nil, domainInventory.PageInfo{}, domainInventory.ErrLotNumberRequired
+	}
+	page, err := resolvePageQuery(filter.Page, stockMovementSortColumns, "created_at", "id")
 	if err != nil {
-		return nil, err
+		return nil, domainInventory.PageInfo{}, err
 	}
-	defer rows.Close()
 
 	movements := make([]domainInventory.StockLedgerMovement, 0)
-	for rows.Next() {
-		var movement domainInventory.StockLedgerMovement
-		if err := rows.Scan(
-			&movement.ID,
-			&movement.ItemID,
-			&movement.TransactionType,
-			&movement.Quantity,
-			&movement.ReferenceID,
-			&movement.LotNumber,
-			&movement.Notes,
-			&movement.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		movements = append(movements, movement)
+	info, err := queryPage(r.db, page,
+		`id, item_id, transaction_type, quantity, reference_id, lot_number, notes, COALESCE(created_by, ''), created_at`,
+		"stock_ledger", clauses, args,
+		func(rows *sql.Rows, sortValue *any) (int64, error) {
+			var movement domainInventory.StockLedgerMovement
+			if err := rows.Scan(
+				&movement.ID,
+				&movement.ItemID,
+				&movement.TransactionType,
+				&movement.Quantity,
+				&movement.ReferenceID,
+				&movement.LotNumber,
+				&movement.Notes,
+				&movement.CreatedBy,
+				&movement.CreatedAt,
+				sortValue,
+			); err != nil {
+				return 0, err
+			}
+			movements = append(movements, movement)
+			return movement.ID, nil
+		})
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, err
 	}
-
-	return movements, rows.Err()
+	return movements, info, nil
 }
 
 func (r *SqliteInventoryRepository) UpdateGRN(grn *domainInventory.GRN) error {
-	res, err := r.db.ExecContext(
-		context.Background(),
-		`UPDATE grns
-		 SET grn_number = ?, supplier_id = ?, invoice_no = ?, notes = ?, updated_at = STRFTIME('%Y-%m-%dT%H:%M:%fZ', 'now')
-		 WHERE id = ? AND updated_at = ?`,
-		grn.GRNNumber, grn.SupplierID, grn.InvoiceNo, grn.Notes, grn.ID, grn.UpdatedAt,
-	)
-	if err != nil {
-		return err
-	}
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		before, err := snapshotRowTx(tx, "grns", "id", grn.ID)
+		if err != nil {
+			return err
+		}
 
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return domainErrors.ErrConcurrencyConflict
-	}
+		res, err := tx.ExecContext(
+			context.Background(),
+			`UPDATE grns
+			 SET grn_number = ?, supplier_id = ?, invoice_no = ?, notes = ?, updated_at = STRFTIME('%Y-%m-%dT%H:%M:%fZ', 'now')
+			 WHERE id = ? AND updated_at = ?`,
+			grn.GRNNumber, grn.SupplierID, grn.InvoiceNo, grn.Notes, grn.ID, grn.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	return r.db.QueryRowContext(context.Background(), "SELECT updated_at FROM grns WHERE id = ?", grn.ID).Scan(&grn.UpdatedAt)
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return domainErrors.ErrConcurrencyConflict
+		}
+
+		if err := tx.QueryRowContext(context.Background(), "SELECT updated_at FROM grns WHERE id = ?", grn.ID).Scan(&grn.UpdatedAt); err != nil {
+			return err
+		}
+		return r.auditTx(tx, domainAudit.ActionUpdate, "grn", "grns", grn.ID, before)
+	})
 }
 
 func (r *SqliteInventoryRepository) CreateStockAdjustment(adj *domainInventory.StockAdjustment) error {
@@ -1412,61 +1695,185 @@ func (r *SqliteInventoryRepository) CreateStockAdjustment(adj *domainInventory.S
 		adj.CreatedAt = time.Now().UTC()
 	}
 
-	res, err := r.db.ExecContext(
-		context.Background(),
-		`INSERT INTO stock_adjustments (item_id, lot_id, qty_delta, reason_code, notes, created_by, created_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		adj.ItemID, adj.LotID, adj.QtyDelta, adj.ReasonCode, adj.Notes, adj.CreatedBy, adj.CreatedAt,
-	)
-	if err != nil {
-		return err
-	}
-	adj.ID, _ = res.LastInsertId()
-	return nil
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			context.Background(),
+			`INSERT INTO stock_adjustments (item_id, lot_id, qty_delta, reason_code, notes, created_by, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			adj.ItemID, adj.LotID, adj.QtyDelta, adj.ReasonCode, adj.Notes, adj.CreatedBy, adj.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		adj.ID, _ = res.LastInsertId()
+		return r.auditTx(tx, domainAudit.ActionCreate, "stock_adjustment", "stock_adjustments", adj.ID, "")
+	})
 }
 
-func (r *SqliteInventoryRepository) ListStockAdjustments(itemID int64) ([]domainInventory.StockAdjustment, error) {
-	rows, err := r.db.QueryContext(
-		context.Background(),
-		`SELECT id, item_id, lot_id, qty_delta, reason_code, notes, created_by, created_at
-		 FROM stock_adjustments
-		 WHERE item_id = ?
-		 ORDER BY created_at DESC, id DESC`,
-		itemID,
-	)
+var stockAdjustmentSortColumns = map[string]sortColumn{
+	"created_at": {expr: "created_at", defaultDesc: true},
+	"qty_delta":  {expr: "qty_delta", numeric: true},
+}
+
+func (r *SqliteInventoryRepository) ListStockAdjustments(filter domainInventory.StockAdjustmentListFilter) ([]domainInventory.StockAdjustment, domainInventory.PageInfo, error) {
+	page, err := resolvePageQuery(filter.Page, stockAdjustmentSortColumns, "created_at", "id")
 	if err != nil {
-		return nil, err
+		return nil, domainInventory.PageInfo{}, err
 	}
-	defer rows.Close()
 
 	adjustments := make([]domainInventory.StockAdjustment, 0)
-	for rows.Next() {
-		var adj domainInventory.StockAdjustment
-		if err := rows.Scan(
-			&adj.ID,
-			&adj.ItemID,
-			&adj.LotID,
-			&adj.QtyDelta,
-			&adj.ReasonCode,
-			&adj.Notes,
-			&adj.CreatedBy,
-			&adj.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		adjustments = append(adjustments, adj)
+	info, err := queryPage(r.db, page,
+		`id, item_id, lot_id, qty_delta, reason_code, notes, created_by, created_at`,
+		"stock_adjustments", []string{"item_id = ?"}, []any{filter.ItemID},
+		func(rows *sql.Rows, sortValue *any) (int64, error) {
+			var adj domainInventory.StockAdjustment
+			if err := rows.Scan(
+				&adj.ID,
+				&adj.ItemID,
+				&adj.LotID,
+				&adj.QtyDelta,
+				&adj.ReasonCode,
+				&adj.Notes,
+				&adj.CreatedBy,
+				&adj.CreatedAt,
+				sortValue,
+			); err != nil {
+				return 0, err
+			}
+			adjustments = append(adjustments, adj)
+			return adj.ID, nil
+		})
+	if err != nil {
+		return nil, domainInventory.PageInfo{}, err
 	}
-	return adjustments, rows.Err()
+	return adjustments, info, nil
 }
 
+// GetItemStockBalance is what was received into the item's lots, less the OUT and
+// ADJUSTMENT movements recorded against them, plus its stock adjustments.
 func (r *SqliteInventoryRepository) GetItemStockBalance(itemID int64) (float64, error) {
 	var balance float64
 	err := r.db.QueryRowContext(
 		context.Background(),
 		`SELECT
 		  COALESCE((SELECT SUM(ml.quantity_received) FROM material_lots ml WHERE ml.item_id = ?), 0)
+		- COALESCE((SELECT SUM(sl.quantity) FROM stock_ledger sl WHERE sl.item_id = ? AND sl.transaction_type <> 'IN'), 0)
 		+ COALESCE((SELECT SUM(sa.qty_delta) FROM stock_adjustments sa WHERE sa.item_id = ?), 0)`,
-		itemID, itemID,
+		itemID, itemID, itemID,
 	).Scan(&balance)
 	return balance, err
 }
+
+// nextOpeningGRNNumberTx numbers the GRNs that receive imported opening stock OB-YYYYMMDD-NNN.
+func nextOpeningGRNNumberTx(tx *sql.Tx, createdAt time.Time) (string, error) {
+	prefix := "OB-" + createdAt.UTC().Format("20060102") + "-"
+
+	var latest sql.NullString
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT grn_number
+		 FROM grns
+		 WHERE grn_number LIKE ?
+		 ORDER BY grn_number DESC
+		 LIMIT 1`,
+		prefix+"%",
+	).Scan(&latest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	seq := 1
+	if latest.Valid {
+		if parsed, parseErr := strconv.Atoi(strings.TrimPrefix(latest.String, prefix)); parseErr == nil && parsed >= 1 {
+			seq = parsed + 1
+		}
+	}
+	return fmt.Sprintf("%s%03d", prefix, seq), nil
+}
+
+func (r *SqliteInventoryRepository) ImportMasterData(batch *domainInventory.MasterDataImport) error {
+	if batch == nil {
+		return errors.New("import batch is nil")
+	}
+	for i := range batch.Parties {
+		if err := batch.Parties[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range batch.Items {
+		if err := batch.Items[i].ValidateMasterContract(); err != nil {
+			return err
+		}
+	}
+	for i := range batch.OpeningStock {
+		if err := batch.OpeningStock[i].Validate(); err != nil {
+			return err
+		}
+	}
+	if batch.CreatedAt.IsZero() {
+		batch.CreatedAt = time.Now().UTC()
+	}
+	createdAt := batch.CreatedAt
+
+	// Opening stock is received on one GRN per supplier, since a GRN has a single supplier.
+	supplierOrder := make([]int64, 0)
+	linesBySupplier := make(map[int64][]int)
+	for i, line := range batch.OpeningStock {
+		if _, seen := linesBySupplier[line.SupplierID]; !seen {
+			supplierOrder = append(supplierOrder, line.SupplierID)
+		}
+		linesBySupplier[line.SupplierID] = append(linesBySupplier[line.SupplierID], i)
+	}
+
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		for i := range batch.Parties {
+			party := &batch.Parties[i]
+			party.CreatedAt, party.UpdatedAt = createdAt, createdAt
+			if err := r.insertPartyTx(tx, party); err != nil {
+				return fmt.Errorf("party %q: %w", party.Name, err)
+			}
+		}
+		for i := range batch.Items {
+			item := &batch.Items[i]
+			item.CreatedAt, item.UpdatedAt = createdAt, createdAt
+			if err := r.insertItemTx(tx, item); err != nil {
+				return fmt.Errorf("item %q: %w", item.SKU, err)
+			}
+		}
+
+		batch.GRNs = make([]domainInventory.GRN, 0, len(supplierOrder))
+		for _, supplierID := range supplierOrder {
+			grnNumber, err := nextOpeningGRNNumberTx(tx, createdAt)
+			if err != nil {
+				return err
+			}
+			indexes := linesBySupplier[supplierID]
+			grn := domainInventory.GRN{
+				GRNNumber:  grnNumber,
+				SupplierID: supplierID,
+				Notes:      "Opening balance import",
+				CreatedBy:  batch.CreatedBy,
+				CreatedAt:  createdAt,
+				UpdatedAt:  createdAt,
+				Lines:      make([]domainInventory.GRNLine, 0, len(indexes)),
+			}
+			for n, index := range indexes {
+				line := batch.OpeningStock[index]
+				grn.Lines = append(grn.Lines, domainInventory.GRNLine{
+					LineNo:           n + 1,
+					ItemID:           line.ItemID,
+					QuantityReceived: line.Quantity,
+					UnitPrice:        line.UnitCost,
+				})
+			}
+			if err := r.insertGRNTx(tx, &grn, domainInventory.LotSourceOpeningBalance); err != nil {
+				return err
+			}
+			for n, index := range indexes {
+				batch.OpeningStock[index].LotNumber = grn.Lines[n].LotNumber
+			}
+			batch.GRNs = append(batch.GRNs, grn)
+		}
+		return nil
+	})
+}
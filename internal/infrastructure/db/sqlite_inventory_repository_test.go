@@ -360,7 +360,7 @@ func TestSqliteInventoryRepository_ListMaterialLots_FiltersAndReturnsNewestFirst
 		t.Fatalf("CreateGRN failed: %v", err)
 	}
 
-	lots, err := repo.ListMaterialLots(domainInventory.MaterialLotListFilter{
+	lots, _, err := repo.ListMaterialLots(domainInventory.MaterialLotListFilter{
 		Search:     "trace supplier",
 		ActiveOnly: true,
 	})
@@ -401,12 +401,13 @@ func TestSqliteInventoryRepository_RecordAndListLotStockMovements_TracksNonInbou
 		Quantity:        5,
 		ReferenceID:     "PK-3301",
 		Notes:           "packing consumption",
+		CreatedBy:       "operator-a",
 	}
 	if err := repo.RecordLotStockMovement(movement); err != nil {
 		t.Fatalf("RecordLotStockMovement failed: %v", err)
 	}
 
-	movements, err := repo.ListLotStockMovements(domainInventory.StockLedgerMovementListFilter{
+	movements, _, err := repo.ListLotStockMovements(domainInventory.StockLedgerMovementListFilter{
 		LotNumber: lotNumber,
 	})
 	if err != nil {
@@ -427,6 +428,9 @@ func TestSqliteInventoryRepository_RecordAndListLotStockMovements_TracksNonInbou
 			if m.LotNumber != lotNumber {
 				t.Fatalf("expected downstream movement lot continuity, got %q", m.LotNumber)
 			}
+			if m.CreatedBy != "operator-a" {
+				t.Fatalf("expected downstream movement created_by operator-a, got %q", m.CreatedBy)
+			}
 		}
 	}
 	if !foundDownstream {
@@ -444,6 +448,7 @@ func TestSqliteInventoryRepository_CreateGRN_PersistsSupplierAndInvoice(t *testi
 		GRNNumber:  "GRN-3003",
 		SupplierID: partyID,
 		InvoiceNo:  "INV-3003",
+		CreatedBy:  "operator-a",
 		Lines: []domainInventory.GRNLine{
 			{LineNo: 1, ItemID: rawID, QuantityReceived: 11},
 		},
@@ -453,12 +458,12 @@ func TestSqliteInventoryRepository_CreateGRN_PersistsSupplierAndInvoice(t *testi
 	}
 
 	var persistedSupplierID int64
-	var invoice string
-	if err := manager.GetDB().QueryRow("SELECT supplier_id, invoice_no FROM grns WHERE id = ?", grn.ID).Scan(&persistedSupplierID, &invoice); err != nil {
+	var invoice, createdBy string
+	if err := manager.GetDB().QueryRow("SELECT supplier_id, invoice_no, created_by FROM grns WHERE id = ?", grn.ID).Scan(&persistedSupplierID, &invoice, &createdBy); err != nil {
 		t.Fatalf("failed to query persisted grn header: %v", err)
 	}
-	if persistedSupplierID != partyID || invoice != "INV-3003" {
-		t.Fatalf("unexpected persisted supplier_id/invoice: %d / %q", persistedSupplierID, invoice)
+	if persistedSupplierID != partyID || invoice != "INV-3003" || createdBy != "operator-a" {
+		t.Fatalf("unexpected persisted supplier_id/invoice/created_by: %d / %q / %q", persistedSupplierID, invoice, createdBy)
 	}
 }
 
@@ -602,7 +607,7 @@ func TestSqliteInventoryRepository_ListItems_FilterByTypeAndActive(t *testing.T)
 		t.Fatalf("CreateItem inactivePacking failed: %v", err)
 	}
 
-	items, err := repo.ListItems(domainInventory.ItemListFilter{
+	items, _, err := repo.ListItems(domainInventory.ItemListFilter{
 		ActiveOnly: true,
 		ItemType:   domainInventory.ItemTypeRaw,
 	})
@@ -1165,7 +1170,7 @@ func TestSqliteInventoryRepository_CreateAndListParties(t *testing.T) {
 		t.Fatalf("CreateParty customer failed: %v", err)
 	}
 
-	partyRows, err := repo.ListParties(domainInventory.PartyListFilter{
+	partyRows, _, err := repo.ListParties(domainInventory.PartyListFilter{
 		ActiveOnly: true,
 		PartyType:  domainInventory.PartyTypeSupplier,
 		Search:     "acme",
@@ -1306,7 +1311,7 @@ func TestSqliteInventoryRepository_CreateGRN_BulkPowder_AppearsInListMaterialLot
 		t.Fatalf("CreateGRN (BULK_POWDER) failed: %v", err)
 	}
 
-	lots, err := repo.ListMaterialLots(domainInventory.MaterialLotListFilter{
+	lots, _, err := repo.ListMaterialLots(domainInventory.MaterialLotListFilter{
 		ItemID: &bulkID,
 	})
 	if err != nil {
@@ -1456,6 +1461,85 @@ func TestSqliteInventoryRepository_GetItemStockBalance_SumsLotsAndAdjustments(t
 	if balance != 130 {
 		t.Fatalf("expected balance 130, got %v", balance)
 	}
+
+	// An OUT movement from a lot lowers the item balance too.
+	out := &domainInventory.StockLedgerMovement{LotNumber: grn1.Lines[0].LotNumber, TransactionType: "OUT", Quantity: 25, CreatedBy: "test-user"}
+	if err := repo.RecordLotStockMovement(out); err != nil {
+		t.Fatalf("RecordLotStockMovement failed: %v", err)
+	}
+	balance, err = repo.GetItemStockBalance(itemID)
+	if err != nil {
+		t.Fatalf("GetItemStockBalance failed: %v", err)
+	}
+	if balance != 105 {
+		t.Fatalf("expected balance 105 after dispatching 25, got %v", balance)
+	}
+}
+
+// The item balance is what the reconciliation screen, the stock summary export and the
+// low-stock alert read, so it must agree with the lot balances behind it.
+func TestSqliteInventoryRepository_GetItemStockBalance_MatchesItsLotBalances(t *testing.T) {
+	repo, _ := setupInventoryRepo(t)
+
+	itemID := createTestInventoryItem(t, repo, domainInventory.ItemTypeRaw, "RAW-BAL-03", "Ledger Balance Item", "kg")
+	supplierID := createTestParty(t, repo, "Ledger Balance Supplier")
+
+	lotNumbers := make([]string, 0, 2)
+	for _, received := range []struct {
+		grnNumber string
+		qty       float64
+	}{{"GRN-LBAL-001", 60}, {"GRN-LBAL-002", 45}} {
+		grn := &domainInventory.GRN{
+			GRNNumber:  received.grnNumber,
+			SupplierID: supplierID,
+			Lines:      []domainInventory.GRNLine{{LineNo: 1, ItemID: itemID, QuantityReceived: received.qty}},
+		}
+		if err := repo.CreateGRN(grn); err != nil {
+			t.Fatalf("CreateGRN failed: %v", err)
+		}
+		lotNumbers = append(lotNumbers, grn.Lines[0].LotNumber)
+	}
+
+	movements := []domainInventory.StockLedgerMovement{
+		{LotNumber: lotNumbers[0], TransactionType: "OUT", Quantity: 12},
+		{LotNumber: lotNumbers[0], TransactionType: "ADJUSTMENT", Quantity: 1.5},
+		{LotNumber: lotNumbers[1], TransactionType: "OUT", Quantity: 20},
+	}
+	for i := range movements {
+		if err := repo.RecordLotStockMovement(&movements[i]); err != nil {
+			t.Fatalf("RecordLotStockMovement failed: %v", err)
+		}
+	}
+	lot, err := repo.GetMaterialLot(lotNumbers[1])
+	if err != nil {
+		t.Fatalf("GetMaterialLot failed: %v", err)
+	}
+	lotID := lot.ID
+	adjustments := []domainInventory.StockAdjustment{
+		{ItemID: itemID, LotID: &lotID, QtyDelta: -3, ReasonCode: "Spoilage"},
+		{ItemID: itemID, QtyDelta: 4, ReasonCode: "Audit Correction"},
+	}
+	for i := range adjustments {
+		if err := repo.CreateStockAdjustment(&adjustments[i]); err != nil {
+			t.Fatalf("CreateStockAdjustment failed: %v", err)
+		}
+	}
+
+	expected := 4.0 // the adjustment not tied to a lot
+	for _, lotNumber := range lotNumbers {
+		lotBalance, err := repo.GetLotStockBalance(lotNumber)
+		if err != nil {
+			t.Fatalf("GetLotStockBalance failed: %v", err)
+		}
+		expected += lotBalance
+	}
+	balance, err := repo.GetItemStockBalance(itemID)
+	if err != nil {
+		t.Fatalf("GetItemStockBalance failed: %v", err)
+	}
+	if balance != expected || balance != 72.5 {
+		t.Fatalf("expected the item balance to equal its lots plus unlotted adjustments (72.5), got %v vs %v", balance, expected)
+	}
 }
 
 func TestSqliteInventoryRepository_GetItemStockBalance_NoLotsNoAdjustments(t *testing.T) {
@@ -1500,3 +1584,130 @@ func TestSqliteInventoryRepository_CreateGRN_UnitPricePersistedOnGRNLine(t *test
 		t.Fatalf("expected unit_price 30.00 on grn_line, got %v", unitPrice)
 	}
 }
+
+func TestSqliteInventoryRepository_ImportMasterData_OpeningStockCreatesOpeningBalanceLots(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	cuminID := createTestInventoryItem(t, repo, domainInventory.ItemTypeRaw, "CUM-1", "Cumin", "kg")
+	pouchID := createTestInventoryItem(t, repo, domainInventory.ItemTypePackingMaterial, "PCH-1", "Pouch", "pcs")
+	hillID := createTestParty(t, repo, "Hill Farms")
+	valleyID := createTestParty(t, repo, "Valley Traders")
+
+	batch := &domainInventory.MasterDataImport{
+		CreatedBy: "admin",
+		OpeningStock: []domainInventory.OpeningStockLine{
+			{ItemID: cuminID, SupplierID: hillID, Quantity: 40, UnitCost: 180},
+			{ItemID: pouchID, SupplierID: valleyID, Quantity: 1000, UnitCost: 1.5},
+			{ItemID: cuminID, SupplierID: hillID, Quantity: 10, UnitCost: 175},
+		},
+	}
+	if err := repo.ImportMasterData(batch); err != nil {
+		t.Fatalf("ImportMasterData failed: %v", err)
+	}
+
+	if len(batch.GRNs) != 2 || len(batch.GRNs[0].Lines) != 2 || batch.GRNs[0].SupplierID != hillID {
+		t.Fatalf("expected one GRN per supplier, got %+v", batch.GRNs)
+	}
+	if !strings.HasPrefix(batch.GRNs[0].GRNNumber, "OB-") || batch.GRNs[0].GRNNumber == batch.GRNs[1].GRNNumber {
+		t.Fatalf("expected distinct OB- GRN numbers, got %q and %q", batch.GRNs[0].GRNNumber, batch.GRNs[1].GRNNumber)
+	}
+	for i, line := range batch.OpeningStock {
+		if line.LotNumber == "" {
+			t.Fatalf("expected line %d to be given a lot number", i)
+		}
+	}
+
+	var openingLots int
+	if err := manager.GetDB().QueryRow(`SELECT COUNT(1) FROM material_lots WHERE source_type = 'OPENING_BALANCE'`).Scan(&openingLots); err != nil {
+		t.Fatalf("failed to count lots: %v", err)
+	}
+	if openingLots != 3 {
+		t.Fatalf("expected 3 OPENING_BALANCE lots, got %d", openingLots)
+	}
+	balance, err := repo.GetItemStockBalance(cuminID)
+	if err != nil {
+		t.Fatalf("GetItemStockBalance failed: %v", err)
+	}
+	if balance != 50 {
+		t.Fatalf("expected cumin opening balance 50, got %v", balance)
+	}
+}
+
+func TestSqliteInventoryRepository_ImportMasterData_RollsBackWholeBatch(t *testing.T) {
+	repo, manager := setupInventoryRepo(t)
+	createTestInventoryItem(t, repo, domainInventory.ItemTypeRaw, "CUM-1", "Cumin", "kg")
+
+	batch := &domainInventory.MasterDataImport{
+		Parties: []domainInventory.Party{{PartyType: domainInventory.PartyTypeSupplier, Name: "Hill Farms", Phone: "1", IsActive: true}},
+		Items: []domainInventory.Item{
+			{SKU: "COR-1", Name: "Coriander", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true},
+			{SKU: "CUM-1", Name: "Cumin again", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", IsActive: true},
+		},
+	}
+	err := repo.ImportMasterData(batch)
+	if err == nil || !strings.Contains(strings.ToLower(err.Error()), "unique constraint failed: items.sku") {
+		t.Fatalf("expected duplicate sku to fail the import, got %v", err)
+	}
+
+	var items, parties int
+	if err := manager.GetDB().QueryRow(`SELECT COUNT(1) FROM items`).Scan(&items); err != nil {
+		t.Fatalf("failed to count items: %v", err)
+	}
+	if err := manager.GetDB().QueryRow(`SELECT COUNT(1) FROM parties`).Scan(&parties); err != nil {
+		t.Fatalf("failed to count parties: %v", err)
+	}
+	if items != 1 || parties != 0 {
+		t.Fatalf("expected the failed import to leave nothing behind, got %d items and %d parties", items, parties)
+	}
+}
+
+func TestSqliteInventoryRepository_GetMaterialLotAndBalance(t *testing.T) {
+	repo, _ := setupInventoryRepo(t)
+	rawID := createTestInventoryItem(t, repo, domainInventory.ItemTypeRaw, "RAW-51", "Raw Fennel", "kg")
+	supplierID := createTestParty(t, repo, "Label Supplier")
+
+	grn := &domainInventory.GRN{
+		GRNNumber:  "GRN-5101",
+		SupplierID: supplierID,
+		Lines: []domainInventory.GRNLine{
+			{LineNo: 1, ItemID: rawID, QuantityReceived: 40},
+		},
+	}
+	if err := repo.CreateGRN(grn); err != nil {
+		t.Fatalf("CreateGRN failed: %v", err)
+	}
+	lotNumber := grn.Lines[0].LotNumber
+
+	lot, err := repo.GetMaterialLot(lotNumber)
+	if err != nil {
+		t.Fatalf("GetMaterialLot failed: %v", err)
+	}
+	if lot.GRNNumber != "GRN-5101" || lot.ItemID != rawID || lot.SupplierName != "Label Supplier" || lot.QuantityReceived != 40 {
+		t.Fatalf("unexpected lot: %+v", lot)
+	}
+
+	if err := repo.RecordLotStockMovement(&domainInventory.StockLedgerMovement{LotNumber: lotNumber, TransactionType: "OUT", Quantity: 12.5}); err != nil {
+		t.Fatalf("RecordLotStockMovement failed: %v", err)
+	}
+	if err := repo.RecordLotStockMovement(&domainInventory.StockLedgerMovement{LotNumber: lotNumber, TransactionType: "ADJUSTMENT", Quantity: 0.5}); err != nil {
+		t.Fatalf("RecordLotStockMovement failed: %v", err)
+	}
+	lotID := lot.ID
+	if err := repo.CreateStockAdjustment(&domainInventory.StockAdjustment{ItemID: rawID, LotID: &lotID, QtyDelta: -2, ReasonCode: "Spoilage"}); err != nil {
+		t.Fatalf("CreateStockAdjustment failed: %v", err)
+	}
+
+	balance, err := repo.GetLotStockBalance(lotNumber)
+	if err != nil {
+		t.Fatalf("GetLotStockBalance failed: %v", err)
+	}
+	if balance != 25 {
+		t.Fatalf("expected 40 - 12.5 - 0.5 - 2 = 25, got %v", balance)
+	}
+
+	if _, err := repo.GetMaterialLot("LOT-19990101-001"); !errors.Is(err, domainInventory.ErrLotNotFound) {
+		t.Fatalf("expected ErrLotNotFound, got %v", err)
+	}
+	if _, err := repo.GetLotStockBalance("LOT-19990101-001"); !errors.Is(err, domainInventory.ErrLotNotFound) {
+		t.Fatalf("expected ErrLotNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+)
+
+const maxInvoiceRows = 1000
+
+type SqliteInvoiceRepository struct {
+	db *sql.DB
+}
+
+func NewSqliteInvoiceRepository(db *sql.DB) *SqliteInvoiceRepository {
+	return &SqliteInvoiceRepository{db: db}
+}
+
+func (r *SqliteInvoiceRepository) Create(invoice *domainInvoice.Invoice, render func(*domainInvoice.Invoice) ([]byte, error)) error {
+	if invoice == nil {
+		return fmt.Errorf("invoice is nil")
+	}
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		var issued string
+		err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT invoice_number FROM invoices WHERE dispatch_reference = ?`,
+			invoice.DispatchReference,
+		).Scan(&issued)
+		if err == nil {
+			return fmt.Errorf("%w as %s", domainInvoice.ErrDispatchAlreadyInvoiced, issued)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		var last int
+		if err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT COALESCE(MAX(sequence), 0) FROM invoices WHERE financial_year = ?`,
+			invoice.FinancialYear,
+		).Scan(&last); err != nil {
+			return err
+		}
+		invoice.Sequence = last + 1
+		invoice.InvoiceNumber = domainInvoice.Number(invoice.FinancialYear, invoice.Sequence)
+		invoice.CreatedAt = time.Now().UTC()
+
+		document, err := render(invoice)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(invoice)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(
+			context.Background(),
+			`INSERT INTO invoices (invoice_number, financial_year, sequence, invoice_date, dispatch_reference, customer_id, grand_total, data, pdf, created_by, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			invoice.InvoiceNumber, invoice.FinancialYear, invoice.Sequence, invoice.InvoiceDate.UTC(), invoice.DispatchReference,
+			invoice.CustomerID, invoice.GrandTotal, string(data), document, invoice.CreatedBy, invoice.CreatedAt,
+		)
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "unique constraint failed: invoices.dispatch_reference") {
+				return domainInvoice.ErrDispatchAlreadyInvoiced
+			}
+			return err
+		}
+		invoice.ID, err = res.LastInsertId()
+		return err
+	})
+}
+
+func (r *SqliteInvoiceRepository) Get(id int64) (*domainInvoice.Invoice, error) {
+	var data string
+	err := r.db.QueryRowContext(context.Background(), `SELECT data FROM invoices WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domainInvoice.ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeInvoice(id, data)
+}
+
+func (r *SqliteInvoiceRepository) GetPDF(id int64) (*domainInvoice.Invoice, []byte, error) {
+	var data string
+	var document []byte
+	err := r.db.QueryRowContext(context.Background(), `SELECT data, pdf FROM invoices WHERE id = ?`, id).Scan(&data, &document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, domainInvoice.ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	invoice, err := decodeInvoice(id, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return invoice, document, nil
+}
+
+// List returns invoices newest first.
+func (r *SqliteInvoiceRepository) List(filter domainInvoice.ListFilter) ([]domainInvoice.Invoice, error) {
+	clauses := make([]string, 0, 2)
+	args := make([]any, 0, 3)
+	if filter.FinancialYear != "" {
+		clauses = append(clauses, "financial_year = ?")
+		args = append(args, filter.FinancialYear)
+	}
+	if filter.CustomerID > 0 {
+		clauses = append(clauses, "customer_id = ?")
+		args = append(args, filter.CustomerID)
+	}
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > maxInvoiceRows {
+		limit = maxInvoiceRows
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(
+		context.Background(),
+		fmt.Sprintf(`SELECT id, data FROM invoices %s ORDER BY id DESC LIMIT ?`, where),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := make([]domainInvoice.Invoice, 0)
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		invoice, err := decodeInvoice(id, data)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *invoice)
+	}
+	return invoices, rows.Err()
+}
+
+func decodeInvoice(id int64, data string) (*domainInvoice.Invoice, error) {
+	var invoice domainInvoice.Invoice
+	if err := json.Unmarshal([]byte(data), &invoice); err != nil {
+		return nil, fmt.Errorf("invoice %d is unreadable: %w", id, err)
+	}
+	invoice.ID = id
+	return &invoice, nil
+}
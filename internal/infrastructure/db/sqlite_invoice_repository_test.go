@@ -0,0 +1,81 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+)
+
+func TestInvoiceRepository_NumbersEachFinancialYearWithoutGaps(t *testing.T) {
+	inventoryRepo, manager := setupInventoryRepo(t)
+	repo := NewSqliteInvoiceRepository(manager.GetDB())
+	customer := &domainInventory.Party{PartyType: domainInventory.PartyTypeCustomer, Name: "Spice Mart", Phone: "98220", IsActive: true}
+	if err := inventoryRepo.CreateParty(customer); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+
+	render := func(inv *domainInvoice.Invoice) ([]byte, error) {
+		return []byte("%PDF " + inv.InvoiceNumber), nil
+	}
+	issue := func(reference string, date time.Time) *domainInvoice.Invoice {
+		t.Helper()
+		inv := &domainInvoice.Invoice{
+			FinancialYear:     domainInvoice.FinancialYear(date),
+			InvoiceDate:       date,
+			DispatchReference: reference,
+			CustomerID:        customer.ID,
+			GrandTotal:        1050,
+		}
+		if err := repo.Create(inv, render); err != nil {
+			t.Fatalf("Create(%s) failed: %v", reference, err)
+		}
+		return inv
+	}
+
+	march := issue("DSP-1", time.Date(2026, time.March, 31, 18, 0, 0, 0, time.UTC))
+	april := issue("DSP-2", time.Date(2026, time.April, 1, 9, 0, 0, 0, time.UTC))
+	next := issue("DSP-3", time.Date(2026, time.April, 2, 9, 0, 0, 0, time.UTC))
+	if march.InvoiceNumber != "INV/2025-26/0001" || april.InvoiceNumber != "INV/2026-27/0001" || next.InvoiceNumber != "INV/2026-27/0002" {
+		t.Fatalf("unexpected numbers: %s, %s, %s", march.InvoiceNumber, april.InvoiceNumber, next.InvoiceNumber)
+	}
+
+	// A failed render stores nothing and uses no number.
+	failing := &domainInvoice.Invoice{FinancialYear: "2026-27", DispatchReference: "DSP-4", CustomerID: customer.ID}
+	if err := repo.Create(failing, func(*domainInvoice.Invoice) ([]byte, error) { return nil, errors.New("render failed") }); err == nil {
+		t.Fatalf("expected the render error")
+	}
+	if again := issue("DSP-4", time.Date(2026, time.April, 3, 9, 0, 0, 0, time.UTC)); again.Sequence != 3 {
+		t.Fatalf("expected the next number to be 3, got %d", again.Sequence)
+	}
+
+	duplicate := &domainInvoice.Invoice{FinancialYear: "2026-27", DispatchReference: "DSP-2", CustomerID: customer.ID}
+	if err := repo.Create(duplicate, render); !errors.Is(err, domainInvoice.ErrDispatchAlreadyInvoiced) {
+		t.Fatalf("expected a second invoice for DSP-2 to be refused, got %v", err)
+	}
+
+	stored, document, err := repo.GetPDF(april.ID)
+	if err != nil {
+		t.Fatalf("GetPDF failed: %v", err)
+	}
+	if string(document) != "%PDF INV/2026-27/0001" || stored.DispatchReference != "DSP-2" || stored.ID != april.ID {
+		t.Fatalf("unexpected stored invoice %+v (%q)", stored, document)
+	}
+	if _, err := repo.Get(999); !errors.Is(err, domainInvoice.ErrInvoiceNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	list, err := repo.List(domainInvoice.ListFilter{FinancialYear: "2026-27"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 3 || list[0].InvoiceNumber != "INV/2026-27/0003" {
+		t.Fatalf("expected the newest 2026-27 invoice first, got %+v", list)
+	}
+
+	if _, err := manager.GetDB().Exec(`UPDATE invoices SET grand_total = 0 WHERE id = ?`, april.ID); err == nil {
+		t.Fatalf("expected issued invoices to be immutable")
+	}
+}
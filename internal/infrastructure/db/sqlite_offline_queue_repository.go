@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	domainOffline "masala_inventory_managment/internal/domain/offline"
+)
+
+// The offline queue lives in its own small database on client machines, which never run the
+// server migrations, so its schema is created here rather than in migrations/.
+const offlineQueueSchema = `CREATE TABLE IF NOT EXISTS offline_queue (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	idempotency_key TEXT NOT NULL UNIQUE,
+	operation TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'PENDING',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+)`
+
+type SqliteOfflineQueueRepository struct {
+	db *sql.DB
+}
+
+// NewSqliteOfflineQueueRepository creates the queue table if needed.
+func NewSqliteOfflineQueueRepository(conn *sql.DB) (*SqliteOfflineQueueRepository, error) {
+	if _, err := conn.ExecContext(context.Background(), offlineQueueSchema); err != nil {
+		return nil, fmt.Errorf("failed to create offline queue schema: %w", err)
+	}
+	return &SqliteOfflineQueueRepository{db: conn}, nil
+}
+
+func (r *SqliteOfflineQueueRepository) Enqueue(entry *domainOffline.Entry) error {
+	if entry == nil {
+		return fmt.Errorf("offline queue entry is nil")
+	}
+	now := time.Now().UTC()
+	entry.Status = domainOffline.StatusPending
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	res, err := r.db.ExecContext(
+		context.Background(),
+		`INSERT INTO offline_queue (idempotency_key, operation, payload, status, attempts, last_error, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, 0, '', ?, ?)`,
+		entry.IdempotencyKey, entry.Operation, entry.Payload, entry.Status, now, now,
+	)
+	if err != nil {
+		return err
+	}
+	entry.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r *SqliteOfflineQueueRepository) List(status string) ([]domainOffline.Entry, error) {
+	query := `SELECT id, idempotency_key, operation, payload, status, attempts, last_error, created_at, updated_at
+		FROM offline_queue`
+	args := []any{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := r.db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]domainOffline.Entry, 0)
+	for rows.Next() {
+		entry, err := scanOfflineEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *SqliteOfflineQueueRepository) Get(id int64) (*domainOffline.Entry, error) {
+	row := r.db.QueryRowContext(
+		context.Background(),
+		`SELECT id, idempotency_key, operation, payload, status, attempts, last_error, created_at, updated_at
+		 FROM offline_queue WHERE id = ?`,
+		id,
+	)
+	entry, err := scanOfflineEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domainOffline.ErrEntryNotFound
+	}
+	return entry, err
+}
+
+func (r *SqliteOfflineQueueRepository) RecordAttempt(id int64, lastError string) error {
+	return r.update(`UPDATE offline_queue SET attempts = attempts + 1, last_error = ?, updated_at = ? WHERE id = ?`, lastError, time.Now().UTC(), id)
+}
+
+func (r *SqliteOfflineQueueRepository) MarkConflict(id int64, reason string) error {
+	return r.update(`UPDATE offline_queue SET status = ?, attempts = attempts + 1, last_error = ?, updated_at = ? WHERE id = ?`,
+		domainOffline.StatusConflict, reason, time.Now().UTC(), id)
+}
+
+func (r *SqliteOfflineQueueRepository) Requeue(id int64, payload string) error {
+	return r.update(`UPDATE offline_queue SET status = ?, payload = ?, updated_at = ? WHERE id = ?`,
+		domainOffline.StatusPending, payload, time.Now().UTC(), id)
+}
+
+func (r *SqliteOfflineQueueRepository) Delete(id int64) error {
+	return r.update(`DELETE FROM offline_queue WHERE id = ?`, id)
+}
+
+func (r *SqliteOfflineQueueRepository) update(statement string, args ...any) error {
+	res, err := r.db.ExecContext(context.Background(), statement, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domainOffline.ErrEntryNotFound
+	}
+	return nil
+}
+
+type offlineEntryScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOfflineEntry(scanner offlineEntryScanner) (*domainOffline.Entry, error) {
+	var entry domainOffline.Entry
+	if err := scanner.Scan(
+		&entry.ID,
+		&entry.IdempotencyKey,
+		&entry.Operation,
+		&entry.Payload,
+		&entry.Status,
+		&entry.Attempts,
+		&entry.LastError,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	domainOffline "masala_inventory_managment/internal/domain/offline"
+)
+
+func setupOfflineQueueRepo(t *testing.T) *SqliteOfflineQueueRepository {
+	t.Helper()
+	manager := NewDatabaseManager(filepath.Join(t.TempDir(), "offline_queue.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("Failed to connect to db: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+
+	repo, err := NewSqliteOfflineQueueRepository(manager.GetDB())
+	if err != nil {
+		t.Fatalf("Failed to create offline queue repository: %v", err)
+	}
+	return repo
+}
+
+func TestOfflineQueue_ListsInEnqueueOrderAndTracksConflicts(t *testing.T) {
+	repo := setupOfflineQueueRepo(t)
+	ids := make([]int64, 0, 3)
+	for _, key := range []string{"key-1", "key-2", "key-3"} {
+		entry := &domainOffline.Entry{IdempotencyKey: key, Operation: domainOffline.OperationCreateGRN, Payload: `{"grn_number":"` + key + `"}`}
+		if err := repo.Enqueue(entry); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	if err := repo.Enqueue(&domainOffline.Entry{IdempotencyKey: "key-1", Operation: domainOffline.OperationCreateGRN, Payload: "{}"}); err == nil {
+		t.Fatalf("expected duplicate idempotency key to be rejected")
+	}
+
+	if err := repo.MarkConflict(ids[1], "duplicate GRN number"); err != nil {
+		t.Fatalf("MarkConflict failed: %v", err)
+	}
+	if err := repo.RecordAttempt(ids[0], "connection refused"); err != nil {
+		t.Fatalf("RecordAttempt failed: %v", err)
+	}
+
+	pending, err := repo.List(domainOffline.StatusPending)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(pending) != 2 || pending[0].IdempotencyKey != "key-1" || pending[1].IdempotencyKey != "key-3" {
+		t.Fatalf("unexpected pending entries: %+v", pending)
+	}
+	if pending[0].Attempts != 1 || pending[0].LastError != "connection refused" {
+		t.Fatalf("expected attempt to be recorded: %+v", pending[0])
+	}
+
+	conflicts, _ := repo.List(domainOffline.StatusConflict)
+	if len(conflicts) != 1 || conflicts[0].LastError != "duplicate GRN number" {
+		t.Fatalf("unexpected conflicts: %+v", conflicts)
+	}
+
+	if err := repo.Requeue(ids[1], `{"grn_number":"key-2b"}`); err != nil {
+		t.Fatalf("Requeue failed: %v", err)
+	}
+	all, _ := repo.List("")
+	if len(all) != 3 || all[1].Status != domainOffline.StatusPending {
+		t.Fatalf("expected requeued entry to keep its place: %+v", all)
+	}
+	if all[1].Payload != `{"grn_number":"key-2b"}` {
+		t.Fatalf("expected requeued entry to carry the corrected payload, got %s", all[1].Payload)
+	}
+
+	if err := repo.Delete(ids[0]); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := repo.Get(ids[0]); !errors.Is(err, domainOffline.ErrEntryNotFound) {
+		t.Fatalf("expected ErrEntryNotFound, got %v", err)
+	}
+	if err := repo.Delete(ids[0]); !errors.Is(err, domainOffline.ErrEntryNotFound) {
+		t.Fatalf("expected ErrEntryNotFound on second delete, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"time"
 
+	domainAudit "masala_inventory_managment/internal/domain/audit"
 	"masala_inventory_managment/internal/domain/auth"
 
 	"github.com/google/uuid"
@@ -13,7 +14,8 @@ import (
 
 // SqliteUserRepository implements auth.UserRepository for SQLite.
 type SqliteUserRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	audit auditScope
 }
 
 // NewSqliteUserRepository creates a new SqliteUserRepository.
@@ -21,6 +23,41 @@ func NewSqliteUserRepository(db *sql.DB) *SqliteUserRepository {
 	return &SqliteUserRepository{db: db}
 }
 
+// WithActor returns a repository whose writes are attributed to actor in the audit log.
+func (r *SqliteUserRepository) WithActor(actor string) auth.UserRepository {
+	scoped := *r
+	scoped.audit = auditScope{actor: actor}
+	return &scoped
+}
+
+// execAuditedTx runs a single-user mutation and records before/after snapshots in the same transaction.
+func (r *SqliteUserRepository) execAuditedTx(action, username, query string, args ...interface{}) error {
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		before, err := snapshotRowTx(tx, "users", "username", username)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(context.Background(), query, args...)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+
+		after, err := snapshotRowTx(tx, "users", "username", username)
+		if err != nil {
+			return err
+		}
+		return insertAuditTx(tx, r.audit, action, "user", username, before, after)
+	})
+}
+
 // Save persists a user to the database.
 func (r *SqliteUserRepository) Save(user *auth.User) error {
 	if user.ID == "" {
@@ -34,18 +71,27 @@ func (r *SqliteUserRepository) Save(user *auth.User) error {
 	}
 
 	query := `INSERT INTO users (id, username, password_hash, role, is_active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	_, err := r.db.ExecContext(context.Background(), query, user.ID, user.Username, user.PasswordHash, user.Role, user.IsActive, user.CreatedAt, user.UpdatedAt)
-	return err
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(context.Background(), query, user.ID, user.Username, user.PasswordHash, user.Role, user.IsActive, user.CreatedAt, user.UpdatedAt); err != nil {
+			return err
+		}
+		after, err := snapshotRowTx(tx, "users", "username", user.Username)
+		if err != nil {
+			return err
+		}
+		return insertAuditTx(tx, r.audit, domainAudit.ActionCreate, "user", user.Username, "", after)
+	})
 }
 
 // FindByUsername retrieves a user by their username.
 func (r *SqliteUserRepository) FindByUsername(username string) (*auth.User, error) {
-	query := `SELECT id, username, password_hash, role, is_active, created_at, updated_at FROM users WHERE username = ?`
+	query := `SELECT id, username, password_hash, pin_hash, role, is_active, created_at, updated_at FROM users WHERE username = ?`
 	row := r.db.QueryRowContext(context.Background(), query, username)
 
 	var user auth.User
 	var roleStr string
-	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &roleStr, &user.IsActive, &user.CreatedAt, &user.UpdatedAt)
+	var pinHash sql.NullString
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &pinHash, &roleStr, &user.IsActive, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // Return nil if user not found, let service handle it
@@ -53,6 +99,7 @@ func (r *SqliteUserRepository) FindByUsername(username string) (*auth.User, erro
 		return nil, err
 	}
 	user.Role = auth.Role(roleStr)
+	user.PinHash = pinHash.String
 	return &user, nil
 }
 
@@ -65,7 +112,7 @@ func (r *SqliteUserRepository) Count() (int, error) {
 }
 
 func (r *SqliteUserRepository) List() ([]auth.User, error) {
-	query := `SELECT id, username, password_hash, role, is_active, created_at, updated_at FROM users ORDER BY created_at ASC`
+	query := `SELECT id, username, password_hash, pin_hash, role, is_active, created_at, updated_at FROM users ORDER BY created_at ASC`
 	rows, err := r.db.QueryContext(context.Background(), query)
 	if err != nil {
 		return nil, err
@@ -76,10 +123,12 @@ func (r *SqliteUserRepository) List() ([]auth.User, error) {
 	for rows.Next() {
 		var user auth.User
 		var roleStr string
-		if scanErr := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &roleStr, &user.IsActive, &user.CreatedAt, &user.UpdatedAt); scanErr != nil {
+		var pinHash sql.NullString
+		if scanErr := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &pinHash, &roleStr, &user.IsActive, &user.CreatedAt, &user.UpdatedAt); scanErr != nil {
 			return nil, scanErr
 		}
 		user.Role = auth.Role(roleStr)
+		user.PinHash = pinHash.String
 		users = append(users, user)
 	}
 	if err := rows.Err(); err != nil {
@@ -90,66 +139,32 @@ func (r *SqliteUserRepository) List() ([]auth.User, error) {
 
 func (r *SqliteUserRepository) UpdateRole(username string, role auth.Role) error {
 	query := `UPDATE users SET role = ?, updated_at = ? WHERE username = ?`
-	result, err := r.db.ExecContext(context.Background(), query, role, time.Now(), username)
-	if err != nil {
-		return err
-	}
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if affected == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return r.execAuditedTx(domainAudit.ActionUpdateRole, username, query, role, time.Now(), username)
 }
 
 func (r *SqliteUserRepository) SetActive(username string, isActive bool) error {
 	query := `UPDATE users SET is_active = ?, updated_at = ? WHERE username = ?`
-	result, err := r.db.ExecContext(context.Background(), query, isActive, time.Now(), username)
-	if err != nil {
-		return err
-	}
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if affected == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return r.execAuditedTx(domainAudit.ActionSetActive, username, query, isActive, time.Now(), username)
 }
 
 func (r *SqliteUserRepository) UpdatePasswordHash(username, passwordHash string) error {
 	query := `UPDATE users SET password_hash = ?, updated_at = ? WHERE username = ?`
-	result, err := r.db.ExecContext(context.Background(), query, passwordHash, time.Now(), username)
-	if err != nil {
-		return err
-	}
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if affected == 0 {
-		return sql.ErrNoRows
+	return r.execAuditedTx(domainAudit.ActionResetPassword, username, query, passwordHash, time.Now(), username)
+}
+
+// UpdatePinHash stores the operator PIN hash. An empty hash clears the PIN.
+func (r *SqliteUserRepository) UpdatePinHash(username, pinHash string) error {
+	query := `UPDATE users SET pin_hash = ?, updated_at = ? WHERE username = ?`
+	var value interface{}
+	if pinHash != "" {
+		value = pinHash
 	}
-	return nil
+	return r.execAuditedTx(domainAudit.ActionSetPin, username, query, value, time.Now(), username)
 }
 
 func (r *SqliteUserRepository) DeleteByUsername(username string) error {
 	query := `DELETE FROM users WHERE username = ?`
-	result, err := r.db.ExecContext(context.Background(), query, username)
-	if err != nil {
-		return err
-	}
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if affected == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return r.execAuditedTx(domainAudit.ActionDelete, username, query, username)
 }
 
 func (r *SqliteUserRepository) CountActiveAdmins() (int, error) {
@@ -27,6 +27,7 @@ func TestSqliteUserRepository_CRUDAndManagement(t *testing.T) {
 		id TEXT PRIMARY KEY,
 		username TEXT UNIQUE NOT NULL,
 		password_hash TEXT NOT NULL,
+		pin_hash TEXT,
 		role TEXT NOT NULL,
 		is_active BOOLEAN DEFAULT TRUE,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -35,6 +36,21 @@ func TestSqliteUserRepository_CRUDAndManagement(t *testing.T) {
 	if _, err := manager.GetDB().Exec(query); err != nil {
 		t.Fatalf("failed to create users table: %v", err)
 	}
+	auditQuery := `CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		entity_type TEXT NOT NULL,
+		entity_id TEXT NOT NULL,
+		before_json TEXT,
+		after_json TEXT,
+		created_at DATETIME NOT NULL,
+		prev_hash TEXT,
+		row_hash TEXT
+	)`
+	if _, err := manager.GetDB().Exec(auditQuery); err != nil {
+		t.Fatalf("failed to create audit_log table: %v", err)
+	}
 
 	repo := NewSqliteUserRepository(manager.GetDB())
 
@@ -107,6 +123,27 @@ func TestSqliteUserRepository_CRUDAndManagement(t *testing.T) {
 		t.Fatalf("expected updated password hash, got %#v", updatedOperator)
 	}
 
+	if err := repo.UpdatePinHash("operator", "pin_hash"); err != nil {
+		t.Fatalf("UpdatePinHash failed: %v", err)
+	}
+	updatedOperator, err = repo.FindByUsername("operator")
+	if err != nil {
+		t.Fatalf("FindByUsername operator failed: %v", err)
+	}
+	if updatedOperator == nil || !updatedOperator.HasPin() || updatedOperator.PinHash != "pin_hash" {
+		t.Fatalf("expected updated pin hash, got %#v", updatedOperator)
+	}
+	if err := repo.UpdatePinHash("operator", ""); err != nil {
+		t.Fatalf("UpdatePinHash clear failed: %v", err)
+	}
+	updatedOperator, err = repo.FindByUsername("operator")
+	if err != nil {
+		t.Fatalf("FindByUsername operator failed: %v", err)
+	}
+	if updatedOperator == nil || updatedOperator.HasPin() {
+		t.Fatalf("expected cleared pin hash, got %#v", updatedOperator)
+	}
+
 	activeAdmins, err := repo.CountActiveAdmins()
 	if err != nil {
 		t.Fatalf("CountActiveAdmins failed: %v", err)
@@ -0,0 +1,286 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	domainWebhook "masala_inventory_managment/internal/domain/webhook"
+)
+
+const maxWebhookDeliveryRows = 1000
+
+type SqliteWebhookRepository struct {
+	db *sql.DB
+}
+
+func NewSqliteWebhookRepository(db *sql.DB) *SqliteWebhookRepository {
+	return &SqliteWebhookRepository{db: db}
+}
+
+func (r *SqliteWebhookRepository) CreateSubscription(sub *domainWebhook.Subscription) error {
+	eventTypes, err := json.Marshal(sub.EventTypes)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	res, err := r.db.ExecContext(
+		context.Background(),
+		`INSERT INTO webhook_subscriptions (name, url, event_types, secret, is_active, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sub.Name, sub.URL, string(eventTypes), sub.Secret, sub.IsActive, now, now,
+	)
+	if err != nil {
+		return err
+	}
+	sub.ID, err = res.LastInsertId()
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+	return err
+}
+
+func (r *SqliteWebhookRepository) UpdateSubscription(sub *domainWebhook.Subscription) error {
+	eventTypes, err := json.Marshal(sub.EventTypes)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	res, err := r.db.ExecContext(
+		context.Background(),
+		`UPDATE webhook_subscriptions
+		 SET name = ?, url = ?, event_types = ?, secret = ?, is_active = ?, updated_at = ?
+		 WHERE id = ?`,
+		sub.Name, sub.URL, string(eventTypes), sub.Secret, sub.IsActive, now, sub.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return domainWebhook.ErrSubscriptionNotFound
+	}
+	sub.UpdatedAt = now
+	return nil
+}
+
+func (r *SqliteWebhookRepository) GetSubscription(id int64) (*domainWebhook.Subscription, error) {
+	row := r.db.QueryRowContext(
+		context.Background(),
+		`SELECT id, name, url, event_types, secret, is_active, created_at, updated_at
+		 FROM webhook_subscriptions WHERE id = ?`,
+		id,
+	)
+	sub, err := scanWebhookSubscription(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domainWebhook.ErrSubscriptionNotFound
+	}
+	return sub, err
+}
+
+func (r *SqliteWebhookRepository) ListSubscriptions() ([]domainWebhook.Subscription, error) {
+	rows, err := r.db.QueryContext(
+		context.Background(),
+		`SELECT id, name, url, event_types, secret, is_active, created_at, updated_at
+		 FROM webhook_subscriptions ORDER BY id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := make([]domainWebhook.Subscription, 0)
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// DeleteSubscription removes the subscription and, through the foreign key, its undelivered
+// outbox entries. The delivery log is kept.
+func (r *SqliteWebhookRepository) DeleteSubscription(id int64) error {
+	res, err := r.db.ExecContext(context.Background(), `DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return domainWebhook.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *SqliteWebhookRepository) Enqueue(entries []domainWebhook.OutboxEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		for i := range entries {
+			entry := &entries[i]
+			if entry.NextAttemptAt.IsZero() {
+				entry.NextAttemptAt = now
+			}
+			entry.Status = domainWebhook.OutboxPending
+			entry.CreatedAt = now
+			res, err := tx.ExecContext(
+				context.Background(),
+				`INSERT INTO webhook_outbox (subscription_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at)
+				 VALUES (?, ?, ?, ?, 0, ?, '', ?)`,
+				entry.SubscriptionID, entry.EventType, entry.Payload, entry.Status, entry.NextAttemptAt.UTC(), now,
+			)
+			if err != nil {
+				return err
+			}
+			if entry.ID, err = res.LastInsertId(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SqliteWebhookRepository) DueOutbox(now time.Time, limit int) ([]domainWebhook.OutboxEntry, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := r.db.QueryContext(
+		context.Background(),
+		`SELECT id, subscription_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at
+		 FROM webhook_outbox
+		 WHERE status = ? AND next_attempt_at <= ?
+		 ORDER BY id ASC
+		 LIMIT ?`,
+		domainWebhook.OutboxPending, now.UTC(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]domainWebhook.OutboxEntry, 0)
+	for rows.Next() {
+		var entry domainWebhook.OutboxEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.SubscriptionID,
+			&entry.EventType,
+			&entry.Payload,
+			&entry.Status,
+			&entry.Attempts,
+			&entry.NextAttemptAt,
+			&entry.LastError,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *SqliteWebhookRepository) RecordDelivery(delivery *domainWebhook.Delivery, status string, nextAttemptAt time.Time) error {
+	if delivery == nil {
+		return fmt.Errorf("webhook delivery is nil")
+	}
+	if delivery.AttemptedAt.IsZero() {
+		delivery.AttemptedAt = time.Now().UTC()
+	}
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			context.Background(),
+			`INSERT INTO webhook_deliveries (outbox_id, subscription_id, event_type, attempt, status_code, success, error, duration_ms, attempted_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			delivery.OutboxID, delivery.SubscriptionID, delivery.EventType, delivery.Attempt,
+			delivery.StatusCode, delivery.Success, delivery.Error, delivery.DurationMs, delivery.AttemptedAt.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+		if delivery.ID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			context.Background(),
+			`UPDATE webhook_outbox SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+			status, delivery.Attempt, nextAttemptAt.UTC(), delivery.Error, delivery.OutboxID,
+		)
+		return err
+	})
+}
+
+func (r *SqliteWebhookRepository) ListDeliveries(filter domainWebhook.DeliveryFilter) ([]domainWebhook.Delivery, error) {
+	query := `SELECT id, outbox_id, subscription_id, event_type, attempt, status_code, success, error, duration_ms, attempted_at
+		FROM webhook_deliveries`
+	args := []any{}
+	if filter.SubscriptionID > 0 {
+		query += " WHERE subscription_id = ?"
+		args = append(args, filter.SubscriptionID)
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > maxWebhookDeliveryRows {
+		limit = maxWebhookDeliveryRows
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]domainWebhook.Delivery, 0)
+	for rows.Next() {
+		var d domainWebhook.Delivery
+		if err := rows.Scan(
+			&d.ID,
+			&d.OutboxID,
+			&d.SubscriptionID,
+			&d.EventType,
+			&d.Attempt,
+			&d.StatusCode,
+			&d.Success,
+			&d.Error,
+			&d.DurationMs,
+			&d.AttemptedAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+type webhookSubscriptionScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhookSubscription(scanner webhookSubscriptionScanner) (*domainWebhook.Subscription, error) {
+	var sub domainWebhook.Subscription
+	var eventTypes string
+	if err := scanner.Scan(
+		&sub.ID,
+		&sub.Name,
+		&sub.URL,
+		&eventTypes,
+		&sub.Secret,
+		&sub.IsActive,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(eventTypes), &sub.EventTypes); err != nil {
+		return nil, fmt.Errorf("webhook %d has corrupt event types: %w", sub.ID, err)
+	}
+	return &sub, nil
+}
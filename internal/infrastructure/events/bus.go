@@ -0,0 +1,64 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	domainEvents "masala_inventory_managment/internal/domain/events"
+)
+
+const DefaultSubscriberBuffer = 64
+
+// Bus is an in-process publish/subscribe hub. A subscriber that falls behind loses events
+// rather than slowing down the write that published them; Sequence lets it notice the gap.
+type Bus struct {
+	mu          sync.Mutex
+	sequence    uint64
+	nextID      int
+	subscribers map[int]chan domainEvents.Event
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]chan domainEvents.Event)}
+}
+
+func (b *Bus) Publish(event domainEvents.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sequence++
+	event.Sequence = b.sequence
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of events published from now on and a func that ends the
+// subscription and closes the channel.
+func (b *Bus) Subscribe(buffer int) (<-chan domainEvents.Event, func()) {
+	if buffer <= 0 {
+		buffer = DefaultSubscriberBuffer
+	}
+	ch := make(chan domainEvents.Event, buffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
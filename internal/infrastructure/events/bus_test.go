@@ -0,0 +1,55 @@
+package events
+
+import (
+	"testing"
+
+	domainEvents "masala_inventory_managment/internal/domain/events"
+)
+
+func TestBus_DeliversToSubscribersInOrder(t *testing.T) {
+	bus := NewBus()
+	first, cancelFirst := bus.Subscribe(4)
+	second, cancelSecond := bus.Subscribe(4)
+	defer cancelSecond()
+
+	bus.Publish(domainEvents.Event{Type: domainEvents.TypeGrnCreated, EntityID: "1"})
+	bus.Publish(domainEvents.Event{Type: domainEvents.TypeLotMovementRecorded, EntityID: "2"})
+
+	for _, ch := range []<-chan domainEvents.Event{first, second} {
+		a, b := <-ch, <-ch
+		if a.Type != domainEvents.TypeGrnCreated || b.Type != domainEvents.TypeLotMovementRecorded {
+			t.Fatalf("unexpected order: %+v, %+v", a, b)
+		}
+		if a.Sequence+1 != b.Sequence || a.OccurredAt.IsZero() {
+			t.Fatalf("expected consecutive sequence and timestamp: %+v, %+v", a, b)
+		}
+	}
+
+	cancelFirst()
+	if _, open := <-first; open {
+		t.Fatalf("expected cancelled subscription to be closed")
+	}
+	cancelFirst()
+	bus.Publish(domainEvents.Event{Type: domainEvents.TypeItemUpdated})
+	if got := <-second; got.Type != domainEvents.TypeItemUpdated {
+		t.Fatalf("expected remaining subscriber to keep receiving, got %+v", got)
+	}
+}
+
+func TestBus_SlowSubscriberDoesNotBlockPublish(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Subscribe(1)
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(domainEvents.Event{Type: domainEvents.TypeAdjustmentPosted})
+	}
+	if got := <-ch; got.Sequence != 1 {
+		t.Fatalf("expected first event to be kept, got %+v", got)
+	}
+	select {
+	case extra := <-ch:
+		t.Fatalf("expected overflow to be dropped, got %+v", extra)
+	default:
+	}
+}
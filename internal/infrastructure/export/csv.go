@@ -0,0 +1,74 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	writer  *csv.Writer
+	columns int
+}
+
+// newCSVWriter writes the company header as leading rows, a blank row, then the column
+// titles. A UTF-8 byte order mark makes Excel read non-ASCII names correctly.
+func newCSVWriter(w io.Writer, doc Document) (*csvWriter, error) {
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return nil, err
+	}
+	cw := &csvWriter{writer: csv.NewWriter(w), columns: len(doc.Columns)}
+
+	header := doc.Company.Lines()
+	for _, line := range []string{doc.Title, doc.Subtitle, doc.generatedLine()} {
+		if line != "" {
+			header = append(header, line)
+		}
+	}
+	for _, line := range header {
+		if err := cw.writer.Write([]string{line}); err != nil {
+			return nil, err
+		}
+	}
+	if len(header) > 0 {
+		if err := cw.writer.Write([]string{""}); err != nil {
+			return nil, err
+		}
+	}
+
+	titles := make([]string, len(doc.Columns))
+	for i, column := range doc.Columns {
+		titles[i] = column.Title
+	}
+	if err := cw.writer.Write(titles); err != nil {
+		return nil, err
+	}
+	cw.writer.Flush()
+	if err := cw.writer.Error(); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (c *csvWriter) WriteRow(cells []string) error {
+	record := make([]string, c.columns)
+	for i, cell := range fitRow(cells, c.columns) {
+		record[i] = neutralizeFormula(cell)
+	}
+	// csv.Writer buffers a few kilobytes and then writes through, so rows are not held.
+	return c.writer.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// neutralizeFormula stops a spreadsheet from evaluating user-entered text such as
+// "=HYPERLINK(...)" when the CSV is opened. Numbers, including negative ones, are left alone.
+func neutralizeFormula(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) || isFiniteNumber(cell) {
+		return cell
+	}
+	return "'" + cell
+}
@@ -0,0 +1,120 @@
+// Package export renders tabular data as CSV, XLSX or PDF. Every writer streams: rows are
+// written as they arrive, so the size of an export is not bounded by memory.
+package export
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatPDF  = "pdf"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format: must be csv, xlsx or pdf")
+
+// Company is printed at the top of every export.
+type Company struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	GSTIN   string `json:"gstin"`
+	Phone   string `json:"phone"`
+}
+
+// Lines returns the non-empty header lines, name first.
+func (c Company) Lines() []string {
+	lines := make([]string, 0, 4)
+	for _, value := range []string{c.Name, c.Address, c.Phone} {
+		if value = strings.TrimSpace(value); value != "" {
+			lines = append(lines, value)
+		}
+	}
+	if gstin := strings.TrimSpace(c.GSTIN); gstin != "" {
+		lines = append(lines, "GSTIN: "+gstin)
+	}
+	return lines
+}
+
+// Column describes one column of a table. Numeric columns are right-aligned in PDF and
+// written as numbers in XLSX; Width is a relative PDF column width, defaulting to 1.
+type Column struct {
+	Title   string
+	Numeric bool
+	Width   float64
+}
+
+// Document is everything printed above the rows.
+type Document struct {
+	Company     Company
+	Title       string
+	Subtitle    string
+	GeneratedAt time.Time
+	Columns     []Column
+}
+
+func (d Document) generatedLine() string {
+	if d.GeneratedAt.IsZero() {
+		return ""
+	}
+	return "Generated " + d.GeneratedAt.Format("02 Jan 2006 15:04")
+}
+
+// Writer receives rows one at a time. Close must be called to finish the file; it does not
+// close the underlying io.Writer.
+type Writer interface {
+	WriteRow(cells []string) error
+	Close() error
+}
+
+// NormalizeFormat validates a requested format, defaulting to CSV.
+func NormalizeFormat(format string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(format))
+	switch value {
+	case "":
+		return FormatCSV, nil
+	case FormatCSV, FormatXLSX, FormatPDF:
+		return value, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// ContentType returns the MIME type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// NewWriter starts a document in the given format. The header is written immediately.
+func NewWriter(format string, w io.Writer, doc Document) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, doc)
+	case FormatXLSX:
+		return newXLSXWriter(w, doc)
+	case FormatPDF:
+		return newPDFWriter(w, doc)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// fitRow pads or trims cells to the number of columns.
+func fitRow(cells []string, width int) []string {
+	if len(cells) == width {
+		return cells
+	}
+	fitted := make([]string, width)
+	copy(fitted, cells)
+	return fitted
+}
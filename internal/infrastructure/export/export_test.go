@@ -0,0 +1,189 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"masala_inventory_managment/internal/infrastructure/spreadsheet"
+)
+
+func testDocument() Document {
+	return Document{
+		Company:     Company{Name: "Shree Masala Works", Address: "Plot 4, MIDC, Nashik", GSTIN: "27ABCDE1234F1Z5"},
+		Title:       "Items",
+		Subtitle:    "Active only",
+		GeneratedAt: time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+		Columns:     []Column{{Title: "SKU"}, {Title: "Name", Width: 3}, {Title: "Minimum Stock", Numeric: true}},
+	}
+}
+
+func writeDocument(t *testing.T, format string, doc Document, rows [][]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf, doc)
+	if err != nil {
+		t.Fatalf("NewWriter(%s) failed: %v", format, err)
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestNormalizeFormat(t *testing.T) {
+	for input, want := range map[string]string{"": FormatCSV, " PDF ": FormatPDF, "xlsx": FormatXLSX} {
+		got, err := NormalizeFormat(input)
+		if err != nil || got != want {
+			t.Fatalf("NormalizeFormat(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := NormalizeFormat("ods"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ods to be unsupported, got %v", err)
+	}
+}
+
+func TestCSVWriter_WritesCompanyHeaderAndNeutralizesFormulas(t *testing.T) {
+	content := writeDocument(t, FormatCSV, testDocument(), [][]string{
+		{"CUM-1", "Cumin, whole", "12.5"},
+		{"=HYPERLINK(\"x\")", "@SUM(A1)", "-4"},
+		{"SHORT"},
+	})
+
+	want := "\xef\xbb\xbf" +
+		"Shree Masala Works\n" +
+		"\"Plot 4, MIDC, Nashik\"\n" +
+		"GSTIN: 27ABCDE1234F1Z5\n" +
+		"Items\n" +
+		"Active only\n" +
+		"Generated 18 Oct 2026 09:30\n" +
+		"\n" +
+		"SKU,Name,Minimum Stock\n" +
+		"CUM-1,\"Cumin, whole\",12.5\n" +
+		"\"'=HYPERLINK(\"\"x\"\")\",'@SUM(A1),-4\n" +
+		"SHORT,,\n"
+	if string(content) != want {
+		t.Fatalf("unexpected csv:\n%s", content)
+	}
+}
+
+func TestXLSXWriter_ProducesAWorkbookTheImporterCanRead(t *testing.T) {
+	content := writeDocument(t, FormatXLSX, testDocument(), [][]string{
+		{"CUM-1", "Cumin <whole> & ground", "12.5"},
+		{"COR-1", "Coriander", "n/a"},
+	})
+
+	rows, err := spreadsheet.ReadRows(spreadsheet.FormatXLSX, content)
+	if err != nil {
+		t.Fatalf("ReadRows failed: %v", err)
+	}
+	if len(rows) != 9 {
+		t.Fatalf("expected 6 header rows, column titles and 2 data rows, got %+v", rows)
+	}
+	if rows[0].Cells[0] != "Shree Masala Works" || rows[6].Number != 8 {
+		t.Fatalf("expected the company header first and a blank row before the titles, got %+v", rows)
+	}
+	if want := []string{"SKU", "Name", "Minimum Stock"}; !reflect.DeepEqual(rows[6].Cells, want) {
+		t.Fatalf("unexpected column titles: %+v", rows[6].Cells)
+	}
+	if want := []string{"CUM-1", "Cumin <whole> & ground", "12.5"}; !reflect.DeepEqual(rows[7].Cells, want) {
+		t.Fatalf("unexpected first data row: %+v", rows[7].Cells)
+	}
+	if want := []string{"COR-1", "Coriander", "n/a"}; !reflect.DeepEqual(rows[8].Cells, want) {
+		t.Fatalf("unexpected second data row: %+v", rows[8].Cells)
+	}
+}
+
+func TestPDFWriter_PaginatesAndKeepsCrossReferencesValid(t *testing.T) {
+	rows := make([][]string, 0, 120)
+	for i := 0; i < 120; i++ {
+		rows = append(rows, []string{fmt.Sprintf("SKU-%03d", i), "Garam masala (100 g) – retail pouch ₹", strconv.Itoa(i)})
+	}
+	content := writeDocument(t, FormatPDF, testDocument(), rows)
+
+	if !bytes.HasPrefix(content, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(content, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	pageCount := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(content)
+	if pageCount == nil {
+		t.Fatalf("missing page tree")
+	}
+	if pages, _ := strconv.Atoi(string(pageCount[1])); pages < 2 {
+		t.Fatalf("expected 120 rows to span several pages, got %d", pages)
+	}
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(content)
+	if startxref == nil {
+		t.Fatalf("missing startxref")
+	}
+	xrefOffset, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(content[xrefOffset:], []byte("xref\n")) {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(content[xrefOffset:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(content[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", i+1, content[offset:offset+12])
+		}
+	}
+
+	firstPage := firstContentStream(t, content)
+	for _, want := range []string{"(Shree Masala Works)", "(GSTIN: 27ABCDE1234F1Z5)", "(SKU-000)", "\x96 retail pouch Rs.)", "(Items - Page 1)"} {
+		if !strings.Contains(firstPage, want) {
+			t.Fatalf("expected first page to contain %q", want)
+		}
+	}
+}
+
+func firstContentStream(t *testing.T, content []byte) string {
+	t.Helper()
+	start := bytes.Index(content, []byte("stream\n"))
+	end := bytes.Index(content, []byte("\nendstream"))
+	if start < 0 || end < start {
+		t.Fatalf("no content stream found")
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(content[start+len("stream\n") : end]))
+	if err != nil {
+		t.Fatalf("content stream is not zlib: %v", err)
+	}
+	page, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to inflate content stream: %v", err)
+	}
+	return string(page)
+}
+
+func TestWrapText(t *testing.T) {
+	lines := wrapText("Whole cumin seeds from Unjha mandi", helvetica, 10, 80, 6)
+	for _, line := range lines {
+		if helvetica.width(line, 10) > 80 {
+			t.Fatalf("line %q is wider than the column", line)
+		}
+	}
+	if strings.Join(lines, " ") != "Whole cumin seeds from Unjha mandi" {
+		t.Fatalf("wrapping lost words: %q", lines)
+	}
+
+	long := wrapText(strings.Repeat("W", 200), helvetica, 10, 50, 3)
+	if len(long) != 3 || !strings.HasSuffix(long[2], "...") || helvetica.width(long[2], 10) > 50 {
+		t.Fatalf("expected an unbroken word to be split and cut at 3 lines, got %q", long)
+	}
+
+	if got := wrapText("", helvetica, 10, 50, 3); !reflect.DeepEqual(got, []string{""}) {
+		t.Fatalf("expected an empty cell to keep one line, got %q", got)
+	}
+}
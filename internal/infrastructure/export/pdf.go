@@ -0,0 +1,391 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page geometry, in points: A4 landscape so wide ledgers fit.
+const (
+	pdfPageWidth    = 842.0
+	pdfPageHeight   = 595.0
+	pdfMargin       = 36.0
+	pdfFooterHeight = 16.0
+	pdfFontSize     = 8.0
+	pdfLineHeight   = 10.0
+	pdfCellPadding  = 3.0
+	pdfMaxCellLines = 6
+)
+
+// Fixed object numbers. Pages and the catalog are written last, once every page is known;
+// PDF readers locate objects through the cross-reference table, so order does not matter.
+const (
+	pdfCatalogObject = 1
+	pdfPagesObject   = 2
+	pdfFontObject    = 3
+	pdfBoldObject    = 4
+)
+
+// pdfFont is one of the standard Type 1 fonts every PDF reader ships, so nothing has to be
+// embedded. Widths are in 1/1000 em for the printable ASCII range, from the Adobe AFM files.
+type pdfFont struct {
+	resource string
+	widths   [95]int
+}
+
+func (f *pdfFont) width(text string, size float64) float64 {
+	total := 0
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= 32 && c <= 126 {
+			total += f.widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+var helvetica = &pdfFont{resource: "F1", widths: [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}}
+
+var helveticaBold = &pdfFont{resource: "F2", widths: [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}}
+
+// pdfWinAnsi maps the characters WinAnsiEncoding places in 0x80-0x9F. Latin-1 maps to itself.
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '…': 0x85, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+}
+
+// countingWriter tracks the byte offset of each object for the cross-reference table.
+type countingWriter struct {
+	w      *bufio.Writer
+	offset int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.offset += int64(n)
+	return n, err
+}
+
+type pdfWriter struct {
+	out     *countingWriter
+	doc     Document
+	widths  []float64
+	offsets []int64
+	pages   []int
+
+	page       bytes.Buffer
+	pageNumber int
+	y          float64
+}
+
+func newPDFWriter(w io.Writer, doc Document) (*pdfWriter, error) {
+	pw := &pdfWriter{
+		out:     &countingWriter{w: bufio.NewWriter(w)},
+		doc:     doc,
+		offsets: make([]int64, pdfBoldObject+1),
+	}
+	pw.widths = columnWidths(doc.Columns, pdfPageWidth-2*pdfMargin)
+
+	// The binary comment line tells transfer tools the file is not plain text.
+	if _, err := io.WriteString(pw.out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"); err != nil {
+		return nil, err
+	}
+	if err := pw.writeObject(pdfFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"); err != nil {
+		return nil, err
+	}
+	if err := pw.writeObject(pdfBoldObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"); err != nil {
+		return nil, err
+	}
+
+	pw.startPage()
+	pw.writeDocumentHeader()
+	pw.writeColumnHeader()
+	return pw, nil
+}
+
+func (p *pdfWriter) WriteRow(cells []string) error {
+	cells = fitRow(cells, len(p.widths))
+	lines, height := p.layoutRow(cells, helvetica)
+	if p.y-height < pdfMargin+pdfFooterHeight {
+		if err := p.finishPage(); err != nil {
+			return err
+		}
+		p.startPage()
+		p.writeColumnHeader()
+	}
+	p.drawRow(lines, height, helvetica)
+	fmt.Fprintf(&p.page, "0.8 G 0.4 w %.2f %.2f m %.2f %.2f l S 0 G\n", pdfMargin, p.y, pdfPageWidth-pdfMargin, p.y)
+	return nil
+}
+
+func (p *pdfWriter) Close() error {
+	if err := p.finishPage(); err != nil {
+		return err
+	}
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	if err := p.writeObject(pdfPagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages))); err != nil {
+		return err
+	}
+	if err := p.writeObject(pdfCatalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObject)); err != nil {
+		return err
+	}
+
+	xref := p.out.offset
+	var b strings.Builder
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets))
+	for _, offset := range p.offsets[1:] {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets), pdfCatalogObject, xref)
+	if _, err := io.WriteString(p.out, b.String()); err != nil {
+		return err
+	}
+	return p.out.w.Flush()
+}
+
+func (p *pdfWriter) startPage() {
+	p.page.Reset()
+	p.pageNumber++
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// finishPage compresses the page content and writes it with its page object.
+func (p *pdfWriter) finishPage() error {
+	footerY := pdfMargin
+	p.text(helvetica, 7, pdfMargin, footerY, pdfEncode(p.doc.Company.Name))
+	pageLabel := fmt.Sprintf("%s - Page %d", pdfEncode(p.doc.Title), p.pageNumber)
+	p.text(helvetica, 7, pdfPageWidth-pdfMargin-helvetica.width(pageLabel, 7), footerY, pageLabel)
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(p.page.Bytes()); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	contentObject := p.allocateObject()
+	pageObject := p.allocateObject()
+	if err := p.writeObject(contentObject, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes())); err != nil {
+		return err
+	}
+	if err := p.writeObject(pageObject, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesObject, pdfPageWidth, pdfPageHeight, pdfFontObject, pdfBoldObject, contentObject,
+	)); err != nil {
+		return err
+	}
+	p.pages = append(p.pages, pageObject)
+	return nil
+}
+
+func (p *pdfWriter) writeDocumentHeader() {
+	for i, line := range p.doc.Company.Lines() {
+		if i == 0 {
+			p.y -= 14
+			p.text(helveticaBold, 14, pdfMargin, p.y, pdfEncode(line))
+			continue
+		}
+		p.y -= 11
+		p.text(helvetica, 9, pdfMargin, p.y, pdfEncode(line))
+	}
+	if p.doc.Title != "" {
+		p.y -= 20
+		p.text(helveticaBold, 12, pdfMargin, p.y, pdfEncode(p.doc.Title))
+	}
+	for _, line := range []string{p.doc.Subtitle, p.doc.generatedLine()} {
+		if line != "" {
+			p.y -= 11
+			p.text(helvetica, 9, pdfMargin, p.y, pdfEncode(line))
+		}
+	}
+	p.y -= 10
+}
+
+func (p *pdfWriter) writeColumnHeader() {
+	titles := make([]string, len(p.doc.Columns))
+	for i, column := range p.doc.Columns {
+		titles[i] = column.Title
+	}
+	lines, height := p.layoutRow(titles, helveticaBold)
+	fmt.Fprintf(&p.page, "0.9 g %.2f %.2f %.2f %.2f re f 0 g\n", pdfMargin, p.y-height, pdfPageWidth-2*pdfMargin, height)
+	p.drawRow(lines, height, helveticaBold)
+}
+
+// layoutRow wraps every cell to its column and returns the lines with the row height.
+func (p *pdfWriter) layoutRow(cells []string, font *pdfFont) ([][]string, float64) {
+	lines := make([][]string, len(cells))
+	maxLines := 1
+	for i, cell := range cells {
+		lines[i] = wrapText(pdfEncode(cell), font, pdfFontSize, p.widths[i]-2*pdfCellPadding, pdfMaxCellLines)
+		if len(lines[i]) > maxLines {
+			maxLines = len(lines[i])
+		}
+	}
+	return lines, float64(maxLines)*pdfLineHeight + 2*pdfCellPadding
+}
+
+func (p *pdfWriter) drawRow(lines [][]string, height float64, font *pdfFont) {
+	x := pdfMargin
+	for i, cellLines := range lines {
+		for j, line := range cellLines {
+			baseline := p.y - pdfCellPadding - float64(j+1)*pdfLineHeight + 2
+			left := x + pdfCellPadding
+			if p.doc.Columns[i].Numeric {
+				left = x + p.widths[i] - pdfCellPadding - font.width(line, pdfFontSize)
+			}
+			p.text(font, pdfFontSize, left, baseline, line)
+		}
+		x += p.widths[i]
+	}
+	p.y -= height
+}
+
+// text draws a string that is already WinAnsi encoded.
+func (p *pdfWriter) text(font *pdfFont, size, x, y float64, encoded string) {
+	if encoded == "" {
+		return
+	}
+	fmt.Fprintf(&p.page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font.resource, size, x, y, pdfEscape(encoded))
+}
+
+func (p *pdfWriter) allocateObject() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets) - 1
+}
+
+func (p *pdfWriter) writeObject(number int, body string) error {
+	p.offsets[number] = p.out.offset
+	_, err := fmt.Fprintf(p.out, "%d 0 obj\n%s\nendobj\n", number, body)
+	return err
+}
+
+func columnWidths(columns []Column, available float64) []float64 {
+	total := 0.0
+	for _, column := range columns {
+		total += columnWeight(column)
+	}
+	widths := make([]float64, len(columns))
+	for i, column := range columns {
+		widths[i] = available * columnWeight(column) / total
+	}
+	return widths
+}
+
+func columnWeight(column Column) float64 {
+	if column.Width > 0 {
+		return column.Width
+	}
+	return 1
+}
+
+// pdfEncode converts text to WinAnsiEncoding bytes. Characters the standard fonts cannot
+// show become '?', except the rupee sign, which is spelled out.
+func pdfEncode(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			b.WriteByte('\n')
+		case r == '\t' || r == '\r':
+			b.WriteByte(' ')
+		case r == '₹':
+			b.WriteString("Rs.")
+		case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		default:
+			if mapped, ok := pdfWinAnsi[r]; ok {
+				b.WriteByte(mapped)
+			} else if r >= 32 {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
+
+func pdfEscape(encoded string) string {
+	var b strings.Builder
+	for i := 0; i < len(encoded); i++ {
+		switch c := encoded[i]; c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// wrapText breaks encoded text into lines no wider than width, splitting words only when a
+// single word does not fit. Text beyond maxLines is cut and marked with an ellipsis.
+func wrapText(text string, font *pdfFont, size, width float64, maxLines int) []string {
+	lines := make([]string, 0, 1)
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if font.width(candidate, size) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			for len(word) > 1 && font.width(word, size) > width {
+				cut := fitPrefix(word, font, size, width)
+				lines = append(lines, word[:cut])
+				word = word[cut:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := lines[maxLines-1] + "..."
+		for len(last) > 3 && font.width(last, size) > width {
+			last = last[:len(last)-4] + "..."
+		}
+		lines[maxLines-1] = last
+	}
+	return lines
+}
+
+// fitPrefix returns how many bytes of word fit in width, always at least one.
+func fitPrefix(word string, font *pdfFont, size, width float64) int {
+	n := 1
+	for n < len(word) && font.width(word[:n+1], size) <= width {
+		n++
+	}
+	return n
+}
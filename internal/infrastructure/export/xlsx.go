@@ -0,0 +1,189 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	xlsxStyleDefault = 0
+	xlsxStyleBold    = 1
+)
+
+// The static parts of a single-sheet workbook. Cells use inline strings, so no shared string
+// table has to be collected before the sheet can be written.
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`},
+}
+
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	columns []Column
+	row     int
+}
+
+func newXLSXWriter(w io.Writer, doc Document) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxStaticParts {
+		if err := writeZipPart(archive, part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeZipPart(archive, "xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`+
+		`<sheets><sheet name="`+xmlEscape(sheetName(doc.Title))+`" sheetId="1" r:id="rId1"/></sheets></workbook>`); err != nil {
+		return nil, err
+	}
+
+	// The worksheet is the last part, so it can stay open while rows stream into it.
+	part, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw := &xlsxWriter{archive: archive, sheet: bufio.NewWriter(part), columns: doc.Columns}
+	xw.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	header := doc.Company.Lines()
+	for _, line := range []string{doc.Title, doc.Subtitle, doc.generatedLine()} {
+		if line != "" {
+			header = append(header, line)
+		}
+	}
+	for i, line := range header {
+		style := xlsxStyleDefault
+		if i == 0 {
+			style = xlsxStyleBold
+		}
+		xw.writeCells([]string{line}, style, false)
+	}
+	if len(header) > 0 {
+		xw.row++
+	}
+	titles := make([]string, len(doc.Columns))
+	for i, column := range doc.Columns {
+		titles[i] = column.Title
+	}
+	xw.writeCells(titles, xlsxStyleBold, false)
+	return xw, nil
+}
+
+func (x *xlsxWriter) WriteRow(cells []string) error {
+	x.writeCells(fitRow(cells, len(x.columns)), xlsxStyleDefault, true)
+	// bufio keeps the first write error, so checking it here stops a failed stream early.
+	_, err := x.sheet.Write(nil)
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	x.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.archive.Close()
+}
+
+func (x *xlsxWriter) writeCells(cells []string, style int, typed bool) {
+	x.row++
+	rowRef := strconv.Itoa(x.row)
+	x.sheet.WriteString(`<row r="` + rowRef + `">`)
+	for i, cell := range cells {
+		if cell == "" {
+			continue
+		}
+		ref := columnName(i) + rowRef
+		styleAttr := ""
+		if style != xlsxStyleDefault {
+			styleAttr = ` s="` + strconv.Itoa(style) + `"`
+		}
+		if typed && i < len(x.columns) && x.columns[i].Numeric && isFiniteNumber(cell) {
+			x.sheet.WriteString(`<c r="` + ref + `"` + styleAttr + `><v>` + cell + `</v></c>`)
+			continue
+		}
+		x.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"` + styleAttr + `><is><t xml:space="preserve">`)
+		x.sheet.WriteString(xmlEscape(cell))
+		x.sheet.WriteString(`</t></is></c>`)
+	}
+	x.sheet.WriteString(`</row>`)
+}
+
+func isFiniteNumber(value string) bool {
+	parsed, err := strconv.ParseFloat(value, 64)
+	return err == nil && !math.IsNaN(parsed) && !math.IsInf(parsed, 0)
+}
+
+func writeZipPart(archive *zip.Writer, name, content string) error {
+	part, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, content)
+	return err
+}
+
+func xmlEscape(value string) string {
+	var b strings.Builder
+	// EscapeText only fails when the builder does, which it cannot.
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+// sheetName applies Excel's limits: at most 31 characters and none of : \ / ? * [ ].
+func sheetName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		return "Sheet1"
+	}
+	return name
+}
+
+// columnName converts a zero-based column to its letters, e.g. 27 to "AB".
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,386 @@
+// Package invoice lays out GST tax invoices as PDF. Everything needed to draw one, fonts
+// included, is compiled in, so invoices render offline and identically on every machine.
+package invoice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+	"masala_inventory_managment/internal/infrastructure/pdf"
+)
+
+// Layout in points on A4 portrait.
+const (
+	margin       = 36.0
+	contentWidth = pdf.A4Width - 2*margin
+	footerY      = 24.0
+	bottomLimit  = 48.0
+	textSize     = 8.5
+	tableSize    = 7.5
+	tableLine    = 9.0
+	cellPadding  = 3.0
+)
+
+type column struct {
+	title   string
+	width   float64 // 0 takes the remaining width
+	numeric bool
+}
+
+// renderer keeps the cursor while pages are filled top to bottom.
+type renderer struct {
+	inv     *domainInvoice.Invoice
+	doc     *pdf.Document
+	page    *pdf.Page
+	y       float64
+	regular *pdf.Font
+	bold    *pdf.Font
+}
+
+// Render draws an invoice. The output depends only on the invoice, so rendering the same
+// invoice twice gives the same bytes.
+func Render(inv *domainInvoice.Invoice) ([]byte, error) {
+	r := &renderer{inv: inv, doc: pdf.New(pdf.A4Width, pdf.A4Height), regular: pdf.Regular(), bold: pdf.Bold()}
+	r.newPage()
+	r.drawParties()
+	r.drawLines()
+	r.drawTotals()
+	r.drawHSNSummary()
+	r.drawClosing()
+	r.drawFooters()
+	return r.doc.Bytes()
+}
+
+func (r *renderer) newPage() {
+	r.page = r.doc.AddPage()
+	r.y = pdf.A4Height - margin
+	title := "TAX INVOICE"
+	if len(r.doc.Pages()) > 1 {
+		title = "TAX INVOICE (continued)"
+	}
+	r.y -= 12
+	r.page.TextCentered(r.bold, 13, pdf.A4Width/2, r.y, title)
+	r.page.TextRight(r.regular, 7, pdf.A4Width-margin, r.y, "Original for Recipient")
+	if len(r.doc.Pages()) > 1 {
+		r.y -= 12
+		r.page.Text(r.regular, textSize, margin, r.y, fmt.Sprintf("Invoice No. %s dated %s", r.inv.InvoiceNumber, r.invoiceDate()))
+	}
+	r.y -= 8
+	r.page.Line(margin, r.y, pdf.A4Width-margin, r.y, 0.8)
+}
+
+// ensure starts a new page when fewer than height points are left above the footer.
+func (r *renderer) ensure(height float64) bool {
+	if r.y-height >= bottomLimit {
+		return false
+	}
+	r.newPage()
+	return true
+}
+
+func (r *renderer) invoiceDate() string {
+	return r.inv.InvoiceDate.Format("02 Jan 2006")
+}
+
+func (r *renderer) drawParties() {
+	top := r.y
+	half := contentWidth/2 - 6
+
+	r.y = top
+	r.y -= 14
+	r.page.Text(r.bold, 11, margin, r.y, r.inv.Seller.Name)
+	r.partyDetails(r.inv.Seller, margin, half)
+	sellerBottom := r.y
+
+	details := [][2]string{
+		{"Invoice No.", r.inv.InvoiceNumber},
+		{"Invoice Date", r.invoiceDate()},
+		{"Dispatch Ref.", r.inv.DispatchReference},
+		{"Place of Supply", stateLabel(r.inv.PlaceOfSupply)},
+		{"Reverse Charge", "No"},
+	}
+	x := margin + contentWidth/2 + 6
+	y := top - 4
+	for _, row := range details {
+		y -= 12
+		r.page.Text(r.regular, textSize, x, y, row[0])
+		r.page.Text(r.bold, textSize, x+70, y, row[1])
+	}
+	r.y = min(sellerBottom, y) - 8
+	r.page.Line(margin, r.y, pdf.A4Width-margin, r.y, 0.5)
+
+	r.y -= 12
+	r.page.Text(r.bold, textSize, margin, r.y, "Bill To")
+	r.y -= 12
+	r.page.Text(r.bold, 10, margin, r.y, r.inv.Buyer.Name)
+	r.partyDetails(r.inv.Buyer, margin, contentWidth)
+	r.y -= 8
+}
+
+func (r *renderer) partyDetails(party domainInvoice.Party, x, width float64) {
+	lines := make([]string, 0, 6)
+	if address := strings.TrimSpace(party.Address); address != "" {
+		lines = append(lines, r.regular.Wrap(address, textSize, width)...)
+	}
+	if phone := strings.TrimSpace(party.Phone); phone != "" {
+		lines = append(lines, "Phone: "+phone)
+	}
+	if party.GSTIN != "" {
+		lines = append(lines, "GSTIN: "+party.GSTIN)
+	} else {
+		lines = append(lines, "GSTIN: Unregistered")
+	}
+	if party.StateCode != "" {
+		lines = append(lines, "State: "+stateLabel(party.StateCode))
+	}
+	for _, line := range lines {
+		r.y -= 10.5
+		r.page.Text(r.regular, textSize, x, r.y, line)
+	}
+}
+
+func (r *renderer) itemColumns() []column {
+	columns := []column{
+		{title: "#", width: 16},
+		{title: "Description"},
+		{title: "HSN", width: 40},
+		{title: "Qty", width: 40, numeric: true},
+		{title: "Unit", width: 26},
+		{title: "Rate", width: 48, numeric: true},
+		{title: "Taxable Value", width: 58, numeric: true},
+		{title: "GST %", width: 28, numeric: true},
+	}
+	if r.inv.SupplyType == domainInvoice.SupplyIntraState {
+		columns = append(columns, column{title: "CGST", width: 46, numeric: true}, column{title: "SGST", width: 46, numeric: true})
+	} else {
+		columns = append(columns, column{title: "IGST", width: 52, numeric: true})
+	}
+	return append(columns, column{title: "Amount", width: 60, numeric: true})
+}
+
+func (r *renderer) drawLines() {
+	columns := r.itemColumns()
+	r.tableHeader(columns)
+	for i, line := range r.inv.Lines {
+		cells := []string{
+			strconv.Itoa(i + 1),
+			strings.TrimSpace(line.SKU + " " + line.Description),
+			line.HSNCode,
+			formatQuantity(line.Quantity),
+			line.Unit,
+			domainInvoice.FormatAmount(line.UnitPrice),
+			domainInvoice.FormatAmount(line.TaxableValue),
+			formatRate(line.GSTRate),
+		}
+		if r.inv.SupplyType == domainInvoice.SupplyIntraState {
+			cells = append(cells, domainInvoice.FormatAmount(line.CGST), domainInvoice.FormatAmount(line.SGST))
+		} else {
+			cells = append(cells, domainInvoice.FormatAmount(line.IGST))
+		}
+		cells = append(cells, domainInvoice.FormatAmount(line.Total))
+
+		wrapped, height := r.layoutRow(columns, cells, r.regular)
+		if r.ensure(height) {
+			r.tableHeader(columns)
+		}
+		r.drawRow(columns, wrapped, height, r.regular)
+		r.page.Line(margin, r.y, pdf.A4Width-margin, r.y, 0.3)
+	}
+}
+
+func (r *renderer) tableHeader(columns []column) {
+	titles := make([]string, len(columns))
+	for i, c := range columns {
+		titles[i] = c.title
+	}
+	wrapped, height := r.layoutRow(columns, titles, r.bold)
+	r.ensure(height + 3*tableLine)
+	r.page.FillRect(margin, r.y-height, contentWidth, height, 0.9)
+	r.drawRow(columns, wrapped, height, r.bold)
+}
+
+func (r *renderer) widths(columns []column) []float64 {
+	fixed := 0.0
+	for _, c := range columns {
+		fixed += c.width
+	}
+	widths := make([]float64, len(columns))
+	for i, c := range columns {
+		widths[i] = c.width
+		if c.width == 0 {
+			widths[i] = contentWidth - fixed
+		}
+	}
+	return widths
+}
+
+func (r *renderer) layoutRow(columns []column, cells []string, font *pdf.Font) ([][]string, float64) {
+	widths := r.widths(columns)
+	wrapped := make([][]string, len(cells))
+	lines := 1
+	for i, cell := range cells {
+		wrapped[i] = font.Wrap(cell, tableSize, widths[i]-2*cellPadding)
+		lines = max(lines, len(wrapped[i]))
+	}
+	return wrapped, float64(lines)*tableLine + 2*cellPadding
+}
+
+func (r *renderer) drawRow(columns []column, wrapped [][]string, height float64, font *pdf.Font) {
+	widths := r.widths(columns)
+	x := margin
+	for i, lines := range wrapped {
+		for j, line := range lines {
+			baseline := r.y - cellPadding - float64(j+1)*tableLine + 2
+			if columns[i].numeric {
+				r.page.TextRight(font, tableSize, x+widths[i]-cellPadding, baseline, line)
+			} else {
+				r.page.Text(font, tableSize, x+cellPadding, baseline, line)
+			}
+		}
+		x += widths[i]
+	}
+	r.y -= height
+}
+
+func (r *renderer) drawTotals() {
+	rows := [][2]string{{"Taxable Value", domainInvoice.FormatAmount(r.inv.TaxableValue)}}
+	if r.inv.SupplyType == domainInvoice.SupplyIntraState {
+		rows = append(rows, [2]string{"CGST", domainInvoice.FormatAmount(r.inv.CGST)}, [2]string{"SGST", domainInvoice.FormatAmount(r.inv.SGST)})
+	} else {
+		rows = append(rows, [2]string{"IGST", domainInvoice.FormatAmount(r.inv.IGST)})
+	}
+	rows = append(rows, [2]string{"Round Off", domainInvoice.FormatAmount(r.inv.RoundOff)})
+
+	r.ensure(float64(len(rows)+1)*12 + 10)
+	right := pdf.A4Width - margin - cellPadding
+	labelX := right - 190
+	r.y -= 4
+	for _, row := range rows {
+		r.y -= 12
+		r.page.Text(r.regular, textSize, labelX, r.y, row[0])
+		r.page.TextRight(r.regular, textSize, right, r.y, row[1])
+	}
+	r.y -= 6
+	r.page.Line(labelX, r.y, pdf.A4Width-margin, r.y, 0.5)
+	r.y -= 13
+	r.page.Text(r.bold, 10, labelX, r.y, "Grand Total")
+	r.page.TextRight(r.bold, 10, right, r.y, "Rs. "+domainInvoice.FormatAmount(r.inv.GrandTotal))
+	r.y -= 6
+	r.page.Line(margin, r.y, pdf.A4Width-margin, r.y, 0.5)
+}
+
+type hsnTotal struct {
+	code    string
+	rate    float64
+	taxable float64
+	cgst    float64
+	sgst    float64
+	igst    float64
+}
+
+// drawHSNSummary totals taxable value and tax per HSN code and rate, as GST returns report them.
+func (r *renderer) drawHSNSummary() {
+	totals := make([]*hsnTotal, 0, len(r.inv.Lines))
+	index := make(map[string]*hsnTotal)
+	for _, line := range r.inv.Lines {
+		key := line.HSNCode + "|" + formatRate(line.GSTRate)
+		total, ok := index[key]
+		if !ok {
+			total = &hsnTotal{code: line.HSNCode, rate: line.GSTRate}
+			index[key] = total
+			totals = append(totals, total)
+		}
+		total.taxable += line.TaxableValue
+		total.cgst += line.CGST
+		total.sgst += line.SGST
+		total.igst += line.IGST
+	}
+
+	columns := []column{{title: "HSN"}, {title: "Taxable Value", width: 80, numeric: true}, {title: "GST %", width: 40, numeric: true}}
+	if r.inv.SupplyType == domainInvoice.SupplyIntraState {
+		columns = append(columns, column{title: "CGST", width: 70, numeric: true}, column{title: "SGST", width: 70, numeric: true})
+	} else {
+		columns = append(columns, column{title: "IGST", width: 80, numeric: true})
+	}
+	columns = append(columns, column{title: "Total Tax", width: 80, numeric: true})
+
+	r.ensure(float64(len(totals)+2)*(tableLine+2*cellPadding) + 16)
+	r.y -= 14
+	r.page.Text(r.bold, textSize, margin, r.y, "HSN Summary")
+	r.y -= 4
+	r.tableHeader(columns)
+	for _, total := range totals {
+		cells := []string{total.code, domainInvoice.FormatAmount(total.taxable), formatRate(total.rate)}
+		if r.inv.SupplyType == domainInvoice.SupplyIntraState {
+			cells = append(cells, domainInvoice.FormatAmount(total.cgst), domainInvoice.FormatAmount(total.sgst))
+		} else {
+			cells = append(cells, domainInvoice.FormatAmount(total.igst))
+		}
+		cells = append(cells, domainInvoice.FormatAmount(total.cgst+total.sgst+total.igst))
+		wrapped, height := r.layoutRow(columns, cells, r.regular)
+		if r.ensure(height) {
+			r.tableHeader(columns)
+		}
+		r.drawRow(columns, wrapped, height, r.regular)
+		r.page.Line(margin, r.y, pdf.A4Width-margin, r.y, 0.3)
+	}
+}
+
+func (r *renderer) drawClosing() {
+	words := r.regular.Wrap(r.inv.AmountInWords, textSize, contentWidth-90)
+	notes := []string(nil)
+	if strings.TrimSpace(r.inv.Notes) != "" {
+		notes = r.regular.Wrap(r.inv.Notes, textSize, contentWidth-90)
+	}
+	r.ensure(float64(len(words)+len(notes))*10.5 + 80)
+
+	r.y -= 16
+	r.page.Text(r.bold, textSize, margin, r.y, "Amount in words")
+	for i, line := range words {
+		if i > 0 {
+			r.y -= 10.5
+		}
+		r.page.Text(r.regular, textSize, margin+90, r.y, line)
+	}
+	if len(notes) > 0 {
+		r.y -= 14
+		r.page.Text(r.bold, textSize, margin, r.y, "Notes")
+		for i, line := range notes {
+			if i > 0 {
+				r.y -= 10.5
+			}
+			r.page.Text(r.regular, textSize, margin+90, r.y, line)
+		}
+	}
+
+	right := pdf.A4Width - margin
+	r.y -= 24
+	r.page.TextRight(r.bold, textSize, right, r.y, "For "+r.inv.Seller.Name)
+	r.y -= 34
+	r.page.TextRight(r.regular, textSize, right, r.y, "Authorised Signatory")
+}
+
+func (r *renderer) drawFooters() {
+	pages := r.doc.Pages()
+	for i, page := range pages {
+		page.Text(r.regular, 7, margin, footerY, "This is a computer generated invoice.")
+		page.TextRight(r.regular, 7, pdf.A4Width-margin, footerY, fmt.Sprintf("%s - Page %d of %d", r.inv.InvoiceNumber, i+1, len(pages)))
+	}
+}
+
+func stateLabel(code string) string {
+	if name := domainInvoice.StateName(code); name != "" {
+		return fmt.Sprintf("%s (%s)", name, code)
+	}
+	return code
+}
+
+func formatQuantity(quantity float64) string {
+	return strconv.FormatFloat(quantity, 'f', -1, 64)
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
@@ -0,0 +1,70 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	domainInvoice "masala_inventory_managment/internal/domain/invoice"
+)
+
+func testInvoice(lines int, supplyType string) *domainInvoice.Invoice {
+	inv := &domainInvoice.Invoice{
+		InvoiceNumber:     "INV/2026-27/0042",
+		FinancialYear:     "2026-27",
+		Sequence:          42,
+		InvoiceDate:       time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		DispatchReference: "DSP-1001",
+		Seller:            domainInvoice.Party{Name: "Shree Masala Works", Address: "Plot 4, MIDC, Nashik", GSTIN: "27ABCDE1234F1Z5", StateCode: "27"},
+		Buyer:             domainInvoice.Party{Name: "Spice Mart", Address: "12 Market Road\nIndore", StateCode: "23"},
+		PlaceOfSupply:     "23",
+		SupplyType:        supplyType,
+		Notes:             "Goods once sold will not be taken back.",
+	}
+	for i := 0; i < lines; i++ {
+		inv.Lines = append(inv.Lines, domainInvoice.Line{
+			ItemID: int64(i + 1), SKU: fmt.Sprintf("GM-%03d", i), Description: "Garam masala – 100 g retail pouch",
+			HSNCode: "0910", Quantity: 12.5, Unit: "kg", UnitPrice: 420, GSTRate: 5,
+		})
+	}
+	inv.Calculate()
+	return inv
+}
+
+func TestRender_PaginatesLongInvoicesWithEmbeddedFonts(t *testing.T) {
+	content, err := Render(testInvoice(80, domainInvoice.SupplyInterState))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(content, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	count := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(content)
+	if count == nil {
+		t.Fatalf("missing page tree")
+	}
+	if pages, _ := strconv.Atoi(string(count[1])); pages < 2 {
+		t.Fatalf("expected 80 lines to span several pages, got %d", pages)
+	}
+	if bytes.Count(content, []byte("/FontFile2")) != 2 || bytes.Contains(content, []byte("/Subtype /Type1")) {
+		t.Fatalf("expected only embedded TrueType fonts")
+	}
+}
+
+func TestRender_IsReproducible(t *testing.T) {
+	inv := testInvoice(3, domainInvoice.SupplyIntraState)
+	first, err := Render(inv)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	second, err := Render(inv)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("expected rendering the same invoice twice to give the same bytes")
+	}
+}
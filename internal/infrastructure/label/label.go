@@ -0,0 +1,338 @@
+// Package label renders lot and pack labels with a Code 128 or QR code for thermal label
+// printers, as a PDF with one page per label or as a PNG of a single label.
+package label
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"masala_inventory_managment/internal/infrastructure/barcode"
+	"masala_inventory_managment/internal/infrastructure/pdf"
+)
+
+const (
+	SymbologyCode128 = "code128"
+	SymbologyQR      = "qr"
+
+	FormatPDF = "pdf"
+	FormatPNG = "png"
+
+	// DefaultDPI is the resolution of most desktop thermal printers. PDF bars are snapped
+	// to its dots so they print with even widths.
+	DefaultDPI = 203
+)
+
+var (
+	ErrUnsupportedSymbology = errors.New("symbology must be code128 or qr")
+	ErrUnsupportedFormat    = errors.New("label format must be pdf or png")
+	ErrUnsupportedSize      = errors.New("label size is not supported")
+	ErrUnsupportedDPI       = errors.New("dpi must be 203 or 300")
+	ErrPNGSingleLabel       = errors.New("png renders a single label; use pdf for several")
+	ErrCodeTooLong          = errors.New("code is too long to print on this label size")
+)
+
+// Size is a label stock, in millimetres as printed on the roll.
+type Size struct {
+	Name   string
+	Width  float64
+	Height float64
+	// text is the body text size in points.
+	text float64
+}
+
+// Sizes are the common thermal label stocks, smallest first.
+var Sizes = []Size{
+	{Name: "50x25", Width: 50, Height: 25, text: 5.5},
+	{Name: "75x50", Width: 75, Height: 50, text: 8},
+	{Name: "100x50", Width: 100, Height: 50, text: 9},
+	{Name: "100x150", Width: 100, Height: 150, text: 14},
+}
+
+// DefaultSize suits a 4-inch printer and a sack.
+const DefaultSize = "100x50"
+
+// ParseSize returns the named stock; an empty name selects DefaultSize.
+func ParseSize(name string) (Size, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultSize
+	}
+	for _, size := range Sizes {
+		if size.Name == name {
+			return size, nil
+		}
+	}
+	return Size{}, ErrUnsupportedSize
+}
+
+// Label is what one label shows. Code is encoded in the symbol and printed beneath it; the
+// lookup endpoint resolves it back to the lot.
+type Label struct {
+	Code       string
+	Item       string
+	Quantity   string
+	GRN        string
+	Received   time.Time
+	BestBefore time.Time
+}
+
+// Options choose how labels are rendered. Empty fields take their defaults: Code 128, PDF,
+// DefaultSize and DefaultDPI.
+type Options struct {
+	Symbology string
+	Format    string
+	Size      string
+	DPI       int
+}
+
+// Normalize fills in defaults and validates the options.
+func (o Options) Normalize() (Options, error) {
+	o.Symbology = strings.ToLower(strings.TrimSpace(o.Symbology))
+	if o.Symbology == "" {
+		o.Symbology = SymbologyCode128
+	}
+	if o.Symbology != SymbologyCode128 && o.Symbology != SymbologyQR {
+		return o, ErrUnsupportedSymbology
+	}
+	o.Format = strings.ToLower(strings.TrimSpace(o.Format))
+	if o.Format == "" {
+		o.Format = FormatPDF
+	}
+	if o.Format != FormatPDF && o.Format != FormatPNG {
+		return o, ErrUnsupportedFormat
+	}
+	size, err := ParseSize(o.Size)
+	if err != nil {
+		return o, err
+	}
+	o.Size = size.Name
+	if o.DPI == 0 {
+		o.DPI = DefaultDPI
+	}
+	if o.DPI != 203 && o.DPI != 300 {
+		return o, ErrUnsupportedDPI
+	}
+	return o, nil
+}
+
+// ContentType is the MIME type of the rendered format.
+func (o Options) ContentType() string {
+	if o.Format == FormatPNG {
+		return "image/png"
+	}
+	return "application/pdf"
+}
+
+// Render draws labels with normalized options.
+func Render(labels []Label, opts Options) ([]byte, error) {
+	opts, err := opts.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, errors.New("at least one label is required")
+	}
+	size, _ := ParseSize(opts.Size)
+	width, height := size.Width*pdf.MM, size.Height*pdf.MM
+
+	if opts.Format == FormatPNG {
+		if len(labels) != 1 {
+			return nil, ErrPNGSingleLabel
+		}
+		c := newPNGCanvas(width, height, opts.DPI)
+		if err := draw(c, labels[0], size, opts.Symbology, 72/float64(opts.DPI)); err != nil {
+			return nil, err
+		}
+		return c.bytes()
+	}
+
+	doc := pdf.New(width, height)
+	for _, l := range labels {
+		c := &pdfCanvas{page: doc.AddPage(), height: height}
+		if err := draw(c, l, size, opts.Symbology, 72/float64(opts.DPI)); err != nil {
+			return nil, err
+		}
+	}
+	return doc.Bytes()
+}
+
+// canvas is a drawing surface in points from the top-left corner of the label.
+type canvas interface {
+	fill(x, y, w, h float64)
+	text(bold bool, size, x, baseline float64, s string)
+}
+
+// draw lays out one label. dot is the printer dot in points; bars and QR modules are whole
+// dots wide.
+func draw(c canvas, l Label, size Size, symbology string, dot float64) error {
+	width, height := size.Width*pdf.MM, size.Height*pdf.MM
+	margin := math.Max(1.5*pdf.MM, size.Height*pdf.MM*0.04)
+	body := size.text
+
+	details := detailLines(l)
+	if symbology == SymbologyQR {
+		return drawQR(c, l, details, width, height, margin, body, dot)
+	}
+
+	modules, err := barcode.Code128(l.Code)
+	if err != nil {
+		return err
+	}
+	title := body * 1.2
+	codeSize := body * 1.3
+	y := margin + title
+	c.text(true, title, margin, y, truncate(pdf.Bold(), l.Item, title, width-2*margin))
+	y += title * 0.4
+
+	// Ten modules of quiet zone either side, inside the margins.
+	module := math.Floor((width-2*margin)/float64(len(modules)+20)/dot) * dot
+	if module < dot {
+		return ErrCodeTooLong
+	}
+	bottom := codeSize*1.3 + float64(len(details))*body*1.25 + margin
+	// Tall stock leaves room below rather than stretching the bars.
+	barHeight := math.Min(height-bottom-y, width/2)
+	left := (width - module*float64(len(modules))) / 2
+	fillRuns(modules, func(from, to int) {
+		c.fill(left+float64(from)*module, y, float64(to-from)*module, barHeight)
+	})
+	y += barHeight + codeSize*1.15
+
+	codeFont := fitSize(pdf.Bold(), l.Code, codeSize, width-2*margin)
+	c.text(true, codeFont, (width-pdf.Bold().Width(l.Code, codeFont))/2, y, l.Code)
+	for _, line := range details {
+		y += body * 1.25
+		c.text(false, body, margin, y, truncate(pdf.Regular(), line, body, width-2*margin))
+	}
+	return nil
+}
+
+// drawQR puts the symbol beside the text on landscape stock and above it on portrait stock.
+func drawQR(c canvas, l Label, details []string, width, height, margin, body, dot float64) error {
+	code, err := barcode.QR([]byte(l.Code))
+	if err != nil {
+		return err
+	}
+	// Four modules of quiet zone all round, which may overlap the margin.
+	portrait := height > width
+	room := height - 2*margin
+	if portrait {
+		room = width - 2*margin
+	}
+	module := math.Floor(room/float64(code.Size+8)/dot) * dot
+	if module < dot {
+		return ErrCodeTooLong
+	}
+	side := module * float64(code.Size)
+
+	x0, y0 := margin+4*module, (height-side)/2
+	textLeft, textTop, textWidth := x0+side+4*module, margin, width-(x0+side+4*module)-margin
+	if portrait {
+		x0, y0 = (width-side)/2, margin+4*module
+		textLeft, textTop, textWidth = margin, y0+side+4*module, width-2*margin
+	}
+	for y := 0; y < code.Size; y++ {
+		run := make([]bool, code.Size)
+		for x := range run {
+			run[x] = code.Dark(x, y)
+		}
+		fillRuns(run, func(from, to int) {
+			c.fill(x0+float64(from)*module, y0+float64(y)*module, float64(to-from)*module, module)
+		})
+	}
+
+	title := body * 1.2
+	y := textTop + title
+	for i, line := range pdf.Bold().Wrap(l.Item, title, textWidth) {
+		if i == 2 {
+			break
+		}
+		c.text(true, title, textLeft, y, truncate(pdf.Bold(), line, title, textWidth))
+		y += title * 1.2
+	}
+	codeSize := fitSize(pdf.Bold(), l.Code, body*1.3, textWidth)
+	y += codeSize * 0.2
+	c.text(true, codeSize, textLeft, y, l.Code)
+	for _, line := range details {
+		y += body * 1.3
+		c.text(false, body, textLeft, y, truncate(pdf.Regular(), line, body, textWidth))
+	}
+	return nil
+}
+
+func detailLines(l Label) []string {
+	var lines []string
+	first := make([]string, 0, 2)
+	if l.Quantity != "" {
+		first = append(first, "Qty "+l.Quantity)
+	}
+	if l.GRN != "" {
+		first = append(first, "GRN "+l.GRN)
+	}
+	if len(first) > 0 {
+		lines = append(lines, strings.Join(first, "   "))
+	}
+	if !l.Received.IsZero() {
+		lines = append(lines, "Received "+l.Received.Format("02 Jan 2006"))
+	}
+	if !l.BestBefore.IsZero() {
+		lines = append(lines, "Best before "+l.BestBefore.Format("02 Jan 2006"))
+	}
+	return lines
+}
+
+// fillRuns calls fill once for every run of dark modules.
+func fillRuns(modules []bool, fill func(from, to int)) {
+	for i := 0; i < len(modules); {
+		if !modules[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(modules) && modules[j] {
+			j++
+		}
+		fill(i, j)
+		i = j
+	}
+}
+
+// truncate shortens text with an ellipsis so it fits width.
+func truncate(font *pdf.Font, text string, size, width float64) string {
+	if font.Width(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && font.Width(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimRight(string(runes), " ") + "..."
+}
+
+// fitSize shrinks the text size until text fits width. Codes are never truncated, since the
+// printed code must match the symbol.
+func fitSize(font *pdf.Font, text string, size, width float64) float64 {
+	if w := font.Width(text, size); w > width && w > 0 {
+		return size * width / w
+	}
+	return size
+}
+
+type pdfCanvas struct {
+	page   *pdf.Page
+	height float64
+}
+
+func (c *pdfCanvas) fill(x, y, w, h float64) {
+	c.page.FillRect(x, c.height-y-h, w, h, 0)
+}
+
+func (c *pdfCanvas) text(bold bool, size, x, baseline float64, s string) {
+	font := pdf.Regular()
+	if bold {
+		font = pdf.Bold()
+	}
+	c.page.Text(font, size, x, c.height-baseline, s)
+}
@@ -0,0 +1,95 @@
+package label
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func testLabel() Label {
+	return Label{
+		Code:     "LOT-20260101-001",
+		Item:     "CUM-1 Cumin seeds, whole",
+		Quantity: "25 kg",
+		GRN:      "GRN-5101",
+		Received: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRender_PDFHasOnePageOfTheStockSizePerLabel(t *testing.T) {
+	for _, symbology := range []string{SymbologyCode128, SymbologyQR} {
+		content, err := Render([]Label{testLabel(), testLabel(), testLabel()}, Options{Symbology: symbology, Size: "50x25"})
+		if err != nil {
+			t.Fatalf("Render(%s) failed: %v", symbology, err)
+		}
+		if count := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(content); count == nil || string(count[1]) != "3" {
+			t.Fatalf("expected three pages, got %q", count)
+		}
+		if !bytes.Contains(content, []byte("/MediaBox [0 0 141.73 70.87]")) {
+			t.Fatalf("expected 50 x 25 mm pages")
+		}
+	}
+}
+
+func TestRender_PNGIsOneDotPerPixelWithWholeDotBars(t *testing.T) {
+	content, err := Render([]Label{testLabel()}, Options{Format: FormatPNG, Size: "50x25"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("not a PNG: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 400, 200) {
+		t.Fatalf("expected 50 x 25 mm at 203 dpi, got %v", img.Bounds())
+	}
+
+	// Scan a row through the bars: every bar and space is a whole number of modules.
+	gray := img.(*image.Gray)
+	row := 70
+	var runs []int
+	start, dark := -1, false
+	for x := 0; x < 400; x++ {
+		d := gray.GrayAt(x, row).Y < 128
+		if start < 0 && d {
+			start, dark = x, true
+			continue
+		}
+		if start >= 0 && d != dark {
+			runs = append(runs, x-start)
+			start, dark = x, d
+		}
+	}
+	if len(runs) < 20 {
+		t.Fatalf("expected to cross the bars on row %d, got %v", row, runs)
+	}
+	module := runs[0] / 2 // every symbol starts with a two-module bar
+	for _, run := range runs {
+		if module == 0 || run%module != 0 {
+			t.Fatalf("expected runs in whole %d-dot modules, got %v", module, runs)
+		}
+	}
+}
+
+func TestRender_RejectsUnsupportedRequests(t *testing.T) {
+	cases := []struct {
+		labels []Label
+		opts   Options
+		want   error
+	}{
+		{[]Label{testLabel()}, Options{Symbology: "ean13"}, ErrUnsupportedSymbology},
+		{[]Label{testLabel()}, Options{Format: "svg"}, ErrUnsupportedFormat},
+		{[]Label{testLabel()}, Options{Size: "4x6"}, ErrUnsupportedSize},
+		{[]Label{testLabel()}, Options{DPI: 600}, ErrUnsupportedDPI},
+		{[]Label{testLabel(), testLabel()}, Options{Format: FormatPNG}, ErrPNGSingleLabel},
+	}
+	for _, tc := range cases {
+		if _, err := Render(tc.labels, tc.opts); !errors.Is(err, tc.want) {
+			t.Fatalf("expected %v for %+v, got %v", tc.want, tc.opts, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package label
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// pngCanvas draws in printer dots, so one dot of the label is one pixel of the image.
+type pngCanvas struct {
+	img   *image.Gray
+	scale float64
+	dpi   float64
+	faces map[faceKey]font.Face
+}
+
+type faceKey struct {
+	bold bool
+	size float64
+}
+
+func newPNGCanvas(width, height float64, dpi int) *pngCanvas {
+	scale := float64(dpi) / 72
+	img := image.NewGray(image.Rect(0, 0, int(math.Round(width*scale)), int(math.Round(height*scale))))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	return &pngCanvas{img: img, scale: scale, dpi: float64(dpi), faces: make(map[faceKey]font.Face)}
+}
+
+func (c *pngCanvas) fill(x, y, w, h float64) {
+	r := image.Rect(
+		int(math.Round(x*c.scale)), int(math.Round(y*c.scale)),
+		int(math.Round((x+w)*c.scale)), int(math.Round((y+h)*c.scale)),
+	).Intersect(c.img.Bounds())
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			c.img.SetGray(px, py, color.Gray{})
+		}
+	}
+}
+
+func (c *pngCanvas) text(bold bool, size, x, baseline float64, s string) {
+	if s == "" {
+		return
+	}
+	face, err := c.face(bold, size)
+	if err != nil {
+		return
+	}
+	d := font.Drawer{
+		Dst:  c.img,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(x * c.scale * 64), Y: fixed.Int26_6(baseline * c.scale * 64)},
+	}
+	d.DrawString(s)
+}
+
+func (c *pngCanvas) face(bold bool, size float64) (font.Face, error) {
+	key := faceKey{bold: bold, size: size}
+	if face, ok := c.faces[key]; ok {
+		return face, nil
+	}
+	data := goregular.TTF
+	if bold {
+		data = gobold.TTF
+	}
+	parsed, err := opentype.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: c.dpi, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	c.faces[key] = face
+	return face, nil
+}
+
+func (c *pngCanvas) bytes() ([]byte, error) {
+	var out bytes.Buffer
+	if err := png.Encode(&out, c.img); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
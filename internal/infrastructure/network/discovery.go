@@ -0,0 +1,184 @@
+package network
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDiscoveryPort is the UDP port the server listens on for discovery probes.
+	DefaultDiscoveryPort = 8091
+
+	DiscoveryService  = "masala-inventory"
+	ServerIDFileName  = "server_id"
+	discoveryProbe    = "MASALA_DISCOVER/1"
+	maxDiscoveryReply = 2048
+)
+
+// ServerAnnouncement is a server's reply to a discovery probe. Address is filled in by the
+// client from the reply's source IP and APIPort, so it follows DHCP address changes.
+type ServerAnnouncement struct {
+	Service     string `json:"service"`
+	ServerID    string `json:"server_id"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	APIPort     int    `json:"api_port"`
+	Fingerprint string `json:"fingerprint"`
+	Address     string `json:"-"`
+}
+
+// LoadOrCreateServerID returns the server's stable identity, stored next to the database.
+// Clients remember this rather than an IP address.
+func LoadOrCreateServerID(dir string) (string, error) {
+	path := filepath.Join(dir, ServerIDFileName)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(raw)
+	if err := writeFileAtomic(path, []byte(id+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// StartDiscoveryResponder answers discovery probes on the given UDP port with the current
+// announcement. announce is called per probe so a rotated certificate is reported immediately.
+func StartDiscoveryResponder(port int, announce func() ServerAnnouncement) (func(), error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for discovery probes: %w", err)
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				continue
+			}
+			if strings.TrimSpace(string(buf[:n])) != discoveryProbe {
+				continue
+			}
+			reply := announce()
+			reply.Service = DiscoveryService
+			payload, err := json.Marshal(reply)
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteToUDP(payload, from)
+		}
+	}()
+
+	return func() { _ = conn.Close() }, nil
+}
+
+// Discover broadcasts a probe on every IPv4 interface and collects replies until timeout.
+func Discover(port int, timeout time.Duration) ([]ServerAnnouncement, error) {
+	return DiscoverAt(broadcastTargets(port), timeout)
+}
+
+// DiscoverAt sends a probe to each target address and returns one announcement per server ID.
+func DiscoverAt(targets []string, timeout time.Duration) ([]ServerAnnouncement, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open discovery socket: %w", err)
+	}
+	defer conn.Close()
+
+	sent := 0
+	for _, target := range targets {
+		addr, err := net.ResolveUDPAddr("udp4", target)
+		if err != nil {
+			continue
+		}
+		if _, err := conn.WriteToUDP([]byte(discoveryProbe), addr); err == nil {
+			sent++
+		}
+	}
+	if sent == 0 {
+		return nil, fmt.Errorf("failed to send discovery probe")
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	found := make([]ServerAnnouncement, 0)
+	seen := map[string]bool{}
+	buf := make([]byte, maxDiscoveryReply)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return found, nil
+			}
+			return found, err
+		}
+		var announcement ServerAnnouncement
+		if err := json.Unmarshal(buf[:n], &announcement); err != nil || announcement.Service != DiscoveryService {
+			continue
+		}
+		if announcement.ServerID == "" || seen[announcement.ServerID] || announcement.APIPort <= 0 {
+			continue
+		}
+		seen[announcement.ServerID] = true
+		announcement.Address = net.JoinHostPort(from.IP.String(), strconv.Itoa(announcement.APIPort))
+		found = append(found, announcement)
+	}
+}
+
+func broadcastTargets(port int) []string {
+	portText := strconv.Itoa(port)
+	targets := []string{net.JoinHostPort("255.255.255.255", portText)}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return targets
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip := ipNet.IP.To4()
+			if ip == nil || ip.IsLoopback() {
+				continue
+			}
+			broadcast := make(net.IP, len(ip))
+			for i := range ip {
+				broadcast[i] = ip[i] | ^ipNet.Mask[len(ipNet.Mask)-len(ip)+i]
+			}
+			targets = append(targets, net.JoinHostPort(broadcast.String(), portText))
+		}
+	}
+	return targets
+}
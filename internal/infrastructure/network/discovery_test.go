@@ -0,0 +1,58 @@
+package network
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("skipping UDP test in restricted runtime: %v", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestDiscovery_ResponderAnswersProbe(t *testing.T) {
+	port := freeUDPPort(t)
+	stop, err := StartDiscoveryResponder(port, func() ServerAnnouncement {
+		return ServerAnnouncement{ServerID: "server-1", Name: "godown-pc", Version: "1.2.0", APIPort: 8090, Fingerprint: "AB"}
+	})
+	if err != nil {
+		t.Fatalf("StartDiscoveryResponder failed: %v", err)
+	}
+	defer stop()
+
+	target := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	// Probe twice to check replies are de-duplicated by server ID.
+	found, err := DiscoverAt([]string{target, target}, 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("DiscoverAt failed: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected one server, got %+v", found)
+	}
+	server := found[0]
+	if server.ServerID != "server-1" || server.Name != "godown-pc" || server.Version != "1.2.0" {
+		t.Fatalf("unexpected announcement: %+v", server)
+	}
+	if server.Address != "127.0.0.1:8090" {
+		t.Fatalf("expected address from reply source and API port, got %q", server.Address)
+	}
+}
+
+func TestLoadOrCreateServerID_IsStable(t *testing.T) {
+	dir := t.TempDir()
+	first, err := LoadOrCreateServerID(dir)
+	if err != nil || first == "" {
+		t.Fatalf("LoadOrCreateServerID failed: %q %v", first, err)
+	}
+	second, err := LoadOrCreateServerID(dir)
+	if err != nil || second != first {
+		t.Fatalf("expected stable server id, got %q then %q (%v)", first, second, err)
+	}
+}
@@ -0,0 +1,201 @@
+package network
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CertFileName = "server_tls.crt"
+	KeyFileName  = "server_tls.key"
+
+	certValidity   = 10 * 365 * 24 * time.Hour
+	certCommonName = "Masala Inventory Server"
+)
+
+// CertificateManager owns the server's self-signed TLS certificate. The key pair lives next to
+// the database so it survives restarts and travels with the data directory; clients pin its
+// SHA-256 fingerprint instead of relying on a CA.
+type CertificateManager struct {
+	certPath string
+	keyPath  string
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	fingerprint string
+}
+
+// NewCertificateManager stores the certificate and key in dir.
+func NewCertificateManager(dir string) *CertificateManager {
+	return &CertificateManager{
+		certPath: filepath.Join(dir, CertFileName),
+		keyPath:  filepath.Join(dir, KeyFileName),
+	}
+}
+
+// EnsureCertificate loads the stored key pair, generating one on first start.
+// An expired certificate is replaced; clients will need to re-pair.
+func (m *CertificateManager) EnsureCertificate() error {
+	cert, err := tls.LoadX509KeyPair(m.certPath, m.keyPath)
+	if err == nil {
+		leaf, parseErr := x509.ParseCertificate(cert.Certificate[0])
+		if parseErr == nil && time.Now().Before(leaf.NotAfter) {
+			m.install(&cert)
+			return nil
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	_, err = m.Rotate()
+	return err
+}
+
+// Rotate generates a fresh key pair, persists it and starts serving it immediately.
+// It returns the new fingerprint.
+func (m *CertificateManager) Rotate() (string, error) {
+	certPEM, keyPEM, err := generateSelfSignedCertificate()
+	if err != nil {
+		return "", err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated certificate: %w", err)
+	}
+	if err := writeFileAtomic(m.keyPath, keyPEM, 0600); err != nil {
+		return "", fmt.Errorf("failed to write server key: %w", err)
+	}
+	if err := writeFileAtomic(m.certPath, certPEM, 0644); err != nil {
+		return "", fmt.Errorf("failed to write server certificate: %w", err)
+	}
+	m.install(&cert)
+	return m.Fingerprint(), nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the certificate currently served.
+func (m *CertificateManager) Fingerprint() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fingerprint
+}
+
+// TLSConfig serves whichever certificate is current, so Rotate takes effect without a restart.
+func (m *CertificateManager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+			if m.cert == nil {
+				return nil, fmt.Errorf("server certificate is not loaded")
+			}
+			return m.cert, nil
+		},
+	}
+}
+
+func (m *CertificateManager) install(cert *tls.Certificate) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cert = cert
+	m.fingerprint = Fingerprint(cert.Certificate[0])
+}
+
+// Fingerprint formats the SHA-256 of a DER certificate as colon-separated upper-case hex,
+// the form shown by browsers and openssl.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// NormalizeFingerprint accepts fingerprints typed with or without separators and in any case.
+func NormalizeFingerprint(raw string) string {
+	cleaned := strings.ToUpper(strings.NewReplacer(":", "", " ", "", "-", "").Replace(strings.TrimSpace(raw)))
+	if len(cleaned) != sha256.Size*2 {
+		return ""
+	}
+	parts := make([]string, 0, sha256.Size)
+	for i := 0; i < len(cleaned); i += 2 {
+		pair := cleaned[i : i+2]
+		if strings.Trim(pair, "0123456789ABCDEF") != "" {
+			return ""
+		}
+		parts = append(parts, pair)
+	}
+	return strings.Join(parts, ":")
+}
+
+func generateSelfSignedCertificate() ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate server key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate certificate serial: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: certCommonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+				template.IPAddresses = append(template.IPAddresses, ipNet.IP)
+			}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create server certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode server key: %w", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
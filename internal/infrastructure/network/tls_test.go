@@ -0,0 +1,111 @@
+package network
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCertificateManager_PersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+
+	first := NewCertificateManager(dir)
+	if err := first.EnsureCertificate(); err != nil {
+		t.Fatalf("EnsureCertificate failed: %v", err)
+	}
+	if NormalizeFingerprint(first.Fingerprint()) == "" {
+		t.Fatalf("unexpected fingerprint format: %q", first.Fingerprint())
+	}
+
+	second := NewCertificateManager(dir)
+	if err := second.EnsureCertificate(); err != nil {
+		t.Fatalf("EnsureCertificate on reload failed: %v", err)
+	}
+	if second.Fingerprint() != first.Fingerprint() {
+		t.Fatalf("expected stored certificate to be reused, got %s then %s", first.Fingerprint(), second.Fingerprint())
+	}
+
+	rotated, err := second.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if rotated == first.Fingerprint() {
+		t.Fatalf("expected rotation to change the fingerprint")
+	}
+
+	third := NewCertificateManager(dir)
+	if err := third.EnsureCertificate(); err != nil {
+		t.Fatalf("EnsureCertificate after rotate failed: %v", err)
+	}
+	if third.Fingerprint() != rotated {
+		t.Fatalf("expected rotated certificate to be persisted")
+	}
+}
+
+func TestPinStore_TrustsFirstCertificateAndRejectsChanges(t *testing.T) {
+	certs := NewCertificateManager(t.TempDir())
+	if err := certs.EnsureCertificate(); err != nil {
+		t.Fatalf("EnsureCertificate failed: %v", err)
+	}
+
+	// httptest.StartTLS would install its own certificate, so serve the manager's listener directly.
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.Listener = tls.NewListener(server.Listener, certs.TLSConfig())
+	server.Start()
+	defer server.Close()
+	server.URL = strings.Replace(server.URL, "http://", "https://", 1)
+
+	parsed, _ := url.Parse(server.URL)
+	pins := NewPinStore(filepath.Join(t.TempDir(), "pins.json"))
+	newClient := func() *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: pins.ClientTLSConfig(parsed.Host)}}
+	}
+
+	resp, err := newClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("first connection failed: %v", err)
+	}
+	resp.Body.Close()
+	if pinned, _ := pins.Get(parsed.Host); pinned != certs.Fingerprint() {
+		t.Fatalf("expected first certificate to be pinned, got %q", pinned)
+	}
+
+	if _, err := certs.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	_, err = newClient().Get(server.URL)
+	var mismatch *FingerprintMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrFingerprintMismatch) {
+		t.Fatalf("expected fingerprint mismatch after rotation, got %v", err)
+	}
+	if mismatch.Actual != certs.Fingerprint() {
+		t.Fatalf("expected mismatch to report the presented fingerprint, got %+v", mismatch)
+	}
+
+	if err := pins.Set(parsed.Host, certs.Fingerprint()); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	resp, err = newClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected re-paired connection to succeed, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNormalizeFingerprint(t *testing.T) {
+	raw := "ab cd ef 01 23 45 67 89 ab cd ef 01 23 45 67 89 ab cd ef 01 23 45 67 89 ab cd ef 01 23 45 67 89"
+	want := "AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89"
+	if got := NormalizeFingerprint(raw); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	if got := NormalizeFingerprint("not-a-fingerprint"); got != "" {
+		t.Fatalf("expected invalid fingerprint to normalize to empty, got %q", got)
+	}
+}
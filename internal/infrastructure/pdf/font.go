@@ -0,0 +1,456 @@
+package pdf
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Font is a TrueType font that documents embed, cut down to the glyphs they use. The font
+// program travels inside every file, so output looks the same on any machine and printer.
+// A Font is safe for concurrent use.
+type Font struct {
+	name       string
+	data       []byte
+	parsed     *sfnt.Font
+	unitsPerEm fixed.Int26_6
+
+	// Metrics in 1/1000 em, as the font descriptor wants them.
+	ascent    float64
+	descent   float64
+	capHeight float64
+	bbox      [4]float64
+
+	mu       sync.Mutex
+	buf      sfnt.Buffer
+	glyphs   map[rune]glyph
+	fallback glyph
+}
+
+type glyph struct {
+	id      uint16
+	advance float64 // 1/1000 em
+}
+
+var (
+	regularOnce sync.Once
+	regular     *Font
+	boldOnce    sync.Once
+	bold        *Font
+)
+
+// Regular returns the Go Regular font, which is compiled into the binary.
+func Regular() *Font {
+	regularOnce.Do(func() { regular = mustParseFont("GoRegular", goregular.TTF) })
+	return regular
+}
+
+// Bold returns the Go Bold font, which is compiled into the binary.
+func Bold() *Font {
+	boldOnce.Do(func() { bold = mustParseFont("GoBold", gobold.TTF) })
+	return bold
+}
+
+func mustParseFont(name string, data []byte) *Font {
+	f, err := ParseFont(name, data)
+	if err != nil {
+		panic(fmt.Sprintf("pdf: bundled font %s: %v", name, err))
+	}
+	return f
+}
+
+// ParseFont reads a TrueType (glyf-based) font. name is used as the PostScript font name.
+func ParseFont(name string, data []byte) (*Font, error) {
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := readTables(data); err != nil {
+		return nil, err
+	}
+	f := &Font{
+		name:       strings.ReplaceAll(name, " ", ""),
+		data:       data,
+		parsed:     parsed,
+		unitsPerEm: fixed.I(int(parsed.UnitsPerEm())),
+		glyphs:     make(map[rune]glyph),
+	}
+
+	metrics, err := parsed.Metrics(&f.buf, f.unitsPerEm, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	bounds, err := parsed.Bounds(&f.buf, f.unitsPerEm, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	f.ascent = f.toThousandths(metrics.Ascent)
+	f.descent = -f.toThousandths(metrics.Descent)
+	f.capHeight = f.toThousandths(metrics.CapHeight)
+	if f.capHeight == 0 {
+		f.capHeight = f.ascent
+	}
+	// sfnt measures y downwards; PDF measures it upwards.
+	f.bbox = [4]float64{
+		f.toThousandths(bounds.Min.X), -f.toThousandths(bounds.Max.Y),
+		f.toThousandths(bounds.Max.X), -f.toThousandths(bounds.Min.Y),
+	}
+
+	fallback, ok, err := f.lookupLocked('?')
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("font has no question mark glyph")
+	}
+	f.fallback = fallback
+	return f, nil
+}
+
+func (f *Font) toThousandths(v fixed.Int26_6) float64 {
+	return float64(v) * 1000 / float64(f.unitsPerEm)
+}
+
+// Width returns the advance width of text at size, in points.
+func (f *Font) Width(text string, size float64) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	total := 0.0
+	for _, r := range text {
+		total += f.glyphLocked(r).advance
+	}
+	return total * size / 1000
+}
+
+// Has reports whether the font can draw r.
+func (f *Font) Has(r rune) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok, err := f.lookupLocked(r)
+	return ok && err == nil
+}
+
+func (f *Font) glyphFor(r rune) glyph {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.glyphLocked(r)
+}
+
+// glyphLocked returns the glyph for r, or the question mark when the font lacks it.
+func (f *Font) glyphLocked(r rune) glyph {
+	if g, ok, err := f.lookupLocked(r); ok && err == nil {
+		return g
+	}
+	return f.fallback
+}
+
+func (f *Font) lookupLocked(r rune) (glyph, bool, error) {
+	if g, ok := f.glyphs[r]; ok {
+		return g, g.id != 0, nil
+	}
+	index, err := f.parsed.GlyphIndex(&f.buf, r)
+	if err != nil {
+		return glyph{}, false, err
+	}
+	g := glyph{id: uint16(index)}
+	if index != 0 {
+		advance, err := f.parsed.GlyphAdvance(&f.buf, index, f.unitsPerEm, font.HintingNone)
+		if err != nil {
+			return glyph{}, false, err
+		}
+		g.advance = f.toThousandths(advance)
+	}
+	f.glyphs[r] = g
+	return g, index != 0, nil
+}
+
+// subsetName prefixes the font name with the six-letter tag PDF uses to mark a subset. The tag
+// is derived from the glyphs, so the same text always produces the same file.
+func (f *Font) subsetName(glyphs []uint16) string {
+	h := sha1.New()
+	for _, id := range glyphs {
+		_ = binary.Write(h, binary.BigEndian, id)
+	}
+	sum := h.Sum(nil)
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + sum[i]%26
+	}
+	return string(tag) + "+" + f.name
+}
+
+// Tables kept in an embedded program: what a CIDFontType2 needs, plus the small cmap and OS/2
+// tables that stricter font parsers insist on. post loses its glyph names; name and the rest
+// are dropped.
+var subsetTables = []string{"OS/2", "cmap", "cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+// subset rebuilds the font keeping only the outlines of the given glyphs, plus glyph 0 and any
+// component glyphs they reference. Glyph ids do not change; unused glyphs become empty.
+func (f *Font) subset(glyphs []uint16) ([]byte, error) {
+	tables, err := readTables(f.data)
+	if err != nil {
+		return nil, err
+	}
+	head, maxp, loca, glyf := tables["head"], tables["maxp"], tables["loca"], tables["glyf"]
+	if len(head) < 54 || len(maxp) < 6 || loca == nil || glyf == nil {
+		return nil, errors.New("font is missing a required table")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:6]))
+	longOffsets := binary.BigEndian.Uint16(head[50:52]) == 1
+	offsets := make([]uint32, numGlyphs+1)
+	for i := range offsets {
+		if longOffsets {
+			if 4*i+4 > len(loca) {
+				return nil, errors.New("font loca table is truncated")
+			}
+			offsets[i] = binary.BigEndian.Uint32(loca[4*i:])
+		} else {
+			if 2*i+2 > len(loca) {
+				return nil, errors.New("font loca table is truncated")
+			}
+			offsets[i] = uint32(binary.BigEndian.Uint16(loca[2*i:])) * 2
+		}
+	}
+	glyphData := func(id int) ([]byte, error) {
+		start, end := offsets[id], offsets[id+1]
+		if start > end || int(end) > len(glyf) {
+			return nil, fmt.Errorf("font glyph %d is out of range", id)
+		}
+		return glyf[start:end], nil
+	}
+
+	keep := map[int]bool{0: true}
+	queue := []int{0}
+	for _, id := range glyphs {
+		if int(id) < numGlyphs && !keep[int(id)] {
+			keep[int(id)] = true
+			queue = append(queue, int(id))
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		data, err := glyphData(id)
+		if err != nil {
+			return nil, err
+		}
+		components, err := compositeComponents(data)
+		if err != nil {
+			return nil, fmt.Errorf("font glyph %d: %w", id, err)
+		}
+		for _, component := range components {
+			if component < numGlyphs && !keep[component] {
+				keep[component] = true
+				queue = append(queue, component)
+			}
+		}
+	}
+
+	newGlyf := make([]byte, 0, len(glyphs)*256)
+	newLoca := make([]byte, 4*(numGlyphs+1))
+	for id := 0; id < numGlyphs; id++ {
+		if keep[id] {
+			data, err := glyphData(id)
+			if err != nil {
+				return nil, err
+			}
+			newGlyf = append(newGlyf, data...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+		binary.BigEndian.PutUint32(newLoca[4*(id+1):], uint32(len(newGlyf)))
+	}
+
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint32(newHead[8:12], 0)
+	binary.BigEndian.PutUint16(newHead[50:52], 1)
+
+	out := map[string][]byte{"glyf": newGlyf, "loca": newLoca, "head": newHead}
+	if post := tables["post"]; len(post) >= 32 {
+		out["post"] = append([]byte(nil), post[:32]...)
+		binary.BigEndian.PutUint32(out["post"], 0x00030000)
+	}
+	for _, tag := range subsetTables {
+		if _, done := out[tag]; !done && tables[tag] != nil {
+			out[tag] = tables[tag]
+		}
+	}
+	program := writeTables(out)
+	binary.BigEndian.PutUint32(program[headOffset(program)+8:], 0xB1B0AFBA-tableChecksum(program))
+	return program, nil
+}
+
+// Composite glyph flags, from the TrueType glyf specification.
+const (
+	argsAreWords    = 0x0001
+	haveScale       = 0x0008
+	moreComponents  = 0x0020
+	haveXYScale     = 0x0040
+	haveTwoByTwo    = 0x0080
+	compositeHeader = 10
+)
+
+// compositeComponents lists the glyphs a composite glyph is built from.
+func compositeComponents(data []byte) ([]int, error) {
+	if len(data) < compositeHeader || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil, nil
+	}
+	var components []int
+	pos := compositeHeader
+	for {
+		if pos+4 > len(data) {
+			return nil, errors.New("composite glyph is truncated")
+		}
+		flags := binary.BigEndian.Uint16(data[pos:])
+		components = append(components, int(binary.BigEndian.Uint16(data[pos+2:])))
+		pos += 4
+		if flags&argsAreWords != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			pos += 2
+		case flags&haveXYScale != 0:
+			pos += 4
+		case flags&haveTwoByTwo != 0:
+			pos += 8
+		}
+		if flags&moreComponents == 0 {
+			return components, nil
+		}
+	}
+}
+
+// readTables splits an sfnt file into its tables.
+func readTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("font is truncated")
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 && version != 0x74727565 {
+		return nil, errors.New("only TrueType outline fonts can be embedded")
+	}
+	count := int(binary.BigEndian.Uint16(data[4:6]))
+	if 12+16*count > len(data) {
+		return nil, errors.New("font table directory is truncated")
+	}
+	tables := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		entry := data[12+16*i:]
+		offset := binary.BigEndian.Uint32(entry[8:])
+		length := binary.BigEndian.Uint32(entry[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("font table %q is out of range", entry[:4])
+		}
+		tables[string(entry[:4])] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// writeTables assembles an sfnt file with its tables in tag order.
+func writeTables(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	count := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= count {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	header := make([]byte, 12+16*count)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(count))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(count*16-searchRange))
+
+	out := header
+	for i, tag := range tags {
+		table := tables[tag]
+		entry := out[12+16*i:]
+		copy(entry, tag)
+		binary.BigEndian.PutUint32(entry[4:], tableChecksum(table))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(table)))
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+func headOffset(program []byte) int {
+	count := int(binary.BigEndian.Uint16(program[4:6]))
+	for i := 0; i < count; i++ {
+		entry := program[12+16*i:]
+		if string(entry[:4]) == "head" {
+			return int(binary.BigEndian.Uint32(entry[8:]))
+		}
+	}
+	return 0
+}
+
+func tableChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// Wrap breaks text into lines no wider than width at size, splitting a word only when it does
+// not fit on a line by itself. Newlines in text start new lines. Empty text yields one empty line.
+func (f *Font) Wrap(text string, size, width float64) []string {
+	lines := make([]string, 0, 1)
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if f.Width(candidate, size) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			for f.Width(word, size) > width {
+				runes := []rune(word)
+				cut := 1
+				for cut < len(runes)-1 && f.Width(string(runes[:cut+1]), size) <= width {
+					cut++
+				}
+				if cut >= len(runes) {
+					break
+				}
+				lines = append(lines, string(runes[:cut]))
+				word = string(runes[cut:])
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,288 @@
+// Package pdf writes small, fixed-layout PDF documents such as invoices and labels. Text is
+// set in TrueType fonts that are embedded and subset, so files render identically everywhere
+// and need nothing from the machine that opens them. Documents are built in memory; use the
+// export package for long streamed tables.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Common page sizes in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+	MM       = 72 / 25.4
+)
+
+// Document is a sequence of pages sharing a page size and fonts.
+type Document struct {
+	width  float64
+	height float64
+	pages  []*Page
+	fonts  []*fontUse
+	byFont map[*Font]*fontUse
+}
+
+type fontUse struct {
+	font     *Font
+	resource string
+	glyphs   map[uint16]rune
+}
+
+// Page collects drawing operators. Coordinates are points from the bottom-left corner.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// New starts an empty document whose pages are width by height points.
+func New(width, height float64) *Document {
+	return &Document{width: width, height: height, byFont: make(map[*Font]*fontUse)}
+}
+
+// AddPage appends a blank page.
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages added so far, so running footers can be drawn once the count is known.
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+func (d *Document) use(font *Font) *fontUse {
+	if use, ok := d.byFont[font]; ok {
+		return use
+	}
+	use := &fontUse{font: font, resource: fmt.Sprintf("F%d", len(d.fonts)+1), glyphs: make(map[uint16]rune)}
+	d.fonts = append(d.fonts, use)
+	d.byFont[font] = use
+	return use
+}
+
+// Text draws text with its baseline starting at x, y.
+func (p *Page) Text(font *Font, size, x, y float64, text string) {
+	if text == "" {
+		return
+	}
+	use := p.doc.use(font)
+	var encoded strings.Builder
+	for _, r := range text {
+		if r < ' ' {
+			r = ' '
+		}
+		g := font.glyphFor(r)
+		if _, seen := use.glyphs[g.id]; !seen {
+			use.glyphs[g.id] = r
+		}
+		fmt.Fprintf(&encoded, "%04X", g.id)
+	}
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td <%s> Tj ET\n", use.resource, num(size), num(x), num(y), encoded.String())
+}
+
+// TextRight draws text so that it ends at right.
+func (p *Page) TextRight(font *Font, size, right, y float64, text string) {
+	p.Text(font, size, right-font.Width(text, size), y, text)
+}
+
+// TextCentered draws text centred on x.
+func (p *Page) TextCentered(font *Font, size, x, y float64, text string) {
+	p.Text(font, size, x-font.Width(text, size)/2, y, text)
+}
+
+// Line strokes a straight black line.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// Rect strokes the outline of a rectangle whose bottom-left corner is x, y.
+func (p *Page) Rect(x, y, w, h, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n", num(width), num(x), num(y), num(w), num(h))
+}
+
+// FillRect fills a rectangle in a grey level from 0 (black) to 1 (white).
+func (p *Page) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "%s g %s %s %s %s re f 0 g\n", num(gray), num(x), num(y), num(w), num(h))
+}
+
+// Object numbers of the fixed objects; fonts and pages follow.
+const (
+	catalogObject = 1
+	pagesObject   = 2
+)
+
+// WriteTo writes the finished document. The output depends only on what was drawn, so the
+// same document always produces the same bytes.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	out := &objectWriter{}
+	out.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	next := pagesObject + 1
+	fontRefs := make([]string, 0, len(d.fonts))
+	for _, use := range d.fonts {
+		first := next
+		next += 5
+		if err := out.writeFont(first, use); err != nil {
+			return 0, err
+		}
+		fontRefs = append(fontRefs, fmt.Sprintf("/%s %d 0 R", use.resource, first))
+	}
+	resources := fmt.Sprintf("<< /Font << %s >> >>", strings.Join(fontRefs, " "))
+
+	kids := make([]string, len(d.pages))
+	for i, page := range d.pages {
+		contentObject, pageObject := next, next+1
+		next += 2
+		compressed, err := deflate(page.content.Bytes())
+		if err != nil {
+			return 0, err
+		}
+		out.stream(contentObject, "", compressed)
+		out.object(pageObject, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			pagesObject, num(d.width), num(d.height), resources, contentObject))
+		kids[i] = fmt.Sprintf("%d 0 R", pageObject)
+	}
+	out.object(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	out.object(catalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	out.finish(next)
+
+	n, err := w.Write(out.buf.Bytes())
+	return int64(n), err
+}
+
+// Bytes returns the finished document.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type objectWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func (o *objectWriter) object(number int, body string) {
+	if o.offsets == nil {
+		o.offsets = make(map[int]int)
+	}
+	o.offsets[number] = o.buf.Len()
+	fmt.Fprintf(&o.buf, "%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+func (o *objectWriter) stream(number int, extra string, data []byte) {
+	o.object(number, fmt.Sprintf("<< /Length %d /Filter /FlateDecode%s >>\nstream\n%s\nendstream", len(data), extra, data))
+}
+
+// writeFont embeds a font as a Type 0 font over an Identity-encoded CIDFontType2, using five
+// objects from first: the font, its descendant, the descriptor, the program and a ToUnicode
+// map that keeps the text searchable and copyable.
+func (o *objectWriter) writeFont(first int, use *fontUse) error {
+	ids := make([]uint16, 0, len(use.glyphs))
+	for id := range use.glyphs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	f := use.font
+	program, err := f.subset(ids)
+	if err != nil {
+		return fmt.Errorf("failed to subset font %s: %w", f.name, err)
+	}
+	compressed, err := deflate(program)
+	if err != nil {
+		return err
+	}
+	name := f.subsetName(ids)
+
+	var widths strings.Builder
+	for _, id := range ids {
+		g := f.glyphFor(use.glyphs[id])
+		fmt.Fprintf(&widths, "%d [%s] ", id, num(g.advance))
+	}
+
+	descendant, descriptor, file, toUnicode := first+1, first+2, first+3, first+4
+	o.object(first, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, descendant, toUnicode))
+	o.object(descendant, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		name, descriptor, strings.TrimSpace(widths.String())))
+	o.object(descriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%s %s %s %s] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		name, num(f.bbox[0]), num(f.bbox[1]), num(f.bbox[2]), num(f.bbox[3]), num(f.ascent), num(f.descent), num(f.capHeight), file))
+	o.stream(file, fmt.Sprintf(" /Length1 %d", len(program)), compressed)
+
+	cmap, err := deflate([]byte(toUnicodeCMap(ids, use.glyphs)))
+	if err != nil {
+		return err
+	}
+	o.stream(toUnicode, "", cmap)
+	return nil
+}
+
+func toUnicodeCMap(ids []uint16, runes map[uint16]rune) string {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// A bfchar block may hold at most 100 mappings.
+	for start := 0; start < len(ids); start += 100 {
+		end := min(start+100, len(ids))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, id := range ids[start:end] {
+			fmt.Fprintf(&b, "<%04X> <", id)
+			for _, unit := range utf16.Encode([]rune{runes[id]}) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.String()
+}
+
+func (o *objectWriter) finish(size int) {
+	xref := o.buf.Len()
+	fmt.Fprintf(&o.buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for number := 1; number < size; number++ {
+		fmt.Fprintf(&o.buf, "%010d 00000 n \n", o.offsets[number])
+	}
+	fmt.Fprintf(&o.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, catalogObject, xref)
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// num formats a coordinate with at most two decimals and no trailing zeros.
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,167 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestDocument_EmbedsSubsetFontsAndKeepsCrossReferencesValid(t *testing.T) {
+	doc := New(A4Width, A4Height)
+	first := doc.AddPage()
+	first.Text(Bold(), 14, 40, 800, "TAX INVOICE")
+	first.Text(Regular(), 9, 40, 780, "Cumin – whole, 25 kg")
+	first.FillRect(40, 700, 100, 20, 0.9)
+	doc.AddPage().TextRight(Regular(), 9, 550, 40, "Page 2 of 2")
+
+	content, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(content, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	if count := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(content); count == nil || string(count[1]) != "2" {
+		t.Fatalf("expected two pages, got %q", count)
+	}
+	assertCrossReferences(t, content)
+
+	names := regexp.MustCompile(`/FontName /([A-Z]{6})\+(Go\w+)`).FindAllSubmatch(content, -1)
+	if len(names) != 2 || string(names[0][2]) != "GoBold" || string(names[1][2]) != "GoRegular" {
+		t.Fatalf("expected subset Go Bold and Go Regular fonts, got %q", names)
+	}
+
+	programs := fontPrograms(t, content)
+	if len(programs) != 2 {
+		t.Fatalf("expected two embedded font programs, got %d", len(programs))
+	}
+	for _, program := range programs {
+		if len(program) >= len(Regular().data)/2 {
+			t.Fatalf("expected the embedded font to be subset, got %d bytes", len(program))
+		}
+		parsed, err := sfnt.Parse(program)
+		if err != nil {
+			t.Fatalf("embedded font does not parse: %v", err)
+		}
+		if parsed.NumGlyphs() != Regular().parsed.NumGlyphs() && parsed.NumGlyphs() != Bold().parsed.NumGlyphs() {
+			t.Fatalf("expected glyph ids to be preserved")
+		}
+	}
+
+	if !strings.Contains(string(inflateAll(t, content)), "<2013>") {
+		t.Fatalf("expected the ToUnicode map to carry the en dash")
+	}
+}
+
+func TestDocument_OutputIsReproducible(t *testing.T) {
+	build := func() []byte {
+		doc := New(100*MM, 50*MM)
+		doc.AddPage().TextCentered(Bold(), 12, 50*MM, 25*MM, "LOT-20260101-001")
+		content, err := doc.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes failed: %v", err)
+		}
+		return content
+	}
+	if !bytes.Equal(build(), build()) {
+		t.Fatalf("expected the same drawing to produce the same bytes")
+	}
+}
+
+func TestFont_WidthFallsBackForMissingGlyphs(t *testing.T) {
+	f := Regular()
+	if f.Width("", 10) != 0 || f.Width("ab", 10) <= f.Width("a", 10) {
+		t.Fatalf("unexpected widths")
+	}
+	if f.Has('क') {
+		t.Skip("bundled font now covers Devanagari")
+	}
+	if f.Width("क", 10) != f.Width("?", 10) {
+		t.Fatalf("expected a missing glyph to be measured as a question mark")
+	}
+}
+
+func assertCrossReferences(t *testing.T, content []byte) {
+	t.Helper()
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(content)
+	if startxref == nil {
+		t.Fatalf("missing startxref")
+	}
+	xrefOffset, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(content[xrefOffset:], []byte("xref\n")) {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(content[xrefOffset:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(content[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", i+1, content[offset:offset+12])
+		}
+	}
+}
+
+var streamPattern = regexp.MustCompile(`(?s)<<([^\n]*)>>\nstream\n(.*?)\nendstream`)
+
+func fontPrograms(t *testing.T, content []byte) [][]byte {
+	t.Helper()
+	var programs [][]byte
+	for _, match := range streamPattern.FindAllSubmatch(content, -1) {
+		if bytes.Contains(match[1], []byte("/Length1")) {
+			programs = append(programs, inflate(t, match[2]))
+		}
+	}
+	return programs
+}
+
+func inflateAll(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var all []byte
+	for _, match := range streamPattern.FindAllSubmatch(content, -1) {
+		all = append(all, inflate(t, match[2])...)
+	}
+	return all
+}
+
+func inflate(t *testing.T, data []byte) []byte {
+	t.Helper()
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("stream is not zlib: %v", err)
+	}
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to inflate stream: %v", err)
+	}
+	return out
+}
+
+func TestFont_Wrap(t *testing.T) {
+	f := Regular()
+	lines := f.Wrap("Whole cumin seeds from Unjha mandi\nSecond paragraph", 10, 100)
+	for _, line := range lines {
+		if f.Width(line, 10) > 100 {
+			t.Fatalf("line %q is wider than the column", line)
+		}
+	}
+	if len(lines) < 3 || lines[len(lines)-1] != "Second paragraph" {
+		t.Fatalf("expected the newline to start a new line, got %q", lines)
+	}
+	if strings.Join(lines[:len(lines)-1], " ") != "Whole cumin seeds from Unjha mandi" {
+		t.Fatalf("wrapping lost words: %q", lines)
+	}
+
+	long := f.Wrap(strings.Repeat("W", 40), 10, 50)
+	if len(long) < 2 || strings.Join(long, "") != strings.Repeat("W", 40) {
+		t.Fatalf("expected an unbroken word to be split, got %q", long)
+	}
+	if got := f.Wrap("", 10, 50); len(got) != 1 || got[0] != "" {
+		t.Fatalf("expected one empty line, got %q", got)
+	}
+}
@@ -0,0 +1,324 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+
+	// maxXLSXPartBytes bounds each XML part read from a workbook, so a crafted file cannot
+	// expand into an unbounded amount of memory.
+	maxXLSXPartBytes = 64 << 20
+	// maxXLSXColumns is the column limit of Excel itself (XFD).
+	maxXLSXColumns = 16384
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported file format: must be csv or xlsx")
+	ErrNoWorksheet       = errors.New("workbook has no worksheets")
+)
+
+// ResolveFormat returns the explicit format if given, otherwise the one implied by fileName.
+func ResolveFormat(format, fileName string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(format))
+	if value == "" {
+		value = strings.TrimPrefix(strings.ToLower(filepath.Ext(strings.TrimSpace(fileName))), ".")
+	}
+	switch value {
+	case FormatCSV, FormatXLSX:
+		return value, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// Row is one non-blank row with its 1-based row number in the file, as a spreadsheet
+// program would show it.
+type Row struct {
+	Number int
+	Cells  []string
+}
+
+// ReadRows returns the non-blank rows of a CSV file, or of the first worksheet of an XLSX
+// workbook, as text. Rows are padded to the same width so callers can index columns directly.
+func ReadRows(format string, content []byte) ([]Row, error) {
+	var (
+		rows []Row
+		err  error
+	)
+	switch format {
+	case FormatCSV:
+		rows, err = readCSV(content)
+	case FormatXLSX:
+		rows, err = readXLSX(content)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	return padRows(rows), nil
+}
+
+func readCSV(content []byte) ([]Row, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	rows := make([]Row, 0, len(records))
+	for i, record := range records {
+		if !isBlank(record) {
+			rows = append(rows, Row{Number: i + 1, Cells: record})
+		}
+	}
+	return rows, nil
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is the text of a shared or inline string: either a single <t>, or rich-text runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(content []byte) ([]Row, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[strings.TrimPrefix(file.Name, "/")] = file
+	}
+
+	sheetPath, err := firstWorksheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXLSXPart(file, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	file, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("invalid xlsx: missing %s", sheetPath)
+	}
+	var sheet xlsxWorksheet
+	if err := decodeXLSXPart(file, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		// Blank rows are left out of the file, so <row r="..."> numbers the ones that remain.
+		number := row.Index
+		if number <= 0 {
+			number = 1
+			if len(rows) > 0 {
+				number = rows[len(rows)-1].Number + 1
+			}
+		}
+
+		values := make([]string, 0, len(row.Cells))
+		for j, cell := range row.Cells {
+			col := j
+			if cell.Ref != "" {
+				parsed, err := columnIndex(cell.Ref)
+				if err != nil {
+					return nil, fmt.Errorf("invalid xlsx: row %d: %w", number, err)
+				}
+				col = parsed
+			}
+			if col >= maxXLSXColumns {
+				return nil, fmt.Errorf("invalid xlsx: row %d has too many columns", number)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			value, err := cellText(cell.Type, cell.Value, cell.Inline, shared.Items)
+			if err != nil {
+				return nil, fmt.Errorf("invalid xlsx: cell %s: %w", cell.Ref, err)
+			}
+			values[col] = value
+		}
+		if !isBlank(values) {
+			rows = append(rows, Row{Number: number, Cells: values})
+		}
+	}
+	return rows, nil
+}
+
+// firstWorksheetPath follows the workbook's first <sheet> to its part, falling back to the
+// conventional name for workbooks written without relationships.
+func firstWorksheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	workbookFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", fmt.Errorf("invalid xlsx: missing xl/workbook.xml")
+	}
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(workbookFile, &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", ErrNoWorksheet
+	}
+
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return fallback, nil
+	}
+	var rels xlsxRelationships
+	if err := decodeXLSXPart(relsFile, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+func decodeXLSXPart(file *zip.File, target interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("invalid xlsx: %s: %w", file.Name, err)
+	}
+	defer reader.Close()
+	if err := xml.NewDecoder(io.LimitReader(reader, maxXLSXPartBytes)).Decode(target); err != nil {
+		return fmt.Errorf("invalid xlsx: %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+func cellText(cellType, value string, inline xlsxText, shared []xlsxText) (string, error) {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || index < 0 || index >= len(shared) {
+			return "", fmt.Errorf("shared string %q not found", value)
+		}
+		return shared[index].String(), nil
+	case "inlineStr":
+		return inline.String(), nil
+	case "b":
+		if strings.TrimSpace(value) == "1" {
+			return "true", nil
+		}
+		return "false", nil
+	default:
+		return value, nil
+	}
+}
+
+// columnIndex converts the letters of a cell reference such as "AB12" to a zero-based column.
+func columnIndex(ref string) (int, error) {
+	col := 0
+	letters := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		letters++
+		if letters > 3 {
+			return 0, fmt.Errorf("invalid cell reference %q", ref)
+		}
+	}
+	if letters == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+func isBlank(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func padRows(rows []Row) []Row {
+	width := 0
+	for _, row := range rows {
+		if len(row.Cells) > width {
+			width = len(row.Cells)
+		}
+	}
+	for i := range rows {
+		for len(rows[i].Cells) < width {
+			rows[i].Cells = append(rows[i].Cells, "")
+		}
+	}
+	return rows
+}
@@ -0,0 +1,126 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func buildTestXLSX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range parts {
+		part, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close workbook: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestResolveFormat(t *testing.T) {
+	cases := []struct {
+		format, fileName, want string
+		wantErr                bool
+	}{
+		{fileName: "items.CSV", want: FormatCSV},
+		{fileName: "parties.xlsx", want: FormatXLSX},
+		{format: " XLSX ", fileName: "upload.bin", want: FormatXLSX},
+		{fileName: "items.xls", wantErr: true},
+		{wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ResolveFormat(tc.format, tc.fileName)
+		if tc.wantErr {
+			if !errors.Is(err, ErrUnsupportedFormat) {
+				t.Fatalf("ResolveFormat(%q, %q): expected unsupported format, got %q, %v", tc.format, tc.fileName, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Fatalf("ResolveFormat(%q, %q) = %q, %v; want %q", tc.format, tc.fileName, got, err, tc.want)
+		}
+	}
+}
+
+func TestReadRows_CSVSkipsBlankRowsAndKeepsRowNumbers(t *testing.T) {
+	content := []byte("\xef\xbb\xbfsku,name\nCUM-1,Cumin\n,\n\"COR-1\",\"Coriander, whole\",extra\n")
+	rows, err := ReadRows(FormatCSV, content)
+	if err != nil {
+		t.Fatalf("ReadRows failed: %v", err)
+	}
+	want := []Row{
+		{Number: 1, Cells: []string{"sku", "name", ""}},
+		{Number: 2, Cells: []string{"CUM-1", "Cumin", ""}},
+		{Number: 4, Cells: []string{"COR-1", "Coriander, whole", "extra"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("unexpected rows:\n got %#v\nwant %#v", rows, want)
+	}
+}
+
+func TestReadRows_XLSXResolvesSharedInlineAndSparseCells(t *testing.T) {
+	content := buildTestXLSX(t, map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets><sheet name="Items" sheetId="1" r:id="rId7"/><sheet name="Other" sheetId="2" r:id="rId8"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId8" Type="worksheet" Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId7" Type="worksheet" Target="worksheets/items.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>sku</t></si><si><t>minimum_stock</t></si><si><r><t>Cum</t></r><r><t>in</t></r></si>
+</sst>`,
+		"xl/worksheets/items.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+  <row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+  <row r="3"><c r="A3" t="inlineStr"><is><t>CUM-1</t></is></c><c r="B3" t="s"><v>2</v></c><c r="C3"><v>12.5</v></c><c r="D3" t="b"><v>1</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+  <row r="1"><c r="A1" t="inlineStr"><is><t>wrong sheet</t></is></c></row>
+</sheetData></worksheet>`,
+	})
+
+	rows, err := ReadRows(FormatXLSX, content)
+	if err != nil {
+		t.Fatalf("ReadRows failed: %v", err)
+	}
+	want := []Row{
+		{Number: 1, Cells: []string{"sku", "", "minimum_stock", ""}},
+		{Number: 3, Cells: []string{"CUM-1", "Cumin", "12.5", "true"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("unexpected rows:\n got %#v\nwant %#v", rows, want)
+	}
+}
+
+func TestReadRows_XLSXRejectsBrokenWorkbooks(t *testing.T) {
+	if _, err := ReadRows(FormatXLSX, []byte("sku,name\n")); err == nil {
+		t.Fatal("expected a csv body to be rejected as xlsx")
+	}
+
+	missingSharedString := buildTestXLSX(t, map[string]string{
+		"xl/workbook.xml":          `<workbook><sheets><sheet name="Items" sheetId="1"/></sheets></workbook>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>4</v></c></row></sheetData></worksheet>`,
+	})
+	if _, err := ReadRows(FormatXLSX, missingSharedString); err == nil {
+		t.Fatal("expected a missing shared string to be rejected")
+	}
+
+	noSheets := buildTestXLSX(t, map[string]string{"xl/workbook.xml": `<workbook><sheets/></workbook>`})
+	if _, err := ReadRows(FormatXLSX, noSheets); !errors.Is(err, ErrNoWorksheet) {
+		t.Fatalf("expected ErrNoWorksheet, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package system
+
+import (
+	"sync"
+
+	domainSys "masala_inventory_managment/internal/domain/system"
+)
+
+// ServerInstanceMutex is the single-instance lock the server holds while it runs.
+const ServerInstanceMutex = "MasalaServerMutex"
+
+var (
+	instanceLockMu sync.Mutex
+	instanceLock   domainSys.SysMonitor
+)
+
+// LockServerInstance takes the server's single-instance lock for the rest of the process, for
+// offline tools that work on the database file directly. It reports whether a running server
+// already holds the lock; while the tool holds it, the server cannot start.
+func LockServerInstance() (bool, error) {
+	instanceLockMu.Lock()
+	defer instanceLockMu.Unlock()
+	if instanceLock != nil {
+		return false, nil
+	}
+	monitor := NewMonitor()
+	running, err := monitor.CheckMutex(ServerInstanceMutex)
+	if err == nil && !running {
+		instanceLock = monitor
+	}
+	return running, err
+}
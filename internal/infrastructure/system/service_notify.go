@@ -0,0 +1,57 @@
+package system
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Messages for NotifyServiceManager, from the sd_notify protocol.
+const (
+	ServiceReady    = "READY=1"
+	ServiceStopping = "STOPPING=1"
+	ServiceWatchdog = "WATCHDOG=1"
+)
+
+// NotifyServiceManager tells systemd about the service's state over $NOTIFY_SOCKET, for
+// units with Type=notify. It reports false when the process was not started that way.
+func NotifyServiceManager(state string) (bool, error) {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace.
+	if socketPath[0] == '@' {
+		socketPath = "\x00" + socketPath[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to reach service manager: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify service manager: %w", err)
+	}
+	return true, nil
+}
+
+// ServiceWatchdogInterval is how often systemd expects ServiceWatchdog when the unit sets
+// WatchdogSec, or zero when it does not watch this process.
+func ServiceWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// UnderServiceManager reports whether systemd started this process as a service, in which
+// case it restarts the process itself.
+func UnderServiceManager() bool {
+	return os.Getenv("INVOCATION_ID") != "" || os.Getenv("NOTIFY_SOCKET") != ""
+}
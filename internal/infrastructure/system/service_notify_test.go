@@ -0,0 +1,63 @@
+//go:build !windows
+
+package system
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotifyServiceManager(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := NotifyServiceManager(ServiceReady); sent || err != nil {
+		t.Fatalf("expected nothing sent outside systemd, got %v, %v", sent, err)
+	}
+
+	// Socket paths are limited to about 100 bytes, so keep it short.
+	dir, err := os.MkdirTemp("", "sd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "notify")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socketPath)
+	if sent, err := NotifyServiceManager(ServiceReady); !sent || err != nil {
+		t.Fatalf("NotifyServiceManager: %v, %v", sent, err)
+	}
+	buf := make([]byte, 64)
+	listener.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := listener.Read(buf)
+	if err != nil || string(buf[:n]) != ServiceReady {
+		t.Errorf("expected %q, got %q, %v", ServiceReady, buf[:n], err)
+	}
+	if !UnderServiceManager() {
+		t.Error("expected a notify socket to mean systemd started the process")
+	}
+}
+
+func TestServiceWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if got := ServiceWatchdogInterval(); got != 30*time.Second {
+		t.Errorf("expected 30s, got %v", got)
+	}
+	t.Setenv("WATCHDOG_PID", "1")
+	if got := ServiceWatchdogInterval(); got != 0 {
+		t.Errorf("expected no watchdog for another process, got %v", got)
+	}
+	t.Setenv("WATCHDOG_USEC", "")
+	t.Setenv("WATCHDOG_PID", "")
+	if got := ServiceWatchdogInterval(); got != 0 {
+		t.Errorf("expected no watchdog, got %v", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 type Watchdog struct {
 	interval uint32
 	lastPing time.Time
+	pings    uint64
 	mu       sync.Mutex
 	stopChan chan struct{}
 }
@@ -25,6 +26,14 @@ func (w *Watchdog) Ping() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	w.lastPing = time.Now()
+	w.pings++
+}
+
+// Stats returns when the watchdog was last pinged and how many pings it has had.
+func (w *Watchdog) Stats() (time.Time, uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastPing, w.pings
 }
 
 func (w *Watchdog) Start(ctx context.Context, onFailure func()) {
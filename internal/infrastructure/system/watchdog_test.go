@@ -52,4 +52,10 @@ func TestWatchdog_Ping(t *testing.T) {
 	if atomic.LoadInt32(&failed) != 0 {
 		t.Errorf("Expected watchdog NOT to fail during pings, but it did")
 	}
+
+	// Start pings once itself.
+	lastPing, pings := wd.Stats()
+	if pings != 4 || time.Since(lastPing) > time.Second {
+		t.Errorf("Expected 4 pings, the last just now; got %d at %v", pings, lastPing)
+	}
 }
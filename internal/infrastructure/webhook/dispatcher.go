@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	domainWebhook "masala_inventory_managment/internal/domain/webhook"
+)
+
+// Headers sent with every delivery. The signature is an HMAC-SHA256 of "<timestamp>.<body>"
+// keyed with the subscription secret, so receivers can reject forged or replayed calls.
+const (
+	HeaderEvent     = "X-Masala-Event"
+	HeaderDelivery  = "X-Masala-Delivery"
+	HeaderTimestamp = "X-Masala-Timestamp"
+	HeaderSignature = "X-Masala-Signature"
+)
+
+const (
+	DefaultMaxAttempts  = 10
+	defaultPollInterval = 15 * time.Second
+	defaultBatchSize    = 50
+	requestTimeout      = 10 * time.Second
+	baseRetryDelay      = 30 * time.Second
+	maxRetryDelay       = 6 * time.Hour
+	maxErrorBodyBytes   = 512
+)
+
+// Sign returns the X-Masala-Signature value for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryDelay is how long to wait after the given failed attempt: 30s, 1m, 2m, ... up to 6h.
+func RetryDelay(attempt int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// Dispatcher writes events for matching subscriptions to the outbox, then delivers the outbox
+// in the background. Because the outbox is in the database, deliveries owed when the server
+// stops are sent after it starts again.
+type Dispatcher struct {
+	repo         domainWebhook.Repository
+	client       *http.Client
+	now          func() time.Time
+	pollInterval time.Duration
+	maxAttempts  int
+
+	deliverMu sync.Mutex
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewDispatcher(repo domainWebhook.Repository) *Dispatcher {
+	return &Dispatcher{
+		repo:         repo,
+		client:       &http.Client{Timeout: requestTimeout},
+		now:          time.Now,
+		pollInterval: defaultPollInterval,
+		maxAttempts:  DefaultMaxAttempts,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Enqueue stores the event in the outbox once for each active subscription that wants it.
+func (d *Dispatcher) Enqueue(event domainEvents.Event) error {
+	subs, err := d.repo.ListSubscriptions()
+	if err != nil {
+		return err
+	}
+	entries := make([]domainWebhook.OutboxEntry, 0)
+	var payload []byte
+	for _, sub := range subs {
+		if !sub.Wants(event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+			}
+		}
+		entries = append(entries, domainWebhook.OutboxEntry{
+			SubscriptionID: sub.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			NextAttemptAt:  d.now().UTC(),
+		})
+	}
+	if err := d.repo.Enqueue(entries); err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		d.Wake()
+	}
+	return nil
+}
+
+// EnqueueFor stores an event for a single subscription regardless of its event types, e.g. a
+// test delivery an Admin asked for.
+func (d *Dispatcher) EnqueueFor(subscriptionID int64, event domainEvents.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := d.repo.Enqueue([]domainWebhook.OutboxEntry{{
+		SubscriptionID: subscriptionID,
+		EventType:      event.Type,
+		Payload:        string(payload),
+		NextAttemptAt:  d.now().UTC(),
+	}}); err != nil {
+		return err
+	}
+	d.Wake()
+	return nil
+}
+
+// Wake asks the background loop to deliver now rather than at its next poll.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start delivers the outbox in the background until Stop. Events reach the outbox through
+// Enqueue, which the services that produce them call before their writes return.
+func (d *Dispatcher) Start() {
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.pollInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := d.DeliverDue(); err != nil {
+				slog.Error("Webhook delivery run failed", "error", err, "component", "webhook")
+			}
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	d.stop = nil
+}
+
+// DeliverDue attempts every outbox entry that is due and returns how many were delivered.
+func (d *Dispatcher) DeliverDue() (int, error) {
+	d.deliverMu.Lock()
+	defer d.deliverMu.Unlock()
+
+	entries, err := d.repo.DueOutbox(d.now(), defaultBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, entry := range entries {
+		ok, err := d.deliver(entry)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+func (d *Dispatcher) deliver(entry domainWebhook.OutboxEntry) (bool, error) {
+	attempt := entry.Attempts + 1
+	record := &domainWebhook.Delivery{
+		OutboxID:       entry.ID,
+		SubscriptionID: entry.SubscriptionID,
+		EventType:      entry.EventType,
+		Attempt:        attempt,
+	}
+
+	sub, err := d.repo.GetSubscription(entry.SubscriptionID)
+	if err != nil {
+		return false, err
+	}
+	if !sub.IsActive {
+		record.Error = "subscription is disabled"
+		return false, d.repo.RecordDelivery(record, domainWebhook.OutboxFailed, d.now())
+	}
+
+	started := d.now()
+	record.AttemptedAt = started.UTC()
+	record.StatusCode, record.Error = d.post(sub, entry, started)
+	record.DurationMs = d.now().Sub(started).Milliseconds()
+	record.Success = record.Error == ""
+
+	switch {
+	case record.Success:
+		return true, d.repo.RecordDelivery(record, domainWebhook.OutboxDelivered, started)
+	case attempt >= d.maxAttempts:
+		slog.Warn("Webhook delivery abandoned", "subscription", sub.Name, "event", entry.EventType, "attempts", attempt, "error", record.Error, "component", "webhook")
+		return false, d.repo.RecordDelivery(record, domainWebhook.OutboxFailed, started)
+	default:
+		return false, d.repo.RecordDelivery(record, domainWebhook.OutboxPending, started.Add(RetryDelay(attempt)))
+	}
+}
+
+// post sends one attempt, returning the response status and an error message if it failed.
+func (d *Dispatcher) post(sub *domainWebhook.Subscription, entry domainWebhook.OutboxEntry, sentAt time.Time) (int, string) {
+	body := []byte(entry.Payload)
+	timestamp := sentAt.Unix()
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MasalaInventory-Webhook/1")
+	req.Header.Set(HeaderEvent, entry.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(entry.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, ""
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	message := fmt.Sprintf("receiver responded with status %d", resp.StatusCode)
+	if len(bytes.TrimSpace(snippet)) > 0 {
+		message += ": " + string(bytes.TrimSpace(snippet))
+	}
+	return resp.StatusCode, message
+}
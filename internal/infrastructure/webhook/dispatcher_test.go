@@ -0,0 +1,214 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	masala_inventory_managment "masala_inventory_managment"
+	appInventory "masala_inventory_managment/internal/app/inventory"
+	domainAuth "masala_inventory_managment/internal/domain/auth"
+	domainEvents "masala_inventory_managment/internal/domain/events"
+	domainInventory "masala_inventory_managment/internal/domain/inventory"
+	domainWebhook "masala_inventory_managment/internal/domain/webhook"
+	"masala_inventory_managment/internal/infrastructure/db"
+)
+
+func setupWebhookRepo(t *testing.T) *db.SqliteWebhookRepository {
+	t.Helper()
+	repo, _ := setupWebhookAndInventoryRepos(t)
+	return repo
+}
+
+func setupWebhookAndInventoryRepos(t *testing.T) (*db.SqliteWebhookRepository, *db.SqliteInventoryRepository) {
+	t.Helper()
+	manager := db.NewDatabaseManager(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("Failed to connect to db: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+	if err := db.NewMigrator(manager).RunMigrations(masala_inventory_managment.MigrationAssets, "internal/infrastructure/db/migrations"); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return db.NewSqliteWebhookRepository(manager.GetDB()), db.NewSqliteInventoryRepository(manager.GetDB())
+}
+
+type standIn struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, body)
+	status := http.StatusOK
+	if len(s.statuses) > 0 {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestDispatcher_DeliversSignedEventsToMatchingSubscriptions(t *testing.T) {
+	repo := setupWebhookRepo(t)
+	receiver := &standIn{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	wanted := &domainWebhook.Subscription{Name: "accounts", URL: server.URL, EventTypes: []string{domainEvents.TypeGrnCreated}, Secret: "0123456789abcdef", IsActive: true}
+	other := &domainWebhook.Subscription{Name: "notifier", URL: server.URL, EventTypes: []string{domainEvents.TypeStockBelowMinimum}, Secret: "fedcba9876543210", IsActive: true}
+	for _, sub := range []*domainWebhook.Subscription{wanted, other} {
+		if err := repo.CreateSubscription(sub); err != nil {
+			t.Fatalf("CreateSubscription failed: %v", err)
+		}
+	}
+
+	dispatcher := NewDispatcher(repo)
+	if err := dispatcher.Enqueue(domainEvents.Event{Sequence: 4, Type: domainEvents.TypeGrnCreated, EntityID: "12"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	delivered, err := dispatcher.DeliverDue()
+	if err != nil || delivered != 1 {
+		t.Fatalf("expected one delivery, got %d (%v)", delivered, err)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.requests) != 1 {
+		t.Fatalf("expected only the subscribed webhook to be called, got %d calls", len(receiver.requests))
+	}
+	req, body := receiver.requests[0], receiver.bodies[0]
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("missing timestamp header: %v", err)
+	}
+	if got := req.Header.Get(HeaderSignature); got != Sign(wanted.Secret, timestamp, body) {
+		t.Fatalf("signature does not verify: %q", got)
+	}
+	if req.Header.Get(HeaderEvent) != domainEvents.TypeGrnCreated {
+		t.Fatalf("unexpected event header %q", req.Header.Get(HeaderEvent))
+	}
+
+	log, err := repo.ListDeliveries(domainWebhook.DeliveryFilter{SubscriptionID: wanted.ID})
+	if err != nil || len(log) != 1 || !log[0].Success || log[0].StatusCode != http.StatusOK {
+		t.Fatalf("unexpected delivery log %+v (%v)", log, err)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoffUntilAttemptsRunOut(t *testing.T) {
+	repo := setupWebhookRepo(t)
+	receiver := &standIn{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub := &domainWebhook.Subscription{Name: "accounts", URL: server.URL, EventTypes: []string{"*"}, Secret: "0123456789abcdef", IsActive: true}
+	if err := repo.CreateSubscription(sub); err != nil {
+		t.Fatalf("CreateSubscription failed: %v", err)
+	}
+
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	dispatcher := NewDispatcher(repo)
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.maxAttempts = 3
+	if err := dispatcher.Enqueue(domainEvents.Event{Type: domainEvents.TypeAdjustmentPosted}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	if delivered, _ := dispatcher.DeliverDue(); delivered != 0 {
+		t.Fatalf("expected first attempt to fail")
+	}
+	if due, _ := repo.DueOutbox(now.Add(RetryDelay(1)-time.Second), 10); len(due) != 0 {
+		t.Fatalf("expected retry to wait for backoff, got %+v", due)
+	}
+
+	now = now.Add(RetryDelay(1))
+	dispatcher.DeliverDue()
+	now = now.Add(RetryDelay(2))
+	if delivered, _ := dispatcher.DeliverDue(); delivered != 1 {
+		t.Fatalf("expected third attempt to succeed")
+	}
+
+	log, _ := repo.ListDeliveries(domainWebhook.DeliveryFilter{SubscriptionID: sub.ID})
+	if len(log) != 3 || log[0].Attempt != 3 || !log[0].Success || log[2].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected delivery log %+v", log)
+	}
+	if due, _ := repo.DueOutbox(now.Add(24*time.Hour), 10); len(due) != 0 {
+		t.Fatalf("expected delivered entry to leave the outbox, got %+v", due)
+	}
+
+	receiver.mu.Lock()
+	receiver.statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}
+	receiver.mu.Unlock()
+	dispatcher.Enqueue(domainEvents.Event{Type: domainEvents.TypeAdjustmentPosted})
+	for i := 1; i <= 3; i++ {
+		dispatcher.DeliverDue()
+		now = now.Add(RetryDelay(i))
+	}
+	if due, _ := repo.DueOutbox(now.Add(24*time.Hour), 10); len(due) != 0 {
+		t.Fatalf("expected entry to be abandoned after max attempts, got %+v", due)
+	}
+}
+
+func TestDispatcher_QueuesLowStockWhenADispatchCrossesMinimum(t *testing.T) {
+	repo, inventoryRepo := setupWebhookAndInventoryRepos(t)
+	sub := &domainWebhook.Subscription{Name: "purchasing", URL: "http://127.0.0.1:9/hook", EventTypes: []string{domainEvents.TypeStockBelowMinimum}, Secret: "0123456789abcdef", IsActive: true}
+	if err := repo.CreateSubscription(sub); err != nil {
+		t.Fatalf("CreateSubscription failed: %v", err)
+	}
+
+	item := &domainInventory.Item{SKU: "RAW-LOW-1", Name: "Cumin", ItemType: domainInventory.ItemTypeRaw, BaseUnit: "kg", MinimumStock: 10, IsActive: true}
+	if err := inventoryRepo.CreateItem(item); err != nil {
+		t.Fatalf("CreateItem failed: %v", err)
+	}
+	supplier := &domainInventory.Party{PartyType: "SUPPLIER", Name: "Spice Traders", Phone: "0000000000", IsActive: true}
+	if err := inventoryRepo.CreateParty(supplier); err != nil {
+		t.Fatalf("CreateParty failed: %v", err)
+	}
+	grn := &domainInventory.GRN{GRNNumber: "GRN-LOW-1", SupplierID: supplier.ID, Lines: []domainInventory.GRNLine{{LineNo: 1, ItemID: item.ID, QuantityReceived: 25}}}
+	if err := inventoryRepo.CreateGRN(grn); err != nil {
+		t.Fatalf("CreateGRN failed: %v", err)
+	}
+
+	service := appInventory.NewService(inventoryRepo, func(string) (domainAuth.Role, error) { return domainAuth.RoleAdmin, nil }, nil)
+	service.SetEventOutbox(NewDispatcher(repo))
+	dispatch := func(quantity float64) {
+		t.Helper()
+		if _, err := service.RecordLotStockMovement(appInventory.RecordLotStockMovementInput{
+			LotNumber: grn.Lines[0].LotNumber, TransactionType: "OUT", Quantity: quantity, ReferenceID: "DSP-1", AuthToken: "admin-token",
+		}); err != nil {
+			t.Fatalf("RecordLotStockMovement failed: %v", err)
+		}
+	}
+
+	dispatch(10)
+	if due, _ := repo.DueOutbox(time.Now().Add(time.Minute), 10); len(due) != 0 {
+		t.Fatalf("expected nothing queued while above minimum, got %+v", due)
+	}
+	dispatch(8)
+	due, err := repo.DueOutbox(time.Now().Add(time.Minute), 10)
+	if err != nil || len(due) != 1 || due[0].EventType != domainEvents.TypeStockBelowMinimum || due[0].SubscriptionID != sub.ID {
+		t.Fatalf("expected one low stock delivery queued, got %+v (%v)", due, err)
+	}
+	dispatch(2)
+	if due, _ := repo.DueOutbox(time.Now().Add(time.Minute), 10); len(due) != 1 {
+		t.Fatalf("expected no repeat alert while already below minimum, got %+v", due)
+	}
+}
+
+func TestRetryDelay_DoublesUpToCap(t *testing.T) {
+	if RetryDelay(1) != 30*time.Second || RetryDelay(3) != 2*time.Minute {
+		t.Fatalf("unexpected backoff: %v, %v", RetryDelay(1), RetryDelay(3))
+	}
+	if RetryDelay(40) != maxRetryDelay {
+		t.Fatalf("expected backoff to be capped, got %v", RetryDelay(40))
+	}
+}